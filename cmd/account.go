@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"autocert/internal/acme"
+	"autocert/internal/audit"
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/keystore"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "管理 ACME 账户",
+	Long: `管理 ACME 账户密钥。
+
+账户密钥默认保存在配置目录 account/account.key；配置 acme.key_backend: pkcs11
+后账户密钥保存在硬件令牌（或通过 tpm2-pkcs11 使用 TPM）中，签名在令牌内完成。
+
+子命令:
+  show          显示账户密钥信息
+  rollover-key  轮换账户密钥`,
+}
+
+var accountShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "显示账户密钥信息",
+	RunE:  runAccountShow,
+}
+
+var accountRolloverCmd = &cobra.Command{
+	Use:   "rollover-key",
+	Short: "轮换账户密钥",
+	Long: `生成新的账户密钥，通过 keyChange 请求（RFC 8555 第 7.3.5 节）通知 CA，
+CA 接受后原子地替换本地密钥文件。账户及其已有的授权保持不变，适用于怀疑账户密钥泄露时。
+
+新密钥在提交前先保存为 account.key.next；CA 拒绝或进程中断时可直接重新执行，
+会重用该密钥。PKCS#11 方式的账户密钥需要在令牌中生成新密钥，不支持自动轮换。`,
+	Args: cobra.NoArgs,
+	RunE: runAccountRollover,
+}
+
+func init() {
+	rootCmd.AddCommand(accountCmd)
+	accountCmd.AddCommand(accountShowCmd)
+	accountCmd.AddCommand(accountRolloverCmd)
+}
+
+func runAccountShow(cmd *cobra.Command, args []string) error {
+	acmeConfig := config.GetACMEConfig()
+
+	signer, err := keystore.AccountSigner()
+	if err != nil {
+		return fmt.Errorf("加载账户密钥失败: %w", err)
+	}
+
+	thumbprint, err := acme.Thumbprint(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("ACME 服务器: %s\n", acmeConfig.Server)
+	fmt.Printf("邮箱: %s\n", acmeConfig.Email)
+
+	switch acmeConfig.KeyBackend {
+	case keystore.BackendPKCS11:
+		fmt.Printf("密钥存储: PKCS#11 (%s)\n", acmeConfig.PKCS11.Module)
+	default:
+		fmt.Printf("密钥存储: 文件 (%s)\n", keystore.AccountKeyPath())
+	}
+
+	switch key := signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		fmt.Printf("密钥类型: ECDSA %s\n", key.Curve.Params().Name)
+	case *rsa.PublicKey:
+		fmt.Printf("密钥类型: RSA %d\n", key.N.BitLen())
+	}
+	fmt.Printf("JWK 指纹: %s\n", thumbprint)
+
+	return nil
+}
+
+func runAccountRollover(cmd *cobra.Command, args []string) error {
+	oldKey, newKey, err := cert.RolloverAccountKey()
+	if err != nil {
+		audit.Record(audit.Entry{Action: "account.rollover-key", Result: audit.ResultFailure, Detail: err.Error()})
+		return err
+	}
+
+	oldThumbprint, err := acme.Thumbprint(oldKey.Public())
+	if err != nil {
+		return err
+	}
+	newThumbprint, err := acme.Thumbprint(newKey.Public())
+	if err != nil {
+		return err
+	}
+
+	audit.Record(audit.Entry{Action: "account.rollover-key", Result: audit.ResultSuccess,
+		Detail: fmt.Sprintf("%s -> %s", oldThumbprint, newThumbprint)})
+	console.Success("账户密钥已轮换")
+	fmt.Printf("旧 JWK 指纹: %s\n", oldThumbprint)
+	fmt.Printf("新 JWK 指纹: %s\n", newThumbprint)
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/webserver"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var activateCmd = &cobra.Command{
+	Use:   "activate <证书名或域名>",
+	Short: "启用暂存部署的站点配置",
+	Long: `启用 install --staging-deploy 写入的站点配置：<站点配置>.disabled 替换站点配置并启用，
+配置测试通过后重载 Web 服务器；测试失败时恢复原来的站点配置，保持暂存。
+
+暂存部署在启用前一直保持暂存，续期重新生成的站点配置同样写入 .disabled 文件；
+启用后续期直接更新站点配置。
+
+示例:
+  autocert activate example.com
+  autocert activate example.com_san`,
+	Args: cobra.ExactArgs(1),
+	RunE: runActivate,
+}
+
+func init() {
+	rootCmd.AddCommand(activateCmd)
+}
+
+func runActivate(cmd *cobra.Command, args []string) error {
+	stored, err := findStoredCert(args[0])
+	if err != nil {
+		return err
+	}
+
+	serverType := config.GetWebServerConfig().Type
+	path, err := webserver.ActivateStaged(serverType, stored.Domains[0])
+	if err != nil {
+		return fmt.Errorf("启用站点配置失败: %w", err)
+	}
+	if err := stored.ClearStaged(); err != nil {
+		return fmt.Errorf("更新站点配置选项失败: %w", err)
+	}
+
+	console.Success("已启用站点配置 %s", path)
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"autocert/internal/agent"
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "卫星节点（agent）向控制节点注册和签到",
+	Long: `在卫星节点上使用控制节点生成的一次性加入令牌（autocert ca join-token）注册，
+获得控制节点本地 CA 签发的客户端证书，之后与控制节点守护进程的通信使用 mTLS。
+
+注册后守护进程每轮续期检查、renew --all 结束时自动签到，向控制节点报告本机部署的证书版本，
+控制节点用 autocert agents 查看清单。
+
+子命令:
+  enroll    向控制节点注册
+  checkin   立即向控制节点签到
+
+示例:
+  autocert agent enroll --server https://controller.internal:8443 --token <加入令牌>
+  autocert agent checkin`,
+}
+
+var agentEnrollCmd = &cobra.Command{
+	Use:   "enroll",
+	Short: "向控制节点注册",
+	Long: `使用一次性加入令牌向控制节点注册：在本机生成私钥，只提交 CSR，控制节点的本地 CA 签发客户端证书。
+加入令牌包含控制节点 CA 根证书的指纹，首次连接时据此确认控制节点，不需要预先信任其证书。
+
+身份、客户端证书、私钥（0600）和 CA 根证书保存在配置目录的 agent/ 中。
+已注册时需要 --force 才会重新注册（原证书不会自动吊销，请在控制节点执行 autocert ca revoke）。`,
+	Args: cobra.NoArgs,
+	RunE: runAgentEnroll,
+}
+
+var agentCheckInCmd = &cobra.Command{
+	Use:   "checkin",
+	Short: "立即向控制节点签到",
+	Long: `使用客户端证书向控制节点签到，报告本机证书目录中每张证书的名称、域名、序列号和到期时间。
+控制节点尚未批准本 agent 时只记录签到时间。`,
+	Args: cobra.NoArgs,
+	RunE: runAgentCheckIn,
+}
+
+var (
+	agentServer string
+	agentToken  string
+	agentName   string
+	agentForce  bool
+)
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+	agentCmd.AddCommand(agentEnrollCmd)
+	agentCmd.AddCommand(agentCheckInCmd)
+
+	agentEnrollCmd.Flags().StringVar(&agentServer, "server", "", "控制节点守护进程地址，例如 https://controller.internal:8443")
+	agentEnrollCmd.Flags().StringVar(&agentToken, "token", "", "控制节点 autocert ca join-token 生成的加入令牌")
+	agentEnrollCmd.Flags().StringVar(&agentName, "name", "", "agent 名称（默认使用主机名）")
+	agentEnrollCmd.Flags().BoolVar(&agentForce, "force", false, "已注册时重新注册")
+	agentEnrollCmd.MarkFlagRequired("server")
+	agentEnrollCmd.MarkFlagRequired("token")
+}
+
+func runAgentEnroll(cmd *cobra.Command, args []string) error {
+	existing, err := agent.Load()
+	switch {
+	case err == nil && !agentForce:
+		return fmt.Errorf("本机已作为 %s 注册到 %s（%s），重新注册请使用 --force", existing.Name, existing.Server, clock.Format(existing.EnrolledAt))
+	case err != nil && !errors.Is(err, os.ErrNotExist) && !agentForce:
+		return err
+	}
+
+	name := agentName
+	if name == "" {
+		if name, err = os.Hostname(); err != nil {
+			return fmt.Errorf("读取主机名失败，请使用 --name 指定: %w", err)
+		}
+	}
+
+	identity, err := agent.Enroll(agentServer, agentToken, name)
+	if err != nil {
+		return err
+	}
+	console.Success("已注册到 %s", identity.Server)
+	fmt.Printf("  名称:   %s\n", identity.Name)
+	fmt.Printf("  序列号: %s\n", identity.Serial)
+	fmt.Printf("  到期:   %s\n", clock.Format(identity.NotAfter))
+	fmt.Printf("  目录:   %s\n", agent.Dir())
+	return nil
+}
+
+func runAgentCheckIn(cmd *cobra.Command, args []string) error {
+	identity, err := agent.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("本机尚未注册，请先执行 autocert agent enroll")
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := identity.CheckIn()
+	if err != nil {
+		return err
+	}
+	if status == agent.StatusPending {
+		console.Warn("已签到，%s 尚未批准本 agent（在控制节点执行 autocert agents approve %s）", identity.Server, identity.Name)
+		return nil
+	}
+	console.Success("已向 %s 签到", identity.Server)
+	return nil
+}
+
+// agentCheckIn 本机已注册为 agent 时向控制节点签到，失败只输出警告
+func agentCheckIn() {
+	identity, err := agent.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		_, err = identity.CheckIn()
+	}
+	if err != nil {
+		console.Warn("向控制节点签到失败: %v", err)
+	}
+}
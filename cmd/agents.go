@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"autocert/internal/agent"
+	"autocert/internal/ca"
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"autocert/internal/logger"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var agentsCmd = &cobra.Command{
+	Use:   "agents",
+	Short: "控制节点管理已注册的 agent",
+	Long: `在控制节点上查看和管理 agent 清单。agent 使用加入令牌注册（autocert agent enroll）后进入清单，
+之后每次签到更新最近签到时间和部署的证书版本（名称、域名、序列号、到期时间）。
+
+使用限定名称的加入令牌（ca join-token --name）注册的 agent 直接批准；未限定名称时为待批准，
+批准前签到只记录时间，不记录证书。超过 daemon.agents.stale_after（默认 48h）没有签到的 agent
+标记为失联，并在 status 和 check --nagios 中报告。
+
+清单保存在状态存储中：配置了 storage.backend 时为持久化后端，否则为配置目录。
+
+子命令:
+  list      列出 agent
+  approve   批准待批准的 agent
+  remove    移除 agent 并吊销其客户端证书
+
+示例:
+  autocert agents list
+  autocert agents list --certs
+  autocert agents approve web-01
+  autocert agents remove web-01`,
+}
+
+var agentsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出 agent",
+	Args:  cobra.NoArgs,
+	RunE:  runAgentsList,
+}
+
+var agentsApproveCmd = &cobra.Command{
+	Use:   "approve <名称>",
+	Short: "批准待批准的 agent",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAgentsApprove,
+}
+
+var agentsRemoveCmd = &cobra.Command{
+	Use:   "remove <名称>",
+	Short: "移除 agent 并吊销其客户端证书",
+	Long: `从清单中移除 agent，并吊销其客户端证书（原因 cessationOfOperation），
+守护进程立即拒绝该证书的连接。之后该主机需要新的加入令牌才能重新注册。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAgentsRemove,
+}
+
+var agentsListCerts bool
+
+func init() {
+	rootCmd.AddCommand(agentsCmd)
+	agentsCmd.AddCommand(agentsListCmd)
+	agentsCmd.AddCommand(agentsApproveCmd)
+	agentsCmd.AddCommand(agentsRemoveCmd)
+
+	agentsListCmd.Flags().BoolVar(&agentsListCerts, "certs", false, "同时列出每个 agent 部署的证书版本")
+}
+
+func runAgentsList(cmd *cobra.Command, args []string) error {
+	inventory, err := agent.OpenInventory()
+	if err != nil {
+		return err
+	}
+	records, err := inventory.List()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("没有已注册的 agent")
+		return nil
+	}
+
+	now := clock.Now()
+	staleAfter := agent.StaleAfter()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "名称\t状态\t注册时间\t最近签到\t证书\t地址")
+	fmt.Fprintln(w, "----\t----\t--------\t--------\t----\t----")
+	for _, record := range records {
+		status := "已批准"
+		if record.Status == agent.StatusPending {
+			status = "待批准"
+		}
+		if record.Stale(now, staleAfter) {
+			status += "，失联"
+		}
+		lastCheckIn := "从未签到"
+		if record.LastCheckIn != nil {
+			lastCheckIn = clock.Format(*record.LastCheckIn)
+		}
+		address := record.Address
+		if address == "" {
+			address = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", record.Name, status, clock.Format(record.EnrolledAt),
+			lastCheckIn, len(record.Certificates), address)
+	}
+	w.Flush()
+
+	if agentsListCerts {
+		for _, record := range records {
+			if len(record.Certificates) == 0 {
+				continue
+			}
+			fmt.Printf("\n%s:\n", record.Name)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, deployed := range record.Certificates {
+				fmt.Fprintf(w, "  %s\t%s\t%s\t到期 %s\n", deployed.Name, strings.Join(deployed.Domains, ","),
+					deployed.Serial, clock.Date(deployed.NotAfter))
+			}
+			w.Flush()
+		}
+	}
+	return nil
+}
+
+func runAgentsApprove(cmd *cobra.Command, args []string) error {
+	inventory, err := agent.OpenInventory()
+	if err != nil {
+		return err
+	}
+	record, err := inventory.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if record.Status == agent.StatusApproved {
+		console.Warn("agent %s 已批准", record.Name)
+		return nil
+	}
+
+	record.Status = agent.StatusApproved
+	if err := inventory.Put(record); err != nil {
+		return err
+	}
+	console.Success("已批准 agent %s，下次签到时记录其部署的证书", record.Name)
+	return nil
+}
+
+func runAgentsRemove(cmd *cobra.Command, args []string) error {
+	inventory, err := agent.OpenInventory()
+	if err != nil {
+		return err
+	}
+	record, err := inventory.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	authority, err := ca.Open()
+	if err != nil {
+		return err
+	}
+	if _, err := authority.Revoke(record.Serial, "cessationOfOperation"); err != nil {
+		console.Warn("吊销 agent %s 的客户端证书失败: %v", record.Name, err)
+	}
+	if err := inventory.Delete(record.Name); err != nil {
+		return err
+	}
+	console.Success("已移除 agent %s", record.Name)
+	return nil
+}
+
+// staleAgentNames 控制节点上失联的 agent 名称；不是控制节点或读取失败时返回空
+func staleAgentNames() []string {
+	stale, err := agent.Stale(clock.Now())
+	if err != nil {
+		logger.Warn("读取 agent 清单失败", "error", err)
+		return nil
+	}
+	var names []string
+	for _, record := range stale {
+		names = append(names, record.Name)
+	}
+	return names
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"autocert/internal/approval"
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var approvalCmd = &cobra.Command{
+	Use:   "approval",
+	Short: "管理破坏性操作的审批请求",
+	Long: `配置 approval.enabled 后，删除证书（delete）、停用其他 ACME 客户端（install --takeover）、
+清理证书（gc）和吊销本地 CA 签发的证书（ca revoke）不会直接执行，而是生成审批请求。
+另一位操作员使用自己的审批令牌批准后，申请人再次执行原命令并加上 --approval-id 才会真正执行。
+每个请求只批准一个确定的对象，操作成功后作废；操作失败时可以使用同一个请求重试。
+
+子命令:
+  list      列出审批请求
+  approve   批准请求
+  reject    拒绝请求`,
+}
+
+var approvalListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出审批请求",
+	RunE:  runApprovalList,
+}
+
+var approvalApproveCmd = &cobra.Command{
+	Use:   "approve <请求ID>",
+	Short: "批准请求",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reviewApproval(args[0], true)
+	},
+}
+
+var approvalRejectCmd = &cobra.Command{
+	Use:   "reject <请求ID>",
+	Short: "拒绝请求",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reviewApproval(args[0], false)
+	},
+}
+
+var (
+	approver      string
+	approverToken string
+)
+
+func init() {
+	rootCmd.AddCommand(approvalCmd)
+	approvalCmd.AddCommand(approvalListCmd)
+	approvalCmd.AddCommand(approvalApproveCmd)
+	approvalCmd.AddCommand(approvalRejectCmd)
+
+	for _, c := range []*cobra.Command{approvalApproveCmd, approvalRejectCmd} {
+		c.Flags().StringVar(&approver, "approver", "", "审批人名称（approval.approvers 中的 name）")
+		c.Flags().StringVar(&approverToken, "token", "", "审批令牌（也可通过 AUTOCERT_APPROVAL_TOKEN 环境变量提供）")
+		c.MarkFlagRequired("approver")
+	}
+}
+
+func runApprovalList(cmd *cobra.Command, args []string) error {
+	requests, err := approval.List()
+	if err != nil {
+		return fmt.Errorf("读取审批请求失败: %w", err)
+	}
+	if len(requests) == 0 {
+		fmt.Println("没有审批请求")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t操作\t对象\t状态\t申请人\t申请时间\t审批人")
+	for _, req := range requests {
+		status := req.Status
+		if status == approval.StatusPending && req.Expired() {
+			status = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", req.ID, req.Action, req.Target, status,
+			req.RequestedBy, clock.Format(req.RequestedAt), req.ReviewedBy)
+	}
+	return w.Flush()
+}
+
+func reviewApproval(id string, approve bool) error {
+	token := approverToken
+	if token == "" {
+		token = os.Getenv("AUTOCERT_APPROVAL_TOKEN")
+	}
+	if token == "" {
+		return fmt.Errorf("请通过 --token 或 AUTOCERT_APPROVAL_TOKEN 提供审批令牌")
+	}
+
+	req, err := approval.Review(id, approver, token, approve)
+	if err != nil {
+		return err
+	}
+
+	if approve {
+		console.Success("已批准 %s %s，申请人可使用 --approval-id %s 执行", req.Action, req.Target, req.ID)
+	} else {
+		console.Success("已拒绝 %s %s", req.Action, req.Target)
+	}
+	return nil
+}
+
+// requireApproval 破坏性操作的审批检查，返回 false 表示已提交审批、本次不执行。
+// 返回的请求在操作成功后交给 completeApproval 作废（不需要审批时为 nil）
+func requireApproval(action, target, requestID string) (*approval.Request, bool, error) {
+	req, err := approval.Gate(action, target, requestID)
+	if errors.Is(err, approval.ErrPending) {
+		console.Warn("%s %s 需要另一位操作员审批，已创建审批请求 %s（%s 前有效）", action, target, req.ID,
+			clock.FormatRelative(req.ExpiresAt))
+		fmt.Printf("审批: autocert approval approve %s --approver <审批人>\n", req.ID)
+		fmt.Printf("批准后执行原命令并加上 --approval-id %s\n", req.ID)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("审批校验失败: %w", err)
+	}
+	return req, true, nil
+}
+
+// completeApproval 操作成功后作废审批请求；保存失败时只警告，操作本身已经完成
+func completeApproval(req *approval.Request) {
+	if err := approval.MarkExecuted(req); err != nil {
+		console.Warn("标记审批请求 %s 为已执行失败: %v", req.ID, err)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"autocert/internal/assess"
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var assessCmd = &cobra.Command{
+	Use:   "assess",
+	Short: "评估 TLS 配置并给出评级",
+	Long: `连接服务器检查协议版本、TLS 1.2 密码套件、证书链完整性和 HSTS，
+给出 A+ 到 F 的评级（T 表示证书不受信任）和修复建议。
+
+指定 --min-grade 时，评级低于该值退出码为 1，可用于部署流水线。
+
+示例:
+  autocert assess --domain example.com
+  autocert assess --domain example.com --host 127.0.0.1
+  autocert assess --domain example.com --min-grade A --json`,
+	RunE: runAssess,
+}
+
+var (
+	assessDomain   string
+	assessHost     string
+	assessPort     int
+	assessTimeout  time.Duration
+	assessJSON     bool
+	assessMinGrade string
+)
+
+func init() {
+	rootCmd.AddCommand(assessCmd)
+
+	assessCmd.Flags().StringVarP(&assessDomain, "domain", "d", "", "要评估的域名（SNI）")
+	assessCmd.Flags().StringVar(&assessHost, "host", "", "连接的主机地址（默认为域名）")
+	assessCmd.Flags().IntVar(&assessPort, "port", 443, "TLS 端口")
+	assessCmd.Flags().DurationVar(&assessTimeout, "timeout", 5*time.Second, "单次连接超时")
+	assessCmd.Flags().BoolVar(&assessJSON, "json", false, "以 JSON 格式输出")
+	assessCmd.Flags().StringVar(&assessMinGrade, "min-grade", "", "要求的最低评级，例如 A、B")
+	assessCmd.MarkFlagRequired("domain")
+}
+
+func runAssess(cmd *cobra.Command, args []string) error {
+	if assessMinGrade != "" && !assess.ValidGrade(assessMinGrade) {
+		return fmt.Errorf("无效的评级: %s", assessMinGrade)
+	}
+
+	report, err := assess.Run(assess.Options{
+		Domain:  assessDomain,
+		Host:    assessHost,
+		Port:    assessPort,
+		Timeout: assessTimeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	if assessJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printAssessReport(report)
+	}
+
+	if assessMinGrade != "" && assess.Compare(report.Grade, assessMinGrade) > 0 {
+		return fmt.Errorf("评级 %s 低于要求的 %s", report.Grade, strings.ToUpper(assessMinGrade))
+	}
+	return nil
+}
+
+// printAssessReport 输出评估报告
+func printAssessReport(report *assess.Report) {
+	fmt.Printf("%s（%s）\n\n", report.Domain, report.Address)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, protocol := range report.Protocols {
+		status := "否"
+		if protocol.Supported {
+			status = "是"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", protocol.Name, status)
+	}
+	fmt.Fprintf(w, "协商结果\t%s\n", report.Negotiated)
+	fmt.Fprintf(w, "证书\t%s（%s，签发者 %s，%s 到期）\n", report.Chain.Subject, report.Chain.KeyType,
+		report.Chain.Issuer, clock.Date(report.Chain.NotAfter))
+	fmt.Fprintf(w, "证书链\t%d 张，受信任: %v\n", report.Chain.Length, report.Chain.Trusted)
+	if report.HSTS.Enabled {
+		fmt.Fprintf(w, "HSTS\tmax-age=%d includeSubDomains=%v preload=%v\n",
+			report.HSTS.MaxAge, report.HSTS.IncludeSubDomains, report.HSTS.Preload)
+	} else {
+		fmt.Fprintf(w, "HSTS\t未启用\n")
+	}
+	w.Flush()
+
+	if len(report.Ciphers) > 0 {
+		fmt.Println("\nTLS 1.2 密码套件:")
+		for _, cipher := range report.Ciphers {
+			var notes []string
+			if cipher.Insecure {
+				notes = append(notes, "不安全")
+			}
+			if !cipher.ForwardSecrecy {
+				notes = append(notes, "无前向保密")
+			}
+			suffix := ""
+			if len(notes) > 0 {
+				suffix = "（" + strings.Join(notes, "，") + "）"
+			}
+			fmt.Printf("  %s%s\n", cipher.Name, suffix)
+		}
+	}
+
+	fmt.Println()
+	color := console.Green
+	switch {
+	case assess.Compare(report.Grade, "A") > 0 && assess.Compare(report.Grade, "B") <= 0:
+		color = console.Yellow
+	case assess.Compare(report.Grade, "B") > 0:
+		color = console.Red
+	}
+	fmt.Println("评级: " + console.Colorize(os.Stdout, color, report.Grade))
+
+	if len(report.Findings) > 0 {
+		fmt.Println("\n修复建议:")
+		for _, finding := range report.Findings {
+			fmt.Printf("  [%s] %s（最高 %s）\n", finding.Check, finding.Message, finding.Cap)
+			fmt.Printf("      %s\n", finding.Remediation)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/console"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var authzCmd = &cobra.Command{
+	Use:   "authz <授权 URL>",
+	Short: "查询 CA 上的授权，显示验证失败的原因和排查建议",
+	Long: `以 ACME 账户身份查询 CA 上的授权（CA 日志或错误信息中的 authz URL），
+授权无效时显示 CA 返回的问题详情、CA 验证时访问的 URL、实际连接的地址和域名解析结果，
+并给出排查建议（例如 CA 连接的地址不是本机时检查域名解析和 CDN 代理）。
+
+授权无效时退出码为 1。
+
+示例:
+  autocert authz https://acme-v02.api.letsencrypt.org/acme/authz-v3/123456789`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuthz,
+}
+
+func init() {
+	rootCmd.AddCommand(authzCmd)
+}
+
+func runAuthz(cmd *cobra.Command, args []string) error {
+	authz, err := cert.FetchAuthorization(args[0])
+	if authz == nil {
+		return err
+	}
+
+	domain := authz.Identifier.Value
+	if authz.Wildcard {
+		domain = "*." + domain
+	}
+	fmt.Printf("域名: %s\n", domain)
+	fmt.Printf("状态: %s\n", authz.Status)
+	for _, challenge := range authz.Challenges {
+		fmt.Printf("  %-12s %s\n", challenge.Type, challenge.Status)
+	}
+
+	if err != nil {
+		return fmt.Errorf("域名 %s 授权验证失败: %w", domain, err)
+	}
+	console.Success("授权有效")
+	return nil
+}
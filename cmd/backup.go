@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"autocert/internal/backup"
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/events"
 	"autocert/internal/logger"
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -16,7 +21,25 @@ var exportCmd = &cobra.Command{
 示例:
   autocert export --output certs.tar.gz
   autocert export --output certs.tar.gz --domain example.com
-  autocert export --output certs.zip --format zip`,
+  autocert export --output certs.zip --format zip
+  autocert export --compression zstd --level 10
+  autocert export --output certs.tar --compression none
+  autocert export --output nightly-0102.tar.gz --base nightly-0101.tar.gz
+  autocert export -o - | ssh host autocert import -
+
+--compression 选择 tar 归档的压缩方式：gzip（默认）、zstd（需要安装 zstd 命令，大量域名时
+导出和导入明显更快）或 none（目标存储自带去重/压缩时使用）；--level 指定压缩级别
+（gzip 1-9，zstd 1-19，0 为默认级别）。未指定 --output 时文件扩展名随压缩方式变化
+（.tar.gz、.tar.zst、.tar）。zip 格式支持 gzip（deflate）和 none。
+
+--base 指定上一次导出的归档进行差异导出：按文件哈希比较，只写入有变化或新增的文件，并在元数据中
+记录基准归档的文件名和 SHA-256，适合每晚导出到异地存储时节省带宽。差异备份也可以作为下一次
+差异导出的基准，导入时需要整条链上的归档都在同一目录中。
+
+--output - 将归档写入标准输出，可直接通过管道传到另一台主机导入，不在磁盘上留下临时文件；
+此时日志和进度输出到标准错误。
+
+配置 backup.signing_key（Ed25519 私钥）后同时生成签名文件 <输出文件>.sig（输出到标准输出时不生成）。`,
 	RunE: runExport,
 }
 
@@ -27,15 +50,34 @@ var importCmd = &cobra.Command{
 
 示例:
   autocert import certs.tar.gz
-  autocert import certs.zip --restore-schedule`,
+  autocert import certs.zip --restore-schedule
+  autocert import certs.tar.gz --require-signature
+  ssh host autocert export -o - | autocert import -
+
+归档格式和压缩方式按内容识别（tar.gz、tar.zst、tar 或 zip），不依赖扩展名；
+文件名为 - 时从标准输入读取归档，归档只保存在内存中。
+
+导入差异备份时在同一目录（从标准输入导入时为当前目录）中查找基准归档，校验 SHA-256 和签名后
+按链还原完整状态，每个文件从包含其最新内容的归档中解压。
+
+导出时会记录已安装的定时任务（systemd 单元、cron 任务行、Windows 任务 XML 或服务），
+--restore-schedule 导入时按当前平台的调度器和程序路径重新安装，同名任务已存在时跳过。
+
+备份带有签名文件（<文件>.sig）时使用 backup.trusted_keys 中的公钥校验，校验失败时不导入；
+--require-signature 要求必须有可信签名，用于防止被篡改的备份写入生产主机；
+从标准输入导入时没有签名文件，要求签名时拒绝导入。`,
 	RunE: runImport,
 }
 
 var (
 	outputFile      string
 	exportFormat    string
+	exportCompress  string
+	exportLevel     int
+	exportBase      string
 	exportDomain    string
 	restoreSchedule bool
+	requireSig      bool
 )
 
 func init() {
@@ -43,15 +85,29 @@ func init() {
 	rootCmd.AddCommand(importCmd)
 
 	// export 命令参数
-	exportCmd.Flags().StringVarP(&outputFile, "output", "o", "autocert-backup.tar.gz", "输出文件路径")
-	exportCmd.Flags().StringVar(&exportFormat, "format", "tar.gz", "导出格式 (tar.gz, zip)")
+	exportCmd.Flags().StringVarP(&outputFile, "output", "o", "autocert-backup.tar.gz", "输出文件路径，- 表示标准输出")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "tar", "导出格式 (tar, zip)，tar.gz 等同于 tar")
+	exportCmd.Flags().StringVar(&exportCompress, "compression", backup.CompressionGzip, "压缩方式 (gzip, zstd, none)")
+	exportCmd.Flags().IntVar(&exportLevel, "level", 0, "压缩级别（gzip 1-9，zstd 1-19），0 为默认级别")
+	exportCmd.Flags().StringVar(&exportBase, "base", "", "差异导出的基准归档（上一次导出的文件），只导出有变化的文件")
 	exportCmd.Flags().StringVar(&exportDomain, "domain", "", "只导出指定域名的证书（可选）")
 
 	// import 命令参数
 	importCmd.Flags().BoolVar(&restoreSchedule, "restore-schedule", true, "是否恢复定时任务")
+	importCmd.Flags().BoolVar(&requireSig, "require-signature", false, "要求备份带有可信签名（backup.trusted_keys）")
 }
 
 func runExport(cmd *cobra.Command, args []string) error {
+	// 未指定输出文件时扩展名与格式和压缩方式一致
+	if !cmd.Flags().Changed("output") {
+		outputFile = "autocert-backup" + backup.ArchiveExt(exportFormat, exportCompress)
+	}
+
+	toStdout := backup.IsStdio(outputFile)
+	if toStdout && console.IsTerminal(os.Stdout) {
+		return fmt.Errorf("标准输出是终端，请重定向到文件或管道，例如 autocert export -o - | ssh host autocert import -")
+	}
+
 	logger.Info("开始导出证书和配置", "output", outputFile)
 
 	// 创建备份管理器
@@ -59,9 +115,13 @@ func runExport(cmd *cobra.Command, args []string) error {
 
 	// 设置导出选项
 	options := &backup.ExportOptions{
-		OutputFile: outputFile,
-		Format:     exportFormat,
-		Domain:     exportDomain,
+		OutputFile:      outputFile,
+		AutocertVersion: version,
+		Format:          exportFormat,
+		Compression:     exportCompress,
+		Level:           exportLevel,
+		Base:            exportBase,
+		Domain:          exportDomain,
 	}
 
 	// 执行导出
@@ -71,17 +131,28 @@ func runExport(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Info("导出完成", "output", outputFile)
-	fmt.Printf("✓ 证书和配置已导出到: %s\n", outputFile)
+	if toStdout {
+		// 标准输出是归档内容，不输出提示
+		return nil
+	}
+	console.Success("证书和配置已导出到: %s", outputFile)
+	if config.GetBackupConfig().SigningKey != "" {
+		fmt.Printf("  签名文件: %s\n", backup.SignaturePath(outputFile))
+	}
 
 	return nil
 }
 
 func runImport(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
-		return fmt.Errorf("请指定要导入的文件")
+		return fmt.Errorf("请指定要导入的文件（- 表示标准输入）")
 	}
 
 	inputFile := args[0]
+	source := inputFile
+	if backup.IsStdio(inputFile) {
+		source = "标准输入"
+	}
 	logger.Info("开始导入证书和配置", "input", inputFile)
 
 	// 创建备份管理器
@@ -89,8 +160,10 @@ func runImport(cmd *cobra.Command, args []string) error {
 
 	// 设置导入选项
 	options := &backup.ImportOptions{
-		InputFile:       inputFile,
-		RestoreSchedule: restoreSchedule,
+		InputFile:        inputFile,
+		RestoreSchedule:  restoreSchedule,
+		RequireSignature: requireSig,
+		ConfigFile:       configFileUsed(),
 	}
 
 	// 执行导入
@@ -99,8 +172,18 @@ func runImport(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("导入失败: %w", err)
 	}
 
+	// 解压时按默认权限创建目录，导入后按 storage.dir_mode 重新设置
+	if _, err := cert.CheckPermissions(config.GetCertDir(), true); err != nil {
+		logger.Warn("修正证书目录权限失败", "error", err)
+	}
+
 	logger.Info("导入完成", "input", inputFile)
-	fmt.Printf("✓ 证书和配置已从 %s 导入\n", inputFile)
+	events.Emit(events.Event{
+		Type:    events.ConfigChanged,
+		Message: "已从备份导入证书和配置",
+		Fields:  map[string]string{"source": source},
+	})
+	console.Success("证书和配置已从 %s 导入", source)
 
 	return nil
 }
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/keypool"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "测试本机生成证书私钥的速度",
+	Long: `测试本机生成证书私钥的耗时（默认使用 acme.key_type 和 acme.key_size），
+先逐个生成，再按 --parallel 并行生成，用于评估是否需要开启私钥预生成（acme.key_pool）。
+
+示例:
+  autocert bench
+  autocert bench --key-size 4096 --count 8
+  autocert bench --key-type ecdsa`,
+	Args: cobra.NoArgs,
+	RunE: runBench,
+}
+
+var (
+	benchKeyType  string
+	benchKeySize  int
+	benchCount    int
+	benchParallel int
+)
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().StringVar(&benchKeyType, "key-type", "", "密钥类型 rsa 或 ecdsa（默认使用 acme.key_type）")
+	benchCmd.Flags().IntVar(&benchKeySize, "key-size", 0, "RSA 密钥长度（默认使用 acme.key_size）")
+	benchCmd.Flags().IntVar(&benchCount, "count", 4, "生成的私钥数量")
+	benchCmd.Flags().IntVar(&benchParallel, "parallel", 0, "并行生成的数量（默认为 CPU 核数）")
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	acmeConfig := config.GetACMEConfig()
+	keyType, keySize := acmeConfig.KeyType, acmeConfig.KeySize
+	if benchKeyType != "" {
+		keyType, keySize = benchKeyType, 0
+	}
+	if benchKeySize > 0 {
+		keySize = benchKeySize
+	}
+	spec, err := keypool.NewSpec(keyType, keySize)
+	if err != nil {
+		return err
+	}
+	if benchCount <= 0 {
+		return fmt.Errorf("--count 必须大于 0")
+	}
+	parallel := benchParallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	fmt.Printf("生成 %d 个 %s 私钥（CPU 核数 %d）\n", benchCount, spec, runtime.NumCPU())
+	for _, workers := range []int{1, parallel} {
+		result, err := keypool.Bench(spec, benchCount, workers)
+		if err != nil {
+			return err
+		}
+		var slowest time.Duration
+		for _, d := range result.Durations {
+			slowest = max(slowest, d)
+		}
+		fmt.Printf("  并行 %-3d 总耗时 %-10s 平均 %-10s 最慢 %s\n", result.Workers,
+			result.Total.Round(time.Millisecond), result.Average().Round(time.Millisecond), slowest.Round(time.Millisecond))
+		if parallel == 1 {
+			break
+		}
+	}
+	return nil
+}
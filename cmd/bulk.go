@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"autocert/internal/bulk"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/domainlist"
+	"autocert/internal/keypool"
+	"autocert/internal/logger"
+	"autocert/internal/renewal"
+	"autocert/internal/report"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var bulkCmd = &cobra.Command{
+	Use:   "bulk",
+	Short: "批量签发证书",
+	Long: `从文件批量签发证书，按 Let's Encrypt 速率限制逐个处理，并持久化进度。
+
+域名文件每行一张证书，多个域名用逗号分隔（SAN 证书），# 开始为注释。
+每行可以用 challenge=webroot|standalone|dns 和 webroot=目录 为该证书单独指定验证方式，
+路径包含空格时用双引号括起来。文件可以是 UTF-8 或带 BOM 的 UTF-16，国际化域名自动转换为 Punycode。
+中断（Ctrl+C）后重新执行相同命令即可从上次进度继续。
+
+示例:
+  autocert bulk --domains-file domains.txt --email admin@example.com
+  autocert bulk --domains-file domains.txt --webroot /var/www/html --interval 60s
+  autocert bulk --domains-file domains.txt --retry-failed
+
+域名文件示例:
+  # 主站（SAN 证书）
+  example.com, www.example.com
+  *.example.com               challenge=dns
+  shop.example.com            webroot=/srv/shop/public
+  例子.中国                    challenge=standalone`,
+	RunE: runBulk,
+}
+
+var (
+	bulkFile        string
+	bulkEmail       string
+	bulkWebroot     string
+	bulkState       string
+	bulkInterval    time.Duration
+	bulkWeeklyLimit int
+	bulkRetryFailed bool
+)
+
+func init() {
+	rootCmd.AddCommand(bulkCmd)
+
+	bulkCmd.Flags().StringVar(&bulkFile, "domains-file", "", "域名文件路径 (必需，- 表示标准输入)")
+	bulkCmd.Flags().StringVar(&bulkFile, "file", "", "同 --domains-file")
+	bulkCmd.Flags().StringVarP(&bulkEmail, "email", "e", "", "Let's Encrypt 账户邮箱（默认使用配置 acme.email）")
+	bulkCmd.Flags().StringVarP(&bulkWebroot, "webroot", "w", "", "Webroot 模式的网站根目录路径")
+	bulkCmd.Flags().StringVar(&bulkState, "state", "", "进度文件路径（默认位于配置目录 bulk/ 下）")
+	bulkCmd.Flags().DurationVar(&bulkInterval, "interval", bulk.DefaultOrderInterval, "两次新订单之间的最小间隔")
+	bulkCmd.Flags().IntVar(&bulkWeeklyLimit, "weekly-limit", bulk.DefaultWeeklyLimit, "每个注册域名每周最多签发的证书数")
+	bulkCmd.Flags().BoolVar(&bulkRetryFailed, "retry-failed", false, "重新尝试已失败的证书")
+
+	bulkCmd.MarkFlagsOneRequired("domains-file", "file")
+	bulkCmd.MarkFlagsMutuallyExclusive("domains-file", "file")
+}
+
+func runBulk(cmd *cobra.Command, args []string) error {
+	statePath := bulkState
+	if statePath == "" {
+		statePath = bulk.DefaultStatePath(config.GetConfigDir(), bulkFile)
+	}
+
+	logger.Info("开始批量签发", "file", bulkFile, "state", statePath, "interval", bulkInterval)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 签发受新订单间隔限制，期间并行预生成私钥，签发时不再等待私钥生成
+	acmeConfig := config.GetACMEConfig()
+	if spec, err := keypool.NewSpec(acmeConfig.KeyType, acmeConfig.KeySize); err == nil {
+		workers := acmeConfig.KeyPool.Workers
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		go keypool.Fill(ctx, spec, workers, workers)
+	}
+
+	result, err := bulk.Run(ctx, bulk.Options{
+		File:          bulkFile,
+		StatePath:     statePath,
+		OrderInterval: bulkInterval,
+		WeeklyLimit:   bulkWeeklyLimit,
+		RetryFailed:   bulkRetryFailed,
+		Issue: func(entry *bulk.Entry) error {
+			fmt.Printf("→ 签发 %v\n", entry.Domains)
+			line := domainlist.Entry{Challenge: entry.Challenge, Webroot: entry.Webroot}
+			options := renewal.IssueOptions{Email: bulkEmail, Webroot: bulkWebroot}
+			if challengeType, ok := entryChallenge(line); ok {
+				options.Challenge = challengeType
+				if entry.Webroot != "" {
+					options.Webroot = entry.Webroot
+				}
+			}
+			return renewal.Issue(entry.Domains, options)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("批量签发失败: %w", err)
+	}
+
+	fmt.Printf("批量签发汇总: 完成 %d, 失败 %d, 推迟 %d, 未处理 %d\n",
+		result.Done, result.Failed, result.Deferred, result.Pending)
+	if result.Deferred > 0 {
+		fmt.Printf("部分证书因每周限额推迟，最早可于 %s 后重新执行\n", clock.FormatRelative(result.NextAvailable))
+	}
+	if result.Pending > 0 {
+		fmt.Println("任务已中断，重新执行相同命令即可继续")
+	}
+	if result.Retryable > 0 {
+		fmt.Printf("%d 个失败的证书未达到最大尝试次数，重新执行相同命令会重试\n", result.Retryable)
+	}
+	fmt.Printf("进度文件: %s\n", result.StatePath)
+
+	if result.Failed > 0 {
+		exitCode = report.ExitSomeFailed
+	}
+	return nil
+}
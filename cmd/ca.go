@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"autocert/internal/approval"
+	"autocert/internal/ca"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/pkcs12"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "管理本地 CA（mTLS 客户端证书）",
+	Long: `本地 CA 用于签发内部 mTLS 使用的客户端证书，服务端使用 CA 根证书验证客户端、使用 CRL 拒绝已吊销的证书。
+
+子命令:
+  init           初始化本地 CA
+  issue-client   签发客户端证书（输出 PKCS#12 或 PEM）
+  list           列出已签发的证书
+  revoke         吊销证书并重新生成 CRL
+  crl            重新生成 CRL（nextUpdate 到期前需定期执行）
+  join-token     生成 agent 注册使用的一次性加入令牌
+
+示例:
+  autocert ca init --cn "Corp Internal CA"
+  autocert ca issue-client --cn user@corp --out user.p12
+  autocert ca revoke --serial 3A1F... --reason keyCompromise
+  autocert ca crl --out /etc/nginx/client-crl.pem
+  autocert ca join-token --name web1 --expires 1h`,
+}
+
+var caInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "初始化本地 CA",
+	RunE:  runCAInit,
+}
+
+var caIssueClientCmd = &cobra.Command{
+	Use:   "issue-client",
+	Short: "签发客户端证书",
+	Long: `签发用于 TLS 客户端认证的证书（密钥用途 digitalSignature，扩展密钥用途 clientAuth）。
+
+--out 以 .p12 或 .pfx 结尾时输出 PKCS#12 文件（包含私钥、证书和 CA 根证书），
+未指定 --password 时生成随机密码并输出；其他扩展名输出 PEM 文件（私钥、证书和 CA 根证书）。`,
+	RunE: runCAIssueClient,
+}
+
+var caListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出已签发的证书",
+	RunE:  runCAList,
+}
+
+var caRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "吊销证书",
+	Long: `吊销本地 CA 签发的证书并重新生成 CRL。开启 approval.enabled 时需要另一位操作员审批，
+批准后加上 --approval-id 再次执行。`,
+	RunE: runCARevoke,
+}
+
+var caCRLCmd = &cobra.Command{
+	Use:   "crl",
+	Short: "重新生成 CRL",
+	RunE:  runCACRL,
+}
+
+var caJoinTokenCmd = &cobra.Command{
+	Use:   "join-token",
+	Short: "生成 agent 注册使用的一次性加入令牌",
+	Long: `生成一次性加入令牌，卫星节点执行 autocert agent enroll 时使用它向本机守护进程注册，
+获得本地 CA 签发的客户端证书，之后与守护进程的通信使用 mTLS。
+
+令牌包含本地 CA 根证书的指纹，agent 首次连接时据此确认守护进程，无需预先分发根证书。
+令牌只能使用一次，到期后失效；指定 --name 时只能用于注册该名称的 agent。
+守护进程需要配置 daemon.tls.enabled（client_auth 为 require 或 optional）。`,
+	Args: cobra.NoArgs,
+	RunE: runCAJoinToken,
+}
+
+var (
+	caJoinName    string
+	caJoinExpires string
+)
+
+var (
+	caCommonName string
+	caDays       int
+	caInitDays   int
+	caOut        string
+	caEmails     []string
+	caKeyType    string
+	caPassword   string
+	caSerial     string
+	caReason     string
+	caApprovalID string
+)
+
+func init() {
+	rootCmd.AddCommand(caCmd)
+	caCmd.AddCommand(caInitCmd)
+	caCmd.AddCommand(caIssueClientCmd)
+	caCmd.AddCommand(caListCmd)
+	caCmd.AddCommand(caRevokeCmd)
+	caCmd.AddCommand(caCRLCmd)
+	caCmd.AddCommand(caJoinTokenCmd)
+
+	caInitCmd.Flags().StringVar(&caCommonName, "cn", "", "根证书名称（默认使用配置 ca.common_name）")
+	caInitCmd.Flags().IntVar(&caInitDays, "days", 3650, "根证书有效天数")
+
+	caIssueClientCmd.Flags().StringVar(&caCommonName, "cn", "", "客户端名称，例如 user@corp 或服务名")
+	caIssueClientCmd.Flags().StringVarP(&caOut, "out", "o", "", "输出文件（.p12/.pfx 或 .pem）")
+	caIssueClientCmd.Flags().StringSliceVar(&caEmails, "email", nil, "写入证书的邮箱（可多次指定）")
+	caIssueClientCmd.Flags().IntVar(&caDays, "days", 0, "有效天数（默认使用配置 ca.client_days）")
+	caIssueClientCmd.Flags().StringVar(&caKeyType, "key-type", "ecdsa", "密钥类型: ecdsa, rsa")
+	caIssueClientCmd.Flags().StringVar(&caPassword, "password", "", "PKCS#12 密码（默认随机生成）")
+	caIssueClientCmd.MarkFlagRequired("cn")
+	caIssueClientCmd.MarkFlagRequired("out")
+
+	caRevokeCmd.Flags().StringVar(&caSerial, "serial", "", "证书序列号（十六进制）")
+	caRevokeCmd.Flags().StringVar(&caReason, "reason", "unspecified", "吊销原因: "+strings.Join(ca.ReasonNames(), ", "))
+	caRevokeCmd.Flags().StringVar(&caApprovalID, "approval-id", "", "已批准的审批请求 ID（开启审批时需要）")
+	caRevokeCmd.MarkFlagRequired("serial")
+
+	caCRLCmd.Flags().StringVarP(&caOut, "out", "o", "", "同时将 CRL 复制到该文件（例如 Web 服务器使用的路径）")
+
+	caJoinTokenCmd.Flags().StringVar(&caJoinName, "name", "", "只能用于注册该名称的 agent（默认不限制）")
+	caJoinTokenCmd.Flags().StringVar(&caJoinExpires, "expires", "1h", "有效期，例如 30m、24h、7d")
+}
+
+func runCAInit(cmd *cobra.Command, args []string) error {
+	name := caCommonName
+	if name == "" {
+		name = config.GetCAConfig().CommonName
+	}
+
+	authority, err := ca.Init(name, time.Duration(caInitDays)*24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	console.Success("本地 CA 已初始化: %s", name)
+	fmt.Printf("  根证书: %s\n", authority.CertPath())
+	fmt.Printf("  CRL:    %s\n", authority.CRLPath())
+	return nil
+}
+
+func runCAIssueClient(cmd *cobra.Command, args []string) error {
+	authority, err := ca.Open()
+	if err != nil {
+		return err
+	}
+
+	issued, err := authority.IssueClient(ca.ClientRequest{
+		CommonName: caCommonName,
+		Emails:     caEmails,
+		Validity:   time.Duration(caDays) * 24 * time.Hour,
+		KeyType:    caKeyType,
+	})
+	if err != nil {
+		return err
+	}
+
+	ext := strings.ToLower(filepath.Ext(caOut))
+	password, generated := caPassword, false
+	var data []byte
+	if ext == ".p12" || ext == ".pfx" {
+		if password == "" {
+			if password, err = randomPassword(); err != nil {
+				return err
+			}
+			generated = true
+		}
+		data, err = pkcs12.Encode(issued.Key, issued.Cert, []*x509.Certificate{authority.Cert}, password, caCommonName)
+		if err != nil {
+			return fmt.Errorf("生成 PKCS#12 文件失败: %w", err)
+		}
+	} else {
+		keyDER, err := x509.MarshalPKCS8PrivateKey(issued.Key)
+		if err != nil {
+			return err
+		}
+		data = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issued.Cert.Raw})...)
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: authority.Cert.Raw})...)
+	}
+
+	if err := os.WriteFile(caOut, data, 0600); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", caOut, err)
+	}
+
+	console.Success("已签发客户端证书: %s", caCommonName)
+	fmt.Printf("  序列号: %s\n", issued.Record.Serial)
+	fmt.Printf("  有效期: %s\n", clock.Date(issued.Record.NotAfter))
+	fmt.Printf("  文件:   %s\n", caOut)
+	if generated {
+		fmt.Printf("  密码:   %s\n", password)
+	}
+	return nil
+}
+
+func runCAList(cmd *cobra.Command, args []string) error {
+	authority, err := ca.Open()
+	if err != nil {
+		return err
+	}
+	records, err := authority.List()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("尚未签发证书")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "序列号\t类型\t名称\t到期时间\t状态")
+	fmt.Fprintln(w, "------\t----\t----\t--------\t----")
+	for _, record := range records {
+		status := "有效"
+		switch {
+		case record.Revoked():
+			status = "已吊销（" + record.Reason + "）"
+		case time.Now().After(record.NotAfter):
+			status = "已过期"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", record.Serial, record.Type, record.CommonName,
+			clock.Date(record.NotAfter), status)
+	}
+	return w.Flush()
+}
+
+func runCARevoke(cmd *cobra.Command, args []string) error {
+	authority, err := ca.Open()
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("%s reason=%s", strings.ToUpper(strings.TrimSpace(caSerial)), caReason)
+	approved, proceed, err := requireApproval(approval.ActionRevoke, target, caApprovalID)
+	if !proceed {
+		return err
+	}
+
+	record, err := authority.Revoke(caSerial, caReason)
+	if err != nil {
+		return err
+	}
+	completeApproval(approved)
+
+	console.Success("已吊销 %s（%s）", record.CommonName, record.Serial)
+	fmt.Printf("  CRL 已更新: %s\n", authority.CRLPath())
+	return nil
+}
+
+func runCACRL(cmd *cobra.Command, args []string) error {
+	authority, err := ca.Open()
+	if err != nil {
+		return err
+	}
+
+	data, err := authority.GenerateCRL()
+	if err != nil {
+		return err
+	}
+	console.Success("CRL 已更新: %s", authority.CRLPath())
+
+	if caOut != "" {
+		if err := os.WriteFile(caOut, data, 0644); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", caOut, err)
+		}
+		fmt.Printf("  已复制到: %s\n", caOut)
+	}
+	return nil
+}
+
+// randomPassword 随机生成 PKCS#12 密码
+func randomPassword() (string, error) {
+	buf := make([]byte, 15)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.New("生成随机密码失败")
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func runCAJoinToken(cmd *cobra.Command, args []string) error {
+	ttl, err := parseAge(caJoinExpires)
+	if err != nil {
+		return fmt.Errorf("--expires 无效: %w", err)
+	}
+	authority, err := ca.Open()
+	if err != nil {
+		return err
+	}
+	token, record, err := authority.CreateJoinToken(caJoinName, ttl)
+	if err != nil {
+		return err
+	}
+
+	console.Success("已生成加入令牌 %s，%s 前有效，只能使用一次", record.ID, clock.Format(record.ExpiresAt))
+	fmt.Println(token)
+	fmt.Println()
+	name := caJoinName
+	if name == "" {
+		name = "<名称>"
+	}
+	fmt.Printf("在 agent 上执行:\n  autocert agent enroll --server https://<本机地址>%s --token %s --name %s\n",
+		listenPort(config.GetDaemonConfig().Listen), token, name)
+	return nil
+}
+
+// listenPort 从监听地址中取出 :端口，用于拼接示例命令
+func listenPort(listen string) string {
+	if i := strings.LastIndex(listen, ":"); i >= 0 {
+		return listen[i:]
+	}
+	return ""
+}
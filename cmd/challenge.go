@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"autocert/internal/challenge"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var challengeResponderCmd = &cobra.Command{
+	Use:   "challenge-responder",
+	Short: "从共享存储响应 http-01 挑战（负载均衡后的多台主机）",
+	Long: `运行一个只响应 http-01 挑战的小型 HTTP 服务器：从共享存储（challenge.store：storage.backend、
+共享目录或 Redis）读取其他 autocert 节点发布的挑战 token 并响应 /.well-known/acme-challenge/<token>。
+
+负载均衡后的多台主机都运行该命令（或由 Web 服务器将挑战路径反向代理到它），签发节点开启
+challenge.shared 后，CA 的验证请求无论被分配到哪台主机都能通过 http-01 验证。
+/healthz 供负载均衡器做健康检查，其他路径返回 404。
+
+示例:
+  autocert challenge-responder
+  autocert challenge-responder --listen 127.0.0.1:8402`,
+	RunE: runChallengeResponder,
+}
+
+var responderListen string
+
+func init() {
+	rootCmd.AddCommand(challengeResponderCmd)
+
+	challengeResponderCmd.Flags().StringVar(&responderListen, "listen", "", "监听地址（覆盖配置 challenge.listen，默认 :80）")
+}
+
+func runChallengeResponder(cmd *cobra.Command, args []string) error {
+	store, err := challenge.Open()
+	if err != nil {
+		return err
+	}
+
+	listen := config.GetChallengeConfig().Listen
+	if responderListen != "" {
+		listen = responderListen
+	}
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           challenge.Responder(store),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	logger.Info("http-01 挑战响应服务已启动", "listen", listen, "backend", store.Name())
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("挑战响应服务失败: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("正在停止挑战响应服务")
+	shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdown); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"autocert/internal/report"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "监控检查证书有效期",
+	Long: `以监控插件格式检查证书剩余有效期，可直接接入 Nagios/Icinga 或 Zabbix。
+
+Nagios 模式输出标准状态行和性能数据（剩余天数），控制节点上有失联的 agent 时至少为 WARNING，退出码:
+  0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN
+
+Zabbix 模式:
+  --zabbix-discovery      输出低级发现（LLD）JSON，宏 {#CERTNAME} {#DOMAIN} {#SANS}
+  --zabbix-days <证书>    输出指定证书的剩余天数，用于监控项原型
+
+示例:
+  autocert check --nagios
+  autocert check --nagios --warning 21 --critical 7 --domain example.com
+  autocert check --zabbix-discovery
+  autocert check --zabbix-days example.com`,
+	RunE: runCheck,
+}
+
+var (
+	checkNagios          bool
+	checkZabbixDiscovery bool
+	checkZabbixDays      string
+	checkDomain          string
+	checkWarning         int
+	checkCritical        int
+)
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().BoolVar(&checkNagios, "nagios", false, "输出 Nagios 插件格式")
+	checkCmd.Flags().BoolVar(&checkZabbixDiscovery, "zabbix-discovery", false, "输出 Zabbix 低级发现 JSON")
+	checkCmd.Flags().StringVar(&checkZabbixDays, "zabbix-days", "", "输出指定证书的剩余天数（Zabbix 监控项）")
+	checkCmd.Flags().StringVarP(&checkDomain, "domain", "d", "", "只检查包含该域名的证书")
+	checkCmd.Flags().IntVarP(&checkWarning, "warning", "w", 30, "剩余天数少于该值时为 WARNING")
+	checkCmd.Flags().IntVarP(&checkCritical, "critical", "c", 7, "剩余天数少于该值时为 CRITICAL")
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	rows, err := loadInventory()
+
+	switch {
+	case checkZabbixDiscovery:
+		if err != nil {
+			return err
+		}
+		return report.WriteZabbixDiscovery(os.Stdout, filterInventory(rows, checkDomain))
+
+	case checkZabbixDays != "":
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if row.Name == checkZabbixDays {
+				fmt.Println(row.DaysLeft)
+				return nil
+			}
+		}
+		return fmt.Errorf("未找到证书: %s", checkZabbixDays)
+
+	default:
+		// Nagios 模式（默认）：出错时输出 UNKNOWN 而不是普通错误
+		thresholds := report.CheckThresholds{Warning: checkWarning, Critical: checkCritical}
+		if err != nil {
+			exitCode = report.WriteNagiosUnknown(os.Stdout, err)
+			return nil
+		}
+		// 失联的 agent 只在检查全部证书时报告
+		var stale []string
+		if checkDomain == "" {
+			stale = staleAgentNames()
+		}
+		exitCode = report.WriteNagios(os.Stdout, filterInventory(rows, checkDomain), stale, thresholds)
+		return nil
+	}
+}
+
+// filterInventory 筛选包含指定域名的证书，domain 为空时返回全部
+func filterInventory(rows []report.InventoryRow, domain string) []report.InventoryRow {
+	if domain == "" {
+		return rows
+	}
+
+	var filtered []report.InventoryRow
+	for _, row := range rows {
+		for _, san := range row.SANs {
+			if san == domain {
+				filtered = append(filtered, row)
+				break
+			}
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/console"
+	"autocert/internal/dns"
+	"autocert/internal/logger"
+	"autocert/internal/report"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "在 CI 流水线中签发证书（GitHub Actions、GitLab CI 等）",
+	Long: `在 CI 流水线中无状态地签发证书，证书作为构建产物输出，由流水线的后续步骤部署到其他地方。
+
+子命令:
+  issue  通过 DNS 验证签发证书，输出证书文件和 JSON 结果
+
+ci 命令的所有状态（ACME 账户、证书目录、日志等）都保存在 --state 目录中，不使用系统路径，
+不配置 Web 服务器、不安装定时任务、不执行部署目标和部署钩子。`,
+}
+
+var ciIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "签发证书并输出到产物目录",
+	Long: `通过 DNS 验证签发证书，按 --naming 命名方式将证书文件写入 --out 目录，
+并在其中写入 result.json（是否成功、错误信息、证书序列号和有效期、输出的文件）。
+
+未指定 --state 时使用临时目录，执行结束后删除（每次都会注册新的 ACME 账户）；
+需要复用 ACME 账户时指定 --state 目录并用流水线的缓存保存该目录。该目录包含账户私钥，不要作为产物上传。
+
+DNS 服务商的凭据在 --config 指定的配置文件中配置，可以在流水线中用密钥变量生成该文件。
+
+示例:
+  autocert ci issue --domain example.com --dns desec --out ./artifacts --config ci.yaml
+  autocert ci issue --domains "example.com,*.example.com" --dns powerdns --out ./artifacts --naming certbot
+  autocert ci issue --domain example.com --dns desec --out ./artifacts --state .autocert-state`,
+	Args: cobra.NoArgs,
+	RunE: runCIIssue,
+}
+
+var (
+	ciDomain   string
+	ciDomains  string
+	ciEmail    string
+	ciDNS      string
+	ciOut      string
+	ciState    string
+	ciNaming   string
+	ciDualCert bool
+	ciChain    string
+
+	ciTempState string // 未指定 --state 时使用的临时目录
+)
+
+func init() {
+	rootCmd.AddCommand(ciCmd)
+	ciCmd.AddCommand(ciIssueCmd)
+
+	ciIssueCmd.Flags().StringVarP(&ciDomain, "domain", "d", "", "要申请证书的单个域名")
+	ciIssueCmd.Flags().StringVar(&ciDomains, "domains", "", "多个域名，用逗号分隔")
+	ciIssueCmd.Flags().StringVarP(&ciEmail, "email", "e", "", "ACME 账户的邮箱地址")
+	ciIssueCmd.Flags().StringVar(&ciDNS, "dns", "", "DNS 服务商（默认使用配置的 dns.provider），可选: "+strings.Join(dns.Providers(), ", "))
+	ciIssueCmd.Flags().StringVar(&ciOut, "out", "", "证书产物和 result.json 的输出目录")
+	ciIssueCmd.Flags().StringVar(&ciState, "state", "", "保存 ACME 账户、证书和日志的状态目录（默认使用临时目录）")
+	ciIssueCmd.Flags().StringVar(&ciNaming, "naming", cert.NamingFlat, "证书文件的命名方式: certbot、acme.sh、synology、flat")
+	ciIssueCmd.Flags().BoolVar(&ciDualCert, "dual-cert", false, "同时签发 RSA 和 ECDSA 证书")
+	ciIssueCmd.Flags().StringVar(&ciChain, "preferred-chain", "", "首选证书链的根证书名称，例如 \"ISRG Root X1\"（默认使用配置 acme.preferred_chain）")
+	ciIssueCmd.MarkFlagRequired("out")
+}
+
+// applyCIState 执行 ci issue 时把配置目录、证书目录和日志目录指向状态目录，证书输出到产物目录。
+// 在加载配置前调用，未执行 ci issue 时不做任何修改
+func applyCIState() error {
+	if !ciIssueCmd.Flags().Parsed() || ciOut == "" {
+		return nil
+	}
+
+	stateDir := ciState
+	if stateDir == "" {
+		tmp, err := os.MkdirTemp("", "autocert-ci-")
+		if err != nil {
+			return fmt.Errorf("创建临时状态目录失败: %w", err)
+		}
+		ciTempState = tmp
+		stateDir = tmp
+	}
+	stateDir, err := filepath.Abs(stateDir)
+	if err != nil {
+		return err
+	}
+	out, err := filepath.Abs(ciOut)
+	if err != nil {
+		return err
+	}
+
+	viper.Set("config_dir", stateDir)
+	viper.Set("cert_dir", filepath.Join(stateDir, "certs"))
+	viper.Set("log_dir", filepath.Join(stateDir, "logs"))
+	viper.Set("output.naming", ciNaming)
+	viper.Set("output.dir", out)
+	if ciDNS != "" {
+		viper.Set("dns.provider", ciDNS)
+	}
+	return nil
+}
+
+// removeCITempState 删除未指定 --state 时创建的临时状态目录
+func removeCITempState() {
+	if ciTempState != "" {
+		os.RemoveAll(ciTempState)
+		ciTempState = ""
+	}
+}
+
+func runCIIssue(cmd *cobra.Command, args []string) error {
+	defer removeCITempState()
+
+	result := &report.IssueResult{StartedAt: time.Now(), Files: []string{}}
+	resultPath := filepath.Join(ciOut, report.IssueResultFileName)
+
+	err := issueCI(result)
+	result.FinishedAt = time.Now()
+	result.Success = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if writeErr := result.Write(resultPath); writeErr != nil {
+		logger.Error("写入结果文件失败", "path", resultPath, "error", writeErr)
+		if err == nil {
+			err = fmt.Errorf("写入结果文件失败: %w", writeErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	console.Success("证书已签发，有效期至 %s", result.NotAfter.Format("2006-01-02"))
+	for _, file := range result.Files {
+		fmt.Printf("  %s\n", filepath.Join(ciOut, file))
+	}
+	fmt.Printf("结果: %s\n", resultPath)
+	return nil
+}
+
+// issueCI 签发证书并在 result 中记录证书信息和输出的文件
+func issueCI(result *report.IssueResult) error {
+	var domainList []string
+	switch {
+	case ciDomains != "":
+		for _, d := range strings.Split(ciDomains, ",") {
+			domainList = append(domainList, strings.TrimSpace(d))
+		}
+	case ciDomain != "":
+		domainList = []string{strings.TrimSpace(ciDomain)}
+	default:
+		return fmt.Errorf("必须指定 --domain 或 --domains 参数")
+	}
+	result.Domains = domainList
+	for _, d := range domainList {
+		if err := validateDomainName(d); err != nil {
+			return fmt.Errorf("域名 %s 格式无效: %w", d, err)
+		}
+	}
+
+	// CI 中无法手动添加 TXT 记录，必须使用 DNS 服务商
+	provider := viper.GetString("dns.provider")
+	if provider == "" {
+		return fmt.Errorf("需要通过 --dns 或配置 dns.provider 指定 DNS 服务商（支持: %s）", strings.Join(dns.Providers(), ", "))
+	}
+	if _, err := dns.OpenProvider(provider); err != nil {
+		return err
+	}
+
+	logger.Info("CI 模式签发证书", "domains", domainList, "dns", provider, "out", ciOut)
+
+	var certInfo *cert.CertInfo
+	var err error
+	if len(domainList) == 1 {
+		manager := cert.NewManager(domainList[0], ciEmail)
+		manager.SetChallengeType(cert.ChallengeDNS)
+		manager.SetDualCert(ciDualCert)
+		manager.SetIssueOnly(true)
+		if ciChain != "" {
+			manager.SetPreferredChain(ciChain)
+		}
+		if err := manager.Install(); err != nil {
+			return fmt.Errorf("域名 %s 证书签发失败: %w", domainList[0], err)
+		}
+		certInfo, err = manager.GetCertInfo()
+	} else {
+		manager := cert.NewMultiDomainManager(domainList, ciEmail)
+		if manager == nil {
+			return fmt.Errorf("创建多域名管理器失败")
+		}
+		manager.SetChallengeType(cert.ChallengeDNS)
+		manager.SetDualCert(ciDualCert)
+		manager.SetIssueOnly(true)
+		if ciChain != "" {
+			manager.SetPreferredChain(ciChain)
+		}
+		if err := manager.Install(); err != nil {
+			return fmt.Errorf("多域名证书签发失败: %w", err)
+		}
+		certInfo, err = manager.GetCertInfo()
+	}
+	if err != nil {
+		return fmt.Errorf("读取签发的证书失败: %w", err)
+	}
+
+	if err := fillCertResult(result, certInfo.CertPath); err != nil {
+		return fmt.Errorf("读取签发的证书失败: %w", err)
+	}
+	return filepath.WalkDir(ciOut, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || entry.Name() == report.IssueResultFileName {
+			return err
+		}
+		rel, err := filepath.Rel(ciOut, path)
+		if err != nil {
+			return err
+		}
+		result.Files = append(result.Files, filepath.ToSlash(rel))
+		return nil
+	})
+}
+
+// fillCertResult 在结果中记录叶子证书的序列号、颁发者、有效期和指纹
+func fillCertResult(result *report.IssueResult, certPath string) error {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("%s 不是有效的 PEM 证书", certPath)
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	fingerprint := sha256.Sum256(leaf.Raw)
+	result.Serial = fmt.Sprintf("%X", leaf.SerialNumber)
+	result.Issuer = leaf.Issuer.String()
+	result.NotBefore = &leaf.NotBefore
+	result.NotAfter = &leaf.NotAfter
+	result.FingerprintSHA256 = hex.EncodeToString(fingerprint[:])
+	return nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/daemon"
+	"autocert/internal/scheduler"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "以守护进程模式运行",
+	Long: `以守护进程模式运行，定期检查并续期证书。
+
+配置 daemon.webhook 后会提供入站 webhook，外部系统（如 CMS 新增客户域名时）
+可远程触发签发/续期，无需 SSH 登录服务器:
+
+  curl -X POST -H "Authorization: Bearer <token>" \
+    "http://127.0.0.1:8089/hooks/renew?domain=shop.example.com"
+
+由 schedule install --mode service 注册为 Windows 服务时，守护进程由服务控制管理器启动和停止。
+
+配置文件（包括 include 的文件）修改后或收到 SIGHUP 时自动重新加载：校验通过后应用新配置并在日志中记录
+变化的配置项，新增或修改的域名立即签发/续期；配置无效时继续使用当前配置。监听地址、webhook 等
+需要重启后生效。
+
+示例:
+  autocert daemon
+  autocert daemon --interval 12h --listen 127.0.0.1:8089`,
+	RunE: runDaemon,
+}
+
+var (
+	daemonListen   string
+	daemonInterval time.Duration
+
+	// daemonServiceName 作为 Windows 服务运行时的服务名称（服务独占进程时仅用于日志）
+	daemonServiceName = scheduler.DefaultTaskName
+)
+
+// daemonDeployHelperCmd 降权运行的守护进程在降权前启动的 root 部署助手，不直接使用
+var daemonDeployHelperCmd = &cobra.Command{
+	Use:    "deploy-helper",
+	Short:  "守护进程的部署助手（内部使用）",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// 标准输出用于响应，部署过程中的其他输出改到标准错误
+		out := os.Stdout
+		os.Stdout = os.Stderr
+		console.Stdout = os.Stderr
+		return daemon.RunDeployHelper(os.Stdin, out)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonDeployHelperCmd)
+
+	daemonCmd.Flags().StringVar(&daemonListen, "listen", "", "HTTP 监听地址（覆盖配置 daemon.listen）")
+	daemonCmd.Flags().DurationVar(&daemonInterval, "interval", 0, "续期检查间隔（覆盖配置 daemon.interval）")
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	d, err := daemon.New(daemonConfig())
+	if err != nil {
+		return fmt.Errorf("创建守护进程失败: %w", err)
+	}
+	// 重新加载配置后命令行参数仍然优先
+	d.ConfigSource = daemonConfig
+
+	// 作为 Windows 服务运行时，服务停止请求会取消 ctx
+	if scheduler.IsService() {
+		return scheduler.RunService(daemonServiceName, d.Run)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return d.Run(ctx)
+}
+
+// daemonConfig 守护进程配置，应用命令行参数的覆盖
+func daemonConfig() config.DaemonConfig {
+	cfg := config.GetDaemonConfig()
+	if daemonListen != "" {
+		cfg.Listen = daemonListen
+	}
+	if daemonInterval > 0 {
+		cfg.Interval = daemonInterval
+	}
+	return cfg
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"autocert/internal/approval"
+	"autocert/internal/audit"
+	"autocert/internal/cert"
+	"autocert/internal/console"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete <证书名或域名>",
+	Short: "删除证书",
+	Long: `删除证书目录（证书、私钥和相关记录）。仍被 Web 服务器配置引用的证书需要先修改配置，
+或使用 --force 强制删除。开启 approval.enabled 时需要另一位操作员审批。
+
+示例:
+  autocert delete old.example.com
+  autocert delete old.example.com --approval-id 3f2a9c1b7e4d`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDelete,
+}
+
+var (
+	deleteForce      bool
+	deleteApprovalID string
+)
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	deleteCmd.Flags().BoolVar(&deleteForce, "force", false, "删除仍被 Web 服务器配置或证书链接引用的证书")
+	deleteCmd.Flags().StringVar(&deleteApprovalID, "approval-id", "", "已批准的审批请求 ID")
+}
+
+func runDelete(cmd *cobra.Command, args []string) error {
+	stored, err := findStoredCert(args[0])
+	if err != nil {
+		return err
+	}
+
+	details, err := stored.Details()
+	if err == nil && len(details.Deployments) > 0 && !deleteForce {
+		return fmt.Errorf("证书仍被引用: %s（使用 --force 强制删除）", strings.Join(details.Deployments, ", "))
+	}
+	if links := stored.Links(); len(links) > 0 && !deleteForce {
+		return fmt.Errorf("证书仍被链接: %s（使用 --force 强制删除）", strings.Join(links, ", "))
+	}
+
+	approved, proceed, err := requireApproval(approval.ActionDelete, stored.Name, deleteApprovalID)
+	if !proceed {
+		return err
+	}
+
+	if err := os.RemoveAll(stored.Dir); err != nil {
+		return fmt.Errorf("删除证书失败: %w", err)
+	}
+	if err := cert.DeleteRemoteCertificate(stored.Name); err != nil {
+		return fmt.Errorf("从存储后端删除证书失败: %w", err)
+	}
+
+	completeApproval(approved)
+	audit.Record(audit.Entry{Action: "certificate.delete", Domains: stored.Domains, Result: audit.ResultSuccess, Detail: stored.Dir})
+	console.Success("已删除证书 %s", stored.Name)
+	return nil
+}
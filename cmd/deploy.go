@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/deploy"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "查看或执行证书部署目标",
+	Long: `显示域名配置的部署目标（NAS、虚拟化平台、路由器和防火墙等），或使用 --run 将已有证书部署一次。
+
+证书签发/续期并配置 Web 服务器后，会先部署到配置的部署目标，再执行部署钩子。
+支持的类型: ` + strings.Join(deploy.Types(), ", ") + `
+
+示例:
+  autocert deploy --domain nas.example.com
+  autocert deploy --domain nas.example.com --run
+  autocert deploy --domain nas.example.com --run --target dsm`,
+	RunE: runDeploy,
+}
+
+var (
+	deployDomain string
+	deployRun    bool
+	deployTarget string
+)
+
+func init() {
+	rootCmd.AddCommand(deployCmd)
+
+	deployCmd.Flags().StringVarP(&deployDomain, "domain", "d", "", "域名（证书的主域名）")
+	deployCmd.Flags().BoolVar(&deployRun, "run", false, "将已有证书部署到配置的目标")
+	deployCmd.Flags().StringVar(&deployTarget, "target", "", "只部署到指定名称的目标")
+	deployCmd.MarkFlagRequired("domain")
+}
+
+func runDeploy(cmd *cobra.Command, args []string) error {
+	targets := config.GetDeployTargets(deployDomain)
+	if len(targets) == 0 {
+		fmt.Printf("域名 %s 没有配置部署目标\n", deployDomain)
+		return nil
+	}
+
+	if !deployRun {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "名称\t类型\t失败策略")
+		fmt.Fprintln(w, "----\t----\t--------")
+		for _, target := range targets {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", deploy.TargetName(target), target.Type, deploy.OnFailure(target))
+		}
+		return w.Flush()
+	}
+
+	stored, err := findStoredCert(deployDomain)
+	if err != nil {
+		return err
+	}
+
+	results, runErr := stored.Deploy(deployTarget)
+	for _, result := range results {
+		switch {
+		case result.Skipped:
+			console.Warn("%s 已跳过：之前的目标部署失败", result.Name)
+		case result.Err != nil:
+			console.Error("%s: %v", result.Name, result.Err)
+		default:
+			console.Success("%s（%s）", result.Name, result.Duration.Round(time.Millisecond))
+		}
+	}
+	return runErr
+}
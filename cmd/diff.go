@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "比较线上证书与本地证书",
+	Long: `通过 TLS 连接获取服务器实际提供的证书，与证书目录中的证书比较序列号、域名和到期时间，
+并检查 Web 服务器配置引用的证书文件，找出 "Nginx 实际提供的是哪张证书"。
+
+证书不一致时退出码为 1。
+
+示例:
+  autocert diff --domain example.com
+  autocert diff --domain example.com --host 10.0.0.5 --port 8443`,
+	RunE: runDiff,
+}
+
+var (
+	diffDomain  string
+	diffHost    string
+	diffPort    int
+	diffTimeout time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&diffDomain, "domain", "d", "", "要比较的域名（SNI）")
+	diffCmd.Flags().StringVar(&diffHost, "host", "", "连接的主机地址（默认为域名，可指定负载均衡后的单台服务器）")
+	diffCmd.Flags().IntVar(&diffPort, "port", 443, "TLS 端口")
+	diffCmd.Flags().DurationVar(&diffTimeout, "timeout", 10*time.Second, "连接超时")
+	diffCmd.MarkFlagRequired("domain")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	stored, err := findStoredCert(diffDomain)
+	if err != nil {
+		return err
+	}
+
+	host := diffHost
+	if host == "" {
+		host = diffDomain
+	}
+
+	live, err := cert.FetchLiveCertificate(host, diffPort, diffDomain, diffTimeout)
+	if err != nil {
+		return fmt.Errorf("获取 %s:%d 的证书失败: %w", host, diffPort, err)
+	}
+
+	diff, err := stored.Diff(live)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\t本地 (%s)\t线上 (%s:%d)\n", diff.LocalFile, host, diffPort)
+	printDiffRow(w, "序列号", diff.Local, live, func(c *x509.Certificate) string { return fmt.Sprintf("%X", c.SerialNumber) })
+	printDiffRow(w, "域名", diff.Local, live, func(c *x509.Certificate) string { return strings.Join(c.DNSNames, ",") })
+	printDiffRow(w, "签发者", diff.Local, live, func(c *x509.Certificate) string { return c.Issuer.CommonName })
+	printDiffRow(w, "到期时间", diff.Local, live, func(c *x509.Certificate) string { return clock.Format(c.NotAfter) })
+	w.Flush()
+
+	if len(diff.References) > 0 {
+		fmt.Println("\nWeb 服务器配置:")
+		for _, ref := range diff.References {
+			switch {
+			case ref.CertFile == "":
+				fmt.Printf("  %s: 无法读取配置文件\n", ref.Config)
+			case !ref.Local:
+				fmt.Printf("  %s: %s（不是 AutoCert 管理的证书，序列号 %s）\n", ref.Config, ref.CertFile, ref.Serial)
+			default:
+				fmt.Printf("  %s: %s\n", ref.Config, ref.CertFile)
+			}
+		}
+	}
+
+	fmt.Println()
+	if diff.Match() {
+		console.Success("线上证书与本地证书一致")
+		return nil
+	}
+
+	for _, mismatch := range diff.Mismatches {
+		fmt.Printf("  - %s\n", mismatch)
+	}
+	if liveIsOlder(diff.Local, live) {
+		fmt.Println("线上证书比本地证书旧，Web 服务器可能未重载，或配置引用了其他证书文件")
+	}
+	return fmt.Errorf("线上证书与本地证书不一致")
+}
+
+// printDiffRow 输出一行比较结果，不一致时标记
+func printDiffRow(w *tabwriter.Writer, label string, local, live *x509.Certificate, value func(*x509.Certificate) string) {
+	a, b := value(local), value(live)
+	mark := ""
+	if a != b {
+		mark = " ≠"
+	}
+	fmt.Fprintf(w, "%s%s\t%s\t%s\n", label, mark, a, b)
+}
+
+// liveIsOlder 线上证书是否比本地证书签发得更早
+func liveIsOlder(local, live *x509.Certificate) bool {
+	return live.NotBefore.Before(local.NotBefore)
+}
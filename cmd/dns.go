@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/dns"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var cleanupDNSCmd = &cobra.Command{
+	Use:   "cleanup-dns",
+	Short: "删除残留的 DNS 验证记录",
+	Long: `删除 DNS-01 验证残留的 _acme-challenge TXT 记录。
+
+通过 DNS 服务商（dns.provider）添加的验证记录会记录在配置目录的 dns-records.json 中，
+验证结束后自动删除；验证失败或进程中断时记录可能残留。该命令通过添加记录时使用的
+DNS 服务商删除创建时间超过 --older-than（默认 dns.cleanup_after）的记录，
+守护进程每轮续期检查时也会自动执行。
+
+示例:
+  autocert cleanup-dns --dry-run
+  autocert cleanup-dns --older-than 10m`,
+	RunE: runCleanupDNS,
+}
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "DNS 服务商工具",
+}
+
+var dnsTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "测试 DNS 服务商的凭据和记录管理",
+	Long: `在签发泛域名证书前验证 DNS 服务商配置：
+
+  1. 凭据和区域：查询 _acme-challenge 记录所在的区域（服务商支持时）
+  2. 添加测试记录：添加一条随机值的 TXT 记录
+  3. 记录生效：向区域的权威服务器确认记录已生效（按 dns.propagation 配置）
+  4. 删除测试记录：立即删除测试记录
+
+测试记录与验证记录一样记录在 dns-records.json 中，删除失败时可执行 cleanup-dns 清理。
+任一步骤失败时退出码为 1。
+
+示例:
+  autocert dns test --domain example.com
+  autocert dns test --provider powerdns --domain example.com`,
+	RunE: runDNSTest,
+}
+
+var (
+	cleanupDNSOlderThan string
+	cleanupDNSDryRun    bool
+
+	dnsTestProvider string
+	dnsTestDomain   string
+)
+
+func init() {
+	rootCmd.AddCommand(cleanupDNSCmd)
+	rootCmd.AddCommand(dnsCmd)
+	dnsCmd.AddCommand(dnsTestCmd)
+
+	cleanupDNSCmd.Flags().StringVar(&cleanupDNSOlderThan, "older-than", "", "只删除创建时间超过该时长的记录，例如 30m、1d（默认 dns.cleanup_after）")
+	cleanupDNSCmd.Flags().BoolVar(&cleanupDNSDryRun, "dry-run", false, "只列出将被删除的记录，不实际删除")
+
+	dnsTestCmd.Flags().StringVar(&dnsTestProvider, "provider", "", "DNS 服务商（默认 dns.provider）")
+	dnsTestCmd.Flags().StringVarP(&dnsTestDomain, "domain", "d", "", "测试的域名，记录名为 _acme-challenge.<域名> (必需)")
+	dnsTestCmd.MarkFlagRequired("domain")
+}
+
+func runDNSTest(cmd *cobra.Command, args []string) error {
+	name := dnsTestProvider
+	if name == "" {
+		name = config.GetDNSConfig().Provider
+	}
+	if name == "" {
+		return fmt.Errorf("未指定 --provider 且未配置 dns.provider（支持: %s）", strings.Join(dns.Providers(), ", "))
+	}
+	provider, err := dns.OpenProvider(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("测试 DNS 服务商 %s（域名 %s）\n", provider.Name(), dnsTestDomain)
+	failed := 0
+	for _, step := range dns.Check(provider, dnsTestDomain) {
+		switch {
+		case step.Err != nil:
+			failed++
+			fmt.Println(console.Colorize(os.Stdout, console.Red, fmt.Sprintf("✗ %s: %v", step.Name, step.Err)))
+		case step.Skipped:
+			fmt.Println(console.Colorize(os.Stdout, console.Gray, fmt.Sprintf("- %s: 已跳过（%s）", step.Name, step.Detail)))
+		default:
+			fmt.Println(console.Colorize(os.Stdout, console.Green, fmt.Sprintf("✓ %s: %s", step.Name, step.Detail)))
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("DNS 服务商 %s 测试未通过", provider.Name())
+	}
+	console.Success("DNS 服务商 %s 可用于 DNS-01 验证", provider.Name())
+	return nil
+}
+
+func runCleanupDNS(cmd *cobra.Command, args []string) error {
+	maxAge := config.GetDNSConfig().CleanupAfter
+	if cleanupDNSOlderThan != "" {
+		age, err := parseAge(cleanupDNSOlderThan)
+		if err != nil {
+			return fmt.Errorf("无效的 --older-than: %w", err)
+		}
+		maxAge = age
+	}
+
+	removed, err := dns.Cleanup(maxAge, cleanupDNSDryRun)
+	for _, record := range removed {
+		fmt.Printf("  %s（%s，%s 前由 %s 添加）\n", record.FQDN, record.Domain,
+			time.Since(record.CreatedAt).Round(time.Second), record.Provider)
+	}
+
+	switch {
+	case len(removed) == 0 && err == nil:
+		fmt.Println("没有需要清理的验证记录")
+	case cleanupDNSDryRun:
+		fmt.Printf("将删除 %d 条验证记录\n", len(removed))
+	case len(removed) > 0:
+		console.Success("已删除 %d 条验证记录", len(removed))
+	}
+	if err != nil {
+		return fmt.Errorf("部分验证记录删除失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/preflight"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [域名...]",
+	Short: "检查签发证书的运行环境",
+	Long: `执行签发证书前的环境检查（与 install/renew 签发前执行的预检相同），
+例如系统时间是否与 CA 服务器一致。指定域名时同时检查域名解析：
+本机只有 IPv6 时是否有 AAAA 记录、AAAA/A 记录是否指向本机
+（Let's Encrypt 在有 AAAA 记录时优先通过 IPv6 验证），以及是否轮询解析到多台主机。
+
+同时检查证书目录的权限：目录应为 storage.dir_mode（默认 0700），
+私钥文件不允许组和其他用户访问；--fix 修正不符合要求的权限。
+
+存在阻止签发的问题或权限不符合要求时退出码为 1。
+
+示例:
+  autocert doctor
+  autocert doctor --fix
+  autocert doctor example.com www.example.com`,
+	RunE: runDoctor,
+}
+
+var (
+	doctorDNS       bool
+	doctorFix       bool
+	doctorMultiHost bool
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().BoolVar(&doctorDNS, "dns", false, "按 DNS 验证检查（跳过 http-01 的域名解析检查）")
+	doctorCmd.Flags().BoolVar(&doctorMultiHost, "expect-multi-host", false, "确认域名解析到的多台主机都能响应 http-01 挑战")
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "修正证书目录和私钥文件的权限")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	failed := 0
+	for _, result := range preflight.Run(args, preflight.Options{HTTP01: !doctorDNS, ExpectMultiHost: doctorMultiHost}) {
+		if !printCheckResult(result) {
+			failed++
+		}
+	}
+
+	if !checkPermissions() {
+		failed++
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d 项检查未通过", failed)
+	}
+	return nil
+}
+
+// printCheckResult 输出单项检查的结果，检查未通过时返回 false
+func printCheckResult(result preflight.Result) bool {
+	switch {
+	case result.Err != nil:
+		fmt.Println(console.Colorize(os.Stdout, console.Red, fmt.Sprintf("✗ %s: %v", result.Name, result.Err)))
+	case result.Skipped:
+		fmt.Println(console.Colorize(os.Stdout, console.Gray, fmt.Sprintf("- %s: 已跳过（%s）", result.Name, result.Detail)))
+	case len(result.Warnings) > 0:
+		fmt.Println(console.Colorize(os.Stdout, console.Yellow, fmt.Sprintf("⚠ %s: %s", result.Name, result.Detail)))
+	default:
+		fmt.Println(console.Colorize(os.Stdout, console.Green, fmt.Sprintf("✓ %s: %s", result.Name, result.Detail)))
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Printf("    %s\n", warning)
+	}
+	return result.Err == nil
+}
+
+// checkPermissions 检查证书目录权限并输出结果，存在未修正的问题时返回 false
+func checkPermissions() bool {
+	const name = "证书目录权限"
+	issues, err := cert.CheckPermissions(config.GetCertDir(), doctorFix)
+	if err != nil {
+		fmt.Println(console.Colorize(os.Stdout, console.Red, fmt.Sprintf("✗ %s: %v", name, err)))
+		return false
+	}
+
+	var fixed, remaining int
+	for _, issue := range issues {
+		if issue.Fixed {
+			fixed++
+		} else {
+			remaining++
+		}
+	}
+
+	switch {
+	case len(issues) == 0:
+		fmt.Println(console.Colorize(os.Stdout, console.Green, fmt.Sprintf("✓ %s: 符合要求（目录 %04o）", name, cert.DirMode())))
+	case remaining == 0:
+		fmt.Println(console.Colorize(os.Stdout, console.Yellow, fmt.Sprintf("⚠ %s: 已修正 %d 项", name, fixed)))
+	default:
+		hint := ""
+		if !doctorFix {
+			hint = "，使用 --fix 修正"
+		}
+		fmt.Println(console.Colorize(os.Stdout, console.Red, fmt.Sprintf("✗ %s: %d 项不符合要求%s", name, remaining, hint)))
+	}
+
+	for _, issue := range issues {
+		line := fmt.Sprintf("    %s: %04o，应为 %04o", issue.Path, issue.Mode, issue.Want)
+		switch {
+		case issue.Fixed:
+			line += "（已修正）"
+		case issue.Err != nil:
+			line += fmt.Sprintf("（修正失败: %v）", issue.Err)
+		}
+		fmt.Println(line)
+	}
+	return remaining == 0
+}
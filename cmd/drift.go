@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"autocert/internal/webserver"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "检查生成的站点配置是否被手工修改",
+	Long: `autocert 记录每个生成的 Nginx/Apache 站点配置的内容和 SHA-256（配置目录的 managed-configs.json），
+之后每次写入前比较：文件在上次生成后被手工修改时，新生成的内容没有变化则保留手工修改，
+有变化则拒绝覆盖，新内容保存到 <站点配置>.autocert-new 等待合并。
+
+处理拒绝覆盖的站点配置:
+  1. autocert drift diff <站点配置>     查看手工修改和 autocert 要做的变更
+  2. 将变更合并到站点配置后执行 autocert drift accept <站点配置>
+     或者放弃手工修改，使用 --overwrite-drift 重新执行 install/renew
+
+子命令:
+  status   列出生成的站点配置及其状态
+  diff     显示手工修改和待合并的变更
+  accept   确认已合并待合并的新配置
+
+示例:
+  autocert drift status
+  autocert drift diff /etc/nginx/sites-available/example.com
+  autocert drift accept example.com`,
+}
+
+var driftStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "列出生成的站点配置及其状态（有手工修改时退出码为 2）",
+	Args:  cobra.NoArgs,
+	RunE:  runDriftStatus,
+}
+
+var driftDiffCmd = &cobra.Command{
+	Use:   "diff [站点配置或域名]",
+	Short: "显示手工修改和待合并的变更（默认显示全部有手工修改的站点配置）",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runDriftDiff,
+}
+
+var driftAcceptCmd = &cobra.Command{
+	Use:   "accept <站点配置或域名>",
+	Short: "确认已将 .autocert-new 中的新配置合并到站点配置",
+	Long: `确认已将拒绝覆盖时保存的新配置（<站点配置>.autocert-new）合并到站点配置：
+之后以新配置作为比较的基准，生成的内容不再变化时保留当前文件，并删除 .autocert-new。`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDriftAccept,
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+	driftCmd.AddCommand(driftStatusCmd)
+	driftCmd.AddCommand(driftDiffCmd)
+	driftCmd.AddCommand(driftAcceptCmd)
+}
+
+func runDriftStatus(cmd *cobra.Command, args []string) error {
+	configs, err := webserver.ManagedConfigs()
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		fmt.Println("没有记录生成的站点配置")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "站点配置\t服务器\t域名\t生成时间\t状态")
+	fmt.Fprintln(w, "--------\t------\t----\t--------\t----")
+	for _, managed := range configs {
+		status, _ := managed.Status()
+		label := "未修改"
+		switch status {
+		case webserver.DriftModified:
+			label = "已手工修改"
+			exitCode = exitChangesNeeded
+		case webserver.DriftMissing:
+			label = "文件不存在"
+		}
+		if _, ok := managed.Pending(); ok {
+			label += "，有待合并的新配置"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", managed.Path, managed.Server, managed.Domain, clock.Format(managed.WrittenAt), label)
+	}
+	return w.Flush()
+}
+
+func runDriftDiff(cmd *cobra.Command, args []string) error {
+	var configs []*webserver.ManagedConfig
+	var err error
+	if len(args) == 1 {
+		configs, err = findManagedConfigs(args[0])
+	} else {
+		configs, err = webserver.ManagedConfigs()
+	}
+	if err != nil {
+		return err
+	}
+
+	shown := 0
+	for _, managed := range configs {
+		status, current := managed.Status()
+		pending, hasPending := managed.Pending()
+		if status != webserver.DriftModified && !hasPending {
+			continue
+		}
+		shown++
+
+		fmt.Printf("# %s\n", managed.Path)
+		if status == webserver.DriftModified {
+			fmt.Println("## 手工修改（上次生成 → 当前）")
+			fmt.Print(webserver.UnifiedDiff(managed.Path+"（上次生成）", managed.Path, managed.Content, current))
+		}
+		if hasPending {
+			fmt.Printf("## 待合并的变更（上次生成 → %s）\n", managed.Path+webserver.PendingSuffix)
+			fmt.Print(webserver.UnifiedDiff(managed.Path+"（上次生成）", managed.Path+webserver.PendingSuffix, managed.Content, pending))
+		}
+		fmt.Println()
+	}
+	if shown == 0 {
+		fmt.Println("站点配置没有手工修改")
+	}
+	return nil
+}
+
+func runDriftAccept(cmd *cobra.Command, args []string) error {
+	configs, err := findManagedConfigs(args[0])
+	if err != nil {
+		return err
+	}
+	for _, managed := range configs {
+		if err := webserver.AcceptMerged(managed.Path); err != nil {
+			return err
+		}
+		console.Success("已确认合并 %s，生成的内容不再变化时保留当前文件", managed.Path)
+	}
+	return nil
+}
+
+// findManagedConfigs 按路径或域名查找生成的站点配置
+func findManagedConfigs(target string) ([]*webserver.ManagedConfig, error) {
+	configs, err := webserver.ManagedConfigs()
+	if err != nil {
+		return nil, err
+	}
+	var found []*webserver.ManagedConfig
+	for _, managed := range configs {
+		if managed.Path == target || managed.Domain == target {
+			found = append(found, managed)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("没有记录 %s 的站点配置（autocert drift status 查看）", target)
+	}
+	return found, nil
+}
+
+// printDrift 站点配置被手工修改、保留手工修改继续部署时，输出手工修改的差异和处理方法
+func printDrift(drift *webserver.DriftError) {
+	console.Warn("%v", drift)
+	if drift.Diff != "" {
+		fmt.Print(drift.Diff)
+	}
+}
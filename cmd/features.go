@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"autocert/internal/features"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var featuresCmd = &cobra.Command{
+	Use:   "features",
+	Short: "查看实验性功能开关",
+	Long: `较大的新功能先以实验性功能发布，默认关闭，在配置的 features 中按主机开启：
+
+  features:
+    dual_cert: true
+    daemon_api: true
+
+子命令:
+  list   列出实验性功能及其是否开启
+
+示例:
+  autocert features list`,
+}
+
+var featuresListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出实验性功能及其是否开启",
+	Args:  cobra.NoArgs,
+	RunE:  runFeaturesList,
+}
+
+func init() {
+	rootCmd.AddCommand(featuresCmd)
+	featuresCmd.AddCommand(featuresListCmd)
+}
+
+func runFeaturesList(cmd *cobra.Command, args []string) error {
+	for _, f := range features.List() {
+		status := "未开启"
+		switch {
+		case !f.Available:
+			status = "不可用"
+		case f.Enabled:
+			status = "已开启"
+		}
+		fmt.Printf("%-12s %-6s %s\n", f.Name, status, f.Description)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"autocert/internal/approval"
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/system"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "清理过期、孤立和不再使用的证书",
+	Long: `清理不再需要的证书文件并报告回收的空间：
+
+  - 过期超过 --older-than 的证书目录
+  - 配置 domains 中已移除、且超过 --older-than 未更新的证书目录（配置了 domains 时）
+  - 超过 --older-than 仍没有证书文件的残留目录
+  - 证书库（storage.layout: canonical）中不再被引用的旧版本
+  - 已失效的证书链接记录
+
+仍被 Web 服务器配置或 autocert link 引用的证书目录默认跳过，可用 --force 强制删除。
+
+示例:
+  autocert gc --dry-run
+  autocert gc --older-than 30d`,
+	RunE: runGC,
+}
+
+var (
+	gcDryRun    bool
+	gcOlderThan string
+	gcForce     bool
+	gcApproval  string
+)
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "只列出将被删除的文件，不实际删除")
+	gcCmd.Flags().StringVar(&gcOlderThan, "older-than", "90d", "证书过期（或未更新）超过该时长才清理，例如 90d、720h")
+	gcCmd.Flags().BoolVar(&gcForce, "force", false, "同时删除仍被 Web 服务器配置或证书链接引用的证书目录")
+	gcCmd.Flags().StringVar(&gcApproval, "approval-id", "", "已批准的审批请求 ID（开启审批时需要）")
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	olderThan, err := parseAge(gcOlderThan)
+	if err != nil {
+		return fmt.Errorf("无效的 --older-than: %w", err)
+	}
+
+	certDir := config.GetCertDir()
+	stale, err := cert.FindStaleCertificates(certDir, olderThan)
+	if err != nil {
+		return fmt.Errorf("读取证书目录失败: %w", err)
+	}
+
+	var (
+		doomed  []cert.StaleCert
+		names   []string
+		skipped int
+	)
+	for _, s := range stale {
+		if s.InUse() && !gcForce {
+			fmt.Printf("  跳过 %s（%s，仍被引用: %s）\n", s.Dir, s.Reason, strings.Join(append(s.Deployments, s.Links...), ", "))
+			skipped++
+			continue
+		}
+		doomed = append(doomed, s)
+		names = append(names, filepath.Base(s.Dir))
+	}
+
+	// 实际删除前需要审批（dry-run 不需要）。审批对象是这次要删除的证书目录列表，
+	// 批准后目录列表有变化（例如又有证书过期）时需要重新审批
+	var approved *approval.Request
+	if !gcDryRun {
+		target := fmt.Sprintf("older-than=%s force=%t: %s", gcOlderThan, gcForce, strings.Join(names, ","))
+		var proceed bool
+		approved, proceed, err = requireApproval(approval.ActionGC, target, gcApproval)
+		if !proceed {
+			return err
+		}
+	}
+
+	var (
+		removed []string
+		bytes   int64
+	)
+	for _, s := range doomed {
+		fmt.Printf("  %s（%s）\n", s.Dir, s.Reason)
+		if !gcDryRun {
+			if err := os.RemoveAll(s.Dir); err != nil {
+				return fmt.Errorf("删除 %s 失败: %w", s.Dir, err)
+			}
+		}
+		removed = append(removed, s.Dir)
+		bytes += s.Bytes
+	}
+
+	// 删除证书目录后，其引用的证书库版本也一并清理
+	result, err := cert.CollectStore(certDir, gcDryRun, removed...)
+	if err != nil {
+		return fmt.Errorf("清理证书库失败: %w", err)
+	}
+	for _, path := range result.Removed {
+		fmt.Printf("  %s（证书库中未被引用的版本）\n", path)
+	}
+
+	total := len(removed) + len(result.Removed)
+	bytes += result.Bytes
+
+	if total == 0 {
+		fmt.Println("没有需要清理的文件")
+	} else if gcDryRun {
+		fmt.Printf("将删除 %d 项，可回收 %s\n", total, system.FormatBytes(bytes))
+	} else {
+		console.Success("已删除 %d 项，回收 %s", total, system.FormatBytes(bytes))
+	}
+	if !gcDryRun {
+		completeApproval(approved)
+	}
+
+	if skipped > 0 {
+		console.Warn("%d 个证书目录仍被引用，已跳过（使用 --force 强制删除）", skipped)
+	}
+	return nil
+}
+
+// parseAge 解析时长，在 time.ParseDuration 的基础上支持以天为单位，例如 90d
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q 不是有效的天数", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
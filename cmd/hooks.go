@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/hooks"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "查看或执行部署钩子",
+	Long: `显示域名配置的部署钩子执行计划（按 after 依赖关系分层，同一层并行执行），
+或使用 --run 对已有证书手动执行一次。
+
+证书签发/续期并配置 Web 服务器后会自动执行部署钩子。
+
+示例:
+  autocert hooks --domain example.com
+  autocert hooks --domain example.com --run`,
+	RunE: runHooks,
+}
+
+var (
+	hooksDomain string
+	hooksRun    bool
+)
+
+func init() {
+	rootCmd.AddCommand(hooksCmd)
+
+	hooksCmd.Flags().StringVarP(&hooksDomain, "domain", "d", "", "域名（证书的主域名）")
+	hooksCmd.Flags().BoolVar(&hooksRun, "run", false, "对已有证书执行部署钩子")
+	hooksCmd.MarkFlagRequired("domain")
+}
+
+func runHooks(cmd *cobra.Command, args []string) error {
+	deployHooks := config.GetHooks(hooksDomain)
+	if len(deployHooks) == 0 {
+		fmt.Printf("域名 %s 没有配置部署钩子\n", hooksDomain)
+		return nil
+	}
+
+	levels, err := hooks.Plan(deployHooks)
+	if err != nil {
+		return err
+	}
+
+	if !hooksRun {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "阶段\t钩子\t依赖\t超时\t重试\t失败策略\t命令")
+		fmt.Fprintln(w, "----\t----\t----\t----\t----\t--------\t----")
+		for i, level := range levels {
+			for _, hook := range level {
+				timeout := hook.Timeout
+				if timeout <= 0 {
+					timeout = hooks.DefaultTimeout
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n", i+1, hook.Name, strings.Join(hook.After, ","),
+					timeout, hook.Retries, hooks.OnFailure(hook), hook.Command)
+			}
+		}
+		return w.Flush()
+	}
+
+	stored, err := findStoredCert(hooksDomain)
+	if err != nil {
+		return err
+	}
+
+	results, runErr := stored.RunHooks()
+	for _, result := range results {
+		switch result.Status {
+		case hooks.StatusSuccess:
+			console.Success("%s（%s，%d 次）", result.Name, result.Duration.Round(time.Millisecond), result.Attempts)
+		case hooks.StatusFailed:
+			console.Error("%s: %v", result.Name, result.Err)
+		default:
+			console.Warn("%s 已跳过: %v", result.Name, result.Err)
+		}
+	}
+	return runErr
+}
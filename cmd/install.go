@@ -1,9 +1,17 @@
 package cmd
 
 import (
+	"autocert/internal/approval"
+	"autocert/internal/assess"
 	"autocert/internal/cert"
+	"autocert/internal/console"
+	"autocert/internal/domainlist"
+	"autocert/internal/features"
 	"autocert/internal/logger"
+	"autocert/internal/system"
+	"autocert/internal/webserver"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -24,17 +32,43 @@ var installCmd = &cobra.Command{
   # 泛域名证书（需要DNS验证）
   autocert install --domain "*.example.com" --email admin@example.com --nginx --dns
   
+  # 同时签发 RSA 和 ECDSA 证书
+  autocert install --domain example.com --email admin@example.com --nginx --dual-cert
+  autocert install --domain example.com --email admin@example.com --nginx --preferred-chain "ISRG Root X1"
+
   # 二级域名
   autocert install --domain sub.example.com --email admin@example.com --nginx
   
   # 混合域名（主域名+泛域名）
-  autocert install --domains "example.com,*.example.com" --email admin@example.com --nginx --dns`,
+  autocert install --domains "example.com,*.example.com" --email admin@example.com --nginx --dns
+
+  # 从 certbot/acme.sh 迁移，并停用它们对这些域名的自动续期
+  autocert install --domain example.com --email admin@example.com --nginx --takeover
+
+  # 域名轮询解析到多台主机，且各主机共享 webroot 目录
+  autocert install --domain example.com --email admin@example.com --nginx --webroot /mnt/shared/www --expect-multi-host
+
+  # 混合验证（泛域名成员使用 dns-01，其余成员使用 webroot）
+  autocert install --domains "example.com,*.example.com" --email admin@example.com --nginx --webroot /var/www/html
+
+  # 站点配置被手工修改过，确认覆盖
+  autocert install --domain example.com --email admin@example.com --nginx --overwrite-drift
+
+  # 迁移期间：HTTP 继续提供站点，不重定向到 HTTPS
+  autocert install --domain example.com --email admin@example.com --nginx --no-redirect
+
+  # 站点配置写入 .disabled，检查后手工启用
+  autocert install --domain example.com --email admin@example.com --nginx --staging-deploy
+
+  # 从域名文件读取 SAN 成员（每行一个或多个域名，可以用 challenge=、webroot= 为该行的域名指定验证方式）
+  autocert install --domains-file domains.txt --email admin@example.com --nginx --webroot /var/www/html`,
 	RunE: runInstall,
 }
 
 var (
 	domain       string
 	domains      string // 多域名，逗号分隔
+	domainsFile  string // 域名文件，每行一个或多个域名
 	email        string
 	webroot      string
 	standalone   bool
@@ -42,14 +76,38 @@ var (
 	nginx        bool
 	apache       bool
 	iis          bool
+	dualCert     bool // 同时签发 RSA 和 ECDSA 证书
+	takeover     bool // 停用其他 ACME 客户端对这些域名的自动续期
+
+	expectMultiHost    bool // 已确认域名解析到的多台主机都能响应 http-01 挑战
+	expectMultiHostSet bool // 指定了 --expect-multi-host（包括 --expect-multi-host=false）
+
+	installApprovalID string // 已批准的审批请求 ID（--takeover 需要审批时）
+
+	installCheck bool // 只检查是否需要变更，不执行
+	installDiff  bool // 显示将要执行的变更差异
+
+	installAssess bool // 部署后评估 TLS 配置
+
+	preferredChain string // 首选证书链的根证书名称
+
+	overwriteDrift bool // 覆盖手工修改的站点配置
+
+	noRedirect    bool // HTTP 不重定向到 HTTPS
+	noRedirectSet bool // 指定了 --no-redirect（包括 --no-redirect=false）
+	stagingDeploy bool // 站点配置写入 .disabled，不启用
 )
 
+// exitChangesNeeded install --check 发现需要变更时的退出码
+const exitChangesNeeded = 2
+
 func init() {
 	rootCmd.AddCommand(installCmd)
 
 	// 域名参数
 	installCmd.Flags().StringVarP(&domain, "domain", "d", "", "要申请证书的单个域名")
 	installCmd.Flags().StringVar(&domains, "domains", "", "多个域名，用逗号分隔 (例: example.com,www.example.com,*.example.com)")
+	installCmd.Flags().StringVar(&domainsFile, "domains-file", "", "从文件读取域名（每行一个或多个域名，# 开始注释，- 表示标准输入）")
 	installCmd.Flags().StringVarP(&email, "email", "e", "", "用于 Let's Encrypt 账户的邮箱地址 (必需)")
 
 	// 验证模式
@@ -62,11 +120,33 @@ func init() {
 	installCmd.Flags().BoolVar(&apache, "apache", false, "配置 Apache")
 	installCmd.Flags().BoolVar(&iis, "iis", false, "配置 IIS")
 
+	// 证书选项
+	installCmd.Flags().BoolVar(&dualCert, "dual-cert", false, "同时签发 RSA 和 ECDSA 证书（Nginx 同时提供两张证书）")
+	installCmd.Flags().StringVar(&preferredChain, "preferred-chain", "", "CA 提供多条证书链时选择根证书为该名称的证书链，例如 \"ISRG Root X1\"（默认使用配置 acme.preferred_chain）")
+	installCmd.Flags().BoolVar(&takeover, "takeover", false, "停用 certbot/acme.sh 对这些域名的自动续期，避免重复签发")
+	installCmd.Flags().BoolVar(&expectMultiHost, "expect-multi-host", false, "确认域名解析到的多台主机都能响应 http-01 挑战（不再警告 DNS 轮询，续期时沿用，--expect-multi-host=false 取消）")
+	installCmd.Flags().StringVar(&installApprovalID, "approval-id", "", "已批准的审批请求 ID（开启审批时 --takeover 需要）")
+
+	// 幂等执行（Ansible/Terraform）
+	installCmd.Flags().BoolVar(&installCheck, "check", false, "只检查是否需要变更（退出码 0 无变更，2 需要变更），不执行")
+	installCmd.Flags().BoolVar(&installDiff, "diff", false, "显示证书域名和站点配置的变更差异")
+
+	installCmd.Flags().BoolVar(&installAssess, "assess", false, "部署后评估本机 TLS 配置并给出评级和修复建议")
+	installCmd.Flags().BoolVar(&overwriteDrift, "overwrite-drift", false, "覆盖上次生成后被手工修改的站点配置（默认拒绝覆盖）")
+
+	// 站点配置（迁移期间）
+	installCmd.Flags().BoolVar(&noRedirect, "no-redirect", false, "HTTP 不重定向到 HTTPS，80 端口继续提供站点（续期时沿用，--no-redirect=false 恢复重定向）")
+	installCmd.Flags().BoolVar(&stagingDeploy, "staging-deploy", false, "站点配置写入 <站点配置>.disabled 且不启用，确认后 autocert activate 启用")
+
 	// 标记必需参数
 	installCmd.MarkFlagRequired("email")
 }
 
 func runInstall(cmd *cobra.Command, args []string) error {
+	noRedirectSet = cmd.Flags().Changed("no-redirect")
+	expectMultiHostSet = cmd.Flags().Changed("expect-multi-host")
+	webserver.SetDriftReporter(printDrift)
+
 	// 解析域名列表
 	domainList, err := parseDomains()
 	if err != nil {
@@ -80,12 +160,83 @@ func runInstall(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("参数验证失败: %w", err)
 	}
 
+	// 检查将要执行的变更，--check 时不执行
+	if installCheck || installDiff {
+		changed, err := planInstall(domainList)
+		if err != nil {
+			return err
+		}
+		if installCheck {
+			if changed {
+				exitCode = exitChangesNeeded
+			} else {
+				fmt.Println("无变更")
+			}
+			return nil
+		}
+		if !changed {
+			console.Success("证书和站点配置已是最新，无需变更")
+			return nil
+		}
+	}
+
+	// 停用其他客户端的自动续期属于破坏性操作，开启审批时需要先批准
+	var takeoverApproval *approval.Request
+	if takeover {
+		approved, proceed, err := requireApproval(approval.ActionTakeover, strings.Join(domainList, ","), installApprovalID)
+		if !proceed {
+			return err
+		}
+		takeoverApproval = approved
+	}
+
+	// 检查是否有其他 ACME 客户端管理相同的域名
+	foreign := checkOtherACMEClients(domainList, takeover)
+
 	// 如果只有一个域名，使用单域名管理器
 	if len(domainList) == 1 {
-		return installSingleDomain(domainList[0])
+		err = installSingleDomain(domainList[0])
 	} else {
 		// 多域名证书，使用多域名管理器
-		return installMultiDomain(domainList)
+		err = installMultiDomain(domainList)
+	}
+
+	// 证书安装成功后才停用其他客户端的自动续期，安装失败时原有证书继续由它们续期
+	if err == nil && takeover {
+		err = takeOverRenewal(foreign)
+		if err == nil {
+			completeApproval(takeoverApproval)
+		}
+	}
+	if err == nil && installAssess {
+		assessAfterInstall(domainList)
+	}
+	return err
+}
+
+// assessAfterInstall 部署完成后评估本机的 TLS 配置，评估结果只作为提示，不影响安装结果
+func assessAfterInstall(domainList []string) {
+	var target string
+	for _, d := range domainList {
+		if !strings.HasPrefix(d, "*.") {
+			target = d
+			break
+		}
+	}
+	if target == "" {
+		console.Warn("泛域名证书无法确定评估的主机名，请使用 autocert assess --domain <主机名> 评估")
+		return
+	}
+
+	report, err := assess.Run(assess.Options{Domain: target, Host: "127.0.0.1"})
+	if err != nil {
+		console.Warn("TLS 配置评估失败: %v", err)
+		return
+	}
+
+	fmt.Printf("\nTLS 配置评级: %s（%s）\n", report.Grade, report.Negotiated)
+	for _, finding := range report.Findings {
+		fmt.Printf("  [%s] %s\n      %s\n", finding.Check, finding.Message, finding.Remediation)
 	}
 }
 
@@ -93,7 +244,19 @@ func runInstall(cmd *cobra.Command, args []string) error {
 func installSingleDomain(domain string) error {
 	logger.Info("安装单域名证书", "domain", domain)
 
-	// 创建证书管理器
+	// 申请并安装证书
+	if err := newSingleDomainManager(domain).Install(); err != nil {
+		logger.Error("证书安装失败", "domain", domain, "error", err)
+		return fmt.Errorf("域名 %s 证书安装失败: %w", domain, err)
+	}
+
+	logger.Info("证书安装成功", "domain", domain)
+	console.Success("域名 %s 证书安装成功", domain)
+	return nil
+}
+
+// newSingleDomainManager 按命令行参数创建单域名证书管理器
+func newSingleDomainManager(domain string) *cert.Manager {
 	certManager := cert.NewManager(domain, email)
 
 	// 设置验证模式
@@ -110,6 +273,36 @@ func installSingleDomain(domain string) error {
 		certManager.SetChallengeType(cert.ChallengeWebroot)
 	}
 
+	// 域名文件中为该域名指定的验证方式优先于命令行参数
+	if challengeType, webrootPath, ok := memberChallenge(domain); ok {
+		certManager.SetChallengeType(challengeType)
+		if challengeType == cert.ChallengeWebroot {
+			if webrootPath == "" {
+				webrootPath = webroot
+			}
+			certManager.SetWebrootPath(webrootPath)
+		}
+	}
+
+	if dualCert {
+		certManager.SetDualCert(true)
+	}
+	if preferredChain != "" {
+		certManager.KeepPreferredChain(preferredChain)
+	}
+	if expectMultiHostSet {
+		certManager.SetExpectMultiHost(expectMultiHost)
+	}
+	if overwriteDrift {
+		certManager.SetOverwriteDrift(true)
+	}
+	if noRedirectSet {
+		certManager.SetNoRedirect(noRedirect)
+	}
+	if stagingDeploy {
+		certManager.SetStagingDeploy(true)
+	}
+
 	// 设置 Web 服务器类型
 	if nginx {
 		certManager.SetWebServer(cert.WebServerNginx)
@@ -119,39 +312,41 @@ func installSingleDomain(domain string) error {
 		certManager.SetWebServer(cert.WebServerIIS)
 	}
 
-	// 申请并安装证书
-	if err := certManager.Install(); err != nil {
-		logger.Error("证书安装失败", "domain", domain, "error", err)
-		return fmt.Errorf("域名 %s 证书安装失败: %w", domain, err)
-	}
-
-	logger.Info("证书安装成功", "domain", domain)
-	fmt.Printf("✓ 域名 %s 证书安装成功\n", domain)
-	return nil
+	return certManager
 }
 
 // installMultiDomain 安装多域名证书（SAN证书）
 func installMultiDomain(domains []string) error {
 	logger.Info("安装多域名证书", "domains", domains, "count", len(domains))
 
-	// 创建多域名证书管理器
-	multiManager := cert.NewMultiDomainManager(domains, email)
+	multiManager := newMultiDomainManager(domains)
 	if multiManager == nil {
 		return fmt.Errorf("创建多域名管理器失败")
 	}
 
-	// 设置验证模式
-	hasWildcard := false
-	for _, d := range domains {
-		if strings.HasPrefix(d, "*.") {
-			hasWildcard = true
-			break
-		}
+	// 申请并安装多域名证书
+	if err := multiManager.Install(); err != nil {
+		logger.Error("多域名证书安装失败", "domains", domains, "error", err)
+		return fmt.Errorf("多域名证书安装失败: %w", err)
 	}
 
-	if dnsChallenge || hasWildcard {
+	logger.Info("多域名证书安装成功", "domains", domains)
+	console.Success("多域名证书安装成功，包含 %d 个域名: %s", len(domains), strings.Join(domains, ", "))
+	return nil
+}
+
+// newMultiDomainManager 按命令行参数创建多域名证书管理器
+func newMultiDomainManager(domains []string) *cert.MultiDomainManager {
+	multiManager := cert.NewMultiDomainManager(domains, email)
+	if multiManager == nil {
+		return nil
+	}
+
+	// 设置验证模式
+	// 泛域名成员总是通过 DNS 验证，其余成员按授权单独选择验证方式
+	if dnsChallenge {
 		multiManager.SetChallengeType(cert.ChallengeDNS)
-		logger.Info("使用 DNS 验证模式", "reason", "多域名或包含泛域名")
+		logger.Info("使用 DNS 验证模式", "domains", domains)
 	} else if standalone {
 		multiManager.SetChallengeType(cert.ChallengeStandalone)
 	} else if webroot != "" {
@@ -161,6 +356,30 @@ func installMultiDomain(domains []string) error {
 		// 默认尝试 webroot 模式
 		multiManager.SetChallengeType(cert.ChallengeWebroot)
 	}
+	for _, d := range domains {
+		if challengeType, webrootPath, ok := memberChallenge(d); ok {
+			multiManager.SetMemberChallenge(d, challengeType, webrootPath)
+		}
+	}
+
+	if dualCert {
+		multiManager.SetDualCert(true)
+	}
+	if preferredChain != "" {
+		multiManager.KeepPreferredChain(preferredChain)
+	}
+	if expectMultiHostSet {
+		multiManager.SetExpectMultiHost(expectMultiHost)
+	}
+	if overwriteDrift {
+		multiManager.SetOverwriteDrift(true)
+	}
+	if noRedirectSet {
+		multiManager.SetNoRedirect(noRedirect)
+	}
+	if stagingDeploy {
+		multiManager.SetStagingDeploy(true)
+	}
 
 	// 设置 Web 服务器类型
 	if nginx {
@@ -171,23 +390,66 @@ func installMultiDomain(domains []string) error {
 		multiManager.SetWebServer(cert.WebServerIIS)
 	}
 
-	// 申请并安装多域名证书
-	if err := multiManager.Install(); err != nil {
-		logger.Error("多域名证书安装失败", "domains", domains, "error", err)
-		return fmt.Errorf("多域名证书安装失败: %w", err)
+	return multiManager
+}
+
+// planInstall 检查安装会产生的变更并输出（--diff 时包括差异），返回是否需要变更
+func planInstall(domainList []string) (bool, error) {
+	var changes []cert.Change
+	var err error
+	if len(domainList) == 1 {
+		changes, err = newSingleDomainManager(domainList[0]).Plan()
+	} else {
+		changes, err = newMultiDomainManager(domainList).Plan()
+	}
+	if err != nil {
+		return false, fmt.Errorf("检查变更失败: %w", err)
 	}
 
-	logger.Info("多域名证书安装成功", "domains", domains)
-	fmt.Printf("✓ 多域名证书安装成功，包含 %d 个域名: %s\n", len(domains), strings.Join(domains, ", "))
-	return nil
+	if takeover {
+		for _, client := range system.DetectACMEClients() {
+			for _, foreign := range client.Overlapping(domainList) {
+				changes = append(changes, cert.Change{
+					Action: "takeover",
+					Target: foreign.Name,
+					Reason: fmt.Sprintf("停用 %s 的自动续期", client.Name),
+				})
+			}
+		}
+	}
+
+	for _, change := range changes {
+		fmt.Printf("%s %s: %s\n", console.Colorize(os.Stdout, console.Yellow, change.Action), change.Target, change.Reason)
+		if installDiff && change.Diff != "" {
+			fmt.Print(change.Diff)
+		}
+	}
+	return len(changes) > 0, nil
 }
 
 // parseDomains 解析域名列表
 func parseDomains() ([]string, error) {
 	var domainList []string
 
-	// 如果指定了 domains 参数，优先使用
-	if domains != "" {
+	if domainsFile != "" {
+		if domains != "" || domain != "" {
+			return nil, fmt.Errorf("--domains-file 不能与 --domain、--domains 同时使用")
+		}
+		entries, err := domainlist.Load(domainsFile)
+		if err != nil {
+			return nil, err
+		}
+		domainList = domainlist.Domains(entries)
+		domainOptions = make(map[string]domainlist.Entry)
+		for _, entry := range entries {
+			for _, d := range entry.Domains {
+				if _, ok := domainOptions[d]; !ok {
+					domainOptions[d] = entry
+				}
+			}
+		}
+	} else if domains != "" {
+		// 如果指定了 domains 参数，优先使用
 		domainList = strings.Split(domains, ",")
 		for i, d := range domainList {
 			domainList[i] = strings.TrimSpace(d)
@@ -196,7 +458,7 @@ func parseDomains() ([]string, error) {
 		// 否则使用单个 domain 参数
 		domainList = []string{strings.TrimSpace(domain)}
 	} else {
-		return nil, fmt.Errorf("必须指定 --domain 或 --domains 参数")
+		return nil, fmt.Errorf("必须指定 --domain、--domains 或 --domains-file 参数")
 	}
 
 	// 验证域名格式
@@ -212,6 +474,30 @@ func parseDomains() ([]string, error) {
 	return domainList, nil
 }
 
+// domainOptions 域名文件中各域名所在行的选项
+var domainOptions map[string]domainlist.Entry
+
+// memberChallenge 域名文件中为该域名指定的验证方式和网站根目录，未指定时 ok 为 false
+func memberChallenge(domain string) (challengeType cert.ChallengeType, webrootPath string, ok bool) {
+	entry := domainOptions[domain]
+	challengeType, ok = entryChallenge(entry)
+	return challengeType, entry.Webroot, ok
+}
+
+// entryChallenge 域名文件中一行指定的验证方式，未指定时 ok 为 false
+func entryChallenge(entry domainlist.Entry) (cert.ChallengeType, bool) {
+	switch entry.Challenge {
+	case "":
+		return cert.ChallengeWebroot, false
+	case domainlist.ChallengeDNS:
+		return cert.ChallengeDNS, true
+	case domainlist.ChallengeStandalone:
+		return cert.ChallengeStandalone, true
+	default:
+		return cert.ChallengeWebroot, true
+	}
+}
+
 // validateDomainName 验证域名格式
 func validateDomainName(domain string) error {
 	// 基本的域名格式验证
@@ -269,7 +555,11 @@ func validateInstallFlags(domainList []string) error {
 		}
 	}
 
-	if hasWildcard && !dnsChallenge {
+	// 单个泛域名证书只能通过 DNS 验证；SAN 证书中的泛域名成员会单独使用 dns-01
+	if challengeType, _, ok := memberChallenge(domainList[0]); ok && challengeType == cert.ChallengeDNS {
+		hasWildcard = false
+	}
+	if hasWildcard && !dnsChallenge && len(domainList) == 1 {
 		return fmt.Errorf("泛域名证书必须使用 DNS 验证模式，请添加 --dns 参数")
 	}
 
@@ -289,5 +579,53 @@ func validateInstallFlags(domainList []string) error {
 		return fmt.Errorf("只能指定一种验证模式: --standalone, --webroot, 或 --dns")
 	}
 
+	if dualCert {
+		if err := features.Require(features.DualCert, "--dual-cert"); err != nil {
+			return err
+		}
+	}
+
+	// IIS 不生成站点配置
+	if iis && (noRedirect || stagingDeploy) {
+		return fmt.Errorf("--no-redirect 和 --staging-deploy 只适用于 Nginx 和 Apache")
+	}
+
+	return nil
+}
+
+// checkOtherACMEClients 检测 certbot/acme.sh 是否也在管理这些域名，返回它们管理的证书
+// 未指定 --takeover 时只给出警告；指定时由 takeOverRenewal 在安装成功后停用这些证书的自动续期
+func checkOtherACMEClients(domainList []string, takeover bool) []system.ForeignCert {
+	var foreign []system.ForeignCert
+	for _, client := range system.DetectACMEClients() {
+		overlapping := client.Overlapping(domainList)
+		if len(overlapping) == 0 {
+			continue
+		}
+		foreign = append(foreign, overlapping...)
+
+		if takeover {
+			continue
+		}
+		for _, cert := range overlapping {
+			console.Warn("域名 %s 同时由 %s 管理（%s），两个工具会重复续期，可使用 --takeover 停用 %s 的自动续期",
+				strings.Join(cert.Domains, ","), client.Name, cert.ConfigPath, client.Name)
+		}
+		if len(client.Scheduled) > 0 {
+			console.Warn("%s 的自动续期任务: %s", client.Name, strings.Join(client.Scheduled, ", "))
+		}
+	}
+	return foreign
+}
+
+// takeOverRenewal 停用其他客户端对这些证书的自动续期（install --takeover）
+func takeOverRenewal(foreign []system.ForeignCert) error {
+	for _, cert := range foreign {
+		if err := system.DisableRenewal(cert); err != nil {
+			return fmt.Errorf("证书已安装，但停用 %s 对 %s 的自动续期失败: %w", cert.Client, cert.Name, err)
+		}
+		logger.Info("已停用其他客户端的自动续期", "client", cert.Client, "cert", cert.Name, "config", cert.ConfigPath)
+		console.Success("已停用 %s 对 %s 的自动续期", cert.Client, cert.Name)
+	}
 	return nil
 }
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var linkCmd = &cobra.Command{
+	Use:   "link <证书名或域名> <目录>",
+	Short: "在其他服务的目录中创建证书符号链接",
+	Long: `在指定目录中创建指向证书文件的符号链接，让多个虚拟主机或服务共享同一张证书。
+证书续期后所有链接自动指向新证书，无需重新复制。
+
+配合 storage.layout: canonical 使用时，证书文件保存在证书目录下的 .store 证书库中，
+证书目录和链接位置都只保存符号链接；旧版本由 autocert gc 清理。
+
+示例:
+  autocert link example.com /etc/postfix/certs
+  autocert link example.com_san /opt/app/tls`,
+	Args: cobra.ExactArgs(2),
+	RunE: runLink,
+}
+
+func init() {
+	rootCmd.AddCommand(linkCmd)
+}
+
+func runLink(cmd *cobra.Command, args []string) error {
+	stored, err := findStoredCert(args[0])
+	if err != nil {
+		return err
+	}
+
+	linked, err := stored.LinkConsumer(args[1])
+	if err != nil {
+		return fmt.Errorf("创建证书链接失败: %w", err)
+	}
+
+	for _, path := range linked {
+		fmt.Printf("  %s\n", path)
+	}
+	console.Success("已在 %s 中创建 %d 个证书链接", args[1], len(linked))
+	return nil
+}
+
+// findStoredCert 按证书目录名或包含的域名查找已保存的证书
+func findStoredCert(name string) (*cert.StoredCert, error) {
+	certs, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		return nil, fmt.Errorf("读取证书目录失败: %w", err)
+	}
+
+	for i := range certs {
+		if certs[i].Name == name {
+			return &certs[i], nil
+		}
+	}
+	for i := range certs {
+		for _, domain := range certs[i].Domains {
+			if domain == name {
+				return &certs[i], nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("未找到证书: %s", name)
+}
@@ -1,11 +1,19 @@
 package cmd
 
 import (
+	"autocert/internal/agent"
 	"autocert/internal/cert"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/console"
 	"autocert/internal/logger"
+	"autocert/internal/renewal"
+	"autocert/internal/report"
 	"autocert/internal/scheduler"
+	"autocert/internal/webserver"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -16,10 +24,16 @@ var renewCmd = &cobra.Command{
 	Short: "续期证书",
 	Long: `检查并续期即将到期的证书。
 
+续期所有证书后会输出汇总，并将 JSON 报告写入日志目录下的 autocert-renewal.json。
+退出码: 0 成功（包括没有需要续期的证书）, 1 执行出错, 2 部分证书续期失败；
+指定 --renewed-exit-code 时有证书续期成功且没有失败的退出码为 3，便于脚本区分是否发生了续期。
+
 示例:
-  autocert renew                    # 续期所有证书
+  autocert renew                    # 续期所有到期证书
   autocert renew --domain example.com  # 续期指定域名的证书
-  autocert renew --all              # 强制续期所有证书`,
+  autocert renew --all              # 检查并续期所有到期证书
+  autocert renew --all --force      # 强制续期所有证书
+  autocert renew --days 15          # 剩余有效期少于 15 天时续期`,
 	RunE: runRenew,
 }
 
@@ -28,9 +42,14 @@ var statusCmd = &cobra.Command{
 	Short: "查看证书状态",
 	Long: `显示已安装证书的状态信息。
 
+支持导出证书清单（域名、SAN、颁发者、序列号、到期时间、密钥类型、部署位置），
+便于提供给不使用命令行的审计或管理人员。
+
 示例:
   autocert status                   # 显示所有证书状态
-  autocert status --domain example.com # 显示指定域名证书状态`,
+  autocert status --domain example.com # 显示指定域名证书状态
+  autocert status --format csv --output certs.csv   # 导出 CSV 清单
+  autocert status --format html --output certs.html # 导出 HTML 清单`,
 	RunE: runStatus,
 }
 
@@ -43,13 +62,22 @@ var scheduleCmd = &cobra.Command{
   install   安装定时任务
   remove    删除定时任务
   list      列出定时任务
-  status    查看任务状态`,
+  verify    检查定时任务是否仍然有效`,
 }
 
 var scheduleInstallCmd = &cobra.Command{
 	Use:   "install",
 	Short: "安装定时任务",
-	RunE:  runScheduleInstall,
+	Long: `安装证书自动续期定时任务。
+
+--mode service（仅 Windows）将 AutoCert 注册为自动启动的 Windows 服务，
+由守护进程的内部定时器（daemon.interval）检查并续期证书，不依赖任务计划程序，
+适用于组策略禁止 schtasks 的服务器。
+
+示例:
+  autocert schedule install
+  autocert schedule install --mode service --config C:\autocert\autocert.yaml`,
+	RunE: runScheduleInstall,
 }
 
 var scheduleRemoveCmd = &cobra.Command{
@@ -64,11 +92,30 @@ var scheduleListCmd = &cobra.Command{
 	RunE:  runScheduleList,
 }
 
+var scheduleVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "检查定时任务是否仍然有效",
+	Long: `检查已安装的 cron/systemd/Windows 任务计划是否仍然指向当前程序路径、
+语法是否正确以及是否启用。程序升级或移动后旧任务会静默失效，导致证书不再续期。
+
+使用 --repair 重新安装有问题的任务。`,
+	RunE: runScheduleVerify,
+}
+
 var (
 	renewDomain  string
 	renewAll     bool
+	renewForce   bool
+	renewDays    int
+	renewChain   string
+	renewDrift   bool
+	renewExit3   bool
 	statusDomain string
+	statusFormat string
+	statusOutput string
 	taskName     string
+	taskMode     string
+	repairTask   bool
 )
 
 func init() {
@@ -78,23 +125,35 @@ func init() {
 
 	// renew 命令参数
 	renewCmd.Flags().StringVarP(&renewDomain, "domain", "d", "", "要续期的域名")
-	renewCmd.Flags().BoolVar(&renewAll, "all", false, "强制续期所有证书")
+	renewCmd.Flags().BoolVar(&renewAll, "all", false, "检查并续期所有证书")
+	renewCmd.Flags().BoolVar(&renewForce, "force", false, "忽略续期阈值，强制续期")
+	renewCmd.Flags().IntVar(&renewDays, "days", 0, "剩余有效期少于该天数时续期（默认使用配置 renew_before_days）")
+	renewCmd.Flags().StringVar(&renewChain, "preferred-chain", "", "首选证书链的根证书名称，例如 \"ISRG Root X1\"（默认使用配置 acme.preferred_chain）")
+	renewCmd.Flags().BoolVar(&renewDrift, "overwrite-drift", false, "覆盖上次生成后被手工修改的站点配置")
+	renewCmd.Flags().BoolVar(&renewExit3, "renewed-exit-code", false, "有证书续期成功时以退出码 3 结束（默认 0）")
 
 	// status 命令参数
 	statusCmd.Flags().StringVarP(&statusDomain, "domain", "d", "", "要查看的域名")
+	statusCmd.Flags().StringVar(&statusFormat, "format", report.FormatTable, "输出格式: table, csv, html")
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "", "清单输出文件（默认输出到标准输出）")
 
 	// schedule 子命令
 	scheduleCmd.AddCommand(scheduleInstallCmd)
 	scheduleCmd.AddCommand(scheduleRemoveCmd)
 	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleVerifyCmd)
 
 	// schedule 命令参数
-	scheduleInstallCmd.Flags().StringVar(&taskName, "name", "autocert-renew", "任务名称")
-	scheduleRemoveCmd.Flags().StringVar(&taskName, "name", "autocert-renew", "任务名称")
+	scheduleInstallCmd.Flags().StringVar(&taskName, "name", scheduler.DefaultTaskName, "任务名称")
+	scheduleInstallCmd.Flags().StringVar(&taskMode, "mode", scheduler.ModeTask, "安装方式: task（定时任务）, service（Windows 服务）")
+	scheduleRemoveCmd.Flags().StringVar(&taskName, "name", scheduler.DefaultTaskName, "任务名称")
+	scheduleVerifyCmd.Flags().StringVar(&taskName, "name", scheduler.DefaultTaskName, "任务名称")
+	scheduleVerifyCmd.Flags().BoolVar(&repairTask, "repair", false, "重新安装有问题的任务")
 }
 
 func runRenew(cmd *cobra.Command, args []string) error {
-	logger.Info("开始证书续期", "domain", renewDomain, "forceAll", renewAll)
+	logger.Info("开始证书续期", "domain", renewDomain, "all", renewAll, "force", renewForce)
+	webserver.SetDriftReporter(printDrift)
 
 	if renewDomain != "" {
 		// 续期指定域名
@@ -108,6 +167,11 @@ func runRenew(cmd *cobra.Command, args []string) error {
 func runStatus(cmd *cobra.Command, args []string) error {
 	logger.Info("查看证书状态", "domain", statusDomain)
 
+	if statusFormat != report.FormatTable {
+		// 导出证书清单
+		return exportInventory(statusFormat, statusOutput)
+	}
+
 	if statusDomain != "" {
 		// 显示指定域名状态
 		return showDomainStatus(statusDomain)
@@ -118,7 +182,10 @@ func runStatus(cmd *cobra.Command, args []string) error {
 }
 
 func runScheduleInstall(cmd *cobra.Command, args []string) error {
-	logger.Info("安装定时任务", "taskName", taskName)
+	if err := scheduler.ValidateMode(taskMode); err != nil {
+		return err
+	}
+	logger.Info("安装定时任务", "taskName", taskName, "mode", taskMode)
 
 	// 获取当前执行文件路径
 	execPath, err := os.Executable()
@@ -126,21 +193,40 @@ func runScheduleInstall(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("获取执行文件路径失败: %w", err)
 	}
 
+	if taskMode == scheduler.ModeService {
+		return installRenewalService(execPath)
+	}
+
 	// 创建调度器
 	sched := scheduler.NewScheduler()
 
 	// 安装任务（每日凌晨2点检查）
-	schedule := "0 2 * * *" // cron 格式
-	if err := sched.Install(taskName, execPath, schedule); err != nil {
+	if err := sched.Install(taskName, execPath, scheduler.DefaultSchedule); err != nil {
 		return fmt.Errorf("安装定时任务失败: %w", err)
 	}
 
-	fmt.Printf("✓ 定时任务 '%s' 安装成功\n", taskName)
+	console.Success("定时任务 '%s' 安装成功", taskName)
 	fmt.Println("任务将在每日凌晨2点自动检查并续期证书")
 
 	return nil
 }
 
+// installRenewalService 以 Windows 服务方式安装续期任务
+func installRenewalService(execPath string) error {
+	configFile := configFileUsed()
+	if configFile == "" {
+		console.Warn("未使用配置文件，服务将以 LocalSystem 账户的默认配置运行")
+	}
+
+	if err := scheduler.InstallService(taskName, execPath, scheduler.ServiceArgs(configFile)); err != nil {
+		return fmt.Errorf("安装服务失败: %w", err)
+	}
+
+	console.Success("服务 '%s' 安装成功", taskName)
+	fmt.Printf("服务将开机自动启动，每 %s 检查并续期证书\n", config.GetDaemonConfig().Interval)
+	return nil
+}
+
 func runScheduleRemove(cmd *cobra.Command, args []string) error {
 	logger.Info("删除定时任务", "taskName", taskName)
 
@@ -152,7 +238,7 @@ func runScheduleRemove(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("删除定时任务失败: %w", err)
 	}
 
-	fmt.Printf("✓ 定时任务 '%s' 删除成功\n", taskName)
+	console.Success("定时任务 '%s' 删除成功", taskName)
 	return nil
 }
 
@@ -175,36 +261,118 @@ func runScheduleList(cmd *cobra.Command, args []string) error {
 
 	// 显示任务列表
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "任务名称\t状态\t下次运行\t上次运行")
-	fmt.Fprintln(w, "--------\t----\t--------\t--------")
+	fmt.Fprintln(w, "任务名称\t状态\t下次运行\t上次运行\t上次结果")
+	fmt.Fprintln(w, "--------\t----\t--------\t--------\t--------")
 
 	for _, task := range tasks {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", task.Name, task.Status, task.NextRun, task.LastRun)
+		result := task.LastResult
+		if result == "" {
+			result = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", task.Name, task.Status, task.NextRunText(), task.LastRunText(), result)
 	}
 
 	w.Flush()
 	return nil
 }
 
+func runScheduleVerify(cmd *cobra.Command, args []string) error {
+	logger.Info("检查定时任务", "taskName", taskName, "repair", repairTask)
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取执行文件路径失败: %w", err)
+	}
+
+	sched := scheduler.NewScheduler()
+	result, err := sched.Verify(taskName, execPath)
+	if err != nil {
+		return fmt.Errorf("检查定时任务失败: %w", err)
+	}
+
+	if result.OK() {
+		console.Success("定时任务 '%s' 正常（%s，%s）", taskName, result.Backend, result.Command)
+		return nil
+	}
+
+	if !result.Installed {
+		console.Warn("定时任务 '%s' 未安装（%s）", taskName, result.Backend)
+	} else {
+		console.Error("定时任务 '%s' 存在问题（%s）:", taskName, result.Backend)
+		for _, problem := range result.Problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+	}
+
+	if !repairTask {
+		return fmt.Errorf("定时任务 '%s' 无效，使用 --repair 重新安装", taskName)
+	}
+
+	// 保留原有的有效 cron 表达式
+	schedule := scheduler.DefaultSchedule
+	if result.Schedule != "" && scheduler.ValidateCronExpression(result.Schedule) == nil {
+		schedule = result.Schedule
+	}
+
+	if result.Backend == scheduler.ModeService {
+		return installRenewalService(execPath)
+	}
+
+	if result.Installed {
+		if err := sched.Remove(taskName); err != nil {
+			logger.Warn("删除旧定时任务失败", "taskName", taskName, "error", err)
+		}
+	}
+	if err := sched.Install(taskName, execPath, schedule); err != nil {
+		return fmt.Errorf("重新安装定时任务失败: %w", err)
+	}
+
+	console.Success("定时任务 '%s' 已重新安装（%s）", taskName, execPath)
+	return nil
+}
+
 func renewDomainCert(domain string) error {
 	// 创建证书管理器
 	certManager := cert.NewManager(domain, "")
+	if renewDays > 0 {
+		certManager.SetRenewBeforeDays(renewDays)
+	}
+	if renewChain != "" {
+		certManager.SetPreferredChain(renewChain)
+	}
+	certManager.SetOverwriteDrift(renewDrift)
 
 	// 续期证书
-	if err := certManager.Renew(); err != nil {
+	var err error
+	if renewForce {
+		err = certManager.Install()
+	} else {
+		err = certManager.Renew()
+	}
+	if err != nil {
 		return fmt.Errorf("域名 %s 证书续期失败: %w", domain, err)
 	}
 
-	fmt.Printf("✓ 域名 %s 证书续期成功\n", domain)
+	console.Success("域名 %s 证书续期成功", domain)
 	return nil
 }
 
 func renewAllCerts() error {
-	// 这里应该遍历所有已安装的证书进行续期
-	// 为简化演示，这里只是打印消息
+	renewalReport, err := renewal.RenewAll(renewal.Options{
+		Force:           renewForce,
+		RenewBeforeDays: renewDays,
+		PreferredChain:  renewChain,
+		OverwriteDrift:  renewDrift,
+
+		RenewedExitCode: renewExit3,
+	})
+	if err != nil {
+		return err
+	}
 
-	logger.Info("开始续期所有证书")
-	fmt.Println("✓ 所有证书续期检查完成")
+	renewalReport.Print(os.Stdout)
+	exitCode = renewalReport.ExitCode
+	agentCheckIn()
 	return nil
 }
 
@@ -222,7 +390,7 @@ func showDomainStatus(domain string) error {
 	fmt.Printf("域名: %s\n", certInfo.Domain)
 	fmt.Printf("证书路径: %s\n", certInfo.CertPath)
 	fmt.Printf("私钥路径: %s\n", certInfo.KeyPath)
-	fmt.Printf("到期时间: %s\n", certInfo.ExpiryDate.Format("2006-01-02 15:04:05"))
+	fmt.Printf("到期时间: %s\n", clock.FormatRelative(certInfo.ExpiryDate))
 
 	if certInfo.IsValid {
 		fmt.Printf("状态: ✓ 有效\n")
@@ -234,16 +402,79 @@ func showDomainStatus(domain string) error {
 }
 
 func showAllStatus() error {
-	// 这里应该遍历所有已安装的证书显示状态
-	// 为简化演示，这里只是显示表头
+	rows, err := loadInventory()
+	if err != nil {
+		return err
+	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "域名\t状态\t到期时间\t剩余天数")
-	fmt.Fprintln(w, "----\t----\t--------\t--------")
+	if len(rows) == 0 {
+		fmt.Println("尚未安装任何证书")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "域名\t状态\t到期时间\t剩余")
+		fmt.Fprintln(w, "----\t----\t--------\t----")
 
-	// 这里应该有实际的证书信息
-	fmt.Fprintln(w, "example.com\t有效\t2024-12-31\t30天")
+		for _, row := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", strings.Join(row.SANs, ","), row.Status(),
+				clock.Format(row.NotAfter), clock.Relative(row.NotAfter))
+		}
 
-	w.Flush()
+		w.Flush()
+	}
+
+	if stale := staleAgentNames(); len(stale) > 0 {
+		fmt.Println()
+		console.Warn("%d 个 agent 超过 %s 没有签到: %s（autocert agents list 查看）",
+			len(stale), agent.StaleAfter(), strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// loadInventory 读取证书目录下所有证书的清单信息
+func loadInventory() ([]report.InventoryRow, error) {
+	certs, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		return nil, fmt.Errorf("读取证书目录失败: %w", err)
+	}
+
+	var rows []report.InventoryRow
+	for _, stored := range certs {
+		details, err := stored.Details()
+		if err != nil {
+			logger.Warn("读取证书失败", "name", stored.Name, "error", err)
+			continue
+		}
+		rows = append(rows, report.NewInventoryRow(details))
+	}
+
+	return rows, nil
+}
+
+// exportInventory 导出证书清单
+func exportInventory(format, output string) error {
+	if format != report.FormatCSV && format != report.FormatHTML {
+		return fmt.Errorf("不支持的输出格式: %s（可选 table, csv, html）", format)
+	}
+
+	rows, err := loadInventory()
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return report.WriteInventory(os.Stdout, format, rows)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("创建输出文件失败: %w", err)
+	}
+	defer f.Close()
+
+	if err := report.WriteInventory(f, format, rows); err != nil {
+		return fmt.Errorf("导出证书清单失败: %w", err)
+	}
+
+	fmt.Printf("证书清单已导出: %s (%d 张证书)\n", output, len(rows))
 	return nil
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/preflight"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var pingCACmd = &cobra.Command{
+	Use:   "ping-ca",
+	Short: "检查本机到 ACME 服务器的连通性和延迟",
+	Long: `检查本机到 ACME 服务器（acme.server）的连通性：
+  - 域名解析：CA 域名解析到的 IPv4/IPv6 地址
+  - ACME 目录：多次请求目录，输出延迟（最小/平均/最大）及解析、TCP 连接、TLS 握手、首字节的耗时
+  - TLS 证书信任：CA 的证书链是否被系统根证书信任，不受信任时显示实际收到证书的颁发者
+    （HTTPS 拦截代理和安全软件通常会替换颁发者）
+  - 目录中的接口：newNonce、newAccount、newOrder、revokeCert、keyChange 是否可达
+  - CA 状态页：无法访问 CA 时，根据状态页区分"本机网络无法访问 CA"和"CA 故障"
+
+Let's Encrypt 默认使用其状态页，其他 CA 在 acme.status_url 中配置状态页接口
+（Atlassian Statuspage 的 /api/v2/status.json 或 status.io 的状态接口）。
+遵循 HTTPS_PROXY/NO_PROXY 环境变量。无法访问 ACME 目录时退出码为 1。
+
+示例:
+  autocert ping-ca
+  autocert ping-ca --count 10
+  autocert ping-ca --server https://acme-staging-v02.api.letsencrypt.org/directory`,
+	Args: cobra.NoArgs,
+	RunE: runPingCA,
+}
+
+var (
+	pingCAServer  string
+	pingCACount   int
+	pingCATimeout time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(pingCACmd)
+
+	pingCACmd.Flags().StringVar(&pingCAServer, "server", "", "ACME 目录地址（默认使用配置 acme.server）")
+	pingCACmd.Flags().IntVarP(&pingCACount, "count", "c", 3, "请求 ACME 目录的次数")
+	pingCACmd.Flags().DurationVar(&pingCATimeout, "timeout", 10*time.Second, "每个请求的超时时间")
+}
+
+func runPingCA(cmd *cobra.Command, args []string) error {
+	server := pingCAServer
+	if server == "" {
+		server = config.GetACMEConfig().Server
+	}
+	fmt.Printf("ACME 服务器: %s\n\n", server)
+
+	report := preflight.PingCA(server, pingCACount, pingCATimeout)
+	for _, result := range report.Results {
+		printCheckResult(result)
+	}
+
+	fmt.Println()
+	if !report.Reachable {
+		console.Error("%s", report.Diagnosis)
+		return errors.New("无法访问 ACME 服务器")
+	}
+	if report.Degraded {
+		console.Warn("%s", report.Diagnosis)
+		return nil
+	}
+	console.Success("%s", report.Diagnosis)
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"autocert/internal/console"
+	"autocert/internal/provision"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var provisionCmd = &cobra.Command{
+	Use:   "provision",
+	Short: "首次启动自动配置（cloud-init）",
+	Long: `非交互式首次启动配置，适合在 cloud-init/user-data 中执行一次:
+
+  1. 等待域名解析生效（provision.dns_timeout；配置 provision.expected_ips 时等待解析到这些地址）
+  2. 为所有配置的域名签发证书并配置 Web 服务器
+  3. 安装续期定时任务
+  4. 将执行结果上报到 provision.webhook
+
+成功后写入标记文件（默认为配置目录下的 provisioned），再次执行时直接跳过。
+
+示例:
+  autocert provision --config /etc/autocert/bootstrap.yaml
+  autocert provision --config /etc/autocert/bootstrap.yaml --force`,
+	RunE: runProvision,
+}
+
+var provisionOptions provision.Options
+
+func init() {
+	rootCmd.AddCommand(provisionCmd)
+
+	provisionCmd.Flags().BoolVar(&provisionOptions.Force, "force", false, "忽略标记文件，重新执行")
+	provisionCmd.Flags().BoolVar(&provisionOptions.SkipDNSWait, "skip-dns-wait", false, "不等待域名解析")
+}
+
+func runProvision(cmd *cobra.Command, args []string) error {
+	report, err := provision.Run(provisionOptions)
+	if errors.Is(err, provision.ErrAlreadyProvisioned) {
+		fmt.Printf("已完成首次启动配置（%s），跳过；使用 --force 重新执行\n", provision.MarkerPath())
+		return nil
+	}
+	if report == nil {
+		return err
+	}
+
+	for _, result := range report.Certificates {
+		if result.Status == provision.StatusSuccess {
+			console.Success("%v", result.Domains)
+		} else {
+			console.Error("%v: %s", result.Domains, result.Error)
+		}
+	}
+	fmt.Printf("DNS 等待: %s  定时任务: %s\n", report.DNS, report.Schedule)
+
+	if err != nil {
+		return fmt.Errorf("首次启动配置失败: %w", err)
+	}
+	console.Success("首次启动配置完成（%s）", report.FinishedAt.Sub(report.StartedAt).Round(time.Second))
+	return nil
+}
@@ -1,17 +1,24 @@
 package cmd
 
 import (
+	"autocert/internal/clock"
 	"autocert/internal/config"
 	"autocert/internal/logger"
+	"autocert/internal/stats"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
-	rootCmd = &cobra.Command{
+	cfgFile  string
+	fakeNow  string
+	exitCode int // 命令成功执行时的进程退出码（如续期结果）
+	rootCmd  = &cobra.Command{
 		Use:   "autocert",
 		Short: "Let's Encrypt HTTPS 证书一键安装部署工具",
 		Long: `AutoCert 是一个跨平台的 Let's Encrypt HTTPS 证书管理工具，
@@ -20,12 +27,34 @@ var (
 支持的 Web 服务器：
 - Linux: Nginx, Apache
 - Windows: IIS, Nginx for Windows`,
+		// 错误由 main 统一输出到标准错误
+		SilenceErrors: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// 参数解析成功后，运行时错误不再打印用法说明
+			cmd.SilenceUsage = true
+		},
 	}
 )
 
 // Execute 执行根命令
 func Execute() error {
-	return rootCmd.Execute()
+	start := time.Now()
+	// 参数校验等在 ci issue 执行前失败时也删除临时状态目录
+	defer removeCITempState()
+	executed, err := rootCmd.ExecuteC()
+
+	// 记录命令耗时和结果（需开启 stats.enabled）
+	if executed != nil && executed != rootCmd && executed != statsCmd {
+		path := strings.TrimPrefix(executed.CommandPath(), rootCmd.Name()+" ")
+		stats.Record(stats.CommandOperation(path), time.Since(start), err)
+	}
+
+	return err
+}
+
+// ExitCode 返回命令成功执行后的退出码
+func ExitCode() int {
+	return exitCode
 }
 
 func init() {
@@ -34,9 +63,12 @@ func init() {
 	// 全局标志
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "配置文件路径 (默认搜索路径: $HOME/.autocert.yaml)")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "详细输出")
+	rootCmd.PersistentFlags().Bool("no-color", false, "禁用彩色输出")
+	rootCmd.PersistentFlags().StringVar(&fakeNow, "fake-now", "", "模拟当前时间（如 2025-12-01），仅影响到期和续期判断，用于验证续期阈值")
 
 	// 绑定标志到 viper
 	viper.BindPFlag("verbose", rootCmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag("no_color", rootCmd.PersistentFlags().Lookup("no-color"))
 }
 
 // initConfig 初始化配置
@@ -56,10 +88,65 @@ func initConfig() {
 
 	viper.AutomaticEnv()
 
-	if err := viper.ReadInConfig(); err == nil {
+	// 命令行参数解析后再设置日志级别和颜色
+	logger.Configure(viper.GetBool("verbose"), viper.GetBool("no_color"))
+
+	configRead := viper.ReadInConfig() == nil
+
+	// 严格校验使用的配置文件（--config 指定的或在搜索路径中找到的），未知配置项和类型错误带行号报告，
+	// 避免拼写错误被静默忽略；配置文件语法错误导致读取失败时也在这里报告
+	cobra.CheckErr(config.ValidateFile(viper.ConfigFileUsed()))
+
+	// 合并 include 中的配置文件
+	included, err := config.LoadIncludes()
+	cobra.CheckErr(err)
+
+	// ci 命令的所有状态保存在 --state 目录中，不使用系统路径；之后的步骤失败时删除创建的临时状态目录
+	checkConfigErr(applyCIState())
+
+	// 应用配置
+	checkConfigErr(config.Load())
+
+	// 在日志目录中打开日志文件
+	logger.SetupFile(config.GetLogDir())
+	if configRead {
 		logger.Info("使用配置文件", "config", viper.ConfigFileUsed())
 	}
+	for _, file := range included {
+		logger.Debug("合并配置文件", "config", file)
+	}
 
-	// 应用配置
-	config.Load()
+	// 显示时间使用的时区
+	if err := clock.SetLocation(config.GetTimezone()); err != nil {
+		logger.Warn("时区配置无效，使用系统本地时区", "error", err)
+	}
+
+	// 模拟时间（诊断用）
+	if fakeNow != "" {
+		t, err := clock.ParseFakeNow(fakeNow)
+		checkConfigErr(err)
+		clock.SetFakeNow(t)
+		logger.Warn("使用模拟时间，到期和续期判断基于该时间", "fakeNow", clock.Format(t))
+	}
+}
+
+// checkConfigErr 初始化配置失败时先删除 ci issue 的临时状态目录再退出
+// （cobra.CheckErr 直接退出进程，runCIIssue 中的 defer 不会执行）
+func checkConfigErr(err error) {
+	if err != nil {
+		removeCITempState()
+	}
+	cobra.CheckErr(err)
+}
+
+// configFileUsed 当前使用的配置文件的绝对路径，没有使用配置文件时为空
+func configFileUsed() string {
+	configFile := viper.ConfigFileUsed()
+	if configFile == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(configFile); err == nil {
+		return abs
+	}
+	return configFile
 }
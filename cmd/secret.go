@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/secret"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "读取 AutoCert 生成的密码（PFX 密码等）",
+	Long: `AutoCert 生成的随机密码保存在配置目录的 secrets.json 中（权限 0600，Windows 上使用 DPAPI 加密）。
+配置 pfx.enabled 后，每张证书的 PFX 密码保存为 pfx:<主域名>，pfx.password 为 rotate（默认）时每次续期更换。
+
+子命令:
+  get    输出密码（只输出密码本身，便于在脚本中使用）
+  list   列出保存的密码名称和更新时间
+
+示例:
+  autocert secret get pfx:example.com
+  autocert secret list`,
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <名称>",
+	Short: "输出密码",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretGet,
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出保存的密码名称和更新时间",
+	Args:  cobra.NoArgs,
+	RunE:  runSecretList,
+}
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretGetCmd)
+	secretCmd.AddCommand(secretListCmd)
+}
+
+func runSecretGet(cmd *cobra.Command, args []string) error {
+	value, err := secret.Get(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runSecretList(cmd *cobra.Command, args []string) error {
+	infos, err := secret.List()
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		fmt.Println("没有保存的密码")
+		return nil
+	}
+	for _, info := range infos {
+		fmt.Printf("%-40s 更新于 %s\n", info.Name, clock.Format(info.UpdatedAt))
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/webserver"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var sitesCmd = &cobra.Command{
+	Use:   "sites",
+	Short: "管理共享 IP 的多站点 Nginx 配置",
+	Long: `多个证书部署在同一台服务器（同一 IP）上时，Nginx 按 SNI 选择 server 块和证书。
+
+子命令:
+  generate    为所有已保存的证书生成合并的 Nginx 配置和 default_server 默认站点
+  conflicts   检查重复的 server_name 和 default_server，可使用 --fix 停用冲突的旧配置
+
+示例:
+  autocert sites generate --dry-run
+  autocert sites generate
+  autocert sites conflicts
+  autocert sites conflicts --fix`,
+}
+
+var sitesGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "生成合并的多站点 Nginx 配置",
+	Long: `为证书目录中的每张证书生成一组基于 SNI 的 server 块，写入同一个配置文件，
+并写入 default_server 默认站点：未匹配任何 server_name 的 HTTPS 请求使用自签名证书并关闭连接。
+
+AutoCert 之前为这些域名生成的单站点配置会被停用，避免 server_name 重复；
+Nginx 配置测试失败时恢复原有配置，不会重载。`,
+	RunE: runSitesGenerate,
+}
+
+var sitesConflictsCmd = &cobra.Command{
+	Use:   "conflicts",
+	Short: "检查重复的 server_name",
+	Long: `检查 Nginx 站点配置中同一端口上重复的 server_name 和多个 default_server。
+
+--fix 会停用与 AutoCert 配置重复的旧配置（删除 sites-enabled 中的链接，或为 conf.d 中的文件
+追加 .autocert-disabled 后缀），仅当该文件中的所有 server_name 都已由 AutoCert 配置提供时才会停用；
+Nginx 配置测试失败时恢复。其余冲突需要手动处理。`,
+	RunE: runSitesConflicts,
+}
+
+var (
+	sitesOutput    string
+	sitesWebroot   string
+	sitesDryRun    bool
+	sitesFix       bool
+	sitesOverwrite bool
+)
+
+func init() {
+	rootCmd.AddCommand(sitesCmd)
+	sitesCmd.AddCommand(sitesGenerateCmd)
+	sitesCmd.AddCommand(sitesConflictsCmd)
+
+	sitesGenerateCmd.Flags().StringVarP(&sitesOutput, "output", "o", "", "合并配置文件路径（默认 conf.d/autocert-sites.conf）")
+	sitesGenerateCmd.Flags().StringVarP(&sitesWebroot, "webroot", "w", "", "网站根目录")
+	sitesGenerateCmd.Flags().BoolVar(&sitesDryRun, "dry-run", false, "只输出生成的配置，不写入")
+	sitesGenerateCmd.Flags().BoolVar(&sitesOverwrite, "overwrite-drift", false, "覆盖上次生成后被手工修改的合并配置（默认拒绝覆盖）")
+	sitesConflictsCmd.Flags().BoolVar(&sitesFix, "fix", false, "停用与 AutoCert 配置重复的旧配置")
+}
+
+func runSitesGenerate(cmd *cobra.Command, args []string) error {
+	certs, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		return fmt.Errorf("读取证书目录失败: %w", err)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("证书目录中没有证书")
+	}
+
+	var sites []*webserver.Config
+	var domains []string
+	for _, stored := range certs {
+		sites = append(sites, stored.SiteConfig(sitesWebroot))
+		domains = append(domains, stored.Domains[0])
+	}
+
+	content, err := webserver.RenderNginxSites(sites)
+	if err != nil {
+		return err
+	}
+	if sitesDryRun {
+		fmt.Print(content)
+		return nil
+	}
+
+	output := sitesOutput
+	if output == "" {
+		if output, err = webserver.NginxSitesPath(); err != nil {
+			return err
+		}
+	} else if output, err = filepath.Abs(output); err != nil {
+		return err
+	}
+
+	restoreSites, err := webserver.WriteNginxSites(output, content, sitesOverwrite)
+	if err != nil {
+		var drift *webserver.DriftError
+		if errors.As(err, &drift) && drift.Diff != "" {
+			fmt.Print(drift.Diff)
+		}
+		return fmt.Errorf("写入合并配置失败: %w", err)
+	}
+	restore := func(disabled []webserver.DisabledConfig) {
+		webserver.RestoreConfigs(disabled)
+		restoreSites()
+	}
+
+	disabled, err := webserver.DisableManagedSites(domains)
+	if err != nil {
+		restore(nil)
+		return fmt.Errorf("停用单站点配置失败: %w", err)
+	}
+
+	defaultPath, err := webserver.EnsureNginxDefaultServer()
+	if err != nil {
+		restore(disabled)
+		return err
+	}
+
+	if err := webserver.TestNginx(); err != nil {
+		restore(disabled)
+		return fmt.Errorf("%w（已恢复原有配置）", err)
+	}
+	if err := webserver.RecordNginxSites(output); err != nil {
+		console.Warn("记录合并配置路径失败，续期时可能重新启用单站点配置: %v", err)
+	}
+	if err := webserver.ReloadNginx(); err != nil {
+		return err
+	}
+
+	console.Success("已生成 %d 个站点的合并配置: %s", len(sites), output)
+	console.Success("默认站点: %s", defaultPath)
+	for _, item := range disabled {
+		fmt.Printf("  已停用单站点配置: %s\n", item.Path)
+	}
+	return nil
+}
+
+func runSitesConflicts(cmd *cobra.Command, args []string) error {
+	files, err := webserver.NginxConfigFiles()
+	if err != nil {
+		return fmt.Errorf("查找 Nginx 配置失败: %w", err)
+	}
+	conflicts, err := webserver.FindNginxConflicts(files)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) == 0 {
+		console.Success("没有发现冲突")
+		return nil
+	}
+
+	if !sitesFix {
+		printConflicts(conflicts)
+		return fmt.Errorf("发现 %d 处冲突", len(conflicts))
+	}
+
+	disabled, manual, err := webserver.ResolveNginxConflicts(conflicts)
+	if err != nil {
+		webserver.RestoreConfigs(disabled)
+		return err
+	}
+	if len(disabled) > 0 {
+		if err := webserver.TestNginx(); err != nil {
+			webserver.RestoreConfigs(disabled)
+			return fmt.Errorf("%w（已恢复原有配置）", err)
+		}
+		if err := webserver.ReloadNginx(); err != nil {
+			return err
+		}
+		for _, item := range disabled {
+			console.Success("已停用: %s", item.Path)
+		}
+	}
+
+	if len(manual) > 0 {
+		console.Warn("以下冲突需要手动处理:")
+		printConflicts(manual)
+		return fmt.Errorf("%d 处冲突需要手动处理", len(manual))
+	}
+	return nil
+}
+
+// printConflicts 输出冲突及其所在位置
+func printConflicts(conflicts []webserver.NginxConflict) {
+	for _, conflict := range conflicts {
+		if conflict.Kind == webserver.ConflictDefaultServer {
+			fmt.Printf("端口 %s 上有多个 default_server:\n", conflict.Port)
+		} else {
+			fmt.Printf("端口 %s 上重复的 server_name %s:\n", conflict.Port, conflict.Name)
+		}
+		for _, server := range conflict.Servers {
+			suffix := ""
+			if server.Managed {
+				suffix = "（AutoCert）"
+			}
+			fmt.Printf("  %s:%d%s\n", server.File, server.Line, suffix)
+		}
+	}
+}
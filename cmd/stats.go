@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"autocert/internal/stats"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "查看本地使用统计",
+	Long: `查看本地记录的使用统计：各命令和证书操作的次数、耗时、失败次数及失败类别。
+
+统计默认关闭，需要在配置文件中开启:
+  stats:
+    enabled: true
+
+统计只保存在本机配置目录的 stats.json 中，只有配置了 stats.endpoint
+并执行 --push 时才会发送到外部。
+
+示例:
+  autocert stats
+  autocert stats --json
+  autocert stats --reset
+  autocert stats --push`,
+	RunE: runStats,
+}
+
+var (
+	statsJSON  bool
+	statsReset bool
+	statsPush  bool
+)
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "以 JSON 格式输出")
+	statsCmd.Flags().BoolVar(&statsReset, "reset", false, "清空统计")
+	statsCmd.Flags().BoolVar(&statsPush, "push", false, "将统计发送到配置的 stats.endpoint")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	if statsReset {
+		if err := stats.Reset(); err != nil {
+			return fmt.Errorf("清空统计失败: %w", err)
+		}
+		console.Success("统计已清空")
+		return nil
+	}
+
+	s, err := stats.Load()
+	if err != nil {
+		return fmt.Errorf("读取统计失败: %w", err)
+	}
+
+	if statsPush {
+		if err := s.Push(); err != nil {
+			return fmt.Errorf("发送统计失败: %w", err)
+		}
+		console.Success("统计已发送")
+		return nil
+	}
+
+	if statsJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+	}
+
+	if !stats.Enabled() {
+		fmt.Println("提示: 统计未开启，在配置文件中设置 stats.enabled: true 后开始记录")
+	}
+	if len(s.Operations) == 0 {
+		fmt.Println("暂无统计数据")
+		return nil
+	}
+
+	fmt.Printf("统计开始时间: %s\n\n", clock.Format(s.Since))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "操作\t次数\t失败\t平均耗时\t最长耗时\t最近执行")
+	fmt.Fprintln(w, "----\t----\t----\t--------\t--------\t--------")
+	for _, name := range s.OperationNames() {
+		op := s.Operations[name]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n", name, op.Count, op.Failures,
+			op.Average().Round(time.Millisecond), op.Max.Round(time.Millisecond), clock.Format(op.LastRun))
+	}
+	w.Flush()
+
+	if len(s.Failures) > 0 {
+		categories := make([]string, 0, len(s.Failures))
+		for category := range s.Failures {
+			categories = append(categories, category)
+		}
+		sort.Slice(categories, func(i, j int) bool { return s.Failures[categories[i]] > s.Failures[categories[j]] })
+
+		fmt.Println("\n失败类别:")
+		for _, category := range categories {
+			fmt.Printf("  %-12s %d\n", category, s.Failures[category])
+		}
+	}
+
+	return nil
+}
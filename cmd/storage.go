@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/storage"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "管理证书持久化后端（fs、S3、etcd、Consul）",
+	Long: `配置 storage.backend 后，签发和续期的证书同时写入持久化后端，本地证书目录作为 Web 服务器使用的缓存。
+无状态容器启动时执行 autocert storage pull 从后端恢复证书（守护进程启动时也会自动恢复）。
+
+子命令:
+  list   列出后端中保存的证书
+  push   将本地证书写入后端（迁移已有证书，或写入失败后重试）
+  pull   将后端中的证书恢复到本地证书目录
+
+示例:
+  autocert storage pull
+  autocert storage push example.com`,
+}
+
+var storageListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出后端中保存的证书",
+	Args:  cobra.NoArgs,
+	RunE:  runStorageList,
+}
+
+var storagePushCmd = &cobra.Command{
+	Use:   "push [证书名或域名...]",
+	Short: "将本地证书写入后端（不指定时写入所有证书）",
+	RunE:  runStoragePush,
+}
+
+var storagePullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "将后端中的证书恢复到本地证书目录",
+	Args:  cobra.NoArgs,
+	RunE:  runStoragePull,
+}
+
+func init() {
+	rootCmd.AddCommand(storageCmd)
+	storageCmd.AddCommand(storageListCmd)
+	storageCmd.AddCommand(storagePushCmd)
+	storageCmd.AddCommand(storagePullCmd)
+}
+
+// openStorage 打开配置的后端，未配置时返回错误
+func openStorage() (storage.Storage, error) {
+	backend, err := storage.Open()
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return nil, errors.New("未配置存储后端（storage.backend）")
+	}
+	return backend, nil
+}
+
+func runStorageList(cmd *cobra.Command, args []string) error {
+	backend, err := openStorage()
+	if err != nil {
+		return err
+	}
+
+	names, err := cert.RemoteCertificates(backend)
+	if err != nil {
+		return fmt.Errorf("读取存储后端失败: %w", err)
+	}
+	if len(names) == 0 {
+		fmt.Println("存储后端中没有证书")
+		return nil
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func runStoragePush(cmd *cobra.Command, args []string) error {
+	backend, err := openStorage()
+	if err != nil {
+		return err
+	}
+
+	var certs []cert.StoredCert
+	if len(args) == 0 {
+		if certs, err = cert.ListCertificates(config.GetCertDir()); err != nil {
+			return fmt.Errorf("读取证书目录失败: %w", err)
+		}
+	} else {
+		for _, name := range args {
+			stored, err := findStoredCert(name)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, *stored)
+		}
+	}
+
+	for _, stored := range certs {
+		if err := cert.PushCertificate(backend, stored.Dir); err != nil {
+			return fmt.Errorf("写入证书 %s 失败: %w", stored.Name, err)
+		}
+		fmt.Printf("  %s\n", stored.Name)
+	}
+	console.Success("已将 %d 张证书写入存储后端 %s", len(certs), backend.Name())
+	return nil
+}
+
+func runStoragePull(cmd *cobra.Command, args []string) error {
+	backend, err := openStorage()
+	if err != nil {
+		return err
+	}
+
+	changed, err := cert.PullCertificates(backend)
+	for _, name := range changed {
+		fmt.Printf("  %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(changed) == 0 {
+		console.Success("本地证书已是最新")
+	} else {
+		console.Success("已从存储后端 %s 恢复 %d 张证书", backend.Name(), len(changed))
+	}
+	return nil
+}
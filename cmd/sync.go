@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"autocert/internal/certsync"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/logger"
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "通过 SSH 增量同步证书目录到其他主机",
+	Long: `通过 SSH 将证书目录同步到其他主机。比较两端文件的 SHA-256，只传输有变化的证书和私钥文件，
+保留文件权限；有文件变化时可以在目标主机上执行重新加载命令。比完整的 export/import 更轻量，
+适合放在部署钩子或定时任务中定期复制。
+
+使用系统的 ssh 命令（非交互模式，需要配置密钥认证），目标主机需要 sh、tar 和 sha256sum（或 shasum）。
+未指定 --to 时同步配置 sync.targets 中的所有目标，--target 只同步其中一个。
+
+示例:
+  autocert sync --to deploy@web1:/etc/autocert/certs
+  autocert sync --to deploy@web1:/etc/autocert/certs --reload "sudo systemctl reload nginx"
+  autocert sync --to web1:/etc/ssl/example.com --domain example.com --dry-run
+  autocert sync --target web1`,
+	RunE: runSync,
+}
+
+var (
+	syncTo       string
+	syncTarget   string
+	syncDomain   string
+	syncPort     int
+	syncIdentity string
+	syncReload   string
+	syncDelete   bool
+	syncDryRun   bool
+)
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVar(&syncTo, "to", "", "目标位置，格式为 [user@]host:/path")
+	syncCmd.Flags().StringVar(&syncTarget, "target", "", "只同步配置 sync.targets 中指定名称的目标")
+	syncCmd.Flags().StringVar(&syncDomain, "domain", "", "只同步指定域名的证书目录")
+	syncCmd.Flags().IntVar(&syncPort, "port", 0, "SSH 端口（默认使用 ssh 的配置）")
+	syncCmd.Flags().StringVar(&syncIdentity, "identity", "", "SSH 私钥文件")
+	syncCmd.Flags().StringVar(&syncReload, "reload", "", "有文件变化时在目标主机上执行的命令")
+	syncCmd.Flags().BoolVar(&syncDelete, "delete", false, "删除目标主机上本地已不存在的文件")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "只列出需要同步的文件，不实际传输")
+}
+
+func runSync(cmd *cobra.Command, args []string) error {
+	targets, err := syncTargets()
+	if err != nil {
+		return err
+	}
+
+	var failed int
+	for _, t := range targets {
+		if err := syncOne(cmd.Context(), t); err != nil {
+			logger.Error("同步失败", "target", t.To, "error", err)
+			console.Error("同步到 %s 失败: %v", t.To, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d 个目标同步失败", failed)
+	}
+	return nil
+}
+
+// syncTargets 命令行指定的目标，或配置中的目标
+func syncTargets() ([]config.SyncTarget, error) {
+	if syncTo != "" {
+		return []config.SyncTarget{{
+			To:       syncTo,
+			Port:     syncPort,
+			Identity: syncIdentity,
+			Reload:   syncReload,
+			Delete:   syncDelete,
+			Domain:   syncDomain,
+		}}, nil
+	}
+
+	configured := config.GetSyncConfig().Targets
+	if len(configured) == 0 {
+		return nil, fmt.Errorf("请使用 --to 指定目标，或在配置 sync.targets 中配置同步目标")
+	}
+	var targets []config.SyncTarget
+	for _, t := range configured {
+		if syncTarget != "" && t.Name != syncTarget {
+			continue
+		}
+		// 命令行参数覆盖配置
+		if syncDomain != "" {
+			t.Domain = syncDomain
+		}
+		if syncReload != "" {
+			t.Reload = syncReload
+		}
+		if syncDelete {
+			t.Delete = true
+		}
+		if syncPort != 0 {
+			t.Port = syncPort
+		}
+		if syncIdentity != "" {
+			t.Identity = syncIdentity
+		}
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("配置 sync.targets 中没有名为 %s 的目标", syncTarget)
+	}
+	return targets, nil
+}
+
+func syncOne(ctx context.Context, t config.SyncTarget) error {
+	host, dir, err := certsync.ParseDestination(t.To)
+	if err != nil {
+		return err
+	}
+
+	// 只同步一个域名时，本地和目标都使用该域名的子目录
+	localDir := config.GetCertDir()
+	if t.Domain != "" {
+		localDir = filepath.Join(localDir, t.Domain)
+		dir = path.Join(dir, t.Domain)
+	}
+
+	result, err := certsync.Sync(ctx, localDir, certsync.Target{
+		Host:     host,
+		Dir:      dir,
+		Port:     t.Port,
+		Identity: t.Identity,
+		Reload:   t.Reload,
+		Delete:   t.Delete,
+	}, syncDryRun)
+	if err != nil {
+		return err
+	}
+
+	label := host + ":" + dir
+	if syncDryRun {
+		fmt.Printf("%s（预览，未传输）\n", label)
+		for _, name := range result.Uploaded {
+			fmt.Printf("  上传 %s\n", name)
+		}
+		for _, name := range result.Deleted {
+			fmt.Printf("  删除 %s\n", name)
+		}
+		fmt.Printf("  未变化 %d 个文件\n", result.Unchanged)
+		return nil
+	}
+
+	if !result.Changed() {
+		console.Success("%s 已是最新（%d 个文件）", label, result.Unchanged)
+		return nil
+	}
+	console.Success("已同步到 %s: 上传 %d 个文件，删除 %d 个，未变化 %d 个",
+		label, len(result.Uploaded), len(result.Deleted), result.Unchanged)
+	if result.Reloaded {
+		fmt.Printf("  已在目标主机上执行: %s\n", t.Reload)
+	}
+	return nil
+}
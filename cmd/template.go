@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/webserver"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "管理站点配置模板",
+	Long: `管理自定义的 Web 服务器站点配置模板。
+
+在配置文件中设置 webserver.template 后，AutoCert 使用该模板（Go text/template 语法）
+生成站点配置，并在启用前用 nginx -t / apachectl -t 检查渲染结果。
+
+可用占位符: {{.Domain}} {{.Aliases}} {{.CertPath}} {{.KeyPath}}
+            {{.ECDSACertPath}} {{.ECDSAKeyPath}} {{.WebRoot}}
+            {{.NoRedirect}}（install --no-redirect，HTTP 不重定向到 HTTPS）
+
+只需要为某个域名增加几行配置时不必替换整个模板：在域名配置 domains[].template_blocks 中
+按名称覆盖模板中的 block（内置模板提供 server、locations 和 default_location），
+片段每行缩进 4 个空格插入，可以使用相同的占位符。
+
+子命令:
+  lint      检查模板
+  show      显示内置模板`,
+}
+
+var templateLintCmd = &cobra.Command{
+	Use:   "lint [模板文件]",
+	Short: "检查模板",
+	Long: `检查模板语法和占位符，使用示例数据渲染，并用 Web 服务器自身的语法检查验证渲染结果。
+未指定文件时检查配置 webserver.template 指定的模板；指定 --domain 时同时检查该域名的
+template_blocks（未配置 webserver.template 时使用内置模板）。
+
+示例:
+  autocert template lint /etc/autocert/nginx-site.tmpl
+  autocert template lint --server apache /etc/autocert/apache-site.tmpl
+  autocert template lint --domain example.com`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTemplateLint,
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "显示内置模板（可作为自定义模板的起点）",
+	RunE:  runTemplateShow,
+}
+
+var (
+	templateServer string
+	templateDomain string
+)
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateLintCmd)
+	templateCmd.AddCommand(templateShowCmd)
+
+	templateCmd.PersistentFlags().StringVar(&templateServer, "server", "", "Web 服务器类型: nginx, apache（默认使用配置 webserver.type）")
+	templateLintCmd.Flags().StringVar(&templateDomain, "domain", "", "同时检查该域名配置的模板片段（template_blocks）")
+}
+
+func runTemplateLint(cmd *cobra.Command, args []string) error {
+	path := webserver.CustomTemplatePath()
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if path == "" && templateDomain == "" {
+		return fmt.Errorf("请指定模板文件，或在配置中设置 webserver.template")
+	}
+
+	serverType := templateServerType()
+	if err := webserver.LintTemplate(serverType, path, templateDomain); err != nil {
+		return fmt.Errorf("模板检查未通过: %w", err)
+	}
+
+	name := path
+	if name == "" {
+		name = "内置模板"
+	}
+	if templateDomain != "" {
+		name += "（" + templateDomain + " 的模板片段）"
+	}
+	console.Success("%s 检查通过 (%s)", name, serverType)
+	return nil
+}
+
+func runTemplateShow(cmd *cobra.Command, args []string) error {
+	serverType := templateServerType()
+	builtin := webserver.BuiltinTemplate(serverType)
+	if builtin == "" {
+		return fmt.Errorf("%s 没有内置模板", serverType)
+	}
+
+	fmt.Print(builtin)
+	return nil
+}
+
+// templateServerType 获取模板对应的 Web 服务器类型
+func templateServerType() string {
+	if templateServer != "" {
+		return templateServer
+	}
+	return config.GetWebServerConfig().Type
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"autocert/internal/apitoken"
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "管理守护进程 HTTP 接口的 API 令牌",
+	Long: `为守护进程的 HTTP 接口（webhook 和 /api）创建带权限范围和有效期的 API 令牌，
+仪表盘读取和自动化操作可以使用不同的令牌，单独吊销。
+
+权限范围（高级别包含低级别的全部权限）:
+  read    查询证书状态（GET /api/certificates）
+  renew   触发签发和续期（POST /hooks/renew、POST /api/on-demand）
+  admin   全部权限，包括管理令牌（GET /api/tokens、DELETE /api/tokens/<id>）
+
+令牌只在创建时显示一次，状态存储中只保存其 SHA-256。令牌和吊销列表保存在状态存储中：
+配置了 storage.backend 时为持久化后端（多个守护进程共享），否则为配置目录。
+
+子命令:
+  create   创建令牌
+  list     列出令牌
+  revoke   吊销令牌
+
+示例:
+  autocert token create --scope read --name dashboard --expires 90d
+  autocert token create --scope renew --name ci --expires 30d
+  autocert token list
+  autocert token revoke 3f9a1c2e`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "创建令牌",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenCreate,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "列出令牌",
+	Args:  cobra.NoArgs,
+	RunE:  runTokenList,
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <令牌 ID>",
+	Short: "吊销令牌",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenRevoke,
+}
+
+var (
+	tokenScope   string
+	tokenName    string
+	tokenExpires string
+)
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+
+	tokenCreateCmd.Flags().StringVar(&tokenScope, "scope", "", "权限范围: read, renew, admin")
+	tokenCreateCmd.Flags().StringVar(&tokenName, "name", "", "令牌用途说明，例如 dashboard")
+	tokenCreateCmd.Flags().StringVar(&tokenExpires, "expires", "90d", "有效期，例如 30d、12h；0 表示永不过期")
+	tokenCreateCmd.MarkFlagRequired("scope")
+}
+
+func runTokenCreate(cmd *cobra.Command, args []string) error {
+	ttl, err := parseAge(tokenExpires)
+	if err != nil {
+		return fmt.Errorf("--expires 无效: %w", err)
+	}
+	store, err := apitoken.Open()
+	if err != nil {
+		return err
+	}
+	value, token, err := store.Create(tokenName, tokenScope, ttl)
+	if err != nil {
+		return err
+	}
+
+	console.Success("已创建 API 令牌 %s（%s）", token.ID, token.Scope)
+	if token.ExpiresAt != nil {
+		fmt.Printf("  到期时间: %s\n", clock.Format(*token.ExpiresAt))
+	} else {
+		fmt.Println("  到期时间: 永不过期")
+	}
+	fmt.Println("  令牌只显示这一次，请妥善保存:")
+	fmt.Println(value)
+	return nil
+}
+
+func runTokenList(cmd *cobra.Command, args []string) error {
+	store, err := apitoken.Open()
+	if err != nil {
+		return err
+	}
+	tokens, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		fmt.Println("没有 API 令牌")
+		return nil
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t名称\t权限\t创建时间\t到期时间\t状态")
+	fmt.Fprintln(w, "--\t----\t----\t--------\t--------\t----")
+	for _, token := range tokens {
+		expires := "永不过期"
+		if token.ExpiresAt != nil {
+			expires = clock.Format(*token.ExpiresAt)
+		}
+		status := "有效"
+		switch {
+		case token.RevokedAt != nil:
+			status = "已吊销（" + clock.Format(*token.RevokedAt) + "）"
+		case token.Expired(now):
+			status = "已过期"
+		}
+		name := token.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", token.ID, name, token.Scope, clock.Format(token.CreatedAt), expires, status)
+	}
+	return w.Flush()
+}
+
+func runTokenRevoke(cmd *cobra.Command, args []string) error {
+	store, err := apitoken.Open()
+	if err != nil {
+		return err
+	}
+	if err := store.Revoke(args[0]); err != nil {
+		return err
+	}
+	console.Success("已吊销 API 令牌 %s，所有守护进程立即拒绝该令牌", args[0])
+	return nil
+}
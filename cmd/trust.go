@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/console"
+	"autocert/internal/trust"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "查看证书的完整信任链和根证书状态",
+	Long: `显示证书的完整信任链（叶子证书、各级中间证书和根证书）及每张证书的有效期，
+并检查本机的系统信任存储是否包含所需的根证书。
+
+以下情况会给出警告：
+  - 中间证书或根证书早于叶子证书过期（例如交叉签名的根证书到期）
+  - 中间证书或根证书在 --warn-days 天内过期
+  - 证书文件包含交叉签名证书、根证书或本机信任链没有用到的证书
+
+发现错误（证书已过期、信任存储中没有所需的根证书）时退出码为 1。
+
+示例:
+  autocert trust --domain example.com
+  autocert trust --domain example.com --warn-days 90
+  autocert trust --domain example.com --json`,
+	RunE: runTrust,
+}
+
+var (
+	trustDomain   string
+	trustWarnDays int
+	trustJSON     bool
+)
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+
+	trustCmd.Flags().StringVarP(&trustDomain, "domain", "d", "", "域名（证书的主域名）")
+	trustCmd.Flags().IntVar(&trustWarnDays, "warn-days", 30, "中间证书或根证书在该天数内过期时警告")
+	trustCmd.Flags().BoolVar(&trustJSON, "json", false, "以 JSON 格式输出")
+	trustCmd.MarkFlagRequired("domain")
+}
+
+func runTrust(cmd *cobra.Command, args []string) error {
+	stored, err := findStoredCert(trustDomain)
+	if err != nil {
+		return err
+	}
+	certs, err := stored.Chain()
+	if err != nil {
+		return err
+	}
+	report, err := trust.Inspect(certs, trust.Options{WarnDays: trustWarnDays})
+	if err != nil {
+		return err
+	}
+
+	if trustJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printTrustReport(report)
+	}
+
+	errorCount := 0
+	for _, finding := range report.Findings {
+		if finding.Level == trust.LevelError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("信任链检查发现 %d 个错误", errorCount)
+	}
+	return nil
+}
+
+// printTrustReport 输出信任链和发现的问题
+func printTrustReport(report *trust.Report) {
+	roles := map[string]string{
+		trust.RoleLeaf:         "叶子证书",
+		trust.RoleIntermediate: "中间证书",
+		trust.RoleRoot:         "根证书",
+	}
+	sources := map[string]string{
+		trust.SourceFile:  "证书文件",
+		trust.SourceStore: "系统信任存储",
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "角色\t主题\t到期时间\t剩余天数\t来源")
+	fmt.Fprintln(w, "----\t----\t--------\t--------\t----")
+	for _, entry := range report.Chain {
+		role := roles[entry.Role]
+		switch {
+		case entry.CrossSigned:
+			role += "（交叉签名）"
+		case entry.Unused:
+			role += "（未使用）"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", role, entry.Subject, clock.Date(entry.NotAfter), entry.DaysLeft, sources[entry.Source])
+	}
+	w.Flush()
+	fmt.Println()
+
+	for _, finding := range report.Findings {
+		switch finding.Level {
+		case trust.LevelError:
+			console.Error("%s", finding.Message)
+		case trust.LevelWarning:
+			console.Warn("%s", finding.Message)
+		default:
+			fmt.Println(finding.Message)
+		}
+	}
+}
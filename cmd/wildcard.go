@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/console"
+	"autocert/internal/dns"
+	"autocert/internal/webserver"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var wildcardCmd = &cobra.Command{
+	Use:   "wildcard",
+	Short: "列出泛域名证书下的子域名并检查覆盖范围",
+	Long: `为每张泛域名证书列出正在提供服务的子域名（来自 Nginx/Apache 配置中的
+server_name、ServerName、ServerAlias，以及可选的区域文件或 DNS 服务商 API），并给出警告：
+
+  - 子域名不在泛域名的覆盖范围内（*.example.com 不覆盖 example.com 和 a.b.example.com）
+  - 子域名已被泛域名覆盖，但还有单独的证书：该证书的所有域名都被覆盖时是多余的，
+    进入续期窗口后会重复签发；只包含部分域名时 Web 服务器配置决定实际使用哪一张
+
+示例:
+  autocert wildcard
+  autocert wildcard --zone-file /etc/bind/db.example.com --zone-origin example.com
+  autocert wildcard --zone example.com   # 通过 dns.provider 的 API 读取区域（desec、powerdns、zonefile）`,
+	RunE: runWildcard,
+}
+
+var (
+	wildcardZoneFiles  []string
+	wildcardZoneOrigin string
+	wildcardZones      []string
+)
+
+func init() {
+	rootCmd.AddCommand(wildcardCmd)
+
+	wildcardCmd.Flags().StringSliceVar(&wildcardZoneFiles, "zone-file", nil, "同时读取 BIND 格式区域文件中的主机名（可多次指定）")
+	wildcardCmd.Flags().StringVar(&wildcardZoneOrigin, "zone-origin", "", "区域文件没有 $ORIGIN 时使用的区域名")
+	wildcardCmd.Flags().StringSliceVar(&wildcardZones, "zone", nil, "同时通过配置的 DNS 服务商 API 读取区域中的主机名（可多次指定）")
+}
+
+func runWildcard(cmd *cobra.Command, args []string) error {
+	served := webserver.ServedNames()
+	for _, zoneFile := range wildcardZoneFiles {
+		names, err := dns.ZoneNames(zoneFile, wildcardZoneOrigin)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			served[name] = append(served[name], "zone:"+zoneFile)
+		}
+	}
+	for _, zone := range wildcardZones {
+		names, err := dns.ZoneHostNames(zone)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			served[name] = append(served[name], "dns:"+zone)
+		}
+	}
+
+	inventories, err := cert.InventoryWildcards(config.GetCertDir(), served)
+	if err != nil {
+		return fmt.Errorf("读取证书目录失败: %w", err)
+	}
+	if len(inventories) == 0 {
+		fmt.Println("证书目录中没有泛域名证书")
+		return nil
+	}
+
+	warnings := 0
+	for i, inventory := range inventories {
+		if i > 0 {
+			fmt.Println()
+		}
+		expiry := ""
+		if !inventory.NotAfter.IsZero() {
+			expiry = "，到期 " + clock.Date(inventory.NotAfter)
+		}
+		fmt.Printf("%s（证书 %s%s）\n", strings.Join(inventory.Wildcards, ", "), inventory.Cert.Name, expiry)
+
+		if len(inventory.Subdomains) == 0 {
+			fmt.Println("  没有发现提供服务的子域名")
+			continue
+		}
+		for _, sub := range inventory.Subdomains {
+			warnings += printSubdomain(sub)
+		}
+	}
+
+	fmt.Println()
+	if warnings > 0 {
+		console.Warn("%d 个子域名需要注意", warnings)
+	} else {
+		console.Success("所有子域名都在泛域名证书的覆盖范围内")
+	}
+	return nil
+}
+
+// printSubdomain 输出子域名的覆盖情况，返回警告数量
+func printSubdomain(sub cert.Subdomain) int {
+	source := "仅出现在证书中"
+	if len(sub.Sources) > 0 {
+		source = strings.Join(sub.Sources, ", ")
+	}
+
+	var notes []string
+	warning := false
+	for _, separate := range sub.Separate {
+		switch {
+		case sub.Covered && separate.Redundant && separate.Renewing:
+			notes = append(notes, fmt.Sprintf("单独证书 %s 是多余的，且即将续期（%s 到期），续期会重复签发", separate.Name, clock.Date(separate.NotAfter)))
+			warning = true
+		case sub.Covered && separate.Redundant:
+			notes = append(notes, fmt.Sprintf("单独证书 %s 是多余的，可改用泛域名证书后删除", separate.Name))
+			warning = true
+		case sub.Covered:
+			notes = append(notes, fmt.Sprintf("同时包含在证书 %s 中，实际使用哪一张取决于 Web 服务器配置", separate.Name))
+			warning = true
+		default:
+			notes = append(notes, fmt.Sprintf("使用单独证书 %s", separate.Name))
+		}
+	}
+	if !sub.Covered && len(sub.Separate) == 0 {
+		notes = append(notes, "不在泛域名覆盖范围内（泛域名只覆盖一级子域名，不包括基础域名），没有证书覆盖")
+		warning = true
+	}
+
+	line := fmt.Sprintf(" %s  %s", sub.Name, source)
+	switch {
+	case warning && !sub.Covered:
+		fmt.Println(console.Colorize(os.Stdout, console.Red, "✗"+line))
+	case warning:
+		fmt.Println(console.Colorize(os.Stdout, console.Yellow, "⚠"+line))
+	default:
+		fmt.Println(console.Colorize(os.Stdout, console.Green, "✓"+line))
+	}
+	for _, note := range notes {
+		fmt.Printf("      %s\n", note)
+	}
+
+	if warning {
+		return 1
+	}
+	return 0
+}
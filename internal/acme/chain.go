@@ -0,0 +1,60 @@
+package acme
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+)
+
+// AlternateLinks 解析下载证书响应中 rel="alternate" 的 Link 头，返回备用证书链的 URL（RFC 8555 第 7.4.2 节）
+func AlternateLinks(header http.Header) []string {
+	var links []string
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				name, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if ok && strings.EqualFold(name, "rel") && strings.EqualFold(strings.Trim(val, `"`), "alternate") {
+					links = append(links, strings.Trim(target, "<>"))
+					break
+				}
+			}
+		}
+	}
+	return links
+}
+
+// ChainRoot 证书链最顶层证书的颁发者 CN，即客户端需要信任的根证书名称
+func ChainRoot(chain []*x509.Certificate) string {
+	if len(chain) == 0 {
+		return ""
+	}
+	return chain[len(chain)-1].Issuer.CommonName
+}
+
+// SelectChain 按首选根证书名称选择证书链，返回下标，没有匹配时返回 -1。
+// 优先匹配最顶层证书的颁发者 CN（例如 "ISRG Root X1" 选择不含交叉签名的短链），
+// 都不匹配时再匹配链中任一证书的颁发者 CN。名称不区分大小写
+func SelectChain(chains [][]*x509.Certificate, preferred string) int {
+	preferred = strings.TrimSpace(preferred)
+	if preferred == "" {
+		return -1
+	}
+	for i, chain := range chains {
+		if strings.EqualFold(ChainRoot(chain), preferred) {
+			return i
+		}
+	}
+	for i, chain := range chains {
+		for _, cert := range chain {
+			if strings.EqualFold(cert.Issuer.CommonName, preferred) {
+				return i
+			}
+		}
+	}
+	return -1
+}
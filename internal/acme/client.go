@@ -0,0 +1,95 @@
+package acme
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Directory ACME 目录中的接口地址（RFC 8555 第 7.1.1 节）
+type Directory struct {
+	NewNonce   string `json:"newNonce"`
+	NewAccount string `json:"newAccount"`
+	NewOrder   string `json:"newOrder"`
+	RevokeCert string `json:"revokeCert"`
+	KeyChange  string `json:"keyChange"`
+}
+
+// Client 最小的 ACME HTTP 客户端：读取目录、获取 nonce、提交已签名的请求
+type Client struct {
+	Directory Directory
+	http      *http.Client
+	nonce     string
+}
+
+// NewClient 读取 server 指向的 ACME 目录
+func NewClient(server string) (*Client, error) {
+	c := &Client{http: &http.Client{Timeout: 30 * time.Second}}
+
+	resp, err := c.http.Get(server)
+	if err != nil {
+		return nil, fmt.Errorf("请求 ACME 目录失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求 ACME 目录失败: HTTP %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&c.Directory); err != nil {
+		return nil, fmt.Errorf("解析 ACME 目录失败: %w", err)
+	}
+	if c.Directory.NewNonce == "" {
+		return nil, fmt.Errorf("ACME 目录缺少 newNonce")
+	}
+	return c, nil
+}
+
+// Nonce 返回下一个请求使用的 nonce：优先使用上一个响应的 Replay-Nonce，否则请求 newNonce
+func (c *Client) Nonce() (string, error) {
+	if c.nonce != "" {
+		nonce := c.nonce
+		c.nonce = ""
+		return nonce, nil
+	}
+
+	resp, err := c.http.Head(c.Directory.NewNonce)
+	if err != nil {
+		return "", fmt.Errorf("获取 nonce 失败: %w", err)
+	}
+	resp.Body.Close()
+	nonce := resp.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", fmt.Errorf("获取 nonce 失败: newNonce 没有返回 Replay-Nonce")
+	}
+	return nonce, nil
+}
+
+// Post 提交已签名的请求，CA 返回错误时解析问题详情作为 *Problem 返回
+func (c *Client) Post(url string, jws *JWS) (*http.Response, []byte, error) {
+	data, err := json.Marshal(jws)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.http.Post(url, "application/jose+json", bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("请求 %s 失败: %w", url, err)
+	}
+	defer resp.Body.Close()
+	c.nonce = resp.Header.Get("Replay-Nonce")
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 %s 响应失败: %w", url, err)
+	}
+	if resp.StatusCode >= 400 {
+		problem := &Problem{Status: resp.StatusCode}
+		if err := json.Unmarshal(body, problem); err != nil || problem.Type == "" {
+			return nil, nil, fmt.Errorf("请求 %s 失败: HTTP %d", url, resp.StatusCode)
+		}
+		return nil, nil, problem
+	}
+	return resp, body, nil
+}
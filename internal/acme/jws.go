@@ -0,0 +1,187 @@
+package acme
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// JWS ACME 请求体（flattened JSON 序列化，RFC 8555 第 6.2 节）
+type JWS struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// SignJWS 使用账户密钥对 ACME 请求签名
+// kid 为空时在 protected header 中携带 JWK（新建账户等请求），否则携带账户 URL
+// 签名通过 crypto.Signer 完成，账户密钥可以位于硬件令牌中
+func SignJWS(signer crypto.Signer, url, nonce, kid string, payload []byte) (*JWS, error) {
+	alg, err := algorithm(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	header := map[string]interface{}{
+		"alg":   alg,
+		"url":   url,
+		"nonce": nonce,
+	}
+	if kid != "" {
+		header["kid"] = kid
+	} else {
+		jwk, err := JWK(signer.Public())
+		if err != nil {
+			return nil, err
+		}
+		header["jwk"] = jwk
+	}
+
+	return sign(signer, header, payload)
+}
+
+// KeyChange 构造账户密钥轮换请求（RFC 8555 第 7.3.5 节）
+// 内层 JWS 由新密钥签名，protected header 携带新密钥的 JWK 且没有 nonce，载荷为账户 URL 和旧密钥；
+// 外层 JWS 由旧密钥以账户 URL 签名，载荷为内层 JWS
+func KeyChange(oldSigner, newSigner crypto.Signer, url, nonce, accountURL string) (*JWS, error) {
+	alg, err := algorithm(newSigner.Public())
+	if err != nil {
+		return nil, err
+	}
+	newJWK, err := JWK(newSigner.Public())
+	if err != nil {
+		return nil, err
+	}
+	oldJWK, err := JWK(oldSigner.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"account": accountURL,
+		"oldKey":  oldJWK,
+	})
+	if err != nil {
+		return nil, err
+	}
+	inner, err := sign(newSigner, map[string]interface{}{"alg": alg, "url": url, "jwk": newJWK}, payload)
+	if err != nil {
+		return nil, err
+	}
+	innerJSON, err := json.Marshal(inner)
+	if err != nil {
+		return nil, err
+	}
+
+	return SignJWS(oldSigner, url, nonce, accountURL, innerJSON)
+}
+
+// sign 使用 protected header 对载荷签名
+func sign(signer crypto.Signer, header map[string]interface{}, payload []byte) (*JWS, error) {
+	protectedJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	jws := &JWS{
+		Protected: encode(protectedJSON),
+		Payload:   encode(payload),
+	}
+
+	digest := sha256.Sum256([]byte(jws.Protected + "." + jws.Payload))
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("签名失败: %w", err)
+	}
+
+	if key, ok := signer.Public().(*ecdsa.PublicKey); ok {
+		if sig, err = ecdsaRawSignature(sig, key); err != nil {
+			return nil, err
+		}
+	}
+
+	jws.Signature = encode(sig)
+	return jws, nil
+}
+
+// JWK 返回公钥的 JWK 表示（字段按 RFC 7638 要求的字典序排列）
+func JWK(pub crypto.PublicKey) (map[string]string, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return map[string]string{
+			"crv": key.Curve.Params().Name,
+			"kty": "EC",
+			"x":   encode(key.X.FillBytes(make([]byte, size))),
+			"y":   encode(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case *rsa.PublicKey:
+		return map[string]string{
+			"e":   encode(big.NewInt(int64(key.E)).Bytes()),
+			"kty": "RSA",
+			"n":   encode(key.N.Bytes()),
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的账户密钥类型: %T", pub)
+	}
+}
+
+// Thumbprint 计算公钥的 JWK 指纹（RFC 7638）
+func Thumbprint(pub crypto.PublicKey) (string, error) {
+	jwk, err := JWK(pub)
+	if err != nil {
+		return "", err
+	}
+
+	// encoding/json 按键名排序输出 map，满足规范要求
+	data, err := json.Marshal(jwk)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return encode(sum[:]), nil
+}
+
+// algorithm 根据公钥类型选择 JWS 签名算法
+func algorithm(pub crypto.PublicKey) (string, error) {
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve.Params().BitSize != 256 {
+			return "", fmt.Errorf("账户密钥仅支持 P-256 曲线")
+		}
+		return "ES256", nil
+	case *rsa.PublicKey:
+		return "RS256", nil
+	default:
+		return "", fmt.Errorf("不支持的账户密钥类型: %T", pub)
+	}
+}
+
+// ecdsaRawSignature 将 ASN.1 DER 格式的 ECDSA 签名转换为 JWS 要求的 r||s 格式
+func ecdsaRawSignature(der []byte, key *ecdsa.PublicKey) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("解析 ECDSA 签名失败: %w", err)
+	}
+
+	size := (key.Curve.Params().BitSize + 7) / 8
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+// encode base64url 编码（无填充）
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
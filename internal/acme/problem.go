@@ -0,0 +1,82 @@
+package acme
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 授权和挑战状态（RFC 8555 第 7.1.6 节）
+const (
+	StatusPending = "pending"
+	StatusValid   = "valid"
+	StatusInvalid = "invalid"
+)
+
+// Problem CA 返回的问题详情（RFC 7807，ACME 错误类型见 RFC 8555 第 6.7 节）
+type Problem struct {
+	Type        string      `json:"type"`
+	Detail      string      `json:"detail"`
+	Status      int         `json:"status,omitempty"`
+	Identifier  *Identifier `json:"identifier,omitempty"`
+	Subproblems []Problem   `json:"subproblems,omitempty"`
+}
+
+// Identifier 订单或授权中的标识（目前只有 dns 类型）
+type Identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// ValidationRecord CA 验证时的连接记录（Boulder 扩展）：http-01 跟随重定向时每一跳一条
+type ValidationRecord struct {
+	URL               string   `json:"url,omitempty"`
+	Hostname          string   `json:"hostname"`
+	Port              string   `json:"port,omitempty"`
+	AddressesResolved []string `json:"addressesResolved,omitempty"`
+	AddressUsed       string   `json:"addressUsed,omitempty"`
+}
+
+// Challenge 授权中的挑战（RFC 8555 第 7.1.5 节）
+type Challenge struct {
+	Type             string             `json:"type"`
+	URL              string             `json:"url"`
+	Status           string             `json:"status"`
+	Token            string             `json:"token,omitempty"`
+	Error            *Problem           `json:"error,omitempty"`
+	ValidationRecord []ValidationRecord `json:"validationRecord,omitempty"`
+}
+
+// Authorization 授权对象（RFC 8555 第 7.1.4 节）
+type Authorization struct {
+	Identifier Identifier  `json:"identifier"`
+	Status     string      `json:"status"`
+	Wildcard   bool        `json:"wildcard,omitempty"`
+	Challenges []Challenge `json:"challenges"`
+}
+
+// FailedChallenge 返回验证失败的挑战，没有时返回 nil
+func (a *Authorization) FailedChallenge() *Challenge {
+	for i := range a.Challenges {
+		if a.Challenges[i].Status == StatusInvalid || a.Challenges[i].Error != nil {
+			return &a.Challenges[i]
+		}
+	}
+	return nil
+}
+
+// ShortType 去掉 urn:ietf:params:acme:error: 前缀的错误类型，例如 connection、unauthorized
+func (p *Problem) ShortType() string {
+	return strings.TrimPrefix(p.Type, "urn:ietf:params:acme:error:")
+}
+
+func (p *Problem) Error() string {
+	msg := fmt.Sprintf("%s: %s", p.ShortType(), p.Detail)
+	for _, sub := range p.Subproblems {
+		if sub.Identifier != nil {
+			msg += fmt.Sprintf("; %s: %s", sub.Identifier.Value, sub.Detail)
+		} else {
+			msg += "; " + sub.Detail
+		}
+	}
+	return msg
+}
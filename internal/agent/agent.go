@@ -0,0 +1,317 @@
+// Package agent 卫星节点（agent）向控制节点注册：使用控制节点 autocert ca join-token 生成的一次性加入令牌，
+// 通过 HTTPS 提交本机生成私钥的 CSR，获得控制节点本地 CA 签发的客户端证书，之后与控制节点的通信使用 mTLS。
+// 加入令牌携带 CA 根证书的指纹，首次连接时据此确认控制节点，无需预先分发 CA 根证书
+package agent
+
+import (
+	"autocert/internal/ca"
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 控制节点的 agent 接口
+const (
+	EnrollPath  = "/agent/enroll"  // 使用加入令牌注册
+	CheckInPath = "/agent/checkin" // 使用客户端证书签到
+)
+
+// 配置目录下 agent 身份的文件
+const (
+	dirName      = "agent"
+	identityFile = "agent.json"
+	certFile     = "agent.pem"
+	keyFile      = "agent.key"
+	caFile       = "ca.pem"
+)
+
+// EnrollRequest POST /agent/enroll 的请求
+type EnrollRequest struct {
+	Token string `json:"token"`
+	Name  string `json:"name"`
+	CSR   string `json:"csr"` // PEM
+}
+
+// EnrollResponse POST /agent/enroll 的响应
+type EnrollResponse struct {
+	Certificate string    `json:"certificate"` // PEM
+	CA          string    `json:"ca"`          // PEM
+	Serial      string    `json:"serial"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+// CheckInRequest POST /agent/checkin 的请求
+type CheckInRequest struct {
+	Certificates []Deployed `json:"certificates"`
+}
+
+// CheckInResponse POST /agent/checkin 的响应
+type CheckInResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Identity agent 的身份：控制节点地址、名称和客户端证书
+type Identity struct {
+	Name       string    `json:"name"`
+	Server     string    `json:"server"`
+	Serial     string    `json:"serial"`
+	EnrolledAt time.Time `json:"enrolled_at"`
+	NotAfter   time.Time `json:"not_after"`
+}
+
+// Dir agent 身份的保存目录
+func Dir() string {
+	return filepath.Join(config.GetConfigDir(), dirName)
+}
+
+// Enroll 使用加入令牌向控制节点 server（https://host:port）注册名为 name 的 agent，保存身份和客户端证书
+func Enroll(server, token, name string) (*Identity, error) {
+	serverURL, err := url.Parse(strings.TrimSuffix(server, "/"))
+	if err != nil || serverURL.Scheme != "https" || serverURL.Host == "" {
+		return nil, fmt.Errorf("控制节点地址无效: %s（需要 https://主机:端口）", server)
+	}
+	if !ca.ValidAgentName(name) {
+		return nil, fmt.Errorf("agent 名称 %q 无效", name)
+	}
+	_, _, fingerprint, err := ca.ParseJoinToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成 agent 私钥失败: %w", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: name}}, key)
+	if err != nil {
+		return nil, fmt.Errorf("生成 CSR 失败: %w", err)
+	}
+	body, err := json.Marshal(EnrollRequest{
+		Token: token,
+		Name:  name,
+		CSR:   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			// 尚未获得 CA 根证书：由 VerifyConnection 按加入令牌中的指纹校验
+			InsecureSkipVerify: true,
+			VerifyConnection: func(state tls.ConnectionState) error {
+				return verifyPinned(state, fingerprint, serverURL.Hostname())
+			},
+		}},
+	}
+	resp, err := client.Post(serverURL.String()+EnrollPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("连接控制节点失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取控制节点响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(data, &failure)
+		return nil, fmt.Errorf("注册失败（HTTP %d）: %s", resp.StatusCode, failure.Error)
+	}
+
+	var result EnrollResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析控制节点响应失败: %w", err)
+	}
+	caBlock, _ := pem.Decode([]byte(result.CA))
+	if caBlock == nil {
+		return nil, errors.New("控制节点没有返回 CA 根证书")
+	}
+	caCert, err := x509.ParseCertificate(caBlock.Bytes)
+	if err != nil || ca.CertFingerprint(caCert) != fingerprint {
+		return nil, errors.New("控制节点返回的 CA 根证书与加入令牌不符")
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	identity := &Identity{
+		Name:       name,
+		Server:     serverURL.String(),
+		Serial:     result.Serial,
+		EnrolledAt: time.Now(),
+		NotAfter:   result.NotAfter,
+	}
+	if err := save(identity, []byte(result.Certificate), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), []byte(result.CA)); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// Load 读取本机的 agent 身份，尚未注册时返回 os.ErrNotExist
+func Load() (*Identity, error) {
+	data, err := os.ReadFile(filepath.Join(Dir(), identityFile))
+	if err != nil {
+		return nil, err
+	}
+	var identity Identity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return nil, fmt.Errorf("解析 agent 身份失败: %w", err)
+	}
+	return &identity, nil
+}
+
+// CheckIn 使用客户端证书向控制节点签到，报告本机证书目录中的证书版本，返回控制节点上 agent 的状态
+func (identity *Identity) CheckIn() (string, error) {
+	client, err := identity.client()
+	if err != nil {
+		return "", err
+	}
+
+	stored, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		return "", fmt.Errorf("读取证书目录失败: %w", err)
+	}
+	request := CheckInRequest{Certificates: []Deployed{}}
+	for _, s := range stored {
+		details, err := s.Details()
+		if err != nil {
+			continue
+		}
+		request.Certificates = append(request.Certificates, Deployed{
+			Name:     details.Name,
+			Domains:  details.Domains,
+			Serial:   details.Serial,
+			NotAfter: details.NotAfter,
+		})
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Post(identity.Server+CheckInPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("连接控制节点失败: %w", err)
+	}
+	defer resp.Body.Close()
+	var result CheckInResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return "", fmt.Errorf("解析控制节点响应失败（HTTP %d）: %w", resp.StatusCode, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return result.Status, nil
+	case http.StatusForbidden:
+		if result.Status == StatusPending {
+			return StatusPending, nil
+		}
+	}
+	return "", fmt.Errorf("签到失败（HTTP %d）: %s", resp.StatusCode, result.Error)
+}
+
+// client 使用 agent 客户端证书、只信任控制节点 CA 的 HTTP 客户端
+func (identity *Identity) client() (*http.Client, error) {
+	dir := Dir()
+	keyPair, err := tls.LoadX509KeyPair(filepath.Join(dir, certFile), filepath.Join(dir, keyFile))
+	if err != nil {
+		return nil, fmt.Errorf("加载 agent 客户端证书失败: %w", err)
+	}
+	caPEM, err := os.ReadFile(filepath.Join(dir, caFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取控制节点 CA 根证书失败: %w", err)
+	}
+	// 控制节点的服务端证书由本地 CA 签发，或为公共证书（daemon.tls.cert_file）
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("控制节点 CA 根证书格式错误: %s", filepath.Join(dir, caFile))
+	}
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{keyPair},
+		}},
+	}, nil
+}
+
+// save 保存身份、客户端证书、私钥（0600）和 CA 根证书
+func save(identity *Identity, certPEM, keyPEM, caPEM []byte) error {
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("创建 agent 目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(identity, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{keyFile, keyPEM, 0600},
+		{certFile, certPEM, 0644},
+		{caFile, caPEM, 0644},
+		{identityFile, append(data, '\n'), 0644},
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.name), f.data, f.perm); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// verifyPinned 在服务端提供的证书链中查找指纹与加入令牌一致的 CA 根证书，并用它验证服务端证书。
+// 控制节点配置了公共证书（daemon.tls.cert_file）时证书链中没有本地 CA，改为按系统信任的 CA 验证；
+// 两种情况下注册响应中的 CA 根证书都要与加入令牌中的指纹一致
+func verifyPinned(state tls.ConnectionState, fingerprint, host string) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("控制节点没有提供证书")
+	}
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		if cert.IsCA && ca.CertFingerprint(cert) == fingerprint {
+			roots.AddCert(cert)
+		}
+		intermediates.AddCert(cert)
+	}
+	leaf := state.PeerCertificates[0]
+	_, pinnedErr := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+	if pinnedErr == nil {
+		return nil
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates}); err != nil {
+		return fmt.Errorf("控制节点的证书既不是加入令牌对应的 CA 签发的（%v），也不受系统信任: %w", pinnedErr, err)
+	}
+	return nil
+}
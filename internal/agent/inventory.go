@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 控制节点上 agent 的状态
+const (
+	StatusPending  = "pending"  // 已注册，等待 autocert agents approve
+	StatusApproved = "approved" // 已批准，签到时记录部署的证书
+)
+
+// DefaultStaleAfter 超过该时间没有签到的 agent 视为失联
+const DefaultStaleAfter = 48 * time.Hour
+
+// inventoryPrefix 状态存储中 agent 记录的键前缀
+const inventoryPrefix = "agents/"
+
+// ErrUnknownAgent agent 不在清单中
+var ErrUnknownAgent = errors.New("agent 不在清单中")
+
+// Record 控制节点清单中的 agent
+type Record struct {
+	Name         string     `json:"name"`
+	Status       string     `json:"status"`
+	Serial       string     `json:"serial"` // 当前客户端证书的序列号，重新注册后旧证书不能再签到
+	EnrolledAt   time.Time  `json:"enrolled_at"`
+	Address      string     `json:"address,omitempty"` // 最近一次注册或签到的来源地址
+	LastCheckIn  *time.Time `json:"last_check_in,omitempty"`
+	Certificates []Deployed `json:"certificates,omitempty"`
+}
+
+// Deployed agent 上部署的证书版本
+type Deployed struct {
+	Name     string    `json:"name"`
+	Domains  []string  `json:"domains"`
+	Serial   string    `json:"serial"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// Stale 是否超过 staleAfter 没有签到（从未签到时按注册时间计算）
+func (r *Record) Stale(now time.Time, staleAfter time.Duration) bool {
+	last := r.EnrolledAt
+	if r.LastCheckIn != nil {
+		last = *r.LastCheckIn
+	}
+	return now.Sub(last) > staleAfter
+}
+
+// StaleAfter 配置的失联阈值
+func StaleAfter() time.Duration {
+	if d := config.GetDaemonConfig().Agents.StaleAfter; d > 0 {
+		return d
+	}
+	return DefaultStaleAfter
+}
+
+// Inventory 控制节点的 agent 清单，保存在状态存储中（配置了 storage.backend 时为持久化后端，否则为配置目录）
+type Inventory struct {
+	backend storage.Storage
+}
+
+// OpenInventory 打开 agent 清单
+func OpenInventory() (*Inventory, error) {
+	backend, err := storage.OpenState(config.GetConfigDir())
+	if err != nil {
+		return nil, fmt.Errorf("打开 agent 清单失败: %w", err)
+	}
+	return &Inventory{backend: backend}, nil
+}
+
+// Get 读取 agent 记录，不存在时返回 ErrUnknownAgent
+func (inv *Inventory) Get(name string) (*Record, error) {
+	data, err := inv.backend.Get(inventoryPrefix + name + ".json")
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAgent, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 agent %s 失败: %w", name, err)
+	}
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("解析 agent %s 失败: %w", name, err)
+	}
+	return &record, nil
+}
+
+// Put 保存 agent 记录
+func (inv *Inventory) Put(record *Record) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := inv.backend.Put(inventoryPrefix+record.Name+".json", data); err != nil {
+		return fmt.Errorf("保存 agent %s 失败: %w", record.Name, err)
+	}
+	return nil
+}
+
+// Delete 删除 agent 记录
+func (inv *Inventory) Delete(name string) error {
+	err := inv.backend.Delete(inventoryPrefix + name + ".json")
+	if errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrUnknownAgent, name)
+	}
+	return err
+}
+
+// List 列出所有 agent，按名称排序
+func (inv *Inventory) List() ([]*Record, error) {
+	keys, err := inv.backend.List(inventoryPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("读取 agent 清单失败: %w", err)
+	}
+	var records []*Record
+	for _, key := range keys {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(key, inventoryPrefix), ".json")
+		if !ok || strings.Contains(name, "/") {
+			continue
+		}
+		record, err := inv.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	return records, nil
+}
+
+// Stale 列出失联的 agent；没有 agent 清单（不是控制节点）时返回空
+func Stale(now time.Time) ([]*Record, error) {
+	inv, err := OpenInventory()
+	if err != nil {
+		return nil, err
+	}
+	records, err := inv.List()
+	if err != nil {
+		return nil, err
+	}
+	staleAfter := StaleAfter()
+	var stale []*Record
+	for _, record := range records {
+		if record.Stale(now, staleAfter) {
+			stale = append(stale, record)
+		}
+	}
+	return stale, nil
+}
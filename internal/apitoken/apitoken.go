@@ -0,0 +1,318 @@
+// Package apitoken 守护进程 HTTP 接口的 API 令牌：每个令牌有权限范围（read、renew、admin）和有效期，
+// 只保存令牌的 SHA-256。令牌和吊销列表保存在状态存储中（配置了 storage.backend 时为持久化后端，
+// 多个守护进程共享；否则为配置目录），吊销立即对所有节点生效
+package apitoken
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/storage"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 权限范围，高级别包含低级别的全部权限
+const (
+	ScopeRead  = "read"  // 只读：查询证书状态（仪表盘）
+	ScopeRenew = "renew" // 触发签发和续期（webhook、按需签发队列）
+	ScopeAdmin = "admin" // 全部权限，包括管理令牌
+)
+
+// scopeLevels 权限范围的级别
+var scopeLevels = map[string]int{ScopeRead: 1, ScopeRenew: 2, ScopeAdmin: 3}
+
+// tokenPrefix 令牌前缀，便于在日志和代码仓库中识别泄露的令牌
+const tokenPrefix = "act_"
+
+// 状态存储中的键。每个吊销记录单独保存在 revoked/ 下，吊销只写入一个新键，
+// 多个节点同时吊销不会互相覆盖；revoked.json 为旧版本的吊销列表，只读取
+const (
+	keyPrefix        = "api-tokens/"
+	revokedKey       = keyPrefix + "revoked.json"
+	revokedKeyPrefix = keyPrefix + "revoked/"
+)
+
+// maxIDAttempts 生成令牌 ID 时遇到已存在的 ID 的最大重试次数
+const maxIDAttempts = 10
+
+// 验证失败的原因
+var (
+	ErrInvalid = errors.New("令牌无效")
+	ErrExpired = errors.New("令牌已过期")
+	ErrRevoked = errors.New("令牌已吊销")
+)
+
+// Token 令牌记录（不包含令牌本身）
+type Token struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	Scope     string     `json:"scope"`
+	Hash      string     `json:"hash,omitempty"` // 令牌的 SHA-256
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 为空时永不过期
+	RevokedAt *time.Time `json:"revoked_at,omitempty"` // 从吊销列表中读取，不保存在令牌记录中
+}
+
+// Expired 令牌是否已过期
+func (t *Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// Allows 令牌的权限范围是否包含 scope
+func (t *Token) Allows(scope string) bool {
+	return ValidScope(t.Scope) && scopeLevels[t.Scope] >= scopeLevels[scope]
+}
+
+// ValidScope 权限范围是否有效
+func ValidScope(scope string) bool {
+	_, ok := scopeLevels[scope]
+	return ok
+}
+
+// Store 令牌存储
+type Store struct {
+	backend storage.Storage
+}
+
+// Open 打开令牌存储
+func Open() (*Store, error) {
+	backend, err := storage.OpenState(config.GetConfigDir())
+	if err != nil {
+		return nil, fmt.Errorf("打开令牌存储失败: %w", err)
+	}
+	return &Store{backend: backend}, nil
+}
+
+// Create 创建令牌，ttl 为 0 时永不过期；返回的令牌只在创建时可见
+func (s *Store) Create(name, scope string, ttl time.Duration) (string, *Token, error) {
+	if !ValidScope(scope) {
+		return "", nil, fmt.Errorf("无效的权限范围 %q（可选 read、renew、admin）", scope)
+	}
+
+	id, err := s.newID()
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := randomBytes(32)
+	if err != nil {
+		return "", nil, err
+	}
+	value := tokenPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	// 有效期使用真实时间，--fake-now 不影响令牌
+	now := time.Now()
+	token := &Token{ID: id, Name: name, Scope: scope, Hash: hash(value), CreatedAt: now}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		token.ExpiresAt = &expires
+	}
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.backend.Put(tokenKey(id), data); err != nil {
+		return "", nil, fmt.Errorf("保存令牌失败: %w", err)
+	}
+	return value, token, nil
+}
+
+// newID 生成未被使用的令牌 ID，ID 只有 4 字节，遇到已存在的 ID 时重新生成
+func (s *Store) newID() (string, error) {
+	for i := 0; i < maxIDAttempts; i++ {
+		idBytes, err := randomBytes(4)
+		if err != nil {
+			return "", err
+		}
+		id := hex.EncodeToString(idBytes)
+		_, err = s.backend.Get(tokenKey(id))
+		if errors.Is(err, storage.ErrNotFound) {
+			return id, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("读取令牌失败: %w", err)
+		}
+	}
+	return "", fmt.Errorf("生成令牌 ID 失败: 连续 %d 次与已有令牌重复", maxIDAttempts)
+}
+
+// List 列出所有令牌（包括已过期和已吊销的令牌），按创建时间排序
+func (s *Store) List() ([]*Token, error) {
+	keys, err := s.backend.List(keyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌列表失败: %w", err)
+	}
+	revoked, err := s.revoked()
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*Token
+	for _, key := range keys {
+		if key == revokedKey || strings.HasPrefix(key, revokedKeyPrefix) || !strings.HasSuffix(key, ".json") {
+			continue
+		}
+		token, err := s.get(strings.TrimSuffix(strings.TrimPrefix(key, keyPrefix), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if at, ok := revoked[token.ID]; ok {
+			token.RevokedAt = &at
+		}
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// Revoke 将令牌加入吊销列表
+func (s *Store) Revoke(id string) error {
+	if _, err := s.get(id); err != nil {
+		return err
+	}
+	revoked, err := s.revoked()
+	if err != nil {
+		return err
+	}
+	if _, ok := revoked[id]; ok {
+		return nil
+	}
+	data, err := json.Marshal(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Put(revokedKeyPrefix+id+".json", data); err != nil {
+		return fmt.Errorf("保存吊销记录失败: %w", err)
+	}
+	return nil
+}
+
+// Verify 验证令牌，返回令牌记录；令牌格式错误、不存在或不匹配时返回 ErrInvalid
+func (s *Store) Verify(value string) (*Token, error) {
+	rest, ok := strings.CutPrefix(value, tokenPrefix)
+	if !ok {
+		return nil, ErrInvalid
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok || !validID(id) {
+		return nil, ErrInvalid
+	}
+
+	token, err := s.get(id)
+	if errors.Is(err, ErrInvalid) {
+		return nil, ErrInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hash(value)), []byte(token.Hash)) != 1 {
+		return nil, ErrInvalid
+	}
+
+	revoked, err := s.revoked()
+	if err != nil {
+		return nil, err
+	}
+	if at, ok := revoked[id]; ok {
+		token.RevokedAt = &at
+		return token, ErrRevoked
+	}
+	if token.Expired(time.Now()) {
+		return token, ErrExpired
+	}
+	return token, nil
+}
+
+// get 读取令牌记录，不存在时返回 ErrInvalid
+func (s *Store) get(id string) (*Token, error) {
+	if !validID(id) {
+		return nil, fmt.Errorf("令牌 ID %q 格式错误: %w", id, ErrInvalid)
+	}
+	data, err := s.backend.Get(tokenKey(id))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("令牌 %s 不存在: %w", id, ErrInvalid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌失败: %w", err)
+	}
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("解析令牌 %s 失败: %w", id, err)
+	}
+	return &token, nil
+}
+
+// revoked 读取吊销列表：令牌 ID -> 吊销时间
+func (s *Store) revoked() (map[string]time.Time, error) {
+	revoked := make(map[string]time.Time)
+	data, err := s.backend.Get(revokedKey)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("读取吊销列表失败: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &revoked); err != nil {
+			return nil, fmt.Errorf("解析吊销列表失败: %w", err)
+		}
+	}
+
+	keys, err := s.backend.List(revokedKeyPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("读取吊销列表失败: %w", err)
+	}
+	for _, key := range keys {
+		id := strings.TrimSuffix(strings.TrimPrefix(key, revokedKeyPrefix), ".json")
+		if !validID(id) {
+			continue
+		}
+		data, err := s.backend.Get(key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取吊销记录失败: %w", err)
+		}
+		var at time.Time
+		if err := json.Unmarshal(data, &at); err != nil {
+			return nil, fmt.Errorf("解析令牌 %s 的吊销记录失败: %w", id, err)
+		}
+		revoked[id] = at
+	}
+	return revoked, nil
+}
+
+func tokenKey(id string) string {
+	return keyPrefix + id + ".json"
+}
+
+// validID 令牌 ID 为 8 位小写十六进制，同时防止拼接存储键时出现路径
+func validID(id string) bool {
+	if len(id) != 8 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,279 @@
+package approval
+
+import (
+	"autocert/internal/audit"
+	"autocert/internal/config"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 需要审批的破坏性操作
+const (
+	ActionDelete   = "delete"   // 删除证书
+	ActionTakeover = "takeover" // 停用其他 ACME 客户端的自动续期
+	ActionGC       = "gc"       // 清理证书
+	ActionRevoke   = "revoke"   // 吊销证书
+)
+
+// 审批请求状态
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusExecuted = "executed"
+)
+
+// defaultTTL 审批请求的默认有效期
+const defaultTTL = 24 * time.Hour
+
+// ErrPending 操作已提交审批，等待批准
+var ErrPending = errors.New("操作需要审批")
+
+// Request 审批请求
+type Request struct {
+	ID          string    `json:"id"`
+	Action      string    `json:"action"`
+	Target      string    `json:"target"` // 操作对象，执行时必须与审批时一致
+	Status      string    `json:"status"`
+	RequestedBy string    `json:"requested_by"`
+	RequestedAt time.Time `json:"requested_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	ReviewedBy  string    `json:"reviewed_by,omitempty"`
+	ReviewedAt  time.Time `json:"reviewed_at,omitempty"`
+	ExecutedAt  time.Time `json:"executed_at,omitempty"`
+}
+
+// Expired 请求是否已过期
+func (r *Request) Expired() bool {
+	return time.Now().After(r.ExpiresAt)
+}
+
+// Required 操作是否需要审批
+func Required(action string) bool {
+	cfg := config.GetApprovalConfig()
+	if !cfg.Enabled {
+		return false
+	}
+	if len(cfg.Actions) == 0 {
+		return true
+	}
+	for _, a := range cfg.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Gate 执行破坏性操作前调用：
+// 不需要审批时直接通过（返回 nil 请求）；未提供请求 ID 时创建审批请求并返回 ErrPending；
+// 提供请求 ID 时校验请求已批准且与本次操作一致。操作成功后调用 MarkExecuted，
+// 请求才会作废（只能成功使用一次），操作失败时可以使用同一个请求重试
+func Gate(action, target, requestID string) (*Request, error) {
+	if !Required(action) {
+		return nil, nil
+	}
+
+	if requestID == "" {
+		req, err := Submit(action, target)
+		if err != nil {
+			return nil, err
+		}
+		return req, ErrPending
+	}
+
+	return Check(requestID, action, target)
+}
+
+// Submit 创建审批请求
+func Submit(action, target string) (*Request, error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	ttl := config.GetApprovalConfig().TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	now := time.Now()
+	req := &Request{
+		ID:          hex.EncodeToString(id),
+		Action:      action,
+		Target:      target,
+		Status:      StatusPending,
+		RequestedBy: audit.CurrentUser(),
+		RequestedAt: now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	if err := save(req); err != nil {
+		return nil, err
+	}
+
+	audit.Record(audit.Entry{Action: "approval.request", Result: audit.ResultPending,
+		Detail: fmt.Sprintf("%s %s (%s)", action, target, req.ID)})
+	return req, nil
+}
+
+// Review 审批人使用令牌批准或拒绝请求，审批人不能是申请人
+func Review(id, approver, token string, approve bool) (*Request, error) {
+	req, err := Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != StatusPending {
+		return nil, fmt.Errorf("请求 %s 状态为 %s，无法审批", id, req.Status)
+	}
+	if req.Expired() {
+		return nil, fmt.Errorf("请求 %s 已过期", id)
+	}
+	if !verifyToken(approver, token) {
+		audit.Record(audit.Entry{Action: "approval.review", Result: audit.ResultDenied,
+			Detail: fmt.Sprintf("审批人 %s 令牌无效 (%s)", approver, id)})
+		return nil, fmt.Errorf("审批人或令牌无效")
+	}
+	if sameUser(req.RequestedBy, approver) {
+		return nil, fmt.Errorf("审批人不能是申请人")
+	}
+
+	req.ReviewedBy = approver
+	req.ReviewedAt = time.Now()
+	result := audit.ResultAllowed
+	req.Status = StatusApproved
+	if !approve {
+		req.Status = StatusRejected
+		result = audit.ResultDenied
+	}
+	if err := save(req); err != nil {
+		return nil, err
+	}
+
+	audit.Record(audit.Entry{Action: "approval.review", Result: result,
+		Detail: fmt.Sprintf("%s 审批 %s %s (%s)", approver, req.Action, req.Target, req.ID)})
+	return req, nil
+}
+
+// Check 校验请求已批准、未过期且与操作一致（操作类型和对象都必须相同）
+func Check(id, action, target string) (*Request, error) {
+	req, err := Load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.Status != StatusApproved:
+		return nil, fmt.Errorf("请求 %s 尚未批准（状态: %s）", id, req.Status)
+	case req.Expired():
+		return nil, fmt.Errorf("请求 %s 已过期", id)
+	case req.Action != action || req.Target != target:
+		return nil, fmt.Errorf("请求 %s 批准的操作是 %s %s，与本次操作不一致", id, req.Action, req.Target)
+	}
+	return req, nil
+}
+
+// MarkExecuted 操作成功后将请求标记为已执行，之后不能再次使用；req 为 nil（不需要审批）时忽略
+func MarkExecuted(req *Request) error {
+	if req == nil {
+		return nil
+	}
+	req.Status = StatusExecuted
+	req.ExecutedAt = time.Now()
+	if err := save(req); err != nil {
+		return err
+	}
+
+	audit.Record(audit.Entry{Action: "approval.execute", Result: audit.ResultSuccess,
+		Detail: fmt.Sprintf("%s %s (%s，批准人 %s)", req.Action, req.Target, req.ID, req.ReviewedBy)})
+	return nil
+}
+
+// Load 读取审批请求
+func Load(id string) (*Request, error) {
+	if strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("无效的请求 ID: %s", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir(), id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("审批请求不存在: %s", id)
+		}
+		return nil, err
+	}
+
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("解析审批请求失败: %w", err)
+	}
+	return &req, nil
+}
+
+// List 列出所有审批请求（按申请时间排序）
+func List() ([]*Request, error) {
+	entries, err := os.ReadDir(dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var requests []*Request
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), ".json"); ok {
+			if req, err := Load(id); err == nil {
+				requests = append(requests, req)
+			}
+		}
+	}
+
+	sort.Slice(requests, func(i, j int) bool { return requests[i].RequestedAt.Before(requests[j].RequestedAt) })
+	return requests, nil
+}
+
+// dir 审批请求目录
+func dir() string {
+	return filepath.Join(config.GetConfigDir(), "approvals")
+}
+
+func save(req *Request) error {
+	if err := os.MkdirAll(dir(), 0700); err != nil {
+		return fmt.Errorf("创建审批目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir(), req.ID+".json"), data, 0600)
+}
+
+// verifyToken 校验审批人令牌（配置中保存令牌的 SHA-256）
+func verifyToken(approver, token string) bool {
+	sum := sha256.Sum256([]byte(token))
+	got := hex.EncodeToString(sum[:])
+
+	for _, a := range config.GetApprovalConfig().Approvers {
+		if a.Name == approver {
+			return subtle.ConstantTimeCompare([]byte(strings.ToLower(a.TokenSHA256)), []byte(got)) == 1
+		}
+	}
+	return false
+}
+
+// sameUser 申请人是否与审批人相同（申请人可能记录为 "alice (sudo root)"）
+func sameUser(requestedBy, approver string) bool {
+	name, _, _ := strings.Cut(requestedBy, " ")
+	return name == approver
+}
@@ -0,0 +1,362 @@
+package assess
+
+import (
+	"autocert/internal/clock"
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 评级，从好到差
+var grades = []string{"A+", "A", "A-", "B", "C", "T", "F"}
+
+// hstsMinAge 评为 A+ 要求的 HSTS max-age（180 天）
+const hstsMinAge = 180 * 24 * 60 * 60
+
+// Options 评估选项
+type Options struct {
+	Domain  string        // 域名（SNI 和证书主机名）
+	Host    string        // 连接的主机地址，为空时使用域名
+	Port    int           // TLS 端口，默认 443
+	Timeout time.Duration // 单次连接超时
+}
+
+// Protocol 协议版本支持情况
+type Protocol struct {
+	Name      string `json:"name"`
+	Supported bool   `json:"supported"`
+}
+
+// Cipher 服务器接受的 TLS 1.2 密码套件
+type Cipher struct {
+	Name           string `json:"name"`
+	Insecure       bool   `json:"insecure"`
+	ForwardSecrecy bool   `json:"forward_secrecy"`
+}
+
+// Chain 证书链检查结果
+type Chain struct {
+	Length        int       `json:"length"`
+	Subject       string    `json:"subject"`
+	Issuer        string    `json:"issuer"`
+	KeyType       string    `json:"key_type"`
+	NotAfter      time.Time `json:"not_after"`
+	HostnameMatch bool      `json:"hostname_match"`
+	Trusted       bool      `json:"trusted"`
+	Complete      bool      `json:"complete"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// HSTS Strict-Transport-Security 响应头
+type HSTS struct {
+	Enabled           bool   `json:"enabled"`
+	MaxAge            int64  `json:"max_age"`
+	IncludeSubDomains bool   `json:"include_subdomains"`
+	Preload           bool   `json:"preload"`
+	Error             string `json:"error,omitempty"`
+}
+
+// Finding 发现的问题和修复建议
+type Finding struct {
+	Check       string `json:"check"`
+	Cap         string `json:"cap"` // 该问题限制的最高评级
+	Message     string `json:"message"`
+	Remediation string `json:"remediation"`
+}
+
+// Report 评估报告
+type Report struct {
+	Domain     string     `json:"domain"`
+	Address    string     `json:"address"`
+	Grade      string     `json:"grade"`
+	Negotiated string     `json:"negotiated"` // 默认握手协商的协议和密码套件
+	Protocols  []Protocol `json:"protocols"`
+	Ciphers    []Cipher   `json:"ciphers"`
+	Chain      Chain      `json:"chain"`
+	HSTS       HSTS       `json:"hsts"`
+	Findings   []Finding  `json:"findings"`
+}
+
+// Run 连接服务器检查协议版本、密码套件、证书链和 HSTS，给出评级和修复建议；
+// 无法建立 TLS 连接时返回错误
+func Run(options Options) (*Report, error) {
+	if options.Host == "" {
+		options.Host = options.Domain
+	}
+	if options.Port == 0 {
+		options.Port = 443
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+
+	report := &Report{
+		Domain:  options.Domain,
+		Address: net.JoinHostPort(options.Host, strconv.Itoa(options.Port)),
+	}
+
+	state, err := handshake(report.Address, options, &tls.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("无法与 %s 建立 TLS 连接: %w", report.Address, err)
+	}
+	report.Negotiated = tls.VersionName(state.Version) + " " + tls.CipherSuiteName(state.CipherSuite)
+
+	report.checkChain(state.PeerCertificates, options.Domain)
+	report.checkProtocols(options)
+	report.checkCiphers(options)
+	report.checkHSTS(options)
+	report.grade()
+	return report, nil
+}
+
+// handshake 以 SNI 为域名完成一次 TLS 握手（不验证证书，证书由 checkChain 单独检查）
+func handshake(address string, options Options, cfg *tls.Config) (*tls.ConnectionState, error) {
+	cfg.ServerName = options.Domain
+	cfg.InsecureSkipVerify = true
+
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	return &state, nil
+}
+
+// checkChain 检查主机名、有效期、信任链和证书链完整性
+func (r *Report) checkChain(certs []*x509.Certificate, domain string) {
+	if len(certs) == 0 {
+		r.addFinding("证书", "F", "服务器没有提供证书", "检查 Web 服务器的 ssl_certificate 配置")
+		return
+	}
+
+	leaf := certs[0]
+	r.Chain = Chain{
+		Length:   len(certs),
+		Subject:  leaf.Subject.CommonName,
+		Issuer:   leaf.Issuer.CommonName,
+		KeyType:  keyType(leaf),
+		NotAfter: leaf.NotAfter,
+	}
+
+	r.Chain.HostnameMatch = leaf.VerifyHostname(domain) == nil
+	if !r.Chain.HostnameMatch {
+		r.addFinding("证书", "F", fmt.Sprintf("证书不包含域名 %s（证书域名: %s）", domain, strings.Join(leaf.DNSNames, ", ")),
+			"为该域名签发证书，或检查 server_name 与 ssl_certificate 是否对应")
+	}
+
+	now := time.Now()
+	switch {
+	case now.After(leaf.NotAfter):
+		r.addFinding("证书", "F", fmt.Sprintf("证书已于 %s 过期", clock.Date(leaf.NotAfter)),
+			"续期证书: autocert renew --domain "+domain+" --force")
+	case now.Before(leaf.NotBefore):
+		r.addFinding("证书", "F", "证书尚未生效", "检查服务器时间")
+	}
+
+	if key, ok := leaf.PublicKey.(*rsa.PublicKey); ok && key.N.BitLen() < 2048 {
+		r.addFinding("证书", "C", fmt.Sprintf("RSA 密钥长度只有 %d 位", key.N.BitLen()), "使用 2048 位以上的 RSA 密钥或 ECDSA 密钥重新签发")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	// 按当前时间验证信任链，过期的中间证书同样导致客户端不信任
+	_, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: now})
+	r.Chain.Trusted = err == nil
+	r.Chain.Complete = err == nil
+
+	if err != nil {
+		r.Chain.Error = err.Error()
+		selfSigned := leaf.CheckSignatureFrom(leaf) == nil
+		var unknownAuthority x509.UnknownAuthorityError
+		var invalid x509.CertificateInvalidError
+		switch {
+		case errors.As(err, &invalid) && invalid.Reason == x509.Expired && invalid.Cert == leaf:
+			// 站点证书的有效期已在上面单独报告
+		case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+			r.addFinding("证书链", "T", fmt.Sprintf("中间证书 %s 已过期或尚未生效", invalid.Cert.Subject.CommonName),
+				"更新证书链：重新续期证书，或使用 CA 当前提供的中间证书")
+		case len(certs) == 1 && !selfSigned && errors.As(err, &unknownAuthority):
+			r.addFinding("证书链", "B", "服务器只发送了站点证书，缺少中间证书",
+				"ssl_certificate 使用包含中间证书的完整证书链（fullchain）")
+		default:
+			r.addFinding("证书链", "T", "证书不受信任: "+err.Error(),
+				"使用受信任 CA 签发的证书（自签名或内部 CA 证书只能用于内部客户端）")
+		}
+	}
+}
+
+// checkProtocols 逐个协议版本握手
+func (r *Report) checkProtocols(options Options) {
+	versions := []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+	supported := make(map[uint16]bool)
+	for _, version := range versions {
+		_, err := handshake(r.Address, options, &tls.Config{MinVersion: version, MaxVersion: version})
+		supported[version] = err == nil
+		r.Protocols = append(r.Protocols, Protocol{Name: tls.VersionName(version), Supported: err == nil})
+	}
+
+	if supported[tls.VersionTLS10] || supported[tls.VersionTLS11] {
+		r.addFinding("协议", "B", "支持已弃用的 TLS 1.0/1.1", "ssl_protocols TLSv1.2 TLSv1.3;")
+	}
+	if !supported[tls.VersionTLS12] && !supported[tls.VersionTLS13] {
+		r.addFinding("协议", "F", "不支持 TLS 1.2 和 TLS 1.3", "ssl_protocols TLSv1.2 TLSv1.3;")
+	}
+	if !supported[tls.VersionTLS13] {
+		r.addFinding("协议", "A-", "不支持 TLS 1.3", "ssl_protocols TLSv1.2 TLSv1.3;（需要 OpenSSL 1.1.1 以上）")
+	}
+}
+
+// checkCiphers 逐个测试 TLS 1.2 密码套件（TLS 1.3 的套件均为安全套件，不单独测试）
+func (r *Report) checkCiphers(options Options) {
+	suites := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+
+	var insecure, noForwardSecrecy []string
+	for _, suite := range suites {
+		if !supportsTLS12(suite) {
+			continue
+		}
+		cfg := &tls.Config{MinVersion: tls.VersionTLS12, MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{suite.ID}}
+		if _, err := handshake(r.Address, options, cfg); err != nil {
+			continue
+		}
+
+		cipher := Cipher{
+			Name:           suite.Name,
+			Insecure:       suite.Insecure,
+			ForwardSecrecy: strings.HasPrefix(suite.Name, "TLS_ECDHE_"),
+		}
+		r.Ciphers = append(r.Ciphers, cipher)
+		if cipher.Insecure {
+			insecure = append(insecure, cipher.Name)
+		} else if !cipher.ForwardSecrecy {
+			noForwardSecrecy = append(noForwardSecrecy, cipher.Name)
+		}
+	}
+
+	const recommended = "ssl_ciphers ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-RSA-AES256-GCM-SHA384:ECDHE-ECDSA-CHACHA20-POLY1305:ECDHE-RSA-CHACHA20-POLY1305;"
+	if len(insecure) > 0 {
+		r.addFinding("密码套件", "C", "接受不安全的密码套件: "+strings.Join(insecure, ", "), recommended)
+	}
+	if len(noForwardSecrecy) > 0 {
+		r.addFinding("密码套件", "B", "接受不支持前向保密的密码套件: "+strings.Join(noForwardSecrecy, ", "), recommended)
+	}
+}
+
+// supportsTLS12 密码套件是否可用于 TLS 1.2
+func supportsTLS12(suite *tls.CipherSuite) bool {
+	for _, version := range suite.SupportedVersions {
+		if version == tls.VersionTLS12 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHSTS 请求首页检查 Strict-Transport-Security 响应头（不跟随重定向）
+func (r *Report) checkHSTS(options Options) {
+	const remediation = `add_header Strict-Transport-Security "max-age=31536000; includeSubDomains" always;`
+
+	dialer := &net.Dialer{Timeout: options.Timeout}
+	client := &http.Client{
+		Timeout: options.Timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, r.Address)
+			},
+			TLSClientConfig: &tls.Config{ServerName: options.Domain, InsecureSkipVerify: true},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	url := "https://" + options.Domain
+	if options.Port != 443 {
+		url += ":" + strconv.Itoa(options.Port)
+	}
+	resp, err := client.Get(url + "/")
+	if err != nil {
+		r.HSTS.Error = err.Error()
+		r.addFinding("HSTS", "A", "无法检查 HSTS: "+err.Error(), remediation)
+		return
+	}
+	resp.Body.Close()
+
+	header := resp.Header.Get("Strict-Transport-Security")
+	if header == "" {
+		r.addFinding("HSTS", "A", "没有设置 HSTS（Strict-Transport-Security）", remediation)
+		return
+	}
+
+	r.HSTS.Enabled = true
+	for _, directive := range strings.Split(header, ";") {
+		directive = strings.TrimSpace(directive)
+		switch {
+		case strings.HasPrefix(strings.ToLower(directive), "max-age="):
+			r.HSTS.MaxAge, _ = strconv.ParseInt(strings.Trim(directive[len("max-age="):], `"`), 10, 64)
+		case strings.EqualFold(directive, "includeSubDomains"):
+			r.HSTS.IncludeSubDomains = true
+		case strings.EqualFold(directive, "preload"):
+			r.HSTS.Preload = true
+		}
+	}
+	if r.HSTS.MaxAge < hstsMinAge {
+		r.addFinding("HSTS", "A", fmt.Sprintf("HSTS max-age 只有 %d 秒（建议至少 180 天）", r.HSTS.MaxAge), remediation)
+	}
+}
+
+// addFinding 记录问题
+func (r *Report) addFinding(check, cap, message, remediation string) {
+	r.Findings = append(r.Findings, Finding{Check: check, Cap: cap, Message: message, Remediation: remediation})
+}
+
+// grade 按所有问题中最低的评级上限给出评级，没有问题且启用了 HSTS 时为 A+
+func (r *Report) grade() {
+	r.Grade = "A+"
+	for _, finding := range r.Findings {
+		if Compare(finding.Cap, r.Grade) > 0 {
+			r.Grade = finding.Cap
+		}
+	}
+}
+
+// Compare 比较两个评级：a 比 b 差时返回正数，相同返回 0，更好返回负数
+func Compare(a, b string) int {
+	return gradeRank(a) - gradeRank(b)
+}
+
+// ValidGrade 是否为有效的评级
+func ValidGrade(grade string) bool {
+	return gradeRank(grade) < len(grades)
+}
+
+// gradeRank 评级的序号，未知评级排在最后
+func gradeRank(grade string) int {
+	for i, g := range grades {
+		if strings.EqualFold(g, grade) {
+			return i
+		}
+	}
+	return len(grades)
+}
+
+// keyType 描述证书公钥类型
+func keyType(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
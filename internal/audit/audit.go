@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 审计结果
+const (
+	ResultDenied  = "denied"  // 被策略拒绝
+	ResultAllowed = "allowed" // 已批准
+	ResultPending = "pending" // 等待审批
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Entry 审计日志记录（每行一个 JSON 对象）
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	User    string    `json:"user"`
+	Action  string    `json:"action"`
+	Domains []string  `json:"domains,omitempty"`
+	Result  string    `json:"result"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+var mu sync.Mutex
+
+// Record 追加一条审计日志；写入失败只记录警告
+func Record(entry Entry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+	if entry.User == "" {
+		entry.User = CurrentUser()
+	}
+
+	if err := write(entry); err != nil {
+		logger.Warn("写入审计日志失败", "path", Path(), "error", err)
+	}
+}
+
+// Path 返回审计日志文件路径
+func Path() string {
+	if path := config.GetAuditConfig().File; path != "" {
+		return path
+	}
+	return filepath.Join(config.GetLogDir(), "autocert-audit.log")
+}
+
+func write(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// CurrentUser 返回执行操作的用户（通过 sudo 执行时记录原用户）
+func CurrentUser() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		name = sudoUser + " (sudo " + name + ")"
+	}
+	return name
+}
@@ -0,0 +1,187 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// 归档的压缩方式
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+	CompressionNone = "none"
+)
+
+// 各压缩方式支持的压缩级别
+var compressionLevels = map[string][2]int{
+	CompressionGzip: {gzip.BestSpeed, gzip.BestCompression},
+	CompressionZstd: {1, 19},
+}
+
+// 压缩格式的文件头
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// ArchiveExt 归档文件的扩展名，例如 tar + zstd 为 .tar.zst
+func ArchiveExt(format, compression string) string {
+	if strings.EqualFold(format, "zip") {
+		return ".zip"
+	}
+	switch strings.ToLower(compression) {
+	case CompressionZstd:
+		return ".tar.zst"
+	case CompressionNone:
+		return ".tar"
+	}
+	return ".tar.gz"
+}
+
+// checkCompression 校验压缩方式和级别，level 为 0 时使用默认级别
+func checkCompression(compression string, level int) error {
+	switch compression {
+	case CompressionGzip, CompressionZstd:
+	case CompressionNone:
+		if level != 0 {
+			return fmt.Errorf("不压缩（%s）时不能指定压缩级别", CompressionNone)
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的压缩方式: %s（可选 gzip、zstd、none）", compression)
+	}
+	if levels := compressionLevels[compression]; level != 0 && (level < levels[0] || level > levels[1]) {
+		return fmt.Errorf("%s 的压缩级别应为 %d-%d，实际为 %d", compression, levels[0], levels[1], level)
+	}
+	if compression == CompressionZstd {
+		return requireZstd("压缩")
+	}
+	return nil
+}
+
+// requireZstd 检查系统中是否安装了 zstd 命令，action 为压缩或解压
+func requireZstd(action string) error {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return fmt.Errorf("zstd %s需要安装 zstd 命令（例如 apt install zstd、yum install zstd）: %w", action, err)
+	}
+	return nil
+}
+
+// detectCompression 按文件头判断 tar 归档的压缩方式
+func detectCompression(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// nopWriteCloser 不压缩时直接写入下层
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// newCompressor 创建压缩写入器，Close 时写入压缩流的结尾（不关闭 w）
+func newCompressor(w io.Writer, compression string, level int) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionZstd:
+		return newZstdWriter(w, level)
+	}
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// newDecompressor 创建解压读取器
+func newDecompressor(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionZstd:
+		if err := requireZstd("解压"); err != nil {
+			return nil, fmt.Errorf("归档使用 zstd 压缩: %w", err)
+		}
+		return newZstdReader(r)
+	}
+	return gzip.NewReader(r)
+}
+
+// zstdStream 通过 zstd 命令压缩或解压，数据经管道传递，不产生临时文件
+type zstdStream struct {
+	cmd    *exec.Cmd
+	pipe   io.Closer
+	stderr *bytes.Buffer
+	closed bool
+	io.Reader
+	io.Writer
+}
+
+func newZstdWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	args := []string{"-q", "-c"}
+	if level != 0 {
+		args = append(args, fmt.Sprintf("-%d", level))
+	}
+	cmd := exec.Command("zstd", args...)
+	cmd.Stdout = w
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stream := &zstdStream{pipe: stdin, Writer: stdin}
+	if err := stream.start(cmd); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func newZstdReader(r io.Reader) (io.ReadCloser, error) {
+	cmd := exec.Command("zstd", "-q", "-d", "-c")
+	cmd.Stdin = r
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stream := &zstdStream{pipe: stdout, Reader: stdout}
+	if err := stream.start(cmd); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (s *zstdStream) start(cmd *exec.Cmd) error {
+	s.cmd = cmd
+	s.stderr = &bytes.Buffer{}
+	cmd.Stderr = s.stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 zstd 失败: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭管道并等待 zstd 结束
+func (s *zstdStream) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.pipe.Close()
+	if err := s.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+			return fmt.Errorf("zstd 失败: %s", msg)
+		}
+		return fmt.Errorf("zstd 失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"autocert/internal/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxChainLength 差异备份链的最大长度，防止基准互相引用时无限循环
+const maxChainLength = 100
+
+// BaseArchive 差异备份（格式版本 2.0）引用的基准归档
+type BaseArchive struct {
+	File   string `json:"file"`   // 基准归档的文件名，导入时在差异备份所在目录中查找
+	SHA256 string `json:"sha256"` // 基准归档的 SHA-256，防止基准被替换
+}
+
+// chainLink 差异备份链中的一个归档
+type chainLink struct {
+	src      *archiveSource
+	metadata *BackupMetadata
+	isZip    bool
+}
+
+// hashFiles 计算要导出的文件的 SHA-256（归档路径 -> 哈希），读取失败的文件不记录
+func hashFiles(files map[string]string) map[string]string {
+	hashes := make(map[string]string, len(files))
+	for archivePath, localPath := range files {
+		sum, err := fileSHA256(localPath)
+		if err != nil {
+			logger.Warn("计算文件哈希失败", "file", localPath, "error", err)
+			continue
+		}
+		hashes[archivePath] = sum
+	}
+	return hashes
+}
+
+// diffAgainstBase 与基准归档的清单比较，返回内容有变化或新增的文件，并在元数据中记录基准归档
+func diffAgainstBase(basePath, outputFile string, files map[string]string, metadata *BackupMetadata) (map[string]string, error) {
+	base, _, err := openArchive(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取基准备份失败: %w", err)
+	}
+	if base.Hashes == nil {
+		return nil, fmt.Errorf("基准备份 %s 没有文件哈希（由 1.3 之前的格式创建），请先进行一次完整导出", basePath)
+	}
+	sum, err := fileSHA256(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取基准备份失败: %w", err)
+	}
+
+	changed := make(map[string]string)
+	for archivePath, localPath := range files {
+		if hash, ok := metadata.Hashes[archivePath]; !ok || base.Hashes[archivePath] != hash {
+			changed[archivePath] = localPath
+		}
+	}
+
+	if !IsStdio(outputFile) {
+		if absDir(basePath) != absDir(outputFile) {
+			logger.Warn("基准备份与输出不在同一目录，导入时需要将两者放在同一目录", "base", basePath, "output", outputFile)
+		}
+	}
+	metadata.Base = &BaseArchive{File: filepath.Base(basePath), SHA256: sum}
+	metadata.Version = DiffMetadataVersion
+	logger.Info("差异导出", "base", basePath, "changed", len(changed), "unchanged", len(files)-len(changed))
+	return changed, nil
+}
+
+// absDir 文件所在目录的绝对路径
+func absDir(path string) string {
+	dir, _ := filepath.Abs(filepath.Dir(path))
+	return dir
+}
+
+// openArchive 读取归档文件的元数据
+func openArchive(path string) (*BackupMetadata, bool, error) {
+	src := &archiveSource{path: path}
+	isZip, err := src.isZip()
+	if err != nil {
+		return nil, false, err
+	}
+	metadata, err := readArchiveMetadata(src, isZip)
+	if err != nil {
+		return nil, false, err
+	}
+	return metadata, isZip, nil
+}
+
+// resolveChain 从差异备份开始沿基准引用找到完整备份，返回的链从新到旧。
+// 每个基准都校验 SHA-256 和签名；从标准输入导入时在当前目录中查找基准
+func (m *Manager) resolveChain(options *ImportOptions, head chainLink) ([]chainLink, error) {
+	dir := filepath.Dir(options.InputFile)
+	if IsStdio(options.InputFile) {
+		dir = "."
+	}
+
+	chain := []chainLink{head}
+	for link := head; link.metadata.Base != nil; {
+		if len(chain) > maxChainLength {
+			return nil, fmt.Errorf("差异备份链超过 %d 个归档", maxChainLength)
+		}
+		base := link.metadata.Base
+		basePath := filepath.Join(dir, base.File)
+
+		sum, err := fileSHA256(basePath)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("找不到基准备份 %s，请将其放在差异备份所在的目录", basePath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取基准备份失败: %w", err)
+		}
+		if !strings.EqualFold(sum, base.SHA256) {
+			return nil, fmt.Errorf("基准备份 %s 与差异备份记录的不一致（SHA-256 不匹配）", basePath)
+		}
+		if err := m.checkSignature(&ImportOptions{InputFile: basePath, RequireSignature: options.RequireSignature}); err != nil {
+			return nil, err
+		}
+
+		metadata, isZip, err := openArchive(basePath)
+		if err != nil {
+			return nil, fmt.Errorf("读取基准备份 %s 失败: %w", basePath, err)
+		}
+		logger.Info("差异备份的基准", "base", basePath, "created_at", metadata.CreatedAt)
+
+		link = chainLink{src: &archiveSource{path: basePath}, metadata: metadata, isZip: isZip}
+		chain = append(chain, link)
+	}
+	return chain, nil
+}
+
+// planChain 为最新状态的每个文件选择提供该内容的归档（从新到旧查找），
+// 每个文件只从一个归档中解压，旧版本不会覆盖新版本
+func planChain(chain []chainLink) error {
+	final := chain[0].metadata.Hashes
+	assigned := make(map[string]bool, len(final))
+	for _, link := range chain {
+		link.metadata.extract = make(map[string]bool)
+		for _, name := range link.metadata.Files {
+			if !assigned[name] && final[name] != "" && link.metadata.Hashes[name] == final[name] {
+				link.metadata.extract[name] = true
+				assigned[name] = true
+			}
+		}
+	}
+	for name := range final {
+		if !assigned[name] {
+			return fmt.Errorf("差异备份链不完整，找不到文件 %s", name)
+		}
+	}
+	return nil
+}
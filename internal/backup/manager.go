@@ -5,12 +5,20 @@ import (
 	"archive/zip"
 	"autocert/internal/config"
 	"autocert/internal/logger"
-	"compress/gzip"
+	"autocert/internal/progress"
+	"autocert/internal/scheduler"
+	"autocert/internal/secmem"
+	"autocert/internal/system"
+	"compress/flate"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"time"
 )
@@ -23,24 +31,39 @@ type Manager struct {
 
 // ExportOptions 导出选项
 type ExportOptions struct {
-	OutputFile string
-	Format     string // tar.gz, zip
-	Domain     string // 可选，只导出指定域名
+	OutputFile      string
+	Format          string // tar（tar.gz、tgz 为兼容写法）, zip
+	Compression     string // gzip（默认）, zstd, none
+	Level           int    // 压缩级别，0 为默认级别
+	Base            string // 可选，差异导出的基准归档，只导出与其相比有变化的文件
+	Domain          string // 可选，只导出指定域名
+	AutocertVersion string // 写入元数据，便于导入时提示版本不兼容
 }
 
 // ImportOptions 导入选项
 type ImportOptions struct {
-	InputFile       string
-	RestoreSchedule bool
+	InputFile        string
+	RestoreSchedule  bool
+	RequireSignature bool   // 要求备份带有可信签名
+	ConfigFile       string // 恢复为 Windows 服务时服务使用的配置文件
 }
 
 // BackupMetadata 备份元数据
 type BackupMetadata struct {
-	Version     string    `json:"version"`
-	CreatedAt   time.Time `json:"created_at"`
-	Platform    string    `json:"platform"`
-	Domains     []string  `json:"domains"`
-	HasSchedule bool      `json:"has_schedule"`
+	Version         string                 `json:"version"`
+	AutocertVersion string                 `json:"autocert_version,omitempty"` // 1.1 起
+	CreatedAt       time.Time              `json:"created_at"`
+	Platform        string                 `json:"platform"`
+	Domains         []string               `json:"domains"`
+	HasSchedule     bool                   `json:"has_schedule"`
+	Files           []string               `json:"files"`               // 1.1 起，归档中的文件清单
+	Schedules       []scheduler.Definition `json:"schedules,omitempty"` // 1.2 起，导出时已安装的定时任务
+	Hashes          map[string]string      `json:"hashes,omitempty"`    // 1.3 起，导出时全部文件的 SHA-256（包括差异备份中未变化的文件）
+	Base            *BaseArchive           `json:"base,omitempty"`      // 差异备份（2.0 起）引用的基准归档
+
+	source      string          // 归档中记录的原始版本
+	legacyPaths bool            // 1.0 的归档路径可能使用反斜杠
+	extract     map[string]bool // 差异备份链中该归档需要解压的文件，nil 时解压全部
 }
 
 // NewManager 创建备份管理器
@@ -53,7 +76,15 @@ func NewManager() *Manager {
 
 // Export 导出证书和配置
 func (m *Manager) Export(options *ExportOptions) error {
-	logger.Info("开始导出", "format", options.Format, "output", options.OutputFile)
+	logger.Info("开始导出", "format", options.Format, "compression", options.Compression, "output", options.OutputFile)
+
+	if options.Compression == "" {
+		options.Compression = CompressionGzip
+	}
+	options.Compression = strings.ToLower(options.Compression)
+	if err := checkCompression(options.Compression, options.Level); err != nil {
+		return err
+	}
 
 	// 收集要导出的文件
 	files, err := m.collectFiles(options.Domain)
@@ -61,45 +92,195 @@ func (m *Manager) Export(options *ExportOptions) error {
 		return fmt.Errorf("收集文件失败: %w", err)
 	}
 
+	// 收集已安装的定时任务定义，迁移时一并恢复自动续期
+	schedules := scheduler.ExportDefinitions()
+
 	// 创建元数据
-	metadata, err := m.createMetadata(files, options.Domain)
+	metadata, err := m.createMetadata(files, options.AutocertVersion)
 	if err != nil {
 		return fmt.Errorf("创建元数据失败: %w", err)
 	}
+	metadata.Schedules = schedules
+	metadata.HasSchedule = len(schedules) > 0
+	metadata.Hashes = hashFiles(files)
+
+	// 差异导出：只写入与基准归档相比有变化的文件，清单中仍记录全部文件的哈希
+	if options.Base != "" {
+		if files, err = diffAgainstBase(options.Base, options.OutputFile, files, metadata); err != nil {
+			return err
+		}
+	}
+
+	// 检查输出位置的剩余空间（按未压缩大小估算），输出到标准输出时由接收方负责
+	toStdout := IsStdio(options.OutputFile)
+	if !toStdout {
+		if err := system.CheckDiskSpace(filepath.Dir(options.OutputFile), totalSize(files)+config.GetStorageConfig().MinFreeBytes()); err != nil {
+			return err
+		}
+	}
 
 	// 根据格式选择导出方法
 	switch strings.ToLower(options.Format) {
-	case "tar.gz", "tgz":
-		return m.exportTarGz(options.OutputFile, files, metadata)
+	case "tar", "tar.gz", "tgz":
+		err = m.exportTar(options, files, metadata)
 	case "zip":
-		return m.exportZip(options.OutputFile, files, metadata)
+		if options.Compression == CompressionZstd {
+			return fmt.Errorf("zip 格式不支持 zstd 压缩，请使用 --format tar")
+		}
+		err = m.exportZip(options, files, metadata)
 	default:
 		return fmt.Errorf("不支持的导出格式: %s", options.Format)
 	}
+
+	// 配置了签名私钥时生成签名文件；标准输出没有对应的签名文件位置
+	if err == nil && !toStdout {
+		err = signArchive(options.OutputFile)
+	} else if err == nil && config.GetBackupConfig().SigningKey != "" {
+		logger.Warn("输出到标准输出时不生成签名文件", "signing_key", config.GetBackupConfig().SigningKey)
+	}
+
+	// 导出失败时删除不完整的归档文件
+	if err != nil && !toStdout {
+		os.Remove(options.OutputFile)
+	}
+	return err
+}
+
+// totalSize 计算文件的总大小
+func totalSize(files map[string]string) uint64 {
+	var size uint64
+	for _, localPath := range files {
+		if info, err := os.Stat(localPath); err == nil {
+			size += uint64(info.Size())
+		}
+	}
+	return size
 }
 
 // Import 导入证书和配置
 func (m *Manager) Import(options *ImportOptions) error {
 	logger.Info("开始导入", "input", options.InputFile)
 
-	// 检查文件是否存在
-	if _, err := os.Stat(options.InputFile); os.IsNotExist(err) {
-		return fmt.Errorf("导入文件不存在: %s", options.InputFile)
+	var src *archiveSource
+	var size int64
+	if IsStdio(options.InputFile) {
+		stdin, err := readStdin()
+		if err != nil {
+			return err
+		}
+		defer stdin.release()
+		src, size = stdin, int64(len(stdin.data))
+	} else {
+		// 检查文件是否存在
+		info, err := os.Stat(options.InputFile)
+		if os.IsNotExist(err) {
+			return fmt.Errorf("导入文件不存在: %s", options.InputFile)
+		}
+		if err == nil {
+			size = info.Size()
+		}
+		src = &archiveSource{path: options.InputFile}
+	}
+
+	// 按内容选择导入方法：zip，或 gzip、zstd 压缩或不压缩的 tar
+	isZip, err := src.isZip()
+	if err != nil {
+		return fmt.Errorf("读取导入文件失败: %w", err)
+	}
+
+	// 检查证书目录的剩余空间（按归档大小估算）
+	if size > 0 {
+		if err := system.CheckDiskSpace(m.certDir, uint64(size)+config.GetStorageConfig().MinFreeBytes()); err != nil {
+			return err
+		}
 	}
 
-	// 根据文件扩展名选择导入方法
-	ext := strings.ToLower(filepath.Ext(options.InputFile))
-	switch ext {
-	case ".gz":
-		if strings.HasSuffix(options.InputFile, ".tar.gz") {
-			return m.importTarGz(options.InputFile, options.RestoreSchedule)
+	// 校验签名，防止被篡改的备份写入证书和 Web 服务器配置
+	if err := m.checkSignature(options); err != nil {
+		return err
+	}
+
+	// 解压前读取并校验元数据，旧版本的布局在这里升级
+	metadata, err := readArchiveMetadata(src, isZip)
+	if err != nil {
+		return fmt.Errorf("读取备份元数据失败: %w", err)
+	}
+	logger.Info("备份元数据", "version", metadata.source, "created_at", metadata.CreatedAt, "domains", len(metadata.Domains))
+
+	// 差异备份：沿基准引用找到完整备份，每个文件从包含其最新内容的归档中解压
+	chain := []chainLink{{src: src, metadata: metadata, isZip: isZip}}
+	if metadata.Base != nil {
+		if chain, err = m.resolveChain(options, chain[0]); err != nil {
+			return err
 		}
-		return fmt.Errorf("不支持的文件格式: %s", options.InputFile)
-	case ".zip":
-		return m.importZip(options.InputFile, options.RestoreSchedule)
-	default:
-		return fmt.Errorf("不支持的文件格式: %s", options.InputFile)
+		if err := planChain(chain); err != nil {
+			return err
+		}
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		link := chain[i]
+		if link.isZip {
+			err = m.importZip(link.src, link.metadata, options.RestoreSchedule)
+		} else {
+			err = m.importTar(link.src, link.metadata, options.RestoreSchedule)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.RestoreSchedule {
+		m.restoreSchedules(metadata, options.ConfigFile)
+	}
+	return nil
+}
+
+// restoreSchedules 按当前平台重新安装备份中的定时任务，例如 Linux 的 cron 任务
+// 迁移到 Windows 后安装为任务计划程序中的任务；失败时只记录警告，不影响证书导入
+func (m *Manager) restoreSchedules(metadata *BackupMetadata, configFile string) {
+	if len(metadata.Schedules) == 0 {
+		return
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		logger.Warn("获取执行文件路径失败，跳过恢复定时任务", "error", err)
+		return
+	}
+	for _, definition := range metadata.Schedules {
+		if err := scheduler.RestoreDefinition(definition, execPath, configFile); err != nil {
+			logger.Warn("恢复定时任务失败", "taskName", definition.Name, "backend", definition.Backend, "error", err)
+		}
+	}
+}
+
+// checkSignature 要求签名时必须有可信签名；不要求时只要备份带有签名文件就校验，未配置可信公钥时跳过
+func (m *Manager) checkSignature(options *ImportOptions) error {
+	required := options.RequireSignature || config.GetBackupConfig().RequireSignature
+	if IsStdio(options.InputFile) {
+		// 标准输入没有对应的签名文件
+		if required {
+			return errors.New("从标准输入导入时无法校验签名，要求签名时请先保存备份和签名文件再导入")
+		}
+		return nil
 	}
+	if !required {
+		if _, err := os.Stat(SignaturePath(options.InputFile)); err != nil {
+			return nil
+		}
+		if len(config.GetBackupConfig().TrustedKeys) == 0 {
+			logger.Warn("备份带有签名，但未配置 backup.trusted_keys，跳过签名校验", "input", options.InputFile)
+			return nil
+		}
+	}
+
+	signature, err := VerifyArchive(options.InputFile)
+	if err != nil {
+		return fmt.Errorf("备份签名校验失败: %w", err)
+	}
+	logger.Info("备份签名校验通过", "key_id", signature.KeyID, "signed_at", signature.CreatedAt)
+	return nil
 }
 
 // collectFiles 收集要导出的文件
@@ -152,7 +333,7 @@ func (m *Manager) addDomainFiles(files map[string]string, domain, domainDir stri
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			localPath := filepath.Join(domainDir, entry.Name())
-			archivePath := filepath.Join("certs", domain, entry.Name())
+			archivePath := path.Join("certs", domain, entry.Name())
 			files[archivePath] = localPath
 		}
 	}
@@ -175,7 +356,7 @@ func (m *Manager) addConfigFiles(files map[string]string) error {
 			for _, entry := range entries {
 				if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yaml") {
 					localPath := filepath.Join(m.configDir, entry.Name())
-					archivePath := filepath.Join("config", entry.Name())
+					archivePath := path.Join("config", entry.Name())
 					files[archivePath] = localPath
 				}
 			}
@@ -188,7 +369,7 @@ func (m *Manager) addConfigFiles(files map[string]string) error {
 		for _, configFile := range configFiles {
 			configPath := filepath.Join(homeDir, configFile)
 			if _, err := os.Stat(configPath); err == nil {
-				archivePath := filepath.Join("config", configFile)
+				archivePath := path.Join("config", configFile)
 				files[archivePath] = configPath
 			}
 		}
@@ -198,12 +379,13 @@ func (m *Manager) addConfigFiles(files map[string]string) error {
 }
 
 // createMetadata 创建备份元数据
-func (m *Manager) createMetadata(files map[string]string, domain string) (*BackupMetadata, error) {
+func (m *Manager) createMetadata(files map[string]string, autocertVersion string) (*BackupMetadata, error) {
 	metadata := &BackupMetadata{
-		Version:     "1.0",
-		CreatedAt:   time.Now(),
-		Platform:    getOSInfo(),
-		HasSchedule: false,
+		Version:         MetadataVersion,
+		AutocertVersion: autocertVersion,
+		CreatedAt:       time.Now(),
+		Platform:        runtime.GOOS + "/" + runtime.GOARCH,
+		Files:           []string{},
 	}
 
 	// 提取域名列表
@@ -220,55 +402,73 @@ func (m *Manager) createMetadata(files map[string]string, domain string) (*Backu
 	for d := range domainsMap {
 		metadata.Domains = append(metadata.Domains, d)
 	}
-
-	// 检查是否有定时任务（这里简化处理）
-	metadata.HasSchedule = true
+	sort.Strings(metadata.Domains)
 
 	return metadata, nil
 }
 
-// exportTarGz 导出为 tar.gz 格式
-func (m *Manager) exportTarGz(outputFile string, files map[string]string, metadata *BackupMetadata) error {
-	logger.Debug("导出为 tar.gz 格式", "output", outputFile)
+// exportTar 导出为 tar 格式，按 options.Compression 压缩
+func (m *Manager) exportTar(options *ExportOptions, files map[string]string, metadata *BackupMetadata) error {
+	logger.Debug("导出为 tar 格式", "output", options.OutputFile, "compression", options.Compression, "level", options.Level)
 
 	// 创建输出文件
-	outFile, err := os.Create(outputFile)
+	outFile, err := createOutput(options.OutputFile)
 	if err != nil {
 		return err
 	}
 	defer outFile.Close()
 
-	// 创建 gzip writer
-	gzWriter := gzip.NewWriter(outFile)
-	defer gzWriter.Close()
+	// 创建压缩 writer
+	compressor, err := newCompressor(outFile, options.Compression, options.Level)
+	if err != nil {
+		return err
+	}
+	defer compressor.Close()
 
 	// 创建 tar writer
-	tarWriter := tar.NewWriter(gzWriter)
+	tarWriter := tar.NewWriter(compressor)
 	defer tarWriter.Close()
 
-	// 添加元数据文件
-	if err := m.addMetadataToTar(tarWriter, metadata); err != nil {
-		return err
-	}
-
 	// 添加文件
+	bar := progress.NewBar("导出文件", len(files))
 	for archivePath, localPath := range files {
 		if err := m.addFileToTar(tarWriter, archivePath, localPath); err != nil {
 			logger.Warn("跳过文件", "file", localPath, "error", err)
-			continue
+			delete(metadata.Hashes, archivePath)
+		} else {
+			metadata.Files = append(metadata.Files, archivePath)
 		}
+		bar.Increment()
+	}
+	bar.Finish(nil)
+
+	// 最后添加元数据文件，文件清单只包含实际写入的文件
+	sort.Strings(metadata.Files)
+	if err := m.addMetadataToTar(tarWriter, metadata); err != nil {
+		return err
 	}
 
-	logger.Debug("tar.gz 导出完成")
+	// 依次关闭写入器，写入失败（例如磁盘已满）时返回错误
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+	if err := outFile.Close(); err != nil {
+		return err
+	}
+
+	logger.Debug("tar 导出完成")
 	return nil
 }
 
-// exportZip 导出为 zip 格式
-func (m *Manager) exportZip(outputFile string, files map[string]string, metadata *BackupMetadata) error {
-	logger.Debug("导出为 zip 格式", "output", outputFile)
+// exportZip 导出为 zip 格式，gzip 对应 zip 的 deflate 压缩，none 时只存储不压缩
+func (m *Manager) exportZip(options *ExportOptions, files map[string]string, metadata *BackupMetadata) error {
+	logger.Debug("导出为 zip 格式", "output", options.OutputFile, "compression", options.Compression, "level", options.Level)
 
 	// 创建输出文件
-	outFile, err := os.Create(outputFile)
+	outFile, err := createOutput(options.OutputFile)
 	if err != nil {
 		return err
 	}
@@ -277,44 +477,65 @@ func (m *Manager) exportZip(outputFile string, files map[string]string, metadata
 	// 创建 zip writer
 	zipWriter := zip.NewWriter(outFile)
 	defer zipWriter.Close()
-
-	// 添加元数据文件
-	if err := m.addMetadataToZip(zipWriter, metadata); err != nil {
-		return err
+	if level, ok := zipLevel(options); ok {
+		zipWriter.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, level)
+		})
 	}
 
 	// 添加文件
+	bar := progress.NewBar("导出文件", len(files))
 	for archivePath, localPath := range files {
 		if err := m.addFileToZip(zipWriter, archivePath, localPath); err != nil {
 			logger.Warn("跳过文件", "file", localPath, "error", err)
-			continue
+			delete(metadata.Hashes, archivePath)
+		} else {
+			metadata.Files = append(metadata.Files, archivePath)
 		}
+		bar.Increment()
+	}
+	bar.Finish(nil)
+
+	// 最后添加元数据文件，文件清单只包含实际写入的文件
+	sort.Strings(metadata.Files)
+	if err := m.addMetadataToZip(zipWriter, metadata); err != nil {
+		return err
+	}
+
+	// 依次关闭写入器，写入失败（例如磁盘已满）时返回错误
+	if err := zipWriter.Close(); err != nil {
+		return err
+	}
+	if err := outFile.Close(); err != nil {
+		return err
 	}
 
 	logger.Debug("zip 导出完成")
 	return nil
 }
 
-// importTarGz 导入 tar.gz 格式
-func (m *Manager) importTarGz(inputFile string, restoreSchedule bool) error {
-	logger.Debug("导入 tar.gz 格式", "input", inputFile)
-
-	// 打开文件
-	file, err := os.Open(inputFile)
-	if err != nil {
-		return err
+// zipLevel zip 条目的 deflate 压缩级别，使用默认级别时返回 false。
+// none 使用不压缩的 deflate 块，内容原样写入，便于目标存储去重
+func zipLevel(options *ExportOptions) (int, bool) {
+	if options.Compression == CompressionNone {
+		return flate.NoCompression, true
 	}
-	defer file.Close()
+	return options.Level, options.Level != 0
+}
+
+// importTar 导入 tar 格式（gzip、zstd 压缩或不压缩）
+func (m *Manager) importTar(src *archiveSource, metadata *BackupMetadata, restoreSchedule bool) error {
+	logger.Debug("导入 tar 格式", "input", src.path)
 
-	// 创建 gzip reader
-	gzReader, err := gzip.NewReader(file)
+	// 打开文件并解压
+	reader, closeReader, err := src.openTar()
 	if err != nil {
 		return err
 	}
-	defer gzReader.Close()
+	defer closeReader()
 
 	// 创建 tar reader
-	tarReader := tar.NewReader(gzReader)
+	tarReader := tar.NewReader(reader)
 
 	// 读取文件
 	for {
@@ -326,30 +547,41 @@ func (m *Manager) importTarGz(inputFile string, restoreSchedule bool) error {
 			return err
 		}
 
-		if err := m.extractFileFromTar(tarReader, header, restoreSchedule); err != nil {
+		if header.FileInfo().IsDir() {
+			continue
+		}
+		if err := m.extractFileFromTar(tarReader, header, metadata, restoreSchedule); err != nil {
 			logger.Warn("提取文件失败", "file", header.Name, "error", err)
 			continue
 		}
 	}
 
-	logger.Debug("tar.gz 导入完成")
+	logger.Debug("tar 导入完成")
 	return nil
 }
 
 // importZip 导入 zip 格式
-func (m *Manager) importZip(inputFile string, restoreSchedule bool) error {
-	logger.Debug("导入 zip 格式", "input", inputFile)
+func (m *Manager) importZip(src *archiveSource, metadata *BackupMetadata, restoreSchedule bool) error {
+	logger.Debug("导入 zip 格式", "input", src.path)
 
 	// 打开 zip 文件
-	zipReader, err := zip.OpenReader(inputFile)
+	file, closeFile, err := src.open()
+	if err != nil {
+		return err
+	}
+	defer closeFile()
+
+	zipReader, err := zip.NewReader(file, file.Size())
 	if err != nil {
 		return err
 	}
-	defer zipReader.Close()
 
 	// 提取文件
 	for _, file := range zipReader.File {
-		if err := m.extractFileFromZip(file, restoreSchedule); err != nil {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if err := m.extractFileFromZip(file, metadata, restoreSchedule); err != nil {
 			logger.Warn("提取文件失败", "file", file.Name, "error", err)
 			continue
 		}
@@ -368,7 +600,7 @@ func (m *Manager) addMetadataToTar(tarWriter *tar.Writer, metadata *BackupMetada
 	}
 
 	header := &tar.Header{
-		Name: "metadata.json",
+		Name: metadataFile,
 		Size: int64(len(data)),
 		Mode: 0644,
 	}
@@ -403,7 +635,7 @@ func (m *Manager) addFileToTar(tarWriter *tar.Writer, archivePath, localPath str
 		return err
 	}
 
-	_, err = io.Copy(tarWriter, file)
+	_, err = secmem.Copy(tarWriter, file)
 	return err
 }
 
@@ -413,7 +645,7 @@ func (m *Manager) addMetadataToZip(zipWriter *zip.Writer, metadata *BackupMetada
 		return err
 	}
 
-	writer, err := zipWriter.Create("metadata.json")
+	writer, err := zipWriter.Create(metadataFile)
 	if err != nil {
 		return err
 	}
@@ -434,18 +666,21 @@ func (m *Manager) addFileToZip(zipWriter *zip.Writer, archivePath, localPath str
 		return err
 	}
 
-	_, err = io.Copy(writer, file)
+	_, err = secmem.Copy(writer, file)
 	return err
 }
 
-func (m *Manager) extractFileFromTar(tarReader *tar.Reader, header *tar.Header, restoreSchedule bool) error {
-	// 跳过元数据文件（已经处理）
-	if header.Name == "metadata.json" {
+func (m *Manager) extractFileFromTar(tarReader *tar.Reader, header *tar.Header, metadata *BackupMetadata, restoreSchedule bool) error {
+	// 跳过元数据文件（已经处理）和原始定时任务定义（按元数据恢复）
+	if header.Name == metadataFile || strings.HasPrefix(metadata.entryPath(header.Name), scheduleDir) {
+		return nil
+	}
+	if !metadata.shouldExtract(header.Name) {
 		return nil
 	}
 
 	// 确定目标路径
-	targetPath, err := m.getTargetPath(header.Name)
+	targetPath, err := m.getTargetPath(metadata.entryPath(header.Name))
 	if err != nil {
 		return err
 	}
@@ -463,7 +698,7 @@ func (m *Manager) extractFileFromTar(tarReader *tar.Reader, header *tar.Header,
 	defer outFile.Close()
 
 	// 复制内容
-	_, err = io.Copy(outFile, tarReader)
+	_, err = secmem.Copy(outFile, tarReader)
 	if err != nil {
 		return err
 	}
@@ -477,14 +712,17 @@ func (m *Manager) extractFileFromTar(tarReader *tar.Reader, header *tar.Header,
 	return nil
 }
 
-func (m *Manager) extractFileFromZip(file *zip.File, restoreSchedule bool) error {
-	// 跳过元数据文件
-	if file.Name == "metadata.json" {
+func (m *Manager) extractFileFromZip(file *zip.File, metadata *BackupMetadata, restoreSchedule bool) error {
+	// 跳过元数据文件和原始定时任务定义
+	if file.Name == metadataFile || strings.HasPrefix(metadata.entryPath(file.Name), scheduleDir) {
+		return nil
+	}
+	if !metadata.shouldExtract(file.Name) {
 		return nil
 	}
 
 	// 确定目标路径
-	targetPath, err := m.getTargetPath(file.Name)
+	targetPath, err := m.getTargetPath(metadata.entryPath(file.Name))
 	if err != nil {
 		return err
 	}
@@ -509,7 +747,7 @@ func (m *Manager) extractFileFromZip(file *zip.File, restoreSchedule bool) error
 	defer dstFile.Close()
 
 	// 复制内容
-	_, err = io.Copy(dstFile, srcFile)
+	_, err = secmem.Copy(dstFile, srcFile)
 	if err != nil {
 		return err
 	}
@@ -542,8 +780,3 @@ func (m *Manager) getTargetPath(archivePath string) (string, error) {
 
 	return "", fmt.Errorf("未知的归档路径: %s", archivePath)
 }
-
-func getOSInfo() string {
-	// 简化的操作系统信息
-	return fmt.Sprintf("%s/%s", "go", "1.21")
-}
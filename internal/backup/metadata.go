@@ -0,0 +1,311 @@
+package backup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"autocert/internal/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MetadataVersion 完整导出时写入的元数据版本（主版本.次版本）。
+// 主版本变化表示归档布局不兼容；次版本只增加字段，旧版本的读取器可以忽略
+const MetadataVersion = "1.3"
+
+// DiffMetadataVersion 差异导出时写入的元数据版本。差异归档只包含变化的文件，
+// 只支持 1.x 的读取器会把它当作完整备份导入，因此使用新的主版本，旧版本会拒绝导入
+const DiffMetadataVersion = "2.0"
+
+// latestVersions 各主版本支持的最新版本
+var latestVersions = map[int]string{
+	1: MetadataVersion,
+	2: DiffMetadataVersion,
+}
+
+// metadataFile 归档中元数据文件的名称
+const metadataFile = "metadata.json"
+
+// scheduleDir 早期 1.2 归档中保存原始定时任务定义的目录（现在只记录在 metadata.json 中），导入时忽略
+const scheduleDir = "schedule/"
+
+// metadataReader 读取某个主版本的元数据，并升级为当前结构
+type metadataReader func(data []byte, minor int) (*BackupMetadata, error)
+
+// metadataReaders 各主版本的读取器
+var metadataReaders = map[int]metadataReader{
+	1: readMetadataV1,
+	2: readMetadataV2,
+}
+
+// parseMetadata 按 version 字段选择读取器，校验并升级元数据
+func parseMetadata(data []byte) (*BackupMetadata, error) {
+	var header struct {
+		Version         string `json:"version"`
+		AutocertVersion string `json:"autocert_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", metadataFile, err)
+	}
+	if header.Version == "" {
+		return nil, fmt.Errorf("%s 缺少 version 字段", metadataFile)
+	}
+
+	major, minor, err := parseVersion(header.Version)
+	if err != nil {
+		return nil, err
+	}
+	reader, ok := metadataReaders[major]
+	if !ok {
+		if major > len(metadataReaders) {
+			createdBy := header.AutocertVersion
+			if createdBy == "" {
+				createdBy = "未知版本"
+			}
+			return nil, fmt.Errorf("备份格式版本 %s 由更新的 autocert（%s）创建，当前最高支持 %s，请升级 autocert 后再导入",
+				header.Version, createdBy, latestVersions[len(metadataReaders)])
+		}
+		return nil, fmt.Errorf("不支持的备份格式版本: %s", header.Version)
+	}
+	if _, latestMinor, _ := parseVersion(latestVersions[major]); minor > latestMinor {
+		logger.Warn("备份由更新版本的 autocert 创建，将忽略无法识别的字段", "version", header.Version, "supported", latestVersions[major])
+	}
+
+	metadata, err := reader(data, minor)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 版本的备份元数据失败: %w", header.Version, err)
+	}
+	metadata.source = header.Version
+	if err := metadata.validate(); err != nil {
+		return nil, fmt.Errorf("备份元数据无效: %w", err)
+	}
+	return metadata, nil
+}
+
+// readMetadataV1 读取 1.x 版本的元数据
+func readMetadataV1(data []byte, minor int) (*BackupMetadata, error) {
+	var metadata BackupMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	if minor == 0 {
+		upgradeV10(&metadata)
+	}
+	metadata.Version = MetadataVersion
+	return &metadata, nil
+}
+
+// readMetadataV2 读取 2.x 版本（差异导出）的元数据
+func readMetadataV2(data []byte, minor int) (*BackupMetadata, error) {
+	var metadata BackupMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Base == nil {
+		return nil, fmt.Errorf("差异备份缺少基准备份（base 字段）")
+	}
+	metadata.Version = DiffMetadataVersion
+	return &metadata, nil
+}
+
+// upgradeV10 升级 1.0 版本的元数据：
+// 1.0 没有文件清单，Windows 上导出的归档路径使用反斜杠，平台信息固定为 go/1.21
+func upgradeV10(metadata *BackupMetadata) {
+	metadata.Files = nil
+	metadata.legacyPaths = true
+	if metadata.Platform == "go/1.21" {
+		metadata.Platform = ""
+	}
+}
+
+// parseVersion 解析“主版本.次版本”格式的版本号
+func parseVersion(version string) (major, minor int, err error) {
+	majorPart, minorPart, ok := strings.Cut(version, ".")
+	if !ok {
+		minorPart = "0"
+	}
+	major, err1 := strconv.Atoi(majorPart)
+	minor, err2 := strconv.Atoi(minorPart)
+	if err1 != nil || err2 != nil || major < 0 || minor < 0 {
+		return 0, 0, fmt.Errorf("无效的备份格式版本: %q", version)
+	}
+	return major, minor, nil
+}
+
+// validate 校验元数据的字段
+func (b *BackupMetadata) validate() error {
+	if b.CreatedAt.IsZero() {
+		return errors.New("缺少 created_at 字段")
+	}
+	for _, domain := range b.Domains {
+		if domain == "" || domain == "." || domain == ".." || strings.ContainsAny(domain, `/\`) {
+			return fmt.Errorf("无效的域名目录: %q", domain)
+		}
+	}
+	for _, name := range b.Files {
+		if err := checkEntryPath(name); err != nil {
+			return err
+		}
+	}
+	for name := range b.Hashes {
+		if err := checkEntryPath(name); err != nil {
+			return err
+		}
+	}
+	if b.Base != nil && (b.Base.File == "" || b.Base.File != path.Base(b.Base.File) || strings.Contains(b.Base.File, `\`) || b.Base.File == "..") {
+		return fmt.Errorf("无效的基准备份文件名: %q", b.Base.File)
+	}
+	return nil
+}
+
+// shouldExtract 差异备份链中该归档是否需要解压该条目
+func (b *BackupMetadata) shouldExtract(name string) bool {
+	return b.extract == nil || b.extract[b.entryPath(name)]
+}
+
+// entryPath 归档条目在当前布局中的路径
+func (b *BackupMetadata) entryPath(name string) string {
+	if b.legacyPaths {
+		name = strings.ReplaceAll(name, `\`, "/")
+	}
+	return name
+}
+
+// checkEntries 校验归档中的条目：路径必须位于 certs/、config/ 或 schedule/ 下；
+// 有文件清单时条目必须与清单一致，防止备份不完整或被混入额外的文件
+func (b *BackupMetadata) checkEntries(names []string) error {
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = b.entryPath(name)
+		if err := checkEntryPath(name); err != nil {
+			return err
+		}
+		present[name] = true
+	}
+	if b.Files == nil {
+		return nil
+	}
+
+	listed := make(map[string]bool, len(b.Files))
+	for _, name := range b.Files {
+		listed[name] = true
+		if !present[name] {
+			return fmt.Errorf("备份不完整，缺少文件: %s", name)
+		}
+	}
+	for name := range present {
+		if !listed[name] {
+			return fmt.Errorf("归档包含元数据中未列出的文件: %s", name)
+		}
+	}
+	return nil
+}
+
+// checkEntryPath 拒绝绝对路径、上级目录和未知位置的条目
+func checkEntryPath(name string) error {
+	if path.IsAbs(name) || strings.Contains(name, `\`) || path.Clean(name) != name || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("不安全的归档路径: %s", name)
+	}
+	if !strings.HasPrefix(name, "certs/") && !strings.HasPrefix(name, "config/") && !strings.HasPrefix(name, scheduleDir) {
+		return fmt.Errorf("未知的归档路径: %s", name)
+	}
+	return nil
+}
+
+// readArchiveMetadata 读取归档的元数据并校验条目，不解压文件内容
+func readArchiveMetadata(src *archiveSource, zipFormat bool) (*BackupMetadata, error) {
+	var data []byte
+	var names []string
+	var err error
+	if zipFormat {
+		data, names, err = zipEntries(src)
+	} else {
+		data, names, err = tarEntries(src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("归档中没有 %s，不是 autocert 备份", metadataFile)
+	}
+
+	metadata, err := parseMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := metadata.checkEntries(names); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+func tarEntries(src *archiveSource) ([]byte, []string, error) {
+	reader, closeReader, err := src.openTar()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeReader()
+
+	var data []byte
+	var names []string
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.FileInfo().IsDir() {
+			continue
+		}
+		if header.Name == metadataFile {
+			if data, err = io.ReadAll(tarReader); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		names = append(names, header.Name)
+	}
+	return data, names, nil
+}
+
+func zipEntries(src *archiveSource) ([]byte, []string, error) {
+	file, closeFile, err := src.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer closeFile()
+
+	zipReader, err := zip.NewReader(file, file.Size())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var data []byte
+	var names []string
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if file.Name == metadataFile {
+			reader, err := file.Open()
+			if err != nil {
+				return nil, nil, err
+			}
+			data, err = io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		names = append(names, file.Name)
+	}
+	return data, names, nil
+}
@@ -0,0 +1,203 @@
+package backup
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/secmem"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// signatureAlgorithm 签名算法
+const signatureAlgorithm = "ed25519"
+
+// signatureContext 签名内容的前缀，避免签名被用于其他用途
+const signatureContext = "autocert-backup-v1\n"
+
+// ErrNoSignature 备份没有签名文件
+var ErrNoSignature = errors.New("备份没有签名文件")
+
+// Signature 备份的签名文件（与归档文件同名，后缀 .sig）
+type Signature struct {
+	Algorithm string    `json:"algorithm"`
+	KeyID     string    `json:"key_id"` // 公钥 SHA-256 的前 8 字节
+	SHA256    string    `json:"sha256"` // 归档文件的 SHA-256
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SignaturePath 归档文件对应的签名文件路径
+func SignaturePath(archive string) string {
+	return archive + ".sig"
+}
+
+// signArchive 使用配置的 Ed25519 私钥为归档文件生成签名文件，未配置私钥时不签名
+func signArchive(archive string) error {
+	keyPath := config.GetBackupConfig().SigningKey
+	if keyPath == "" {
+		return nil
+	}
+
+	key, err := loadSigningKey(keyPath)
+	if err != nil {
+		return err
+	}
+	defer secmem.Wipe(key)
+	digest, err := fileSHA256(archive)
+	if err != nil {
+		return err
+	}
+
+	public := key.Public().(ed25519.PublicKey)
+	signature := Signature{
+		Algorithm: signatureAlgorithm,
+		KeyID:     keyID(public),
+		SHA256:    digest,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(signatureContext+digest))),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	data, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(SignaturePath(archive), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("写入签名文件失败: %w", err)
+	}
+	return nil
+}
+
+// VerifyArchive 使用配置的可信公钥校验归档文件的签名，返回签名信息
+func VerifyArchive(archive string) (*Signature, error) {
+	data, err := os.ReadFile(SignaturePath(archive))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSignature
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var signature Signature
+	if err := json.Unmarshal(data, &signature); err != nil {
+		return nil, fmt.Errorf("解析签名文件失败: %w", err)
+	}
+	if signature.Algorithm != signatureAlgorithm {
+		return nil, fmt.Errorf("不支持的签名算法: %s", signature.Algorithm)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("签名格式错误: %w", err)
+	}
+
+	keys, err := loadTrustedKeys()
+	if err != nil {
+		return nil, err
+	}
+	var trusted ed25519.PublicKey
+	for _, key := range keys {
+		if keyID(key) == signature.KeyID {
+			trusted = key
+			break
+		}
+	}
+	if trusted == nil {
+		return nil, fmt.Errorf("签名密钥 %s 不在 backup.trusted_keys 中", signature.KeyID)
+	}
+
+	digest, err := fileSHA256(archive)
+	if err != nil {
+		return nil, err
+	}
+	if digest != signature.SHA256 {
+		return nil, errors.New("归档文件内容与签名不一致（文件已被修改）")
+	}
+	if !ed25519.Verify(trusted, []byte(signatureContext+digest), sig) {
+		return nil, errors.New("签名无效")
+	}
+	return &signature, nil
+}
+
+// loadSigningKey 读取 PKCS#8 PEM 格式的 Ed25519 私钥
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	secmem.Wipe(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析签名私钥 %s 失败: %w", path, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("签名私钥 %s 不是 Ed25519 密钥", path)
+	}
+	return private, nil
+}
+
+// loadTrustedKeys 读取配置的可信公钥
+func loadTrustedKeys() ([]ed25519.PublicKey, error) {
+	paths := config.GetBackupConfig().TrustedKeys
+	if len(paths) == 0 {
+		return nil, errors.New("未配置可信公钥（backup.trusted_keys）")
+	}
+
+	var keys []ed25519.PublicKey
+	for _, path := range paths {
+		block, err := readPEM(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析公钥 %s 失败: %w", path, err)
+		}
+		public, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("公钥 %s 不是 Ed25519 密钥", path)
+		}
+		keys = append(keys, public)
+	}
+	return keys, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是 PEM 格式", path)
+	}
+	return block, nil
+}
+
+// keyID 公钥标识
+func keyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
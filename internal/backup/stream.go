@@ -0,0 +1,126 @@
+package backup
+
+import (
+	"autocert/internal/secmem"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Stdio 导出到标准输出或从标准输入导入时使用的文件名，例如
+// autocert export -o - | ssh host autocert import -
+const Stdio = "-"
+
+// IsStdio 文件名是否表示标准输入/输出
+func IsStdio(name string) bool {
+	return name == Stdio
+}
+
+// nopCloser 标准输出由进程关闭，导出完成时不关闭
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// createOutput 创建导出的归档文件，Stdio 时写入标准输出
+func createOutput(outputFile string) (io.WriteCloser, error) {
+	if IsStdio(outputFile) {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(outputFile)
+}
+
+// archiveSource 待导入的归档：文件，或从标准输入读入内存的内容。
+// tar.gz 的元数据位于归档末尾，zip 需要随机读取，因此标准输入需要完整读入后再处理（不写临时文件）
+type archiveSource struct {
+	path string
+	data []byte
+}
+
+// readStdin 读取标准输入中的归档
+func readStdin() (*archiveSource, error) {
+	if f, err := os.Stdin.Stat(); err == nil && f.Mode()&os.ModeCharDevice != 0 {
+		return nil, errors.New("标准输入是终端，请通过管道或重定向提供备份，例如 autocert export -o - | autocert import -")
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("读取标准输入失败: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("标准输入为空")
+	}
+	return &archiveSource{path: Stdio, data: data}, nil
+}
+
+// header 归档的文件头，用于按内容判断格式和压缩方式
+func (s *archiveSource) header() ([]byte, error) {
+	r, closeFile, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer closeFile()
+
+	header := make([]byte, 4)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return header[:n], nil
+}
+
+// isZip 按内容判断是否为 zip 归档，否则为 tar（可能经过压缩）
+func (s *archiveSource) isZip() (bool, error) {
+	header, err := s.header()
+	if err != nil {
+		return false, err
+	}
+	return bytes.HasPrefix(header, zipMagic), nil
+}
+
+// openTar 打开 tar 归档，按文件头选择解压方式（gzip、zstd 或不压缩）
+func (s *archiveSource) openTar() (io.Reader, func() error, error) {
+	header, err := s.header()
+	if err != nil {
+		return nil, nil, err
+	}
+	r, closeFile, err := s.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, err := newDecompressor(r, detectCompression(header))
+	if err != nil {
+		closeFile()
+		return nil, nil, err
+	}
+	return reader, func() error {
+		err := reader.Close()
+		closeFile()
+		return err
+	}, nil
+}
+
+// open 打开归档，返回可随机读取的内容和关闭函数
+func (s *archiveSource) open() (*io.SectionReader, func() error, error) {
+	if s.data != nil {
+		return io.NewSectionReader(bytes.NewReader(s.data), 0, int64(len(s.data))), func() error { return nil }, nil
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return io.NewSectionReader(file, 0, info.Size()), file.Close, nil
+}
+
+// release 清零读入内存的归档（其中包含私钥）
+func (s *archiveSource) release() {
+	secmem.Wipe(s.data)
+}
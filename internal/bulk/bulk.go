@@ -0,0 +1,269 @@
+package bulk
+
+import (
+	"autocert/internal/dns"
+	"autocert/internal/domainlist"
+	"autocert/internal/logger"
+	"autocert/internal/progress"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 条目状态
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Let's Encrypt 默认限额
+const (
+	DefaultOrderInterval = 40 * time.Second // 每账户每 3 小时 300 个新订单
+	DefaultWeeklyLimit   = 50               // 每个注册域名每周 50 张证书
+	DefaultMaxAttempts   = 3
+)
+
+// IssueFunc 签发一张证书（多个域名时为 SAN 证书）
+type IssueFunc func(entry *Entry) error
+
+// Entry 队列中的一张证书
+type Entry struct {
+	Domains   []string  `json:"domains"`
+	Challenge string    `json:"challenge,omitempty"` // 域名文件中该行指定的验证方式
+	Webroot   string    `json:"webroot,omitempty"`   // 域名文件中该行指定的网站根目录
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// State 批量签发进度，持久化后中断可恢复
+type State struct {
+	Source    string                 `json:"source"`
+	Entries   []*Entry               `json:"entries"`
+	Issued    map[string][]time.Time `json:"issued"` // 按注册域名记录的签发时间
+	LastOrder time.Time              `json:"last_order"`
+}
+
+// Options 批量签发选项
+type Options struct {
+	File          string
+	StatePath     string
+	OrderInterval time.Duration
+	WeeklyLimit   int
+	MaxAttempts   int
+	RetryFailed   bool
+	Issue         IssueFunc
+}
+
+// Result 批量签发结果
+type Result struct {
+	Done          int
+	Failed        int
+	Retryable     int       // 失败但未达到最大尝试次数、下次执行会重试的数量（包含在 Failed 中）
+	Deferred      int       // 因每周限额推迟的数量
+	Pending       int       // 因中断尚未处理的数量
+	NextAvailable time.Time // 推迟的证书最早可签发时间
+	StatePath     string
+}
+
+// DefaultStatePath 根据域名文件路径生成默认的进度文件路径
+func DefaultStatePath(configDir, file string) string {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		absPath = file
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	return filepath.Join(configDir, "bulk", "state-"+hex.EncodeToString(sum[:6])+".json")
+}
+
+// Run 按速率限制依次签发队列中的证书
+func Run(ctx context.Context, options Options) (*Result, error) {
+	if options.OrderInterval <= 0 {
+		options.OrderInterval = DefaultOrderInterval
+	}
+	if options.WeeklyLimit <= 0 {
+		options.WeeklyLimit = DefaultWeeklyLimit
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+
+	lines, err := domainlist.Load(options.File)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := LoadState(options.StatePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取进度文件失败: %w", err)
+	}
+	state.Source = options.File
+	state.merge(lines)
+
+	if options.RetryFailed {
+		for _, entry := range state.Entries {
+			if entry.Status == StatusFailed {
+				entry.Status = StatusPending
+				entry.Attempts = 0
+			}
+		}
+	}
+
+	result := &Result{StatePath: options.StatePath}
+
+	for _, entry := range state.Entries {
+		if entry.Status == StatusDone {
+			result.Done++
+			continue
+		}
+		if entry.Status == StatusFailed && entry.Attempts >= options.MaxAttempts {
+			result.Failed++
+			continue
+		}
+
+		if ctx.Err() != nil {
+			result.Pending++
+			continue
+		}
+
+		// 每个注册域名每周签发数量限制
+		registered := dns.RegisteredDomain(entry.Domains[0])
+		if next, ok := state.weeklyAvailable(registered, options.WeeklyLimit); !ok {
+			logger.Info("已达到每周签发限额，推迟签发", "domains", entry.Domains, "registeredDomain", registered, "next", next)
+			result.Deferred++
+			if result.NextAvailable.IsZero() || next.Before(result.NextAvailable) {
+				result.NextAvailable = next
+			}
+			continue
+		}
+
+		// 新订单速率限制
+		if wait := time.Until(state.LastOrder.Add(options.OrderInterval)); wait > 0 {
+			spinner := progress.Start(fmt.Sprintf("等待新订单间隔 %s", wait.Round(time.Second)))
+			select {
+			case <-ctx.Done():
+				spinner.Stop(ctx.Err())
+				result.Pending++
+				continue
+			case <-time.After(wait):
+				spinner.Stop(nil)
+			}
+		}
+
+		state.LastOrder = time.Now()
+		entry.Attempts++
+		entry.UpdatedAt = time.Now()
+
+		if err := options.Issue(entry); err != nil {
+			entry.Status = StatusFailed
+			entry.LastError = err.Error()
+			logger.Error("批量签发失败", "domains", entry.Domains, "attempt", entry.Attempts, "error", err)
+			result.Failed++
+			if entry.Attempts < options.MaxAttempts {
+				result.Retryable++
+			}
+		} else {
+			entry.Status = StatusDone
+			entry.LastError = ""
+			state.Issued[registered] = append(state.Issued[registered], time.Now())
+			result.Done++
+			logger.Info("批量签发成功", "domains", entry.Domains)
+		}
+
+		// 每处理一张证书保存一次进度，保证中断后可恢复
+		if err := state.Save(options.StatePath); err != nil {
+			return result, fmt.Errorf("保存进度失败: %w", err)
+		}
+	}
+
+	if err := state.Save(options.StatePath); err != nil {
+		return result, fmt.Errorf("保存进度失败: %w", err)
+	}
+
+	return result, nil
+}
+
+// LoadState 读取进度文件，不存在时返回空进度
+func LoadState(path string) (*State, error) {
+	state := &State{Issued: make(map[string][]time.Time)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	if state.Issued == nil {
+		state.Issued = make(map[string][]time.Time)
+	}
+	return state, nil
+}
+
+// Save 保存进度文件
+func (s *State) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// merge 将域名文件中新增的证书加入队列，已有证书使用域名文件中最新的选项
+func (s *State) merge(lines []domainlist.Entry) {
+	existing := make(map[string]*Entry)
+	for _, entry := range s.Entries {
+		existing[strings.Join(entry.Domains, ",")] = entry
+	}
+
+	for _, line := range lines {
+		key := strings.Join(line.Domains, ",")
+		if entry, ok := existing[key]; ok {
+			entry.Challenge, entry.Webroot = line.Challenge, line.Webroot
+			continue
+		}
+		entry := &Entry{Domains: line.Domains, Challenge: line.Challenge, Webroot: line.Webroot, Status: StatusPending}
+		existing[key] = entry
+		s.Entries = append(s.Entries, entry)
+	}
+}
+
+// weeklyAvailable 检查注册域名本周是否还能签发，不能时返回最早可签发时间
+func (s *State) weeklyAvailable(registered string, limit int) (time.Time, bool) {
+	week := 7 * 24 * time.Hour
+	cutoff := time.Now().Add(-week)
+
+	var recent []time.Time
+	for _, t := range s.Issued[registered] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	s.Issued[registered] = recent
+
+	if len(recent) < limit {
+		return time.Time{}, true
+	}
+	return recent[len(recent)-limit].Add(week), false
+}
@@ -0,0 +1,533 @@
+package ca
+
+import (
+	"autocert/internal/audit"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CA 目录中的文件
+const (
+	certFile  = "ca.pem"
+	keyFile   = "ca.key"
+	indexFile = "index.json"
+	crlFile   = "crl.pem"
+)
+
+// 证书类型
+const (
+	TypeClient = "client"
+	TypeServer = "server" // 守护进程 HTTPS 接口的服务端证书
+	TypeAgent  = "agent"  // 通过加入令牌注册的 agent 的客户端证书
+)
+
+var (
+	// ErrNotInitialized CA 尚未初始化
+	ErrNotInitialized = errors.New("本地 CA 尚未初始化，请先执行 autocert ca init")
+	// ErrExists CA 已存在
+	ErrExists = errors.New("本地 CA 已存在")
+)
+
+// reasonCodes 吊销原因（RFC 5280 5.3.1）
+var reasonCodes = map[string]int{
+	"unspecified":          0,
+	"keyCompromise":        1,
+	"affiliationChanged":   3,
+	"superseded":           4,
+	"cessationOfOperation": 5,
+	"certificateHold":      6,
+}
+
+// Record 已签发证书的记录
+type Record struct {
+	Serial     string     `json:"serial"`
+	Type       string     `json:"type"`
+	CommonName string     `json:"common_name"`
+	Emails     []string   `json:"emails,omitempty"`
+	NotBefore  time.Time  `json:"not_before"`
+	NotAfter   time.Time  `json:"not_after"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+}
+
+// Revoked 是否已吊销
+func (r *Record) Revoked() bool {
+	return r.RevokedAt != nil
+}
+
+// index 签发记录和 CRL 编号
+type index struct {
+	CRLNumber    int64    `json:"crl_number"`
+	Certificates []Record `json:"certificates"`
+}
+
+// CA 本地证书颁发机构
+type CA struct {
+	dir  string
+	Cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// Dir CA 目录
+func (c *CA) Dir() string {
+	return c.dir
+}
+
+// CertPath CA 根证书路径（配置到服务端用于验证客户端证书）
+func (c *CA) CertPath() string {
+	return filepath.Join(c.dir, certFile)
+}
+
+// CRLPath CRL 文件路径
+func (c *CA) CRLPath() string {
+	return filepath.Join(c.dir, crlFile)
+}
+
+// Init 生成 CA 根证书和私钥（ECDSA P-384）
+func Init(commonName string, validity time.Duration) (*CA, error) {
+	if validity <= 0 {
+		return nil, fmt.Errorf("CA 根证书有效期必须大于 0")
+	}
+
+	dir := config.GetCAConfig().Dir
+	if _, err := os.Stat(filepath.Join(dir, certFile)); err == nil {
+		return nil, ErrExists
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建 CA 目录失败: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成 CA 私钥失败: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("生成 CA 根证书失败: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, keyFile), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, certFile), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return nil, err
+	}
+
+	c := &CA{dir: dir, Cert: cert, key: key}
+	if _, err := c.GenerateCRL(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("本地 CA 初始化完成", "dir", dir, "commonName", commonName)
+	audit.Record(audit.Entry{Action: "ca.init", Result: audit.ResultSuccess, Detail: commonName})
+	return c, nil
+}
+
+// Open 加载本地 CA
+func Open() (*CA, error) {
+	dir := config.GetCAConfig().Dir
+
+	certPEM, err := os.ReadFile(filepath.Join(dir, certFile))
+	if os.IsNotExist(err) {
+		return nil, ErrNotInitialized
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 根证书失败: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("CA 根证书格式错误: %s", filepath.Join(dir, certFile))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析 CA 根证书失败: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(dir, keyFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 私钥失败: %w", err)
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("CA 私钥格式错误: %s", filepath.Join(dir, keyFile))
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析 CA 私钥失败: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("不支持的 CA 私钥类型")
+	}
+
+	return &CA{dir: dir, Cert: cert, key: key}, nil
+}
+
+// ClientRequest 客户端证书申请
+type ClientRequest struct {
+	CommonName string
+	Emails     []string      // 写入 SAN 的邮箱，CommonName 为邮箱时自动加入
+	Validity   time.Duration // 为 0 时使用配置 ca.client_days
+	KeyType    string        // ecdsa（默认，P-256）或 rsa（2048 位）
+}
+
+// Issued 签发结果
+type Issued struct {
+	Cert   *x509.Certificate
+	Key    crypto.Signer
+	Record Record
+}
+
+// IssueClient 签发用于 mTLS 客户端认证的证书（扩展密钥用途 clientAuth）
+func (c *CA) IssueClient(req ClientRequest) (*Issued, error) {
+	if strings.TrimSpace(req.CommonName) == "" {
+		return nil, fmt.Errorf("客户端证书名称（CN）不能为空")
+	}
+	cfg := config.GetCAConfig()
+
+	validity := req.Validity
+	if validity <= 0 {
+		validity = time.Duration(cfg.ClientDays) * 24 * time.Hour
+	}
+
+	emails := append([]string{}, req.Emails...)
+	if strings.Contains(req.CommonName, "@") && !containsString(emails, req.CommonName) {
+		emails = append([]string{req.CommonName}, emails...)
+	}
+
+	var key crypto.Signer
+	var err error
+	usage := x509.KeyUsageDigitalSignature
+	switch strings.ToLower(req.KeyType) {
+	case "", "ecdsa":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		usage |= x509.KeyUsageKeyEncipherment
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %s", req.KeyType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成客户端私钥失败: %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: req.CommonName},
+		EmailAddresses: emails,
+		KeyUsage:       usage,
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, record, err := c.sign(template, key.Public(), TypeClient, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("已签发客户端证书", "commonName", req.CommonName, "serial", record.Serial, "notAfter", record.NotAfter)
+	audit.Record(audit.Entry{Action: "ca.issue-client", Result: audit.ResultSuccess,
+		Detail: fmt.Sprintf("cn=%s serial=%s", req.CommonName, record.Serial)})
+	return &Issued{Cert: cert, Key: key, Record: record}, nil
+}
+
+// sign 用 CA 私钥签发证书并保存签发记录：template 提供主题、SAN 和密钥用途，
+// 序列号、有效期（不超过根证书）、CRL 分发点和 OCSP 地址由 sign 填写
+func (c *CA) sign(template *x509.Certificate, pub crypto.PublicKey, certType string, validity time.Duration) (*x509.Certificate, Record, error) {
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, Record{}, err
+	}
+
+	cfg := config.GetCAConfig()
+	now := time.Now()
+	notAfter := now.Add(validity)
+	if notAfter.After(c.Cert.NotAfter) {
+		notAfter = c.Cert.NotAfter
+	}
+	template.SerialNumber = serial
+	template.NotBefore = now.Add(-5 * time.Minute)
+	template.NotAfter = notAfter
+	template.BasicConstraintsValid = true
+	if cfg.CRLURL != "" {
+		template.CRLDistributionPoints = []string{cfg.CRLURL}
+	}
+	if cfg.OCSPURL != "" {
+		template.OCSPServer = []string{cfg.OCSPURL}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, c.Cert, pub, c.key)
+	if err != nil {
+		return nil, Record{}, fmt.Errorf("签发证书失败: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, Record{}, err
+	}
+
+	record := Record{
+		Serial:     formatSerial(cert.SerialNumber),
+		Type:       certType,
+		CommonName: template.Subject.CommonName,
+		Emails:     template.EmailAddresses,
+		NotBefore:  cert.NotBefore,
+		NotAfter:   cert.NotAfter,
+	}
+
+	idx, err := c.loadIndex()
+	if err != nil {
+		return nil, Record{}, err
+	}
+	idx.Certificates = append(idx.Certificates, record)
+	if err := c.saveIndex(idx); err != nil {
+		return nil, Record{}, err
+	}
+	return cert, record, nil
+}
+
+// List 已签发的证书记录（按签发时间排序）
+func (c *CA) List() ([]Record, error) {
+	idx, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	records := idx.Certificates
+	sort.SliceStable(records, func(i, j int) bool { return records[i].NotBefore.Before(records[j].NotBefore) })
+	return records, nil
+}
+
+// Revoke 吊销证书并重新生成 CRL，serial 为十六进制序列号（不区分大小写，可包含冒号）
+func (c *CA) Revoke(serial, reason string) (*Record, error) {
+	if reason == "" {
+		reason = "unspecified"
+	}
+	if _, ok := reasonCodes[reason]; !ok {
+		return nil, fmt.Errorf("未知的吊销原因: %s（可选: %s）", reason, strings.Join(ReasonNames(), ", "))
+	}
+
+	idx, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := normalizeSerial(serial)
+	var record *Record
+	for i := range idx.Certificates {
+		if idx.Certificates[i].Serial == wanted {
+			record = &idx.Certificates[i]
+			break
+		}
+	}
+	if record == nil {
+		return nil, fmt.Errorf("未找到序列号为 %s 的证书", serial)
+	}
+	if record.Revoked() {
+		return nil, fmt.Errorf("证书 %s 已于 %s 吊销", record.Serial, clock.Format(*record.RevokedAt))
+	}
+
+	now := time.Now()
+	record.RevokedAt = &now
+	record.Reason = reason
+	if err := c.saveIndex(idx); err != nil {
+		return nil, err
+	}
+	revoked := *record
+
+	if _, err := c.GenerateCRL(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("已吊销客户端证书", "serial", revoked.Serial, "commonName", revoked.CommonName, "reason", reason)
+	audit.Record(audit.Entry{Action: "ca.revoke", Result: audit.ResultSuccess,
+		Detail: fmt.Sprintf("cn=%s serial=%s reason=%s", revoked.CommonName, revoked.Serial, reason)})
+	return &revoked, nil
+}
+
+// GenerateCRL 生成包含所有已吊销证书的 CRL 并写入 CA 目录，返回 PEM 内容
+func (c *CA) GenerateCRL() ([]byte, error) {
+	idx, err := c.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []x509.RevocationListEntry
+	for _, record := range idx.Certificates {
+		if !record.Revoked() {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(record.Serial, 16)
+		if !ok {
+			return nil, fmt.Errorf("签发记录中的序列号无效: %s", record.Serial)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: *record.RevokedAt,
+			ReasonCode:     reasonCodes[record.Reason],
+		})
+	}
+
+	idx.CRLNumber++
+	now := time.Now()
+	template := &x509.RevocationList{
+		Number:                    big.NewInt(idx.CRLNumber),
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(time.Duration(config.GetCAConfig().CRLDays) * 24 * time.Hour),
+		RevokedCertificateEntries: entries,
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, c.Cert, c.key)
+	if err != nil {
+		return nil, fmt.Errorf("生成 CRL 失败: %w", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+	if err := writeFile(c.CRLPath(), data, 0644); err != nil {
+		return nil, err
+	}
+	if err := c.saveIndex(idx); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// RefreshCRL CRL 不存在或有效期已过半时重新生成，返回是否重新生成
+func (c *CA) RefreshCRL() (bool, error) {
+	if crl, err := c.LoadCRL(); err == nil {
+		if time.Until(crl.NextUpdate) > crl.NextUpdate.Sub(crl.ThisUpdate)/2 {
+			return false, nil
+		}
+	}
+	if _, err := c.GenerateCRL(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// LoadCRL 读取并解析 CA 目录中的 CRL
+func (c *CA) LoadCRL() (*x509.RevocationList, error) {
+	data, err := os.ReadFile(c.CRLPath())
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("CRL 格式错误: %s", c.CRLPath())
+	}
+	return x509.ParseRevocationList(block.Bytes)
+}
+
+// ReasonNames 支持的吊销原因
+func ReasonNames() []string {
+	var names []string
+	for name := range reasonCodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadIndex 读取签发记录，不存在时返回空记录
+func (c *CA) loadIndex() (*index, error) {
+	idx := &index{}
+	data, err := os.ReadFile(filepath.Join(c.dir, indexFile))
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取签发记录失败: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("解析签发记录失败: %w", err)
+	}
+	return idx, nil
+}
+
+// saveIndex 保存签发记录
+func (c *CA) saveIndex(idx *index) error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(c.dir, indexFile), append(data, '\n'), 0600)
+}
+
+// writeFile 先写入临时文件再替换，避免中断时留下不完整的文件
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// randomSerial 随机的 128 位正整数序列号
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return nil, fmt.Errorf("生成序列号失败: %w", err)
+	}
+	return serial.Add(serial, big.NewInt(1)), nil
+}
+
+// formatSerial 序列号的十六进制表示（大写）
+func formatSerial(serial *big.Int) string {
+	return strings.ToUpper(serial.Text(16))
+}
+
+// normalizeSerial 统一用户输入的序列号格式
+func normalizeSerial(serial string) string {
+	serial = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(serial), ":", ""))
+	return strings.TrimLeft(strings.TrimPrefix(serial, "0X"), "0")
+}
+
+// containsString 判断字符串切片中是否包含指定值
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
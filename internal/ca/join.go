@@ -0,0 +1,169 @@
+package ca
+
+import (
+	"autocert/internal/audit"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// joinTokensFile CA 目录中保存加入令牌的文件（只保存令牌的 SHA-256）
+const joinTokensFile = "join-tokens.json"
+
+// joinTokenRetention 已使用或已过期的加入令牌保留的时间
+const joinTokenRetention = 7 * 24 * time.Hour
+
+// ErrJoinToken 加入令牌无效、已过期或已使用
+var ErrJoinToken = errors.New("加入令牌无效、已过期或已使用")
+
+// joinMu 串行化加入令牌文件的读写
+var joinMu sync.Mutex
+
+// JoinToken 一次性加入令牌：agent 用它注册并获得本地 CA 签发的客户端证书
+type JoinToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"` // 限定注册的 agent 名称，为空时由 agent 指定
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	UsedBy    string     `json:"used_by,omitempty"`
+}
+
+// CreateJoinToken 创建一次性加入令牌，返回的令牌只在创建时可见。
+// 令牌格式为 <ID>.<密钥>.<CA 根证书指纹>，agent 据此在首次连接时确认服务端
+func (c *CA) CreateJoinToken(name string, ttl time.Duration) (string, *JoinToken, error) {
+	if name != "" && !ValidAgentName(name) {
+		return "", nil, fmt.Errorf("agent 名称 %q 无效", name)
+	}
+	if ttl <= 0 {
+		return "", nil, fmt.Errorf("加入令牌的有效期必须大于 0")
+	}
+
+	random := make([]byte, 36)
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	id := hex.EncodeToString(random[:4])
+	secret := base64.RawURLEncoding.EncodeToString(random[4:])
+
+	now := time.Now()
+	token := &JoinToken{ID: id, Name: name, Hash: hashJoinSecret(secret), CreatedAt: now, ExpiresAt: now.Add(ttl)}
+
+	joinMu.Lock()
+	defer joinMu.Unlock()
+	tokens, err := c.loadJoinTokens()
+	if err != nil {
+		return "", nil, err
+	}
+	tokens = append(tokens, *token)
+	if err := c.saveJoinTokens(tokens); err != nil {
+		return "", nil, err
+	}
+
+	audit.Record(audit.Entry{Action: "ca.join-token", Result: audit.ResultSuccess,
+		Detail: fmt.Sprintf("id=%s name=%s expires=%s", id, name, token.ExpiresAt.Format(time.RFC3339))})
+	return id + "." + secret + "." + c.Fingerprint(), token, nil
+}
+
+// ParseJoinToken 拆分加入令牌，返回 ID、密钥和 CA 根证书指纹
+func ParseJoinToken(value string) (id, secret, fingerprint string, err error) {
+	parts := strings.Split(strings.TrimSpace(value), ".")
+	if len(parts) != 3 || len(parts[0]) != 8 || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("加入令牌格式错误（应为 autocert ca join-token 输出的完整令牌）")
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// RedeemJoinToken 使用加入令牌注册名为 agent 的 agent：令牌有效时调用 enroll（签发客户端证书），
+// enroll 成功后令牌才失效，失败时令牌仍可重试。enroll 期间持有锁，同一令牌不会被并发使用两次
+func (c *CA) RedeemJoinToken(value, agent string, enroll func(*JoinToken) error) (*JoinToken, error) {
+	id, secret, fingerprint, err := ParseJoinToken(value)
+	if err != nil {
+		return nil, ErrJoinToken
+	}
+	if subtle.ConstantTimeCompare([]byte(fingerprint), []byte(c.Fingerprint())) != 1 {
+		return nil, ErrJoinToken
+	}
+
+	joinMu.Lock()
+	defer joinMu.Unlock()
+	tokens, err := c.loadJoinTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range tokens {
+		token := &tokens[i]
+		if token.ID != id {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hashJoinSecret(secret)), []byte(token.Hash)) != 1 ||
+			token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+			return nil, ErrJoinToken
+		}
+		if token.Name != "" && token.Name != agent {
+			return nil, fmt.Errorf("加入令牌只能用于注册 agent %s", token.Name)
+		}
+		if err := enroll(token); err != nil {
+			return nil, err
+		}
+		token.UsedAt = &now
+		token.UsedBy = agent
+		if err := c.saveJoinTokens(tokens); err != nil {
+			return nil, err
+		}
+		redeemed := *token
+		return &redeemed, nil
+	}
+	return nil, ErrJoinToken
+}
+
+// loadJoinTokens 读取加入令牌，不存在时返回空列表
+func (c *CA) loadJoinTokens() ([]JoinToken, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, joinTokensFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取加入令牌失败: %w", err)
+	}
+	var tokens []JoinToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("解析加入令牌失败: %w", err)
+	}
+	return tokens, nil
+}
+
+// saveJoinTokens 保存加入令牌，同时清理已使用或已过期超过 joinTokenRetention 的令牌
+func (c *CA) saveJoinTokens(tokens []JoinToken) error {
+	cutoff := time.Now().Add(-joinTokenRetention)
+	kept := tokens[:0]
+	for _, token := range tokens {
+		if (token.UsedAt != nil && token.UsedAt.Before(cutoff)) || token.ExpiresAt.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, token)
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(c.dir, joinTokensFile), append(data, '\n'), 0600)
+}
+
+func hashJoinSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,110 @@
+package ca
+
+import (
+	"autocert/internal/audit"
+	"autocert/internal/logger"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+)
+
+// agentNamePattern agent 名称：字母、数字、点、连字符和下划线（通常为主机名）
+var agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
+// ValidAgentName agent 名称是否有效
+func ValidAgentName(name string) bool {
+	return agentNamePattern.MatchString(name)
+}
+
+// Fingerprint CA 根证书的 SHA-256（base64url），加入令牌中携带该值，agent 首次连接时据此确认服务端
+func (c *CA) Fingerprint() string {
+	return CertFingerprint(c.Cert)
+}
+
+// CertFingerprint 证书的 SHA-256（base64url）
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// IssueServer 签发守护进程 HTTPS 接口使用的服务端证书（扩展密钥用途 serverAuth），names 为域名或 IP
+func (c *CA) IssueServer(names []string, validity time.Duration) (*Issued, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("服务端证书至少需要一个域名或 IP")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成服务端私钥失败: %w", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: names[0]},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+	cert, record, err := c.sign(template, key.Public(), TypeServer, validity)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("已签发守护进程服务端证书", "names", names, "serial", record.Serial, "notAfter", record.NotAfter)
+	return &Issued{Cert: cert, Key: key, Record: record}, nil
+}
+
+// SignAgent 为注册的 agent 签发客户端证书：私钥由 agent 生成，只提交 CSR，CN 为 agent 名称
+func (c *CA) SignAgent(name string, csr *x509.CertificateRequest, validity time.Duration) (*x509.Certificate, Record, error) {
+	if !ValidAgentName(name) {
+		return nil, Record{}, fmt.Errorf("agent 名称 %q 无效", name)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, Record{}, fmt.Errorf("CSR 签名无效: %w", err)
+	}
+	if _, ok := csr.PublicKey.(*ecdsa.PublicKey); !ok {
+		return nil, Record{}, fmt.Errorf("agent 私钥只支持 ECDSA，收到 %T", csr.PublicKey)
+	}
+
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, record, err := c.sign(template, csr.PublicKey, TypeAgent, validity)
+	if err != nil {
+		return nil, Record{}, err
+	}
+
+	logger.Info("已为 agent 签发客户端证书", "agent", name, "serial", record.Serial, "notAfter", record.NotAfter)
+	audit.Record(audit.Entry{Action: "ca.enroll-agent", Result: audit.ResultSuccess,
+		Detail: fmt.Sprintf("agent=%s serial=%s", name, record.Serial)})
+	return cert, record, nil
+}
+
+// CheckRevoked 证书由本 CA 签发且已吊销时返回错误（mTLS 握手时校验，吊销立即生效，无需等待 CRL 更新）
+func (c *CA) CheckRevoked(cert *x509.Certificate) error {
+	idx, err := c.loadIndex()
+	if err != nil {
+		return err
+	}
+	serial := formatSerial(cert.SerialNumber)
+	for _, record := range idx.Certificates {
+		if record.Serial == serial && record.Revoked() {
+			return fmt.Errorf("客户端证书 %s（%s）已吊销", record.CommonName, serial)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package ca
+
+import (
+	"autocert/internal/logger"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OCSP 响应状态（RFC 6960 4.2.1）
+const (
+	ocspSuccessful       = 0
+	ocspMalformedRequest = 1
+	ocspInternalError    = 2
+	ocspUnauthorized     = 6
+)
+
+// ocspValidity OCSP 响应的有效期（nextUpdate）
+const ocspValidity = time.Hour
+
+var (
+	oidOCSPBasic    = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidSHA1         = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidSHA256       = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidECDSASHA256  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSASHA384  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidRSASHA256    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	certIDHashFuncs = map[string]crypto.Hash{oidSHA1.String(): crypto.SHA1, oidSHA256.String(): crypto.SHA256}
+)
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type tbsRequest struct {
+	Version       int              `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName pkix.RDNSequence `asn1:"explicit,tag:1,optional"`
+	RequestList   []singleRequest
+}
+
+type singleRequest struct {
+	Cert certID
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+type responseData struct {
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []singleResponse
+}
+
+type singleResponse struct {
+	CertID     certID
+	Good       asn1.Flag   `asn1:"tag:0,optional"`
+	Revoked    revokedInfo `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag   `asn1:"tag:2,optional"`
+	ThisUpdate time.Time   `asn1:"generalized"`
+	NextUpdate time.Time   `asn1:"generalized,explicit,tag:0,optional"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// RespondOCSP 处理 DER 编码的 OCSP 请求，返回由 CA 私钥直接签名的 DER 编码响应；
+// 不属于本 CA 的证书返回 unauthorized，未签发过的序列号返回 unknown
+func (c *CA) RespondOCSP(request []byte) []byte {
+	var req ocspRequest
+	if rest, err := asn1.Unmarshal(request, &req); err != nil || len(rest) > 0 || len(req.TBSRequest.RequestList) == 0 {
+		return ocspStatus(ocspMalformedRequest)
+	}
+
+	idx, err := c.loadIndex()
+	if err != nil {
+		logger.Error("OCSP 读取签发记录失败", "error", err)
+		return ocspStatus(ocspInternalError)
+	}
+	records := make(map[string]*Record, len(idx.Certificates))
+	for i := range idx.Certificates {
+		records[idx.Certificates[i].Serial] = &idx.Certificates[i]
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	var responses []singleResponse
+	for _, single := range req.TBSRequest.RequestList {
+		id := single.Cert
+		if !c.issuedBy(id) {
+			return ocspStatus(ocspUnauthorized)
+		}
+
+		response := singleResponse{CertID: id, ThisUpdate: now, NextUpdate: now.Add(ocspValidity)}
+		record, ok := records[formatSerial(id.SerialNumber)]
+		switch {
+		case !ok:
+			response.Unknown = true
+		case record.Revoked():
+			response.Revoked = revokedInfo{
+				RevocationTime: record.RevokedAt.UTC().Truncate(time.Second),
+				Reason:         asn1.Enumerated(reasonCodes[record.Reason]),
+			}
+		default:
+			response.Good = true
+		}
+		responses = append(responses, response)
+	}
+
+	der, err := c.signOCSP(responses, now)
+	if err != nil {
+		logger.Error("生成 OCSP 响应失败", "error", err)
+		return ocspStatus(ocspInternalError)
+	}
+	return der
+}
+
+// issuedBy 请求中的颁发者名称和公钥摘要是否与本 CA 一致
+func (c *CA) issuedBy(id certID) bool {
+	hash, ok := certIDHashFuncs[id.HashAlgorithm.Algorithm.String()]
+	if !ok || id.SerialNumber == nil {
+		return false
+	}
+
+	var spki subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(c.Cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return false
+	}
+
+	h := hash.New()
+	h.Write(c.Cert.RawSubject)
+	nameHash := h.Sum(nil)
+	h = hash.New()
+	h.Write(spki.PublicKey.RightAlign())
+	keyHash := h.Sum(nil)
+
+	return bytes.Equal(nameHash, id.NameHash) && bytes.Equal(keyHash, id.IssuerKeyHash)
+}
+
+// signOCSP 生成并签名 BasicOCSPResponse
+func (c *CA) signOCSP(responses []singleResponse, producedAt time.Time) ([]byte, error) {
+	var spki subjectPublicKeyInfo
+	if _, err := asn1.Unmarshal(c.Cert.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return nil, err
+	}
+	keyHash := sha1.Sum(spki.PublicKey.RightAlign())
+	keyHashDER, err := asn1.Marshal(keyHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	tbs, err := asn1.Marshal(responseData{
+		// byKey [2] EXPLICIT KeyHash
+		ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, IsCompound: true, Bytes: keyHashDER},
+		ProducedAt:  producedAt,
+		Responses:   responses,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm, hash, err := signatureAlgorithm(c.key)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write(tbs)
+	signature, err := c.key.Sign(rand.Reader, h.Sum(nil), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	basic, err := asn1.Marshal(basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: algorithm,
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(ocspResponse{
+		Status:   ocspSuccessful,
+		Response: responseBytes{ResponseType: oidOCSPBasic, Response: basic},
+	})
+}
+
+// signatureAlgorithm CA 私钥对应的签名算法
+func signatureAlgorithm(key crypto.Signer) (pkix.AlgorithmIdentifier, crypto.Hash, error) {
+	switch pub := key.Public().(type) {
+	case *ecdsa.PublicKey:
+		if pub.Curve == elliptic.P384() {
+			return pkix.AlgorithmIdentifier{Algorithm: oidECDSASHA384}, crypto.SHA384, nil
+		}
+		return pkix.AlgorithmIdentifier{Algorithm: oidECDSASHA256}, crypto.SHA256, nil
+	case *rsa.PublicKey:
+		return pkix.AlgorithmIdentifier{Algorithm: oidRSASHA256, Parameters: asn1.NullRawValue}, crypto.SHA256, nil
+	default:
+		return pkix.AlgorithmIdentifier{}, 0, fmt.Errorf("不支持的 CA 私钥类型")
+	}
+}
+
+// ocspStatus 不包含响应内容的错误状态
+func ocspStatus(status int) []byte {
+	der, _ := asn1.Marshal(ocspResponse{Status: asn1.Enumerated(status)})
+	return der
+}
+
+// maxOCSPRequest OCSP 请求的大小上限
+const maxOCSPRequest = 16 * 1024
+
+// OCSPHandler OCSP 服务（RFC 6960 附录 A）：支持 POST 和 GET /<prefix>/<base64 编码的请求>
+func (c *CA) OCSPHandler(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request []byte
+		switch r.Method {
+		case http.MethodPost:
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxOCSPRequest))
+			if err != nil {
+				http.Error(w, "读取请求失败", http.StatusBadRequest)
+				return
+			}
+			request = body
+		case http.MethodGet:
+			encoded := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+			if unescaped, err := url.PathUnescape(encoded); err == nil {
+				encoded = unescaped
+			}
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				http.Error(w, "请求格式错误", http.StatusBadRequest)
+				return
+			}
+			request = decoded
+		default:
+			http.Error(w, "仅支持 GET 和 POST 请求", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public", int(ocspValidity/time.Second)/2))
+		w.Write(c.RespondOCSP(request))
+	})
+}
@@ -0,0 +1,133 @@
+package cert
+
+import (
+	"autocert/internal/acme"
+	"autocert/internal/config"
+	"autocert/internal/keystore"
+	"autocert/internal/logger"
+	"crypto"
+	"encoding/json"
+	"fmt"
+)
+
+// RolloverAccountKey 轮换 ACME 账户密钥：用旧密钥查询账户 URL，再提交旧密钥和新密钥共同签名的
+// keyChange 请求，CA 接受后才替换本地密钥文件，账户及其已有的授权保持不变。
+// 上次轮换中断留下的新密钥已能查询到账户时，说明 CA 已接受该密钥，只替换本地密钥文件
+func RolloverAccountKey() (oldKey, newKey crypto.Signer, err error) {
+	var client *acme.Client
+	connect := func() (*acme.Client, error) {
+		if client == nil {
+			c, err := acme.NewClient(config.GetACMEConfig().Server)
+			if err != nil {
+				return nil, err
+			}
+			client = c
+		}
+		return client, nil
+	}
+
+	accepted := func(newKey crypto.Signer) bool {
+		client, err := connect()
+		if err != nil {
+			return false
+		}
+		accountURL, err := lookupAccount(client, newKey)
+		if err != nil {
+			logger.Debug("新密钥未关联账户，重新提交密钥轮换请求", "error", err)
+			return false
+		}
+		logger.Debug("新密钥已关联账户", "account", accountURL)
+		return true
+	}
+
+	return keystore.RolloverAccountKey(accepted, func(oldKey, newKey crypto.Signer) error {
+		client, err := connect()
+		if err != nil {
+			return err
+		}
+		if client.Directory.KeyChange == "" {
+			return fmt.Errorf("CA 不支持账户密钥轮换（目录缺少 keyChange）")
+		}
+
+		accountURL, err := lookupAccount(client, oldKey)
+		if err != nil {
+			return err
+		}
+
+		nonce, err := client.Nonce()
+		if err != nil {
+			return err
+		}
+		jws, err := acme.KeyChange(oldKey, newKey, client.Directory.KeyChange, nonce, accountURL)
+		if err != nil {
+			return fmt.Errorf("密钥轮换请求签名失败: %w", err)
+		}
+		if _, _, err := client.Post(client.Directory.KeyChange, jws); err != nil {
+			return fmt.Errorf("提交密钥轮换请求失败: %w", err)
+		}
+
+		logger.Debug("CA 已接受密钥轮换", "account", accountURL)
+		return nil
+	})
+}
+
+// lookupAccount 使用账户密钥查询账户 URL（newAccount，onlyReturnExisting），CA 在 Location 中返回
+func lookupAccount(client *acme.Client, signer crypto.Signer) (string, error) {
+	payload, err := json.Marshal(map[string]bool{"onlyReturnExisting": true})
+	if err != nil {
+		return "", err
+	}
+	nonce, err := client.Nonce()
+	if err != nil {
+		return "", err
+	}
+	jws, err := acme.SignJWS(signer, client.Directory.NewAccount, nonce, "", payload)
+	if err != nil {
+		return "", fmt.Errorf("查询账户请求签名失败: %w", err)
+	}
+	resp, _, err := client.Post(client.Directory.NewAccount, jws)
+	if err != nil {
+		return "", fmt.Errorf("查询账户失败: %w", err)
+	}
+	accountURL := resp.Header.Get("Location")
+	if accountURL == "" {
+		return "", fmt.Errorf("查询账户失败: CA 没有返回账户 URL")
+	}
+	return accountURL, nil
+}
+
+// FetchAuthorization 以账户身份查询 CA 上的授权（POST-as-GET，RFC 8555 第 6.3 节），
+// 授权无效时返回 CA 的问题详情、验证记录和排查建议
+func FetchAuthorization(url string) (*acme.Authorization, error) {
+	signer, err := keystore.AccountSigner()
+	if err != nil {
+		return nil, fmt.Errorf("加载账户密钥失败: %w", err)
+	}
+	client, err := acme.NewClient(config.GetACMEConfig().Server)
+	if err != nil {
+		return nil, err
+	}
+	accountURL, err := lookupAccount(client, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := client.Nonce()
+	if err != nil {
+		return nil, err
+	}
+	jws, err := acme.SignJWS(signer, url, nonce, accountURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询授权请求签名失败: %w", err)
+	}
+	_, body, err := client.Post(url, jws)
+	if err != nil {
+		return nil, fmt.Errorf("查询授权失败: %w", err)
+	}
+
+	var authz acme.Authorization
+	if err := json.Unmarshal(body, &authz); err != nil {
+		return nil, fmt.Errorf("解析授权失败: %w", err)
+	}
+	return &authz, checkAuthorization(&authz)
+}
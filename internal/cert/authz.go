@@ -0,0 +1,277 @@
+package cert
+
+import (
+	"autocert/internal/acme"
+	"autocert/internal/challenge"
+	"autocert/internal/config"
+	"autocert/internal/dns"
+	"autocert/internal/logger"
+	"autocert/internal/progress"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// authzSession 一个订单内所有授权共享的验证资源：
+// Standalone 服务器只启动一次，每个 webroot 的挑战目录只准备一次，同一条 TXT 记录只添加一次
+type authzSession struct {
+	challengeDirs map[string]string // 网站根目录 → 挑战目录
+	server        *standaloneLease
+	dnsProvider   dns.Provider
+	shared        *challenge.Store // 开启 challenge.shared 时发布 http-01 挑战的共享存储
+
+	mu           sync.Mutex
+	dnsRecords   map[string]bool
+	dnsPresented []dns.Record // 通过 DNS 服务商添加、订单结束后删除的记录
+	published    []string     // 发布到共享存储、订单结束后删除的挑战 token
+}
+
+// newAuthzSession 按订单中用到的验证方式准备共享资源，webroots 为 Webroot 模式的成员用到的网站根目录
+func newAuthzSession(webroots []string, types map[ChallengeType]bool) (*authzSession, error) {
+	s := &authzSession{challengeDirs: make(map[string]string), dnsRecords: make(map[string]bool)}
+
+	if types[ChallengeWebroot] {
+		// 准备挑战目录（所有者、权限）并清理残留的挑战文件
+		for _, webrootPath := range webroots {
+			dir, err := prepareChallengeDir(webrootPath)
+			if err != nil {
+				return nil, err
+			}
+			s.challengeDirs[webrootPath] = dir
+			logger.Debug("挑战目录已就绪", "dir", dir)
+		}
+	}
+
+	if types[ChallengeStandalone] {
+		// 启动临时 HTTP 服务器（IPv4 和 IPv6），所有授权共用，按 token 区分响应
+		server, err := startStandaloneServer()
+		if err != nil {
+			return nil, err
+		}
+		s.server = server
+	}
+
+	if (types[ChallengeWebroot] || types[ChallengeStandalone]) && config.GetChallengeConfig().Shared {
+		// 负载均衡后的其他主机通过 challenge-responder 从共享存储响应挑战
+		store, err := challenge.Open()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.shared = store
+		if s.server != nil {
+			s.server.SetStore(store)
+		}
+		logger.Debug("http-01 挑战将发布到共享存储", "backend", store.Name())
+	}
+
+	if types[ChallengeDNS] {
+		// 未配置 DNS 服务商时提示手动添加记录
+		provider, err := dns.Open()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.dnsProvider = provider
+	}
+
+	return s, nil
+}
+
+// Close 释放共享资源，删除添加的 TXT 记录
+func (s *authzSession) Close() {
+	if s.server != nil {
+		s.server.Close()
+	}
+	for _, token := range s.published {
+		if err := s.shared.Remove(token); err != nil {
+			logger.Warn("删除共享挑战失败，将在过期后失效", "token", token, "error", err)
+		}
+	}
+	for _, record := range s.dnsPresented {
+		if err := dns.CleanUp(s.dnsProvider, record.FQDN, record.Value); err != nil {
+			logger.Warn("删除验证记录失败，将由 cleanup-dns 清理", "record", record.FQDN, "error", err)
+		}
+	}
+}
+
+// solve 完成单个域名的授权验证，可并发调用。webrootPath 只用于 Webroot 模式
+func (s *authzSession) solve(domain string, challengeType ChallengeType, webrootPath string) error {
+	switch challengeType {
+	case ChallengeWebroot:
+		// 这里应该在挑战目录下写入挑战文件（权限 0644），验证完成后删除
+		logger.Debug("使用 Webroot 模式验证域名", "domain", domain, "dir", s.challengeDirs[webrootPath])
+		if err := s.publishShared(domain); err != nil {
+			return err
+		}
+		return s.validate(domain, "http-01")
+	case ChallengeStandalone:
+		// 这里应该通过 s.server.SetToken 设置挑战响应
+		logger.Debug("使用 Standalone 模式验证域名", "domain", domain)
+		if err := s.publishShared(domain); err != nil {
+			return err
+		}
+		return s.validate(domain, "http-01")
+	case ChallengeDNS:
+		if err := s.solveDNS(domain); err != nil {
+			return err
+		}
+		return s.validate(domain, "dns-01")
+	default:
+		return fmt.Errorf("不支持的验证模式: %d", challengeType)
+	}
+}
+
+// publishShared 开启 challenge.shared 时将 http-01 挑战发布到共享存储，
+// CA 的验证请求被负载均衡到其他主机时由其 challenge-responder 响应
+func (s *authzSession) publishShared(domain string) error {
+	if s.shared == nil {
+		return nil
+	}
+
+	// 这里应该使用 CA 返回的 token 和账户密钥指纹计算 key authorization，模拟时使用随机值
+	token := challengeToken()
+	if err := s.shared.Publish(domain, token, token+"."+challengeValue()); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.published = append(s.published, token)
+	s.mu.Unlock()
+	return nil
+}
+
+// solveDNS 通过 DNS 服务商添加 TXT 记录，未配置时提示手动添加；
+// 泛域名与基础域名使用同一记录名，只处理一次
+func (s *authzSession) solveDNS(domain string) error {
+	record := dns.ChallengeRecord(domain)
+
+	s.mu.Lock()
+	seen := s.dnsRecords[record]
+	s.dnsRecords[record] = true
+	s.mu.Unlock()
+	if seen {
+		return nil
+	}
+
+	if s.dnsProvider == nil {
+		logger.Warn("注意：DNS 模式需要手动添加 DNS 记录或配置 DNS API", "domain", domain)
+		logger.Info("需要为域名添加 DNS TXT 记录", "record", record, "domain", domain)
+		return nil
+	}
+
+	// 这里应该使用 CA 返回的 token 计算 key authorization 的摘要，模拟时使用随机值
+	value := challengeValue()
+	err := dns.Present(s.dnsProvider, domain, record, value)
+
+	// 添加失败时记录可能已部分生效，同样需要删除
+	s.mu.Lock()
+	s.dnsPresented = append(s.dnsPresented, dns.Record{FQDN: record, Value: value})
+	s.mu.Unlock()
+	return err
+}
+
+// validate 通知 CA 验证挑战并等待授权结果，失败时返回 CA 的问题详情和排查建议。
+// 这里应该向挑战 URL 发送 POST 请求并通过 FetchAuthorization 轮询授权 URL，模拟时 CA 总是验证通过；
+// CA 上已失败的授权可以用 autocert authz 查询
+func (s *authzSession) validate(domain, challengeType string) error {
+	authz := &acme.Authorization{
+		Identifier: acme.Identifier{Type: "dns", Value: strings.TrimPrefix(domain, "*.")},
+		Status:     acme.StatusValid,
+		Wildcard:   strings.HasPrefix(domain, "*."),
+		Challenges: []acme.Challenge{{Type: challengeType, Status: acme.StatusValid}},
+	}
+	return checkAuthorization(authz)
+}
+
+// challengeValue DNS-01 的 TXT 记录值（base64url 编码的 SHA-256 摘要）
+func challengeValue() string {
+	token := make([]byte, 32)
+	rand.Read(token)
+	sum := sha256.Sum256(token)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// challengeToken http-01 挑战 token（base64url 编码的随机值）
+func challengeToken() string {
+	token := make([]byte, 32)
+	rand.Read(token)
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// authzConcurrency 同时处理的授权数量
+func authzConcurrency(total int) int {
+	n := config.GetACMEConfig().AuthzConcurrency
+	if n <= 0 {
+		n = 1
+	}
+	return min(n, total)
+}
+
+// solveAuthorizations 使用有限数量的 worker 并发完成所有授权，逐个显示进度；
+// 任一授权失败后订单已无法完成，不再开始新的授权，返回所有失败的授权。
+// 每个域名按 challengeFor 选择验证方式，Webroot 模式的域名按 webrootFor 选择网站根目录
+func solveAuthorizations(domains []string, webrootFor func(string) string, challengeFor func(string) ChallengeType) error {
+	types := make(map[ChallengeType]bool)
+	var webroots []string
+	seen := make(map[string]bool)
+	for _, domain := range domains {
+		challengeType := challengeFor(domain)
+		types[challengeType] = true
+		if webrootPath := webrootFor(domain); challengeType == ChallengeWebroot && !seen[webrootPath] {
+			seen[webrootPath] = true
+			webroots = append(webroots, webrootPath)
+		}
+	}
+
+	session, err := newAuthzSession(webroots, types)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	workers := authzConcurrency(len(domains))
+	logger.Debug("开始处理授权", "count", len(domains), "workers", workers)
+
+	bar := progress.NewBar("验证域名授权", len(domains))
+	jobs := make(chan string)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		failed atomic.Bool
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for domain := range jobs {
+				if failed.Load() {
+					continue
+				}
+				err := session.solve(domain, challengeFor(domain), webrootFor(domain))
+				bar.Step(domain, err)
+				if err != nil {
+					failed.Store(true)
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("域名 %s 授权验证失败: %w", domain, err))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, domain := range domains {
+		jobs <- domain
+	}
+	close(jobs)
+	wg.Wait()
+
+	err = errors.Join(errs...)
+	bar.Finish(err)
+	return err
+}
@@ -0,0 +1,29 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/preflight"
+	"strings"
+)
+
+// cdnFallback 返回 domains（使用 http-01 验证的域名）中经过 CDN 代理、改用 DNS 验证的域名。CA 的 http-01 验证请求由 CDN 转发，
+// 可能被缓存或强制跳转；只在 acme.cdn_fallback 为 dns 且配置了 DNS 服务商时切换，否则由预检给出警告
+func cdnFallback(domains []string) map[string]bool {
+	if len(domains) == 0 || config.GetACMEConfig().CDNFallback != "dns" || config.GetDNSConfig().Provider == "" {
+		return nil
+	}
+
+	switched := make(map[string]bool)
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			continue
+		}
+		if cdn := preflight.DetectCDN(domain); cdn != nil {
+			logger.Warn("域名经过 CDN 代理，http-01 验证请求可能无法到达本机，改用 DNS 验证",
+				"domain", domain, "cdn", cdn.Name, "evidence", cdn.Evidence)
+			switched[domain] = true
+		}
+	}
+	return switched
+}
@@ -0,0 +1,50 @@
+package cert
+
+import (
+	"autocert/internal/acme"
+	"autocert/internal/logger"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// errAlternateChains 当前签发流程不下载备用证书链（rel="alternate"），无法按首选证书链选择
+var errAlternateChains = errors.New("当前签发流程只获取默认证书链，不支持首选证书链，请移除 --preferred-chain 参数或 preferred_chain 配置")
+
+// checkPreferredChain 配置了首选证书链时返回错误，避免首选证书链被静默忽略
+func checkPreferredChain(domain, preferred string) error {
+	if preferred == "" {
+		return nil
+	}
+	return fmt.Errorf("%s 配置了首选证书链 %q: %w", domain, preferred, errAlternateChains)
+}
+
+// selectPreferredChain 从 CA 提供的证书链（PEM，默认证书链在前，之后为备用证书链）中选择首选证书链，
+// 未配置首选证书链或没有匹配的证书链时使用默认证书链
+func selectPreferredChain(domain, preferred string, chains [][]byte) ([]byte, error) {
+	if len(chains) == 0 {
+		return nil, errors.New("CA 没有返回证书")
+	}
+	if preferred == "" {
+		return chains[0], nil
+	}
+
+	parsed := make([][]*x509.Certificate, len(chains))
+	roots := make([]string, len(chains))
+	for i, chain := range chains {
+		certs, err := parseCertificateChain(chain)
+		if err != nil {
+			return nil, fmt.Errorf("解析第 %d 条证书链失败: %w", i+1, err)
+		}
+		parsed[i] = certs
+		roots[i] = acme.ChainRoot(certs)
+	}
+
+	index := acme.SelectChain(parsed, preferred)
+	if index < 0 {
+		logger.Warn("CA 没有提供首选证书链，使用默认证书链", "domain", domain, "preferred", preferred, "available", roots)
+		return chains[0], nil
+	}
+	logger.Info("使用首选证书链", "domain", domain, "preferred", preferred, "root", roots[index], "alternate", index > 0)
+	return chains[index], nil
+}
@@ -0,0 +1,340 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/dns"
+	"autocert/internal/logger"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tlsaFile 证书目录中记录已发布 TLSA 记录的文件
+const tlsaFile = "tlsa.txt"
+
+// daneKey 证书使用的私钥（双证书模式下 RSA 和 ECDSA 各一个）
+type daneKey struct {
+	path    string
+	keyType string
+	keySize int
+}
+
+// tlsaRecord 已发布的 TLSA 记录
+type tlsaRecord struct {
+	Name      string
+	Value     string
+	Published time.Time
+}
+
+// daneSession 一次签发中的 TLSA 记录管理。
+// 签发前为本次使用的私钥（上一次签发时预生成的下一个私钥）发布记录，部署后预生成并发布
+// 下一次续期使用的私钥，最后删除不再使用的旧私钥的记录
+type daneSession struct {
+	cfg     config.DANEConfig
+	dir     string
+	names   []string
+	keys    []daneKey
+	manager dns.RecordManager
+	records []tlsaRecord
+}
+
+// nextKeyPath 下一次续期使用的私钥，例如 key.pem 对应 key-next.pem
+func nextKeyPath(keyPath string) string {
+	return strings.TrimSuffix(keyPath, ".pem") + "-next.pem"
+}
+
+// newPrivateKey 生成证书私钥；启用 DANE 时使用已发布 TLSA 记录的预生成私钥
+func newPrivateKey(keyType string, keySize int, keyPath string) (crypto.Signer, error) {
+	if config.GetDANEConfig().Enabled {
+		if key, err := loadPrivateKey(nextKeyPath(keyPath)); err == nil && keyMatches(key, keyType, keySize) {
+			logger.Info("使用已发布 TLSA 记录的预生成私钥", "key", nextKeyPath(keyPath))
+			return key, nil
+		}
+	}
+	return generatePrivateKey(keyType, keySize)
+}
+
+// keyMatches 私钥是否为指定的类型和长度
+func keyMatches(key crypto.Signer, keyType string, keySize int) bool {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if keySize == 0 {
+			keySize = 2048
+		}
+		return (keyType == "" || strings.EqualFold(keyType, KeyTypeRSA)) && k.N.BitLen() == keySize
+	case *ecdsa.PrivateKey:
+		return strings.EqualFold(keyType, KeyTypeECDSA) || strings.EqualFold(keyType, "ec")
+	default:
+		return false
+	}
+}
+
+// newDANESession 未启用 DANE 时返回 nil
+func newDANESession(domains []string, dir string, keys []daneKey) (*daneSession, error) {
+	cfg := config.GetDANEConfig()
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if len(cfg.Ports) == 0 {
+		return nil, errors.New("未配置 dane.ports")
+	}
+
+	provider, err := dns.Open()
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, errors.New("发布 TLSA 记录需要配置 dns.provider")
+	}
+	manager, ok := provider.(dns.RecordManager)
+	if !ok {
+		return nil, fmt.Errorf("DNS 服务商 %s 不支持发布 TLSA 记录", provider.Name())
+	}
+
+	protocol := cfg.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	var names []string
+	for _, domain := range domains {
+		// 泛域名的 TLSA 记录需要通配符记录名，不自动发布
+		if strings.HasPrefix(domain, "*.") {
+			logger.Warn("泛域名不发布 TLSA 记录", "domain", domain)
+			continue
+		}
+		for _, port := range cfg.Ports {
+			names = append(names, fmt.Sprintf("_%d._%s.%s", port, protocol, domain))
+		}
+	}
+
+	return &daneSession{
+		cfg:     cfg,
+		dir:     dir,
+		names:   names,
+		keys:    keys,
+		manager: manager,
+		records: readTLSARecords(dir),
+	}, nil
+}
+
+// prepare 签发前确保本次使用的私钥已生成并发布 TLSA 记录，发布失败时不签发
+func (s *daneSession) prepare() error {
+	for _, key := range s.keys {
+		next, err := s.ensureNextKey(key, false)
+		if err != nil {
+			return err
+		}
+		value, err := tlsaValue(next.Public(), s.cfg.MatchingType)
+		if err != nil {
+			return err
+		}
+		if err := s.publish(value); err != nil {
+			return err
+		}
+
+		// 记录发布后至少等待两个 TTL，解析器缓存的旧记录集才会过期
+		if published := s.publishedAt(value); s.hasOtherRecords(value) && time.Since(published) < 2*s.cfg.TTL {
+			logger.Warn("新私钥的 TLSA 记录发布时间不足两个 TTL，部分解析器可能仍缓存旧记录",
+				"key", nextKeyPath(key.path), "published", published, "ttl", s.cfg.TTL)
+		}
+	}
+	return s.save()
+}
+
+// rotate 新证书保存后预生成下一次续期使用的私钥并发布记录；失败只记录警告，下次签发前重试
+func (s *daneSession) rotate() {
+	for _, key := range s.keys {
+		next, err := s.ensureNextKey(key, true)
+		if err != nil {
+			logger.Warn("预生成下一个私钥失败", "key", key.path, "error", err)
+			continue
+		}
+		value, err := tlsaValue(next.Public(), s.cfg.MatchingType)
+		if err == nil {
+			err = s.publish(value)
+		}
+		if err != nil {
+			logger.Warn("发布下一个私钥的 TLSA 记录失败，将在下次签发前重试", "key", nextKeyPath(key.path), "error", err)
+		}
+	}
+	if err := s.save(); err != nil {
+		logger.Warn("保存 TLSA 记录状态失败", "error", err)
+	}
+}
+
+// retire 部署完成后删除当前私钥和下一个私钥以外的 TLSA 记录
+func (s *daneSession) retire() {
+	keep := make(map[string]bool)
+	for _, key := range s.keys {
+		for _, path := range []string{key.path, nextKeyPath(key.path)} {
+			signer, err := loadPrivateKey(path)
+			if err != nil {
+				continue
+			}
+			if value, err := tlsaValue(signer.Public(), s.cfg.MatchingType); err == nil {
+				keep[value] = true
+			}
+		}
+	}
+	names := make(map[string]bool)
+	for _, name := range s.names {
+		names[name] = true
+	}
+
+	kept := s.records[:0]
+	for _, record := range s.records {
+		if keep[record.Value] && names[record.Name] {
+			kept = append(kept, record)
+			continue
+		}
+		if err := s.manager.DeleteRecord(record.Name, "TLSA", record.Value); err != nil {
+			logger.Warn("删除旧 TLSA 记录失败，将在下次签发后重试", "record", record.Name, "error", err)
+			kept = append(kept, record)
+			continue
+		}
+		logger.Info("已删除旧 TLSA 记录", "record", record.Name, "value", record.Value)
+	}
+	s.records = kept
+	if err := s.save(); err != nil {
+		logger.Warn("保存 TLSA 记录状态失败", "error", err)
+	}
+}
+
+// ensureNextKey 返回下一个私钥，不存在、类型不符或 replaceCurrent 时与当前私钥相同则重新生成
+func (s *daneSession) ensureNextKey(key daneKey, replaceCurrent bool) (crypto.Signer, error) {
+	path := nextKeyPath(key.path)
+	next, err := loadPrivateKey(path)
+	if err == nil && keyMatches(next, key.keyType, key.keySize) {
+		current, err := loadPrivateKey(key.path)
+		if !replaceCurrent || err != nil || !samePublicKey(current, next) {
+			return next, nil
+		}
+	}
+
+	next, err = generatePrivateKey(key.keyType, key.keySize)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePrivateKey(path, next); err != nil {
+		return nil, fmt.Errorf("保存下一个私钥失败: %w", err)
+	}
+	logger.Info("已预生成下一次续期使用的私钥", "key", path)
+	return next, nil
+}
+
+// publish 为所有记录名发布该值的 TLSA 记录（已发布的跳过）
+func (s *daneSession) publish(value string) error {
+	ttl := int(s.cfg.TTL / time.Second)
+	for _, name := range s.names {
+		if s.isPublished(name, value) {
+			continue
+		}
+		if err := s.manager.AddRecord(name, "TLSA", value, ttl); err != nil {
+			return fmt.Errorf("发布 TLSA 记录 %s 失败: %w", name, err)
+		}
+		s.records = append(s.records, tlsaRecord{Name: name, Value: value, Published: time.Now()})
+		logger.Info("已发布 TLSA 记录", "record", name, "value", value)
+	}
+	return nil
+}
+
+func (s *daneSession) isPublished(name, value string) bool {
+	for _, record := range s.records {
+		if record.Name == name && record.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// hasOtherRecords 是否已发布其他私钥的记录（首次发布时解析器没有缓存的旧记录集）
+func (s *daneSession) hasOtherRecords(value string) bool {
+	for _, record := range s.records {
+		if record.Value != value {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedAt 该值的记录中最晚的发布时间
+func (s *daneSession) publishedAt(value string) time.Time {
+	var latest time.Time
+	for _, record := range s.records {
+		if record.Value == value && record.Published.After(latest) {
+			latest = record.Published
+		}
+	}
+	return latest
+}
+
+func (s *daneSession) save() error {
+	var lines []string
+	for _, record := range s.records {
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%d", record.Name, record.Value, record.Published.Unix()))
+	}
+	path := filepath.Join(s.dir, tlsaFile)
+	if len(lines) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// readTLSARecords 读取证书目录中的 tlsa.txt（每行：记录名、记录值、发布时间）
+func readTLSARecords(dir string) []tlsaRecord {
+	var records []tlsaRecord
+	for _, line := range readLines(filepath.Join(dir, tlsaFile)) {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 3 {
+			continue
+		}
+		published, _ := strconv.ParseInt(parts[2], 10, 64)
+		records = append(records, tlsaRecord{Name: parts[0], Value: parts[1], Published: time.Unix(published, 0)})
+	}
+	return records
+}
+
+// tlsaValue TLSA 记录值：3 1 <匹配类型> <SubjectPublicKeyInfo 的摘要>（DANE-EE，SPKI）
+func tlsaValue(pub crypto.PublicKey, matchingType int) (string, error) {
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+
+	var data []byte
+	switch matchingType {
+	case 0:
+		data = spki
+	case 1:
+		sum := sha256.Sum256(spki)
+		data = sum[:]
+	case 2:
+		sum := sha512.Sum512(spki)
+		data = sum[:]
+	default:
+		return "", fmt.Errorf("不支持的 TLSA 匹配类型: %d", matchingType)
+	}
+	return fmt.Sprintf("3 1 %d %x", matchingType, data), nil
+}
+
+// samePublicKey 两个私钥的公钥是否相同
+func samePublicKey(a, b crypto.Signer) bool {
+	type equaler interface {
+		Equal(crypto.PublicKey) bool
+	}
+	pub, ok := a.Public().(equaler)
+	return ok && pub.Equal(b.Public())
+}
@@ -0,0 +1,64 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// deployHandler 设置后由它代替本进程配置 Web 服务器和执行部署钩子，参数为证书目录名。
+// 守护进程降权后，写入站点配置、重载 Web 服务器和部署钩子需要 root 权限，交给降权前启动的部署助手
+var deployHandler func(name string) error
+
+// SetDeployHandler 设置部署处理函数，nil 表示在本进程中部署
+func SetDeployHandler(handler func(name string) error) {
+	deployHandler = handler
+}
+
+// storedDomainPattern 证书目录中记录的域名必须满足的格式（部署助手写入站点配置前校验）
+var storedDomainPattern = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+([a-zA-Z]{2,63}|xn--[a-zA-Z0-9-]{1,59})$`)
+
+// storedChainPattern preferred-chain 选项值允许的格式（根证书名称）
+var storedChainPattern = regexp.MustCompile(`^[a-zA-Z0-9 ._()-]{1,64}$`)
+
+// DeployStored 为证书目录中名为 name 的证书配置 Web 服务器并执行部署钩子（部署助手使用）。
+// 证书目录属于降权后的守护进程用户，domains.txt 和 site-options.txt 不可信，校验通过后才部署
+func DeployStored(name string) error {
+	certs, err := ListCertificates(config.GetCertDir())
+	if err != nil {
+		return fmt.Errorf("读取证书目录失败: %w", err)
+	}
+	for _, stored := range certs {
+		if stored.Name == name {
+			if err := validateStoredMetadata(stored); err != nil {
+				return err
+			}
+			return stored.Manager("").Deploy()
+		}
+	}
+	return fmt.Errorf("证书目录中没有 %s", name)
+}
+
+// validateStoredMetadata 校验证书目录中记录的域名和站点选项，
+// 防止被改写的文件向 root 写入的站点配置注入指令
+func validateStoredMetadata(stored StoredCert) error {
+	for _, domain := range stored.Domains {
+		if len(domain) > 253 || !storedDomainPattern.MatchString(domain) {
+			return fmt.Errorf("证书目录 %s 的 domains.txt 包含无效域名 %q", stored.Name, domain)
+		}
+	}
+
+	for _, line := range readLines(filepath.Join(stored.Dir, siteOptionsFile)) {
+		switch line {
+		case siteOptionNoRedirect, siteOptionStagingDeploy, siteOptionExpectMultiHost:
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, siteOptionPreferredChain+"="); ok && storedChainPattern.MatchString(value) {
+			continue
+		}
+		return fmt.Errorf("证书目录 %s 的 %s 包含无效选项 %q", stored.Name, siteOptionsFile, line)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package cert
+
+import (
+	"autocert/internal/clock"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FetchLiveCertificate 通过 TLS 连接获取服务器实际提供的证书（不校验证书链）
+// host 为连接地址，serverName 为 SNI 域名
+func FetchLiveCertificate(host string, port int, serverName string, timeout time.Duration) (*x509.Certificate, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true, // 需要获取过期或自签名的证书进行比较
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TLS 连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("服务器没有返回证书")
+	}
+	return certs[0], nil
+}
+
+// ConfigReference Web 服务器配置中引用的证书文件
+type ConfigReference struct {
+	Config   string // 配置文件（部署记录，如 nginx:/etc/nginx/sites-available/example.com）
+	CertFile string // 配置的证书文件
+	Local    bool   // 是否引用本证书目录中的文件
+	Serial   string // 该证书文件的序列号，无法读取时为空
+}
+
+// CertDiff 线上证书与本地证书的比较结果
+type CertDiff struct {
+	Live       *x509.Certificate
+	Local      *x509.Certificate // 与线上证书最接近的本地证书（双证书模式下按密钥类型选择）
+	LocalFile  string
+	Mismatches []string
+	References []ConfigReference
+}
+
+// Match 线上证书是否与本地证书一致
+func (d *CertDiff) Match() bool {
+	return len(d.Mismatches) == 0
+}
+
+// Diff 比较线上证书与证书目录中的证书，并检查 Web 服务器配置引用的证书文件
+func (s StoredCert) Diff(live *x509.Certificate) (*CertDiff, error) {
+	diff := &CertDiff{Live: live}
+
+	// 双证书模式下服务器按客户端能力选择 RSA 或 ECDSA 证书
+	for _, name := range []string{"cert.pem", "cert-ecdsa.pem"} {
+		path := filepath.Join(s.Dir, name)
+		local, err := loadCertificate(path)
+		if err != nil {
+			continue
+		}
+		if diff.Local == nil || local.PublicKeyAlgorithm == live.PublicKeyAlgorithm {
+			diff.Local, diff.LocalFile = local, path
+		}
+		if local.SerialNumber.Cmp(live.SerialNumber) == 0 {
+			break
+		}
+	}
+	if diff.Local == nil {
+		return nil, fmt.Errorf("证书目录中没有可读取的证书: %s", s.Dir)
+	}
+
+	local := diff.Local
+	if local.SerialNumber.Cmp(live.SerialNumber) != 0 {
+		diff.Mismatches = append(diff.Mismatches, fmt.Sprintf("序列号不同: 本地 %X，线上 %X", local.SerialNumber, live.SerialNumber))
+	}
+	if a, b := sortedNames(local), sortedNames(live); a != b {
+		diff.Mismatches = append(diff.Mismatches, fmt.Sprintf("域名不同: 本地 %s，线上 %s", a, b))
+	}
+	if !local.NotAfter.Equal(live.NotAfter) {
+		diff.Mismatches = append(diff.Mismatches, fmt.Sprintf("到期时间不同: 本地 %s，线上 %s",
+			clock.Format(local.NotAfter), clock.Format(live.NotAfter)))
+	}
+
+	diff.References = s.configReferences()
+	return diff, nil
+}
+
+// sortedNames 证书的 SAN 列表（排序后以逗号连接）
+func sortedNames(cert *x509.Certificate) string {
+	names := append([]string(nil), cert.DNSNames...)
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// certDirectivePattern Nginx ssl_certificate 和 Apache SSLCertificateFile 指令
+var certDirectivePattern = regexp.MustCompile(`(?m)^\s*(?:ssl_certificate|SSLCertificateFile)\s+"?([^";\s]+)"?`)
+
+// configReferences 读取部署记录中的 Web 服务器配置，找出其中引用的证书文件
+func (s StoredCert) configReferences() []ConfigReference {
+	var refs []ConfigReference
+	for _, deployment := range readLines(filepath.Join(s.Dir, deploymentsFile)) {
+		_, path, ok := strings.Cut(deployment, ":")
+		if !ok {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			refs = append(refs, ConfigReference{Config: deployment})
+			continue
+		}
+
+		for _, match := range certDirectivePattern.FindAllStringSubmatch(string(data), -1) {
+			ref := ConfigReference{Config: deployment, CertFile: match[1], Local: s.ownsFile(match[1])}
+			if cert, err := loadCertificate(match[1]); err == nil {
+				ref.Serial = fmt.Sprintf("%X", cert.SerialNumber)
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// ownsFile 文件是否位于本证书目录中（解析符号链接后比较）
+func (s StoredCert) ownsFile(path string) bool {
+	for _, name := range []string{"cert.pem", "cert-ecdsa.pem"} {
+		if sameFile(path, filepath.Join(s.Dir, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFile 两个路径是否指向同一个文件
+func sameFile(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(infoA, infoB)
+}
@@ -0,0 +1,133 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StaleCert 可清理的证书目录
+type StaleCert struct {
+	Name        string
+	Dir         string
+	Reason      string
+	Bytes       int64
+	Deployments []string // 仍引用该证书的 Web 服务器配置
+	Links       []string // 仍链接到该证书的目录（autocert link）
+}
+
+// InUse 证书是否仍被 Web 服务器配置或其他服务引用
+func (s StaleCert) InUse() bool {
+	return len(s.Deployments) > 0 || len(s.Links) > 0
+}
+
+// FindStaleCertificates 查找可清理的证书目录：
+// 过期超过 olderThan 的证书、配置 domains 中已移除的证书（超过 olderThan 未更新），
+// 以及没有证书文件的残留目录
+func FindStaleCertificates(certRoot string, olderThan time.Duration) ([]StaleCert, error) {
+	entries, err := os.ReadDir(certRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// 删除目录不可恢复，使用真实时间，--fake-now 不会让未过期的证书被清理
+	now := time.Now()
+	cutoff := now.Add(-olderThan)
+	managed := managedDomains()
+
+	var stale []StaleCert
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		dir := filepath.Join(certRoot, entry.Name())
+		reason := staleReason(dir, entry.Name(), now, cutoff, managed)
+		if reason == "" {
+			continue
+		}
+
+		stale = append(stale, StaleCert{
+			Name:        entry.Name(),
+			Dir:         dir,
+			Reason:      reason,
+			Bytes:       certDirSize(dir),
+			Deployments: readLines(filepath.Join(dir, deploymentsFile)),
+			Links:       readLines(filepath.Join(dir, linksFile)),
+		})
+	}
+
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Name < stale[j].Name })
+	return stale, nil
+}
+
+// staleReason 返回证书目录可被清理的原因，不可清理时返回空
+func staleReason(dir, name string, now, cutoff time.Time, managed map[string]bool) string {
+	certPath := filepath.Join(dir, "cert.pem")
+	info, err := os.Stat(certPath)
+	if err != nil {
+		// 没有证书文件的目录（申请中断或证书库版本已丢失）
+		if dirInfo, err := os.Stat(dir); err == nil && dirInfo.ModTime().Before(cutoff) {
+			return "目录中没有有效的证书文件"
+		}
+		return ""
+	}
+
+	cert, err := loadCertificate(certPath)
+	if err != nil {
+		return ""
+	}
+
+	if cert.NotAfter.Before(cutoff) {
+		days := int(now.Sub(cert.NotAfter).Hours() / 24)
+		return fmt.Sprintf("证书已过期 %d 天", days)
+	}
+
+	if len(managed) > 0 && info.ModTime().Before(cutoff) {
+		for _, domain := range readDomainsList(dir, name) {
+			if managed[domain] {
+				return ""
+			}
+		}
+		return "配置 domains 中已不包含该证书"
+	}
+
+	return ""
+}
+
+// managedDomains 返回配置 domains 中列出的主域名
+func managedDomains() map[string]bool {
+	managed := make(map[string]bool)
+	current := config.Current()
+	if current == nil {
+		return managed
+	}
+	for _, domainConfig := range current.Domains {
+		managed[domainConfig.Domain] = true
+	}
+	return managed
+}
+
+// certDirSize 计算证书目录中普通文件占用的空间（证书库中的版本由 CollectStore 统计）
+func certDirSize(dir string) int64 {
+	var size int64
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+	}
+	return size
+}
@@ -0,0 +1,81 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/deploy"
+	"autocert/internal/hooks"
+	"autocert/internal/webserver"
+	"fmt"
+	"path/filepath"
+)
+
+// runDeployHooks 证书安装完成后先部署到主域名配置的部署目标，再按依赖关系执行部署钩子
+func runDeployHooks(domains []string, dir string) error {
+	if targets := config.GetDeployTargets(domains[0]); len(targets) > 0 {
+		if _, err := deploy.Run(targets, deployCertificate(domains, dir)); err != nil {
+			return err
+		}
+	}
+
+	deployHooks := config.GetHooks(domains[0])
+	if len(deployHooks) == 0 {
+		return nil
+	}
+
+	if _, err := hooks.Run(deployHooks, hookEnv(domains, dir)); err != nil {
+		return fmt.Errorf("执行部署钩子失败: %w", err)
+	}
+	return nil
+}
+
+// RunHooks 对已保存的证书手动执行部署钩子
+func (s StoredCert) RunHooks() ([]hooks.Result, error) {
+	return hooks.Run(config.GetHooks(s.Domains[0]), hookEnv(s.Domains, s.Dir))
+}
+
+// Deploy 将已保存的证书手动部署到配置的部署目标，name 不为空时只部署到该目标
+func (s StoredCert) Deploy(name string) ([]deploy.Result, error) {
+	targets := config.GetDeployTargets(s.Domains[0])
+	if name != "" {
+		var selected []config.DeployConfig
+		for _, target := range targets {
+			if deploy.TargetName(target) == name {
+				selected = append(selected, target)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("域名 %s 没有名为 %s 的部署目标", s.Domains[0], name)
+		}
+		targets = selected
+	}
+	return deploy.Run(targets, deployCertificate(s.Domains, s.Dir))
+}
+
+// deployCertificate 证书目录中要部署的证书
+func deployCertificate(domains []string, dir string) deploy.Certificate {
+	return deploy.Certificate{
+		Domains:   domains,
+		CertPath:  filepath.Join(dir, "cert.pem"),
+		KeyPath:   filepath.Join(dir, "key.pem"),
+		ChainPath: filepath.Join(dir, "chain.pem"),
+	}
+}
+
+// hookEnv 钩子命令的执行环境，使用与 Web 服务器测试/重载命令相同的工作目录和环境变量
+func hookEnv(domains []string, dir string) hooks.Env {
+	commands := webserver.CommandsFor(domains[0])
+	pfxPath := ""
+	if config.GetPFXConfig().Enabled {
+		pfxPath = filepath.Join(dir, pfxFile)
+	}
+	return hooks.Env{
+		Domains:   domains,
+		CertDir:   dir,
+		CertPath:  filepath.Join(dir, "cert.pem"),
+		KeyPath:   filepath.Join(dir, "key.pem"),
+		ChainPath: filepath.Join(dir, "chain.pem"),
+		PFXPath:   pfxPath,
+		WorkDir:   commands.WorkDir,
+		Extra:     commands.Env,
+	}
+}
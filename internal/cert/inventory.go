@@ -0,0 +1,270 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// deploymentsFile 证书目录中记录部署位置的文件
+const deploymentsFile = "deployments.txt"
+
+// Renewable 可续期的证书管理器（单域名或多域名）
+type Renewable interface {
+	Install() error
+	Deploy() error
+	NeedsRenewal() (bool, *CertInfo, error)
+	GetCertInfo() (*CertInfo, error)
+	SetRenewBeforeDays(days int)
+	SetPreferredChain(name string)
+	SetOverwriteDrift(overwrite bool)
+	SetInterrupt(check func() error)
+}
+
+// StoredCert 证书目录中已保存的证书
+type StoredCert struct {
+	Name    string   // 证书目录名
+	Dir     string   // 证书目录路径
+	Domains []string // 证书包含的域名
+}
+
+// IsStaged 站点配置是否为暂存部署，尚未通过 autocert activate 启用
+func (s StoredCert) IsStaged() bool {
+	return hasSiteOption(s.Dir, siteOptionStagingDeploy)
+}
+
+// ClearStaged 站点配置已启用，之后续期重新生成时直接写入并启用
+func (s StoredCert) ClearStaged() error {
+	return setSiteOption(s.Dir, siteOptionStagingDeploy, false)
+}
+
+// IsSAN 是否为多域名证书
+func (s StoredCert) IsSAN() bool {
+	return strings.HasSuffix(s.Name, "_san")
+}
+
+// Manager 为已保存的证书创建对应的证书管理器
+func (s StoredCert) Manager(email string) Renewable {
+	if s.IsSAN() && len(s.Domains) > 1 {
+		return NewMultiDomainManager(s.Domains, email)
+	}
+	return NewManager(s.Domains[0], email)
+}
+
+// ListCertificates 列出证书目录下所有已保存的证书
+func ListCertificates(certDir string) ([]StoredCert, error) {
+	entries, err := os.ReadDir(certDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var certs []StoredCert
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(certDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "cert.pem")); err != nil {
+			continue
+		}
+
+		certs = append(certs, StoredCert{
+			Name:    entry.Name(),
+			Dir:     dir,
+			Domains: readDomainsList(dir, entry.Name()),
+		})
+	}
+
+	sort.Slice(certs, func(i, j int) bool { return certs[i].Name < certs[j].Name })
+	return certs, nil
+}
+
+// readDomainsList 读取证书目录中的 domains.txt，不存在时根据目录名推断
+func readDomainsList(dir, name string) []string {
+	if domains := readLines(filepath.Join(dir, "domains.txt")); len(domains) > 0 {
+		return domains
+	}
+
+	return []string{strings.TrimSuffix(name, "_san")}
+}
+
+// CertDetails 证书详细信息，用于清单导出
+type CertDetails struct {
+	Name        string
+	Domains     []string // 证书 SAN 列表
+	CommonName  string
+	Issuer      string
+	Serial      string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	KeyType     string
+	Deployments []string // 部署位置，例如 nginx:/etc/nginx/sites-available/example.com
+}
+
+// Details 读取证书文件，返回证书详细信息
+func (s StoredCert) Details() (*CertDetails, error) {
+	cert, err := loadCertificate(filepath.Join(s.Dir, "cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	domains := cert.DNSNames
+	if len(domains) == 0 {
+		domains = s.Domains
+	}
+
+	keyType := describePublicKey(cert)
+	if ecdsaCert, err := loadCertificate(filepath.Join(s.Dir, "cert-ecdsa.pem")); err == nil {
+		keyType += "+" + describePublicKey(ecdsaCert)
+	}
+
+	return &CertDetails{
+		Name:        s.Name,
+		Domains:     domains,
+		CommonName:  cert.Subject.CommonName,
+		Issuer:      cert.Issuer.String(),
+		Serial:      fmt.Sprintf("%X", cert.SerialNumber),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		KeyType:     keyType,
+		Deployments: readLines(filepath.Join(s.Dir, deploymentsFile)),
+	}, nil
+}
+
+// describePublicKey 描述证书公钥类型，例如 RSA-2048、ECDSA-P256
+func describePublicKey(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA-%d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + strings.ReplaceAll(key.Curve.Params().Name, "-", "")
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// recordDeployment 记录证书部署位置（重复的位置只记录一次）
+func recordDeployment(dir, target string) error {
+	return recordLine(filepath.Join(dir, deploymentsFile), target)
+}
+
+// siteOptionsFile 证书目录中记录 install 指定的选项的文件（每行一个，带值的选项为 名称=值），
+// 续期签发证书和重新生成站点配置时沿用
+const siteOptionsFile = "site-options.txt"
+
+// siteOptionNoRedirect HTTP 不重定向到 HTTPS（install --no-redirect）
+const siteOptionNoRedirect = "no-redirect"
+
+// siteOptionStagingDeploy 站点配置暂存部署（install --staging-deploy），autocert activate 启用后移除
+const siteOptionStagingDeploy = "staging-deploy"
+
+// siteOptionExpectMultiHost 已确认域名解析到的多台主机都能响应 http-01 挑战（install --expect-multi-host）
+const siteOptionExpectMultiHost = "expect-multi-host"
+
+// siteOptionPreferredChain 首选证书链的根证书名称（install --preferred-chain）
+const siteOptionPreferredChain = "preferred-chain"
+
+// hasSiteOption 证书目录是否记录了站点配置选项
+func hasSiteOption(dir, option string) bool {
+	for _, line := range readLines(filepath.Join(dir, siteOptionsFile)) {
+		if line == option {
+			return true
+		}
+	}
+	return false
+}
+
+// siteOptionValue 读取证书目录中记录的带值选项，没有记录时返回空字符串
+func siteOptionValue(dir, option string) string {
+	for _, line := range readLines(filepath.Join(dir, siteOptionsFile)) {
+		if value, ok := strings.CutPrefix(line, option+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// setSiteOptionValue 在证书目录中记录带值选项（替换之前记录的值），value 为空时移除
+func setSiteOptionValue(dir, option, value string) error {
+	if err := removeSiteOption(dir, func(line string) bool { return strings.HasPrefix(line, option+"=") }); err != nil {
+		return err
+	}
+	if value == "" {
+		return nil
+	}
+	return recordLine(filepath.Join(dir, siteOptionsFile), option+"="+value)
+}
+
+// setSiteOption 在证书目录中记录或移除站点配置选项
+func setSiteOption(dir, option string, enabled bool) error {
+	if enabled {
+		return recordLine(filepath.Join(dir, siteOptionsFile), option)
+	}
+	return removeSiteOption(dir, func(line string) bool { return line == option })
+}
+
+// removeSiteOption 删除匹配的选项行，没有剩余选项时删除文件
+func removeSiteOption(dir string, match func(line string) bool) error {
+	path := filepath.Join(dir, siteOptionsFile)
+	var lines []string
+	for _, line := range readLines(path) {
+		if !match(line) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// recordLine 向文件追加一行（已存在的行不重复记录）
+func recordLine(path, line string) error {
+	lines := readLines(path)
+	for _, l := range lines {
+		if l == line {
+			return nil
+		}
+	}
+
+	lines = append(lines, line)
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// readLines 读取文件中的非空行，文件不存在时返回空
+func readLines(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// fileExists 判断文件是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
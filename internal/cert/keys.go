@@ -0,0 +1,234 @@
+package cert
+
+import (
+	"autocert/internal/keypool"
+	"autocert/internal/secmem"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 密钥类型
+const (
+	KeyTypeRSA   = "rsa"
+	KeyTypeECDSA = "ecdsa"
+)
+
+// generatePrivateKey 按密钥类型生成私钥，守护进程或批量签发预生成了私钥时直接取用
+func generatePrivateKey(keyType string, keySize int) (crypto.Signer, error) {
+	return keypool.Generate(keyType, keySize)
+}
+
+// writePrivateKey 以 PEM 格式保存私钥（权限 0600），DER 和 PEM 编码的中间数据写入后清零
+func writePrivateKey(keyPath string, key crypto.Signer) error {
+	var block *pem.Block
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+		}
+	case *ecdsa.PrivateKey:
+		keyBytes, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return err
+		}
+		block = &pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: keyBytes,
+		}
+	default:
+		return fmt.Errorf("不支持的私钥类型: %T", key)
+	}
+	defer secmem.Wipe(block.Bytes)
+
+	data := pem.EncodeToMemory(block)
+	defer secmem.Wipe(data)
+	return writeFileAtomic(keyPath, data, 0600)
+}
+
+// createCSR 创建证书签名请求
+func createCSR(commonName string, dnsNames []string, key crypto.Signer) ([]byte, error) {
+	template := x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName: commonName,
+		},
+		DNSNames: dnsNames,
+	}
+
+	return x509.CreateCertificateRequest(rand.Reader, &template, key)
+}
+
+// encodeCertificate 将 DER 证书编码为 PEM 格式
+func encodeCertificate(certBytes []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: certBytes,
+	})
+}
+
+// writeCertificateChain 保存 PEM 证书链（叶子证书在前）：certPath 始终包含整条证书链；
+// chainPath 不为空时中间证书另外写入 chainPath，没有中间证书时删除之前留下的 chainPath
+func writeCertificateChain(certPath, chainPath string, chain []byte) error {
+	block, rest := pem.Decode(chain)
+	if block == nil {
+		return fmt.Errorf("证书链不是有效的 PEM 数据")
+	}
+	if err := writeFileAtomic(certPath, chain, 0644); err != nil {
+		return err
+	}
+	if chainPath == "" {
+		return nil
+	}
+
+	if intermediates := bytes.TrimLeft(rest, "\r\n"); len(intermediates) > 0 {
+		return writeFileAtomic(chainPath, intermediates, 0644)
+	}
+	if err := os.Remove(chainPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除旧的 %s 失败: %w", chainPath, err)
+	}
+	return nil
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并同步到磁盘，成功后再替换目标文件，
+// 磁盘写满等写入失败时保留原文件，不会留下空的或截断的证书和私钥。
+// 目标为指向证书库的符号链接时替换链接本身，不修改证书库中的旧版本
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath, err := writeTempFile(path, data, perm)
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// writeTempFile 在 path 同目录下写入临时文件并同步到磁盘，返回临时文件路径，由调用方重命名或删除
+func writeTempFile(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmpPath := tmp.Name()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	return tmpPath, err
+}
+
+// loadCertificate 读取并解析 PEM 格式证书文件中的第一张证书
+func loadCertificate(certPath string) (*x509.Certificate, error) {
+	// 检查证书文件是否存在
+	if _, err := os.Stat(certPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("证书文件不存在: %s", certPath)
+	}
+
+	// 读取证书文件
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书文件失败: %w", err)
+	}
+
+	// 解析证书
+	block, _ := pem.Decode(certData)
+	if block == nil {
+		return nil, fmt.Errorf("无法解析证书文件")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书失败: %w", err)
+	}
+
+	return cert, nil
+}
+
+// loadPrivateKey 读取并解析 PEM 格式私钥（PKCS#1、SEC 1 或 PKCS#8），文件内容读入锁定的内存，解析后清零
+func loadPrivateKey(keyPath string) (crypto.Signer, error) {
+	keyData, err := secmem.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取私钥文件失败: %w", err)
+	}
+	defer keyData.Destroy()
+
+	block, _ := pem.Decode(keyData.Bytes())
+	if block == nil {
+		return nil, fmt.Errorf("无法解析私钥文件")
+	}
+	defer secmem.Wipe(block.Bytes)
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %w", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("不支持的私钥类型: %T", key)
+		}
+		return signer, nil
+	}
+}
+
+// signDemoCertificate 使用临时签发密钥为 CSR 签发证书（仅用于演示）
+func signDemoCertificate(csr []byte, validity int) ([]byte, error) {
+	csrParsed, err := x509.ParseCertificateRequest(csr)
+	if err != nil {
+		return nil, err
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := csrParsed.PublicKey.(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      csrParsed.Subject,
+		DNSNames:     csrParsed.DNSNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().AddDate(0, 0, validity),
+		KeyUsage:     keyUsage,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	// 生成签发密钥（用于签名）
+	signer, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	// 证书公钥来自 CSR，保证与本地私钥匹配
+	return x509.CreateCertificate(rand.Reader, &template, &template, csrParsed.PublicKey, signer)
+}
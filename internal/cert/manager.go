@@ -1,16 +1,21 @@
 package cert
 
 import (
+	"autocert/internal/clock"
 	"autocert/internal/config"
+	"autocert/internal/events"
+	"autocert/internal/features"
 	"autocert/internal/logger"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
+	"autocert/internal/policy"
+	"autocert/internal/preflight"
+	"autocert/internal/progress"
+	"autocert/internal/stats"
+	"autocert/internal/system"
+	"autocert/internal/webserver"
+	"crypto"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -23,6 +28,20 @@ const (
 	ChallengeDNS
 )
 
+// String 返回挑战类型对应的 ACME 名称
+func (c ChallengeType) String() string {
+	switch c {
+	case ChallengeWebroot:
+		return "http-01(webroot)"
+	case ChallengeStandalone:
+		return "http-01(standalone)"
+	case ChallengeDNS:
+		return "dns-01"
+	default:
+		return "unknown"
+	}
+}
+
 // WebServerType Web 服务器类型
 type WebServerType int
 
@@ -32,6 +51,20 @@ const (
 	WebServerIIS
 )
 
+// ParseWebServerType 将配置中的 Web 服务器名称转换为 WebServerType
+func ParseWebServerType(name string) (WebServerType, error) {
+	switch strings.ToLower(name) {
+	case "nginx":
+		return WebServerNginx, nil
+	case "apache":
+		return WebServerApache, nil
+	case "iis":
+		return WebServerIIS, nil
+	default:
+		return WebServerNginx, fmt.Errorf("不支持的 Web 服务器类型: %s", name)
+	}
+}
+
 // Manager 证书管理器
 type Manager struct {
 	domain        string
@@ -40,7 +73,20 @@ type Manager struct {
 	webrootPath   string
 	webServerType WebServerType
 	certDir       string
+	keyType       string
 	keySize       int
+	dualCert      bool
+	multiHost     bool         // 已确认域名解析到的多台主机都能响应 http-01 挑战
+	multiHostSet  bool         // install 指定了 --expect-multi-host，签发后记录到证书目录
+	issueOnly     bool         // 只签发证书并按命名方式输出
+	renewBefore   int          // 续期天数
+	preferredRoot string       // 首选证书链的根证书名称
+	keepRoot      bool         // install 指定了 --preferred-chain，签发后记录到证书目录
+	overwrite     bool         // 覆盖手工修改的站点配置
+	noRedirect    bool         // HTTP 不重定向到 HTTPS
+	noRedirectSet bool         // install 指定了 --no-redirect，配置站点时记录到证书目录
+	staging       bool         // 暂存部署站点配置，不启用
+	interrupt     func() error // 返回错误时停止签发（例如分布式锁已丢失）
 }
 
 // CertInfo 证书信息
@@ -49,19 +95,41 @@ type CertInfo struct {
 	CertPath   string
 	KeyPath    string
 	ChainPath  string
+	IssuedDate time.Time
 	ExpiryDate time.Time
 	IsValid    bool
 }
 
 // NewManager 创建新的证书管理器
 func NewManager(domain, email string) *Manager {
-	return &Manager{
+	acmeConfig := config.GetACMEConfig()
+
+	// 默认使用配置的 Web 服务器类型（续期已有证书时也能部署到正确的服务器）
+	webServerType, _ := ParseWebServerType(config.GetWebServerConfig().Type)
+
+	m := &Manager{
 		domain:        domain,
 		email:         email,
 		challengeType: ChallengeWebroot,
+		webServerType: webServerType,
 		certDir:       config.GetCertDir(),
-		keySize:       2048,
+		keyType:       acmeConfig.KeyType,
+		keySize:       acmeConfig.KeySize,
+		dualCert:      acmeConfig.DualCert && features.Gate(features.DualCert, "acme.dual_cert"),
+		renewBefore:   config.GetRenewBeforeDays(domain),
+		preferredRoot: config.GetPreferredChain(domain),
+	}
+	// 之前 install --preferred-chain 记录的首选证书链优先于配置
+	if name := siteOptionValue(filepath.Join(m.certDir, m.domain), siteOptionPreferredChain); name != "" {
+		m.preferredRoot = name
 	}
+	// 域名配置 no_redirect 或之前 install --no-redirect 记录的选项
+	m.noRedirect = config.GetNoRedirect(domain) || hasSiteOption(filepath.Join(m.certDir, m.domain), siteOptionNoRedirect)
+	// 之前 install --expect-multi-host 记录的确认
+	m.multiHost = hasSiteOption(filepath.Join(m.certDir, m.domain), siteOptionExpectMultiHost)
+	// 之前 install --staging-deploy 暂存、尚未启用的站点配置保持暂存
+	m.staging = hasSiteOption(filepath.Join(m.certDir, m.domain), siteOptionStagingDeploy)
+	return m
 }
 
 // SetChallengeType 设置挑战类型
@@ -79,45 +147,239 @@ func (m *Manager) SetWebServer(webServerType WebServerType) {
 	m.webServerType = webServerType
 }
 
+// SetDualCert 设置是否同时签发 RSA 和 ECDSA 证书
+func (m *Manager) SetDualCert(dualCert bool) {
+	m.dualCert = dualCert
+}
+
+// SetExpectMultiHost 确认域名解析到的多台主机都能响应 http-01 挑战，预检不再警告；签发后记录到证书目录，续期时沿用
+func (m *Manager) SetExpectMultiHost(expect bool) {
+	m.multiHost = expect
+	m.multiHostSet = true
+}
+
+// SetIssueOnly 只签发证书并按命名方式输出（ci 命令），不发布 TLSA 记录、不保存到证书库和存储后端、
+// 不配置 Web 服务器、不执行部署
+func (m *Manager) SetIssueOnly(issueOnly bool) {
+	m.issueOnly = issueOnly
+}
+
+// SetRenewBeforeDays 设置续期天数（剩余有效期少于该天数时续期）
+func (m *Manager) SetRenewBeforeDays(days int) {
+	m.renewBefore = days
+}
+
+// SetPreferredChain 设置首选证书链：CA 提供多条证书链时选择根证书为该名称的证书链
+func (m *Manager) SetPreferredChain(name string) {
+	m.preferredRoot = name
+}
+
+// KeepPreferredChain 设置首选证书链，签发成功后记录到证书目录，之后续期时沿用（install --preferred-chain）
+func (m *Manager) KeepPreferredChain(name string) {
+	m.preferredRoot = name
+	m.keepRoot = true
+}
+
+// SetOverwriteDrift 站点配置在上次生成后被手工修改时仍然覆盖（默认拒绝覆盖）
+func (m *Manager) SetOverwriteDrift(overwrite bool) {
+	m.overwrite = overwrite
+}
+
+// SetInterrupt 设置签发过程中的检查：申请证书、写入存储后端和部署前调用，返回错误时停止
+func (m *Manager) SetInterrupt(check func() error) {
+	m.interrupt = check
+}
+
+// SetNoRedirect 生成的站点配置中 HTTP 不重定向到 HTTPS，80 端口继续提供站点（Nginx、Apache）。
+// 配置站点时记录到证书目录，续期重新生成站点配置时沿用
+func (m *Manager) SetNoRedirect(noRedirect bool) {
+	m.noRedirect = noRedirect
+	m.noRedirectSet = true
+}
+
+// SetStagingDeploy 暂存部署：站点配置写入 <站点配置>.disabled 且不启用（Nginx、Apache），
+// 记录在证书目录中，之后续期重新生成时保持暂存，直到 autocert activate 启用
+func (m *Manager) SetStagingDeploy(staging bool) {
+	m.staging = staging
+}
+
 // Install 安装证书
 func (m *Manager) Install() error {
+	renewal := fileExists(m.getCertPath())
+	start := time.Now()
+	err := m.install()
+	stats.Record(stats.CertOperation(renewal), time.Since(start), err)
+	events.Emit(events.CertResult([]string{m.domain}, renewal, err))
+	return err
+}
+
+// install 申请证书并配置 Web 服务器
+func (m *Manager) install() error {
 	logger.Info("开始安装证书", "domain", m.domain)
 
+	// 检查签发策略（允许的域名和 ACME 服务器）
+	if err := policy.CheckIssuance("certificate.issue", []string{m.domain}, config.GetACMEConfig().Server); err != nil {
+		return err
+	}
+
+	// 经过 CDN 代理的域名改用 DNS 验证
+	if m.challengeType != ChallengeDNS && cdnFallback([]string{m.domain})[m.domain] {
+		m.challengeType = ChallengeDNS
+	}
+
+	// 签发前检查运行环境（例如系统时间）
+	if err := preflight.Issuance([]string{m.domain}, preflight.Options{HTTP01: m.challengeType != ChallengeDNS, ExpectMultiHost: m.multiHost}); err != nil {
+		return fmt.Errorf("签发前检查未通过: %w", err)
+	}
+
 	// 1. 创建证书目录
 	if err := m.createCertDir(); err != nil {
 		return fmt.Errorf("创建证书目录失败: %w", err)
 	}
+	if err := system.CheckDiskSpace(m.certDir, config.GetStorageConfig().MinFreeBytes()); err != nil {
+		return err
+	}
+
+	// 启用 DANE 时签发前发布新私钥的 TLSA 记录，发布失败不签发；只签发时由部署证书的一方发布
+	keyType := m.keyType
+	if m.dualCert {
+		keyType = KeyTypeRSA
+	}
+	keys := []daneKey{{path: m.getKeyPath(), keyType: keyType, keySize: m.keySize}}
+	if m.dualCert {
+		keys = append(keys, daneKey{path: m.getECDSAKeyPath(), keyType: KeyTypeECDSA})
+	}
+	var dane *daneSession
+	if !m.issueOnly {
+		var err error
+		dane, err = newDANESession([]string{m.domain}, filepath.Join(m.certDir, m.domain), keys)
+		if err != nil {
+			return fmt.Errorf("发布 TLSA 记录失败: %w", err)
+		}
+	}
+	if dane != nil {
+		if err := dane.prepare(); err != nil {
+			return fmt.Errorf("发布 TLSA 记录失败: %w", err)
+		}
+	}
+
+	// 2. 申请主证书（双证书模式下主证书固定为 RSA）
+	if err := checkInterrupt(m.interrupt); err != nil {
+		return err
+	}
+	if err := m.issue(keyType, m.getKeyPath(), m.getCertPath(), m.getChainPath()); err != nil {
+		return err
+	}
+	if m.keepRoot {
+		if err := setSiteOptionValue(filepath.Join(m.certDir, m.domain), siteOptionPreferredChain, m.preferredRoot); err != nil {
+			logger.Warn("记录首选证书链失败", "dir", filepath.Join(m.certDir, m.domain), "error", err)
+		}
+	}
 
-	// 2. 生成私钥
-	privateKey, err := m.generatePrivateKey()
+	if m.multiHostSet {
+		if err := setSiteOption(filepath.Join(m.certDir, m.domain), siteOptionExpectMultiHost, m.multiHost); err != nil {
+			logger.Warn("记录站点配置选项失败", "dir", filepath.Join(m.certDir, m.domain), "error", err)
+		}
+	}
+
+	// 3. 双证书模式下再申请一张 ECDSA 证书，与 RSA 证书并列存放
+	if m.dualCert {
+		logger.Info("申请 ECDSA 证书", "domain", m.domain)
+		if err := m.issue(KeyTypeECDSA, m.getECDSAKeyPath(), m.getECDSACertPath(), ""); err != nil {
+			return fmt.Errorf("ECDSA 证书申请失败: %w", err)
+		}
+	}
+
+	// 4. 按配置生成 PFX 证书包和按命名方式输出的证书；证书库布局下将证书移入证书库，证书目录中保留符号链接
+	if err := writePFXBundle(filepath.Join(m.certDir, m.domain), []string{m.domain}); err != nil {
+		return err
+	}
+	if err := writeOutput(filepath.Join(m.certDir, m.domain), []string{m.domain}); err != nil {
+		return err
+	}
+	if m.issueOnly {
+		logger.Info("证书已签发", "domain", m.domain)
+		return nil
+	}
+	if err := checkInterrupt(m.interrupt); err != nil {
+		return err
+	}
+	if err := storeCertificate(m.certDir, filepath.Join(m.certDir, m.domain)); err != nil {
+		return fmt.Errorf("保存到证书库失败: %w", err)
+	}
+	if err := uploadCertificate(filepath.Join(m.certDir, m.domain)); err != nil {
+		return fmt.Errorf("保存到存储后端失败（证书已保存在本地，可执行 autocert storage push 重试）: %w", err)
+	}
+
+	// 新证书已保存，预生成并发布下一次续期使用的私钥的记录
+	if dane != nil {
+		dane.rotate()
+	}
+
+	// 5. 配置 Web 服务器，按依赖关系执行部署钩子
+	if err := checkInterrupt(m.interrupt); err != nil {
+		return err
+	}
+	if err := m.Deploy(); err != nil {
+		return err
+	}
+
+	// 新证书已部署，删除旧私钥的 TLSA 记录
+	if dane != nil {
+		dane.retire()
+	}
+
+	logger.Info("证书安装完成", "domain", m.domain)
+	return nil
+}
+
+// issue 生成私钥、创建 CSR 并申请证书，保存到指定路径。证书文件包含整条证书链，chainPath 不为空时另外写入中间证书
+func (m *Manager) issue(keyType, keyPath, certPath, chainPath string) error {
+	// 生成私钥
+	spinner := progress.Start(fmt.Sprintf("生成 %s 私钥", strings.ToUpper(keyType)))
+	privateKey, err := m.generatePrivateKey(keyType, keyPath)
+	spinner.Stop(err)
 	if err != nil {
 		return fmt.Errorf("生成私钥失败: %w", err)
 	}
 
-	// 3. 创建证书签名请求
+	// 创建证书签名请求
 	csr, err := m.createCSR(privateKey)
 	if err != nil {
 		return fmt.Errorf("创建 CSR 失败: %w", err)
 	}
 
-	// 4. 通过 ACME 获取证书
-	cert, err := m.obtainCertificate(csr)
+	// 通过 ACME 获取证书
+	spinner = progress.Start("申请证书 " + m.domain)
+	chains, err := m.obtainCertificate(csr)
+	spinner.Stop(err)
+	if err != nil {
+		return fmt.Errorf("获取证书失败: %w", err)
+	}
+	chain, err := selectPreferredChain(m.domain, m.preferredRoot, chains)
 	if err != nil {
 		return fmt.Errorf("获取证书失败: %w", err)
 	}
 
-	// 5. 保存证书和私钥
-	if err := m.saveCertificate(cert, privateKey); err != nil {
+	// 保存证书
+	if err := m.saveCertificate(chain, certPath, chainPath); err != nil {
 		return fmt.Errorf("保存证书失败: %w", err)
 	}
 
-	// 6. 配置 Web 服务器
+	return nil
+}
+
+// Deploy 使用证书目录中已有的证书配置 Web 服务器并执行部署钩子，不申请证书
+// 用于集群中由其他节点续期、本节点从共享存储取回证书后重新部署
+// 守护进程降权运行时交给 root 的部署助手执行
+func (m *Manager) Deploy() error {
+	if deployHandler != nil {
+		return deployHandler(m.domain)
+	}
 	if err := m.configureWebServer(); err != nil {
 		return fmt.Errorf("配置 Web 服务器失败: %w", err)
 	}
-
-	logger.Info("证书安装完成", "domain", m.domain)
-	return nil
+	return runDeployHooks([]string{m.domain}, filepath.Join(m.certDir, m.domain))
 }
 
 // Renew 续期证书
@@ -125,14 +387,11 @@ func (m *Manager) Renew() error {
 	logger.Info("开始续期证书", "domain", m.domain)
 
 	// 检查证书是否需要续期
-	certInfo, err := m.GetCertInfo()
+	needsRenewal, _, err := m.NeedsRenewal()
 	if err != nil {
-		return fmt.Errorf("获取证书信息失败: %w", err)
+		return err
 	}
-
-	// 如果证书有效期超过 30 天，则不需要续期
-	if time.Until(certInfo.ExpiryDate) > 30*24*time.Hour {
-		logger.Info("证书还未到续期时间", "domain", m.domain, "expiry", certInfo.ExpiryDate)
+	if !needsRenewal {
 		return nil
 	}
 
@@ -140,30 +399,30 @@ func (m *Manager) Renew() error {
 	return m.Install()
 }
 
-// GetCertInfo 获取证书信息
-func (m *Manager) GetCertInfo() (*CertInfo, error) {
-	certPath := m.getCertPath()
-
-	// 检查证书文件是否存在
-	if _, err := os.Stat(certPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("证书文件不存在: %s", certPath)
-	}
-
-	// 读取证书文件
-	certData, err := os.ReadFile(certPath)
+// NeedsRenewal 检查证书是否已到续期时间
+func (m *Manager) NeedsRenewal() (bool, *CertInfo, error) {
+	certInfo, err := m.GetCertInfo()
 	if err != nil {
-		return nil, fmt.Errorf("读取证书文件失败: %w", err)
+		return false, nil, fmt.Errorf("获取证书信息失败: %w", err)
 	}
 
-	// 解析证书
-	block, _ := pem.Decode(certData)
-	if block == nil {
-		return nil, fmt.Errorf("无法解析证书文件")
+	// 剩余有效期超过续期阈值时不需要续期
+	if !certInfo.NeedsRenewal(m.renewBefore) {
+		logger.Info("证书还未到续期时间", "domain", m.domain, "expiry", certInfo.ExpiryDate,
+			"threshold", certInfo.RenewalThreshold(m.renewBefore))
+		return false, certInfo, nil
 	}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
+	return true, certInfo, nil
+}
+
+// GetCertInfo 获取证书信息
+func (m *Manager) GetCertInfo() (*CertInfo, error) {
+	certPath := m.getCertPath()
+
+	cert, err := loadCertificate(certPath)
 	if err != nil {
-		return nil, fmt.Errorf("解析证书失败: %w", err)
+		return nil, err
 	}
 
 	return &CertInfo{
@@ -171,46 +430,28 @@ func (m *Manager) GetCertInfo() (*CertInfo, error) {
 		CertPath:   certPath,
 		KeyPath:    m.getKeyPath(),
 		ChainPath:  m.getChainPath(),
+		IssuedDate: cert.NotBefore,
 		ExpiryDate: cert.NotAfter,
-		IsValid:    time.Now().Before(cert.NotAfter),
+		IsValid:    clock.Now().Before(cert.NotAfter),
 	}, nil
 }
 
 // createCertDir 创建证书目录
 func (m *Manager) createCertDir() error {
-	certDir := filepath.Join(m.certDir, m.domain)
-	return os.MkdirAll(certDir, 0755)
+	return ensureDirs(m.certDir, filepath.Join(m.certDir, m.domain))
 }
 
 // generatePrivateKey 生成私钥
-func (m *Manager) generatePrivateKey() (*rsa.PrivateKey, error) {
-	logger.Debug("生成私钥", "keySize", m.keySize)
+func (m *Manager) generatePrivateKey(keyType, keyPath string) (crypto.Signer, error) {
+	logger.Debug("生成私钥", "keyType", keyType, "keySize", m.keySize)
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, m.keySize)
+	privateKey, err := newPrivateKey(keyType, m.keySize, keyPath)
 	if err != nil {
 		return nil, err
 	}
 
 	// 保存私钥到文件
-	keyPath := m.getKeyPath()
-	keyFile, err := os.Create(keyPath)
-	if err != nil {
-		return nil, err
-	}
-	defer keyFile.Close()
-
-	keyBytes := x509.MarshalPKCS1PrivateKey(privateKey)
-	keyPEM := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: keyBytes,
-	}
-
-	if err := pem.Encode(keyFile, keyPEM); err != nil {
-		return nil, err
-	}
-
-	// 设置私钥文件权限
-	if err := os.Chmod(keyPath, 0600); err != nil {
+	if err := writePrivateKey(keyPath, privateKey); err != nil {
 		return nil, err
 	}
 
@@ -219,17 +460,10 @@ func (m *Manager) generatePrivateKey() (*rsa.PrivateKey, error) {
 }
 
 // createCSR 创建证书签名请求
-func (m *Manager) createCSR(privateKey *rsa.PrivateKey) ([]byte, error) {
+func (m *Manager) createCSR(privateKey crypto.Signer) ([]byte, error) {
 	logger.Debug("创建 CSR", "domain", m.domain)
 
-	template := x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName: m.domain,
-		},
-		DNSNames: []string{m.domain},
-	}
-
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &template, privateKey)
+	csrBytes, err := createCSR(m.domain, []string{m.domain}, privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -238,75 +472,48 @@ func (m *Manager) createCSR(privateKey *rsa.PrivateKey) ([]byte, error) {
 	return csrBytes, nil
 }
 
-// obtainCertificate 通过 ACME 获取证书
-func (m *Manager) obtainCertificate(csr []byte) ([]byte, error) {
+// obtainCertificate 通过 ACME 获取证书，返回 CA 提供的证书链（PEM，默认证书链在前，之后为备用证书链）
+func (m *Manager) obtainCertificate(csr []byte) ([][]byte, error) {
 	logger.Info("开始 ACME 证书申请流程", "domain", m.domain, "challengeType", m.challengeType)
 
+	// 演示签发流程没有证书下载 URL，无法按 rel="alternate" 的 Link 头（acme.AlternateLinks）获取备用证书链
+	if err := checkPreferredChain(m.domain, m.preferredRoot); err != nil {
+		return nil, err
+	}
+
+	var cert []byte
+	var err error
 	switch m.challengeType {
 	case ChallengeWebroot:
-		return m.obtainCertificateWebroot(csr)
+		cert, err = m.obtainCertificateWebroot(csr)
 	case ChallengeStandalone:
-		return m.obtainCertificateStandalone(csr)
+		cert, err = m.obtainCertificateStandalone(csr)
 	case ChallengeDNS:
-		return m.obtainCertificateDNS(csr)
+		cert, err = m.obtainCertificateDNS(csr)
 	default:
 		return nil, fmt.Errorf("不支持的验证模式: %d", m.challengeType)
 	}
-}
-
-// generateSelfSignedCert 生成自签名证书（仅用于演示）
-func (m *Manager) generateSelfSignedCert(csr []byte) ([]byte, error) {
-	logger.Warn("生成自签名证书（仅用于演示）", "domain", m.domain)
-
-	// 解析 CSR
-	csrParsed, err := x509.ParseCertificateRequest(csr)
 	if err != nil {
 		return nil, err
 	}
 
-	// 创建证书模板
-	template := x509.Certificate{
-		Subject:     csrParsed.Subject,
-		DNSNames:    csrParsed.DNSNames,
-		NotBefore:   time.Now(),
-		NotAfter:    time.Now().Add(90 * 24 * time.Hour), // 90 天有效期
-		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-	}
-
-	// 生成私钥（用于签名）
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, err
-	}
+	// 为了演示，这里只有自签名证书一条证书链
+	return [][]byte{encodeCertificate(cert)}, nil
+}
 
-	// 创建证书
-	certBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		return nil, err
-	}
+// generateSelfSignedCert 生成自签名证书（仅用于演示）
+func (m *Manager) generateSelfSignedCert(csr []byte) ([]byte, error) {
+	logger.Warn("生成自签名证书（仅用于演示）", "domain", m.domain)
 
-	return certBytes, nil
+	// 90 天有效期
+	return signDemoCertificate(csr, 90)
 }
 
-// saveCertificate 保存证书和私钥
-func (m *Manager) saveCertificate(certBytes []byte, privateKey *rsa.PrivateKey) error {
+// saveCertificate 保存证书链
+func (m *Manager) saveCertificate(chain []byte, certPath, chainPath string) error {
 	logger.Debug("保存证书", "domain", m.domain)
 
-	// 保存证书
-	certPath := m.getCertPath()
-	certFile, err := os.Create(certPath)
-	if err != nil {
-		return err
-	}
-	defer certFile.Close()
-
-	certPEM := &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	}
-
-	if err := pem.Encode(certFile, certPEM); err != nil {
+	if err := writeCertificateChain(certPath, chainPath, chain); err != nil {
 		return err
 	}
 
@@ -318,6 +525,18 @@ func (m *Manager) saveCertificate(certBytes []byte, privateKey *rsa.PrivateKey)
 func (m *Manager) configureWebServer() error {
 	logger.Info("配置 Web 服务器", "type", m.webServerType)
 
+	// 记录 install 指定的站点配置选项，续期时沿用
+	if m.noRedirectSet {
+		if err := setSiteOption(filepath.Join(m.certDir, m.domain), siteOptionNoRedirect, m.noRedirect); err != nil {
+			logger.Warn("记录站点配置选项失败", "domain", m.domain, "error", err)
+		}
+	}
+	if m.staging {
+		if err := setSiteOption(filepath.Join(m.certDir, m.domain), siteOptionStagingDeploy, true); err != nil {
+			logger.Warn("记录站点配置选项失败", "domain", m.domain, "error", err)
+		}
+	}
+
 	switch m.webServerType {
 	case WebServerNginx:
 		return m.configureNginx()
@@ -334,18 +553,93 @@ func (m *Manager) configureWebServer() error {
 func (m *Manager) configureNginx() error {
 	logger.Info("配置 Nginx SSL", "domain", m.domain)
 
-	// 这里应该实现真正的 Nginx 配置逻辑
-	// 包括创建虚拟主机配置、启用 SSL 等
+	configurator, err := webserver.NewConfigurator("nginx")
+	if err != nil {
+		return err
+	}
+
+	cfg := m.webServerConfig("nginx")
+	if err := configurator.Configure(cfg); err != nil {
+		return err
+	}
+
+	if err := configurator.Test(); err != nil {
+		return err
+	}
+
+	if err := configurator.Reload(); err != nil {
+		return err
+	}
+
+	events.Emit(events.Event{
+		Type:    events.ConfigChanged,
+		Domains: []string{m.domain},
+		Message: "Web 服务器配置已更新",
+		Fields:  map[string]string{"server": "nginx", "path": cfg.ConfigPath},
+	})
+
+	if err := recordDeployment(filepath.Join(m.certDir, m.domain), "nginx:"+cfg.ConfigPath); err != nil {
+		logger.Warn("记录证书部署位置失败", "domain", m.domain, "error", err)
+	}
 
 	logger.Info("Nginx 配置完成")
 	return nil
 }
 
+// webServerConfig 生成 Web 服务器配置参数
+func (m *Manager) webServerConfig(serverType string) *webserver.Config {
+	cfg := &webserver.Config{
+		Type:     serverType,
+		Domain:   m.domain,
+		CertPath: m.getCertPath(),
+		KeyPath:  m.getKeyPath(),
+		WebRoot:  m.webrootPath,
+
+		OverwriteDrift: m.overwrite,
+		NoRedirect:     m.noRedirect,
+		StagingDeploy:  m.staging,
+	}
+
+	if m.dualCert {
+		cfg.ECDSACertPath = m.getECDSACertPath()
+		cfg.ECDSAKeyPath = m.getECDSAKeyPath()
+	}
+
+	return cfg
+}
+
 // configureApache 配置 Apache
 func (m *Manager) configureApache() error {
 	logger.Info("配置 Apache SSL", "domain", m.domain)
 
-	// 这里应该实现真正的 Apache 配置逻辑
+	configurator, err := webserver.NewConfigurator("apache")
+	if err != nil {
+		return err
+	}
+
+	cfg := m.webServerConfig("apache")
+	if err := configurator.Configure(cfg); err != nil {
+		return err
+	}
+
+	if err := configurator.Test(); err != nil {
+		return err
+	}
+
+	if err := configurator.Reload(); err != nil {
+		return err
+	}
+
+	events.Emit(events.Event{
+		Type:    events.ConfigChanged,
+		Domains: []string{m.domain},
+		Message: "Web 服务器配置已更新",
+		Fields:  map[string]string{"server": "apache", "path": cfg.ConfigPath},
+	})
+
+	if err := recordDeployment(filepath.Join(m.certDir, m.domain), "apache:"+cfg.ConfigPath); err != nil {
+		logger.Warn("记录证书部署位置失败", "domain", m.domain, "error", err)
+	}
 
 	logger.Info("Apache 配置完成")
 	return nil
@@ -355,7 +649,36 @@ func (m *Manager) configureApache() error {
 func (m *Manager) configureIIS() error {
 	logger.Info("配置 IIS SSL", "domain", m.domain)
 
-	// 这里应该实现真正的 IIS 配置逻辑
+	configurator, err := webserver.NewConfigurator("iis")
+	if err != nil {
+		return err
+	}
+
+	cfg := m.webServerConfig("iis")
+	if err := configurator.Configure(cfg); err != nil {
+		return err
+	}
+
+	if err := configurator.Test(); err != nil {
+		return err
+	}
+
+	if err := configurator.Reload(); err != nil {
+		return err
+	}
+
+	if cfg.ConfigPath != "" {
+		events.Emit(events.Event{
+			Type:    events.ConfigChanged,
+			Domains: []string{m.domain},
+			Message: "Web 服务器配置已更新",
+			Fields:  map[string]string{"server": "iis", "path": cfg.ConfigPath},
+		})
+
+		if err := recordDeployment(filepath.Join(m.certDir, m.domain), "iis:"+cfg.ConfigPath); err != nil {
+			logger.Warn("记录证书部署位置失败", "domain", m.domain, "error", err)
+		}
+	}
 
 	logger.Info("IIS 配置完成")
 	return nil
@@ -374,12 +697,27 @@ func (m *Manager) getChainPath() string {
 	return filepath.Join(m.certDir, m.domain, "chain.pem")
 }
 
+func (m *Manager) getECDSACertPath() string {
+	return filepath.Join(m.certDir, m.domain, "cert-ecdsa.pem")
+}
+
+func (m *Manager) getECDSAKeyPath() string {
+	return filepath.Join(m.certDir, m.domain, "key-ecdsa.pem")
+}
+
 // obtainCertificateWebroot 使用 Webroot 模式获取证书
 func (m *Manager) obtainCertificateWebroot(csr []byte) ([]byte, error) {
 	logger.Info("使用 Webroot 模式获取证书", "domain", m.domain, "webroot", m.webrootPath)
 
+	// 准备挑战目录（所有者、权限）并清理残留的挑战文件
+	challengeDir, err := prepareChallengeDir(m.webrootPath)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("挑战目录已就绪", "dir", challengeDir)
+
 	// 这里应该实现真正的 ACME Webroot 验证逻辑
-	// 1. 在 webroot/.well-known/acme-challenge/ 目录下创建挑战文件
+	// 1. 在挑战目录下创建挑战文件（权限 0644），验证完成后删除
 	// 2. 向 Let's Encrypt 服务器发送证书申请
 	// 3. Let's Encrypt 服务器通过 HTTP 访问挑战文件进行验证
 
@@ -391,9 +729,15 @@ func (m *Manager) obtainCertificateWebroot(csr []byte) ([]byte, error) {
 func (m *Manager) obtainCertificateStandalone(csr []byte) ([]byte, error) {
 	logger.Info("使用 Standalone 模式获取证书", "domain", m.domain)
 
+	// 1. 启动临时 HTTP 服务器监听 80 端口（IPv4 和 IPv6，或使用套接字激活传入的套接字）
+	server, err := startStandaloneServer()
+	if err != nil {
+		return nil, err
+	}
+	defer server.Close()
+
 	// 这里应该实现真正的 ACME Standalone 验证逻辑
-	// 1. 启动临时 HTTP 服务器监听 80 端口
-	// 2. 向 Let's Encrypt 服务器发送证书申请
+	// 2. 向 Let's Encrypt 服务器发送证书申请，通过 server.SetToken 设置挑战响应
 	// 3. Let's Encrypt 服务器通过 HTTP 访问挑战路径进行验证
 	// 4. 验证成功后关闭临时服务器
 
@@ -408,12 +752,18 @@ func (m *Manager) obtainCertificateDNS(csr []byte) ([]byte, error) {
 	// 这里应该实现真正的 ACME DNS 验证逻辑
 	// 1. 向 Let's Encrypt 服务器发送证书申请
 	// 2. 获取 DNS 挑战记录值
-	// 3. 在 DNS 服务商中添加 TXT 记录：_acme-challenge.domain.com
+	// 3. 在 DNS 服务商中添加 TXT 记录：_acme-challenge.domain.com（未配置服务商时提示手动添加）
 	// 4. 等待 DNS 传播完成
 	// 5. 通知 Let's Encrypt 服务器进行验证
-	// 6. 验证成功后清理 DNS 记录
-
-	logger.Warn("注意：DNS 模式需要手动添加 DNS 记录或配置 DNS API", "domain", m.domain)
+	// 6. 验证结束后清理 DNS 记录（关闭 session 时删除）
+	session, err := newAuthzSession(nil, map[ChallengeType]bool{ChallengeDNS: true})
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	if err := session.solveDNS(m.domain); err != nil {
+		return nil, err
+	}
 
 	// 为了演示，这里使用自签名证书
 	return m.generateSelfSignedCert(csr)
@@ -0,0 +1,98 @@
+package cert
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveDirName 域名集合变更后旧证书的归档目录名（位于证书根目录下）
+const archiveDirName = ".archive"
+
+// MemberChanges 比较证书当前的域名和配置的域名，返回新增和移除的 SAN 成员
+func MemberChanges(current, configured []string) (added, removed []string) {
+	have := make(map[string]bool)
+	for _, domain := range current {
+		have[strings.ToLower(domain)] = true
+	}
+	want := make(map[string]bool)
+	for _, domain := range configured {
+		want[strings.ToLower(domain)] = true
+		if !have[strings.ToLower(domain)] {
+			added = append(added, domain)
+		}
+	}
+	for _, domain := range current {
+		if !want[strings.ToLower(domain)] {
+			removed = append(removed, domain)
+		}
+	}
+	return added, removed
+}
+
+// WithDomains 按新的域名集合签发后的证书（多个域名时目录带 _san 后缀）
+func (s StoredCert) WithDomains(domains []string) StoredCert {
+	name := domains[0]
+	if len(domains) > 1 {
+		name += "_san"
+	}
+	return StoredCert{
+		Name:    name,
+		Dir:     filepath.Join(filepath.Dir(s.Dir), name),
+		Domains: domains,
+	}
+}
+
+// Archive 将证书文件和记录复制到证书根目录下的 .archive/<证书名>/<时间>，返回归档目录。
+// 证书库布局下复制符号链接指向的文件内容，归档不受 autocert gc 清理证书库的影响
+func (s StoredCert) Archive() (string, error) {
+	archiveDir := filepath.Join(filepath.Dir(s.Dir), archiveDirName, s.Name, clock.Now().Format("20060102-150405"))
+	if err := ensureDirs(filepath.Dir(filepath.Dir(archiveDir)), filepath.Dir(archiveDir), archiveDir); err != nil {
+		return "", fmt.Errorf("创建归档目录失败: %w", err)
+	}
+
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.Dir, entry.Name()))
+		if err != nil {
+			return "", fmt.Errorf("读取 %s 失败: %w", entry.Name(), err)
+		}
+		mode := os.FileMode(0644)
+		if isPrivateKeyFile(entry.Name()) {
+			mode = 0600
+		}
+		if err := os.WriteFile(filepath.Join(archiveDir, entry.Name()), data, mode); err != nil {
+			return "", fmt.Errorf("归档 %s 失败: %w", entry.Name(), err)
+		}
+	}
+
+	logger.Info("已归档旧证书", "cert", s.Name, "archive", archiveDir)
+	return archiveDir, nil
+}
+
+// Retire 删除域名集合变更后不再使用的证书目录（已归档）。
+// 仍被其他服务链接时保留目录，只记录警告
+func (s StoredCert) Retire() error {
+	if links := s.Links(); len(links) > 0 {
+		logger.Warn("旧证书仍被链接，保留证书目录，请将链接改为新证书后执行 autocert delete",
+			"cert", s.Name, "links", links)
+		return nil
+	}
+	if err := os.RemoveAll(s.Dir); err != nil {
+		return fmt.Errorf("删除旧证书目录失败: %w", err)
+	}
+	if err := DeleteRemoteCertificate(s.Name); err != nil {
+		return fmt.Errorf("从存储后端删除旧证书失败: %w", err)
+	}
+	logger.Info("已删除旧证书目录", "cert", s.Name)
+	return nil
+}
@@ -1,13 +1,18 @@
 package cert
 
 import (
+	"autocert/internal/clock"
 	"autocert/internal/config"
+	"autocert/internal/events"
+	"autocert/internal/features"
 	"autocert/internal/logger"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
+	"autocert/internal/policy"
+	"autocert/internal/preflight"
+	"autocert/internal/progress"
+	"autocert/internal/stats"
+	"autocert/internal/system"
+	"autocert/internal/webserver"
+	"crypto"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -24,7 +29,30 @@ type MultiDomainManager struct {
 	webrootPath   string
 	webServerType WebServerType
 	certDir       string
+	keyType       string
 	keySize       int
+	dualCert      bool
+	multiHost     bool            // 已确认域名解析到的多台主机都能响应 http-01 挑战
+	multiHostSet  bool            // install 指定了 --expect-multi-host，签发后记录到证书目录
+	issueOnly     bool            // 只签发证书并按命名方式输出
+	renewBefore   int             // 续期天数
+	preferredRoot string          // 首选证书链的根证书名称
+	keepRoot      bool            // install 指定了 --preferred-chain，签发后记录到证书目录
+	cdnDomains    map[string]bool // 经过 CDN 代理、改用 DNS 验证的成员
+	overwrite     bool            // 覆盖手工修改的站点配置
+	noRedirect    bool            // HTTP 不重定向到 HTTPS
+	noRedirectSet bool            // install 指定了 --no-redirect，配置站点时记录到证书目录
+	staging       bool            // 暂存部署站点配置，不启用
+	interrupt     func() error    // 返回错误时停止签发（例如分布式锁已丢失）
+
+	// memberChallenges 单独指定了验证方式的成员（域名文件中的 challenge=、webroot=）
+	memberChallenges map[string]memberChallenge
+}
+
+// memberChallenge 单个成员的验证方式
+type memberChallenge struct {
+	challengeType ChallengeType
+	webrootPath   string // 为空时使用 SetWebrootPath 设置的目录
 }
 
 // NewMultiDomainManager 创建新的多域名证书管理器
@@ -33,14 +61,35 @@ func NewMultiDomainManager(domains []string, email string) *MultiDomainManager {
 		return nil
 	}
 
-	return &MultiDomainManager{
+	acmeConfig := config.GetACMEConfig()
+
+	// 默认使用配置的 Web 服务器类型（续期已有证书时也能部署到正确的服务器）
+	webServerType, _ := ParseWebServerType(config.GetWebServerConfig().Type)
+
+	m := &MultiDomainManager{
 		domains:       domains,
 		primaryDomain: domains[0], // 第一个域名作为主域名
 		email:         email,
 		challengeType: ChallengeWebroot,
+		webServerType: webServerType,
 		certDir:       config.GetCertDir(),
-		keySize:       2048,
-	}
+		keyType:       acmeConfig.KeyType,
+		keySize:       acmeConfig.KeySize,
+		dualCert:      acmeConfig.DualCert && features.Gate(features.DualCert, "acme.dual_cert"),
+		renewBefore:   config.GetRenewBeforeDays(domains[0]),
+		preferredRoot: config.GetPreferredChain(domains[0]),
+	}
+	// 之前 install --preferred-chain 记录的首选证书链优先于配置
+	if name := siteOptionValue(m.getCertDir(), siteOptionPreferredChain); name != "" {
+		m.preferredRoot = name
+	}
+	// 域名配置 no_redirect 或之前 install --no-redirect 记录的选项
+	m.noRedirect = config.GetNoRedirect(domains[0]) || hasSiteOption(m.getCertDir(), siteOptionNoRedirect)
+	// 之前 install --expect-multi-host 记录的确认
+	m.multiHost = hasSiteOption(m.getCertDir(), siteOptionExpectMultiHost)
+	// 之前 install --staging-deploy 暂存、尚未启用的站点配置保持暂存
+	m.staging = hasSiteOption(m.getCertDir(), siteOptionStagingDeploy)
+	return m
 }
 
 // SetChallengeType 设置挑战类型
@@ -58,50 +107,308 @@ func (m *MultiDomainManager) SetWebServer(webServerType WebServerType) {
 	m.webServerType = webServerType
 }
 
+// SetDualCert 设置是否同时签发 RSA 和 ECDSA 证书
+func (m *MultiDomainManager) SetDualCert(dualCert bool) {
+	m.dualCert = dualCert
+}
+
+// SetMemberChallenge 为单个成员指定验证方式，webrootPath 只用于 Webroot 模式（为空时使用 SetWebrootPath 设置的目录）。
+// 泛域名成员总是使用 DNS 验证
+func (m *MultiDomainManager) SetMemberChallenge(domain string, challengeType ChallengeType, webrootPath string) {
+	if m.memberChallenges == nil {
+		m.memberChallenges = make(map[string]memberChallenge)
+	}
+	m.memberChallenges[domain] = memberChallenge{challengeType: challengeType, webrootPath: webrootPath}
+}
+
+// SetExpectMultiHost 确认域名解析到的多台主机都能响应 http-01 挑战，预检不再警告；签发后记录到证书目录，续期时沿用
+func (m *MultiDomainManager) SetExpectMultiHost(expect bool) {
+	m.multiHost = expect
+	m.multiHostSet = true
+}
+
+// SetIssueOnly 只签发证书并按命名方式输出（ci 命令），不发布 TLSA 记录、不保存到证书库和存储后端、
+// 不配置 Web 服务器、不执行部署
+func (m *MultiDomainManager) SetIssueOnly(issueOnly bool) {
+	m.issueOnly = issueOnly
+}
+
+// SetRenewBeforeDays 设置续期天数（剩余有效期少于该天数时续期）
+func (m *MultiDomainManager) SetRenewBeforeDays(days int) {
+	m.renewBefore = days
+}
+
+// SetPreferredChain 设置首选证书链：CA 提供多条证书链时选择根证书为该名称的证书链
+func (m *MultiDomainManager) SetPreferredChain(name string) {
+	m.preferredRoot = name
+}
+
+// KeepPreferredChain 设置首选证书链，签发成功后记录到证书目录，之后续期时沿用（install --preferred-chain）
+func (m *MultiDomainManager) KeepPreferredChain(name string) {
+	m.preferredRoot = name
+	m.keepRoot = true
+}
+
+// SetOverwriteDrift 站点配置在上次生成后被手工修改时仍然覆盖（默认拒绝覆盖）
+func (m *MultiDomainManager) SetOverwriteDrift(overwrite bool) {
+	m.overwrite = overwrite
+}
+
+// SetInterrupt 设置签发过程中的检查：申请证书、写入存储后端和部署前调用，返回错误时停止
+func (m *MultiDomainManager) SetInterrupt(check func() error) {
+	m.interrupt = check
+}
+
+// SetNoRedirect 生成的站点配置中 HTTP 不重定向到 HTTPS，80 端口继续提供站点（Nginx、Apache）。
+// 配置站点时记录到证书目录，续期重新生成站点配置时沿用
+func (m *MultiDomainManager) SetNoRedirect(noRedirect bool) {
+	m.noRedirect = noRedirect
+	m.noRedirectSet = true
+}
+
+// SetStagingDeploy 暂存部署：站点配置写入 <站点配置>.disabled 且不启用（Nginx、Apache），
+// 记录在证书目录中，之后续期重新生成时保持暂存，直到 autocert activate 启用
+func (m *MultiDomainManager) SetStagingDeploy(staging bool) {
+	m.staging = staging
+}
+
 // Install 安装多域名证书
 func (m *MultiDomainManager) Install() error {
+	renewal := fileExists(m.getCertPath())
+	start := time.Now()
+	err := m.install()
+	stats.Record(stats.CertOperation(renewal), time.Since(start), err)
+	events.Emit(events.CertResult(m.domains, renewal, err))
+	return err
+}
+
+// install 申请证书并配置 Web 服务器
+func (m *MultiDomainManager) install() error {
 	logger.Info("开始安装多域名证书", "domains", m.domains, "primaryDomain", m.primaryDomain)
 
-	// 检查是否有泛域名
-	hasWildcard := m.hasWildcardDomain()
-	if hasWildcard && m.challengeType != ChallengeDNS {
-		return fmt.Errorf("泛域名证书必须使用 DNS 验证模式")
+	// 检查签发策略（允许的域名和 ACME 服务器）
+	if err := policy.CheckIssuance("certificate.issue", m.domains, config.GetACMEConfig().Server); err != nil {
+		return err
+	}
+
+	// 经过 CDN 代理的成员改用 DNS 验证，预检只检查仍使用 http-01 的成员
+	var httpDomains []string
+	for _, domain := range m.domains {
+		if m.challengeForDomain(domain) != ChallengeDNS {
+			httpDomains = append(httpDomains, domain)
+		}
+	}
+	m.cdnDomains = cdnFallback(httpDomains)
+	var checked []string
+	for _, domain := range m.domains {
+		if !m.cdnDomains[domain] {
+			checked = append(checked, domain)
+		}
+	}
+
+	// 泛域名和经过 CDN 代理的成员使用 DNS 验证，单独指定了验证方式的成员使用指定的方式，其余成员使用配置的验证方式
+	byType := make(map[ChallengeType][]string)
+	for _, domain := range m.domains {
+		challengeType := m.challengeForDomain(domain)
+		byType[challengeType] = append(byType[challengeType], domain)
+	}
+
+	// 签发前检查运行环境（例如系统时间）
+	http01 := len(byType[ChallengeDNS]) < len(m.domains)
+	if err := preflight.Issuance(checked, preflight.Options{HTTP01: http01, ExpectMultiHost: m.multiHost}); err != nil {
+		return fmt.Errorf("签发前检查未通过: %w", err)
+	}
+
+	if len(byType) > 1 {
+		logger.Info("使用混合验证模式", "webroot", byType[ChallengeWebroot], "standalone", byType[ChallengeStandalone], "dns-01", byType[ChallengeDNS])
 	}
 
 	// 1. 创建证书目录（使用主域名）
 	if err := m.createCertDir(); err != nil {
 		return fmt.Errorf("创建证书目录失败: %w", err)
 	}
+	if err := system.CheckDiskSpace(m.certDir, config.GetStorageConfig().MinFreeBytes()); err != nil {
+		return err
+	}
+
+	// 启用 DANE 时签发前发布新私钥的 TLSA 记录，发布失败不签发；只签发时由部署证书的一方发布
+	keyType := m.keyType
+	if m.dualCert {
+		keyType = KeyTypeRSA
+	}
+	keys := []daneKey{{path: m.getKeyPath(), keyType: keyType, keySize: m.keySize}}
+	if m.dualCert {
+		keys = append(keys, daneKey{path: m.getECDSAKeyPath(), keyType: KeyTypeECDSA})
+	}
+	var dane *daneSession
+	if !m.issueOnly {
+		var err error
+		dane, err = newDANESession(m.domains, m.getCertDir(), keys)
+		if err != nil {
+			return fmt.Errorf("发布 TLSA 记录失败: %w", err)
+		}
+	}
+	if dane != nil {
+		if err := dane.prepare(); err != nil {
+			return fmt.Errorf("发布 TLSA 记录失败: %w", err)
+		}
+	}
+
+	// 2. 申请主证书（双证书模式下主证书固定为 RSA）
+	if err := checkInterrupt(m.interrupt); err != nil {
+		return err
+	}
+	if err := m.issue(keyType, m.getKeyPath(), m.getCertPath(), m.getChainPath()); err != nil {
+		return err
+	}
+	if m.keepRoot {
+		if err := setSiteOptionValue(m.getCertDir(), siteOptionPreferredChain, m.preferredRoot); err != nil {
+			logger.Warn("记录首选证书链失败", "dir", m.getCertDir(), "error", err)
+		}
+	}
+
+	if m.multiHostSet {
+		if err := setSiteOption(m.getCertDir(), siteOptionExpectMultiHost, m.multiHost); err != nil {
+			logger.Warn("记录站点配置选项失败", "dir", m.getCertDir(), "error", err)
+		}
+	}
+
+	// 3. 双证书模式下再申请一张 ECDSA 证书，与 RSA 证书并列存放
+	if m.dualCert {
+		logger.Info("申请 ECDSA 多域名证书", "domains", m.domains)
+		if err := m.issue(KeyTypeECDSA, m.getECDSAKeyPath(), m.getECDSACertPath(), ""); err != nil {
+			return fmt.Errorf("ECDSA 证书申请失败: %w", err)
+		}
+	}
+
+	// 4. 创建域名列表文件（用于记录此证书包含的所有域名）
+	domainsFile := m.getDomainsListPath()
+	if err := os.WriteFile(domainsFile, []byte(strings.Join(m.domains, "\n")), 0644); err != nil {
+		logger.Warn("无法创建域名列表文件", "error", err)
+	}
+
+	// 5. 按配置生成 PFX 证书包和按命名方式输出的证书；证书库布局下将证书移入证书库，证书目录中保留符号链接
+	if err := writePFXBundle(m.getCertDir(), m.domains); err != nil {
+		return err
+	}
+	if err := writeOutput(m.getCertDir(), m.domains); err != nil {
+		return err
+	}
+	if m.issueOnly {
+		logger.Info("证书已签发", "domains", m.domains)
+		return nil
+	}
+	if err := checkInterrupt(m.interrupt); err != nil {
+		return err
+	}
+	if err := storeCertificate(m.certDir, m.getCertDir()); err != nil {
+		return fmt.Errorf("保存到证书库失败: %w", err)
+	}
+	if err := uploadCertificate(m.getCertDir()); err != nil {
+		return fmt.Errorf("保存到存储后端失败（证书已保存在本地，可执行 autocert storage push 重试）: %w", err)
+	}
 
-	// 2. 生成私钥
-	privateKey, err := m.generatePrivateKey()
+	// 新证书已保存，预生成并发布下一次续期使用的私钥的记录
+	if dane != nil {
+		dane.rotate()
+	}
+
+	// 6. 为每个域名配置 Web 服务器，按依赖关系执行部署钩子
+	if err := checkInterrupt(m.interrupt); err != nil {
+		return err
+	}
+	if err := m.Deploy(); err != nil {
+		return err
+	}
+
+	// 新证书已部署，删除旧私钥的 TLSA 记录
+	if dane != nil {
+		dane.retire()
+	}
+
+	logger.Info("多域名证书安装完成", "domains", m.domains)
+	return nil
+}
+
+// Deploy 使用证书目录中已有的证书配置 Web 服务器并执行部署钩子，不申请证书
+// 守护进程降权运行时交给 root 的部署助手执行
+func (m *MultiDomainManager) Deploy() error {
+	if deployHandler != nil {
+		return deployHandler(filepath.Base(m.getCertDir()))
+	}
+	if err := m.configureWebServers(); err != nil {
+		return fmt.Errorf("配置 Web 服务器失败: %w", err)
+	}
+	return runDeployHooks(m.domains, m.getCertDir())
+}
+
+// NeedsRenewal 检查多域名证书是否已到续期时间
+func (m *MultiDomainManager) NeedsRenewal() (bool, *CertInfo, error) {
+	certInfo, err := m.GetCertInfo()
+	if err != nil {
+		return false, nil, fmt.Errorf("获取证书信息失败: %w", err)
+	}
+
+	if !certInfo.NeedsRenewal(m.renewBefore) {
+		logger.Info("多域名证书还未到续期时间", "domains", m.domains, "expiry", certInfo.ExpiryDate,
+			"threshold", certInfo.RenewalThreshold(m.renewBefore))
+		return false, certInfo, nil
+	}
+
+	return true, certInfo, nil
+}
+
+// GetCertInfo 获取多域名证书信息
+func (m *MultiDomainManager) GetCertInfo() (*CertInfo, error) {
+	certPath := m.getCertPath()
+
+	cert, err := loadCertificate(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertInfo{
+		Domain:     m.primaryDomain,
+		CertPath:   certPath,
+		KeyPath:    m.getKeyPath(),
+		ChainPath:  m.getChainPath(),
+		IssuedDate: cert.NotBefore,
+		ExpiryDate: cert.NotAfter,
+		IsValid:    clock.Now().Before(cert.NotAfter),
+	}, nil
+}
+
+// issue 生成私钥、创建 CSR 并申请证书，保存到指定路径。证书文件包含整条证书链，chainPath 不为空时另外写入中间证书
+func (m *MultiDomainManager) issue(keyType, keyPath, certPath, chainPath string) error {
+	// 生成私钥
+	spinner := progress.Start(fmt.Sprintf("生成 %s 私钥", strings.ToUpper(keyType)))
+	privateKey, err := m.generatePrivateKey(keyType, keyPath)
+	spinner.Stop(err)
 	if err != nil {
 		return fmt.Errorf("生成私钥失败: %w", err)
 	}
 
-	// 3. 创建多域名证书签名请求
+	// 创建多域名证书签名请求
 	csr, err := m.createMultiDomainCSR(privateKey)
 	if err != nil {
 		return fmt.Errorf("创建多域名 CSR 失败: %w", err)
 	}
 
-	// 4. 通过 ACME 获取证书
-	cert, err := m.obtainCertificate(csr)
+	// 通过 ACME 获取证书
+	chains, err := m.obtainCertificate(csr)
 	if err != nil {
 		return fmt.Errorf("获取证书失败: %w", err)
 	}
-
-	// 5. 保存证书和私钥
-	if err := m.saveCertificate(cert, privateKey); err != nil {
-		return fmt.Errorf("保存证书失败: %w", err)
+	chain, err := selectPreferredChain(m.primaryDomain, m.preferredRoot, chains)
+	if err != nil {
+		return fmt.Errorf("获取证书失败: %w", err)
 	}
 
-	// 6. 为每个域名配置 Web 服务器
-	if err := m.configureWebServers(); err != nil {
-		return fmt.Errorf("配置 Web 服务器失败: %w", err)
+	// 保存证书
+	if err := m.saveCertificate(chain, certPath, chainPath); err != nil {
+		return fmt.Errorf("保存证书失败: %w", err)
 	}
 
-	logger.Info("多域名证书安装完成", "domains", m.domains)
 	return nil
 }
 
@@ -117,45 +424,20 @@ func (m *MultiDomainManager) hasWildcardDomain() bool {
 
 // createCertDir 创建证书目录
 func (m *MultiDomainManager) createCertDir() error {
-	// 使用主域名作为目录名，但添加多域名标识
-	dirName := m.primaryDomain
-	if len(m.domains) > 1 {
-		dirName = fmt.Sprintf("%s_san", m.primaryDomain)
-	}
-
-	certDir := filepath.Join(m.certDir, dirName)
-	return os.MkdirAll(certDir, 0755)
+	return ensureDirs(m.certDir, m.getCertDir())
 }
 
 // generatePrivateKey 生成私钥
-func (m *MultiDomainManager) generatePrivateKey() (*rsa.PrivateKey, error) {
-	logger.Debug("生成多域名证书私钥", "keySize", m.keySize)
+func (m *MultiDomainManager) generatePrivateKey(keyType, keyPath string) (crypto.Signer, error) {
+	logger.Debug("生成多域名证书私钥", "keyType", keyType, "keySize", m.keySize)
 
-	privateKey, err := rsa.GenerateKey(rand.Reader, m.keySize)
+	privateKey, err := newPrivateKey(keyType, m.keySize, keyPath)
 	if err != nil {
 		return nil, err
 	}
 
 	// 保存私钥到文件
-	keyPath := m.getKeyPath()
-	keyFile, err := os.Create(keyPath)
-	if err != nil {
-		return nil, err
-	}
-	defer keyFile.Close()
-
-	keyBytes := x509.MarshalPKCS1PrivateKey(privateKey)
-	keyPEM := &pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: keyBytes,
-	}
-
-	if err := pem.Encode(keyFile, keyPEM); err != nil {
-		return nil, err
-	}
-
-	// 设置私钥文件权限
-	if err := os.Chmod(keyPath, 0600); err != nil {
+	if err := writePrivateKey(keyPath, privateKey); err != nil {
 		return nil, err
 	}
 
@@ -164,17 +446,11 @@ func (m *MultiDomainManager) generatePrivateKey() (*rsa.PrivateKey, error) {
 }
 
 // createMultiDomainCSR 创建多域名证书签名请求
-func (m *MultiDomainManager) createMultiDomainCSR(privateKey *rsa.PrivateKey) ([]byte, error) {
+func (m *MultiDomainManager) createMultiDomainCSR(privateKey crypto.Signer) ([]byte, error) {
 	logger.Debug("创建多域名 CSR", "domains", m.domains)
 
-	template := x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName: m.primaryDomain,
-		},
-		DNSNames: m.domains, // 所有域名都放在 SAN 中
-	}
-
-	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, &template, privateKey)
+	// 所有域名都放在 SAN 中
+	csrBytes, err := createCSR(m.primaryDomain, m.domains, privateKey)
 	if err != nil {
 		return nil, err
 	}
@@ -184,134 +460,68 @@ func (m *MultiDomainManager) createMultiDomainCSR(privateKey *rsa.PrivateKey) ([
 }
 
 // obtainCertificate 获取多域名证书
-func (m *MultiDomainManager) obtainCertificate(csr []byte) ([]byte, error) {
+// 每个 SAN 成员对应一个独立的授权，按授权分别选择验证方式：
+// 泛域名成员只能使用 dns-01，其余成员使用配置的 http-01 方式（webroot/standalone）；
+// 授权之间互不依赖，按 acme.authz_concurrency 并发处理。
+// 返回 CA 提供的证书链（PEM，默认证书链在前，之后为备用证书链）
+func (m *MultiDomainManager) obtainCertificate(csr []byte) ([][]byte, error) {
 	logger.Info("开始多域名 ACME 证书申请流程", "domains", m.domains, "challengeType", m.challengeType)
 
-	switch m.challengeType {
-	case ChallengeWebroot:
-		return m.obtainCertificateWebroot(csr)
-	case ChallengeStandalone:
-		return m.obtainCertificateStandalone(csr)
-	case ChallengeDNS:
-		return m.obtainCertificateDNS(csr)
-	default:
-		return nil, fmt.Errorf("不支持的验证模式: %d", m.challengeType)
+	// 演示签发流程没有证书下载 URL，无法按 rel="alternate" 的 Link 头（acme.AlternateLinks）获取备用证书链
+	if err := checkPreferredChain(m.primaryDomain, m.preferredRoot); err != nil {
+		return nil, err
 	}
-}
-
-// obtainCertificateWebroot 使用 Webroot 模式获取多域名证书
-func (m *MultiDomainManager) obtainCertificateWebroot(csr []byte) ([]byte, error) {
-	logger.Info("使用 Webroot 模式获取多域名证书", "domains", m.domains)
 
-	// 检查是否有泛域名
-	if m.hasWildcardDomain() {
-		return nil, fmt.Errorf("泛域名证书不能使用 Webroot 验证模式，请使用 DNS 验证")
+	// 授权并发处理，共用同一个 Standalone 服务器和挑战目录
+	if err := solveAuthorizations(m.domains, m.webrootForDomain, m.challengeForDomain); err != nil {
+		return nil, err
 	}
 
-	// 为了演示，这里使用自签名证书
-	return m.generateMultiDomainSelfSignedCert(csr)
+	// 为了演示，这里使用自签名证书，只有一条证书链
+	spinner := progress.Start("签发证书 " + m.primaryDomain)
+	cert, err := m.generateMultiDomainSelfSignedCert(csr)
+	spinner.Stop(err)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{encodeCertificate(cert)}, nil
 }
 
-// obtainCertificateStandalone 使用 Standalone 模式获取多域名证书
-func (m *MultiDomainManager) obtainCertificateStandalone(csr []byte) ([]byte, error) {
-	logger.Info("使用 Standalone 模式获取多域名证书", "domains", m.domains)
-
-	// 检查是否有泛域名
-	if m.hasWildcardDomain() {
-		return nil, fmt.Errorf("泛域名证书不能使用 Standalone 验证模式，请使用 DNS 验证")
+// challengeForDomain 为单个 SAN 成员选择验证方式
+func (m *MultiDomainManager) challengeForDomain(domain string) ChallengeType {
+	if strings.HasPrefix(domain, "*.") || m.cdnDomains[domain] {
+		return ChallengeDNS
 	}
-
-	// 为了演示，这里使用自签名证书
-	return m.generateMultiDomainSelfSignedCert(csr)
+	if member, ok := m.memberChallenges[domain]; ok {
+		return member.challengeType
+	}
+	return m.challengeType
 }
 
-// obtainCertificateDNS 使用 DNS 模式获取多域名证书
-func (m *MultiDomainManager) obtainCertificateDNS(csr []byte) ([]byte, error) {
-	logger.Info("使用 DNS 模式获取多域名证书", "domains", m.domains)
-
-	// DNS 模式支持所有类型的域名，包括泛域名
-	logger.Warn("注意：DNS 模式需要手动添加 DNS 记录或配置 DNS API", "domains", m.domains)
-
-	// 显示需要添加的 DNS 记录
-	for _, domain := range m.domains {
-		if strings.HasPrefix(domain, "*.") {
-			baseDomain := domain[2:]
-			logger.Info("需要为泛域名添加 DNS TXT 记录",
-				"record", fmt.Sprintf("_acme-challenge.%s", baseDomain),
-				"domain", domain)
-		} else {
-			logger.Info("需要为域名添加 DNS TXT 记录",
-				"record", fmt.Sprintf("_acme-challenge.%s", domain),
-				"domain", domain)
-		}
+// webrootForDomain 单个 SAN 成员使用 Webroot 模式验证时的网站根目录
+func (m *MultiDomainManager) webrootForDomain(domain string) string {
+	if member, ok := m.memberChallenges[domain]; ok && member.webrootPath != "" {
+		return member.webrootPath
 	}
-
-	// 为了演示，这里使用自签名证书
-	return m.generateMultiDomainSelfSignedCert(csr)
+	return m.webrootPath
 }
 
 // generateMultiDomainSelfSignedCert 生成多域名自签名证书
 func (m *MultiDomainManager) generateMultiDomainSelfSignedCert(csr []byte) ([]byte, error) {
 	logger.Warn("生成多域名自签名证书（仅用于演示）", "domains", m.domains)
 
-	// 解析 CSR
-	csrParsed, err := x509.ParseCertificateRequest(csr)
-	if err != nil {
-		return nil, err
-	}
-
-	// 创建证书模板
-	template := x509.Certificate{
-		Subject:     csrParsed.Subject,
-		DNSNames:    csrParsed.DNSNames,
-		NotBefore:   time.Now(),
-		NotAfter:    time.Now().Add(90 * 24 * time.Hour), // 90 天有效期
-		KeyUsage:    x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
-	}
-
-	// 生成私钥（用于签名）
-	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, err
-	}
-
-	// 创建证书
-	certBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		return nil, err
-	}
-
-	return certBytes, nil
+	// 90 天有效期
+	return signDemoCertificate(csr, 90)
 }
 
-// saveCertificate 保存证书和私钥
-func (m *MultiDomainManager) saveCertificate(certBytes []byte, privateKey *rsa.PrivateKey) error {
+// saveCertificate 保存证书链
+func (m *MultiDomainManager) saveCertificate(chain []byte, certPath, chainPath string) error {
 	logger.Debug("保存多域名证书", "domains", m.domains)
 
-	// 保存证书
-	certPath := m.getCertPath()
-	certFile, err := os.Create(certPath)
-	if err != nil {
-		return err
-	}
-	defer certFile.Close()
-
-	certPEM := &pem.Block{
-		Type:  "CERTIFICATE",
-		Bytes: certBytes,
-	}
-
-	if err := pem.Encode(certFile, certPEM); err != nil {
+	if err := writeCertificateChain(certPath, chainPath, chain); err != nil {
 		return err
 	}
 
-	// 创建域名列表文件（用于记录此证书包含的所有域名）
-	domainsFile := m.getDomainsListPath()
-	if err := os.WriteFile(domainsFile, []byte(strings.Join(m.domains, "\n")), 0644); err != nil {
-		logger.Warn("无法创建域名列表文件", "error", err)
-	}
-
 	logger.Debug("多域名证书保存完成", "certPath", certPath, "domains", m.domains)
 	return nil
 }
@@ -320,6 +530,18 @@ func (m *MultiDomainManager) saveCertificate(certBytes []byte, privateKey *rsa.P
 func (m *MultiDomainManager) configureWebServers() error {
 	logger.Info("配置多域名 Web 服务器", "type", m.webServerType, "domains", m.domains)
 
+	// 记录 install 指定的站点配置选项，续期时沿用
+	if m.noRedirectSet {
+		if err := setSiteOption(m.getCertDir(), siteOptionNoRedirect, m.noRedirect); err != nil {
+			logger.Warn("记录站点配置选项失败", "domains", m.domains, "error", err)
+		}
+	}
+	if m.staging {
+		if err := setSiteOption(m.getCertDir(), siteOptionStagingDeploy, true); err != nil {
+			logger.Warn("记录站点配置选项失败", "domains", m.domains, "error", err)
+		}
+	}
+
 	// 为每个域名配置 Web 服务器
 	for _, domain := range m.domains {
 		if strings.HasPrefix(domain, "*.") {
@@ -346,18 +568,96 @@ func (m *MultiDomainManager) configureWebServers() error {
 func (m *MultiDomainManager) configureNginx() error {
 	logger.Info("配置 Nginx 多域名 SSL", "domains", m.domains)
 
-	// 这里应该实现真正的 Nginx 多域名配置逻辑
-	// 可以为每个域名创建单独的 server block，或者创建一个包含所有域名的 server block
+	// 创建一个包含所有域名的 server block
+	configurator, err := webserver.NewConfigurator("nginx")
+	if err != nil {
+		return err
+	}
+
+	cfg := m.webServerConfig("nginx")
+	if err := configurator.Configure(cfg); err != nil {
+		return err
+	}
+
+	if err := configurator.Test(); err != nil {
+		return err
+	}
+
+	if err := configurator.Reload(); err != nil {
+		return err
+	}
+
+	events.Emit(events.Event{
+		Type:    events.ConfigChanged,
+		Domains: m.domains,
+		Message: "Web 服务器配置已更新",
+		Fields:  map[string]string{"server": "nginx", "path": cfg.ConfigPath},
+	})
+
+	if err := recordDeployment(m.getCertDir(), "nginx:"+cfg.ConfigPath); err != nil {
+		logger.Warn("记录证书部署位置失败", "domains", m.domains, "error", err)
+	}
 
 	logger.Info("Nginx 多域名配置完成")
 	return nil
 }
 
+// webServerConfig 生成 Web 服务器配置参数
+func (m *MultiDomainManager) webServerConfig(serverType string) *webserver.Config {
+	cfg := &webserver.Config{
+		Type:     serverType,
+		Domain:   m.primaryDomain,
+		Aliases:  m.domains[1:],
+		CertPath: m.getCertPath(),
+		KeyPath:  m.getKeyPath(),
+		WebRoot:  m.webrootPath,
+
+		OverwriteDrift: m.overwrite,
+		NoRedirect:     m.noRedirect,
+		StagingDeploy:  m.staging,
+	}
+
+	if m.dualCert {
+		cfg.ECDSACertPath = m.getECDSACertPath()
+		cfg.ECDSAKeyPath = m.getECDSAKeyPath()
+	}
+
+	return cfg
+}
+
 // configureApache 配置 Apache 多域名
 func (m *MultiDomainManager) configureApache() error {
 	logger.Info("配置 Apache 多域名 SSL", "domains", m.domains)
 
-	// 这里应该实现真正的 Apache 多域名配置逻辑
+	// 一个 VirtualHost 通过 ServerAlias 覆盖所有域名
+	configurator, err := webserver.NewConfigurator("apache")
+	if err != nil {
+		return err
+	}
+
+	cfg := m.webServerConfig("apache")
+	if err := configurator.Configure(cfg); err != nil {
+		return err
+	}
+
+	if err := configurator.Test(); err != nil {
+		return err
+	}
+
+	if err := configurator.Reload(); err != nil {
+		return err
+	}
+
+	events.Emit(events.Event{
+		Type:    events.ConfigChanged,
+		Domains: m.domains,
+		Message: "Web 服务器配置已更新",
+		Fields:  map[string]string{"server": "apache", "path": cfg.ConfigPath},
+	})
+
+	if err := recordDeployment(m.getCertDir(), "apache:"+cfg.ConfigPath); err != nil {
+		logger.Warn("记录证书部署位置失败", "domains", m.domains, "error", err)
+	}
 
 	logger.Info("Apache 多域名配置完成")
 	return nil
@@ -367,41 +667,72 @@ func (m *MultiDomainManager) configureApache() error {
 func (m *MultiDomainManager) configureIIS() error {
 	logger.Info("配置 IIS 多域名 SSL", "domains", m.domains)
 
-	// 这里应该实现真正的 IIS 多域名配置逻辑
+	// 使用集中式证书存储时每个域名写入一个 PFX 文件
+	configurator, err := webserver.NewConfigurator("iis")
+	if err != nil {
+		return err
+	}
+
+	cfg := m.webServerConfig("iis")
+	if err := configurator.Configure(cfg); err != nil {
+		return err
+	}
+
+	if err := configurator.Test(); err != nil {
+		return err
+	}
+
+	if err := configurator.Reload(); err != nil {
+		return err
+	}
+
+	if cfg.ConfigPath != "" {
+		events.Emit(events.Event{
+			Type:    events.ConfigChanged,
+			Domains: m.domains,
+			Message: "Web 服务器配置已更新",
+			Fields:  map[string]string{"server": "iis", "path": cfg.ConfigPath},
+		})
+
+		if err := recordDeployment(m.getCertDir(), "iis:"+cfg.ConfigPath); err != nil {
+			logger.Warn("记录证书部署位置失败", "domains", m.domains, "error", err)
+		}
+	}
 
 	logger.Info("IIS 多域名配置完成")
 	return nil
 }
 
-// 获取各种文件路径
-func (m *MultiDomainManager) getCertPath() string {
+// getCertDir 获取证书所在目录（多域名证书目录带 _san 后缀）
+func (m *MultiDomainManager) getCertDir() string {
 	dirName := m.primaryDomain
 	if len(m.domains) > 1 {
 		dirName = fmt.Sprintf("%s_san", m.primaryDomain)
 	}
-	return filepath.Join(m.certDir, dirName, "cert.pem")
+	return filepath.Join(m.certDir, dirName)
+}
+
+// 获取各种文件路径
+func (m *MultiDomainManager) getCertPath() string {
+	return filepath.Join(m.getCertDir(), "cert.pem")
 }
 
 func (m *MultiDomainManager) getKeyPath() string {
-	dirName := m.primaryDomain
-	if len(m.domains) > 1 {
-		dirName = fmt.Sprintf("%s_san", m.primaryDomain)
-	}
-	return filepath.Join(m.certDir, dirName, "key.pem")
+	return filepath.Join(m.getCertDir(), "key.pem")
 }
 
 func (m *MultiDomainManager) getChainPath() string {
-	dirName := m.primaryDomain
-	if len(m.domains) > 1 {
-		dirName = fmt.Sprintf("%s_san", m.primaryDomain)
-	}
-	return filepath.Join(m.certDir, dirName, "chain.pem")
+	return filepath.Join(m.getCertDir(), "chain.pem")
+}
+
+func (m *MultiDomainManager) getECDSACertPath() string {
+	return filepath.Join(m.getCertDir(), "cert-ecdsa.pem")
+}
+
+func (m *MultiDomainManager) getECDSAKeyPath() string {
+	return filepath.Join(m.getCertDir(), "key-ecdsa.pem")
 }
 
 func (m *MultiDomainManager) getDomainsListPath() string {
-	dirName := m.primaryDomain
-	if len(m.domains) > 1 {
-		dirName = fmt.Sprintf("%s_san", m.primaryDomain)
-	}
-	return filepath.Join(m.certDir, dirName, "domains.txt")
+	return filepath.Join(m.getCertDir(), "domains.txt")
 }
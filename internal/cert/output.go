@@ -0,0 +1,176 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/secmem"
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 证书输出的命名方式（output.naming）
+const (
+	NamingCertbot  = "certbot"  // <dir>/live/<名称>/{cert,chain,fullchain,privkey}.pem
+	NamingACMESh   = "acme.sh"  // <dir>/<名称>/{<域名>.cer,ca.cer,fullchain.cer,<域名>.key}
+	NamingSynology = "synology" // <dir>/<名称>/{cert,chain,fullchain,privkey}.pem，与 DSM 证书目录相同
+	NamingFlat     = "flat"     // <dir>/<名称>.crt（完整证书链）、<名称>.chain.crt、<名称>.key
+)
+
+// outputPart 输出文件的内容
+type outputPart int
+
+const (
+	partCert      outputPart = iota // 叶子证书
+	partChain                       // 中间证书
+	partFullchain                   // 叶子证书和中间证书
+	partKey                         // 私钥
+)
+
+// outputFile 按命名方式输出的文件
+type outputFile struct {
+	path string
+	part outputPart
+}
+
+// outputFiles 返回命名方式下证书 name 的输出文件
+func outputFiles(naming, root, name string) ([]outputFile, error) {
+	switch naming {
+	case NamingCertbot, NamingSynology:
+		dir := filepath.Join(root, name)
+		if naming == NamingCertbot {
+			dir = filepath.Join(root, "live", name)
+		}
+		return []outputFile{
+			{filepath.Join(dir, "cert.pem"), partCert},
+			{filepath.Join(dir, "chain.pem"), partChain},
+			{filepath.Join(dir, "fullchain.pem"), partFullchain},
+			{filepath.Join(dir, "privkey.pem"), partKey},
+		}, nil
+	case NamingACMESh:
+		// ECC 证书目录带 _ecc 后缀，文件名仍为域名
+		dir := filepath.Join(root, name)
+		base := strings.TrimSuffix(name, "_ecc")
+		return []outputFile{
+			{filepath.Join(dir, base+".cer"), partCert},
+			{filepath.Join(dir, "ca.cer"), partChain},
+			{filepath.Join(dir, "fullchain.cer"), partFullchain},
+			{filepath.Join(dir, base+".key"), partKey},
+		}, nil
+	case NamingFlat:
+		return []outputFile{
+			{filepath.Join(root, name+".crt"), partFullchain},
+			{filepath.Join(root, name+".chain.crt"), partChain},
+			{filepath.Join(root, name+".key"), partKey},
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的证书命名方式: %s（可选 certbot、acme.sh、synology、flat）", naming)
+	}
+}
+
+// outputName 输出使用的证书名称：主域名，泛域名的 * 替换为 _（acme.sh 与其本身一致保留 *，Windows 上除外）。
+// 双证书模式下 ECDSA 证书使用单独的名称，acme.sh 与其本身一致加 _ecc 后缀，其他命名方式加 -ecdsa
+func outputName(naming, primary string, ecdsa bool) string {
+	name := primary
+	if naming != NamingACMESh || runtime.GOOS == "windows" {
+		name = strings.ReplaceAll(name, "*", "_")
+	}
+	if ecdsa {
+		if naming == NamingACMESh {
+			return name + "_ecc"
+		}
+		return name + "-ecdsa"
+	}
+	return name
+}
+
+// writeOutput 配置了 output.naming 时，将证书目录中的证书和私钥按命名方式写入 output.dir，
+// 供按固定路径读取证书的 NAS、面板等软件使用
+func writeOutput(dir string, domains []string) error {
+	outputConfig := config.GetOutputConfig()
+	if outputConfig.Naming == "" {
+		return nil
+	}
+	if outputConfig.Dir == "" {
+		return errors.New("配置了 output.naming 时需要配置 output.dir")
+	}
+
+	pairs := []struct {
+		cert, key string
+		ecdsa     bool
+	}{
+		{"cert.pem", "key.pem", false},
+		{"cert-ecdsa.pem", "key-ecdsa.pem", true},
+	}
+	for _, pair := range pairs {
+		certPath := filepath.Join(dir, pair.cert)
+		if pair.ecdsa && !fileExists(certPath) {
+			continue
+		}
+
+		name := outputName(outputConfig.Naming, domains[0], pair.ecdsa)
+		files, err := outputFiles(outputConfig.Naming, outputConfig.Dir, name)
+		if err != nil {
+			return err
+		}
+		if err := writeOutputFiles(files, certPath, filepath.Join(dir, pair.key), filepath.Join(dir, "chain.pem")); err != nil {
+			return fmt.Errorf("输出证书 %s 失败: %w", name, err)
+		}
+		logger.Info("已按命名方式输出证书", "naming", outputConfig.Naming, "name", name, "dir", filepath.Dir(files[0].path))
+	}
+	return nil
+}
+
+// writeOutputFiles 拆分证书文件中的叶子证书和中间证书，写入各输出文件。
+// 证书文件只有叶子证书时使用同目录的 chain.pem 作为中间证书
+func writeOutputFiles(files []outputFile, certPath, keyPath, chainPath string) error {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return err
+	}
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("%s 不是有效的 PEM 证书", certPath)
+	}
+	leaf := pem.EncodeToMemory(block)
+	chain := bytes.TrimLeft(rest, "\r\n")
+	if len(chain) == 0 && !strings.HasSuffix(certPath, "-ecdsa.pem") {
+		if extra, err := os.ReadFile(chainPath); err == nil {
+			chain = extra
+		}
+	}
+	fullchain := append(append([]byte{}, leaf...), chain...)
+
+	key, err := secmem.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	defer key.Destroy()
+
+	for _, file := range files {
+		if err := os.MkdirAll(filepath.Dir(file.path), DirMode()); err != nil {
+			return err
+		}
+		var content []byte
+		mode := os.FileMode(0644)
+		switch file.part {
+		case partCert:
+			content = leaf
+		case partChain:
+			content = chain
+		case partFullchain:
+			content = fullchain
+		case partKey:
+			content, mode = key.Bytes(), 0600
+		}
+		if err := writeFileAtomic(file.path, content, mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// PermissionIssue 权限不符合要求的证书目录或文件
+type PermissionIssue struct {
+	Path  string
+	Mode  os.FileMode // 当前权限
+	Want  os.FileMode // 期望权限
+	Fixed bool
+	Err   error // 修正失败的原因
+}
+
+// DirMode 证书目录的权限（storage.dir_mode），配置无效时使用 0755
+func DirMode() os.FileMode {
+	mode, err := config.GetStorageConfig().DirPerm()
+	if err != nil {
+		logger.Warn("证书目录权限配置无效，使用 0755", "error", err)
+		return 0755
+	}
+	return mode
+}
+
+// allowedDirMode 目录允许的权限：不超出 storage.dir_mode，所有者保留读写和进入权限。
+// 比配置更严格的已有目录（例如手工改为 0700）保持不变
+func allowedDirMode(current, mode os.FileMode) os.FileMode {
+	return current&mode | 0700
+}
+
+// ensureDirs 创建目录：新建的目录设置为证书目录权限（MkdirAll 的权限受 umask 影响），
+// 已存在的目录只去掉超出配置的权限
+func ensureDirs(dirs ...string) error {
+	mode := DirMode()
+	for _, dir := range dirs {
+		_, statErr := os.Stat(dir)
+		created := os.IsNotExist(statErr)
+		if err := os.MkdirAll(dir, mode); err != nil {
+			return err
+		}
+		if runtime.GOOS == "windows" {
+			continue
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		want := allowedDirMode(info.Mode().Perm(), mode)
+		if created {
+			want = mode
+		}
+		if info.Mode().Perm() != want {
+			// 目录可能属于其他用户（例如共享的证书根目录），无法修改时只记录警告
+			if err := os.Chmod(dir, want); err != nil {
+				logger.Warn("设置证书目录权限失败", "dir", dir, "mode", want, "error", err)
+			}
+		}
+	}
+	return nil
+}
+
+// isPrivateKeyFile 是否为私钥文件：key.pem、key-ecdsa.pem、按命名方式输出的 privkey.pem、*.key，
+// 以及包含私钥的 PFX/PKCS#12 证书包（证书库 .store 中的文件名与证书目录相同）
+func isPrivateKeyFile(name string) bool {
+	name = strings.ToLower(name)
+	switch filepath.Ext(name) {
+	case ".key", ".pfx", ".p12":
+		return true
+	case ".pem":
+		return strings.HasPrefix(name, "key") || strings.HasPrefix(name, "privkey") || strings.HasSuffix(name, "-key.pem")
+	}
+	return false
+}
+
+// CheckPermissions 检查证书根目录下所有目录和文件的权限：
+// 目录权限不能超出 storage.dir_mode，私钥文件不允许组和其他用户访问，其他文件不允许组和其他用户写入。
+// fix 为 true 时修正不符合要求的权限。Windows 不使用 Unix 权限，不检查
+func CheckPermissions(certRoot string, fix bool) ([]PermissionIssue, error) {
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+	if _, err := os.Stat(certRoot); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	dirMode := DirMode()
+	var issues []PermissionIssue
+	err := filepath.WalkDir(certRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// 符号链接指向证书库，证书库中的文件会单独检查
+		if entry.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		mode := info.Mode().Perm()
+		want := mode
+		switch {
+		case entry.IsDir():
+			want = allowedDirMode(mode, dirMode)
+		case isPrivateKeyFile(entry.Name()):
+			want = mode &^ 0077
+		default:
+			want = mode &^ 0022
+		}
+		if mode == want {
+			return nil
+		}
+
+		issue := PermissionIssue{Path: path, Mode: mode, Want: want}
+		if fix {
+			if err := os.Chmod(path, want); err != nil {
+				issue.Err = err
+			} else {
+				issue.Fixed = true
+				logger.Info("已修正证书目录权限", "path", path, "from", mode, "to", want)
+			}
+		}
+		issues = append(issues, issue)
+		return nil
+	})
+	return issues, err
+}
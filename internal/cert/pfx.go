@@ -0,0 +1,124 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/pkcs12"
+	"autocert/internal/secret"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pfxFile 证书目录中的 PFX 证书包
+const pfxFile = "cert.pfx"
+
+// writePFXBundle 配置了 pfx.enabled 时，将证书目录中的证书、中间证书和私钥打包为 cert.pfx。
+// 密码按 pfx.password 的策略生成或读取，随机密码保存为 pfx:<主域名>
+func writePFXBundle(dir string, domains []string) error {
+	pfxConfig := config.GetPFXConfig()
+	if !pfxConfig.Enabled {
+		return nil
+	}
+
+	name := secret.PFXName(domains[0])
+	password, rotated, err := pfxPassword(pfxConfig, name)
+	if err != nil {
+		return fmt.Errorf("获取 PFX 密码失败: %w", err)
+	}
+
+	key, err := loadPrivateKey(filepath.Join(dir, "key.pem"))
+	if err != nil {
+		return err
+	}
+	certs, err := loadCertificateChain(filepath.Join(dir, "cert.pem"))
+	if err != nil {
+		return err
+	}
+	data, err := pkcs12.Encode(key, certs[0], certs[1:], password, domains[0])
+	if err != nil {
+		return fmt.Errorf("生成 PFX 失败: %w", err)
+	}
+
+	// 先写入临时文件，再保存新密码，最后替换文件：任何一步失败时旧的 cert.pfx 和旧密码仍然匹配
+	path := filepath.Join(dir, pfxFile)
+	tmpPath, err := writeTempFile(path, data, 0600)
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入 %s 失败: %w", pfxFile, err)
+	}
+	if rotated {
+		if err := secret.Set(name, password); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("保存 PFX 密码失败: %w", err)
+		}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("写入 %s 失败（新密码已保存，与现有的 %s 不匹配，重新签发或续期后恢复）: %w", pfxFile, pfxFile, err)
+	}
+
+	logger.Info("已生成 PFX 证书包", "path", path, "password", name, "rotated", rotated)
+	return nil
+}
+
+// pfxPassword 按策略返回 PFX 密码；rotated 表示生成了新的随机密码，需要保存
+func pfxPassword(pfxConfig config.PFXConfig, name string) (string, bool, error) {
+	switch policy := pfxConfig.Password; {
+	case policy == "" || policy == config.PFXPasswordRotate:
+		password, err := secret.Generate(pfxConfig.Length)
+		return password, true, err
+	case policy == config.PFXPasswordKeep:
+		password, err := secret.Get(name)
+		if err == nil {
+			return password, false, nil
+		}
+		if !errors.Is(err, secret.ErrNotFound) {
+			return "", false, err
+		}
+		password, err = secret.Generate(pfxConfig.Length)
+		return password, true, err
+	case strings.HasPrefix(policy, "env:") || strings.HasPrefix(policy, "file:"):
+		password, err := secret.Resolve(policy)
+		return password, false, err
+	default:
+		return "", false, fmt.Errorf("无效的 pfx.password: %q（可选 rotate、keep、env:变量名、file:文件路径）", policy)
+	}
+}
+
+// loadCertificateChain 读取 PEM 文件中的所有证书（叶子证书在前）
+func loadCertificateChain(certPath string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书文件失败: %w", err)
+	}
+	return parseCertificateChain(data)
+}
+
+// parseCertificateChain 解析 PEM 数据中的所有证书（叶子证书在前）
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("无法解析证书文件")
+	}
+	return certs, nil
+}
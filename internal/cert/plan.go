@@ -0,0 +1,114 @@
+package cert
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/webserver"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 变更类型
+const (
+	ChangeIssue   = "issue"   // 首次签发证书
+	ChangeReissue = "reissue" // 域名或证书类型变化，重新签发
+	ChangeRenew   = "renew"   // 证书到期续期
+	ChangeConfig  = "config"  // 写入或启用 Web 服务器站点配置
+)
+
+// Change install 将要执行的变更（用于 --check/--diff）
+type Change struct {
+	Action string
+	Target string // 证书目录或站点配置文件
+	Reason string
+	Diff   string // 统一格式差异，没有可比较的内容时为空
+}
+
+// Plan 检查安装证书会产生的变更，不修改任何文件
+func (m *Manager) Plan() ([]Change, error) {
+	changes := planCertificate(m.getCertPath(), m.getECDSACertPath(), []string{m.domain}, m.dualCert, m.renewBefore)
+
+	if m.webServerType == WebServerNginx {
+		change, err := planSiteConfig(m.webServerConfig("nginx"))
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// Plan 检查安装多域名证书会产生的变更，不修改任何文件
+func (m *MultiDomainManager) Plan() ([]Change, error) {
+	changes := planCertificate(m.getCertPath(), m.getECDSACertPath(), m.domains, m.dualCert, m.renewBefore)
+
+	if m.webServerType == WebServerNginx {
+		change, err := planSiteConfig(m.webServerConfig("nginx"))
+		if err != nil {
+			return nil, err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+	}
+	return changes, nil
+}
+
+// planCertificate 比较已有证书与要求的域名、双证书设置和续期阈值
+func planCertificate(certPath, ecdsaCertPath string, domains []string, dualCert bool, renewBefore int) []Change {
+	existing, err := loadCertificate(certPath)
+	if err != nil {
+		return []Change{{Action: ChangeIssue, Target: certPath, Reason: "证书不存在"}}
+	}
+
+	current := append([]string{}, existing.DNSNames...)
+	wanted := append([]string{}, domains...)
+	sort.Strings(current)
+	sort.Strings(wanted)
+	if strings.Join(current, ",") != strings.Join(wanted, ",") {
+		return []Change{{
+			Action: ChangeReissue,
+			Target: certPath,
+			Reason: "证书域名与要求不一致",
+			Diff: webserver.UnifiedDiff("当前证书域名", "要求的域名",
+				strings.Join(current, "\n")+"\n", strings.Join(wanted, "\n")+"\n"),
+		}}
+	}
+
+	if dualCert && !fileExists(ecdsaCertPath) {
+		return []Change{{Action: ChangeReissue, Target: certPath, Reason: "缺少 ECDSA 证书（--dual-cert）"}}
+	}
+
+	info := &CertInfo{IssuedDate: existing.NotBefore, ExpiryDate: existing.NotAfter}
+	if info.NeedsRenewal(renewBefore) {
+		return []Change{{
+			Action: ChangeRenew,
+			Target: certPath,
+			Reason: fmt.Sprintf("证书将于 %s 到期（%s）", clock.Date(existing.NotAfter), clock.Relative(existing.NotAfter)),
+		}}
+	}
+	return nil
+}
+
+// planSiteConfig 比较现有站点配置与将要生成的配置
+func planSiteConfig(cfg *webserver.Config) (*Change, error) {
+	preview, err := webserver.PreviewSiteConfig(cfg)
+	if err != nil || preview == nil || !preview.Changed() {
+		return nil, err
+	}
+
+	change := &Change{Action: ChangeConfig, Target: preview.Path, Diff: preview.Diff()}
+	switch {
+	case preview.Drifted:
+		change.Reason = "站点配置已被手工修改，将拒绝覆盖（需要合并或 --overwrite-drift）"
+	case !preview.Exists:
+		change.Reason = "站点配置不存在"
+	case preview.Current != preview.Desired:
+		change.Reason = "站点配置内容变化"
+	default:
+		change.Reason = "站点未启用"
+	}
+	return change, nil
+}
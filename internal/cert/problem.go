@@ -0,0 +1,132 @@
+package cert
+
+import (
+	"autocert/internal/acme"
+	"autocert/internal/preflight"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// authzError 授权验证失败：CA 返回的问题详情、验证时的连接记录和排查建议
+type authzError struct {
+	challenge string
+	problem   *acme.Problem
+	records   []acme.ValidationRecord
+	hints     []string
+}
+
+func (e *authzError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CA 验证 %s 失败: %v", e.challenge, e.problem)
+	for _, record := range e.records {
+		target := record.Hostname
+		if record.URL != "" {
+			target = record.URL
+		}
+		fmt.Fprintf(&b, "\n    CA 访问 %s", target)
+		if record.AddressUsed != "" {
+			fmt.Fprintf(&b, "，连接的地址 %s", record.AddressUsed)
+		}
+		if len(record.AddressesResolved) > 0 {
+			fmt.Fprintf(&b, "（解析结果 %s）", strings.Join(record.AddressesResolved, ", "))
+		}
+	}
+	for _, hint := range e.hints {
+		b.WriteString("\n    建议: " + hint)
+	}
+	return b.String()
+}
+
+func (e *authzError) Unwrap() error {
+	return e.problem
+}
+
+// checkAuthorization 授权有效时返回 nil，否则把 CA 的问题详情和验证记录转换为带排查建议的错误
+func checkAuthorization(authz *acme.Authorization) error {
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	challenge := authz.FailedChallenge()
+	if challenge == nil || challenge.Error == nil {
+		return fmt.Errorf("授权状态为 %s", authz.Status)
+	}
+	return &authzError{
+		challenge: challenge.Type,
+		problem:   challenge.Error,
+		records:   challenge.ValidationRecord,
+		hints:     problemHints(challenge),
+	}
+}
+
+// problemHints 按错误类型和 CA 实际连接的地址给出排查建议
+func problemHints(challenge *acme.Challenge) []string {
+	var hints []string
+	problem := challenge.Error
+	detail := strings.ToLower(problem.Detail)
+	http01 := challenge.Type == "http-01"
+
+	// CA 连接的地址不是本机时，解析或代理把请求送到了其他主机
+	if used := lastAddressUsed(challenge.ValidationRecord); used != nil && http01 {
+		local := preflight.LocalAddresses()
+		conn := preflight.DetectConnectivity()
+		switch {
+		case local[used.String()]:
+		case used.To4() != nil && conn.IPv4 != nil && conn.IPv4.IsPrivate():
+			hints = append(hints, fmt.Sprintf("CA 连接的 %s 不是本机网卡地址；本机在 NAT 后时确认该地址的 80 端口转发到本机", used))
+		default:
+			hints = append(hints, fmt.Sprintf("CA 连接的 %s 不是本机，检查域名解析是否指向本机，或是否开启了 CDN 代理（例如 Cloudflare 的代理模式）", used))
+		}
+		if used.To4() == nil && problem.ShortType() == "connection" {
+			hints = append(hints, fmt.Sprintf("CA 通过 IPv6（%s）连接失败；域名有 AAAA 记录时 Let's Encrypt 优先使用 IPv6，检查 IPv6 防火墙或删除错误的 AAAA 记录", used))
+		}
+	}
+	if len(challenge.ValidationRecord) > 1 {
+		var urls []string
+		for _, record := range challenge.ValidationRecord {
+			urls = append(urls, record.URL)
+		}
+		hints = append(hints, "CA 跟随了重定向 "+strings.Join(urls, " → ")+"；确认重定向目标可访问且没有改写 /.well-known/acme-challenge/ 路径")
+	}
+
+	switch problem.ShortType() {
+	case "connection":
+		if strings.Contains(detail, "timeout") {
+			hints = append(hints, "连接超时，通常是防火墙、云服务器安全组或端口转发没有放行 80 端口")
+		} else {
+			hints = append(hints, "CA 无法连接到服务器，确认 80 端口对公网开放且 Web 服务正在运行")
+		}
+	case "unauthorized", "incorrectResponse":
+		switch {
+		case !http01:
+			hints = append(hints, "CA 查询到的 TXT 记录与预期不符，删除残留的旧 _acme-challenge 记录，并确认所有权威服务器已同步")
+		case strings.Contains(detail, "404"):
+			hints = append(hints, "CA 请求挑战文件返回 404，检查 webroot 是否为站点实际的根目录，以及是否有重写规则拦截 /.well-known/acme-challenge/")
+		case strings.Contains(detail, "403"):
+			hints = append(hints, "CA 请求挑战文件返回 403，检查挑战目录的权限和 Web 服务器的访问控制规则")
+		default:
+			hints = append(hints, "CA 收到的内容不是挑战响应，检查是否有其他站点或 CDN 缓存响应了该请求")
+		}
+	case "dns":
+		hints = append(hints, "CA 无法解析域名，检查域名的 NS 记录是否正确、DNSSEC 签名是否有效")
+	case "caa":
+		hints = append(hints, "域名的 CAA 记录不允许该 CA 签发，在 CAA 记录中加入 CA 的标识（Let's Encrypt 为 letsencrypt.org）")
+	case "rateLimited":
+		hints = append(hints, "已达到 CA 的频率限制，等待限制解除后再试，调试时请使用测试环境")
+	case "rejectedIdentifier":
+		hints = append(hints, "CA 拒绝为该域名签发证书（策略限制或高风险域名）")
+	case "serverInternal":
+		hints = append(hints, "CA 服务临时故障，稍后重试")
+	}
+	return hints
+}
+
+// lastAddressUsed 最后一跳（重定向后）CA 实际连接的地址
+func lastAddressUsed(records []acme.ValidationRecord) net.IP {
+	for i := len(records) - 1; i >= 0; i-- {
+		if ip := net.ParseIP(records[i].AddressUsed); ip != nil {
+			return ip
+		}
+	}
+	return nil
+}
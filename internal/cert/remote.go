@@ -0,0 +1,171 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/storage"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// remoteFiles 保存到持久化后端的证书目录文件
+var remoteFiles = append(append([]string{}, storeFiles...), "domains.txt")
+
+// remoteKeyPrefix 后端中证书文件的键前缀
+const remoteKeyPrefix = "certs"
+
+// uploadCertificate 将证书目录中的文件写入持久化后端（未配置后端时不执行）
+func uploadCertificate(dir string) error {
+	backend, err := storage.Open()
+	if err != nil || backend == nil {
+		return err
+	}
+	return PushCertificate(backend, dir)
+}
+
+// PushCertificate 将证书目录中的文件写入后端，后端中多余的旧文件（例如已不再使用的 ECDSA 证书）一并删除
+func PushCertificate(backend storage.Storage, dir string) error {
+	name := filepath.Base(dir)
+	prefix := storage.Key(remoteKeyPrefix, name) + "/"
+
+	existing, err := backend.List(prefix)
+	if err != nil {
+		return fmt.Errorf("读取存储后端失败: %w", err)
+	}
+
+	written := make(map[string]bool)
+	for _, file := range remoteFiles {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		key := prefix + file
+		if err := backend.Put(key, data); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", key, err)
+		}
+		written[key] = true
+	}
+
+	for _, key := range existing {
+		if !written[key] {
+			if err := backend.Delete(key); err != nil {
+				return fmt.Errorf("删除 %s 失败: %w", key, err)
+			}
+		}
+	}
+
+	logger.Debug("证书已写入存储后端", "name", name, "backend", backend.Name(), "files", len(written))
+	return nil
+}
+
+// RemoteCertificates 后端中保存的证书名称
+func RemoteCertificates(backend storage.Storage) ([]string, error) {
+	keys, err := backend.List(remoteKeyPrefix + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, key := range keys {
+		parts := strings.Split(strings.TrimPrefix(key, remoteKeyPrefix+"/"), "/")
+		if len(parts) != 2 || parts[0] == "." || parts[0] == ".." || seen[parts[0]] {
+			continue
+		}
+		seen[parts[0]] = true
+		names = append(names, parts[0])
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// PullCertificate 将后端中的证书恢复到本地证书目录，返回是否有文件发生变化
+func PullCertificate(backend storage.Storage, certRoot, name string) (bool, error) {
+	dir := filepath.Join(certRoot, name)
+	if err := ensureDirs(certRoot, dir); err != nil {
+		return false, err
+	}
+
+	changed := false
+	for _, file := range remoteFiles {
+		data, err := backend.Get(storage.Key(remoteKeyPrefix, name, file))
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return changed, fmt.Errorf("读取 %s/%s 失败: %w", name, file, err)
+		}
+
+		path := filepath.Join(dir, file)
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+			continue
+		}
+
+		// 证书库布局下目标是指向证书库的符号链接，先删除链接，避免改写证书库中的旧版本
+		os.Remove(path)
+		mode := os.FileMode(0644)
+		if strings.HasPrefix(file, "key") {
+			mode = 0600
+		}
+		if err := os.WriteFile(path, data, mode); err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+
+	if changed {
+		// 多域名证书目录带 _san 后缀，输出使用主域名
+		if err := writeOutput(dir, []string{strings.TrimSuffix(name, "_san")}); err != nil {
+			return changed, err
+		}
+		if err := storeCertificate(certRoot, dir); err != nil {
+			return changed, fmt.Errorf("保存到证书库失败: %w", err)
+		}
+	}
+	return changed, nil
+}
+
+// PullCertificates 将后端中的所有证书恢复到本地证书目录，返回发生变化的证书
+func PullCertificates(backend storage.Storage) ([]string, error) {
+	names, err := RemoteCertificates(backend)
+	if err != nil {
+		return nil, fmt.Errorf("读取存储后端失败: %w", err)
+	}
+
+	certRoot := config.GetCertDir()
+	var changed []string
+	for _, name := range names {
+		updated, err := PullCertificate(backend, certRoot, name)
+		if err != nil {
+			return changed, err
+		}
+		if updated {
+			changed = append(changed, name)
+		}
+	}
+	return changed, nil
+}
+
+// DeleteRemoteCertificate 从后端删除证书（未配置后端时不执行）
+func DeleteRemoteCertificate(name string) error {
+	backend, err := storage.Open()
+	if err != nil || backend == nil {
+		return err
+	}
+
+	keys, err := backend.List(storage.Key(remoteKeyPrefix, name) + "/")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := backend.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package cert
+
+import (
+	"autocert/internal/clock"
+	"time"
+)
+
+// renewAtRatio 未配置续期天数时，证书已使用有效期达到该比例时续期（90 天证书为剩余 30 天）
+const renewAtRatio = 2.0 / 3.0
+
+// RenewalThreshold 计算续期阈值：剩余有效期低于该值时需要续期。
+// 配置了续期天数时使用配置的天数；未配置（renewBeforeDays <= 0），或配置的天数不短于证书总有效期
+// （短期证书刚签发就会被判定为需要续期）时，在剩余总有效期的 1/3 时续期
+func RenewalThreshold(notBefore, notAfter time.Time, renewBeforeDays int) time.Duration {
+	threshold := time.Duration(renewBeforeDays) * 24 * time.Hour
+
+	lifetime := notAfter.Sub(notBefore)
+	if lifetime > 0 && (renewBeforeDays <= 0 || threshold >= lifetime) {
+		threshold = time.Duration(float64(lifetime) * (1 - renewAtRatio))
+	}
+
+	return threshold
+}
+
+// checkInterrupt 调用签发过程中的检查，未设置时返回 nil
+func checkInterrupt(check func() error) error {
+	if check == nil {
+		return nil
+	}
+	return check()
+}
+
+// NeedsRenewal 判断证书是否需要续期
+func (c *CertInfo) NeedsRenewal(renewBeforeDays int) bool {
+	return clock.Until(c.ExpiryDate) <= c.RenewalThreshold(renewBeforeDays)
+}
+
+// RenewalThreshold 计算该证书的续期阈值
+func (c *CertInfo) RenewalThreshold(renewBeforeDays int) time.Duration {
+	return RenewalThreshold(c.IssuedDate, c.ExpiryDate, renewBeforeDays)
+}
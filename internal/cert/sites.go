@@ -0,0 +1,24 @@
+package cert
+
+import (
+	"autocert/internal/webserver"
+	"path/filepath"
+)
+
+// SiteConfig 已保存证书对应的 Nginx 站点配置，用于生成共享 IP 的多站点配置
+func (s StoredCert) SiteConfig(webroot string) *webserver.Config {
+	cfg := &webserver.Config{
+		Type:     "nginx",
+		Domain:   s.Domains[0],
+		Aliases:  s.Domains[1:],
+		CertPath: filepath.Join(s.Dir, "cert.pem"),
+		KeyPath:  filepath.Join(s.Dir, "key.pem"),
+		WebRoot:  webroot,
+	}
+
+	if fileExists(filepath.Join(s.Dir, "cert-ecdsa.pem")) {
+		cfg.ECDSACertPath = filepath.Join(s.Dir, "cert-ecdsa.pem")
+		cfg.ECDSAKeyPath = filepath.Join(s.Dir, "key-ecdsa.pem")
+	}
+	return cfg
+}
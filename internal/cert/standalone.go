@@ -0,0 +1,221 @@
+package cert
+
+import (
+	"autocert/internal/challenge"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/system"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// challengePathPrefix http-01 挑战路径前缀
+const challengePathPrefix = "/.well-known/acme-challenge/"
+
+// standaloneServer Standalone 模式的临时 HTTP 服务器，同时进行的多个验证共用一个服务器，
+// 最后一个使用者释放后才关闭。分别监听 IPv4 和 IPv6：Let's Encrypt 在域名有 AAAA 记录时优先通过 IPv6 访问
+type standaloneServer struct {
+	server    *http.Server
+	listeners []net.Listener
+	shared    bool // 继承或预先绑定的套接字，在进程内一直保留，不随验证结束关闭
+	users     int  // 尚未释放的使用者数量，由 sharedMu 保护
+
+	mu     sync.Mutex
+	tokens map[string]string                     // token -> key authorization
+	stores map[*standaloneLease]*challenge.Store // 开启 challenge.shared 时，本机没有的 token 从共享存储查找
+}
+
+// standaloneLease 一次验证对 Standalone 服务器的使用，关闭时只清除自己设置的 token
+type standaloneLease struct {
+	server *standaloneServer
+	tokens []string
+	closed bool
+}
+
+var (
+	sharedMu     sync.Mutex
+	sharedServer *standaloneServer
+)
+
+// standalonePort 返回配置的 Standalone 端口
+func standalonePort() int {
+	if port := config.GetStandaloneConfig().Port; port > 0 {
+		return port
+	}
+	return 80
+}
+
+// startStandaloneServer 启动临时 HTTP 服务器，已有其他验证在使用时共用该服务器
+// 有继承的监听套接字（systemd 套接字激活或 standalone.listen_fds）或预先绑定的端口时直接使用，
+// 无需 root 权限绑定 80 端口；否则在 IPv4 和 IPv6 上分别绑定端口，任一协议族可用即可
+func startStandaloneServer() (*standaloneLease, error) {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if sharedServer == nil {
+		port := standalonePort()
+		if listeners := system.ListenersOnPort(config.GetStandaloneConfig().ListenFDs, port); len(listeners) > 0 {
+			sharedServer = newStandaloneServer()
+			sharedServer.shared = true
+			for _, listener := range listeners {
+				sharedServer.serve(listener)
+			}
+			logger.Debug("Standalone 服务器使用继承的套接字", "count", len(listeners))
+		} else {
+			s, err := bindStandaloneServer(port)
+			if err != nil {
+				return nil, err
+			}
+			sharedServer = s
+		}
+	}
+
+	sharedServer.users++
+	return &standaloneLease{server: sharedServer}, nil
+}
+
+// PrebindStandalone 预先绑定 Standalone 端口并在进程内保留，供降权后的 Standalone 验证使用
+func PrebindStandalone() error {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+
+	if sharedServer != nil {
+		return nil
+	}
+
+	s, err := bindStandaloneServer(standalonePort())
+	if err != nil {
+		return err
+	}
+	s.shared = true
+	sharedServer = s
+	return nil
+}
+
+// bindStandaloneServer 在 IPv4 和 IPv6 上分别绑定端口并启动服务器
+func bindStandaloneServer(port int) (*standaloneServer, error) {
+	s := newStandaloneServer()
+
+	var errs []error
+	for _, network := range []string{"tcp4", "tcp6"} {
+		listener, err := net.Listen(network, fmt.Sprintf(":%d", port))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", network, err))
+			continue
+		}
+		s.serve(listener)
+	}
+
+	if len(s.listeners) == 0 {
+		return nil, fmt.Errorf("无法监听端口 %d: %w", port, errors.Join(errs...))
+	}
+	for _, err := range errs {
+		logger.Debug("Standalone 服务器未监听该协议族", "error", err)
+	}
+
+	addrs := make([]string, len(s.listeners))
+	for i, listener := range s.listeners {
+		addrs[i] = listener.Addr().String()
+	}
+	logger.Debug("Standalone 服务器已启动", "listen", strings.Join(addrs, ", "))
+	return s, nil
+}
+
+// newStandaloneServer 创建临时服务器（未监听），每个请求都记录访问日志，非挑战路径按来源限流
+func newStandaloneServer() *standaloneServer {
+	s := &standaloneServer{tokens: make(map[string]string), stores: make(map[*standaloneLease]*challenge.Store)}
+	s.server = &http.Server{
+		Handler:           challenge.Protect("standalone", http.HandlerFunc(s.serveChallenge)),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	return s
+}
+
+// serve 在监听器上提供服务
+func (s *standaloneServer) serve(listener net.Listener) {
+	s.listeners = append(s.listeners, listener)
+	go s.server.Serve(listener)
+}
+
+// SetToken 设置挑战 token 对应的响应内容
+func (l *standaloneLease) SetToken(token, keyAuthorization string) {
+	l.server.mu.Lock()
+	defer l.server.mu.Unlock()
+	l.server.tokens[token] = keyAuthorization
+	l.tokens = append(l.tokens, token)
+}
+
+// SetStore 设置共享挑战存储：同一负载均衡后的多个节点各自签发时，可以响应其他节点发布的挑战
+func (l *standaloneLease) SetStore(store *challenge.Store) {
+	l.server.mu.Lock()
+	defer l.server.mu.Unlock()
+	l.server.stores[l] = store
+}
+
+// Close 清除本次验证设置的 token 和共享存储，最后一个使用者释放后关闭服务器
+// （继承或预先绑定的套接字留给后续验证使用）
+func (l *standaloneLease) Close() {
+	s := l.server
+	s.mu.Lock()
+	for _, token := range l.tokens {
+		delete(s.tokens, token)
+	}
+	l.tokens = nil
+	delete(s.stores, l)
+	s.mu.Unlock()
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if l.closed {
+		return
+	}
+	l.closed = true
+	s.users--
+	if s.users > 0 || s.shared {
+		return
+	}
+	s.server.Close()
+	if sharedServer == s {
+		sharedServer = nil
+	}
+	logger.Debug("Standalone 服务器已关闭")
+}
+
+// serveChallenge 响应 /.well-known/acme-challenge/<token> 请求，只响应已知的 token，其他请求返回 404
+func (s *standaloneServer) serveChallenge(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, challengePathPrefix)
+	if token == r.URL.Path {
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	keyAuthorization, ok := s.tokens[token]
+	var store *challenge.Store
+	for _, st := range s.stores {
+		store = st // 各次验证的共享存储来自同一配置
+		break
+	}
+	s.mu.Unlock()
+	if !ok && store != nil {
+		var err error
+		keyAuthorization, err = store.Lookup(token)
+		if err != nil && !errors.Is(err, challenge.ErrNotFound) {
+			logger.Warn("读取共享挑战失败", "token", token, "error", err)
+		}
+		ok = err == nil
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write([]byte(keyAuthorization))
+}
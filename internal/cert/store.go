@@ -0,0 +1,237 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 证书存储布局
+const (
+	LayoutFlat      = "flat"      // 证书文件直接保存在证书目录中
+	LayoutCanonical = "canonical" // 证书文件保存在内容寻址的证书库中，证书目录中为符号链接
+)
+
+// storeDirName 证书库目录名（位于证书根目录下）
+const storeDirName = ".store"
+
+// linksFile 证书目录中记录外部引用（autocert link）的文件
+const linksFile = "links.txt"
+
+// storeFiles 证书目录中需要放入证书库的文件
+var storeFiles = []string{"cert.pem", "key.pem", "chain.pem", "cert-ecdsa.pem", "key-ecdsa.pem", pfxFile}
+
+// canonicalLayout 是否使用证书库布局（Windows 创建符号链接需要额外权限，不支持）
+func canonicalLayout() bool {
+	if config.GetStorageConfig().Layout != LayoutCanonical {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		logger.Warn("Windows 不支持证书库布局，证书仍直接保存在证书目录中")
+		return false
+	}
+	return true
+}
+
+// storeCertificate 将证书目录中新写入的文件移入证书库，并在原位置创建指向证书库的符号链接
+// 证书库按证书内容寻址，续期后符号链接切换到新版本，旧版本由 autocert gc 清理
+func storeCertificate(certRoot, dir string) error {
+	if !canonicalLayout() {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "cert.pem"))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	version := hex.EncodeToString(sum[:16])
+	versionDir := filepath.Join(certRoot, storeDirName, version)
+
+	if err := ensureDirs(filepath.Join(certRoot, storeDirName), versionDir); err != nil {
+		return fmt.Errorf("创建证书库目录失败: %w", err)
+	}
+
+	for _, name := range storeFiles {
+		path := filepath.Join(dir, name)
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+
+		if err := os.Rename(path, filepath.Join(versionDir, name)); err != nil {
+			return fmt.Errorf("移动 %s 到证书库失败: %w", name, err)
+		}
+
+		target, err := filepath.Rel(dir, filepath.Join(versionDir, name))
+		if err != nil {
+			return err
+		}
+		if err := replaceSymlink(target, path); err != nil {
+			return fmt.Errorf("创建符号链接失败: %w", err)
+		}
+	}
+
+	logger.Debug("证书已放入证书库", "dir", dir, "version", version)
+	return nil
+}
+
+// replaceSymlink 原子地创建或替换符号链接
+func replaceSymlink(target, path string) error {
+	tmpPath := path + ".tmp-link"
+	os.Remove(tmpPath)
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// LinkConsumer 在目标目录中创建指向证书目录文件的符号链接，供其他服务共享同一张证书
+// 证书续期后链接自动指向新证书；链接位置记录在证书目录的 links.txt 中
+func (s StoredCert) LinkConsumer(dest string) ([]string, error) {
+	if runtime.GOOS == "windows" {
+		return nil, fmt.Errorf("Windows 暂不支持证书符号链接")
+	}
+
+	dest, err := filepath.Abs(dest)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, err
+	}
+
+	var linked []string
+	for _, name := range storeFiles {
+		source := filepath.Join(s.Dir, name)
+		if _, err := os.Stat(source); err != nil {
+			continue
+		}
+
+		target := filepath.Join(dest, name)
+		if err := replaceSymlink(source, target); err != nil {
+			return linked, fmt.Errorf("创建符号链接 %s 失败: %w", target, err)
+		}
+		linked = append(linked, target)
+	}
+
+	if err := recordLine(filepath.Join(s.Dir, linksFile), dest); err != nil {
+		return linked, err
+	}
+	return linked, nil
+}
+
+// Links 返回证书的外部引用位置
+func (s StoredCert) Links() []string {
+	return readLines(filepath.Join(s.Dir, linksFile))
+}
+
+// GCResult 垃圾回收结果
+type GCResult struct {
+	Removed []string // 已删除（或 dry-run 下将删除）的路径
+	Bytes   int64    // 回收的空间
+}
+
+// CollectStore 清理证书库中不再被任何证书目录引用的版本，以及已失效的外部引用记录
+// ignore 中的证书目录视为已删除（用于 dry-run 时预估删除证书目录后可回收的版本）
+func CollectStore(certRoot string, dryRun bool, ignore ...string) (*GCResult, error) {
+	result := &GCResult{}
+
+	certs, err := ListCertificates(certRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	ignored := make(map[string]bool)
+	for _, dir := range ignore {
+		ignored[dir] = true
+	}
+
+	// 收集仍被引用的证书库版本
+	referenced := make(map[string]bool)
+	for _, stored := range certs {
+		if ignored[stored.Dir] {
+			continue
+		}
+		for _, name := range storeFiles {
+			target, err := filepath.EvalSymlinks(filepath.Join(stored.Dir, name))
+			if err == nil {
+				referenced[filepath.Dir(target)] = true
+			}
+		}
+
+		if !dryRun {
+			pruneLinks(stored)
+		}
+	}
+
+	storeRoot := filepath.Join(certRoot, storeDirName)
+	entries, err := os.ReadDir(storeRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		versionDir := filepath.Join(storeRoot, entry.Name())
+		resolved, err := filepath.EvalSymlinks(versionDir)
+		if err != nil || referenced[resolved] {
+			continue
+		}
+
+		result.Bytes += dirSize(versionDir)
+		result.Removed = append(result.Removed, versionDir)
+		if dryRun {
+			continue
+		}
+		if err := os.RemoveAll(versionDir); err != nil {
+			return result, fmt.Errorf("删除 %s 失败: %w", versionDir, err)
+		}
+		logger.Info("已删除未引用的证书库版本", "path", versionDir)
+	}
+
+	return result, nil
+}
+
+// pruneLinks 删除已不再指向该证书的外部引用记录
+func pruneLinks(stored StoredCert) {
+	links := stored.Links()
+	if len(links) == 0 {
+		return
+	}
+
+	var kept []string
+	for _, dest := range links {
+		target, err := os.Readlink(filepath.Join(dest, "cert.pem"))
+		if err == nil && strings.HasPrefix(target, stored.Dir+string(filepath.Separator)) {
+			kept = append(kept, dest)
+		}
+	}
+
+	path := filepath.Join(stored.Dir, linksFile)
+	if len(kept) == 0 {
+		os.Remove(path)
+		return
+	}
+	os.WriteFile(path, []byte(strings.Join(kept, "\n")+"\n"), 0644)
+}
+
+// dirSize 计算目录占用空间
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,74 @@
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// keyPairs 证书目录中成对存放的证书和私钥
+var keyPairs = [][2]string{
+	{"cert.pem", "key.pem"},
+	{"cert-ecdsa.pem", "key-ecdsa.pem"},
+}
+
+// Verify 检查证书目录中的证书和私钥能否解析、私钥是否与证书公钥匹配
+// 双证书模式下同时检查 ECDSA 证书
+func (s StoredCert) Verify() error {
+	for i, pair := range keyPairs {
+		certPath := filepath.Join(s.Dir, pair[0])
+		keyPath := filepath.Join(s.Dir, pair[1])
+
+		// ECDSA 证书只在双证书模式下存在
+		if i > 0 && !fileExists(certPath) && !fileExists(keyPath) {
+			continue
+		}
+
+		if err := verifyKeyPair(certPath, keyPath); err != nil {
+			return fmt.Errorf("%s: %w", pair[0], err)
+		}
+	}
+	return nil
+}
+
+// verifyKeyPair 检查证书和私钥是否匹配
+func verifyKeyPair(certPath, keyPath string) error {
+	if info, err := os.Stat(certPath); err == nil && info.Size() == 0 {
+		return fmt.Errorf("证书文件为空")
+	}
+	if info, err := os.Stat(keyPath); err == nil && info.Size() == 0 {
+		return fmt.Errorf("私钥文件为空")
+	}
+
+	cert, err := loadCertificate(certPath)
+	if err != nil {
+		return err
+	}
+
+	key, err := loadPrivateKey(keyPath)
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(cert.PublicKey) {
+		return fmt.Errorf("私钥与证书公钥不匹配")
+	}
+	return nil
+}
+
+// Chain 读取证书目录中的证书链（叶子证书在前）。cert.pem 只有叶子证书时追加 chain.pem 中的中间证书
+func (s StoredCert) Chain() ([]*x509.Certificate, error) {
+	certs, err := loadCertificateChain(filepath.Join(s.Dir, "cert.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 1 {
+		if chain, err := loadCertificateChain(filepath.Join(s.Dir, "chain.pem")); err == nil {
+			certs = append(certs, chain...)
+		}
+	}
+	return certs, nil
+}
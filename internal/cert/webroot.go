@@ -0,0 +1,135 @@
+package cert
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/system"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// challengeDirMode 新建挑战目录的权限：Web 服务器用户可读
+const challengeDirMode = 0755
+
+// prepareChallengeDir 创建并修正 webroot 下的 .well-known/acme-challenge 目录，返回目录路径。
+// 目录所有者为配置的 webroot.user（默认与 webroot 目录一致），补上 Web 服务器读取需要的权限，
+// 并清理超过 webroot.stale_after 的残留挑战文件。未指定 webroot（例如早期签发的证书续期）时不处理
+func prepareChallengeDir(webroot string) (string, error) {
+	if webroot == "" {
+		logger.Debug("未指定 webroot 目录，跳过挑战目录检查")
+		return "", nil
+	}
+	if info, err := os.Stat(webroot); err != nil {
+		return "", fmt.Errorf("webroot 目录不可用: %w", err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("webroot 不是目录: %s", webroot)
+	}
+
+	wellKnown := filepath.Join(webroot, ".well-known")
+	dir := filepath.Join(wellKnown, "acme-challenge")
+	if err := os.MkdirAll(dir, challengeDirMode); err != nil {
+		return "", fmt.Errorf("创建挑战目录失败: %w", err)
+	}
+
+	for _, path := range []string{wellKnown, dir} {
+		if err := fixChallengeDirMode(path); err != nil {
+			return "", err
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		if err := chownChallengeDir(webroot, wellKnown, dir); err != nil {
+			logger.Warn("修改挑战目录所有者失败", "dir", dir, "error", err)
+		}
+	}
+
+	cleanStaleChallenges(dir, config.GetWebrootConfig().StaleAfter)
+	return dir, nil
+}
+
+// fixChallengeDirMode 保证 Web 服务器可以读取挑战目录：只补上缺少的读取和进入权限，
+// 已有的写权限保持不变（其他工具可能也通过组权限写入该目录）
+func fixChallengeDirMode(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s 不是目录", path)
+	}
+
+	mode := info.Mode().Perm()
+	fixed := mode | 0555
+	if mode == fixed || runtime.GOOS == "windows" {
+		return nil
+	}
+
+	logger.Warn("修正挑战目录权限", "dir", path, "from", fmt.Sprintf("%04o", mode), "to", fmt.Sprintf("%04o", fixed))
+	if err := os.Chmod(path, fixed); err != nil {
+		return fmt.Errorf("修改 %s 权限失败: %w", path, err)
+	}
+	return nil
+}
+
+// chownChallengeDir 将挑战目录的所有者改为配置的用户，未配置时与 webroot 目录一致
+func chownChallengeDir(webroot string, dirs ...string) error {
+	cfg := config.GetWebrootConfig()
+
+	var uid, gid int
+	if cfg.User != "" {
+		creds, err := system.LookupCredentials(cfg.User, cfg.Group)
+		if err != nil {
+			return err
+		}
+		uid, gid = creds.UID, creds.GID
+	} else {
+		var err error
+		if uid, gid, err = system.FileOwner(webroot); err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range dirs {
+		if owner, group, err := system.FileOwner(dir); err == nil && owner == uid && group == gid {
+			continue
+		}
+		if err := os.Lchown(dir, uid, gid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanStaleChallenges 删除挑战目录中超过 maxAge 的残留挑战文件
+// （中断的验证留下的 token 会让排查 webroot 失败更加困难）
+func cleanStaleChallenges(dir string, maxAge time.Duration) {
+	if maxAge <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			logger.Warn("清理残留挑战文件失败", "file", path, "error", err)
+			continue
+		}
+		logger.Debug("已清理残留挑战文件", "file", path, "modified", info.ModTime())
+	}
+}
@@ -0,0 +1,158 @@
+package cert
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WildcardCovers 泛域名是否覆盖该域名：*.example.com 只覆盖一级子域名，
+// 不覆盖 example.com 和 a.b.example.com
+func WildcardCovers(wildcard, domain string) bool {
+	base, ok := strings.CutPrefix(strings.ToLower(wildcard), "*.")
+	if !ok {
+		return false
+	}
+	label, rest, ok := strings.Cut(strings.ToLower(domain), ".")
+	return ok && label != "" && label != "*" && rest == base
+}
+
+// coveredBy 域名是否被证书的域名列表覆盖（精确匹配或泛域名匹配）
+func coveredBy(domains []string, domain string) bool {
+	for _, d := range domains {
+		if strings.EqualFold(d, domain) || WildcardCovers(d, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// SeparateCert 同时包含泛域名下某个主机名的其他证书
+type SeparateCert struct {
+	Name      string
+	NotAfter  time.Time
+	Redundant bool // 证书中的所有域名都已被泛域名证书覆盖
+	Renewing  bool // 已进入续期窗口，下次续期检查会重新签发
+}
+
+// Subdomain 泛域名下的主机名
+type Subdomain struct {
+	Name     string
+	Sources  []string // 提供服务的 Web 服务器配置或区域文件，只出现在证书中时为空
+	Covered  bool     // 被泛域名证书覆盖
+	Separate []SeparateCert
+}
+
+// WildcardInventory 泛域名证书及其下的主机名
+type WildcardInventory struct {
+	Cert       StoredCert
+	Wildcards  []string
+	NotAfter   time.Time
+	Subdomains []Subdomain
+}
+
+// InventoryWildcards 列出每张泛域名证书下正在提供服务的主机名（served 为主机名到来源的映射）
+// 以及其他证书中位于同一域名下的主机名，标出未被覆盖的主机名和多余的单独证书
+func InventoryWildcards(certRoot string, served map[string][]string) ([]WildcardInventory, error) {
+	stored, err := ListCertificates(certRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	type certInfo struct {
+		stored    StoredCert
+		domains   []string
+		notBefore time.Time
+		notAfter  time.Time
+	}
+	var certs []certInfo
+	for _, s := range stored {
+		info := certInfo{stored: s, domains: s.Domains}
+		if details, err := s.Details(); err == nil {
+			info.domains = details.Domains
+			info.notBefore = details.NotBefore
+			info.notAfter = details.NotAfter
+		}
+		certs = append(certs, info)
+	}
+
+	var inventories []WildcardInventory
+	for _, wc := range certs {
+		var wildcards []string
+		for _, domain := range wc.domains {
+			if strings.HasPrefix(domain, "*.") {
+				wildcards = append(wildcards, strings.ToLower(domain))
+			}
+		}
+		if len(wildcards) == 0 {
+			continue
+		}
+
+		// 泛域名的基础域名及其下所有层级的主机名
+		under := func(name string) bool {
+			for _, wildcard := range wildcards {
+				base := strings.TrimPrefix(wildcard, "*.")
+				if name == base || strings.HasSuffix(name, "."+base) {
+					return true
+				}
+			}
+			return false
+		}
+
+		subdomains := make(map[string]*Subdomain)
+		get := func(name string) *Subdomain {
+			if sub, ok := subdomains[name]; ok {
+				return sub
+			}
+			sub := &Subdomain{Name: name, Covered: coveredBy(wc.domains, name)}
+			subdomains[name] = sub
+			return sub
+		}
+
+		for name, sources := range served {
+			if under(name) {
+				get(name).Sources = sources
+			}
+		}
+
+		for _, other := range certs {
+			if other.stored.Name == wc.stored.Name {
+				continue
+			}
+			redundant := true
+			for _, domain := range other.domains {
+				if !coveredBy(wc.domains, domain) {
+					redundant = false
+				}
+			}
+			renewBefore := RenewalThreshold(other.notBefore, other.notAfter, config.GetRenewBeforeDays(other.stored.Domains[0]))
+			separate := SeparateCert{
+				Name:      other.stored.Name,
+				NotAfter:  other.notAfter,
+				Redundant: redundant,
+				Renewing:  !other.notAfter.IsZero() && clock.Now().Add(renewBefore).After(other.notAfter),
+			}
+
+			for _, domain := range other.domains {
+				name := strings.ToLower(domain)
+				if strings.HasPrefix(name, "*.") || !under(name) {
+					continue
+				}
+				sub := get(name)
+				sub.Separate = append(sub.Separate, separate)
+			}
+		}
+
+		inventory := WildcardInventory{Cert: wc.stored, Wildcards: wildcards, NotAfter: wc.notAfter}
+		for _, sub := range subdomains {
+			inventory.Subdomains = append(inventory.Subdomains, *sub)
+		}
+		sort.Slice(inventory.Subdomains, func(i, j int) bool {
+			return inventory.Subdomains[i].Name < inventory.Subdomains[j].Name
+		})
+		inventories = append(inventories, inventory)
+	}
+	return inventories, nil
+}
@@ -0,0 +1,294 @@
+// Package certsync 通过 SSH 将证书目录增量同步到其他主机：比较两端文件的 SHA-256，
+// 只传输有变化的证书和私钥文件，比完整的导出/导入更轻量，适合定期复制。
+// 使用系统的 ssh 命令（沿用 ~/.ssh/config、known_hosts 和 ssh-agent），目标主机需要 sh、tar 和 sha256sum（或 shasum）
+package certsync
+
+import (
+	"archive/tar"
+	"autocert/internal/logger"
+	"autocert/internal/secmem"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Target 同步目标
+type Target struct {
+	Host     string // [user@]host
+	Dir      string // 目标主机上的证书目录
+	Port     int
+	Identity string
+	Reload   string // 有文件变化时在目标主机上执行的命令
+	Delete   bool   // 删除目标主机上本地已不存在的文件
+}
+
+// ParseDestination 解析 [user@]host:/path 格式的目标位置
+func ParseDestination(to string) (host, dir string, err error) {
+	host, dir, ok := strings.Cut(to, ":")
+	if !ok || host == "" || dir == "" {
+		return "", "", fmt.Errorf("无效的同步目标 %q，格式为 [user@]host:/path", to)
+	}
+	return host, dir, nil
+}
+
+// Result 同步结果
+type Result struct {
+	Uploaded  []string // 新增或有变化的文件（相对于证书目录）
+	Deleted   []string // 目标主机上删除的文件
+	Unchanged int
+	Reloaded  bool
+}
+
+// Changed 是否有文件变化
+func (r *Result) Changed() bool {
+	return len(r.Uploaded) > 0 || len(r.Deleted) > 0
+}
+
+// Sync 将本地目录 localDir 同步到目标主机的 target.Dir。dryRun 时只比较不传输
+func Sync(ctx context.Context, localDir string, target Target, dryRun bool) (*Result, error) {
+	local, err := localHashes(localDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取本地证书目录失败: %w", err)
+	}
+	remote, err := remoteHashes(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("读取目标主机 %s 的文件列表失败: %w", target.Host, err)
+	}
+
+	result := &Result{}
+	for name, hash := range local {
+		if remote[name] == hash {
+			result.Unchanged++
+		} else {
+			result.Uploaded = append(result.Uploaded, name)
+		}
+	}
+	if target.Delete {
+		for name := range remote {
+			if _, ok := local[name]; !ok {
+				result.Deleted = append(result.Deleted, name)
+			}
+		}
+	}
+	sort.Strings(result.Uploaded)
+	sort.Strings(result.Deleted)
+
+	logger.Info("比较证书目录", "target", target.Host+":"+target.Dir,
+		"upload", len(result.Uploaded), "delete", len(result.Deleted), "unchanged", result.Unchanged)
+	if dryRun || !result.Changed() {
+		return result, nil
+	}
+
+	if len(result.Uploaded) > 0 {
+		if err := upload(ctx, localDir, target, result.Uploaded); err != nil {
+			return nil, fmt.Errorf("上传到 %s 失败: %w", target.Host, err)
+		}
+	}
+	if len(result.Deleted) > 0 {
+		if err := remove(ctx, target, result.Deleted); err != nil {
+			return nil, fmt.Errorf("删除 %s 上的文件失败: %w", target.Host, err)
+		}
+	}
+
+	if target.Reload != "" {
+		logger.Info("在目标主机上重新加载", "host", target.Host, "command", target.Reload)
+		if _, err := RunSSH(ctx, target, target.Reload, nil); err != nil {
+			return result, fmt.Errorf("文件已同步，但在 %s 上执行重新加载命令失败: %w", target.Host, err)
+		}
+		result.Reloaded = true
+	}
+	return result, nil
+}
+
+// skipDirs 不同步的内部目录：证书存储（证书文件是指向其中的符号链接，同步的是链接指向的内容）和归档副本
+var skipDirs = map[string]bool{".store": true, ".archive": true}
+
+// localHashes 本地目录中所有文件的 SHA-256（相对路径使用 /）。符号链接按指向的文件计算，
+// 上传时同样传输指向的内容，跳过 skipDirs 中的目录
+func localHashes(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			info, err := os.Stat(p)
+			if err != nil {
+				logger.Warn("跳过无效的符号链接", "path", p, "error", err)
+				return nil
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		hashes[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	return hashes, err
+}
+
+func fileSHA256(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := secmem.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remoteHashes 目标目录中所有文件的 SHA-256，目录不存在时为空。与本地一致，符号链接按指向的文件计算，
+// 跳过 skipDirs 中的目录
+func remoteHashes(ctx context.Context, target Target) (map[string]string, error) {
+	var prune []string
+	for name := range skipDirs {
+		prune = append(prune, "-name "+ShellQuote(name))
+	}
+	sort.Strings(prune)
+	find := fmt.Sprintf(`find -L . -type d \( %s \) -prune -o -type f`, strings.Join(prune, " -o "))
+	script := fmt.Sprintf(`cd %s 2>/dev/null || exit 0
+if command -v sha256sum >/dev/null 2>&1; then %s -exec sha256sum {} +
+else %s -exec shasum -a 256 {} +; fi`, ShellQuote(target.Dir), find, find)
+	out, err := RunSSH(ctx, target, script, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// 格式: <hash>  ./<path>；文件名含特殊字符时 sha256sum 以反斜杠开头转义，这类文件总是重新上传
+		hash, name, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok || strings.HasPrefix(hash, `\`) {
+			continue
+		}
+		hashes[strings.TrimPrefix(name, "./")] = hash
+	}
+	return hashes, scanner.Err()
+}
+
+// upload 将文件打包为 tar 通过 SSH 传到目标主机解压，保留文件权限（私钥保持 0600）
+func upload(ctx context.Context, localDir string, target Target, names []string) error {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeTar(writer, localDir, names))
+	}()
+
+	dir := ShellQuote(target.Dir)
+	_, err := RunSSH(ctx, target, fmt.Sprintf("mkdir -p %s && tar -xof - -C %s", dir, dir), reader)
+	reader.Close()
+	return err
+}
+
+func writeTar(w io.Writer, localDir string, names []string) error {
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		if err := addFile(tw, localDir, name); err != nil {
+			return err
+		}
+		logger.Debug("上传文件", "file", name)
+	}
+	return tw.Close()
+}
+
+func addFile(tw *tar.Writer, localDir, name string) error {
+	f, err := os.Open(filepath.Join(localDir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Name:    name,
+		Size:    info.Size(),
+		Mode:    int64(info.Mode().Perm()),
+		ModTime: info.ModTime(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = secmem.Copy(tw, f)
+	return err
+}
+
+// remove 删除目标主机上的文件
+func remove(ctx context.Context, target Target, names []string) error {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		if path.IsAbs(name) || strings.HasPrefix(path.Clean(name), "..") {
+			return fmt.Errorf("不安全的路径: %s", name)
+		}
+		quoted = append(quoted, ShellQuote(name))
+	}
+	_, err := RunSSH(ctx, target, fmt.Sprintf("cd %s && rm -f -- %s", ShellQuote(target.Dir), strings.Join(quoted, " ")), nil)
+	return err
+}
+
+// RunSSH 通过系统的 ssh 命令（非交互模式）在目标主机上执行命令，返回标准输出
+func RunSSH(ctx context.Context, target Target, command string, stdin io.Reader) ([]byte, error) {
+	args := []string{"-o", "BatchMode=yes"}
+	if target.Port != 0 {
+		args = append(args, "-p", fmt.Sprint(target.Port))
+	}
+	if target.Identity != "" {
+		args = append(args, "-i", target.Identity)
+	}
+	args = append(args, target.Host, command)
+
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("未找到 ssh 命令: %w", err)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// ShellQuote 用单引号包裹参数，供目标主机的 sh 解析
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,146 @@
+// Package challenge 负载均衡后多台主机共享 http-01 挑战：签发节点将 token 发布到共享存储，
+// 每台主机运行 challenge-responder（或 Standalone 服务器）从存储中读取并响应，
+// CA 的验证请求无论到达哪台主机都能通过。存储可以是 storage.backend、共享目录或 Redis。
+package challenge
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PathPrefix http-01 挑战路径前缀
+const PathPrefix = "/.well-known/acme-challenge/"
+
+// ErrNotFound 挑战不存在或已过期
+var ErrNotFound = errors.New("挑战不存在或已过期")
+
+// tokenPattern ACME token 只包含 base64url 字符，同时防止拼接存储键时出现路径
+var tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// ValidToken token 格式是否有效
+func ValidToken(token string) bool {
+	return tokenPattern.MatchString(token)
+}
+
+// 共享挑战的存储类型
+const (
+	BackendStorage = "storage"
+	BackendFS      = "fs"
+	BackendRedis   = "redis"
+)
+
+// Backend 共享挑战的存储后端，传入的 token 已校验格式
+type Backend interface {
+	Name() string
+	// Put 保存挑战，ttl 后不再返回
+	Put(token, keyAuthorization string, ttl time.Duration) error
+	// Get 读取挑战，不存在或已过期时返回 ErrNotFound
+	Get(token string) (string, error)
+	Delete(token string) error
+}
+
+// Store 共享挑战存储
+type Store struct {
+	backend Backend
+	ttl     time.Duration
+}
+
+// Open 按配置 challenge.store 打开共享挑战存储
+func Open() (*Store, error) {
+	cfg := config.GetChallengeConfig()
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+
+	var backend Backend
+	var err error
+	switch cfg.Store {
+	case "", BackendStorage:
+		backend, err = newStorageBackend()
+	case BackendFS:
+		backend, err = newFSBackend(cfg.FS, ttl)
+	case BackendRedis:
+		backend, err = newRedisBackend(cfg.Redis)
+	default:
+		return nil, fmt.Errorf("不支持的挑战存储: %s（可选 storage、fs、redis）", cfg.Store)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Store{backend: backend, ttl: ttl}, nil
+}
+
+// Name 存储后端名称
+func (s *Store) Name() string {
+	return s.backend.Name()
+}
+
+// Publish 发布挑战，在 TTL 内由各节点的 challenge-responder 或 Standalone 服务器响应
+func (s *Store) Publish(domain, token, keyAuthorization string) error {
+	if !ValidToken(token) {
+		return fmt.Errorf("无效的挑战 token: %q", token)
+	}
+	if err := s.backend.Put(token, keyAuthorization, s.ttl); err != nil {
+		return fmt.Errorf("发布 http-01 挑战失败: %w", err)
+	}
+	logger.Debug("已发布共享 http-01 挑战", "domain", domain, "token", token, "backend", s.backend.Name())
+	return nil
+}
+
+// Remove 删除挑战，不存在时不报错
+func (s *Store) Remove(token string) error {
+	if !ValidToken(token) {
+		return nil
+	}
+	return s.backend.Delete(token)
+}
+
+// Lookup 查找挑战的 key authorization，不存在或已过期时返回 ErrNotFound
+func (s *Store) Lookup(token string) (string, error) {
+	if !ValidToken(token) {
+		return "", ErrNotFound
+	}
+	return s.backend.Get(token)
+}
+
+// HealthPath challenge-responder 的健康检查路径
+const HealthPath = "/healthz"
+
+// Responder 响应 /.well-known/acme-challenge/<token> 请求的 HTTP 处理器，只响应存储中已知的 token，
+// HealthPath 供负载均衡器做健康检查，其他路径返回 404；每个请求都记录访问日志，非挑战路径按来源限流
+func Responder(store *Store) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc(PathPrefix, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		token := strings.TrimPrefix(r.URL.Path, PathPrefix)
+		keyAuthorization, err := store.Lookup(token)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			// 存储暂时不可用时返回 503，CA 会在重试时再次请求
+			logger.Warn("读取共享挑战失败", "token", token, "error", err)
+			http.Error(w, "challenge store unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte(keyAuthorization))
+	})
+	mux.HandleFunc("/", http.NotFound)
+	return Protect("challenge-responder", mux, HealthPath)
+}
@@ -0,0 +1,83 @@
+package challenge
+
+import (
+	"autocert/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fsBackend 共享目录（例如 NFS 挂载的 webroot 挑战目录）。文件名为 token、内容为 key authorization，
+// 与 webroot 模式的挑战文件格式相同，各台 Web 服务器可以直接提供这些文件，无需运行 challenge-responder。
+// 文件没有过期机制，按修改时间判断是否超过 TTL
+type fsBackend struct {
+	dir string
+	ttl time.Duration
+}
+
+func newFSBackend(cfg config.ChallengeFSConfig, ttl time.Duration) (*fsBackend, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("challenge.store 为 fs 时需要配置 challenge.fs.path")
+	}
+	// Web 服务器需要读取挑战文件，目录和文件对其他用户可读
+	if err := os.MkdirAll(cfg.Path, 0755); err != nil {
+		return nil, fmt.Errorf("创建挑战目录失败: %w", err)
+	}
+	return &fsBackend{dir: cfg.Path, ttl: ttl}, nil
+}
+
+func (b *fsBackend) Name() string {
+	return "fs"
+}
+
+// Put 先写入临时文件再重命名，其他主机不会读到不完整的内容
+func (b *fsBackend) Put(token, keyAuthorization string, ttl time.Duration) error {
+	tmp, err := os.CreateTemp(b.dir, ".tmp-"+token+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(keyAuthorization); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(b.dir, token))
+}
+
+func (b *fsBackend) Get(token string) (string, error) {
+	path := filepath.Join(b.dir, token)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if time.Since(info.ModTime()) > b.ttl {
+		return "", ErrNotFound
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	return string(data), err
+}
+
+func (b *fsBackend) Delete(token string) error {
+	err := os.Remove(filepath.Join(b.dir, token))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,242 @@
+package challenge
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRateLimit 非挑战路径每个来源每分钟的默认请求数上限
+const defaultRateLimit = 30
+
+// rateWindow 限流的时间窗口
+const rateWindow = time.Minute
+
+// maxLoggedPath 访问日志中路径的最大长度，扫描器发送的超长路径截断后记录
+const maxLoggedPath = 256
+
+// Protect 为挑战 HTTP 服务器（Standalone 服务器和 challenge-responder）记录每个请求的访问日志，
+// 并按来源 IP 限制非挑战路径和未知挑战 token 的请求频率（challenge.rate_limit），超过时返回 429。
+// 验证期间暴露在公网的 80 端口因此不会成为没有记录的盲区；exempt 中的路径（例如负载均衡器的
+// 健康检查）不限流，只记录调试日志
+func Protect(server string, next http.Handler, exempt ...string) http.Handler {
+	limit := config.GetChallengeConfig().RateLimit
+	if limit == 0 {
+		limit = defaultRateLimit
+	}
+	g := &guard{server: server, next: next, exempt: exempt}
+	if limit > 0 {
+		g.limiter = newLimiter(limit, rateWindow)
+		g.misses = newLimiter(limit, rateWindow)
+	}
+	return g
+}
+
+type guard struct {
+	server  string
+	next    http.Handler
+	exempt  []string
+	limiter *limiter // 为 nil 时不限流
+	misses  *limiter // 未知挑战 token（404）的计数，为 nil 时不限流
+}
+
+func (g *guard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ip := remoteIP(r.RemoteAddr)
+	token, isChallenge := strings.CutPrefix(r.URL.Path, PathPrefix)
+	exempt := g.isExempt(r.URL.Path)
+
+	fields := []interface{}{
+		"server", g.server,
+		"remote", ip,
+		"method", r.Method,
+		"host", r.Host,
+		"user_agent", r.UserAgent(),
+	}
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		// 只记录，不用于限流：该请求头可以由客户端任意伪造
+		fields = append(fields, "forwarded", forwarded)
+	}
+	if isChallenge {
+		fields = append(fields, "token", truncate(token))
+	} else {
+		fields = append(fields, "path", truncate(r.URL.Path))
+	}
+
+	if !isChallenge && !exempt && g.limiter != nil {
+		allowed, retryAfter, first := g.limiter.allow(limitKey(ip), time.Now())
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+0.5)))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			// 每个来源在一个时间窗口内只警告一次，避免扫描时日志刷屏
+			if first {
+				logger.Warn("挑战服务器非挑战路径请求过于频繁，已限流", append(fields, "limit", g.limiter.limit)...)
+			} else {
+				logger.Debug("挑战服务器请求被限流", append(fields, "status", http.StatusTooManyRequests)...)
+			}
+			return
+		}
+	}
+
+	// 挑战路径只统计返回 404 的未知 token：猜测 token 的来源超过上限后返回 429，
+	// CA 的验证请求只访问已发布的 token，不会被计数
+	if isChallenge && g.misses != nil {
+		if limited, retryAfter := g.misses.exceeded(limitKey(ip), time.Now()); limited {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds()+0.5)))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			logger.Debug("挑战服务器请求被限流", append(fields, "status", http.StatusTooManyRequests)...)
+			return
+		}
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	g.next.ServeHTTP(recorder, r)
+	fields = append(fields, "status", recorder.status)
+
+	switch {
+	case exempt:
+		logger.Debug("挑战服务器请求", fields...)
+	case isChallenge && recorder.status == http.StatusOK:
+		logger.Info("响应 http-01 挑战", fields...)
+	case isChallenge && recorder.status == http.StatusNotFound:
+		g.reportMiss(ip, fields)
+	case isChallenge:
+		logger.Warn("挑战请求未成功响应", fields...)
+	default:
+		logger.Info("挑战服务器收到非挑战路径请求", fields...)
+	}
+}
+
+// reportMiss 记录未知挑战 token 的请求：计入来源的限流计数，超过上限时（每个来源每个窗口）
+// 警告一次，之后的请求返回 429，只记录调试日志
+func (g *guard) reportMiss(ip string, fields []interface{}) {
+	if g.misses == nil {
+		logger.Warn("请求了未知的挑战 token", fields...)
+		return
+	}
+	allowed, _, first := g.misses.allow(limitKey(ip), time.Now())
+	switch {
+	case allowed:
+		logger.Warn("请求了未知的挑战 token", fields...)
+	case first:
+		logger.Warn("挑战服务器未知 token 请求过于频繁，已限流", append(fields, "limit", g.misses.limit)...)
+	}
+}
+
+func (g *guard) isExempt(path string) bool {
+	for _, p := range g.exempt {
+		if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// limiter 按来源的固定窗口计数限流
+type limiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*rateClient
+	swept   time.Time
+}
+
+type rateClient struct {
+	start   time.Time
+	count   int
+	limited bool // 当前窗口内已被限流
+}
+
+func newLimiter(limit int, window time.Duration) *limiter {
+	return &limiter{limit: limit, window: window, clients: make(map[string]*rateClient)}
+}
+
+// allow 返回请求是否允许；不允许时返回距窗口结束的时间，以及是否为该来源在当前窗口内第一次被限流
+func (l *limiter) allow(key string, now time.Time) (bool, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 每个窗口清理一次过期的来源，扫描大量来源时内存不会持续增长
+	if now.Sub(l.swept) >= l.window {
+		for k, c := range l.clients {
+			if now.Sub(c.start) >= l.window {
+				delete(l.clients, k)
+			}
+		}
+		l.swept = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok || now.Sub(c.start) >= l.window {
+		c = &rateClient{start: now}
+		l.clients[key] = c
+	}
+	c.count++
+	if c.count <= l.limit {
+		return true, 0, false
+	}
+	first := !c.limited
+	c.limited = true
+	return false, c.start.Add(l.window).Sub(now), first
+}
+
+// exceeded 来源在当前窗口内的计数是否已超过上限（不计数），超过时返回距窗口结束的时间
+func (l *limiter) exceeded(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.clients[key]
+	if !ok || now.Sub(c.start) >= l.window || c.count <= l.limit {
+		return false, 0
+	}
+	return true, c.start.Add(l.window).Sub(now)
+}
+
+// remoteIP 从 RemoteAddr 中取出 IP
+func remoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// limitKey 限流的来源键：IPv4 按地址，IPv6 按 /64 前缀（同一主机通常拥有整个 /64）
+func limitKey(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return addr.String()
+	}
+	prefix, err := addr.Prefix(64)
+	if err != nil {
+		return addr.String()
+	}
+	return prefix.String()
+}
+
+func truncate(s string) string {
+	if len(s) > maxLoggedPath {
+		return s[:maxLoggedPath] + "..."
+	}
+	return s
+}
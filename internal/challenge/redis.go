@@ -0,0 +1,51 @@
+package challenge
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/redis"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// redisBackend Redis，挑战以 SET ... PX 写入并由 Redis 自动过期
+type redisBackend struct {
+	client *redis.Client
+	prefix string
+}
+
+func newRedisBackend(cfg config.RedisConfig) (*redisBackend, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("challenge.store 为 redis 时需要配置 challenge.redis.address")
+	}
+	client, err := redis.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &redisBackend{client: client, prefix: cfg.Prefix}, nil
+}
+
+func (b *redisBackend) Name() string {
+	return "redis"
+}
+
+func (b *redisBackend) Put(token, keyAuthorization string, ttl time.Duration) error {
+	_, err := b.client.Do("SET", b.prefix+token, keyAuthorization, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (b *redisBackend) Get(token string) (string, error) {
+	reply, err := b.client.Do("GET", b.prefix+token)
+	if err != nil {
+		return "", err
+	}
+	if reply == nil {
+		return "", ErrNotFound
+	}
+	return *reply, nil
+}
+
+func (b *redisBackend) Delete(token string) error {
+	_, err := b.client.Do("DEL", b.prefix+token)
+	return err
+}
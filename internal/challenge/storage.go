@@ -0,0 +1,73 @@
+package challenge
+
+import (
+	"autocert/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// storageKeyPrefix 存储后端中挑战的键前缀
+const storageKeyPrefix = "acme-challenge/"
+
+// record 存储后端中的挑战。S3 等后端没有过期机制，过期时间随内容保存
+type record struct {
+	KeyAuthorization string    `json:"key_authorization"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+// storageBackend 使用证书持久化后端（storage.backend：S3、etcd、Consul 或共享目录）
+type storageBackend struct {
+	backend storage.Storage
+}
+
+func newStorageBackend() (*storageBackend, error) {
+	backend, err := storage.Open()
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return nil, errors.New("challenge.store 为 storage 时需要配置存储后端（storage.backend）")
+	}
+	return &storageBackend{backend: backend}, nil
+}
+
+func (b *storageBackend) Name() string {
+	return b.backend.Name()
+}
+
+func (b *storageBackend) Put(token, keyAuthorization string, ttl time.Duration) error {
+	data, err := json.Marshal(record{KeyAuthorization: keyAuthorization, ExpiresAt: time.Now().Add(ttl)})
+	if err != nil {
+		return err
+	}
+	return b.backend.Put(storageKeyPrefix+token, data)
+}
+
+func (b *storageBackend) Get(token string) (string, error) {
+	data, err := b.backend.Get(storageKeyPrefix + token)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return "", fmt.Errorf("解析挑战 %s 失败: %w", token, err)
+	}
+	if time.Now().After(r.ExpiresAt) {
+		return "", ErrNotFound
+	}
+	return r.KeyAuthorization, nil
+}
+
+func (b *storageBackend) Delete(token string) error {
+	err := b.backend.Delete(storageKeyPrefix + token)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+)
+
+// offset 模拟时间与真实时间的差值（仅用于到期判断的诊断和测试）
+var offset time.Duration
+
+// Now 返回当前时间，设置模拟时间后返回模拟时间
+func Now() time.Time {
+	return time.Now().Add(offset)
+}
+
+// Until 返回距离指定时间的间隔（基于 Now）
+func Until(t time.Time) time.Duration {
+	return t.Sub(Now())
+}
+
+// SetFakeNow 设置模拟的当前时间，之后的时间按真实流逝继续推进
+func SetFakeNow(t time.Time) {
+	offset = time.Until(t)
+}
+
+// IsFake 是否设置了模拟时间
+func IsFake() bool {
+	return offset != 0
+}
+
+// ParseFakeNow 解析模拟时间，支持 2006-01-02、2006-01-02 15:04 和 RFC3339 格式
+func ParseFakeNow(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无效的时间格式: %s（示例: 2025-12-01）", value)
+}
@@ -0,0 +1,78 @@
+package clock
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // Windows 和精简的容器镜像中可能没有时区数据库
+)
+
+// location 显示时间使用的时区（配置 timezone），默认为系统本地时区
+var location = time.Local
+
+// SetLocation 设置显示时间使用的时区，例如 UTC、Asia/Shanghai；为空或 Local 时使用系统本地时区
+func SetLocation(name string) error {
+	if name == "" || name == "Local" {
+		location = time.Local
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("无效的时区 %q: %w", name, err)
+	}
+	location = loc
+	return nil
+}
+
+// Location 显示时间使用的时区
+func Location() *time.Location {
+	return location
+}
+
+// In 转换到显示时区
+func In(t time.Time) time.Time {
+	return t.In(location)
+}
+
+// Format 以 RFC3339 格式显示时间（显示时区，精确到秒），零值显示为 -
+func Format(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(location).Format(time.RFC3339)
+}
+
+// Date 显示时区中的日期，用于表格等空间有限的位置
+func Date(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.In(location).Format("2006-01-02")
+}
+
+// Relative 相对当前时间的描述，例如 23 天后、5 小时前
+func Relative(t time.Time) string {
+	d := Until(t)
+	suffix := "后"
+	if d < 0 {
+		d, suffix = -d, "前"
+	}
+
+	switch {
+	case d < time.Minute:
+		return "刚刚"
+	case d < time.Hour:
+		return fmt.Sprintf("%d 分钟%s", int(d/time.Minute), suffix)
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d 小时%s", int(d/time.Hour), suffix)
+	default:
+		return fmt.Sprintf("%d 天%s", int(d/(24*time.Hour)), suffix)
+	}
+}
+
+// FormatRelative RFC3339 时间加相对描述，例如 2025-12-01T08:00:00+08:00（23 天后）
+func FormatRelative(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return fmt.Sprintf("%s（%s）", Format(t), Relative(t))
+}
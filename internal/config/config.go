@@ -1,9 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -16,6 +21,27 @@ type Config struct {
 	CertDir   string `mapstructure:"cert_dir"`
 	LogDir    string `mapstructure:"log_dir"`
 
+	// 显示时间（日志、状态、报告和通知）使用的时区，例如 UTC、Asia/Shanghai，默认为系统本地时区
+	Timezone string `mapstructure:"timezone"`
+
+	// 合并的其他配置文件（支持通配符，例如 conf.d/*.yaml），相对路径相对于当前配置文件所在目录
+	Include []string `mapstructure:"include"`
+
+	// 续期配置：证书剩余有效期少于该天数时续期，未配置时在剩余总有效期的 1/3 时续期
+	RenewBeforeDays int `mapstructure:"renew_before_days"`
+
+	// 续期检查发现证书损坏或与私钥不匹配时自动重新签发
+	SelfHeal bool `mapstructure:"self_heal"`
+
+	// 按域名的配置覆盖
+	Domains []DomainConfig `mapstructure:"domains"`
+
+	// 证书签发/续期后执行的部署钩子（域名配置了 hooks 时使用域名的配置）
+	Hooks []HookConfig `mapstructure:"hooks"`
+
+	// 证书签发/续期后安装证书的部署目标，在部署钩子之前执行（域名配置了 deploy 时使用域名的配置）
+	Deploy []DeployConfig `mapstructure:"deploy"`
+
 	// ACME 配置
 	ACME ACMEConfig `mapstructure:"acme"`
 
@@ -24,6 +50,438 @@ type Config struct {
 
 	// Web 服务器配置
 	WebServer WebServerConfig `mapstructure:"webserver"`
+
+	// 守护进程配置
+	Daemon DaemonConfig `mapstructure:"daemon"`
+
+	// 事件转发配置（SIEM/syslog）
+	Events EventsConfig `mapstructure:"events"`
+
+	// 本地使用统计（默认关闭）
+	Stats StatsConfig `mapstructure:"stats"`
+
+	// 证书存储配置
+	Storage StorageConfig `mapstructure:"storage"`
+
+	// Standalone 验证配置
+	Standalone StandaloneConfig `mapstructure:"standalone"`
+
+	// Webroot 验证配置
+	Webroot WebrootConfig `mapstructure:"webroot"`
+
+	// DNS-01 验证使用的 DNS 服务商
+	DNS DNSConfig `mapstructure:"dns"`
+
+	// 签发后通过 DNS 服务商发布 TLSA 记录
+	DANE DANEConfig `mapstructure:"dane"`
+
+	// 签发策略：限制允许的域名和 ACME 服务器
+	Policy PolicyConfig `mapstructure:"policy"`
+
+	// 审计日志
+	Audit AuditConfig `mapstructure:"audit"`
+
+	// 破坏性操作的双人审批
+	Approval ApprovalConfig `mapstructure:"approval"`
+
+	// 首次启动自动配置（cloud-init）
+	Provision ProvisionConfig `mapstructure:"provision"`
+
+	// 本地 CA（内部 mTLS 客户端证书）
+	CA CAConfig `mapstructure:"ca"`
+
+	// 备份签名
+	Backup BackupConfig `mapstructure:"backup"`
+
+	// PFX 证书包（供 IIS、Exchange 等通过部署钩子导入）
+	PFX PFXConfig `mapstructure:"pfx"`
+
+	// 按下游软件期望的文件名和目录结构额外输出证书
+	Output OutputConfig `mapstructure:"output"`
+
+	// 实验性功能开关
+	Features FeaturesConfig `mapstructure:"features"`
+
+	// 通过 SSH 同步证书目录到其他主机（autocert sync）
+	Sync SyncConfig `mapstructure:"sync"`
+
+	// 负载均衡后多台主机的 http-01 挑战共享（autocert challenge-responder）
+	Challenge ChallengeConfig `mapstructure:"challenge"`
+}
+
+// ChallengeConfig http-01 挑战共享配置
+type ChallengeConfig struct {
+	// Shared 签发时将 http-01 挑战发布到共享存储（storage.backend），负载均衡后的每台主机运行
+	// challenge-responder 从存储中读取并响应，CA 的验证请求无论到达哪台主机都能通过
+	Shared bool `mapstructure:"shared"`
+
+	// Store 共享挑战的存储：storage（默认，使用 storage.backend）、fs（共享目录，例如 NFS 挂载的
+	// webroot 挑战目录，Web 服务器可直接提供其中的文件）或 redis
+	Store string            `mapstructure:"store"`
+	FS    ChallengeFSConfig `mapstructure:"fs"`
+	Redis RedisConfig       `mapstructure:"redis"`
+
+	// Listen challenge-responder 的监听地址，默认 :80
+	Listen string `mapstructure:"listen"`
+
+	// TTL 发布的挑战的有效期，超过后即使未删除也不再响应，默认 10m
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// RateLimit Standalone 服务器和 challenge-responder 的非挑战路径每个来源 IP（IPv6 按 /64）
+	// 每分钟的请求数上限，超过时返回 429；默认 30，-1 不限制。挑战路径只统计未知 token（404），
+	// CA 的验证请求不受影响
+	RateLimit int `mapstructure:"rate_limit"`
+}
+
+// ChallengeFSConfig 共享目录挑战存储，文件名为 token，内容为 key authorization
+type ChallengeFSConfig struct {
+	Path string `mapstructure:"path"` // 例如 /mnt/shared/www/.well-known/acme-challenge
+}
+
+// SyncConfig 证书目录同步配置
+type SyncConfig struct {
+	// Targets autocert sync 未指定 --to 时同步的目标主机
+	Targets []SyncTarget `mapstructure:"targets"`
+}
+
+// SyncTarget 同步目标
+type SyncTarget struct {
+	Name     string `mapstructure:"name"`     // 目标名称，用于 sync --target
+	To       string `mapstructure:"to"`       // 目标位置，例如 deploy@web1:/etc/autocert/certs
+	Port     int    `mapstructure:"port"`     // SSH 端口，默认 22
+	Identity string `mapstructure:"identity"` // SSH 私钥文件，默认使用 ssh 的配置
+	Reload   string `mapstructure:"reload"`   // 有文件变化时在目标主机上执行的命令，例如 systemctl reload nginx
+	Delete   bool   `mapstructure:"delete"`   // 删除目标主机上本地已不存在的文件
+	Domain   string `mapstructure:"domain"`   // 只同步该域名的证书目录
+}
+
+// FeaturesConfig 实验性功能开关，默认全部关闭，可以按主机逐步开启
+type FeaturesConfig struct {
+	TLSALPN   bool `mapstructure:"tls_alpn"`   // tls-alpn-01 验证
+	ARI       bool `mapstructure:"ari"`        // ACME 续期信息（ARI），由 CA 建议续期时间
+	DualCert  bool `mapstructure:"dual_cert"`  // 同时签发 RSA 和 ECDSA 证书（acme.dual_cert、install --dual-cert）
+	DaemonAPI bool `mapstructure:"daemon_api"` // 守护进程的 /api 管理接口
+	OnDemand  bool `mapstructure:"on_demand"`  // 按需签发（daemon.on_demand）
+}
+
+// PFX 密码策略
+const (
+	PFXPasswordRotate = "rotate" // 每次签发/续期生成新的随机密码
+	PFXPasswordKeep   = "keep"   // 首次生成随机密码后保持不变
+)
+
+// PFXConfig PFX 证书包配置
+type PFXConfig struct {
+	// Enabled 签发/续期后在证书目录中生成 cert.pfx（含私钥、证书和中间证书）
+	Enabled bool `mapstructure:"enabled"`
+
+	// Password 密码策略：rotate（默认）、keep，或 env:变量名 / file:文件路径 指定的静态密码。
+	// 随机密码保存在配置目录的 secrets.json 中，通过 autocert secret get pfx:<域名> 读取
+	Password string `mapstructure:"password"`
+
+	// Length 随机密码长度，默认 24，最短 16
+	Length int `mapstructure:"length"`
+}
+
+// OutputConfig 证书输出配置：NAS、面板等软件按固定路径读取证书时，
+// 签发/续期后按它们期望的文件名和目录结构写入证书文件（普通文件，不是符号链接）
+type OutputConfig struct {
+	// Naming 命名方式：certbot、acme.sh、synology 或 flat，为空时不输出
+	Naming string `mapstructure:"naming"`
+
+	// Dir 输出根目录，例如 certbot 的 /etc/letsencrypt
+	Dir string `mapstructure:"dir"`
+}
+
+// BackupConfig 备份签名配置
+type BackupConfig struct {
+	// SigningKey 导出时用于签名的 Ed25519 私钥（PKCS#8 PEM），配置后导出的备份附带 .sig 签名文件
+	SigningKey string `mapstructure:"signing_key"`
+
+	// TrustedKeys 导入时信任的 Ed25519 公钥（PEM）文件
+	TrustedKeys []string `mapstructure:"trusted_keys"`
+
+	// RequireSignature 导入时要求备份带有可信签名（等同于 import --require-signature）
+	RequireSignature bool `mapstructure:"require_signature"`
+}
+
+// CAConfig 本地 CA 配置
+type CAConfig struct {
+	Dir        string `mapstructure:"dir"`         // CA 目录，默认为配置目录下的 ca
+	CommonName string `mapstructure:"common_name"` // 根证书名称
+	ClientDays int    `mapstructure:"client_days"` // 客户端证书有效天数
+	CRLDays    int    `mapstructure:"crl_days"`    // CRL 的 nextUpdate 间隔天数，到期前需重新生成
+	CRLURL     string `mapstructure:"crl_url"`     // 写入客户端证书的 CRL 分发点（可选）
+	OCSPURL    string `mapstructure:"ocsp_url"`    // 写入客户端证书的 OCSP 地址（可选）
+
+	// Serve 守护进程通过 daemon.listen 提供 OCSP（/ca/ocsp）和 CRL（/ca/crl）下载，并定期更新 CRL
+	Serve bool `mapstructure:"serve"`
+}
+
+// ProvisionConfig 首次启动自动配置
+type ProvisionConfig struct {
+	// Certificates 要签发的证书，为空时为 domains 中的每个域名签发单域名证书
+	Certificates []ProvisionCertificate `mapstructure:"certificates"`
+
+	// 等待域名解析的超时时间和检查间隔
+	DNSTimeout  time.Duration `mapstructure:"dns_timeout"`
+	DNSInterval time.Duration `mapstructure:"dns_interval"`
+
+	// ExpectedIPs 域名应解析到的地址（例如云主机的公网 IP 或弹性 IP），为空时只等待域名可以解析
+	ExpectedIPs []string `mapstructure:"expected_ips"`
+
+	// Schedule 签发完成后安装续期定时任务
+	Schedule bool `mapstructure:"schedule"`
+
+	// 上报执行结果的 Webhook（JSON POST）
+	Webhook      string `mapstructure:"webhook"`
+	WebhookToken string `mapstructure:"webhook_token"` // 以 Bearer 令牌发送
+
+	// Marker 执行成功后写入的标记文件，存在时不再重复执行，默认为配置目录下的 provisioned
+	Marker string `mapstructure:"marker"`
+}
+
+// ProvisionCertificate 首次启动时签发的证书
+type ProvisionCertificate struct {
+	Domains []string `mapstructure:"domains"` // 多个域名时签发 SAN 证书
+	Webroot string   `mapstructure:"webroot"` // 不为空时使用 Webroot 模式验证
+}
+
+// ApprovalConfig 双人审批配置
+type ApprovalConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Actions   []string      `mapstructure:"actions"` // 需要审批的操作：delete、takeover、gc、revoke，为空时全部需要审批
+	TTL       time.Duration `mapstructure:"ttl"`     // 审批请求有效期
+	Approvers []Approver    `mapstructure:"approvers"`
+}
+
+// Approver 审批人
+type Approver struct {
+	Name        string `mapstructure:"name"`
+	TokenSHA256 string `mapstructure:"token_sha256"` // 审批令牌的 SHA-256（十六进制）
+}
+
+// PolicyConfig 签发策略，列表为空时不限制
+type PolicyConfig struct {
+	AllowedDomains []string `mapstructure:"allowed_domains"` // 允许的域名后缀（包括其子域名）
+	AllowedServers []string `mapstructure:"allowed_servers"` // 允许的 ACME 目录地址
+}
+
+// AuditConfig 审计日志配置
+type AuditConfig struct {
+	File string `mapstructure:"file"` // 审计日志文件，默认为日志目录下的 autocert-audit.log
+}
+
+// WebrootConfig Webroot 模式 http-01 验证配置
+type WebrootConfig struct {
+	// 挑战目录 .well-known/acme-challenge 的所有者，为空时与 webroot 目录一致
+	User  string `mapstructure:"user"`
+	Group string `mapstructure:"group"`
+
+	// StaleAfter 挑战目录中超过该时长的残留文件会被清理
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+}
+
+// DNSConfig DNS-01 验证配置
+type DNSConfig struct {
+	// Provider DNS 服务商，为空时需要手动添加 TXT 记录；exec 调用外部命令，zonefile 直接修改区域文件，
+	// powerdns、desec、godaddy、namecheap、gandi、huaweicloud、baiducloud 通过服务商 API 管理记录
+	Provider string `mapstructure:"provider"`
+
+	// CleanupAfter 超过该时长仍未删除的验证记录由 cleanup-dns（守护进程自动执行）删除
+	CleanupAfter time.Duration `mapstructure:"cleanup_after"`
+
+	// Propagation 添加验证记录后向区域的权威服务器确认记录已生效（服务商能确定记录所在区域时）
+	Propagation PropagationConfig `mapstructure:"propagation"`
+
+	Exec        ExecDNSConfig     `mapstructure:"exec"`
+	ZoneFile    ZoneFileDNSConfig `mapstructure:"zonefile"`
+	PowerDNS    PowerDNSConfig    `mapstructure:"powerdns"`
+	DeSEC       DeSECConfig       `mapstructure:"desec"`
+	GoDaddy     GoDaddyConfig     `mapstructure:"godaddy"`
+	Namecheap   NamecheapConfig   `mapstructure:"namecheap"`
+	Gandi       GandiConfig       `mapstructure:"gandi"`
+	HuaweiCloud HuaweiCloudConfig `mapstructure:"huaweicloud"`
+	BaiduCloud  BaiduCloudConfig  `mapstructure:"baiducloud"`
+}
+
+// PropagationConfig 验证记录生效检查
+type PropagationConfig struct {
+	Timeout     time.Duration `mapstructure:"timeout"` // 为 0 时不检查
+	Interval    time.Duration `mapstructure:"interval"`
+	Nameservers []string      `mapstructure:"nameservers"` // 代替区域 NS 记录查询的服务器，例如 10.0.0.53:53
+}
+
+// PowerDNSConfig PowerDNS 权威服务器 HTTP API
+type PowerDNSConfig struct {
+	APIURL   string `mapstructure:"api_url"` // 例如 http://127.0.0.1:8081
+	APIKey   string `mapstructure:"api_key"`
+	ServerID string `mapstructure:"server_id"`
+	TTL      int    `mapstructure:"ttl"`
+}
+
+// DeSECConfig deSEC.io API
+type DeSECConfig struct {
+	Token  string `mapstructure:"token"`
+	APIURL string `mapstructure:"api_url"`
+	TTL    int    `mapstructure:"ttl"` // deSEC 要求不小于 3600
+}
+
+// GoDaddyConfig GoDaddy 域名 API（developer.godaddy.com 创建的生产环境密钥）
+type GoDaddyConfig struct {
+	APIKey    string `mapstructure:"api_key"`
+	APISecret string `mapstructure:"api_secret"`
+	APIURL    string `mapstructure:"api_url"` // 测试环境为 https://api.ote-godaddy.com
+	TTL       int    `mapstructure:"ttl"`     // GoDaddy 要求不小于 600
+}
+
+// NamecheapConfig Namecheap API（需要在账户中开启 API 访问并把本机出口 IP 加入白名单）
+type NamecheapConfig struct {
+	APIUser  string `mapstructure:"api_user"`
+	APIKey   string `mapstructure:"api_key"`
+	Username string `mapstructure:"username"` // 为空时与 api_user 相同
+	ClientIP string `mapstructure:"client_ip"`
+	APIURL   string `mapstructure:"api_url"` // 沙箱环境为 https://api.sandbox.namecheap.com/xml.response
+	TTL      int    `mapstructure:"ttl"`
+}
+
+// GandiConfig Gandi LiveDNS API（个人访问令牌，需要“管理域名技术配置”权限）
+type GandiConfig struct {
+	Token  string `mapstructure:"token"`
+	APIURL string `mapstructure:"api_url"`
+	TTL    int    `mapstructure:"ttl"` // Gandi 要求不小于 300
+}
+
+// HuaweiCloudConfig 华为云云解析服务（AK/SK 认证，需要 DNS FullAccess 权限）
+type HuaweiCloudConfig struct {
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	Endpoint  string `mapstructure:"endpoint"`
+	TTL       int    `mapstructure:"ttl"`
+}
+
+// BaiduCloudConfig 百度智能云 DNS（AK/SK 认证）
+type BaiduCloudConfig struct {
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	Endpoint  string `mapstructure:"endpoint"`
+	TTL       int    `mapstructure:"ttl"`
+}
+
+// ExecDNSConfig 通过外部命令管理记录，记录信息通过环境变量传入：
+// AUTOCERT_DNS_ACTION（present 或 cleanup）、AUTOCERT_DNS_RECORD、AUTOCERT_DNS_TYPE（TXT、TLSA）、
+// AUTOCERT_DNS_VALUE，以及可选的 AUTOCERT_DNS_TTL
+type ExecDNSConfig struct {
+	Command string        `mapstructure:"command"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// ZoneFileDNSConfig 直接修改 BIND/NSD 区域文件：写入记录、递增 SOA 序列号后执行重载命令
+type ZoneFileDNSConfig struct {
+	Zones   []ZoneFileConfig `mapstructure:"zones"`
+	TTL     int              `mapstructure:"ttl"`     // 验证记录的 TTL（秒）
+	Timeout time.Duration    `mapstructure:"timeout"` // 重载命令超时时间
+}
+
+// ZoneFileConfig 一个区域，记录写入区域名为其最长后缀的区域
+type ZoneFileConfig struct {
+	Origin string `mapstructure:"origin"` // 区域名，例如 example.com
+	Path   string `mapstructure:"path"`   // 区域文件路径
+	Reload string `mapstructure:"reload"` // 例如 rndc reload example.com、nsd-control reload example.com
+}
+
+// DANEConfig DANE TLSA 记录。只使用 DANE-EE + SPKI（3 1 x）：记录只与私钥有关，
+// 可以在签发时预先生成下一次续期使用的私钥并提前发布其记录，续期部署新证书时记录已生效
+type DANEConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Ports        []int         `mapstructure:"ports"`         // 服务端口，例如 443、25
+	Protocol     string        `mapstructure:"protocol"`      // tcp（默认）或 udp
+	MatchingType int           `mapstructure:"matching_type"` // 1 SHA-256（默认）、2 SHA-512
+	TTL          time.Duration `mapstructure:"ttl"`
+}
+
+// StandaloneConfig Standalone 模式 http-01 验证配置
+type StandaloneConfig struct {
+	// Port 临时 HTTP 服务器监听的端口
+	Port int `mapstructure:"port"`
+
+	// ListenFDs 继承的已绑定监听套接字（文件描述符），优先于自行绑定端口；
+	// systemd 套接字激活（LISTEN_FDS）传入的套接字会自动使用
+	ListenFDs []int `mapstructure:"listen_fds"`
+
+	// Prebind 守护进程降权前预先绑定端口，降权后仍可使用 Standalone 验证
+	// （端口会被守护进程一直占用，不要与监听 80 端口的 Web 服务器同时使用）
+	Prebind bool `mapstructure:"prebind"`
+}
+
+// StorageConfig 证书存储配置
+type StorageConfig struct {
+	// Layout 存储布局：flat（默认）或 canonical（内容寻址证书库 + 符号链接）
+	Layout string `mapstructure:"layout"`
+
+	// MinFreeMB 签发证书、导出备份、写入日志前要求的最小剩余空间（MB）
+	MinFreeMB int `mapstructure:"min_free_mb"`
+
+	// DirMode 证书目录（包括证书根目录和证书库）的最大权限，八进制，默认 0755（私钥文件为 0600）；
+	// 不需要其他用户读取证书时可设为 0700
+	DirMode string `mapstructure:"dir_mode"`
+
+	// FixPermissions 守护进程每轮续期检查前自动修正证书目录和私钥文件的权限（默认只记录警告）
+	FixPermissions bool `mapstructure:"fix_permissions"`
+
+	// Backend 证书持久化后端：为空只保存在本地证书目录；fs、s3、etcd、consul 时证书同时保存到后端，
+	// 本地证书目录作为 Web 服务器使用的缓存，容器重建后可从后端恢复
+	Backend string              `mapstructure:"backend"`
+	Prefix  string              `mapstructure:"prefix"` // 后端中的键前缀
+	FS      FSStorageConfig     `mapstructure:"fs"`
+	S3      S3StorageConfig     `mapstructure:"s3"`
+	Etcd    EtcdStorageConfig   `mapstructure:"etcd"`
+	Consul  ConsulStorageConfig `mapstructure:"consul"`
+
+	// Lock 多个节点共用 etcd/Consul 后端时的续期锁
+	Lock StorageLockConfig `mapstructure:"lock"`
+}
+
+// StorageLockConfig 分布式续期锁：同一张证书同时只有一个节点续期，其他节点等待后从后端部署
+type StorageLockConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	TTL     time.Duration `mapstructure:"ttl"`  // 锁的租约时间，节点崩溃后锁在此时间后自动释放
+	Wait    time.Duration `mapstructure:"wait"` // 等待其他节点释放锁的最长时间
+}
+
+// FSStorageConfig 文件系统后端（例如 NFS 挂载目录）
+type FSStorageConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+// S3StorageConfig S3 兼容对象存储（AWS S3、阿里云 OSS、MinIO 等）
+// 密钥支持 env:变量名 或 file:文件路径
+type S3StorageConfig struct {
+	Endpoint  string `mapstructure:"endpoint"` // 例如 https://oss-cn-hangzhou.aliyuncs.com，为空时使用 AWS
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"` // 使用 endpoint/bucket/key 形式的地址（MinIO）
+}
+
+// EtcdStorageConfig etcd v3（通过 HTTP/JSON 网关访问）
+type EtcdStorageConfig struct {
+	Endpoints []string `mapstructure:"endpoints"` // 例如 http://127.0.0.1:2379
+	Username  string   `mapstructure:"username"`
+	Password  string   `mapstructure:"password"`
+}
+
+// ConsulStorageConfig Consul KV
+type ConsulStorageConfig struct {
+	Address string `mapstructure:"address"` // 例如 http://127.0.0.1:8500
+	Token   string `mapstructure:"token"`
+}
+
+// StatsConfig 本地使用统计配置
+type StatsConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`  // 开启后在配置目录记录命令次数、耗时和失败类别
+	Endpoint string `mapstructure:"endpoint"` // 可选，stats --push 的收集地址；不配置则不会向外发送
 }
 
 // ACMEConfig ACME 相关配置
@@ -32,6 +490,186 @@ type ACMEConfig struct {
 	Email   string `mapstructure:"email"`    // 邮箱地址
 	KeyType string `mapstructure:"key_type"` // 密钥类型
 	KeySize int    `mapstructure:"key_size"` // 密钥大小
+
+	// DualCert 同时签发 RSA 和 ECDSA 两张证书
+	DualCert bool `mapstructure:"dual_cert"`
+
+	// PreferredChain 首选证书链：CA 提供多条证书链时，选择根证书（最顶层证书的颁发者 CN）为该名称的证书链，
+	// 例如 "ISRG Root X1" 选择不含交叉签名的短链。没有匹配时使用 CA 的默认证书链
+	PreferredChain string `mapstructure:"preferred_chain"`
+
+	// AuthzConcurrency 多域名订单同时处理的授权数量
+	AuthzConcurrency int `mapstructure:"authz_concurrency"`
+
+	// CDNFallback 域名经过 CDN 代理时的处理：dns（默认，配置了 dns.provider 时该域名改用 DNS 验证）、
+	// warn（只在预检中警告）、off（不检测）
+	CDNFallback string `mapstructure:"cdn_fallback"`
+
+	// 账户密钥存储方式：file（默认，保存在配置目录）或 pkcs11（硬件令牌/TPM）
+	KeyBackend string       `mapstructure:"key_backend"`
+	PKCS11     PKCS11Config `mapstructure:"pkcs11"`
+
+	// KeyPool 证书私钥预生成
+	KeyPool KeyPoolConfig `mapstructure:"key_pool"`
+
+	// StatusURL CA 状态页接口（Atlassian Statuspage 的 /api/v2/status.json 或 status.io 的状态接口），
+	// ping-ca 无法访问 CA 时据此区分本机网络问题和 CA 故障；Let's Encrypt 默认使用其状态页
+	StatusURL string `mapstructure:"status_url"`
+}
+
+// KeyPoolConfig 证书私钥预生成配置。RSA 私钥（尤其是 4096 位）生成较慢，提前生成后签发时直接取用
+type KeyPoolConfig struct {
+	// Enabled 守护进程在后台预生成私钥（批量签发总是并行预生成）
+	Enabled bool `mapstructure:"enabled"`
+
+	// Size 守护进程保留的预生成私钥数量，默认 2
+	Size int `mapstructure:"size"`
+
+	// Workers 批量签发时并行生成私钥的数量，默认为 CPU 核数
+	Workers int `mapstructure:"workers"`
+}
+
+// PKCS11Config PKCS#11 令牌配置
+type PKCS11Config struct {
+	Module     string `mapstructure:"module"`      // PKCS#11 模块路径，例如 /usr/lib/softhsm/libsofthsm2.so
+	Tool       string `mapstructure:"tool"`        // pkcs11-tool 路径（OpenSC）
+	TokenLabel string `mapstructure:"token_label"` // 令牌标签
+	KeyID      string `mapstructure:"key_id"`      // 密钥对象 ID（十六进制）
+	KeyLabel   string `mapstructure:"key_label"`   // 密钥对象标签，与 key_id 二选一
+	PIN        string `mapstructure:"pin"`         // PIN 来源：env:变量名 或 file:文件路径
+}
+
+// DomainConfig 单个证书（按主域名）的配置
+type DomainConfig struct {
+	Domain          string `mapstructure:"domain"`            // 主域名
+	RenewBeforeDays int    `mapstructure:"renew_before_days"` // 覆盖全局续期天数
+	PreferredChain  string `mapstructure:"preferred_chain"`   // 覆盖全局首选证书链
+
+	// SANs 证书包含的其他域名，修改后续期时按新的域名集合重新签发
+	SANs []string `mapstructure:"sans"`
+
+	// 覆盖 webserver 中的测试/重载命令
+	ReloadCmd string   `mapstructure:"reload_cmd"`
+	TestCmd   string   `mapstructure:"test_cmd"`
+	WorkDir   string   `mapstructure:"work_dir"`
+	Env       []string `mapstructure:"env"`
+
+	// 覆盖全局部署钩子
+	Hooks []HookConfig `mapstructure:"hooks"`
+
+	// 覆盖全局部署目标
+	Deploy []DeployConfig `mapstructure:"deploy"`
+
+	// TemplateBlocks 覆盖站点配置模板中同名的 block（例如 locations、server），
+	// 内容为模板片段，可以使用与模板相同的占位符
+	TemplateBlocks map[string]string `mapstructure:"template_blocks"`
+
+	// NoRedirect 生成的站点配置中 HTTP 不重定向到 HTTPS（同 install --no-redirect）
+	NoRedirect bool `mapstructure:"no_redirect"`
+}
+
+// HookConfig 部署钩子，按 after 声明的依赖关系组成有向无环图执行
+type HookConfig struct {
+	Name       string        `mapstructure:"name"`
+	Command    string        `mapstructure:"command"`
+	After      []string      `mapstructure:"after"`       // 依赖的钩子名称，全部成功后才执行
+	Timeout    time.Duration `mapstructure:"timeout"`     // 单次执行超时，默认 5 分钟
+	Retries    int           `mapstructure:"retries"`     // 失败后的重试次数
+	RetryDelay time.Duration `mapstructure:"retry_delay"` // 重试间隔，默认 5 秒
+	OnFailure  string        `mapstructure:"on_failure"`  // abort（默认，停止后续钩子并使安装失败）或 continue
+}
+
+// DeployConfig 部署目标：将证书安装到 NAS、虚拟化平台或网络设备
+type DeployConfig struct {
+	Name      string `mapstructure:"name"`       // 目标名称，默认与 type 相同
+	Type      string `mapstructure:"type"`       // synology、qnap、proxmox、esxi、routeros、pfsense、opnsense
+	OnFailure string `mapstructure:"on_failure"` // abort（默认，安装失败）或 continue（记录失败，继续部署其他目标）
+
+	Synology SynologyDeployConfig `mapstructure:"synology"`
+	QNAP     QNAPDeployConfig     `mapstructure:"qnap"`
+	Proxmox  ProxmoxDeployConfig  `mapstructure:"proxmox"`
+	ESXi     ESXiDeployConfig     `mapstructure:"esxi"`
+	RouterOS RouterOSDeployConfig `mapstructure:"routeros"`
+	PfSense  PfSenseDeployConfig  `mapstructure:"pfsense"`
+	OPNsense OPNsenseDeployConfig `mapstructure:"opnsense"`
+}
+
+// SynologyDeployConfig 通过 DSM Web API 导入证书，DSM 会重新加载使用该证书的服务
+type SynologyDeployConfig struct {
+	URL        string `mapstructure:"url"`       // DSM 地址，默认 http://localhost:5000
+	Insecure   bool   `mapstructure:"insecure"`  // 不校验 DSM 的 HTTPS 证书（例如首次部署前的自签名证书）
+	Username   string `mapstructure:"username"`  // 管理员账户
+	Password   string `mapstructure:"password"`  // env:变量名 或 file:文件路径
+	DeviceID   string `mapstructure:"device_id"` // 开启两步验证的账户：登录时勾选“信任此设备”得到的 did
+	DeviceName string `mapstructure:"device_name"`
+
+	// Certificate 替换的证书（DSM 证书列表中的描述），为空时替换默认证书；不存在时以该描述导入新证书
+	Certificate string `mapstructure:"certificate"`
+	// Default 将导入的证书设为默认证书
+	Default bool `mapstructure:"default"`
+}
+
+// QNAPDeployConfig 在 QNAP 本机替换 QTS 的系统证书（stunnel.pem）并重启相关服务
+type QNAPDeployConfig struct {
+	Dir      string   `mapstructure:"dir"`      // 证书目录，默认 /etc/stunnel
+	Restarts []string `mapstructure:"restarts"` // 替换后执行的命令，默认重启 stunnel 并重新加载反向代理
+}
+
+// ProxmoxDeployConfig 通过 Proxmox VE API（/nodes/<节点>/certificates/custom，即 pvenode cert set）
+// 上传节点的自定义证书并重启 pveproxy
+type ProxmoxDeployConfig struct {
+	URL         string `mapstructure:"url"`          // 例如 https://pve1.example.com:8006
+	Node        string `mapstructure:"node"`         // 节点名称
+	TokenID     string `mapstructure:"token_id"`     // API 令牌，例如 autocert@pve!deploy，需要节点的 Sys.Modify 权限
+	TokenSecret string `mapstructure:"token_secret"` // env:变量名 或 file:文件路径
+	Insecure    bool   `mapstructure:"insecure"`     // 不校验节点当前的 HTTPS 证书（例如首次部署前的自签名证书）
+	CACert      string `mapstructure:"ca_cert"`      // 校验节点证书的 CA 证书，例如 /etc/pve/pve-root-ca.pem 的副本
+}
+
+// ESXiDeployConfig 通过 SSH 替换 ESXi 主机的 /etc/vmware/ssl/rui.crt 和 rui.key 并重启管理服务
+type ESXiDeployConfig struct {
+	Host     string   `mapstructure:"host"` // [user@]host，通常为 root@esxi1.example.com
+	Port     int      `mapstructure:"port"`
+	Identity string   `mapstructure:"identity"` // SSH 私钥文件
+	Restarts []string `mapstructure:"restarts"` // 替换后在主机上执行的命令，默认重启 hostd、vpxa 和 rhttpproxy
+}
+
+// RouterOSDeployConfig 通过 RouterOS 7 的 REST API 导入证书，并让 www-ssl、api-ssl 等服务使用新证书
+type RouterOSDeployConfig struct {
+	URL      string `mapstructure:"url"`      // 例如 https://router.example.com（需要启用 www-ssl 服务）
+	Username string `mapstructure:"username"` // 需要 read、write、rest-api 权限
+	Password string `mapstructure:"password"` // env:变量名 或 file:文件路径
+	Insecure bool   `mapstructure:"insecure"` // 不校验路由器当前的 HTTPS 证书（例如首次部署前的自签名证书）
+	CACert   string `mapstructure:"ca_cert"`  // 校验路由器证书的 CA 证书
+
+	// Use 使用新证书的服务：/ip/service 中的服务名，ovpn 表示 OpenVPN 服务器；默认 www-ssl、api-ssl
+	Use []string `mapstructure:"use"`
+}
+
+// PfSenseDeployConfig 通过 pfSense REST API（pfSense-pkg-RESTAPI v2）导入证书并更新引用
+type PfSenseDeployConfig struct {
+	URL      string `mapstructure:"url"`     // 例如 https://fw.example.com
+	APIKey   string `mapstructure:"api_key"` // env:变量名 或 file:文件路径
+	Insecure bool   `mapstructure:"insecure"`
+	CACert   string `mapstructure:"ca_cert"`
+
+	// Use 改为使用新证书的位置：webgui、openvpn:<服务器 ID>、haproxy:<前端 ID>；默认 webgui
+	Use []string `mapstructure:"use"`
+}
+
+// OPNsenseDeployConfig 通过 OPNsense API 按描述替换信任存储中的证书，引用该证书的位置（Web 管理界面、
+// OpenVPN、HAProxy 等）自动使用新证书
+type OPNsenseDeployConfig struct {
+	URL       string `mapstructure:"url"` // 例如 https://fw.example.com
+	APIKey    string `mapstructure:"api_key"`
+	APISecret string `mapstructure:"api_secret"` // env:变量名 或 file:文件路径
+	Insecure  bool   `mapstructure:"insecure"`
+	CACert    string `mapstructure:"ca_cert"`
+
+	// Certificate 证书的描述，默认 autocert <主域名>；不存在时以该描述导入新证书
+	Certificate string `mapstructure:"certificate"`
+	// Reload 替换后重新加载的服务：openvpn、haproxy
+	Reload []string `mapstructure:"reload"`
 }
 
 // NotificationConfig 通知配置
@@ -55,25 +693,207 @@ type WebServerConfig struct {
 	Type       string `mapstructure:"type"`        // nginx, apache, iis
 	ConfigPath string `mapstructure:"config_path"` // 配置文件路径
 	ReloadCmd  string `mapstructure:"reload_cmd"`  // 重载命令
+	Template   string `mapstructure:"template"`    // 自定义站点配置模板（Go text/template）
+
+	// 自定义命令的执行环境（例如 chroot 中的 Nginx 或自定义服务管理器）
+	TestCmd string   `mapstructure:"test_cmd"` // 配置测试命令
+	WorkDir string   `mapstructure:"work_dir"` // 执行测试/重载命令的工作目录
+	Env     []string `mapstructure:"env"`      // 额外的环境变量，格式 KEY=VALUE
+
+	// 配置历史：git（提交到本地仓库）或 snapshot（快照目录），为空时不记录
+	ConfigHistory    string `mapstructure:"config_history"`
+	ConfigHistoryDir string `mapstructure:"config_history_dir"` // 默认为配置目录下的 config-history
+
+	// 多站点共享 IP 时（仅 Nginx）写入 default_server 站点，未匹配 SNI 的请求使用自签名证书
+	DefaultServer bool `mapstructure:"default_server"`
+
+	// Nginx 主配置没有 include sites-enabled 或 conf.d 时，在 http 块中添加 include（修改前备份主配置）
+	AddInclude bool `mapstructure:"add_include"`
+
+	// IIS 相关配置
+	IIS IISConfig `mapstructure:"iis"`
 }
 
-var (
-	// AppConfig 全局配置实例
-	AppConfig *Config
-)
+// IISConfig IIS 配置
+type IISConfig struct {
+	// 集中式证书存储（Central Certificate Store）的共享目录，配置后证书以 <主机名>.pfx 写入该目录，
+	// 不导入本机证书存储，Web 场中的所有节点自动使用续期后的证书
+	CentralStore string `mapstructure:"central_store"`
+	// PFX 私钥密码，需与 IIS 集中式证书存储设置中的密码一致：env:变量名 或 file:文件路径
+	CentralStorePassword string `mapstructure:"central_store_password"`
+
+	// 导入本机证书存储时绑定证书的站点，默认 Default Web Site
+	Site string `mapstructure:"site"`
+
+	// 通过 WinRM 管理远程主机上的 IIS（配置 host 后生效），Linux 上的 AutoCert 也可以管理 Windows 服务器
+	Remote WinRMConfig `mapstructure:"remote"`
+}
+
+// WinRMConfig WinRM 连接配置（Basic 认证）
+type WinRMConfig struct {
+	Host      string        `mapstructure:"host"`
+	Port      int           `mapstructure:"port"`       // 默认 HTTPS 5986，HTTP 5985
+	HTTPS     bool          `mapstructure:"https"`      // 默认 true
+	AllowHTTP bool          `mapstructure:"allow_http"` // https 为 false 时必须显式设置：HTTP 不加密，凭据以明文传输
+	Insecure  bool          `mapstructure:"insecure"`   // 不校验服务器证书
+	CACert    string        `mapstructure:"ca_cert"`    // 校验服务器证书的 CA 证书
+	Username  string        `mapstructure:"username"`
+	Password  string        `mapstructure:"password"` // env:变量名 或 file:文件路径
+	Timeout   time.Duration `mapstructure:"timeout"`  // 单个命令的最长执行时间
+}
+
+// DaemonConfig 守护进程配置
+type DaemonConfig struct {
+	Interval time.Duration `mapstructure:"interval"` // 续期检查间隔（smart 调度时为最长间隔）
+	Listen   string        `mapstructure:"listen"`   // HTTP 监听地址，为空时不启动 HTTP 服务
+
+	// 调度方式：fixed（按 interval 固定间隔）或 smart（按最早进入续期窗口的证书安排下次检查）
+	Schedule    string        `mapstructure:"schedule"`
+	MinInterval time.Duration `mapstructure:"min_interval"` // smart 调度两次检查的最短间隔
+	Jitter      time.Duration `mapstructure:"jitter"`       // smart 调度在续期时间前随机提前的最长时间
+
+	// 入站 Webhook，外部系统可通过它触发签发/续期
+	Webhook WebhookConfig `mapstructure:"webhook"`
+
+	// 按需签发（实验性）：监视域名队列文件，自动为新出现的域名签发证书
+	OnDemand OnDemandConfig `mapstructure:"on_demand"`
+
+	// 以 root 启动时，绑定端口后切换到的非特权用户和用户组
+	User  string `mapstructure:"user"`
+	Group string `mapstructure:"group"`
+
+	// TLS HTTP 接口使用 HTTPS，并要求本地 CA 签发的客户端证书（mTLS）
+	TLS DaemonTLSConfig `mapstructure:"tls"`
+
+	// Agents 控制节点管理的卫星节点（agent）
+	Agents AgentsConfig `mapstructure:"agents"`
+}
+
+// AgentsConfig 控制节点的 agent 清单配置
+type AgentsConfig struct {
+	// StaleAfter 超过该时间没有签到的 agent 视为失联，在 agents list、status 和 check 中提示，默认 48h
+	StaleAfter time.Duration `mapstructure:"stale_after"`
+}
+
+// DaemonTLSConfig 守护进程 HTTP 接口的 TLS 配置，需要先执行 autocert ca init
+type DaemonTLSConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// 服务端证书和私钥，为空时由本地 CA 签发（保存在 CA 目录，到期前自动重新签发）
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+
+	// Names 本地 CA 签发服务端证书时写入的域名或 IP，默认为主机名、localhost 和 127.0.0.1
+	Names []string `mapstructure:"names"`
+
+	// ClientAuth 客户端证书要求：require（默认，除 agent 注册和 OCSP/CRL 外都需要本地 CA 签发的客户端证书）、
+	// optional（提供时验证）或 none
+	ClientAuth string `mapstructure:"client_auth"`
+}
+
+// OnDemandConfig 按需签发配置
+type OnDemandConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	QueueFile    string        `mapstructure:"queue_file"`    // 域名队列文件，每行一个域名
+	Webroot      string        `mapstructure:"webroot"`       // 共享 webroot，用于 http-01 验证
+	PollInterval time.Duration `mapstructure:"poll_interval"` // 队列文件检查间隔
+	RetryAfter   time.Duration `mapstructure:"retry_after"`   // 失败后重试间隔
+}
+
+// WebhookConfig 入站 Webhook 配置
+type WebhookConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Token   string `mapstructure:"token"` // 调用方需携带的令牌
+}
+
+// EventsConfig 结构化事件转发配置
+type EventsConfig struct {
+	Syslog SyslogConfig        `mapstructure:"syslog"`
+	HTTP   HTTPCollectorConfig `mapstructure:"http"`
+	MQTT   MQTTConfig          `mapstructure:"mqtt"`
+	Redis  RedisConfig         `mapstructure:"redis"`
+	Kafka  KafkaConfig         `mapstructure:"kafka"`
+}
+
+// SyslogConfig syslog 转发配置（RFC5424）
+type SyslogConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Network  string `mapstructure:"network"`  // udp, tcp；为空时发送到本机 syslog
+	Address  string `mapstructure:"address"`  // 远程地址，例如 siem.example.com:514
+	Facility string `mapstructure:"facility"` // 例如 daemon, local0
+	AppName  string `mapstructure:"app_name"`
+}
+
+// HTTPCollectorConfig HTTP 事件收集器配置
+type HTTPCollectorConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	URL     string        `mapstructure:"url"`
+	Token   string        `mapstructure:"token"` // 以 Bearer 令牌发送
+	Timeout time.Duration `mapstructure:"timeout"`
+}
 
-// Load 加载配置
-func Load() {
-	AppConfig = &Config{}
+// MQTTConfig MQTT 事件发布配置（MQTT 3.1.1）
+type MQTTConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Broker   string        `mapstructure:"broker"` // 例如 mqtt.example.com:1883
+	TLS      bool          `mapstructure:"tls"`
+	ClientID string        `mapstructure:"client_id"`
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	Topic    string        `mapstructure:"topic"` // {type} 替换为事件类型
+	QoS      int           `mapstructure:"qos"`   // 0 或 1
+	Retain   bool          `mapstructure:"retain"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// RedisConfig Redis 连接配置：事件发布（events.redis）和共享挑战存储（challenge.redis）共用
+type RedisConfig struct {
+	Enabled  bool          `mapstructure:"enabled"` // 发布事件（events.redis）
+	Address  string        `mapstructure:"address"` // 例如 redis.example.com:6379
+	TLS      bool          `mapstructure:"tls"`
+	Username string        `mapstructure:"username"` // Redis 6 ACL 用户，留空时只用密码认证
+	Password string        `mapstructure:"password"` // 支持 env:变量名 或 file:文件路径
+	DB       int           `mapstructure:"db"`
+	Channel  string        `mapstructure:"channel"` // 事件频道，{type} 替换为事件类型
+	Prefix   string        `mapstructure:"prefix"`  // 挑战存储的键前缀，默认 autocert:acme-challenge:
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// KafkaConfig Kafka 事件发布配置
+type KafkaConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Brokers  []string      `mapstructure:"brokers"` // 引导服务器，例如 kafka1.example.com:9092
+	TLS      bool          `mapstructure:"tls"`
+	Username string        `mapstructure:"username"` // 配置后使用 SASL/PLAIN 认证
+	Password string        `mapstructure:"password"`
+	Topic    string        `mapstructure:"topic"`
+	ClientID string        `mapstructure:"client_id"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// appConfig 全局配置实例。重新加载时在新实例中解析完成后整体替换，其他 goroutine 不会读到解析到一半的配置
+var appConfig atomic.Pointer[Config]
 
+// Current 当前的全局配置，未加载时为 nil。重新加载后返回新的实例，同一次操作中应只获取一次
+func Current() *Config {
+	return appConfig.Load()
+}
+
+// Load 加载配置，配置值无法转换为对应类型时返回错误
+func Load() error {
 	// 设置默认值
 	setDefaults()
 
 	// 从配置文件和环境变量加载
-	if err := viper.Unmarshal(AppConfig); err != nil {
-		// 如果解析失败，使用默认配置
-		AppConfig = getDefaultConfig()
+	cfg := &Config{}
+	if err := viper.Unmarshal(cfg); err != nil {
+		if Current() == nil {
+			appConfig.Store(getDefaultConfig())
+		}
+		return fmt.Errorf("解析配置失败: %w", err)
 	}
+	appConfig.Store(cfg)
+	return nil
 }
 
 // setDefaults 设置默认配置值
@@ -93,19 +913,152 @@ func setDefaults() {
 
 	// 其他默认值
 	viper.SetDefault("log_level", "info")
+	viper.SetDefault("daemon.interval", "24h")
+	viper.SetDefault("daemon.schedule", "fixed")
+	viper.SetDefault("daemon.min_interval", "1h")
+	viper.SetDefault("daemon.jitter", "1h")
+	viper.SetDefault("daemon.on_demand.poll_interval", "30s")
+	viper.SetDefault("daemon.on_demand.retry_after", "1h")
+	viper.SetDefault("standalone.port", 80)
+	viper.SetDefault("webserver.iis.site", "Default Web Site")
+	viper.SetDefault("pfx.password", PFXPasswordRotate)
+	viper.SetDefault("challenge.listen", ":80")
+	viper.SetDefault("challenge.store", "storage")
+	viper.SetDefault("challenge.redis.prefix", "autocert:acme-challenge:")
+	viper.SetDefault("challenge.redis.timeout", "5s")
+	viper.SetDefault("challenge.ttl", "10m")
+	viper.SetDefault("pfx.length", 24)
+	viper.SetDefault("webserver.iis.remote.https", true)
+	viper.SetDefault("webserver.iis.remote.timeout", "5m")
+	viper.SetDefault("webroot.stale_after", "24h")
+	viper.SetDefault("dns.cleanup_after", "1h")
+	viper.SetDefault("dns.exec.timeout", "2m")
+	viper.SetDefault("dns.zonefile.ttl", 60)
+	viper.SetDefault("dns.zonefile.timeout", "30s")
+	viper.SetDefault("dns.propagation.timeout", "2m")
+	viper.SetDefault("dns.propagation.interval", "5s")
+	viper.SetDefault("dns.powerdns.server_id", "localhost")
+	viper.SetDefault("dns.powerdns.ttl", 60)
+	viper.SetDefault("dns.desec.api_url", "https://desec.io/api/v1")
+	viper.SetDefault("dns.desec.ttl", 3600)
+	viper.SetDefault("dns.godaddy.api_url", "https://api.godaddy.com")
+	viper.SetDefault("dns.godaddy.ttl", 600)
+	viper.SetDefault("dns.namecheap.api_url", "https://api.namecheap.com/xml.response")
+	viper.SetDefault("dns.namecheap.ttl", 60)
+	viper.SetDefault("dns.gandi.api_url", "https://api.gandi.net/v5/livedns")
+	viper.SetDefault("dns.gandi.ttl", 300)
+	viper.SetDefault("dns.huaweicloud.endpoint", "https://dns.myhuaweicloud.com")
+	viper.SetDefault("dns.huaweicloud.ttl", 300)
+	viper.SetDefault("dns.baiducloud.endpoint", "https://dns.baidubce.com")
+	viper.SetDefault("dns.baiducloud.ttl", 300)
+	viper.SetDefault("dane.ports", []int{443})
+	viper.SetDefault("dane.protocol", "tcp")
+	viper.SetDefault("dane.matching_type", 1)
+	viper.SetDefault("dane.ttl", "1h")
+	viper.SetDefault("provision.dns_timeout", "10m")
+	viper.SetDefault("provision.dns_interval", "15s")
+	viper.SetDefault("provision.schedule", true)
+	viper.SetDefault("approval.ttl", "24h")
+	viper.SetDefault("ca.common_name", "AutoCert Local CA")
+	viper.SetDefault("ca.client_days", 365)
+	viper.SetDefault("ca.crl_days", 7)
+	viper.SetDefault("storage.layout", "flat")
+	viper.SetDefault("storage.min_free_mb", 10)
+	viper.SetDefault("storage.dir_mode", "0755")
+	viper.SetDefault("storage.prefix", "autocert")
+	viper.SetDefault("storage.s3.region", "us-east-1")
+	viper.SetDefault("storage.etcd.endpoints", []string{"http://127.0.0.1:2379"})
+	viper.SetDefault("storage.consul.address", "http://127.0.0.1:8500")
+	viper.SetDefault("storage.lock.ttl", "1m")
+	viper.SetDefault("storage.lock.wait", "10m")
+	viper.SetDefault("events.syslog.facility", "daemon")
+	viper.SetDefault("events.syslog.app_name", "autocert")
+	viper.SetDefault("events.http.timeout", "5s")
+	viper.SetDefault("events.mqtt.client_id", "autocert")
+	viper.SetDefault("events.mqtt.topic", "autocert/events/{type}")
+	viper.SetDefault("events.mqtt.timeout", "5s")
+	viper.SetDefault("events.redis.channel", "autocert:events")
+	viper.SetDefault("events.redis.timeout", "5s")
+	viper.SetDefault("events.kafka.topic", "autocert-events")
+	viper.SetDefault("events.kafka.client_id", "autocert")
+	viper.SetDefault("events.kafka.timeout", "10s")
 	viper.SetDefault("acme.server", "https://acme-v02.api.letsencrypt.org/directory")
 	viper.SetDefault("acme.key_type", "rsa")
 	viper.SetDefault("acme.key_size", 2048)
+	viper.SetDefault("acme.key_backend", "file")
+	viper.SetDefault("acme.authz_concurrency", 10)
+	viper.SetDefault("acme.key_pool.size", 2)
+	viper.SetDefault("acme.cdn_fallback", "dns")
+	viper.SetDefault("acme.pkcs11.tool", "pkcs11-tool")
 }
 
 // getDefaultConfig 获取默认配置
 func getDefaultConfig() *Config {
 	config := &Config{
 		LogLevel: "info",
+		Daemon: DaemonConfig{
+			Interval:    24 * time.Hour,
+			Schedule:    "fixed",
+			MinInterval: time.Hour,
+			Jitter:      time.Hour,
+		},
+		Storage: StorageConfig{
+			Layout:    "flat",
+			MinFreeMB: 10,
+			DirMode:   "0755",
+			Prefix:    "autocert",
+			S3:        S3StorageConfig{Region: "us-east-1"},
+			Etcd:      EtcdStorageConfig{Endpoints: []string{"http://127.0.0.1:2379"}},
+			Consul:    ConsulStorageConfig{Address: "http://127.0.0.1:8500"},
+			Lock:      StorageLockConfig{TTL: time.Minute, Wait: 10 * time.Minute},
+		},
+		Standalone: StandaloneConfig{Port: 80},
+		Webroot:    WebrootConfig{StaleAfter: 24 * time.Hour},
+		PFX:        PFXConfig{Password: PFXPasswordRotate, Length: 24},
+		Challenge: ChallengeConfig{
+			Store:     "storage",
+			Redis:     RedisConfig{Prefix: "autocert:acme-challenge:", Timeout: 5 * time.Second},
+			Listen:    ":80",
+			TTL:       10 * time.Minute,
+			RateLimit: 30,
+		},
+		DNS: DNSConfig{
+			CleanupAfter: time.Hour,
+			Exec:         ExecDNSConfig{Timeout: 2 * time.Minute},
+			ZoneFile:     ZoneFileDNSConfig{TTL: 60, Timeout: 30 * time.Second},
+			Propagation:  PropagationConfig{Timeout: 2 * time.Minute, Interval: 5 * time.Second},
+			PowerDNS:     PowerDNSConfig{ServerID: "localhost", TTL: 60},
+			DeSEC:        DeSECConfig{APIURL: "https://desec.io/api/v1", TTL: 3600},
+			GoDaddy:      GoDaddyConfig{APIURL: "https://api.godaddy.com", TTL: 600},
+			Namecheap:    NamecheapConfig{APIURL: "https://api.namecheap.com/xml.response", TTL: 60},
+			Gandi:        GandiConfig{APIURL: "https://api.gandi.net/v5/livedns", TTL: 300},
+			HuaweiCloud:  HuaweiCloudConfig{Endpoint: "https://dns.myhuaweicloud.com", TTL: 300},
+			BaiduCloud:   BaiduCloudConfig{Endpoint: "https://dns.baidubce.com", TTL: 300},
+		},
+		DANE: DANEConfig{Ports: []int{443}, Protocol: "tcp", MatchingType: 1, TTL: time.Hour},
+		CA:   CAConfig{CommonName: "AutoCert Local CA", ClientDays: 365, CRLDays: 7},
+		Provision: ProvisionConfig{
+			DNSTimeout:  10 * time.Minute,
+			DNSInterval: 15 * time.Second,
+			Schedule:    true,
+		},
+		Events: EventsConfig{
+			Syslog: SyslogConfig{Facility: "daemon", AppName: "autocert"},
+			HTTP:   HTTPCollectorConfig{Timeout: 5 * time.Second},
+			MQTT:   MQTTConfig{ClientID: "autocert", Topic: "autocert/events/{type}", Timeout: 5 * time.Second},
+			Redis:  RedisConfig{Channel: "autocert:events", Timeout: 5 * time.Second},
+			Kafka:  KafkaConfig{Topic: "autocert-events", ClientID: "autocert", Timeout: 10 * time.Second},
+		},
 		ACME: ACMEConfig{
-			Server:  "https://acme-v02.api.letsencrypt.org/directory",
-			KeyType: "rsa",
-			KeySize: 2048,
+			Server:     "https://acme-v02.api.letsencrypt.org/directory",
+			KeyType:    "rsa",
+			KeySize:    2048,
+			KeyBackend: "file",
+			PKCS11:     PKCS11Config{Tool: "pkcs11-tool"},
+
+			AuthzConcurrency: 10,
+			KeyPool:          KeyPoolConfig{Size: 2},
+			CDNFallback:      "dns",
 		},
 	}
 
@@ -126,16 +1079,324 @@ func getDefaultConfig() *Config {
 
 // GetConfigDir 获取配置目录
 func GetConfigDir() string {
-	if AppConfig != nil {
-		return AppConfig.ConfigDir
+	if c := Current(); c != nil {
+		return c.ConfigDir
 	}
 	return getDefaultConfig().ConfigDir
 }
 
+// GetACMEConfig 获取 ACME 配置
+func GetACMEConfig() ACMEConfig {
+	if c := Current(); c != nil {
+		return c.ACME
+	}
+	return getDefaultConfig().ACME
+}
+
+// GetDomainConfig 获取指定域名的配置，未配置时返回 nil
+func GetDomainConfig(domain string) *DomainConfig {
+	c := Current()
+	if c == nil {
+		return nil
+	}
+	for i := range c.Domains {
+		if c.Domains[i].Domain == domain {
+			return &c.Domains[i]
+		}
+	}
+	return nil
+}
+
+// GetCertDomains 配置 sans 时返回证书应包含的域名（主域名在前，去除重复），未配置时返回 nil
+func GetCertDomains(domain string) []string {
+	domainConfig := GetDomainConfig(domain)
+	if domainConfig == nil || len(domainConfig.SANs) == 0 {
+		return nil
+	}
+
+	domains := []string{domain}
+	seen := map[string]bool{domain: true}
+	for _, san := range domainConfig.SANs {
+		san = strings.ToLower(strings.TrimSpace(san))
+		if san != "" && !seen[san] {
+			seen[san] = true
+			domains = append(domains, san)
+		}
+	}
+	return domains
+}
+
+// GetRenewBeforeDays 获取指定域名的续期天数（域名配置优先于全局配置），
+// 都未配置时返回 0：按证书有效期计算续期时间
+func GetRenewBeforeDays(domain string) int {
+	if domainConfig := GetDomainConfig(domain); domainConfig != nil && domainConfig.RenewBeforeDays > 0 {
+		return domainConfig.RenewBeforeDays
+	}
+	if c := Current(); c != nil && c.RenewBeforeDays > 0 {
+		return c.RenewBeforeDays
+	}
+	return 0
+}
+
+// GetPreferredChain 获取指定域名的首选证书链（域名配置优先于全局配置），未配置时返回空字符串
+func GetPreferredChain(domain string) string {
+	if domainConfig := GetDomainConfig(domain); domainConfig != nil && domainConfig.PreferredChain != "" {
+		return domainConfig.PreferredChain
+	}
+	return GetACMEConfig().PreferredChain
+}
+
+// GetHooks 获取指定域名的部署钩子（域名配置优先于全局配置）
+func GetHooks(domain string) []HookConfig {
+	if domainConfig := GetDomainConfig(domain); domainConfig != nil && len(domainConfig.Hooks) > 0 {
+		return domainConfig.Hooks
+	}
+	if c := Current(); c != nil {
+		return c.Hooks
+	}
+	return nil
+}
+
+// GetDeployTargets 获取指定域名的部署目标（域名配置优先于全局配置）
+func GetDeployTargets(domain string) []DeployConfig {
+	if domainConfig := GetDomainConfig(domain); domainConfig != nil && len(domainConfig.Deploy) > 0 {
+		return domainConfig.Deploy
+	}
+	if c := Current(); c != nil {
+		return c.Deploy
+	}
+	return nil
+}
+
+// GetTemplateBlocks 获取指定域名覆盖的站点配置模板 block，未配置时返回 nil
+func GetTemplateBlocks(domain string) map[string]string {
+	if domainConfig := GetDomainConfig(domain); domainConfig != nil {
+		return domainConfig.TemplateBlocks
+	}
+	return nil
+}
+
+// GetNoRedirect 域名的站点配置是否不重定向 HTTP 到 HTTPS
+func GetNoRedirect(domain string) bool {
+	domainConfig := GetDomainConfig(domain)
+	return domainConfig != nil && domainConfig.NoRedirect
+}
+
+// GetSelfHeal 证书损坏或与私钥不匹配时是否自动重新签发
+func GetSelfHeal() bool {
+	c := Current()
+	return c != nil && c.SelfHeal
+}
+
+// GetTimezone 显示时间使用的时区，为空表示系统本地时区
+func GetTimezone() string {
+	if c := Current(); c != nil {
+		return c.Timezone
+	}
+	return ""
+}
+
 // GetCertDir 获取证书目录
 func GetCertDir() string {
-	if AppConfig != nil {
-		return AppConfig.CertDir
+	if c := Current(); c != nil {
+		return c.CertDir
 	}
 	return getDefaultConfig().CertDir
 }
+
+// GetLogDir 获取日志目录
+func GetLogDir() string {
+	if c := Current(); c != nil && c.LogDir != "" {
+		return c.LogDir
+	}
+	return getDefaultConfig().LogDir
+}
+
+// GetWebServerConfig 获取 Web 服务器配置
+func GetWebServerConfig() WebServerConfig {
+	if c := Current(); c != nil {
+		return c.WebServer
+	}
+	return getDefaultConfig().WebServer
+}
+
+// GetDaemonConfig 获取守护进程配置
+func GetDaemonConfig() DaemonConfig {
+	if c := Current(); c != nil {
+		return c.Daemon
+	}
+	return getDefaultConfig().Daemon
+}
+
+// GetEventsConfig 获取事件转发配置
+func GetEventsConfig() EventsConfig {
+	if c := Current(); c != nil {
+		return c.Events
+	}
+	return getDefaultConfig().Events
+}
+
+// GetStatsConfig 获取本地使用统计配置
+func GetStatsConfig() StatsConfig {
+	if c := Current(); c != nil {
+		return c.Stats
+	}
+	return getDefaultConfig().Stats
+}
+
+// MinFreeBytes 写入前要求的最小剩余空间（字节）
+func (s StorageConfig) MinFreeBytes() uint64 {
+	if s.MinFreeMB <= 0 {
+		return 0
+	}
+	return uint64(s.MinFreeMB) << 20
+}
+
+// DirPerm 解析证书目录权限，所有者必须有读写和进入权限
+func (s StorageConfig) DirPerm() (os.FileMode, error) {
+	if s.DirMode == "" {
+		return 0755, nil
+	}
+	mode, err := strconv.ParseUint(s.DirMode, 8, 32)
+	if err != nil || mode&^0777 != 0 {
+		return 0, fmt.Errorf("无效的 storage.dir_mode: %q", s.DirMode)
+	}
+	if mode&0700 != 0700 {
+		return 0, fmt.Errorf("storage.dir_mode %q 必须包含所有者的读写和进入权限（0700）", s.DirMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// GetStorageConfig 获取证书存储配置
+func GetStorageConfig() StorageConfig {
+	if c := Current(); c != nil {
+		return c.Storage
+	}
+	return getDefaultConfig().Storage
+}
+
+// GetPFXConfig 获取 PFX 证书包配置
+func GetPFXConfig() PFXConfig {
+	if c := Current(); c != nil {
+		return c.PFX
+	}
+	return getDefaultConfig().PFX
+}
+
+// GetOutputConfig 获取证书输出配置
+func GetOutputConfig() OutputConfig {
+	if c := Current(); c != nil {
+		return c.Output
+	}
+	return getDefaultConfig().Output
+}
+
+// GetStandaloneConfig 获取 Standalone 验证配置
+func GetStandaloneConfig() StandaloneConfig {
+	if c := Current(); c != nil {
+		return c.Standalone
+	}
+	return getDefaultConfig().Standalone
+}
+
+// GetWebrootConfig 获取 Webroot 验证配置
+func GetWebrootConfig() WebrootConfig {
+	if c := Current(); c != nil {
+		return c.Webroot
+	}
+	return getDefaultConfig().Webroot
+}
+
+// GetDNSConfig 获取 DNS-01 验证配置
+func GetDNSConfig() DNSConfig {
+	if c := Current(); c != nil {
+		return c.DNS
+	}
+	return getDefaultConfig().DNS
+}
+
+// GetDANEConfig 获取 DANE 配置
+func GetDANEConfig() DANEConfig {
+	if c := Current(); c != nil {
+		return c.DANE
+	}
+	return getDefaultConfig().DANE
+}
+
+// GetCAConfig 获取本地 CA 配置
+func GetCAConfig() CAConfig {
+	var cfg CAConfig
+	if c := Current(); c != nil {
+		cfg = c.CA
+	} else {
+		cfg = getDefaultConfig().CA
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(GetConfigDir(), "ca")
+	}
+	return cfg
+}
+
+// GetBackupConfig 获取备份签名配置
+func GetBackupConfig() BackupConfig {
+	if c := Current(); c != nil {
+		return c.Backup
+	}
+	return getDefaultConfig().Backup
+}
+
+// GetProvisionConfig 获取首次启动自动配置
+func GetProvisionConfig() ProvisionConfig {
+	if c := Current(); c != nil {
+		return c.Provision
+	}
+	return getDefaultConfig().Provision
+}
+
+// GetPolicyConfig 获取签发策略
+func GetPolicyConfig() PolicyConfig {
+	if c := Current(); c != nil {
+		return c.Policy
+	}
+	return getDefaultConfig().Policy
+}
+
+// GetAuditConfig 获取审计日志配置
+func GetAuditConfig() AuditConfig {
+	if c := Current(); c != nil {
+		return c.Audit
+	}
+	return getDefaultConfig().Audit
+}
+
+// GetChallengeConfig 获取 http-01 挑战共享配置
+func GetChallengeConfig() ChallengeConfig {
+	if c := Current(); c != nil {
+		return c.Challenge
+	}
+	return getDefaultConfig().Challenge
+}
+
+// GetSyncConfig 获取证书目录同步配置
+func GetSyncConfig() SyncConfig {
+	if c := Current(); c != nil {
+		return c.Sync
+	}
+	return getDefaultConfig().Sync
+}
+
+// GetFeaturesConfig 获取实验性功能开关
+func GetFeaturesConfig() FeaturesConfig {
+	if c := Current(); c != nil {
+		return c.Features
+	}
+	return getDefaultConfig().Features
+}
+
+// GetApprovalConfig 获取双人审批配置
+func GetApprovalConfig() ApprovalConfig {
+	if c := Current(); c != nil {
+		return c.Approval
+	}
+	return getDefaultConfig().Approval
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// appendKeys 在包含的文件之间追加而不是覆盖的列表，以及列表项中不允许重复的字段
+var appendKeys = map[string]string{
+	"domains": "domain",
+	"hooks":   "name",
+}
+
+// LoadIncludes 按顺序合并主配置 include 中列出的配置文件，返回合并的文件。
+//
+// 合并规则（结果与文件的读取顺序无关，只取决于 include 的顺序和文件名）：
+//   - 通配符匹配的文件按文件名排序，相对路径相对于声明 include 的文件所在目录
+//   - 包含的文件中也可以声明 include，同一文件只合并一次
+//   - domains 和 hooks 追加到已有列表，同一域名或钩子名称在多个文件中定义时报错
+//   - 其他配置按层级合并，后合并的文件覆盖先前的值，列表整体替换
+func LoadIncludes() ([]string, error) {
+	l, err := loadIncludes(viper.GetViper())
+	if l == nil {
+		return nil, err
+	}
+	watchDirs = l.dirs
+	return l.files, err
+}
+
+// watchDirs 主配置、包含的文件以及 include 模式所在的目录，守护进程监视这些目录以重新加载配置
+var watchDirs []string
+
+// WatchDirs 需要监视的配置目录（包括尚无匹配文件的 conf.d 目录）
+func WatchDirs() []string {
+	return watchDirs
+}
+
+// loadIncludes 将 include 的文件合并到 v 中
+func loadIncludes(v *viper.Viper) (*includeLoader, error) {
+	mainFile := v.ConfigFileUsed()
+	if mainFile == "" {
+		return nil, nil
+	}
+	mainFile, err := filepath.Abs(mainFile)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &includeLoader{
+		v:       v,
+		seen:    map[string]bool{mainFile: true},
+		sources: make(map[string]map[string]string),
+	}
+	l.watch(filepath.Dir(mainFile))
+	for key, field := range appendKeys {
+		l.sources[key] = make(map[string]string)
+		for _, name := range itemNames(v.Get(key), field) {
+			l.sources[key][name] = mainFile
+		}
+	}
+
+	return l, l.include(v.GetStringSlice("include"), filepath.Dir(mainFile))
+}
+
+// includeLoader 记录已合并的文件和 domains/hooks 中各项的来源
+type includeLoader struct {
+	v       *viper.Viper
+	seen    map[string]bool
+	sources map[string]map[string]string // 列表键 -> 名称 -> 定义所在的文件
+	files   []string
+	dirs    []string
+}
+
+// watch 记录需要监视的目录
+func (l *includeLoader) watch(dir string) {
+	for _, existing := range l.dirs {
+		if existing == dir {
+			return
+		}
+	}
+	l.dirs = append(l.dirs, dir)
+}
+
+// include 合并一组 include 模式匹配的文件
+func (l *includeLoader) include(patterns []string, baseDir string) error {
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(baseDir, pattern)
+		}
+		l.watch(filepath.Dir(pattern))
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("include 模式 %s 无效: %w", pattern, err)
+		}
+		// 通配符没有匹配时视为空的 conf.d 目录，明确列出的文件必须存在
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return fmt.Errorf("包含的配置文件不存在: %s", pattern)
+		}
+		sort.Strings(matches)
+
+		for _, file := range matches {
+			if err := l.merge(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge 读取并合并一个文件，然后处理其中的 include
+func (l *includeLoader) merge(file string) error {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+	if l.seen[file] {
+		return nil
+	}
+	l.seen[file] = true
+
+	if err := ValidateFile(file); err != nil {
+		return err
+	}
+
+	sub := viper.New()
+	sub.SetConfigFile(file)
+	if err := sub.ReadInConfig(); err != nil {
+		return fmt.Errorf("读取包含的配置文件 %s 失败: %w", file, err)
+	}
+	settings := sub.AllSettings()
+	nested := sub.GetStringSlice("include")
+	delete(settings, "include")
+
+	for key, field := range appendKeys {
+		items, ok := settings[key].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, name := range itemNames(items, field) {
+			if previous, ok := l.sources[key][name]; ok {
+				return fmt.Errorf("%s 中的 %s 已在 %s 中定义: %s", file, key, previous, name)
+			}
+			l.sources[key][name] = file
+		}
+		if existing, ok := l.v.Get(key).([]interface{}); ok {
+			items = append(append([]interface{}{}, existing...), items...)
+		}
+		settings[key] = items
+	}
+
+	if err := l.v.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("合并配置文件 %s 失败: %w", file, err)
+	}
+	l.files = append(l.files, file)
+
+	return l.include(nested, filepath.Dir(file))
+}
+
+// itemNames 列表中各项的名称字段（域名或钩子名称），没有名称的项忽略
+func itemNames(value interface{}, field string) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, item := range items {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, ok := entry[field].(string); ok && name != "" {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	return names
+}
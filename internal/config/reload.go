@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// FileUsed 当前使用的配置文件路径，没有使用配置文件时为空
+func FileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+// Reload 重新读取配置文件（包括 include 的文件）。先在独立的实例中完成校验、合并和解析，
+// 全部通过后才替换当前配置，失败时当前配置保持不变。返回替换前的配置
+func Reload() (*Config, error) {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return nil, errors.New("未使用配置文件，无法重新加载")
+	}
+	if err := ValidateFile(file); err != nil {
+		return nil, err
+	}
+
+	staged := viper.New()
+	staged.SetConfigFile(file)
+	if err := staged.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if _, err := loadIncludes(staged); err != nil {
+		return nil, err
+	}
+	if err := staged.Unmarshal(&Config{}); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+
+	// 校验通过，应用到全局配置（默认值、环境变量和命令行标志的绑定保留）
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if _, err := LoadIncludes(); err != nil {
+		return nil, err
+	}
+	previous := Current()
+	if err := Load(); err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// Change 一项有效配置的变化
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// sensitiveKeys 配置项名称包含这些词时，差异中不显示值
+var sensitiveKeys = []string{"password", "token", "secret", "api_key", "apikey"}
+
+// Diff 比较两份有效配置，返回值不同的配置项（按键排序）。
+// domains 和 hooks 等列表按域名或名称对应，插入一项不会使后面的项都显示为变化；密码、令牌等敏感值不显示
+func Diff(old, new *Config) []Change {
+	before := make(map[string]string)
+	after := make(map[string]string)
+	flatten(reflect.ValueOf(*old), "", before)
+	flatten(reflect.ValueOf(*new), "", after)
+
+	keys := make(map[string]bool)
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	var changes []Change
+	for key := range keys {
+		o, oldOK := before[key]
+		n, newOK := after[key]
+		if oldOK && newOK && o == n {
+			continue
+		}
+		if isSensitive(key) {
+			o, n = maskValue(o), maskValue(n)
+		}
+		if !oldOK {
+			o = "(未配置)"
+		}
+		if !newOK {
+			n = "(未配置)"
+		}
+		changes = append(changes, Change{Key: key, Old: o, New: n})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flatten 将配置展开为 键路径 -> 值，键路径与配置文件中的写法一致
+func flatten(v reflect.Value, prefix string, out map[string]string) {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		out[prefix] = time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("mapstructure"), ",")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			flatten(v.Field(i), strings.TrimPrefix(prefix+"."+tag, "."), out)
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		for i := 0; i < v.Len(); i++ {
+			flatten(v.Index(i), fmt.Sprintf("%s[%s]", prefix, itemKey(v.Index(i), i)), out)
+		}
+	default:
+		out[prefix] = fmt.Sprint(v.Interface())
+	}
+}
+
+// itemKey 列表项的标识：域名或名称，没有时使用序号
+func itemKey(item reflect.Value, index int) string {
+	for _, field := range []string{"Domain", "Name"} {
+		if f := item.FieldByName(field); f.IsValid() && f.Kind() == reflect.String && f.String() != "" {
+			return f.String()
+		}
+	}
+	return fmt.Sprint(index)
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, word := range sensitiveKeys {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "******"
+}
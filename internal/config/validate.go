@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// flagKeys 通过 viper 绑定到命令行标志的配置项，不在 Config 中但允许写在配置文件里
+var flagKeys = map[string]bool{
+	"verbose":  true,
+	"no_color": true,
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Problem 配置文件中的一处错误
+type Problem struct {
+	Line    int    // 行号，从 1 开始
+	Key     string // 配置项路径，例如 acme.key_size、domains[0].domain
+	Message string
+}
+
+// ValidationError 配置文件校验失败，包含所有发现的错误
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "配置文件 %s 有 %d 处错误:", e.File, len(e.Problems))
+	for _, p := range e.Problems {
+		if p.Key == "" {
+			fmt.Fprintf(&b, "\n  第 %d 行: %s", p.Line, p.Message)
+		} else {
+			fmt.Fprintf(&b, "\n  第 %d 行 %s: %s", p.Line, p.Key, p.Message)
+		}
+	}
+	return b.String()
+}
+
+// ValidateFile 严格校验 YAML 配置文件：未知的配置项（例如把 acme.key_size 写成 acme.keysize）
+// 和类型不符的值都会报错，而不是被忽略后静默使用默认值。非 YAML 格式的文件不校验
+func ValidateFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	default:
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	v := &validator{}
+	v.walk(doc.Content[0], reflect.TypeOf(Config{}), "")
+	if len(v.problems) > 0 {
+		return &ValidationError{File: path, Problems: v.problems}
+	}
+	return nil
+}
+
+// validator 按 Config 的结构（mapstructure 标签）遍历 YAML 节点
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(node *yaml.Node, key, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Line: node.Line, Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) walk(node *yaml.Node, typ reflect.Type, key string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch {
+	case typ == durationType:
+		if node.Kind != yaml.ScalarNode {
+			v.add(node, key, "应为时长，例如 30s、5m、24h")
+		} else if node.Tag != "!!int" {
+			if _, err := time.ParseDuration(node.Value); err != nil {
+				v.add(node, key, "无效的时长 %q，例如 30s、5m、24h", node.Value)
+			}
+		}
+	case typ.Kind() == reflect.Struct:
+		v.walkStruct(node, typ, key)
+	case typ.Kind() == reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.add(node, key, "应为列表")
+			return
+		}
+		for i, item := range node.Content {
+			v.walk(item, typ.Elem(), fmt.Sprintf("%s[%d]", key, i))
+		}
+	case typ.Kind() == reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.add(node, key, "应为配置块（键: 值），实际为 %s", describe(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.walk(node.Content[i+1], typ.Elem(), key+"."+node.Content[i].Value)
+		}
+	case typ.Kind() == reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			v.add(node, key, "应为 true 或 false，实际为 %s", describe(node))
+		}
+	case typ.Kind() == reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			v.add(node, key, "应为整数，实际为 %s", describe(node))
+		}
+	case typ.Kind() == reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			v.add(node, key, "应为字符串，实际为 %s", describe(node))
+		}
+	}
+}
+
+func (v *validator) walkStruct(node *yaml.Node, typ reflect.Type, key string) {
+	if node.Kind != yaml.MappingNode {
+		v.add(node, key, "应为配置块（键: 值），实际为 %s", describe(node))
+		return
+	}
+
+	fields := structFields(typ)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i], node.Content[i+1]
+
+		// YAML 合并键（<<: *anchor）的内容属于同一个配置块
+		if name.Value == "<<" {
+			v.walk(value, typ, key)
+			continue
+		}
+
+		path := strings.TrimPrefix(key+"."+name.Value, ".")
+		field, ok := fields[strings.ToLower(name.Value)]
+		if !ok {
+			if key == "" && flagKeys[strings.ToLower(name.Value)] {
+				continue
+			}
+			if suggestion := suggestKey(name.Value, fields); suggestion != "" {
+				v.add(name, path, "未知的配置项，是否为 %s？", strings.TrimPrefix(key+"."+suggestion, "."))
+			} else {
+				v.add(name, path, "未知的配置项")
+			}
+			continue
+		}
+		v.walk(value, field, path)
+	}
+}
+
+// structFields 结构体中各 mapstructure 键（小写，与 viper 一致）对应的类型
+func structFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fields[strings.ToLower(tag)] = field.Type
+	}
+	return fields
+}
+
+// suggestKey 在同一层级中查找最接近的配置项：忽略下划线和大小写后相同，或编辑距离不超过 2
+func suggestKey(name string, fields map[string]reflect.Type) string {
+	normalize := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	}
+
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if normalize(candidate) == normalize(name) {
+			return candidate
+		}
+		if d := editDistance(strings.ToLower(name), candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance 两个字符串的 Levenshtein 距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// describe 错误信息中显示的节点内容
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "配置块"
+	case yaml.SequenceNode:
+		return "列表"
+	}
+	return strconv.Quote(node.Value)
+}
@@ -0,0 +1,72 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+// ANSI 颜色
+const (
+	Red    = "\033[31m"
+	Green  = "\033[32m"
+	Yellow = "\033[33m"
+	Gray   = "\033[90m"
+	reset  = "\033[0m"
+)
+
+// Stdout 面向用户的正常输出，Stderr 警告和错误输出
+var (
+	Stdout io.Writer = os.Stdout
+	Stderr io.Writer = os.Stderr
+)
+
+// noColor 是否禁用颜色（--no-color 或 NO_COLOR 环境变量）
+var noColor = os.Getenv("NO_COLOR") != ""
+
+// SetNoColor 禁用颜色输出
+func SetNoColor(disabled bool) {
+	noColor = disabled || os.Getenv("NO_COLOR") != ""
+}
+
+// IsTerminal 判断文件是否为交互式终端
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorEnabled 指定输出是否使用颜色：未禁用、是终端且终端支持 ANSI 颜色
+func ColorEnabled(f *os.File) bool {
+	if noColor || !IsTerminal(f) {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// 旧版控制台不支持 ANSI 转义序列，仅在 Windows Terminal 等环境中启用
+		return os.Getenv("WT_SESSION") != "" || os.Getenv("ANSICON") != "" || os.Getenv("TERM") != ""
+	}
+	return true
+}
+
+// Colorize 为文本添加颜色（输出不支持颜色时原样返回）
+func Colorize(f *os.File, color, text string) string {
+	if !ColorEnabled(f) {
+		return text
+	}
+	return color + text + reset
+}
+
+// Success 输出成功信息（标准输出，绿色 ✓）
+func Success(format string, args ...interface{}) {
+	fmt.Fprintln(Stdout, Colorize(os.Stdout, Green, "✓ "+fmt.Sprintf(format, args...)))
+}
+
+// Warn 输出警告信息（标准错误，黄色 ⚠）
+func Warn(format string, args ...interface{}) {
+	fmt.Fprintln(Stderr, Colorize(os.Stderr, Yellow, "⚠ "+fmt.Sprintf(format, args...)))
+}
+
+// Error 输出错误信息（标准错误，红色 ✗）
+func Error(format string, args ...interface{}) {
+	fmt.Fprintln(Stderr, Colorize(os.Stderr, Red, "✗ "+fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"autocert/internal/apitoken"
+	"autocert/internal/cert"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// certificateStatus GET /api/certificates 返回的证书状态
+type certificateStatus struct {
+	Name     string    `json:"name"`
+	Domains  []string  `json:"domains"`
+	Issuer   string    `json:"issuer,omitempty"`
+	NotAfter time.Time `json:"not_after,omitempty"`
+	DaysLeft int       `json:"days_left"`
+	KeyType  string    `json:"key_type,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// registerAPI 注册管理接口：证书状态（read 权限）和令牌管理（admin 权限）
+func (d *Daemon) registerAPI() {
+	d.mux.HandleFunc("/api/certificates", d.requireScope(apitoken.ScopeRead, d.handleCertificates))
+	d.mux.HandleFunc("/api/tokens", d.requireScope(apitoken.ScopeAdmin, d.handleTokens))
+	d.mux.HandleFunc("/api/tokens/", d.requireScope(apitoken.ScopeAdmin, d.handleRevokeToken))
+}
+
+// handleCertificates 处理 GET /api/certificates，列出证书目录中的证书及有效期
+func (d *Daemon) handleCertificates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "仅支持 GET 请求"})
+		return
+	}
+
+	stored, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		logger.Error("读取证书列表失败", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "读取证书列表失败"})
+		return
+	}
+
+	now := clock.Now()
+	certificates := make([]certificateStatus, 0, len(stored))
+	for _, s := range stored {
+		status := certificateStatus{Name: s.Name, Domains: s.Domains}
+		details, err := s.Details()
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Domains = details.Domains
+			status.Issuer = details.Issuer
+			status.NotAfter = details.NotAfter
+			status.DaysLeft = int(details.NotAfter.Sub(now).Hours() / 24)
+			status.KeyType = details.KeyType
+		}
+		certificates = append(certificates, status)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"certificates": certificates})
+}
+
+// handleTokens 处理 GET /api/tokens，列出 API 令牌（不包含令牌本身和哈希）
+func (d *Daemon) handleTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "仅支持 GET 请求"})
+		return
+	}
+
+	tokens, err := d.tokens.List()
+	if err != nil {
+		logger.Error("读取 API 令牌失败", "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "令牌存储暂时不可用"})
+		return
+	}
+	for _, token := range tokens {
+		token.Hash = ""
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+// handleRevokeToken 处理 DELETE /api/tokens/<id>，将令牌加入吊销列表
+func (d *Daemon) handleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "仅支持 DELETE 请求"})
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/api/tokens/")
+	err := d.tokens.Revoke(id)
+	if errors.Is(err, apitoken.ErrInvalid) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Error("吊销 API 令牌失败", "token", id, "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "令牌存储暂时不可用"})
+		return
+	}
+
+	logger.Info("API 令牌已吊销", "token", id, "remote", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked", "id": id})
+}
@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"autocert/internal/apitoken"
+	"autocert/internal/logger"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// requireScope 校验请求携带的令牌（Authorization: Bearer <token> 或 X-AutoCert-Token）是否包含 scope 权限。
+// 接受 API 令牌（autocert token create）和 daemon.webhook.token，后者视为 admin 权限
+func (d *Daemon) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value := requestToken(r)
+		if value == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "缺少令牌"})
+			return
+		}
+
+		if d.config.Webhook.Token != "" && subtle.ConstantTimeCompare([]byte(value), []byte(d.config.Webhook.Token)) == 1 {
+			next(w, r)
+			return
+		}
+
+		token, err := d.tokens.Verify(value)
+		switch {
+		case errors.Is(err, apitoken.ErrInvalid), errors.Is(err, apitoken.ErrExpired), errors.Is(err, apitoken.ErrRevoked):
+			fields := []interface{}{"path", r.URL.Path, "remote", r.RemoteAddr, "reason", err}
+			if token != nil {
+				fields = append(fields, "token", token.ID)
+			}
+			logger.Warn("API 认证失败", fields...)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		case err != nil:
+			logger.Error("验证 API 令牌失败", "path", r.URL.Path, "error", err)
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "令牌存储暂时不可用"})
+			return
+		}
+
+		if !token.Allows(scope) {
+			logger.Warn("API 令牌权限不足", "path", r.URL.Path, "remote", r.RemoteAddr, "token", token.ID, "scope", token.Scope, "required", scope)
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "令牌没有 " + scope + " 权限"})
+			return
+		}
+		logger.Debug("API 请求已认证", "path", r.URL.Path, "token", token.ID, "scope", token.Scope)
+		next(w, r)
+	}
+}
+
+// requestToken 读取请求携带的令牌
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return r.Header.Get("X-AutoCert-Token")
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"autocert/internal/ca"
+	"autocert/internal/logger"
+	"encoding/pem"
+	"net/http"
+	"os"
+)
+
+// ocspPath OCSP 服务路径，GET 请求为 /ca/ocsp/<base64 编码的请求>
+const ocspPath = "/ca/ocsp"
+
+// registerCA 注册本地 CA 的 OCSP 和 CRL 下载路由
+func (d *Daemon) registerCA(authority *ca.CA) {
+	handler := authority.OCSPHandler(ocspPath)
+	d.mux.Handle(ocspPath, handler)
+	d.mux.Handle(ocspPath+"/", handler)
+	d.mux.HandleFunc("/ca/crl", func(w http.ResponseWriter, r *http.Request) {
+		handleCRL(w, r, authority)
+	})
+}
+
+// handleCRL 以 DER 格式（application/pkix-crl）提供 CRL 下载，用于 CRL 分发点
+func handleCRL(w http.ResponseWriter, r *http.Request, authority *ca.CA) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "仅支持 GET 请求", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := os.ReadFile(authority.CRLPath())
+	if err != nil {
+		logger.Error("读取 CRL 失败", "path", authority.CRLPath(), "error", err)
+		http.Error(w, "CRL 不可用", http.StatusInternalServerError)
+		return
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		http.Error(w, "CRL 不可用", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	w.Write(block.Bytes)
+}
+
+// refreshCRL 定期检查时更新即将到期的 CRL
+func (d *Daemon) refreshCRL() {
+	if d.ca == nil {
+		return
+	}
+	refreshed, err := d.ca.RefreshCRL()
+	if err != nil {
+		logger.Error("更新 CRL 失败", "error", err)
+		return
+	}
+	if refreshed {
+		logger.Info("已更新本地 CA 的 CRL", "path", d.ca.CRLPath())
+	}
+}
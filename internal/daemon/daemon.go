@@ -0,0 +1,373 @@
+package daemon
+
+import (
+	"autocert/internal/agent"
+	"autocert/internal/apitoken"
+	"autocert/internal/ca"
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/dns"
+	"autocert/internal/features"
+	"autocert/internal/keypool"
+	"autocert/internal/logger"
+	"autocert/internal/renewal"
+	"autocert/internal/storage"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jobQueueSize 待处理的按需签发/续期任务上限
+const jobQueueSize = 64
+
+// job 按需签发/续期任务
+type job struct {
+	domain  string
+	force   bool
+	webroot string // 不为空时以 Webroot 模式签发新证书
+	source  string // 任务来源，用于日志
+}
+
+// Daemon 守护进程：定期续期证书，并可选地提供 HTTP 接口
+type Daemon struct {
+	config   config.DaemonConfig
+	mux      *http.ServeMux
+	jobs     chan job
+	onDemand *onDemandWatcher
+	ca       *ca.CA          // 本地 CA，配置 ca.serve 时提供 OCSP/CRL
+	tokens   *apitoken.Store // API 令牌，启用 webhook 时校验 HTTP 接口的请求
+
+	serverTLS *serverTLS // 配置 daemon.tls 时 HTTP 接口使用 HTTPS 和 mTLS
+	tlsConfig *tls.Config
+
+	helper *deployHelper // 降权运行时以 root 部署证书的部署助手
+
+	// ConfigSource 重新加载配置后读取守护进程配置，调用方可以在其中应用命令行参数的覆盖
+	ConfigSource func() config.DaemonConfig
+
+	// 串行化证书操作，避免定时续期与按需任务同时写证书文件
+	mu sync.Mutex
+}
+
+// New 创建守护进程
+func New(cfg config.DaemonConfig) (*Daemon, error) {
+	cfg, err := normalizeSchedule(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Daemon{
+		config:       cfg,
+		mux:          http.NewServeMux(),
+		jobs:         make(chan job, jobQueueSize),
+		ConfigSource: config.GetDaemonConfig,
+	}
+
+	if cfg.Webhook.Enabled {
+		if cfg.Listen == "" {
+			return nil, fmt.Errorf("启用 webhook 时必须配置 daemon.listen")
+		}
+		// daemon.webhook.token 可以为空，此时只接受 API 令牌（autocert token create）
+		tokens, err := apitoken.Open()
+		if err != nil {
+			return nil, err
+		}
+		d.tokens = tokens
+		d.registerWebhook()
+		if features.Enabled(features.DaemonAPI) {
+			d.registerAPI()
+		}
+	}
+
+	if cfg.OnDemand.Enabled {
+		if err := features.Require(features.OnDemand, "daemon.on_demand"); err != nil {
+			return nil, err
+		}
+		watcher, err := newOnDemandWatcher(cfg.OnDemand, d)
+		if err != nil {
+			return nil, err
+		}
+		d.onDemand = watcher
+		if cfg.Webhook.Enabled && features.Gate(features.DaemonAPI, "/api/on-demand") {
+			d.registerOnDemandAPI()
+		}
+	}
+
+	if cfg.TLS.Enabled {
+		if cfg.Listen == "" {
+			return nil, fmt.Errorf("启用 daemon.tls 时必须配置 daemon.listen")
+		}
+		tlsConfig, err := d.newServerTLS(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		d.tlsConfig = tlsConfig
+	}
+
+	if config.GetCAConfig().Serve {
+		if cfg.Listen == "" {
+			return nil, fmt.Errorf("启用 ca.serve 时必须配置 daemon.listen")
+		}
+		authority, err := ca.Open()
+		if err != nil {
+			return nil, err
+		}
+		d.ca = authority
+		d.registerCA(authority)
+	}
+
+	return d, nil
+}
+
+// Run 运行守护进程直到 ctx 被取消
+func (d *Daemon) Run(ctx context.Context) error {
+	logger.Info("守护进程启动", "interval", d.config.Interval, "schedule", d.config.Schedule, "listen", d.config.Listen)
+
+	errCh := make(chan error, 1)
+	if d.config.Listen != "" {
+		var handler http.Handler = d.mux
+		if d.serverTLS != nil {
+			handler = d.serverTLS.requireClientCert(handler)
+		}
+		server := &http.Server{
+			Addr:              d.config.Listen,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		// 先绑定端口再降权，监听特权端口时仍可切换到非特权用户
+		listener, err := net.Listen("tcp", d.config.Listen)
+		if err != nil {
+			return fmt.Errorf("HTTP 服务启动失败: %w", err)
+		}
+		if d.tlsConfig != nil {
+			listener = tls.NewListener(listener, d.tlsConfig)
+		}
+
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("HTTP 服务运行失败: %w", err)
+			}
+		}()
+
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+	}
+
+	if err := d.dropPrivileges(); err != nil {
+		return err
+	}
+	if d.helper != nil {
+		defer d.helper.close()
+	}
+
+	go d.worker(ctx)
+	d.startKeyPool(ctx)
+
+	// 配置文件修改或收到 SIGHUP 时重新加载配置
+	reloaded := make(chan config.DaemonConfig, 1)
+	go d.watchConfig(ctx, reloaded)
+
+	if d.onDemand != nil {
+		go d.onDemand.run(ctx)
+	}
+
+	// 无状态部署时先从持久化后端恢复证书，再执行一次续期检查
+	d.pullStorage()
+	d.checkPermissions()
+	d.cleanupDNS()
+	d.renewAll()
+	d.refreshCRL()
+
+	timer := time.NewTimer(d.nextRun())
+	defer timer.Stop()
+
+	// CRL 的有效期可能短于续期检查间隔，单独每小时检查一次
+	var crlTick <-chan time.Time
+	if d.ca != nil {
+		crlTicker := time.NewTicker(time.Hour)
+		defer crlTicker.Stop()
+		crlTick = crlTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("守护进程退出")
+			return nil
+		case err := <-errCh:
+			return err
+		case <-timer.C:
+			d.checkPermissions()
+			d.cleanupDNS()
+			d.renewAll()
+			timer.Reset(d.nextRun())
+		case <-crlTick:
+			d.refreshCRL()
+		case schedule := <-reloaded:
+			if schedule.Interval != d.config.Interval || schedule.Schedule != d.config.Schedule ||
+				schedule.MinInterval != d.config.MinInterval || schedule.Jitter != d.config.Jitter {
+				d.config.Interval = schedule.Interval
+				d.config.Schedule = schedule.Schedule
+				d.config.MinInterval = schedule.MinInterval
+				d.config.Jitter = schedule.Jitter
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(d.nextRun())
+			}
+		}
+	}
+}
+
+// startKeyPool 配置 acme.key_pool.enabled 时在后台预生成私钥，续期时不必等待 RSA 私钥生成
+func (d *Daemon) startKeyPool(ctx context.Context) {
+	acmeConfig := config.GetACMEConfig()
+	if !acmeConfig.KeyPool.Enabled {
+		return
+	}
+	spec, err := keypool.NewSpec(acmeConfig.KeyType, acmeConfig.KeySize)
+	if err != nil {
+		logger.Warn("无法预生成私钥", "error", err)
+		return
+	}
+	go keypool.Fill(ctx, spec, acmeConfig.KeyPool.Size, 1)
+}
+
+// enqueue 提交按需任务，队列已满时返回 false
+func (d *Daemon) enqueue(j job) bool {
+	select {
+	case d.jobs <- j:
+		return true
+	default:
+		return false
+	}
+}
+
+// worker 顺序处理按需任务
+func (d *Daemon) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-d.jobs:
+			d.mu.Lock()
+			logger.Info("处理按需签发/续期任务", "domain", j.domain, "force", j.force, "source", j.source)
+
+			var err error
+			if j.webroot != "" {
+				err = renewal.Issue([]string{j.domain}, renewal.IssueOptions{Webroot: j.webroot})
+			} else {
+				err = renewal.RenewDomain(j.domain, j.force)
+			}
+
+			if err != nil {
+				logger.Error("按需签发/续期失败", "domain", j.domain, "source", j.source, "error", err)
+			} else {
+				logger.Info("按需签发/续期完成", "domain", j.domain, "source", j.source)
+			}
+			if d.onDemand != nil && j.source == onDemandSource {
+				d.onDemand.markResult(j.domain, err)
+			}
+			d.mu.Unlock()
+		}
+	}
+}
+
+// pullStorage 从持久化后端恢复证书到本地证书目录（未配置后端时不执行）
+func (d *Daemon) pullStorage() {
+	backend, err := storage.Open()
+	if err != nil {
+		logger.Error("打开存储后端失败", "error", err)
+		return
+	}
+	if backend == nil {
+		return
+	}
+
+	changed, err := cert.PullCertificates(backend)
+	if err != nil {
+		logger.Error("从存储后端恢复证书失败", "backend", backend.Name(), "error", err)
+		return
+	}
+	logger.Info("已从存储后端恢复证书", "backend", backend.Name(), "changed", changed)
+}
+
+// checkPermissions 检查证书目录和私钥文件的权限，配置 storage.fix_permissions 时自动修正，否则只记录警告
+func (d *Daemon) checkPermissions() {
+	fix := config.GetStorageConfig().FixPermissions
+	issues, err := cert.CheckPermissions(config.GetCertDir(), fix)
+	if err != nil {
+		logger.Error("检查证书目录权限失败", "error", err)
+		return
+	}
+	for _, issue := range issues {
+		switch {
+		case issue.Err != nil:
+			logger.Error("证书目录权限不符合要求且无法修正", "path", issue.Path, "mode", issue.Mode, "want", issue.Want, "error", issue.Err)
+		case !fix:
+			logger.Warn("证书目录权限不符合要求，可执行 autocert doctor --fix 修正（或配置 storage.fix_permissions 自动修正）",
+				"path", issue.Path, "mode", issue.Mode, "want", issue.Want)
+		}
+	}
+}
+
+// cleanupDNS 删除残留的 DNS 验证记录
+func (d *Daemon) cleanupDNS() {
+	removed, err := dns.Cleanup(config.GetDNSConfig().CleanupAfter, false)
+	if len(removed) > 0 {
+		logger.Info("已删除残留的 DNS 验证记录", "count", len(removed))
+	}
+	if err != nil {
+		logger.Error("删除残留的 DNS 验证记录失败", "error", err)
+	}
+}
+
+// renewAll 执行一轮续期检查，本机已注册为 agent 时随后向控制节点签到
+func (d *Daemon) renewAll() {
+	defer checkIn()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	renewalReport, err := renewal.RenewAll(renewal.Options{})
+	if err != nil {
+		logger.Error("续期检查失败", "error", err)
+		return
+	}
+
+	logger.Info("续期检查完成",
+		"renewed", len(renewalReport.Renewed),
+		"skipped", len(renewalReport.Skipped),
+		"failed", len(renewalReport.Failed))
+}
+
+// checkIn 本机已注册为 agent 时向控制节点签到，报告部署的证书版本
+func checkIn() {
+	identity, err := agent.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		logger.Warn("读取 agent 身份失败", "error", err)
+		return
+	}
+	status, err := identity.CheckIn()
+	if err != nil {
+		logger.Warn("向控制节点签到失败", "server", identity.Server, "error", err)
+		return
+	}
+	if status == agent.StatusPending {
+		logger.Warn("agent 等待控制节点批准", "server", identity.Server, "agent", identity.Name)
+		return
+	}
+	logger.Info("已向控制节点签到", "server", identity.Server, "agent", identity.Name)
+}
@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// deployHelperArgs 启动部署助手的子命令
+var deployHelperArgs = []string{"daemon", "deploy-helper"}
+
+// helperRequest 请求部署助手部署证书目录中的证书
+type helperRequest struct {
+	Name string `json:"name"`
+}
+
+// helperResponse 部署结果
+type helperResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// deployHelper 降权前启动的 root 部署助手进程。降权后的守护进程只负责签发，写入站点配置、
+// 重载 Web 服务器和执行部署钩子通过管道交给部署助手
+type deployHelper struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// startDeployHelper 以当前（root）身份启动部署助手，使用相同的配置文件
+func startDeployHelper() (*deployHelper, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{}, deployHelperArgs...)
+	if file := config.FileUsed(); file != "" {
+		args = append(args, "--config", file)
+	}
+
+	cmd := exec.Command(exe, args...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	logger.Info("已启动部署助手", "pid", cmd.Process.Pid)
+	return &deployHelper{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// Deploy 请求部署助手部署证书目录 name 中的证书，等待部署完成
+func (h *deployHelper) Deploy(name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	data, err := json.Marshal(helperRequest{Name: name})
+	if err != nil {
+		return err
+	}
+	if _, err := h.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("部署助手已退出: %w", err)
+	}
+	line, err := h.stdout.ReadBytes('\n')
+	if err != nil {
+		return fmt.Errorf("部署助手已退出: %w", err)
+	}
+	var resp helperResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return fmt.Errorf("解析部署助手响应失败: %w", err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+// close 关闭管道，部署助手读到 EOF 后退出
+func (h *deployHelper) close() {
+	h.stdin.Close()
+	h.cmd.Wait()
+}
+
+// RunDeployHelper 部署助手的主循环：每行一个请求，部署前重新读取配置（守护进程可能已重新加载），
+// 标准输入关闭（守护进程退出）时返回
+func RunDeployHelper(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	encoder := json.NewEncoder(out)
+	for scanner.Scan() {
+		var req helperRequest
+		var resp helperResponse
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = fmt.Sprintf("请求格式错误: %v", err)
+		} else {
+			if config.FileUsed() != "" {
+				if _, err := config.Reload(); err != nil {
+					logger.Warn("部署助手重新读取配置失败，使用当前配置", "error", err)
+				}
+			}
+			if err := cert.DeployStored(req.Name); err != nil {
+				resp.Error = err.Error()
+			}
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
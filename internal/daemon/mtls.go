@@ -0,0 +1,376 @@
+package daemon
+
+import (
+	"autocert/internal/agent"
+	"autocert/internal/ca"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 客户端证书要求
+const (
+	clientAuthRequire  = "require"
+	clientAuthOptional = "optional"
+	clientAuthNone     = "none"
+)
+
+// 本地 CA 签发的服务端证书保存在 CA 目录中，有效期 90 天，剩余不足 30 天时重新签发
+const (
+	serverCertFile     = "daemon.pem"
+	serverKeyFile      = "daemon.key"
+	serverCertValidity = 90 * 24 * time.Hour
+	serverCertRenew    = 30 * 24 * time.Hour
+)
+
+// publicPaths 要求客户端证书时仍允许匿名访问的路径：agent 注册（此时还没有证书）、OCSP 和 CRL
+var publicPaths = []string{agent.EnrollPath, "/ca/"}
+
+// serverTLS 守护进程 HTTPS 接口
+type serverTLS struct {
+	config     config.DaemonTLSConfig
+	authority  *ca.CA
+	clientAuth string
+	inventory  *agent.Inventory
+
+	mu   sync.Mutex
+	cert *tls.Certificate
+}
+
+// newServerTLS 按配置 daemon.tls 创建 TLS 配置，并在启用 mTLS 时注册 agent 注册和签到接口
+func (d *Daemon) newServerTLS(cfg config.DaemonTLSConfig) (*tls.Config, error) {
+	clientAuth := strings.ToLower(cfg.ClientAuth)
+	switch clientAuth {
+	case "":
+		clientAuth = clientAuthRequire
+	case clientAuthRequire, clientAuthOptional, clientAuthNone:
+	default:
+		return nil, fmt.Errorf("daemon.tls.client_auth 无效: %s（可选 require、optional、none）", cfg.ClientAuth)
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("daemon.tls.cert_file 和 key_file 需要同时配置")
+	}
+
+	authority, err := ca.Open()
+	if err != nil {
+		return nil, fmt.Errorf("daemon.tls 需要本地 CA: %w", err)
+	}
+	s := &serverTLS{config: cfg, authority: authority, clientAuth: clientAuth}
+	if _, err := s.certificate(); err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return s.certificate() },
+	}
+	if clientAuth != clientAuthNone {
+		roots := x509.NewCertPool()
+		roots.AddCert(authority.Cert)
+		tlsConfig.ClientCAs = roots
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		tlsConfig.VerifyConnection = s.verifyConnection
+		if s.inventory, err = agent.OpenInventory(); err != nil {
+			return nil, err
+		}
+		d.mux.HandleFunc(agent.EnrollPath, s.handleEnroll)
+		d.mux.HandleFunc(agent.CheckInPath, s.handleCheckIn)
+	}
+	d.serverTLS = s
+	return tlsConfig, nil
+}
+
+// certificate 返回服务端证书：使用配置的证书文件，或由本地 CA 签发（到期前重新签发）
+func (s *serverTLS) certificate() (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cert != nil && (s.config.CertFile != "" || time.Until(s.cert.Leaf.NotAfter) > serverCertRenew) {
+		return s.cert, nil
+	}
+
+	certFile, keyFile := s.config.CertFile, s.config.KeyFile
+	if certFile == "" {
+		certFile = filepath.Join(s.authority.Dir(), serverCertFile)
+		keyFile = filepath.Join(s.authority.Dir(), serverKeyFile)
+		if err := s.issue(certFile, keyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	cert, err := loadKeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载守护进程 TLS 证书失败: %w", err)
+	}
+	s.cert = cert
+	return s.cert, nil
+}
+
+// loadKeyPair 加载证书和私钥，并解析叶子证书
+func loadKeyPair(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &cert, nil
+}
+
+// issue 证书文件不存在、即将到期或域名变化时由本地 CA 重新签发。证书文件同时包含 CA 根证书，
+// agent 注册时据此与加入令牌中的指纹比对
+func (s *serverTLS) issue(certFile, keyFile string) error {
+	names := s.names()
+	if cert, err := loadKeyPair(certFile, keyFile); err == nil &&
+		time.Until(cert.Leaf.NotAfter) > serverCertRenew && coversNames(cert.Leaf, names) {
+		return nil
+	}
+
+	issued, err := s.authority.IssueServer(names, serverCertValidity)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(issued.Key)
+	if err != nil {
+		return err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: issued.Cert.Raw})
+	certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.authority.Cert.Raw})...)
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("写入守护进程 TLS 私钥失败: %w", err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return fmt.Errorf("写入守护进程 TLS 证书失败: %w", err)
+	}
+	return nil
+}
+
+// names 服务端证书的域名和 IP
+func (s *serverTLS) names() []string {
+	if len(s.config.Names) > 0 {
+		return s.config.Names
+	}
+	names := []string{"localhost", "127.0.0.1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		names = append([]string{hostname}, names...)
+	}
+	return names
+}
+
+// coversNames 证书是否包含全部域名和 IP
+func coversNames(cert *x509.Certificate, names []string) bool {
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			found := false
+			for _, certIP := range cert.IPAddresses {
+				found = found || certIP.Equal(ip)
+			}
+			if !found {
+				return false
+			}
+		} else if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyConnection 握手时拒绝已吊销的客户端证书
+func (s *serverTLS) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	if err := s.authority.CheckRevoked(state.PeerCertificates[0]); err != nil {
+		logger.Warn("拒绝已吊销的客户端证书", "subject", state.PeerCertificates[0].Subject.CommonName, "error", err)
+		return err
+	}
+	return nil
+}
+
+// requireClientCert client_auth 为 require 时，除 publicPaths 外的请求都需要已注册 agent 的客户端证书。
+// 本地 CA 也为普通客户端签发证书（ca issue-client），只有序列号与 agent 清单中的记录一致的证书才被接受；
+// 等待批准的 agent 只能签到
+func (s *serverTLS) requireClientCert(next http.Handler) http.Handler {
+	if s.clientAuth != clientAuthRequire {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range publicPaths {
+			if r.URL.Path == path || strings.HasSuffix(path, "/") && strings.HasPrefix(r.URL.Path, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			logger.Warn("请求没有有效的客户端证书", "path", r.URL.Path, "remote", r.RemoteAddr)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "需要已注册 agent 的客户端证书"})
+			return
+		}
+
+		peer := r.TLS.VerifiedChains[0][0]
+		name, serial := peer.Subject.CommonName, fmt.Sprintf("%X", peer.SerialNumber)
+		record, err := s.inventory.Get(name)
+		if err != nil && !errors.Is(err, agent.ErrUnknownAgent) {
+			logger.Error("读取 agent 记录失败", "agent", name, "error", err)
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "读取 agent 清单失败"})
+			return
+		}
+		if err != nil || record.Serial != serial {
+			logger.Warn("拒绝不属于已注册 agent 的客户端证书", "subject", name, "serial", serial, "path", r.URL.Path, "remote", r.RemoteAddr)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "需要已注册 agent 的客户端证书"})
+			return
+		}
+		if record.Status != agent.StatusApproved && r.URL.Path != agent.CheckInPath {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "agent 等待控制节点批准"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleEnroll 处理 POST /agent/enroll：使用一次性加入令牌注册 agent，为其 CSR 签发客户端证书
+func (s *serverTLS) handleEnroll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "仅支持 POST 请求"})
+		return
+	}
+
+	var req agent.EnrollRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "请求格式错误"})
+		return
+	}
+	if !ca.ValidAgentName(req.Name) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "agent 名称无效"})
+		return
+	}
+	block, _ := pem.Decode([]byte(req.CSR))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "CSR 格式错误"})
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err == nil {
+		err = csr.CheckSignature()
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "CSR 格式错误"})
+		return
+	}
+
+	// 证书签发成功后加入令牌才失效，签发失败时 agent 可以用同一令牌重试
+	validity := time.Duration(config.GetCAConfig().ClientDays) * 24 * time.Hour
+	var cert *x509.Certificate
+	var record ca.Record
+	var signErr error
+	token, err := s.authority.RedeemJoinToken(req.Token, req.Name, func(*ca.JoinToken) error {
+		cert, record, signErr = s.authority.SignAgent(req.Name, csr, validity)
+		return signErr
+	})
+	if signErr != nil {
+		logger.Error("为 agent 签发证书失败", "agent", req.Name, "error", signErr)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": signErr.Error()})
+		return
+	}
+	if errors.Is(err, ca.ErrJoinToken) {
+		logger.Warn("agent 注册失败：加入令牌无效", "agent", req.Name, "remote", r.RemoteAddr)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Warn("agent 注册失败", "agent", req.Name, "remote", r.RemoteAddr, "error", err)
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// 限定名称的加入令牌视为已批准；未限定名称时 agent 名称由对方指定，需要在控制节点批准
+	status := agent.StatusPending
+	if token.Name != "" {
+		status = agent.StatusApproved
+	}
+	if err := s.inventory.Put(&agent.Record{
+		Name:       req.Name,
+		Status:     status,
+		Serial:     record.Serial,
+		EnrolledAt: time.Now(),
+		Address:    r.RemoteAddr,
+	}); err != nil {
+		logger.Error("保存 agent 记录失败", "agent", req.Name, "error", err)
+	}
+
+	logger.Info("agent 已注册", "agent", req.Name, "serial", record.Serial, "status", status, "join_token", token.ID, "remote", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, agent.EnrollResponse{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		CA:          string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.authority.Cert.Raw})),
+		Serial:      record.Serial,
+		NotAfter:    record.NotAfter,
+	})
+}
+
+// handleCheckIn 处理 POST /agent/checkin：按客户端证书识别 agent，记录签到时间和部署的证书版本
+func (s *serverTLS) handleCheckIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, agent.CheckInResponse{Error: "仅支持 POST 请求"})
+		return
+	}
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		writeJSON(w, http.StatusUnauthorized, agent.CheckInResponse{Error: "需要本地 CA 签发的客户端证书"})
+		return
+	}
+	peer := r.TLS.VerifiedChains[0][0]
+	name, serial := peer.Subject.CommonName, fmt.Sprintf("%X", peer.SerialNumber)
+
+	record, err := s.inventory.Get(name)
+	if errors.Is(err, agent.ErrUnknownAgent) || err == nil && record.Serial != serial {
+		// 已从清单移除，或已使用新证书重新注册
+		logger.Warn("拒绝未登记的 agent 签到", "agent", name, "serial", serial, "remote", r.RemoteAddr)
+		writeJSON(w, http.StatusForbidden, agent.CheckInResponse{Error: "agent 不在清单中或证书已被替换，请重新注册"})
+		return
+	}
+	if err != nil {
+		logger.Error("读取 agent 记录失败", "agent", name, "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, agent.CheckInResponse{Error: "读取 agent 清单失败"})
+		return
+	}
+
+	var req agent.CheckInRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, agent.CheckInResponse{Error: "请求格式错误"})
+		return
+	}
+
+	now := time.Now()
+	record.LastCheckIn = &now
+	record.Address = r.RemoteAddr
+	if record.Status == agent.StatusApproved {
+		record.Certificates = req.Certificates
+	}
+	if err := s.inventory.Put(record); err != nil {
+		logger.Error("保存 agent 签到失败", "agent", name, "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, agent.CheckInResponse{Error: "保存签到失败"})
+		return
+	}
+
+	if record.Status != agent.StatusApproved {
+		logger.Info("待批准的 agent 签到", "agent", name, "remote", r.RemoteAddr)
+		writeJSON(w, http.StatusForbidden, agent.CheckInResponse{Status: record.Status, Error: "等待控制节点批准"})
+		return
+	}
+	logger.Info("agent 签到", "agent", name, "certificates", len(req.Certificates), "remote", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, agent.CheckInResponse{Status: record.Status})
+}
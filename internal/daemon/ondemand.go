@@ -0,0 +1,320 @@
+package daemon
+
+import (
+	"autocert/internal/apitoken"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// onDemandSource 按需签发任务的来源标识
+const onDemandSource = "on-demand"
+
+// 按需签发状态
+const (
+	onDemandPending = "pending"
+	onDemandIssued  = "issued"
+	onDemandFailed  = "failed"
+)
+
+// onDemandEntry 单个域名的按需签发状态
+type onDemandEntry struct {
+	Status      string    `json:"status"`
+	LastAttempt time.Time `json:"last_attempt"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// onDemandWatcher 监视域名队列文件，为新出现的域名验证所有权并签发证书（实验性）
+type onDemandWatcher struct {
+	config    config.OnDemandConfig
+	daemon    *Daemon
+	statePath string
+	client    *http.Client
+
+	mu    sync.Mutex
+	state map[string]*onDemandEntry
+
+	invalid map[string]bool // 上次扫描时队列文件中的无效行，只对新出现的无效行发出警告（只在 scan 中使用）
+}
+
+// newOnDemandWatcher 创建按需签发监视器
+func newOnDemandWatcher(cfg config.OnDemandConfig, d *Daemon) (*onDemandWatcher, error) {
+	if cfg.QueueFile == "" {
+		return nil, fmt.Errorf("启用按需签发时必须配置 daemon.on_demand.queue_file")
+	}
+	if cfg.Webroot == "" {
+		return nil, fmt.Errorf("启用按需签发时必须配置 daemon.on_demand.webroot")
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 30 * time.Second
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Hour
+	}
+
+	w := &onDemandWatcher{
+		config:    cfg,
+		daemon:    d,
+		statePath: filepath.Join(config.GetConfigDir(), "on-demand-state.json"),
+		client:    &http.Client{Timeout: 10 * time.Second},
+		state:     make(map[string]*onDemandEntry),
+	}
+	w.loadState()
+
+	logger.Warn("按需签发为实验性功能", "queueFile", cfg.QueueFile, "webroot", cfg.Webroot)
+	return w, nil
+}
+
+// run 定期检查队列文件直到 ctx 被取消
+func (w *onDemandWatcher) run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	w.scan()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.scan()
+		}
+	}
+}
+
+// scan 读取队列文件，为需要处理的域名提交签发任务
+func (w *onDemandWatcher) scan() {
+	domains, invalid, err := readQueueFile(w.config.QueueFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("读取域名队列文件失败", "file", w.config.QueueFile, "error", err)
+		}
+		return
+	}
+	w.reportInvalid(invalid)
+
+	for _, domain := range domains {
+		if !w.due(domain) {
+			continue
+		}
+
+		// 确认域名已指向本机（共享 webroot 可访问），否则 http-01 验证必然失败
+		if err := w.verifyOwnership(domain); err != nil {
+			logger.Warn("域名所有权预检失败", "domain", domain, "error", err)
+			w.markResult(domain, err)
+			continue
+		}
+
+		w.setEntry(domain, &onDemandEntry{Status: onDemandPending, LastAttempt: time.Now()})
+		if !w.daemon.enqueue(job{domain: domain, webroot: w.config.Webroot, source: onDemandSource}) {
+			logger.Warn("任务队列已满，稍后重试", "domain", domain)
+			w.setEntry(domain, nil)
+			return
+		}
+		logger.Info("按需签发任务已提交", "domain", domain)
+	}
+}
+
+// reportInvalid 对队列文件中新出现的无效行发出警告，已报告过且仍在文件中的行不再重复警告
+func (w *onDemandWatcher) reportInvalid(lines []string) {
+	current := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		if !w.invalid[line] {
+			logger.Warn("忽略无效的队列域名", "domain", line, "file", w.config.QueueFile)
+		}
+		current[line] = true
+	}
+	w.invalid = current
+}
+
+// due 判断域名是否需要（重新）处理
+func (w *onDemandWatcher) due(domain string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.state[domain]
+	if !ok {
+		// 已有证书的域名无需按需签发
+		_, err := os.Stat(filepath.Join(config.GetCertDir(), domain, "cert.pem"))
+		return err != nil
+	}
+
+	return entry.Status == onDemandFailed && time.Since(entry.LastAttempt) >= w.config.RetryAfter
+}
+
+// markResult 记录签发结果
+func (w *onDemandWatcher) markResult(domain string, err error) {
+	entry := &onDemandEntry{Status: onDemandIssued, LastAttempt: time.Now()}
+	if err != nil {
+		entry.Status = onDemandFailed
+		entry.Error = err.Error()
+	}
+	w.setEntry(domain, entry)
+}
+
+// setEntry 更新域名状态并持久化，entry 为 nil 时删除
+func (w *onDemandWatcher) setEntry(domain string, entry *onDemandEntry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if entry == nil {
+		delete(w.state, domain)
+	} else {
+		w.state[domain] = entry
+	}
+
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(w.statePath, data, 0600); err != nil {
+		logger.Warn("保存按需签发状态失败", "path", w.statePath, "error", err)
+	}
+}
+
+// loadState 加载持久化的按需签发状态，便于重启后继续
+func (w *onDemandWatcher) loadState() {
+	data, err := os.ReadFile(w.statePath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &w.state); err != nil {
+		logger.Warn("解析按需签发状态失败", "path", w.statePath, "error", err)
+		w.state = make(map[string]*onDemandEntry)
+	}
+
+	// 上次退出时仍在处理中的域名重新处理
+	for domain, entry := range w.state {
+		if entry.Status == onDemandPending {
+			delete(w.state, domain)
+		}
+	}
+}
+
+// verifyOwnership 在共享 webroot 写入探测文件，并通过 HTTP 访问域名确认能读到该文件
+func (w *onDemandWatcher) verifyOwnership(domain string) error {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(buf)
+	name := "autocert-probe-" + token
+
+	challengeDir := filepath.Join(w.config.Webroot, ".well-known", "acme-challenge")
+	if err := os.MkdirAll(challengeDir, 0755); err != nil {
+		return err
+	}
+
+	probePath := filepath.Join(challengeDir, name)
+	if err := os.WriteFile(probePath, []byte(token), 0644); err != nil {
+		return err
+	}
+	defer os.Remove(probePath)
+
+	resp, err := w.client.Get(fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", domain, name))
+	if err != nil {
+		return fmt.Errorf("无法访问域名: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != token {
+		return fmt.Errorf("域名未指向本机共享 webroot（HTTP %d）", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// registerOnDemandAPI 注册按需签发队列接口（需要 renew 权限）
+func (d *Daemon) registerOnDemandAPI() {
+	d.mux.HandleFunc("/api/on-demand", d.requireScope(apitoken.ScopeRenew, d.handleOnDemand))
+}
+
+// handleOnDemand 处理 POST /api/on-demand?domain=，将域名追加到队列文件
+func (d *Daemon) handleOnDemand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "仅支持 POST 请求"})
+		return
+	}
+
+	domain := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("domain")))
+	if !domainPattern.MatchString(domain) || strings.HasPrefix(domain, "*.") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "域名参数无效（按需签发不支持泛域名）"})
+		return
+	}
+
+	if err := appendQueueFile(d.onDemand.config.QueueFile, domain); err != nil {
+		logger.Error("写入域名队列文件失败", "domain", domain, "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "写入队列失败"})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued", "domain": domain})
+}
+
+// readQueueFile 读取域名队列文件（每行一个域名，# 开头为注释），同时返回无效的行
+func readQueueFile(path string) (domains, invalid []string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		if !domainPattern.MatchString(line) || strings.HasPrefix(line, "*.") {
+			invalid = append(invalid, line)
+			continue
+		}
+		domains = append(domains, line)
+	}
+
+	return domains, invalid, scanner.Err()
+}
+
+// appendQueueFile 向队列文件追加域名（已存在时忽略）
+func appendQueueFile(path, domain string) error {
+	domains, _, err := readQueueFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, d := range domains {
+		if d == domain {
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintln(file, domain)
+	return err
+}
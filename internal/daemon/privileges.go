@@ -0,0 +1,66 @@
+package daemon
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/keystore"
+	"autocert/internal/logger"
+	"autocert/internal/system"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dropPrivileges 配置 daemon.user 时，在完成需要 root 权限的步骤（绑定端口）后切换到该用户。
+// 证书目录和账户密钥目录的所有者改为该用户，续期时仍可写入证书；写入站点配置、重载 Web 服务器
+// 和部署钩子需要 root 权限，降权前启动 root 的部署助手，之后的部署都交给它
+func (d *Daemon) dropPrivileges() error {
+	if d.config.User == "" {
+		return nil
+	}
+	if os.Geteuid() != 0 {
+		logger.Warn("未以 root 运行，忽略 daemon.user", "user", d.config.User)
+		return nil
+	}
+
+	creds, err := system.LookupCredentials(d.config.User, d.config.Group)
+	if err != nil {
+		return err
+	}
+
+	// Standalone 验证需要的端口在降权前绑定
+	if config.GetStandaloneConfig().Prebind {
+		if err := cert.PrebindStandalone(); err != nil {
+			return fmt.Errorf("预先绑定 Standalone 端口失败: %w", err)
+		}
+	}
+
+	// 配置目录（配置文件、站点配置记录等）保持 root 所有：其中的重载/测试命令和钩子由部署助手以 root 执行。
+	// 只修改证书目录和账户密钥目录，它们不能是配置目录本身或其上级目录
+	configDir := filepath.Clean(config.GetConfigDir())
+	for _, dir := range []string{config.GetCertDir(), filepath.Dir(keystore.AccountKeyPath())} {
+		dir = filepath.Clean(dir)
+		if rel, err := filepath.Rel(dir, configDir); err == nil && (rel == "." || !strings.HasPrefix(rel, "..")) {
+			return fmt.Errorf("%s 包含配置目录 %s，降权时不能修改其所有者，请将 cert_dir 设置为单独的目录", dir, configDir)
+		}
+		if err := system.ChownTree(dir, creds.UID, creds.GID); err != nil {
+			return fmt.Errorf("修改 %s 所有者失败: %w", dir, err)
+		}
+	}
+
+	helper, err := startDeployHelper()
+	if err != nil {
+		return fmt.Errorf("启动部署助手失败: %w", err)
+	}
+
+	if err := system.DropPrivileges(creds); err != nil {
+		helper.close()
+		return fmt.Errorf("降权失败: %w", err)
+	}
+	d.helper = helper
+	cert.SetDeployHandler(helper.Deploy)
+
+	logger.Info("已切换到非特权用户", "user", creds.User, "uid", creds.UID, "gid", creds.GID)
+	return nil
+}
@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadSource 重新加载配置后提交的签发/续期任务的来源
+const reloadSource = "config-reload"
+
+// reloadDebounce 编辑器保存文件时会产生多个事件，最后一个事件之后等待该时间再重新加载
+const reloadDebounce = time.Second
+
+// restartKeys 修改后需要重启守护进程才能生效的配置项（按前缀匹配）
+var restartKeys = []string{
+	"daemon.listen",
+	"daemon.webhook",
+	"daemon.on_demand",
+	"daemon.user",
+	"daemon.group",
+	"ca.serve",
+	"features.daemon_api",
+	"features.on_demand",
+	"log_level",
+	"log_dir",
+}
+
+// watchConfig 监视配置文件所在目录和 SIGHUP，配置变化时重新加载，成功后将新的续期调度发送到 reloaded
+func (d *Daemon) watchConfig(ctx context.Context, reloaded chan config.DaemonConfig) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Warn("无法监视配置文件，只能通过 SIGHUP 重新加载", "error", err)
+	} else {
+		defer watcher.Close()
+		addWatchDirs(watcher)
+		events, errs = watcher.Events, watcher.Errors
+	}
+
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			logger.Info("收到 SIGHUP，重新加载配置")
+			d.reload(reloaded)
+		case event := <-events:
+			if isConfigEvent(event) {
+				debounce.Reset(reloadDebounce)
+			}
+		case err := <-errs:
+			logger.Warn("监视配置文件出错", "error", err)
+		case <-debounce.C:
+			logger.Info("配置文件已修改，重新加载配置")
+			d.reload(reloaded)
+			// include 可能新增了目录
+			if watcher != nil {
+				addWatchDirs(watcher)
+			}
+		}
+	}
+}
+
+// addWatchDirs 监视主配置、包含的文件和 include 模式所在的目录。
+// 监视目录而不是文件：编辑器通常写入新文件后重命名，直接监视文件会丢失后续的修改
+func addWatchDirs(watcher *fsnotify.Watcher) {
+	for _, dir := range config.WatchDirs() {
+		if err := watcher.Add(dir); err != nil {
+			logger.Warn("无法监视配置目录", "dir", dir, "error", err)
+		}
+	}
+}
+
+// isConfigEvent 事件是否可能修改了配置：YAML 文件的写入、创建、删除或重命名
+func isConfigEvent(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(event.Name)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// reload 重新加载配置并应用：记录有效配置的差异，为新增或修改的域名提交签发/续期任务，
+// 续期调度（interval、schedule 等）交给 Run 在下次安排检查时应用。配置无效时保留当前配置继续运行
+func (d *Daemon) reload(reloaded chan config.DaemonConfig) {
+	d.mu.Lock()
+	previous, err := config.Reload()
+	if err != nil {
+		d.mu.Unlock()
+		logger.Error("重新加载配置失败，继续使用当前配置", "error", err)
+		return
+	}
+
+	current := config.Current()
+	changes := config.Diff(previous, current)
+	if len(changes) == 0 {
+		d.mu.Unlock()
+		logger.Info("配置没有变化")
+		return
+	}
+	for _, change := range changes {
+		logger.Info("配置已变更", "key", change.Key, "old", change.Old, "new", change.New)
+		for _, prefix := range restartKeys {
+			if strings.HasPrefix(change.Key, prefix) {
+				logger.Warn("该配置项需要重启守护进程后生效", "key", change.Key)
+				break
+			}
+		}
+	}
+
+	if err := clock.SetLocation(config.GetTimezone()); err != nil {
+		logger.Warn("时区配置无效，使用系统本地时区", "error", err)
+	}
+
+	d.mu.Unlock()
+
+	for _, domain := range changedDomains(previous.Domains, current.Domains) {
+		if !d.enqueue(job{domain: domain, source: reloadSource}) {
+			logger.Warn("任务队列已满，域名将在下次续期检查时处理", "domain", domain)
+		}
+	}
+
+	logger.Info("配置已重新加载", "changes", len(changes))
+
+	schedule, err := normalizeSchedule(d.ConfigSource())
+	if err != nil {
+		logger.Error("续期调度配置无效，保留当前调度", "error", err)
+		return
+	}
+	// Run 正在续期时不阻塞，只保留最新的调度
+	select {
+	case <-reloaded:
+	default:
+	}
+	reloaded <- schedule
+}
+
+// changedDomains 新增或配置有变化的域名：新增的域名签发证书，sans 等变化按新配置续期
+func changedDomains(previous, current []config.DomainConfig) []string {
+	old := make(map[string]config.DomainConfig, len(previous))
+	for _, domain := range previous {
+		old[domain.Domain] = domain
+	}
+
+	var domains []string
+	for _, domain := range current {
+		if domain.Domain == "" {
+			continue
+		}
+		if before, ok := old[domain.Domain]; !ok || !reflect.DeepEqual(before, domain) {
+			domains = append(domains, domain.Domain)
+		}
+	}
+	return domains
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/renewal"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// 续期检查调度方式
+const (
+	scheduleFixed = "fixed"
+	scheduleSmart = "smart"
+)
+
+// normalizeSchedule 校验调度方式并填充 interval、min_interval 的默认值
+func normalizeSchedule(cfg config.DaemonConfig) (config.DaemonConfig, error) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 24 * time.Hour
+	}
+	switch cfg.Schedule {
+	case "":
+		cfg.Schedule = scheduleFixed
+	case scheduleFixed, scheduleSmart:
+	default:
+		return cfg, fmt.Errorf("不支持的调度方式 %q（支持 fixed、smart）", cfg.Schedule)
+	}
+	if cfg.MinInterval <= 0 || cfg.MinInterval > cfg.Interval {
+		cfg.MinInterval = min(time.Hour, cfg.Interval)
+	}
+	return cfg, nil
+}
+
+// nextRun 距离下次续期检查的时间。
+// smart 调度按最早进入续期窗口的证书安排下次检查，并随机提前最多 jitter，避免多台主机同时请求 CA；
+// 结果限制在 min_interval 和 interval 之间：证书较少时减少无意义的唤醒，短期证书也能及时续期
+func (d *Daemon) nextRun() time.Duration {
+	if d.config.Schedule != scheduleSmart {
+		return d.config.Interval
+	}
+
+	name, due, err := renewal.NextDue()
+	if err != nil {
+		logger.Warn("计算下次续期时间失败，使用最长间隔", "error", err)
+		return d.config.Interval
+	}
+	if due.IsZero() {
+		logger.Info("没有证书，按最长间隔安排下次续期检查", "interval", d.config.Interval)
+		return d.config.Interval
+	}
+
+	wait := clock.Until(due)
+	if d.config.Jitter > 0 {
+		wait -= time.Duration(rand.Int63n(int64(d.config.Jitter)))
+	}
+	wait = min(max(wait, d.config.MinInterval), d.config.Interval)
+
+	logger.Info("已安排下次续期检查", "at", clock.Format(clock.Now().Add(wait)), "cert", name, "due", clock.Format(due))
+	return wait
+}
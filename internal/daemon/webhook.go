@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"autocert/internal/apitoken"
+	"autocert/internal/logger"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// domainPattern 允许通过 webhook 提交的域名格式
+var domainPattern = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
+
+// registerWebhook 注册入站 webhook 路由（需要 renew 权限）
+func (d *Daemon) registerWebhook() {
+	d.mux.HandleFunc("/hooks/renew", d.requireScope(apitoken.ScopeRenew, d.handleRenewHook))
+}
+
+// handleRenewHook 处理 POST /hooks/renew?domain=example.com[&force=true]
+func (d *Daemon) handleRenewHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "仅支持 POST 请求"})
+		return
+	}
+
+	domain := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("domain")))
+	if !domainPattern.MatchString(domain) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "域名参数无效"})
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true" || r.URL.Query().Get("force") == "1"
+
+	if !d.enqueue(job{domain: domain, force: force, source: "webhook"}) {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "任务队列已满，请稍后重试"})
+		return
+	}
+
+	logger.Info("webhook 已接收续期请求", "domain", domain, "force", force, "remote", r.RemoteAddr)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"status": "accepted",
+		"domain": domain,
+		"force":  force,
+	})
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,108 @@
+package deploy
+
+import (
+	"autocert/internal/secmem"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// apiClient 设备 HTTP JSON API 的公共请求逻辑
+type apiClient struct {
+	baseURL  string
+	headers  map[string]string
+	username string // 配置后使用 Basic 认证
+	password string
+	client   *http.Client
+}
+
+// newHTTPClient 访问设备 HTTPS API 的客户端；insecure 不校验设备证书，caCert 为校验设备证书的 CA 证书文件
+func newHTTPClient(insecure bool, caCert string) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if caCert != "" {
+		data, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s 中没有有效的 CA 证书", caCert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return &http.Client{
+		Timeout:   time.Minute,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+func newAPIClient(baseURL string, insecure bool, caCert string) (*apiClient, error) {
+	client, err := newHTTPClient(insecure, caCert)
+	if err != nil {
+		return nil, err
+	}
+	return &apiClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: make(map[string]string),
+		client:  client,
+	}, nil
+}
+
+// do 发送请求并把 JSON 响应解析到 out（为 nil 时忽略响应体）。请求体中可能有私钥，发送后清零
+func (c *apiClient) do(method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = data
+		defer secmem.Wipe(payload)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300] + "..."
+		}
+		return fmt.Errorf("%s %s 返回 %s: %s", method, path, resp.Status, msg)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("解析 %s 的响应失败: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package deploy 将签发/续期后的证书安装到 NAS、虚拟化平台、网络设备等无法通过部署钩子
+// 简单复制文件的目标：通过设备的 API 或命令行替换证书，并让相关服务使用新证书。
+package deploy
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/secmem"
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 失败策略
+const (
+	FailureAbort    = "abort"    // 部署失败时安装失败，不再部署后续目标
+	FailureContinue = "continue" // 记录失败，继续部署后续目标
+)
+
+// Certificate 要部署的证书
+type Certificate struct {
+	Domains   []string
+	CertPath  string // 证书，可能包含中间证书
+	KeyPath   string
+	ChainPath string // 证书文件只有叶子证书时使用的中间证书
+}
+
+// Target 部署目标
+type Target interface {
+	Name() string
+
+	// Deploy 安装证书并让相关服务使用新证书
+	Deploy(c Certificate) error
+}
+
+// factories 已支持的部署目标类型
+var factories = map[string]func(config.DeployConfig) (Target, error){
+	"esxi":     newESXi,
+	"opnsense": newOPNsense,
+	"pfsense":  newPfSense,
+	"proxmox":  newProxmox,
+	"qnap":     newQNAP,
+	"routeros": newRouterOS,
+	"synology": newSynology,
+}
+
+// Open 按配置创建部署目标
+func Open(cfg config.DeployConfig) (Target, error) {
+	factory, ok := factories[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("不支持的部署目标类型 %q（支持: %s）", cfg.Type, strings.Join(Types(), ", "))
+	}
+	target, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("初始化部署目标 %s 失败: %w", TargetName(cfg), err)
+	}
+	return target, nil
+}
+
+// Types 已支持的部署目标类型
+func Types() []string {
+	types := make([]string, 0, len(factories))
+	for name := range factories {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// TargetName 部署目标的名称，未配置 name 时使用类型
+func TargetName(cfg config.DeployConfig) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return cfg.Type
+}
+
+// OnFailure 部署目标的失败策略
+func OnFailure(cfg config.DeployConfig) string {
+	if cfg.OnFailure == "" {
+		return FailureAbort
+	}
+	return cfg.OnFailure
+}
+
+// Result 单个部署目标的结果
+type Result struct {
+	Name     string
+	Type     string
+	Duration time.Duration
+	Err      error
+	Skipped  bool // 之前的目标失败且失败策略为 abort
+}
+
+// Run 按配置顺序部署到各目标。失败策略为 abort 的目标失败时不再部署后续目标并返回错误，
+// continue 的目标失败只记录在结果中
+func Run(targets []config.DeployConfig, c Certificate) ([]Result, error) {
+	for _, cfg := range targets {
+		switch cfg.OnFailure {
+		case "", FailureAbort, FailureContinue:
+		default:
+			return nil, fmt.Errorf("部署目标 %s 的 on_failure 无效: %s（可选 abort、continue）", TargetName(cfg), cfg.OnFailure)
+		}
+	}
+
+	results := make([]Result, 0, len(targets))
+	var abortErr error
+	for _, cfg := range targets {
+		result := Result{Name: TargetName(cfg), Type: cfg.Type}
+		if abortErr != nil {
+			result.Skipped = true
+			results = append(results, result)
+			continue
+		}
+
+		start := time.Now()
+		target, err := Open(cfg)
+		if err == nil {
+			logger.Info("部署证书", "target", result.Name, "type", cfg.Type, "domains", c.Domains)
+			err = target.Deploy(c)
+		}
+		result.Duration = time.Since(start)
+		result.Err = err
+		results = append(results, result)
+
+		if err != nil {
+			logger.Error("部署证书失败", "target", result.Name, "error", err)
+			if OnFailure(cfg) == FailureAbort {
+				abortErr = fmt.Errorf("部署到 %s 失败: %w", result.Name, err)
+			}
+			continue
+		}
+		logger.Info("证书已部署", "target", result.Name, "duration", result.Duration.Round(time.Millisecond))
+	}
+	return results, abortErr
+}
+
+// bundle 拆分后的证书：叶子证书、中间证书和私钥（PEM）
+type bundle struct {
+	leaf  []byte
+	chain []byte
+	key   *secmem.Buffer
+}
+
+// fullchain 叶子证书和中间证书
+func (b *bundle) fullchain() []byte {
+	return append(append([]byte{}, b.leaf...), b.chain...)
+}
+
+// leafCert 解析叶子证书
+func (b *bundle) leafCert() (*x509.Certificate, error) {
+	block, _ := pem.Decode(b.leaf)
+	if block == nil {
+		return nil, errors.New("无效的证书")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// chainCerts 中间证书，每个元素是单个证书的 PEM 和解析结果
+func (b *bundle) chainCerts() ([][]byte, []*x509.Certificate, error) {
+	var pems [][]byte
+	var certs []*x509.Certificate
+	rest := b.chain
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return pems, certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("解析中间证书失败: %w", err)
+		}
+		pems = append(pems, pem.EncodeToMemory(block))
+		certs = append(certs, cert)
+	}
+}
+
+// release 清零私钥
+func (b *bundle) release() {
+	b.key.Destroy()
+}
+
+// load 读取证书和私钥，调用方用完后需要 release
+func (c Certificate) load() (*bundle, error) {
+	data, err := os.ReadFile(c.CertPath)
+	if err != nil {
+		return nil, err
+	}
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是有效的 PEM 证书", c.CertPath)
+	}
+	chain := bytes.TrimLeft(rest, "\r\n")
+	if len(chain) == 0 && c.ChainPath != "" {
+		if extra, err := os.ReadFile(c.ChainPath); err == nil {
+			chain = extra
+		}
+	}
+
+	key, err := secmem.ReadFile(c.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(key.Bytes()) == 0 {
+		key.Destroy()
+		return nil, errors.New("私钥文件为空")
+	}
+	return &bundle{leaf: pem.EncodeToMemory(block), chain: chain, key: key}, nil
+}
+
+// writeFile 先写入同目录下的临时文件再替换目标文件，写入失败时保留原文件
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,87 @@
+package deploy
+
+import (
+	"autocert/internal/certsync"
+	"autocert/internal/config"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// esxiSSLDir ESXi 主机证书所在目录
+const esxiSSLDir = "/etc/vmware/ssl"
+
+// defaultESXiRestarts 替换主机证书后默认执行的命令：重启 hostd、vpxa（由 vCenter 管理时）和 rhttpproxy
+var defaultESXiRestarts = []string{
+	"/etc/init.d/hostd restart",
+	"[ ! -x /etc/init.d/vpxa ] || /etc/init.d/vpxa restart",
+	"[ ! -x /etc/init.d/rhttpproxy ] || /etc/init.d/rhttpproxy restart",
+}
+
+// esxiTarget 通过 SSH 替换 ESXi 主机的 rui.crt（完整证书链）和 rui.key，原文件备份为 .bak
+type esxiTarget struct {
+	ssh      certsync.Target
+	restarts []string
+}
+
+func newESXi(cfg config.DeployConfig) (Target, error) {
+	c := cfg.ESXi
+	if c.Host == "" {
+		return nil, errors.New("未配置 esxi.host")
+	}
+	restarts := c.Restarts
+	if len(restarts) == 0 {
+		restarts = defaultESXiRestarts
+	}
+	return &esxiTarget{
+		ssh:      certsync.Target{Host: c.Host, Port: c.Port, Identity: c.Identity},
+		restarts: restarts,
+	}, nil
+}
+
+func (t *esxiTarget) Name() string {
+	return "esxi"
+}
+
+func (t *esxiTarget) Deploy(c Certificate) error {
+	b, err := c.load()
+	if err != nil {
+		return err
+	}
+	defer b.release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	backup := fmt.Sprintf("cd %s && for f in rui.crt rui.key; do [ ! -f $f ] || cp -p $f $f.bak; done", esxiSSLDir)
+	if _, err := certsync.RunSSH(ctx, t.ssh, backup, nil); err != nil {
+		return fmt.Errorf("备份 ESXi 证书失败: %w", err)
+	}
+
+	files := []struct {
+		name string
+		mode string
+		data []byte
+	}{
+		{"rui.key", "0400", b.key.Bytes()},
+		{"rui.crt", "0644", b.fullchain()},
+	}
+	for _, f := range files {
+		// 先写入临时文件再替换，上传中断时不会留下不完整的证书
+		path := esxiSSLDir + "/" + f.name
+		tmp := certsync.ShellQuote(path + ".autocert")
+		command := fmt.Sprintf("umask 077 && cat > %s && chmod %s %s && mv -f %s %s", tmp, f.mode, tmp, tmp, certsync.ShellQuote(path))
+		if _, err := certsync.RunSSH(ctx, t.ssh, command, bytes.NewReader(f.data)); err != nil {
+			return fmt.Errorf("上传 %s 失败: %w", f.name, err)
+		}
+	}
+
+	for _, command := range t.restarts {
+		if _, err := certsync.RunSSH(ctx, t.ssh, command, nil); err != nil {
+			return fmt.Errorf("在 ESXi 主机上执行 %q 失败: %w", command, err)
+		}
+	}
+	return nil
+}
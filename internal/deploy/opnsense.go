@@ -0,0 +1,186 @@
+package deploy
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/secret"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// opnsenseReloads 可以在替换证书后重新加载的服务及其 API
+var opnsenseReloads = map[string]string{
+	"openvpn": "/openvpn/service/reconfigure",
+	"haproxy": "/haproxy/service/reconfigure",
+}
+
+// opnsenseTarget 通过 OPNsense API（系统 > 信任，24.7 及以上版本）按描述替换证书。
+// 证书的 refid 不变，Web 管理界面、OpenVPN、HAProxy 等引用该证书的位置不需要修改
+type opnsenseTarget struct {
+	api         *apiClient
+	certificate string
+	reloads     []string
+}
+
+// opnsenseRow 证书或 CA 的搜索结果
+type opnsenseRow struct {
+	UUID  string `json:"uuid"`
+	Descr string `json:"descr"`
+}
+
+// opnsenseResult 保存操作的结果
+type opnsenseResult struct {
+	Result      string                 `json:"result"`
+	UUID        string                 `json:"uuid"`
+	Validations map[string]interface{} `json:"validations"`
+}
+
+func (r opnsenseResult) err() error {
+	if r.Result == "saved" {
+		return nil
+	}
+	if len(r.Validations) == 0 {
+		return fmt.Errorf("结果为 %q", r.Result)
+	}
+	fields := make([]string, 0, len(r.Validations))
+	for field, msg := range r.Validations {
+		fields = append(fields, fmt.Sprintf("%s: %v", field, msg))
+	}
+	sort.Strings(fields)
+	return errors.New(strings.Join(fields, "; "))
+}
+
+func newOPNsense(cfg config.DeployConfig) (Target, error) {
+	c := cfg.OPNsense
+	if c.URL == "" {
+		return nil, errors.New("未配置 opnsense.url")
+	}
+	if c.APIKey == "" {
+		return nil, errors.New("未配置 opnsense.api_key")
+	}
+	apiSecret, err := secret.Resolve(c.APISecret)
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range c.Reload {
+		if _, ok := opnsenseReloads[service]; !ok {
+			return nil, fmt.Errorf("opnsense.reload 无效: %s（可选 openvpn、haproxy）", service)
+		}
+	}
+	api, err := newAPIClient(strings.TrimSuffix(c.URL, "/")+"/api", c.Insecure, c.CACert)
+	if err != nil {
+		return nil, err
+	}
+	api.username = c.APIKey
+	api.password = apiSecret
+	return &opnsenseTarget{api: api, certificate: c.Certificate, reloads: c.Reload}, nil
+}
+
+func (t *opnsenseTarget) Name() string {
+	return "opnsense"
+}
+
+func (t *opnsenseTarget) Deploy(c Certificate) error {
+	b, err := c.load()
+	if err != nil {
+		return err
+	}
+	defer b.release()
+
+	if err := t.importChain(b); err != nil {
+		return err
+	}
+
+	descr := t.certificate
+	if descr == "" {
+		descr = "autocert " + c.Domains[0]
+	}
+	uuid, err := t.find("cert", descr)
+	if err != nil {
+		return fmt.Errorf("查询证书失败: %w", err)
+	}
+
+	body := map[string]interface{}{"cert": map[string]string{
+		"action":      "import",
+		"descr":       descr,
+		"crt_payload": string(b.leaf),
+		"prv_payload": string(b.key.Bytes()),
+	}}
+	path := "/trust/cert/add"
+	if uuid != "" {
+		path = "/trust/cert/set/" + url.PathEscape(uuid)
+	}
+	var result opnsenseResult
+	if err := t.api.do(http.MethodPost, path, body, &result); err != nil {
+		return fmt.Errorf("导入证书失败: %w", err)
+	}
+	if err := result.err(); err != nil {
+		return fmt.Errorf("导入证书失败: %w", err)
+	}
+	if uuid == "" {
+		logger.Info("已导入新证书", "certificate", descr)
+	}
+
+	for _, service := range t.reloads {
+		if err := t.api.do(http.MethodPost, opnsenseReloads[service], map[string]interface{}{}, nil); err != nil {
+			return fmt.Errorf("重新加载 %s 失败: %w", service, err)
+		}
+	}
+	return nil
+}
+
+// find 按描述查找证书或 CA，没有时返回空字符串
+func (t *opnsenseTarget) find(kind, descr string) (string, error) {
+	var resp struct {
+		Rows []opnsenseRow `json:"rows"`
+	}
+	body := map[string]interface{}{"current": 1, "rowCount": -1, "searchPhrase": descr}
+	if err := t.api.do(http.MethodPost, "/trust/"+kind+"/search", body, &resp); err != nil {
+		return "", err
+	}
+	for _, row := range resp.Rows {
+		if row.Descr == descr {
+			return row.UUID, nil
+		}
+	}
+	return "", nil
+}
+
+// importChain 把还没有的中间证书导入为 CA（以证书主题的 CN 作为描述），OPNsense 据此为服务配置完整的证书链
+func (t *opnsenseTarget) importChain(b *bundle) error {
+	pems, certs, err := b.chainCerts()
+	if err != nil {
+		return err
+	}
+	for i, cert := range certs {
+		descr := cert.Subject.CommonName
+		if descr == "" {
+			continue
+		}
+		uuid, err := t.find("ca", descr)
+		if err != nil {
+			return fmt.Errorf("查询 CA 失败: %w", err)
+		}
+		if uuid != "" {
+			continue
+		}
+		body := map[string]interface{}{"ca": map[string]string{
+			"action":      "import",
+			"descr":       descr,
+			"crt_payload": string(pems[i]),
+		}}
+		var result opnsenseResult
+		if err := t.api.do(http.MethodPost, "/trust/ca/add", body, &result); err != nil {
+			return fmt.Errorf("导入中间证书 %s 失败: %w", descr, err)
+		}
+		if err := result.err(); err != nil {
+			return fmt.Errorf("导入中间证书 %s 失败: %w", descr, err)
+		}
+		logger.Info("已导入中间证书", "ca", descr)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package deploy
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/secret"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pfSense 中引用证书的位置
+const (
+	pfSenseWebGUI  = "webgui"
+	pfSenseOpenVPN = "openvpn"
+	pfSenseHAProxy = "haproxy"
+)
+
+// pfSenseRef 改为使用新证书的位置，id 为 OpenVPN 服务器或 HAProxy 前端的 ID
+type pfSenseRef struct {
+	kind string
+	id   int
+}
+
+func (r pfSenseRef) String() string {
+	if r.kind == pfSenseWebGUI {
+		return r.kind
+	}
+	return fmt.Sprintf("%s:%d", r.kind, r.id)
+}
+
+// pfSenseTarget 通过 pfSense REST API（pfSense-pkg-RESTAPI v2）导入证书，
+// 把 Web 管理界面、OpenVPN 服务器和 HAProxy 前端的证书引用改为新证书，再删除以前导入的证书
+type pfSenseTarget struct {
+	api  *apiClient
+	refs []pfSenseRef
+}
+
+// pfSenseResponse API 响应
+type pfSenseResponse struct {
+	Data interface{} `json:"data"`
+}
+
+// pfSenseCert 证书或 CA
+type pfSenseCert struct {
+	ID    int    `json:"id"`
+	RefID string `json:"refid"`
+	Descr string `json:"descr"`
+}
+
+func newPfSense(cfg config.DeployConfig) (Target, error) {
+	c := cfg.PfSense
+	if c.URL == "" {
+		return nil, errors.New("未配置 pfsense.url")
+	}
+	apiKey, err := secret.Resolve(c.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == "" {
+		return nil, errors.New("未配置 pfsense.api_key")
+	}
+	refs, err := parsePfSenseRefs(c.Use)
+	if err != nil {
+		return nil, err
+	}
+	api, err := newAPIClient(strings.TrimSuffix(c.URL, "/")+"/api/v2", c.Insecure, c.CACert)
+	if err != nil {
+		return nil, err
+	}
+	api.headers["X-API-Key"] = apiKey
+	return &pfSenseTarget{api: api, refs: refs}, nil
+}
+
+// parsePfSenseRefs 解析 use 配置：webgui、openvpn:<服务器 ID>、haproxy:<前端 ID>，默认 webgui
+func parsePfSenseRefs(use []string) ([]pfSenseRef, error) {
+	if len(use) == 0 {
+		return []pfSenseRef{{kind: pfSenseWebGUI}}, nil
+	}
+	refs := make([]pfSenseRef, 0, len(use))
+	for _, item := range use {
+		kind, idText, hasID := strings.Cut(item, ":")
+		switch {
+		case kind == pfSenseWebGUI && !hasID:
+			refs = append(refs, pfSenseRef{kind: kind})
+		case (kind == pfSenseOpenVPN || kind == pfSenseHAProxy) && hasID:
+			id, err := strconv.Atoi(idText)
+			if err != nil {
+				return nil, fmt.Errorf("pfsense.use 中的 ID 无效: %s", item)
+			}
+			refs = append(refs, pfSenseRef{kind: kind, id: id})
+		default:
+			return nil, fmt.Errorf("pfsense.use 无效: %s（可选 webgui、openvpn:<服务器 ID>、haproxy:<前端 ID>）", item)
+		}
+	}
+	return refs, nil
+}
+
+func (t *pfSenseTarget) Name() string {
+	return "pfsense"
+}
+
+func (t *pfSenseTarget) Deploy(c Certificate) error {
+	b, err := c.load()
+	if err != nil {
+		return err
+	}
+	defer b.release()
+
+	leaf, err := b.leafCert()
+	if err != nil {
+		return err
+	}
+	if err := t.importChain(b); err != nil {
+		return err
+	}
+
+	prefix := "autocert " + c.Domains[0] + " "
+	descr := prefix + leaf.NotBefore.UTC().Format("2006-01-02 15:04:05")
+	var created pfSenseCert
+	body := map[string]string{"descr": descr, "crt": string(b.leaf), "prkey": string(b.key.Bytes())}
+	if err := t.api.do(http.MethodPost, "/system/certificate", body, &pfSenseResponse{Data: &created}); err != nil {
+		return fmt.Errorf("导入证书失败: %w", err)
+	}
+	if created.RefID == "" {
+		return errors.New("导入证书的响应中没有 refid")
+	}
+
+	applyHAProxy := false
+	for _, ref := range t.refs {
+		var err error
+		switch ref.kind {
+		case pfSenseWebGUI:
+			err = t.api.do(http.MethodPatch, "/system/webgui/settings", map[string]interface{}{"sslcertref": created.RefID}, nil)
+		case pfSenseOpenVPN:
+			err = t.api.do(http.MethodPatch, "/vpn/openvpn/server", map[string]interface{}{"id": ref.id, "certref": created.RefID}, nil)
+		case pfSenseHAProxy:
+			err = t.api.do(http.MethodPatch, "/services/haproxy/frontend", map[string]interface{}{"id": ref.id, "ssloffloadcert": created.RefID}, nil)
+			applyHAProxy = true
+		}
+		if err != nil {
+			return fmt.Errorf("更新 %s 的证书引用失败: %w", ref, err)
+		}
+	}
+	if applyHAProxy {
+		if err := t.api.do(http.MethodPost, "/services/haproxy/apply", map[string]interface{}{}, nil); err != nil {
+			return fmt.Errorf("应用 HAProxy 配置失败: %w", err)
+		}
+	}
+
+	t.removeOldCerts(prefix, created.RefID)
+	return nil
+}
+
+// importChain 把 pfSense 中还没有的中间证书导入为 CA（以证书主题的 CN 作为描述），
+// pfSense 据此为服务配置完整的证书链
+func (t *pfSenseTarget) importChain(b *bundle) error {
+	pems, certs, err := b.chainCerts()
+	if err != nil || len(certs) == 0 {
+		return err
+	}
+
+	var existing []pfSenseCert
+	if err := t.api.do(http.MethodGet, "/system/certificate_authorities", nil, &pfSenseResponse{Data: &existing}); err != nil {
+		return fmt.Errorf("查询 CA 列表失败: %w", err)
+	}
+	known := make(map[string]bool, len(existing))
+	for _, ca := range existing {
+		known[ca.Descr] = true
+	}
+
+	for i, cert := range certs {
+		descr := cert.Subject.CommonName
+		if descr == "" || known[descr] {
+			continue
+		}
+		if err := t.api.do(http.MethodPost, "/system/certificate_authority", map[string]string{"descr": descr, "crt": string(pems[i])}, nil); err != nil {
+			return fmt.Errorf("导入中间证书 %s 失败: %w", descr, err)
+		}
+		logger.Info("已导入中间证书", "ca", descr)
+	}
+	return nil
+}
+
+// removeOldCerts 删除以前为该域名导入的证书；仍被引用的证书会删除失败，只记录日志
+func (t *pfSenseTarget) removeOldCerts(prefix, current string) {
+	var certs []pfSenseCert
+	if err := t.api.do(http.MethodGet, "/system/certificates", nil, &pfSenseResponse{Data: &certs}); err != nil {
+		logger.Warn("查询 pfSense 证书列表失败", "error", err)
+		return
+	}
+	// ID 是证书在配置中的下标，从后往前删除，前面证书的 ID 不会变化
+	sort.Slice(certs, func(i, j int) bool { return certs[i].ID > certs[j].ID })
+	for _, cert := range certs {
+		if !strings.HasPrefix(cert.Descr, prefix) || cert.RefID == current {
+			continue
+		}
+		if err := t.api.do(http.MethodDelete, "/system/certificate?id="+strconv.Itoa(cert.ID), nil, nil); err != nil {
+			logger.Warn("删除旧证书失败", "certificate", cert.Descr, "error", err)
+			continue
+		}
+		logger.Info("已删除旧证书", "certificate", cert.Descr)
+	}
+}
@@ -0,0 +1,97 @@
+package deploy
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/secmem"
+	"autocert/internal/secret"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// proxmoxTarget 通过 Proxmox VE API 上传节点的自定义证书（与 pvenode cert set --force --restart 相同），
+// 保存在 /etc/pve/nodes/<节点>/pveproxy-ssl.pem 并重启 pveproxy
+type proxmoxTarget struct {
+	baseURL string
+	node    string
+	auth    string // PVEAPIToken=<令牌 ID>=<密钥>
+	client  *http.Client
+}
+
+func newProxmox(cfg config.DeployConfig) (Target, error) {
+	c := cfg.Proxmox
+	if c.URL == "" {
+		return nil, errors.New("未配置 proxmox.url")
+	}
+	if c.Node == "" {
+		return nil, errors.New("未配置 proxmox.node")
+	}
+	if c.TokenID == "" {
+		return nil, errors.New("未配置 proxmox.token_id")
+	}
+	tokenSecret, err := secret.Resolve(c.TokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := newHTTPClient(c.Insecure, c.CACert)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proxmoxTarget{
+		baseURL: strings.TrimSuffix(c.URL, "/"),
+		node:    c.Node,
+		auth:    "PVEAPIToken=" + c.TokenID + "=" + tokenSecret,
+		client:  client,
+	}, nil
+}
+
+func (t *proxmoxTarget) Name() string {
+	return "proxmox"
+}
+
+func (t *proxmoxTarget) Deploy(c Certificate) error {
+	b, err := c.load()
+	if err != nil {
+		return err
+	}
+	defer b.release()
+
+	form := url.Values{
+		"certificates": {string(b.fullchain())},
+		"key":          {string(b.key.Bytes())},
+		"force":        {"1"}, // 替换已有的自定义证书
+		"restart":      {"1"}, // 重启 pveproxy 使用新证书
+	}
+	body := []byte(form.Encode())
+	defer secmem.Wipe(body)
+
+	endpoint := fmt.Sprintf("%s/api2/json/nodes/%s/certificates/custom", t.baseURL, url.PathEscape(t.node))
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", t.auth)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("连接 Proxmox VE 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		msg := strings.TrimSpace(string(data))
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			msg += "（检查 API 令牌及其在 /nodes/" + t.node + " 上的 Sys.Modify 权限）"
+		}
+		return fmt.Errorf("上传节点 %s 的证书失败: %s: %s", t.node, resp.Status, msg)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package deploy
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/secmem"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultQNAPRestarts 替换 QTS 系统证书后默认执行的命令：重启提供 HTTPS 的 stunnel，
+// 并重新加载 QTS 4.3 以后的反向代理（不存在时跳过）
+var defaultQNAPRestarts = []string{
+	"/etc/init.d/stunnel.sh restart",
+	"[ ! -x /etc/init.d/reverse_proxy.sh ] || /etc/init.d/reverse_proxy.sh reload",
+}
+
+// qnapTarget 在 QNAP 本机替换 QTS 的系统证书：stunnel.pem 为私钥和证书，uca.pem 为中间证书
+type qnapTarget struct {
+	dir      string
+	restarts []string
+}
+
+func newQNAP(cfg config.DeployConfig) (Target, error) {
+	c := cfg.QNAP
+	dir := c.Dir
+	if dir == "" {
+		dir = "/etc/stunnel"
+	}
+	restarts := c.Restarts
+	if len(restarts) == 0 {
+		restarts = defaultQNAPRestarts
+	}
+	return &qnapTarget{dir: dir, restarts: restarts}, nil
+}
+
+func (t *qnapTarget) Name() string {
+	return "qnap"
+}
+
+func (t *qnapTarget) Deploy(c Certificate) error {
+	b, err := c.load()
+	if err != nil {
+		return err
+	}
+	defer b.release()
+
+	if _, err := os.Stat(t.dir); err != nil {
+		return fmt.Errorf("QTS 证书目录不可用（autocert 需要在 QNAP 上运行）: %w", err)
+	}
+
+	// 保留替换前的证书，新证书导致服务无法启动时可以手动恢复
+	stunnelPath := filepath.Join(t.dir, "stunnel.pem")
+	if old, err := os.ReadFile(stunnelPath); err == nil {
+		err := os.WriteFile(stunnelPath+".bak", old, 0600)
+		secmem.Wipe(old)
+		if err != nil {
+			return fmt.Errorf("备份 stunnel.pem 失败: %w", err)
+		}
+	}
+
+	combined := append(append([]byte{}, b.key.Bytes()...), b.leaf...)
+	err = writeFile(stunnelPath, combined, 0600)
+	secmem.Wipe(combined)
+	if err != nil {
+		return fmt.Errorf("写入 stunnel.pem 失败: %w", err)
+	}
+	if len(b.chain) > 0 {
+		if err := writeFile(filepath.Join(t.dir, "uca.pem"), b.chain, 0644); err != nil {
+			return fmt.Errorf("写入 uca.pem 失败: %w", err)
+		}
+	}
+
+	for _, command := range t.restarts {
+		if err := runCommand(command); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runCommand 通过 sh -c 执行命令，输出写入日志
+func runCommand(command string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+	logger.Debug("执行部署命令", "command", command, "output", strings.TrimSpace(string(output)))
+	if err != nil {
+		return fmt.Errorf("执行 %q 失败: %w: %s", command, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
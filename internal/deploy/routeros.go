@@ -0,0 +1,164 @@
+package deploy
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/secret"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// routerOSOpenVPN Use 中表示 OpenVPN 服务器的名称
+const routerOSOpenVPN = "ovpn"
+
+// defaultRouterOSServices 默认使用新证书的服务
+var defaultRouterOSServices = []string{"www-ssl", "api-ssl"}
+
+// routerOSTarget 通过 RouterOS 7 的 REST API 上传证书文件并导入（/certificate import），
+// 让服务改用新证书后删除以前导入的证书
+type routerOSTarget struct {
+	api *apiClient
+	use []string
+}
+
+func newRouterOS(cfg config.DeployConfig) (Target, error) {
+	c := cfg.RouterOS
+	if c.URL == "" {
+		return nil, errors.New("未配置 routeros.url")
+	}
+	if c.Username == "" {
+		return nil, errors.New("未配置 routeros.username")
+	}
+	password, err := secret.Resolve(c.Password)
+	if err != nil {
+		return nil, err
+	}
+	api, err := newAPIClient(strings.TrimSuffix(c.URL, "/")+"/rest", c.Insecure, c.CACert)
+	if err != nil {
+		return nil, err
+	}
+	api.username = c.Username
+	api.password = password
+
+	use := c.Use
+	if len(use) == 0 {
+		use = defaultRouterOSServices
+	}
+	return &routerOSTarget{api: api, use: use}, nil
+}
+
+func (t *routerOSTarget) Name() string {
+	return "routeros"
+}
+
+func (t *routerOSTarget) Deploy(c Certificate) error {
+	b, err := c.load()
+	if err != nil {
+		return err
+	}
+	defer b.release()
+
+	leaf, err := b.leafCert()
+	if err != nil {
+		return err
+	}
+	chain, _, err := b.chainCerts()
+	if err != nil {
+		return err
+	}
+
+	// 每次签发的证书使用不同的名称，服务切换到新证书后再删除旧证书
+	prefix := "autocert-" + strings.ReplaceAll(c.Domains[0], "*", "_") + "-"
+	name := prefix + leaf.NotBefore.UTC().Format("20060102-150405")
+
+	// 证书和私钥分别导入，中间证书逐个上传，避免超过 RouterOS 文件内容的长度限制
+	files := []routerOSFile{
+		{name + ".crt", b.leaf, map[string]string{"name": name}},
+		{name + ".key", b.key.Bytes(), nil},
+	}
+	for i, cert := range chain {
+		chainName := fmt.Sprintf("%s-chain%d", name, i+1)
+		files = append(files, routerOSFile{chainName + ".crt", cert, map[string]string{"name": chainName}})
+	}
+
+	for _, f := range files {
+		if err := t.api.do(http.MethodPut, "/file", map[string]string{"name": f.name, "contents": string(f.data)}, nil); err != nil {
+			return fmt.Errorf("上传 %s 失败: %w", f.name, err)
+		}
+		// 导入后不再需要上传的文件（包括私钥），无论成功与否都删除
+		defer t.removeFile(f.name)
+	}
+	for _, f := range files {
+		params := map[string]string{"file-name": f.name, "passphrase": ""}
+		for key, value := range f.params {
+			params[key] = value
+		}
+		if err := t.api.do(http.MethodPost, "/certificate/import", params, nil); err != nil {
+			return fmt.Errorf("导入 %s 失败: %w", f.name, err)
+		}
+	}
+
+	var imported []map[string]string
+	if err := t.api.do(http.MethodGet, "/certificate?name="+url.QueryEscape(name), nil, &imported); err != nil {
+		return fmt.Errorf("查询导入的证书失败: %w", err)
+	}
+	if len(imported) == 0 {
+		return fmt.Errorf("导入后找不到证书 %s", name)
+	}
+	if imported[0]["private-key"] != "true" {
+		return fmt.Errorf("证书 %s 的私钥没有导入", name)
+	}
+
+	for _, service := range t.use {
+		var err error
+		if service == routerOSOpenVPN {
+			err = t.api.do(http.MethodPost, "/interface/ovpn-server/server/set", map[string]string{"certificate": name}, nil)
+		} else {
+			err = t.api.do(http.MethodPost, "/ip/service/set", map[string]string{"numbers": service, "certificate": name}, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("设置 %s 使用证书 %s 失败: %w", service, name, err)
+		}
+	}
+
+	t.removeOldCerts(prefix, name)
+	return nil
+}
+
+// routerOSFile 上传后导入的文件，params 为额外的导入参数
+type routerOSFile struct {
+	name   string
+	data   []byte
+	params map[string]string
+}
+
+// removeFile 删除上传的文件，失败时只记录日志
+func (t *routerOSTarget) removeFile(name string) {
+	if err := t.api.do(http.MethodPost, "/file/remove", map[string]string{"numbers": name}, nil); err != nil {
+		logger.Warn("删除 RouterOS 上的临时文件失败", "file", name, "error", err)
+	}
+}
+
+// removeOldCerts 删除以前为该域名导入的证书和中间证书（名称以 current 开头的是本次导入的，保留）；
+// 仍被其他配置引用的证书会删除失败，只记录日志
+func (t *routerOSTarget) removeOldCerts(prefix, current string) {
+	var certs []map[string]string
+	if err := t.api.do(http.MethodGet, "/certificate", nil, &certs); err != nil {
+		logger.Warn("查询 RouterOS 证书列表失败", "error", err)
+		return
+	}
+	for _, cert := range certs {
+		name := cert["name"]
+		if !strings.HasPrefix(name, prefix) || strings.HasPrefix(name, current) {
+			continue
+		}
+		if err := t.api.do(http.MethodDelete, "/certificate/"+url.PathEscape(cert[".id"]), nil, nil); err != nil {
+			logger.Warn("删除旧证书失败", "certificate", name, "error", err)
+			continue
+		}
+		logger.Info("已删除旧证书", "certificate", name)
+	}
+}
@@ -0,0 +1,322 @@
+package deploy
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/secmem"
+	"autocert/internal/secret"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// synologyTarget 通过 DSM Web API（SYNO.Core.Certificate）导入证书。
+// DSM 导入证书后会重新加载使用该证书的服务（Web 管理界面、WebDAV、反向代理等），无需单独重启
+type synologyTarget struct {
+	cfg      config.SynologyDeployConfig
+	baseURL  string
+	password string
+	client   *http.Client
+
+	paths map[string]string // API 名称 -> CGI 路径，由 SYNO.API.Info 查询
+	sid   string
+	token string // SynoToken，导入证书时需要
+}
+
+// synologyLoginErrors DSM 登录接口的错误码（其他接口的错误码含义不同）
+var synologyLoginErrors = map[int]string{
+	400: "账户或密码错误",
+	401: "账户已停用",
+	402: "没有权限",
+	403: "账户开启了两步验证，请配置 device_id",
+	404: "两步验证失败",
+	406: "需要开启两步验证",
+}
+
+// synologyError DSM 返回的错误码
+type synologyError struct {
+	code int
+}
+
+func (e *synologyError) Error() string {
+	return fmt.Sprintf("DSM 错误 %d", e.code)
+}
+
+func newSynology(cfg config.DeployConfig) (Target, error) {
+	c := cfg.Synology
+	if c.Username == "" {
+		return nil, errors.New("未配置 synology.username")
+	}
+	password, err := secret.Resolve(c.Password)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := c.URL
+	if baseURL == "" {
+		baseURL = "http://localhost:5000"
+	}
+	return &synologyTarget{
+		cfg:      c,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		password: password,
+		client: &http.Client{
+			Timeout:   time.Minute,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: c.Insecure}},
+		},
+	}, nil
+}
+
+func (t *synologyTarget) Name() string {
+	return "synology"
+}
+
+// synologyResponse DSM Web API 的响应
+type synologyResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+// synologyCertificate DSM 证书列表中的证书
+type synologyCertificate struct {
+	ID        string `json:"id"`
+	Desc      string `json:"desc"`
+	IsDefault bool   `json:"is_default"`
+}
+
+func (t *synologyTarget) Deploy(c Certificate) error {
+	b, err := c.load()
+	if err != nil {
+		return err
+	}
+	defer b.release()
+
+	if err := t.queryPaths(); err != nil {
+		return err
+	}
+	if err := t.login(); err != nil {
+		return err
+	}
+	defer t.logout()
+
+	certs, err := t.listCertificates()
+	if err != nil {
+		return err
+	}
+	id, desc, asDefault := t.selectCertificate(certs, c.Domains[0])
+	if err := t.importCertificate(b, id, desc, asDefault); err != nil {
+		return err
+	}
+	logger.Info("已导入 DSM 证书", "url", t.baseURL, "desc", desc, "replaced", id != "", "default", asDefault)
+	return nil
+}
+
+// selectCertificate 选择替换的证书：按描述查找，未配置描述时替换默认证书；都不存在时导入新证书
+func (t *synologyTarget) selectCertificate(certs []synologyCertificate, primary string) (id, desc string, asDefault bool) {
+	if t.cfg.Certificate != "" {
+		for _, cert := range certs {
+			if cert.Desc == t.cfg.Certificate {
+				return cert.ID, cert.Desc, cert.IsDefault || t.cfg.Default
+			}
+		}
+		return "", t.cfg.Certificate, t.cfg.Default
+	}
+
+	for _, cert := range certs {
+		if cert.IsDefault {
+			return cert.ID, cert.Desc, true
+		}
+	}
+	return "", primary, true
+}
+
+// queryPaths 查询各 API 的 CGI 路径（DSM 6 和 7 的登录接口路径不同）
+func (t *synologyTarget) queryPaths() error {
+	var info map[string]struct {
+		Path string `json:"path"`
+	}
+	params := url.Values{
+		"api":     {"SYNO.API.Info"},
+		"version": {"1"},
+		"method":  {"query"},
+		"query":   {"SYNO.API.Auth,SYNO.Core.Certificate,SYNO.Core.Certificate.CRT"},
+	}
+	if err := t.call("query.cgi", params, &info); err != nil {
+		return fmt.Errorf("查询 DSM API 失败: %w", err)
+	}
+
+	t.paths = make(map[string]string)
+	for _, api := range []string{"SYNO.API.Auth", "SYNO.Core.Certificate", "SYNO.Core.Certificate.CRT"} {
+		if info[api].Path == "" {
+			return fmt.Errorf("DSM 不支持 %s", api)
+		}
+		t.paths[api] = info[api].Path
+	}
+	return nil
+}
+
+func (t *synologyTarget) login() error {
+	params := url.Values{
+		"api":               {"SYNO.API.Auth"},
+		"version":           {"6"},
+		"method":            {"login"},
+		"account":           {t.cfg.Username},
+		"passwd":            {t.password},
+		"format":            {"sid"},
+		"enable_syno_token": {"yes"},
+	}
+	if t.cfg.DeviceID != "" {
+		params.Set("device_id", t.cfg.DeviceID)
+		params.Set("device_name", t.cfg.DeviceName)
+	}
+
+	var session struct {
+		SID       string `json:"sid"`
+		SynoToken string `json:"synotoken"`
+	}
+	if err := t.call(t.paths["SYNO.API.Auth"], params, &session); err != nil {
+		var synoErr *synologyError
+		if errors.As(err, &synoErr) && synologyLoginErrors[synoErr.code] != "" {
+			return fmt.Errorf("登录 DSM 失败: %w（%s）", err, synologyLoginErrors[synoErr.code])
+		}
+		return fmt.Errorf("登录 DSM 失败: %w", err)
+	}
+	t.sid, t.token = session.SID, session.SynoToken
+	return nil
+}
+
+func (t *synologyTarget) logout() {
+	params := url.Values{
+		"api":     {"SYNO.API.Auth"},
+		"version": {"6"},
+		"method":  {"logout"},
+		"_sid":    {t.sid},
+	}
+	if err := t.call(t.paths["SYNO.API.Auth"], params, nil); err != nil {
+		logger.Debug("退出 DSM 登录失败", "error", err)
+	}
+}
+
+func (t *synologyTarget) listCertificates() ([]synologyCertificate, error) {
+	params := url.Values{
+		"api":     {"SYNO.Core.Certificate.CRT"},
+		"version": {"1"},
+		"method":  {"list"},
+		"_sid":    {t.sid},
+	}
+	var list struct {
+		Certificates []synologyCertificate `json:"certificates"`
+	}
+	if err := t.call(t.paths["SYNO.Core.Certificate.CRT"], params, &list); err != nil {
+		return nil, fmt.Errorf("读取 DSM 证书列表失败: %w", err)
+	}
+	return list.Certificates, nil
+}
+
+// importCertificate 上传证书、私钥和中间证书；id 为空时导入新证书
+func (t *synologyTarget) importCertificate(b *bundle, id, desc string, asDefault bool) error {
+	var body bytes.Buffer
+	// 请求体中有私钥，发送后清零
+	defer func() { secmem.Wipe(body.Bytes()) }()
+
+	w := multipart.NewWriter(&body)
+	files := []struct {
+		field string
+		data  []byte
+	}{
+		{"key", b.key.Bytes()},
+		{"cert", b.leaf},
+		{"inter_cert", b.chain},
+	}
+	for _, f := range files {
+		part, err := w.CreateFormFile(f.field, f.field+".pem")
+		if err != nil {
+			return err
+		}
+		if _, err := part.Write(f.data); err != nil {
+			return err
+		}
+	}
+	w.WriteField("id", id)
+	w.WriteField("desc", desc)
+	w.WriteField("as_default", fmt.Sprintf("%t", asDefault))
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	params := url.Values{
+		"api":       {"SYNO.Core.Certificate"},
+		"version":   {"1"},
+		"method":    {"import"},
+		"_sid":      {t.sid},
+		"SynoToken": {t.token},
+	}
+	req, err := http.NewRequest(http.MethodPost, t.endpoint(t.paths["SYNO.Core.Certificate"], params), bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("X-SYNO-TOKEN", t.token)
+	if err := t.send(req, nil); err != nil {
+		return fmt.Errorf("导入 DSM 证书失败: %w", err)
+	}
+	return nil
+}
+
+func (t *synologyTarget) endpoint(path string, params url.Values) string {
+	return t.baseURL + "/webapi/" + path + "?" + params.Encode()
+}
+
+// call 以表单提交调用 API，密码等参数不出现在 URL 中
+func (t *synologyTarget) call(path string, params url.Values, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, t.baseURL+"/webapi/"+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return t.send(req, out)
+}
+
+// send 发送请求并解析 DSM 的响应，success 为 false 时返回错误码
+func (t *synologyTarget) send(req *http.Request, out interface{}) error {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("DSM 返回 %s", resp.Status)
+	}
+
+	var result synologyResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("解析 DSM 响应失败: %w", err)
+	}
+	if !result.Success {
+		if result.Error == nil {
+			return errors.New("DSM 返回失败")
+		}
+		return &synologyError{code: result.Error.Code}
+	}
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("解析 DSM 响应失败: %w", err)
+		}
+	}
+	return nil
+}
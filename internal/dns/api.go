@@ -0,0 +1,163 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// apiClient DNS 服务商 HTTP JSON API 的公共请求逻辑
+type apiClient struct {
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+	sign    func(req *http.Request, body []byte) // AK/SK 签名，在设置完其他请求头后调用
+}
+
+func newAPIClient(baseURL string, headers map[string]string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do 发送请求并把 JSON 响应解析到 out（为 nil 时忽略响应体），返回状态码便于调用方区分 404
+func (c *apiClient) do(method, path string, body, out interface{}) (int, error) {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		payload = data
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	if c.sign != nil {
+		c.sign(req, payload)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300] + "..."
+		}
+		return resp.StatusCode, fmt.Errorf("%s %s 返回 %s: %s", method, path, resp.Status, msg)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("解析 %s 的响应失败: %w", path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// longestZone 候选区域中记录名的最长后缀，没有匹配时返回空字符串
+func longestZone(name string, zones []string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	best := ""
+	for _, zone := range zones {
+		zone = strings.TrimSuffix(strings.ToLower(zone), ".")
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > len(best) {
+			best = zone
+		}
+	}
+	return best
+}
+
+// candidateZones 记录名的各级后缀（至少两级），从长到短，用于逐级查询记录所在的区域
+func candidateZones(name string) []string {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(name), "."), ".")
+	var zones []string
+	for i := 0; i+2 <= len(labels); i++ {
+		zones = append(zones, strings.Join(labels[i:], "."))
+	}
+	return zones
+}
+
+// relativeName 记录名相对区域的部分，区域顶点为空字符串
+func relativeName(name, zone string) string {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	return strings.TrimSuffix(strings.TrimSuffix(name, zone), ".")
+}
+
+// uriEncode 按 RFC 3986 编码（只保留非保留字符），云服务商的 AK/SK 签名都使用这种编码
+func uriEncode(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || keepSlash && c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery 按参数名排序并编码的查询字符串
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, false)+"="+uriEncode(value, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// editValues 在记录集中添加（add 为 true）或删除一个值，返回新的记录集和是否有变化
+func editValues(values []string, value string, add bool) ([]string, bool) {
+	result := make([]string, 0, len(values)+1)
+	found := false
+	for _, v := range values {
+		if v == value {
+			found = true
+			if !add {
+				continue
+			}
+		}
+		result = append(result, v)
+	}
+	if add && !found {
+		result = append(result, value)
+	}
+	return result, found != add
+}
@@ -0,0 +1,196 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// baiduCloudProvider 通过百度智能云 DNS API 管理 TXT 记录，每个记录值是一条单独的记录
+type baiduCloudProvider struct {
+	api *apiClient
+	ttl int
+	mu  sync.Mutex
+}
+
+type baiduRecord struct {
+	ID    string `json:"id,omitempty"`
+	RR    string `json:"rr"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	TTL   int    `json:"ttl,omitempty"`
+}
+
+func newBaiduCloud(cfg config.DNSConfig) (Provider, error) {
+	c := cfg.BaiduCloud
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, errors.New("未配置 dns.baiducloud.access_key 或 dns.baiducloud.secret_key")
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://dns.baidubce.com"
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = 300
+	}
+	api := newAPIClient(endpoint, nil)
+	api.sign = func(req *http.Request, _ []byte) {
+		signBaiduCloud(req, c.AccessKey, c.SecretKey, time.Now())
+	}
+	return &baiduCloudProvider{api: api, ttl: ttl}, nil
+}
+
+func (p *baiduCloudProvider) Name() string {
+	return "baiducloud"
+}
+
+func (p *baiduCloudProvider) Present(fqdn, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, rr, records, err := p.records(fqdn)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Value == value {
+			return nil
+		}
+	}
+	record := baiduRecord{RR: rr, Type: "TXT", Value: value, TTL: p.ttl}
+	if _, err := p.api.do(http.MethodPost, p.recordPath(zone, "")+"?clientToken="+clientToken(), record, nil); err != nil {
+		return fmt.Errorf("添加记录失败: %w", err)
+	}
+	return nil
+}
+
+func (p *baiduCloudProvider) CleanUp(fqdn, value string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, _, records, err := p.records(fqdn)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Value != value {
+			continue
+		}
+		if _, err := p.api.do(http.MethodDelete, p.recordPath(zone, record.ID)+"?clientToken="+clientToken(), nil, nil); err != nil {
+			return fmt.Errorf("删除记录失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Zone 账户中域名为记录名最长后缀的域名
+func (p *baiduCloudProvider) Zone(fqdn string) (string, error) {
+	var names []string
+	marker := ""
+	for {
+		var result struct {
+			Zones []struct {
+				Name string `json:"name"`
+			} `json:"zones"`
+			IsTruncated bool   `json:"isTruncated"`
+			NextMarker  string `json:"nextMarker"`
+		}
+		path := "/v1/dns/zone"
+		if marker != "" {
+			path += "?marker=" + url.QueryEscape(marker)
+		}
+		if _, err := p.api.do(http.MethodGet, path, nil, &result); err != nil {
+			return "", fmt.Errorf("获取域名列表失败: %w", err)
+		}
+		for _, zone := range result.Zones {
+			names = append(names, zone.Name)
+		}
+		if !result.IsTruncated || result.NextMarker == "" {
+			break
+		}
+		marker = result.NextMarker
+	}
+
+	if best := longestZone(fqdn, names); best != "" {
+		return best, nil
+	}
+	return "", fmt.Errorf("百度智能云账户中没有记录 %s 所在的域名", fqdn)
+}
+
+// records 记录所在的域名、主机记录和该主机记录下的 TXT 记录
+func (p *baiduCloudProvider) records(fqdn string) (string, string, []baiduRecord, error) {
+	zone, err := p.Zone(fqdn)
+	if err != nil {
+		return "", "", nil, err
+	}
+	rr := relativeName(fqdn, zone)
+	if rr == "" {
+		rr = "@"
+	}
+
+	var result struct {
+		Records []baiduRecord `json:"records"`
+	}
+	if _, err := p.api.do(http.MethodGet, p.recordPath(zone, "")+"?rr="+url.QueryEscape(rr), nil, &result); err != nil {
+		return "", "", nil, fmt.Errorf("获取记录失败: %w", err)
+	}
+	var records []baiduRecord
+	for _, record := range result.Records {
+		if strings.EqualFold(record.RR, rr) && strings.EqualFold(record.Type, "TXT") {
+			records = append(records, record)
+		}
+	}
+	return zone, rr, records, nil
+}
+
+func (p *baiduCloudProvider) recordPath(zone, id string) string {
+	path := "/v1/dns/zone/" + url.PathEscape(zone) + "/record"
+	if id != "" {
+		path += "/" + url.PathEscape(id)
+	}
+	return path
+}
+
+// clientToken 幂等请求标识，重试时服务端不会重复执行
+func clientToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// signBaiduCloud 百度智能云 bce-auth-v1 签名，签名 host 和 x-bce-date 两个请求头
+func signBaiduCloud(req *http.Request, accessKey, secretKey string, now time.Time) {
+	timestamp := now.UTC().Format("2006-01-02T15:04:05Z")
+	req.Header.Set("X-Bce-Date", timestamp)
+
+	headers := map[string]string{"host": req.URL.Host, "x-bce-date": timestamp}
+	signed := make([]string, 0, len(headers))
+	var canonicalHeaders []string
+	for name, value := range headers {
+		signed = append(signed, name)
+		canonicalHeaders = append(canonicalHeaders, uriEncode(name, false)+":"+uriEncode(strings.TrimSpace(value), false))
+	}
+	sort.Strings(signed)
+	sort.Strings(canonicalHeaders)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, true),
+		canonicalQuery(req.URL.Query()),
+		strings.Join(canonicalHeaders, "\n"),
+	}, "\n")
+
+	authPrefix := fmt.Sprintf("bce-auth-v1/%s/%s/1800", accessKey, timestamp)
+	signingKey := hex.EncodeToString(hmacSHA256([]byte(secretKey), authPrefix))
+	signature := hex.EncodeToString(hmacSHA256([]byte(signingKey), canonicalRequest))
+	req.Header.Set("Authorization", authPrefix+"/"+strings.Join(signed, ";")+"/"+signature)
+}
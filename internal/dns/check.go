@@ -0,0 +1,68 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// CheckStep dns test 的一个检查步骤
+type CheckStep struct {
+	Name    string
+	Detail  string
+	Err     error
+	Skipped bool
+}
+
+// Check 在签发前验证服务商配置：查询记录所在区域（同时验证凭据）、添加测试 TXT 记录、
+// 确认记录在权威服务器上生效，最后立即删除。测试记录与验证记录一样写入状态文件，
+// 删除失败时可由 cleanup-dns 清理
+func Check(provider Provider, domain string) []CheckStep {
+	fqdn := ChallengeRecord(domain)
+	var steps []CheckStep
+
+	zone := ""
+	if zoneProvider, ok := provider.(ZoneProvider); ok {
+		z, err := zoneProvider.Zone(fqdn)
+		if err != nil {
+			return append(steps, CheckStep{Name: "凭据和区域", Err: err})
+		}
+		zone = z
+		steps = append(steps, CheckStep{Name: "凭据和区域", Detail: "记录 " + fqdn + " 位于区域 " + zone})
+	} else {
+		steps = append(steps, CheckStep{Name: "凭据和区域", Skipped: true, Detail: provider.Name() + " 不支持查询区域，将在添加记录时验证"})
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	value := "autocert-dns-test-" + hex.EncodeToString(b)
+	if err := track(provider, domain, fqdn, value); err != nil {
+		return append(steps, CheckStep{Name: "添加测试记录", Err: err})
+	}
+	if err := provider.Present(fqdn, value); err != nil {
+		return append(steps, CheckStep{Name: "添加测试记录", Err: err})
+	}
+	steps = append(steps, CheckStep{Name: "添加测试记录", Detail: fmt.Sprintf("%s TXT %q", fqdn, value)})
+
+	propagation := config.GetDNSConfig().Propagation
+	switch {
+	case zone == "":
+		steps = append(steps, CheckStep{Name: "记录生效", Skipped: true, Detail: "无法确定记录所在区域"})
+	case !waitsForPropagation(provider):
+		steps = append(steps, CheckStep{Name: "记录生效", Skipped: true, Detail: provider.Name() + " 重载后记录由本机权威服务器提供，不查询区域 NS"})
+	case propagation.Timeout <= 0:
+		steps = append(steps, CheckStep{Name: "记录生效", Skipped: true, Detail: "dns.propagation.timeout 为 0"})
+	default:
+		if err := waitForTXT(zone, fqdn, value); err != nil {
+			steps = append(steps, CheckStep{Name: "记录生效", Err: err})
+		} else {
+			steps = append(steps, CheckStep{Name: "记录生效", Detail: "所有权威服务器均已返回测试记录"})
+		}
+	}
+
+	if err := CleanUp(provider, fqdn, value); err != nil {
+		return append(steps, CheckStep{Name: "删除测试记录", Err: fmt.Errorf("%w（可稍后执行 cleanup-dns 删除）", err)})
+	}
+	return append(steps, CheckStep{Name: "删除测试记录", Detail: "已删除"})
+}
@@ -0,0 +1,133 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// deSECMinTTL deSEC 允许的最小 TTL
+const deSECMinTTL = 3600
+
+// deSECProvider 通过 deSEC.io API 管理记录
+type deSECProvider struct {
+	api *apiClient
+	ttl int
+	mu  sync.Mutex // 读取后整体写回记录集，同名记录（泛域名和主域名）的并发修改需要串行
+}
+
+type deSECRRset struct {
+	Subname string   `json:"subname"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl,omitempty"`
+	Records []string `json:"records"`
+}
+
+func newDeSEC(cfg config.DNSConfig) (Provider, error) {
+	c := cfg.DeSEC
+	if c.Token == "" {
+		return nil, errors.New("未配置 dns.desec.token")
+	}
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = "https://desec.io/api/v1"
+	}
+	return &deSECProvider{
+		api: newAPIClient(apiURL, map[string]string{"Authorization": "Token " + c.Token}),
+		ttl: max(c.TTL, deSECMinTTL),
+	}, nil
+}
+
+func (p *deSECProvider) Name() string {
+	return "desec"
+}
+
+func (p *deSECProvider) Present(fqdn, value string) error {
+	return p.AddRecord(fqdn, "TXT", value, p.ttl)
+}
+
+func (p *deSECProvider) CleanUp(fqdn, value string) error {
+	return p.DeleteRecord(fqdn, "TXT", value)
+}
+
+func (p *deSECProvider) AddRecord(name, recordType, value string, ttl int) error {
+	return p.modify(name, recordType, max(ttl, p.ttl), zoneValue(recordType, value), true)
+}
+
+func (p *deSECProvider) DeleteRecord(name, recordType, value string) error {
+	return p.modify(name, recordType, p.ttl, zoneValue(recordType, value), false)
+}
+
+// Zone 记录所在的域名（由 deSEC 根据 owns_qname 查询）
+func (p *deSECProvider) Zone(fqdn string) (string, error) {
+	var domains []struct {
+		Name string `json:"name"`
+	}
+	name := strings.TrimSuffix(strings.ToLower(fqdn), ".")
+	if _, err := p.api.do(http.MethodGet, "/domains/?owns_qname="+url.QueryEscape(name), nil, &domains); err != nil {
+		return "", fmt.Errorf("查询记录所在的域名失败: %w", err)
+	}
+	if len(domains) == 0 {
+		return "", fmt.Errorf("deSEC 账户中没有记录 %s 所在的域名", name)
+	}
+	return domains[0].Name, nil
+}
+
+// HostNames 读取域名的全部记录集
+func (p *deSECProvider) HostNames(zone string) ([]string, error) {
+	var rrsets []deSECRRset
+	if _, err := p.api.do(http.MethodGet, "/domains/"+url.PathEscape(zone)+"/rrsets/", nil, &rrsets); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, rrset := range rrsets {
+		name := zone
+		if rrset.Subname != "" {
+			name = strings.ToLower(rrset.Subname) + "." + zone
+		}
+		if isHostRecord(name, rrset.Type) {
+			seen[name] = true
+		}
+	}
+	return sortedNames(seen), nil
+}
+
+// modify 读取记录集、添加或删除一个值后通过批量接口写回（记录为空时 deSEC 删除该记录集）
+func (p *deSECProvider) modify(name, recordType string, ttl int, content string, add bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, err := p.Zone(name)
+	if err != nil {
+		return err
+	}
+	subname := relativeName(name, zone)
+	recordType = strings.ToUpper(recordType)
+
+	// 区域顶点的记录集在 URL 中用 @ 表示
+	urlSubname := subname
+	if urlSubname == "" {
+		urlSubname = "@"
+	}
+	domainPath := "/domains/" + url.PathEscape(zone) + "/rrsets/"
+	var current deSECRRset
+	status, err := p.api.do(http.MethodGet, domainPath+url.PathEscape(urlSubname)+"/"+recordType+"/", nil, &current)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("获取记录失败: %w", err)
+	}
+
+	records, changed := editValues(current.Records, content, add)
+	if !changed {
+		return nil
+	}
+	body := []deSECRRset{{Subname: subname, Type: recordType, TTL: ttl, Records: records}}
+	if _, err := p.api.do(http.MethodPatch, domainPath, body, nil); err != nil {
+		return fmt.Errorf("更新记录失败: %w", err)
+	}
+	return nil
+}
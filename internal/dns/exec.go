@@ -0,0 +1,91 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// execProvider 调用外部命令管理记录，适用于没有内置支持的 DNS 服务商
+type execProvider struct {
+	command string
+	timeout time.Duration
+}
+
+func newExec(cfg config.DNSConfig) (Provider, error) {
+	if cfg.Exec.Command == "" {
+		return nil, errors.New("未配置 dns.exec.command")
+	}
+	timeout := cfg.Exec.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	return &execProvider{command: cfg.Exec.Command, timeout: timeout}, nil
+}
+
+func (p *execProvider) Name() string {
+	return "exec"
+}
+
+func (p *execProvider) Present(fqdn, value string) error {
+	return p.run("present", fqdn, "TXT", value, 0)
+}
+
+func (p *execProvider) CleanUp(fqdn, value string) error {
+	return p.run("cleanup", fqdn, "TXT", value, 0)
+}
+
+func (p *execProvider) AddRecord(name, recordType, value string, ttl int) error {
+	return p.run("present", name, recordType, value, ttl)
+}
+
+func (p *execProvider) DeleteRecord(name, recordType, value string) error {
+	return p.run("cleanup", name, recordType, value, 0)
+}
+
+// run 通过 shell 执行命令，记录信息通过环境变量传入，避免拼接到命令行
+func (p *execProvider) run(action, fqdn, recordType, value string, ttl int) error {
+	env := []string{
+		"AUTOCERT_DNS_ACTION=" + action,
+		"AUTOCERT_DNS_RECORD=" + fqdn,
+		"AUTOCERT_DNS_TYPE=" + recordType,
+		"AUTOCERT_DNS_VALUE=" + value,
+	}
+	if ttl > 0 {
+		env = append(env, fmt.Sprintf("AUTOCERT_DNS_TTL=%d", ttl))
+	}
+	return runShell(p.command, p.timeout, env)
+}
+
+// runShell 通过 sh -c（Windows 上为 cmd /C）执行命令，失败时错误信息包含命令输出
+func runShell(command string, timeout time.Duration, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Env = append(os.Environ(), env...)
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("执行超时（%s）", timeout)
+	}
+	if err != nil {
+		if out := strings.TrimSpace(string(output)); out != "" {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
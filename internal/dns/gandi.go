@@ -0,0 +1,120 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// gandiMinTTL Gandi LiveDNS 允许的最小 TTL
+const gandiMinTTL = 300
+
+// gandiProvider 通过 Gandi LiveDNS API 管理记录
+type gandiProvider struct {
+	api *apiClient
+	ttl int
+	mu  sync.Mutex // 记录集整体替换，同名记录的并发修改需要串行
+}
+
+type gandiRRset struct {
+	TTL    int      `json:"rrset_ttl,omitempty"`
+	Values []string `json:"rrset_values"`
+}
+
+func newGandi(cfg config.DNSConfig) (Provider, error) {
+	c := cfg.Gandi
+	if c.Token == "" {
+		return nil, errors.New("未配置 dns.gandi.token")
+	}
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.gandi.net/v5/livedns"
+	}
+	return &gandiProvider{
+		api: newAPIClient(apiURL, map[string]string{"Authorization": "Bearer " + c.Token}),
+		ttl: max(c.TTL, gandiMinTTL),
+	}, nil
+}
+
+func (p *gandiProvider) Name() string {
+	return "gandi"
+}
+
+func (p *gandiProvider) Present(fqdn, value string) error {
+	return p.AddRecord(fqdn, "TXT", value, p.ttl)
+}
+
+func (p *gandiProvider) CleanUp(fqdn, value string) error {
+	return p.DeleteRecord(fqdn, "TXT", value)
+}
+
+func (p *gandiProvider) AddRecord(name, recordType, value string, ttl int) error {
+	return p.modify(name, recordType, max(ttl, p.ttl), zoneValue(recordType, value), true)
+}
+
+func (p *gandiProvider) DeleteRecord(name, recordType, value string) error {
+	return p.modify(name, recordType, p.ttl, zoneValue(recordType, value), false)
+}
+
+// Zone 从最长的后缀开始逐级查询 LiveDNS 管理的域名
+func (p *gandiProvider) Zone(fqdn string) (string, error) {
+	// 不属于账户的域名可能返回 403，全部后缀都没有找到时附带最后一个 403 错误，便于排查令牌权限
+	var denied error
+	for _, zone := range candidateZones(fqdn) {
+		status, err := p.api.do(http.MethodGet, "/domains/"+url.PathEscape(zone), nil, nil)
+		if err == nil {
+			return zone, nil
+		}
+		if status == http.StatusForbidden {
+			denied = err
+			continue
+		}
+		if status != http.StatusNotFound {
+			return "", fmt.Errorf("查询域名 %s 失败: %w", zone, err)
+		}
+	}
+	if denied != nil {
+		return "", fmt.Errorf("Gandi LiveDNS 中没有记录 %s 所在的域名: %w", fqdn, denied)
+	}
+	return "", fmt.Errorf("Gandi LiveDNS 中没有记录 %s 所在的域名", fqdn)
+}
+
+// modify 读取记录集、添加或删除一个值后整体写回，没有剩余的值时删除记录集
+func (p *gandiProvider) modify(fqdn, recordType string, ttl int, content string, add bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, err := p.Zone(fqdn)
+	if err != nil {
+		return err
+	}
+	name := relativeName(fqdn, zone)
+	if name == "" {
+		name = "@"
+	}
+	path := "/domains/" + url.PathEscape(zone) + "/records/" + url.PathEscape(name) + "/" + strings.ToUpper(recordType)
+
+	var current gandiRRset
+	status, err := p.api.do(http.MethodGet, path, nil, &current)
+	if err != nil && status != http.StatusNotFound {
+		return fmt.Errorf("获取记录失败: %w", err)
+	}
+	values, changed := editValues(current.Values, content, add)
+	if !changed {
+		return nil
+	}
+
+	if len(values) == 0 {
+		_, err = p.api.do(http.MethodDelete, path, nil, nil)
+	} else {
+		_, err = p.api.do(http.MethodPut, path, gandiRRset{TTL: ttl, Values: values}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("更新记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// goDaddyMinTTL GoDaddy 允许的最小 TTL
+const goDaddyMinTTL = 600
+
+// goDaddyProvider 通过 GoDaddy 域名 API 管理 TXT 记录（GoDaddy 不支持 TLSA 记录）
+type goDaddyProvider struct {
+	api *apiClient
+	ttl int
+	mu  sync.Mutex // 记录按名称和类型整体替换，同名记录的并发修改需要串行
+}
+
+type goDaddyRecord struct {
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+func newGoDaddy(cfg config.DNSConfig) (Provider, error) {
+	c := cfg.GoDaddy
+	if c.APIKey == "" || c.APISecret == "" {
+		return nil, errors.New("未配置 dns.godaddy.api_key 或 dns.godaddy.api_secret")
+	}
+	apiURL := c.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.godaddy.com"
+	}
+	return &goDaddyProvider{
+		api: newAPIClient(apiURL+"/v1", map[string]string{"Authorization": "sso-key " + c.APIKey + ":" + c.APISecret}),
+		ttl: max(c.TTL, goDaddyMinTTL),
+	}, nil
+}
+
+func (p *goDaddyProvider) Name() string {
+	return "godaddy"
+}
+
+func (p *goDaddyProvider) Present(fqdn, value string) error {
+	return p.modify(fqdn, value, true)
+}
+
+func (p *goDaddyProvider) CleanUp(fqdn, value string) error {
+	return p.modify(fqdn, value, false)
+}
+
+// Zone 从最长的后缀开始逐级查询账户中的域名
+func (p *goDaddyProvider) Zone(fqdn string) (string, error) {
+	// 不属于账户的域名可能返回 403，全部后缀都没有找到时附带最后一个 403 错误，便于排查令牌权限
+	var denied error
+	for _, zone := range candidateZones(fqdn) {
+		status, err := p.api.do(http.MethodGet, "/domains/"+url.PathEscape(zone), nil, nil)
+		if err == nil {
+			return zone, nil
+		}
+		if status == http.StatusForbidden {
+			denied = err
+			continue
+		}
+		if status != http.StatusNotFound && status != http.StatusUnprocessableEntity {
+			return "", fmt.Errorf("查询域名 %s 失败: %w", zone, err)
+		}
+	}
+	if denied != nil {
+		return "", fmt.Errorf("GoDaddy 账户中没有记录 %s 所在的域名: %w", fqdn, denied)
+	}
+	return "", fmt.Errorf("GoDaddy 账户中没有记录 %s 所在的域名", fqdn)
+}
+
+// modify PUT 会替换同名同类型的全部记录，先读取已有的值再整体写回；没有剩余的值时删除
+func (p *goDaddyProvider) modify(fqdn, value string, add bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, err := p.Zone(fqdn)
+	if err != nil {
+		return err
+	}
+	name := relativeName(fqdn, zone)
+	if name == "" {
+		name = "@"
+	}
+	path := "/domains/" + url.PathEscape(zone) + "/records/TXT/" + url.PathEscape(name)
+
+	var current []goDaddyRecord
+	if _, err := p.api.do(http.MethodGet, path, nil, &current); err != nil {
+		return fmt.Errorf("获取记录失败: %w", err)
+	}
+	values := make([]string, len(current))
+	for i, record := range current {
+		values[i] = record.Data
+	}
+	values, changed := editValues(values, value, add)
+	if !changed {
+		return nil
+	}
+
+	if len(values) == 0 {
+		_, err = p.api.do(http.MethodDelete, path, nil, nil)
+	} else {
+		records := make([]goDaddyRecord, len(values))
+		for i, v := range values {
+			records[i] = goDaddyRecord{Data: v, TTL: p.ttl}
+		}
+		_, err = p.api.do(http.MethodPut, path, records, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("更新记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// huaweiCloudProvider 通过华为云云解析服务 API（v2）管理 TXT 记录
+type huaweiCloudProvider struct {
+	api *apiClient
+	ttl int
+	mu  sync.Mutex // 记录集整体替换，同名记录的并发修改需要串行
+}
+
+type huaweiZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type huaweiRecordSet struct {
+	ID      string   `json:"id,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Type    string   `json:"type,omitempty"`
+	TTL     int      `json:"ttl,omitempty"`
+	Records []string `json:"records"`
+}
+
+func newHuaweiCloud(cfg config.DNSConfig) (Provider, error) {
+	c := cfg.HuaweiCloud
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return nil, errors.New("未配置 dns.huaweicloud.access_key 或 dns.huaweicloud.secret_key")
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://dns.myhuaweicloud.com"
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = 300
+	}
+	api := newAPIClient(endpoint, nil)
+	api.sign = func(req *http.Request, body []byte) {
+		signHuaweiCloud(req, body, c.AccessKey, c.SecretKey, time.Now())
+	}
+	return &huaweiCloudProvider{api: api, ttl: ttl}, nil
+}
+
+func (p *huaweiCloudProvider) Name() string {
+	return "huaweicloud"
+}
+
+func (p *huaweiCloudProvider) Present(fqdn, value string) error {
+	return p.modify(fqdn, zoneValue("TXT", value), true)
+}
+
+func (p *huaweiCloudProvider) CleanUp(fqdn, value string) error {
+	return p.modify(fqdn, zoneValue("TXT", value), false)
+}
+
+func (p *huaweiCloudProvider) Zone(fqdn string) (string, error) {
+	zone, err := p.findZone(fqdn)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(zone.Name, "."), nil
+}
+
+// huaweiPageSize 华为云列表接口单页最大数量
+const huaweiPageSize = 500
+
+// findZone 公网域名中区域名为记录名最长后缀的区域
+func (p *huaweiCloudProvider) findZone(fqdn string) (huaweiZone, error) {
+	zones, err := p.listZones()
+	if err != nil {
+		return huaweiZone{}, err
+	}
+	names := make([]string, len(zones))
+	for i, zone := range zones {
+		names[i] = zone.Name
+	}
+	if best := longestZone(fqdn, names); best != "" {
+		for _, zone := range zones {
+			if strings.EqualFold(strings.TrimSuffix(zone.Name, "."), best) {
+				return zone, nil
+			}
+		}
+	}
+	return huaweiZone{}, fmt.Errorf("华为云账户中没有记录 %s 所在的公网域名", fqdn)
+}
+
+// listZones 按 offset 分页读取全部公网域名
+func (p *huaweiCloudProvider) listZones() ([]huaweiZone, error) {
+	var zones []huaweiZone
+	for offset := 0; ; offset += huaweiPageSize {
+		var result struct {
+			Zones    []huaweiZone `json:"zones"`
+			Metadata struct {
+				TotalCount int `json:"total_count"`
+			} `json:"metadata"`
+		}
+		path := fmt.Sprintf("/v2/zones?type=public&limit=%d&offset=%d", huaweiPageSize, offset)
+		if _, err := p.api.do(http.MethodGet, path, nil, &result); err != nil {
+			return nil, fmt.Errorf("获取域名列表失败: %w", err)
+		}
+		zones = append(zones, result.Zones...)
+		if len(result.Zones) < huaweiPageSize || result.Metadata.TotalCount > 0 && len(zones) >= result.Metadata.TotalCount {
+			return zones, nil
+		}
+	}
+}
+
+// modify 华为云同名同类型的记录为一个记录集：不存在时创建，存在时修改值列表，没有剩余的值时删除
+func (p *huaweiCloudProvider) modify(fqdn, content string, add bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, err := p.findZone(fqdn)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(strings.ToLower(fqdn), ".") + "."
+	zonePath := "/v2/zones/" + url.PathEscape(zone.ID) + "/recordsets"
+
+	var result struct {
+		RecordSets []huaweiRecordSet `json:"recordsets"`
+	}
+	query := url.Values{"type": {"TXT"}, "name": {name}, "search_mode": {"equal"}}
+	if _, err := p.api.do(http.MethodGet, zonePath+"?"+query.Encode(), nil, &result); err != nil {
+		return fmt.Errorf("获取记录失败: %w", err)
+	}
+	var current *huaweiRecordSet
+	for i, rs := range result.RecordSets {
+		if strings.EqualFold(rs.Name, name) && rs.Type == "TXT" {
+			current = &result.RecordSets[i]
+			break
+		}
+	}
+
+	if current == nil {
+		if !add {
+			return nil
+		}
+		_, err = p.api.do(http.MethodPost, zonePath, huaweiRecordSet{Name: name, Type: "TXT", TTL: p.ttl, Records: []string{content}}, nil)
+	} else {
+		values, changed := editValues(current.Records, content, add)
+		if !changed {
+			return nil
+		}
+		path := zonePath + "/" + url.PathEscape(current.ID)
+		if len(values) == 0 {
+			_, err = p.api.do(http.MethodDelete, path, nil, nil)
+		} else {
+			_, err = p.api.do(http.MethodPut, path, huaweiRecordSet{TTL: current.TTL, Records: values}, nil)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("更新记录失败: %w", err)
+	}
+	return nil
+}
+
+// signHuaweiCloud 华为云 API 网关 SDK-HMAC-SHA256 签名
+func signHuaweiCloud(req *http.Request, body []byte, accessKey, secretKey string, now time.Time) {
+	date := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Sdk-Date", date)
+
+	headers := map[string]string{"host": req.URL.Host, "x-sdk-date": date}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	signed := make([]string, 0, len(headers))
+	for name := range headers {
+		signed = append(signed, name)
+	}
+	sort.Strings(signed)
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+
+	// 规范 URI 的每一段单独编码，并以 / 结尾
+	uri := uriEncode(req.URL.Path, true)
+	if !strings.HasSuffix(uri, "/") {
+		uri += "/"
+	}
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		strings.Join(signed, ";"),
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "SDK-HMAC-SHA256\n" + date + "\n" + hex.EncodeToString(requestHash[:])
+	signature := hex.EncodeToString(hmacSHA256([]byte(secretKey), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("SDK-HMAC-SHA256 Access=%s, SignedHeaders=%s, Signature=%s",
+		accessKey, strings.Join(signed, ";"), signature))
+}
@@ -0,0 +1,198 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// namecheapNotFound 域名不存在或不属于该账户的错误码
+var namecheapNotFound = map[string]bool{"2019166": true, "2016166": true}
+
+// namecheapProvider 通过 Namecheap XML API 管理 TXT 记录。
+// setHosts 会替换域名的全部记录，因此每次修改都先读取全部记录再整体写回
+type namecheapProvider struct {
+	cfg    config.NamecheapConfig
+	client *http.Client
+	mu     sync.Mutex
+}
+
+type namecheapResponse struct {
+	Status string `xml:"Status,attr"`
+	Errors []struct {
+		Number  string `xml:"Number,attr"`
+		Message string `xml:",chardata"`
+	} `xml:"Errors>Error"`
+	Hosts struct {
+		EmailType     string          `xml:"EmailType,attr"`
+		IsUsingOurDNS bool            `xml:"IsUsingOurDNS,attr"`
+		Hosts         []namecheapHost `xml:"host"`
+	} `xml:"CommandResponse>DomainDNSGetHostsResult"`
+}
+
+type namecheapHost struct {
+	Name    string `xml:"Name,attr"`
+	Type    string `xml:"Type,attr"`
+	Address string `xml:"Address,attr"`
+	MXPref  string `xml:"MXPref,attr"`
+	TTL     string `xml:"TTL,attr"`
+}
+
+// namecheapError API 返回的错误
+type namecheapError struct {
+	number  string
+	message string
+}
+
+func (e *namecheapError) Error() string {
+	return fmt.Sprintf("Namecheap 错误 %s: %s", e.number, e.message)
+}
+
+func newNamecheap(cfg config.DNSConfig) (Provider, error) {
+	c := cfg.Namecheap
+	if c.APIUser == "" || c.APIKey == "" || c.ClientIP == "" {
+		return nil, errors.New("未配置 dns.namecheap.api_user、api_key 或 client_ip")
+	}
+	if c.Username == "" {
+		c.Username = c.APIUser
+	}
+	if c.APIURL == "" {
+		c.APIURL = "https://api.namecheap.com/xml.response"
+	}
+	if c.TTL <= 0 {
+		c.TTL = 60
+	}
+	return &namecheapProvider{cfg: c, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+func (p *namecheapProvider) Name() string {
+	return "namecheap"
+}
+
+func (p *namecheapProvider) Present(fqdn, value string) error {
+	return p.modify(fqdn, value, true)
+}
+
+func (p *namecheapProvider) CleanUp(fqdn, value string) error {
+	return p.modify(fqdn, value, false)
+}
+
+func (p *namecheapProvider) Zone(fqdn string) (string, error) {
+	zone, _, err := p.findZone(fqdn)
+	return zone, err
+}
+
+// findZone 读取记录所在注册域名的全部记录。Namecheap 的区域就是注册域名，
+// SLD/TLD 按公共后缀拆分（example.co.uk 的 TLD 为 co.uk）
+func (p *namecheapProvider) findZone(fqdn string) (string, *namecheapResponse, error) {
+	zone := RegisteredDomain(fqdn)
+	sld, tld, _ := strings.Cut(zone, ".")
+	resp, err := p.call("namecheap.domains.dns.getHosts", url.Values{"SLD": {sld}, "TLD": {tld}})
+	var apiErr *namecheapError
+	if errors.As(err, &apiErr) && namecheapNotFound[apiErr.number] {
+		return "", nil, fmt.Errorf("Namecheap 账户中没有记录 %s 所在的域名 %s", fqdn, zone)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("读取域名 %s 的记录失败: %w", zone, err)
+	}
+	if !resp.Hosts.IsUsingOurDNS {
+		return "", nil, fmt.Errorf("域名 %s 未使用 Namecheap 的 DNS 服务器", zone)
+	}
+	return zone, resp, nil
+}
+
+func (p *namecheapProvider) modify(fqdn, value string, add bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, resp, err := p.findZone(fqdn)
+	if err != nil {
+		return err
+	}
+	name := relativeName(fqdn, zone)
+	if name == "" {
+		name = "@"
+	}
+
+	var hosts []namecheapHost
+	found := false
+	for _, host := range resp.Hosts.Hosts {
+		if strings.EqualFold(host.Name, name) && strings.EqualFold(host.Type, "TXT") && host.Address == value {
+			found = true
+			if !add {
+				continue
+			}
+		}
+		hosts = append(hosts, host)
+	}
+	if found == add {
+		return nil
+	}
+	if add {
+		hosts = append(hosts, namecheapHost{Name: name, Type: "TXT", Address: value, TTL: strconv.Itoa(p.cfg.TTL)})
+	}
+
+	sld, tld, _ := strings.Cut(zone, ".")
+	params := url.Values{"SLD": {sld}, "TLD": {tld}}
+	if resp.Hosts.EmailType != "" {
+		params.Set("EmailType", resp.Hosts.EmailType)
+	}
+	for i, host := range hosts {
+		n := strconv.Itoa(i + 1)
+		params.Set("HostName"+n, host.Name)
+		params.Set("RecordType"+n, host.Type)
+		params.Set("Address"+n, host.Address)
+		if host.TTL != "" {
+			params.Set("TTL"+n, host.TTL)
+		}
+		if host.MXPref != "" {
+			params.Set("MXPref"+n, host.MXPref)
+		}
+	}
+	if _, err := p.call("namecheap.domains.dns.setHosts", params); err != nil {
+		return fmt.Errorf("更新域名 %s 的记录失败: %w", zone, err)
+	}
+	return nil
+}
+
+// call 以表单 POST 调用 API（setHosts 的参数可能超出 URL 长度限制）
+func (p *namecheapProvider) call(command string, params url.Values) (*namecheapResponse, error) {
+	params.Set("ApiUser", p.cfg.APIUser)
+	params.Set("ApiKey", p.cfg.APIKey)
+	params.Set("UserName", p.cfg.Username)
+	params.Set("ClientIp", p.cfg.ClientIP)
+	params.Set("Command", command)
+
+	httpResp, err := p.client.PostForm(p.cfg.APIURL, params)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("返回 %s", httpResp.Status)
+	}
+
+	var resp namecheapResponse
+	if err := xml.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		return nil, &namecheapError{number: resp.Errors[0].Number, message: strings.TrimSpace(resp.Errors[0].Message)}
+	}
+	if !strings.EqualFold(resp.Status, "OK") {
+		return nil, fmt.Errorf("返回状态 %s", resp.Status)
+	}
+	return &resp, nil
+}
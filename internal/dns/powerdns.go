@@ -0,0 +1,180 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// powerDNSProvider 通过 PowerDNS 权威服务器的 HTTP API（webserver=yes、api=yes）管理记录
+type powerDNSProvider struct {
+	api *apiClient
+	ttl int
+	mu  sync.Mutex // 读取后整体写回记录集，同名记录（泛域名和主域名）的并发修改需要串行
+}
+
+// pdnsZone PowerDNS 区域，列表接口不返回 rrsets
+type pdnsZone struct {
+	ID     string      `json:"id"`
+	Name   string      `json:"name"`
+	RRsets []pdnsRRset `json:"rrsets"`
+}
+
+type pdnsRRset struct {
+	Name       string       `json:"name"`
+	Type       string       `json:"type"`
+	TTL        int          `json:"ttl,omitempty"`
+	ChangeType string       `json:"changetype,omitempty"`
+	Records    []pdnsRecord `json:"records,omitempty"`
+}
+
+type pdnsRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+func newPowerDNS(cfg config.DNSConfig) (Provider, error) {
+	c := cfg.PowerDNS
+	if c.APIURL == "" || c.APIKey == "" {
+		return nil, errors.New("未配置 dns.powerdns.api_url 或 dns.powerdns.api_key")
+	}
+	serverID := c.ServerID
+	if serverID == "" {
+		serverID = "localhost"
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = 60
+	}
+	baseURL := strings.TrimSuffix(c.APIURL, "/") + "/api/v1/servers/" + url.PathEscape(serverID)
+	return &powerDNSProvider{
+		api: newAPIClient(baseURL, map[string]string{"X-API-Key": c.APIKey}),
+		ttl: ttl,
+	}, nil
+}
+
+func (p *powerDNSProvider) Name() string {
+	return "powerdns"
+}
+
+func (p *powerDNSProvider) Present(fqdn, value string) error {
+	return p.AddRecord(fqdn, "TXT", value, p.ttl)
+}
+
+func (p *powerDNSProvider) CleanUp(fqdn, value string) error {
+	return p.DeleteRecord(fqdn, "TXT", value)
+}
+
+func (p *powerDNSProvider) AddRecord(name, recordType, value string, ttl int) error {
+	if ttl <= 0 {
+		ttl = p.ttl
+	}
+	return p.modify(name, recordType, ttl, zoneValue(recordType, value), true)
+}
+
+func (p *powerDNSProvider) DeleteRecord(name, recordType, value string) error {
+	return p.modify(name, recordType, 0, zoneValue(recordType, value), false)
+}
+
+func (p *powerDNSProvider) Zone(fqdn string) (string, error) {
+	zone, err := p.findZone(fqdn)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(zone.Name, "."), nil
+}
+
+// HostNames 读取区域的全部记录集
+func (p *powerDNSProvider) HostNames(zone string) ([]string, error) {
+	found, err := p.findZone(zone)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(strings.TrimSuffix(found.Name, "."), zone) {
+		return nil, fmt.Errorf("PowerDNS 上没有区域 %s", zone)
+	}
+	var full pdnsZone
+	if _, err := p.api.do(http.MethodGet, "/zones/"+url.PathEscape(found.ID), nil, &full); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, rrset := range full.RRsets {
+		name := strings.TrimSuffix(strings.ToLower(rrset.Name), ".")
+		if isHostRecord(name, rrset.Type) {
+			seen[name] = true
+		}
+	}
+	return sortedNames(seen), nil
+}
+
+// findZone 记录所在的区域（服务器上区域名为记录名最长后缀的区域）
+func (p *powerDNSProvider) findZone(name string) (pdnsZone, error) {
+	var zones []pdnsZone
+	if _, err := p.api.do(http.MethodGet, "/zones", nil, &zones); err != nil {
+		return pdnsZone{}, fmt.Errorf("获取区域列表失败: %w", err)
+	}
+	names := make([]string, len(zones))
+	for i, zone := range zones {
+		names[i] = zone.Name
+	}
+	best := longestZone(name, names)
+	for _, zone := range zones {
+		if best != "" && strings.EqualFold(strings.TrimSuffix(zone.Name, "."), best) {
+			return zone, nil
+		}
+	}
+	return pdnsZone{}, fmt.Errorf("PowerDNS 上没有记录 %s 所在的区域", name)
+}
+
+// modify PowerDNS 以记录集为单位替换记录，需要先读取同名同类型的其他值（例如泛域名和主域名
+// 共用的 _acme-challenge 记录）再整体写回；记录集为空时删除
+func (p *powerDNSProvider) modify(name, recordType string, ttl int, content string, add bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zone, err := p.findZone(name)
+	if err != nil {
+		return err
+	}
+	path := "/zones/" + url.PathEscape(zone.ID)
+	var full pdnsZone
+	if _, err := p.api.do(http.MethodGet, path, nil, &full); err != nil {
+		return fmt.Errorf("获取区域 %s 的记录失败: %w", zone.Name, err)
+	}
+
+	owner := strings.TrimSuffix(strings.ToLower(name), ".") + "."
+	recordType = strings.ToUpper(recordType)
+	var contents []string
+	for _, rrset := range full.RRsets {
+		if strings.EqualFold(rrset.Name, owner) && rrset.Type == recordType {
+			for _, record := range rrset.Records {
+				contents = append(contents, record.Content)
+			}
+			if ttl <= 0 {
+				ttl = rrset.TTL
+			}
+		}
+	}
+	contents, changed := editValues(contents, content, add)
+	if !changed {
+		return nil
+	}
+
+	rrset := pdnsRRset{Name: owner, Type: recordType, TTL: ttl, ChangeType: "REPLACE"}
+	for _, c := range contents {
+		rrset.Records = append(rrset.Records, pdnsRecord{Content: c})
+	}
+	if len(contents) == 0 {
+		rrset = pdnsRRset{Name: owner, Type: recordType, ChangeType: "DELETE"}
+	}
+	body := map[string][]pdnsRRset{"rrsets": {rrset}}
+	if _, err := p.api.do(http.MethodPatch, path, body, nil); err != nil {
+		return fmt.Errorf("更新记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// waitForTXT 等待 TXT 记录在区域的所有权威服务器上生效。
+// 直接查询权威服务器，不受递归解析器缓存（包括否定缓存）的影响
+func waitForTXT(zone, fqdn, value string) error {
+	cfg := config.GetDNSConfig().Propagation
+	if cfg.Timeout <= 0 {
+		return nil
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	pending, err := nameservers(zone, cfg.Nameservers)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(cfg.Timeout)
+	for {
+		var missing []string
+		for _, server := range pending {
+			if !hasTXT(server, fqdn, value) {
+				missing = append(missing, server)
+			}
+		}
+		if len(missing) == 0 {
+			logger.Debug("TXT 记录已在权威服务器上生效", "record", fqdn, "zone", zone)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("记录在 %s 内未在权威服务器 %s 上生效", cfg.Timeout, strings.Join(missing, ", "))
+		}
+		pending = missing
+		time.Sleep(interval)
+	}
+}
+
+// nameservers 区域的权威服务器地址（host:53），配置了 dns.propagation.nameservers 时使用配置
+func nameservers(zone string, override []string) ([]string, error) {
+	var servers []string
+	if len(override) > 0 {
+		for _, server := range override {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+			servers = append(servers, server)
+		}
+		return servers, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	records, err := net.DefaultResolver.LookupNS(ctx, zone+".")
+	if err != nil {
+		return nil, fmt.Errorf("查询区域 %s 的 NS 记录失败: %w", zone, err)
+	}
+	for _, ns := range records {
+		servers = append(servers, net.JoinHostPort(strings.TrimSuffix(ns.Host, "."), "53"))
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("区域 %s 没有 NS 记录", zone)
+	}
+	return servers, nil
+}
+
+// hasTXT 权威服务器是否已返回该 TXT 记录值
+func hasTXT(server, fqdn, value string) bool {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, server)
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	values, err := resolver.LookupTXT(ctx, strings.TrimSuffix(fqdn, ".")+".")
+	if err != nil {
+		return false
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Provider DNS 服务商，DNS-01 验证时添加和删除 _acme-challenge TXT 记录
+type Provider interface {
+	Name() string
+
+	// Present 添加 TXT 记录，fqdn 为完整记录名（不带结尾的点）
+	Present(fqdn, value string) error
+
+	// CleanUp 删除 Present 添加的记录，同名的其他 TXT 记录保持不变
+	CleanUp(fqdn, value string) error
+}
+
+// RecordManager 支持添加和删除任意类型记录的 DNS 服务商（例如发布 DANE 的 TLSA 记录）
+type RecordManager interface {
+	// AddRecord 添加记录，同名同类型的其他记录保持不变
+	AddRecord(name, recordType, value string, ttl int) error
+
+	// DeleteRecord 删除指定值的记录
+	DeleteRecord(name, recordType, value string) error
+}
+
+// ZoneProvider 能确定记录所在区域的 DNS 服务商，添加验证记录后向该区域的权威服务器确认记录已生效
+type ZoneProvider interface {
+	// Zone 返回记录所在的区域名（不带结尾的点）
+	Zone(fqdn string) (string, error)
+}
+
+// ZoneLister 能列出区域中主机名的 DNS 服务商（wildcard 命令通过 API 读取子域名）
+type ZoneLister interface {
+	// HostNames 返回区域中 A、AAAA、CNAME 记录的主机名（完整域名，不带结尾的点），
+	// 忽略通配符和以下划线开头的服务记录
+	HostNames(zone string) ([]string, error)
+}
+
+// localZone 直接修改本机权威服务器区域数据的服务商（zonefile）：reload 成功后记录已由本机提供，
+// 不查询区域的 NS 等待生效（隐藏主服务器、内网区域的 NS 通常无法从本机访问或不指向本机）
+type localZone interface {
+	localZone()
+}
+
+// waitsForPropagation 添加记录后是否向区域的权威服务器确认记录已生效
+func waitsForPropagation(provider Provider) bool {
+	_, local := provider.(localZone)
+	return !local
+}
+
+// factories 已支持的 DNS 服务商
+var factories = map[string]func(config.DNSConfig) (Provider, error){
+	"baiducloud":  newBaiduCloud,
+	"desec":       newDeSEC,
+	"exec":        newExec,
+	"gandi":       newGandi,
+	"godaddy":     newGoDaddy,
+	"huaweicloud": newHuaweiCloud,
+	"namecheap":   newNamecheap,
+	"powerdns":    newPowerDNS,
+	"zonefile":    newZoneFile,
+}
+
+// Open 按配置创建 DNS 服务商，未配置 dns.provider 时返回 nil（手动添加记录）
+func Open() (Provider, error) {
+	cfg := config.GetDNSConfig()
+	if cfg.Provider == "" {
+		return nil, nil
+	}
+	return OpenProvider(cfg.Provider)
+}
+
+// OpenProvider 使用当前配置创建指定的 DNS 服务商
+func OpenProvider(name string) (Provider, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的 DNS 服务商 %q（支持: %s）", name, strings.Join(Providers(), ", "))
+	}
+	provider, err := factory(config.GetDNSConfig())
+	if err != nil {
+		return nil, fmt.Errorf("初始化 DNS 服务商 %s 失败: %w", name, err)
+	}
+	return provider, nil
+}
+
+// Providers 已支持的 DNS 服务商名称
+func Providers() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ZoneHostNames 通过配置的 DNS 服务商 API 读取区域中的主机名
+func ZoneHostNames(zone string) ([]string, error) {
+	provider, err := Open()
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, fmt.Errorf("未配置 dns.provider，无法通过 API 读取区域 %s", zone)
+	}
+	lister, ok := provider.(ZoneLister)
+	if !ok {
+		return nil, fmt.Errorf("DNS 服务商 %s 不支持列出区域中的记录（支持: desec, powerdns, zonefile）", provider.Name())
+	}
+	names, err := lister.HostNames(strings.TrimSuffix(strings.ToLower(zone), "."))
+	if err != nil {
+		return nil, fmt.Errorf("读取区域 %s 的记录失败: %w", zone, err)
+	}
+	return names, nil
+}
+
+// ChallengeRecord 域名的验证记录名，泛域名与基础域名使用同一记录
+func ChallengeRecord(domain string) string {
+	return "_acme-challenge." + strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+}
@@ -0,0 +1,32 @@
+package dns
+
+import (
+	"strings"
+)
+
+// multiLabelSuffixes 常见的多级公共后缀（注册域名在这些后缀下再多一级）
+var multiLabelSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "me.uk": true, "ltd.uk": true, "plc.uk": true, "ac.uk": true, "gov.uk": true,
+	"com.cn": true, "net.cn": true, "org.cn": true, "gov.cn": true, "edu.cn": true,
+	"com.au": true, "net.au": true, "org.au": true, "edu.au": true,
+	"co.jp": true, "ne.jp": true, "or.jp": true, "ac.jp": true,
+	"com.br": true, "net.br": true, "com.mx": true, "com.ar": true, "com.co": true,
+	"com.hk": true, "com.tw": true, "com.sg": true, "com.my": true, "co.id": true, "co.th": true,
+	"co.in": true, "net.in": true, "org.in": true, "co.kr": true, "or.kr": true,
+	"co.nz": true, "net.nz": true, "org.nz": true, "co.za": true, "com.tr": true,
+}
+
+// RegisteredDomain 域名的注册域名（公共后缀加一级），例如 www.example.co.uk 为 example.co.uk。
+// 公共后缀按常见的多级后缀判断，其他后缀视为一级
+func RegisteredDomain(domain string) string {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(domain, "*.")), "."), ".")
+	if len(labels) <= 2 {
+		return strings.Join(labels, ".")
+	}
+
+	suffix := strings.Join(labels[len(labels)-2:], ".")
+	if multiLabelSuffixes[suffix] {
+		return strings.Join(labels[len(labels)-3:], ".")
+	}
+	return suffix
+}
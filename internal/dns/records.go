@@ -0,0 +1,179 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Record 通过 DNS 服务商添加的验证记录。
+// 验证失败或进程中断时记录可能没有被删除，由 cleanup-dns 按记录中的服务商删除
+type Record struct {
+	Provider  string    `json:"provider"`
+	FQDN      string    `json:"fqdn"`
+	Value     string    `json:"value"`
+	Domain    string    `json:"domain"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// recordsMu 保护状态文件的读改写
+var recordsMu sync.Mutex
+
+func recordsPath() string {
+	return filepath.Join(config.GetConfigDir(), "dns-records.json")
+}
+
+// Present 记录并添加 TXT 记录；先写入状态文件，添加过程中进程中断时也能清理
+func Present(provider Provider, domain, fqdn, value string) error {
+	if err := track(provider, domain, fqdn, value); err != nil {
+		return err
+	}
+
+	if err := provider.Present(fqdn, value); err != nil {
+		return fmt.Errorf("添加 TXT 记录 %s 失败: %w", fqdn, err)
+	}
+	logger.Info("已添加 DNS TXT 记录", "record", fqdn, "provider", provider.Name())
+
+	if zoneProvider, ok := provider.(ZoneProvider); ok && waitsForPropagation(provider) {
+		zone, err := zoneProvider.Zone(fqdn)
+		if err == nil {
+			err = waitForTXT(zone, fqdn, value)
+		}
+		if err != nil {
+			return fmt.Errorf("等待 TXT 记录 %s 生效失败: %w", fqdn, err)
+		}
+	}
+	return nil
+}
+
+// CleanUp 删除 TXT 记录，成功后从状态文件中移除
+func CleanUp(provider Provider, fqdn, value string) error {
+	if err := provider.CleanUp(fqdn, value); err != nil {
+		return fmt.Errorf("删除 TXT 记录 %s 失败: %w", fqdn, err)
+	}
+	logger.Debug("已删除 DNS TXT 记录", "record", fqdn, "provider", provider.Name())
+	return untrack(fqdn, value)
+}
+
+// Records 返回状态文件中尚未删除的验证记录
+func Records() ([]Record, error) {
+	recordsMu.Lock()
+	defer recordsMu.Unlock()
+	return loadRecords()
+}
+
+// Cleanup 删除创建时间超过 maxAge 的验证记录，返回已删除（dryRun 时为将删除）的记录；
+// 删除失败的记录保留在状态文件中，下次继续清理
+func Cleanup(maxAge time.Duration, dryRun bool) ([]Record, error) {
+	records, err := Records()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	providers := make(map[string]Provider)
+	var removed []Record
+	var errs []error
+	for _, record := range records {
+		if record.CreatedAt.After(cutoff) {
+			continue
+		}
+		if dryRun {
+			removed = append(removed, record)
+			continue
+		}
+
+		provider, ok := providers[record.Provider]
+		if !ok {
+			provider, err = OpenProvider(record.Provider)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", record.FQDN, err))
+				continue
+			}
+			providers[record.Provider] = provider
+		}
+		if err := CleanUp(provider, record.FQDN, record.Value); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, record)
+	}
+	return removed, errors.Join(errs...)
+}
+
+func track(provider Provider, domain, fqdn, value string) error {
+	record := Record{Provider: provider.Name(), FQDN: fqdn, Value: value, Domain: domain, CreatedAt: time.Now()}
+	if err := updateRecords(func(records []Record) []Record {
+		return append(records, record)
+	}); err != nil {
+		return fmt.Errorf("保存 DNS 记录状态失败: %w", err)
+	}
+	return nil
+}
+
+func untrack(fqdn, value string) error {
+	return updateRecords(func(records []Record) []Record {
+		kept := records[:0]
+		for _, record := range records {
+			if record.FQDN != fqdn || record.Value != value {
+				kept = append(kept, record)
+			}
+		}
+		return kept
+	})
+}
+
+// updateRecords 读取、修改并写回状态文件
+func updateRecords(update func([]Record) []Record) error {
+	recordsMu.Lock()
+	defer recordsMu.Unlock()
+
+	records, err := loadRecords()
+	if err != nil {
+		return err
+	}
+	records = update(records)
+
+	path := recordsPath()
+	if len(records) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func loadRecords() ([]Record, error) {
+	data, err := os.ReadFile(recordsPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析 DNS 记录状态失败: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,408 @@
+package dns
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/system"
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// hostRecordTypes 表示主机的记录类型
+var hostRecordTypes = map[string]bool{"A": true, "AAAA": true, "CNAME": true}
+
+// ZoneNames 读取 BIND 格式区域文件中 A、AAAA、CNAME 记录的主机名（完整域名，不带结尾的点）。
+// origin 为区域名，文件中的 $ORIGIN 优先；忽略通配符和以下划线开头的服务记录
+func ZoneNames(path, origin string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取区域文件失败: %w", err)
+	}
+	defer file.Close()
+
+	origin = strings.TrimSuffix(strings.ToLower(origin), ".")
+	seen := make(map[string]bool)
+	var owner string
+	depth := 0 // 未闭合的括号层数
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+
+		// 括号跨行的记录（例如 SOA）只需要第一行中的名称和类型
+		if depth > 0 {
+			depth += strings.Count(line, "(") - strings.Count(line, ")")
+			continue
+		}
+		depth = strings.Count(line, "(") - strings.Count(line, ")")
+
+		fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(line))
+		if len(fields) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "$") {
+			if strings.EqualFold(fields[0], "$ORIGIN") && len(fields) > 1 {
+				origin = strings.TrimSuffix(strings.ToLower(fields[1]), ".")
+			}
+			continue
+		}
+
+		// 行首不是空白时第一个字段为记录名，否则沿用上一条记录的名称
+		if !unicode.IsSpace(rune(line[0])) {
+			name, err := absoluteName(fields[0], origin)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+			}
+			owner = name
+			fields = fields[1:]
+		}
+
+		if owner != "" && isHostRecord(owner, findRecordType(fields)) {
+			seen[owner] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sortedNames(seen), nil
+}
+
+// isHostRecord 记录是否表示一台主机：A、AAAA、CNAME 记录，不是通配符或以下划线开头的服务记录
+func isHostRecord(name, recordType string) bool {
+	return hostRecordTypes[strings.ToUpper(recordType)] && !strings.HasPrefix(name, "*.") && !strings.HasPrefix(name, "_")
+}
+
+// sortedNames 按字母顺序返回集合中的名称
+func sortedNames(seen map[string]bool) []string {
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// absoluteName 将记录名转换为完整域名：@ 为区域名，不以点结尾的名称相对于区域名
+func absoluteName(name, origin string) (string, error) {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".") {
+		return strings.TrimSuffix(name, "."), nil
+	}
+	if origin == "" {
+		return "", fmt.Errorf("相对名称 %q 需要指定区域名（$ORIGIN）", name)
+	}
+	if name == "@" {
+		return origin, nil
+	}
+	return name + "." + origin, nil
+}
+
+// findRecordType 跳过 TTL 和类别，返回记录类型
+func findRecordType(fields []string) string {
+	for _, field := range fields {
+		upper := strings.ToUpper(field)
+		switch {
+		case upper == "IN" || upper == "CH" || upper == "HS":
+			continue
+		case unicode.IsDigit(rune(field[0])):
+			continue
+		default:
+			return upper
+		}
+	}
+	return ""
+}
+
+// managedMarker 标记 autocert 写入的记录，删除时只处理带该标记的行
+const managedMarker = "; autocert"
+
+// zoneFileProvider 直接修改 BIND/NSD 区域文件，适用于自建 DNS
+type zoneFileProvider struct {
+	zones   []config.ZoneFileConfig
+	ttl     int
+	timeout time.Duration
+}
+
+func newZoneFile(cfg config.DNSConfig) (Provider, error) {
+	zoneCfg := cfg.ZoneFile
+	if len(zoneCfg.Zones) == 0 {
+		return nil, errors.New("未配置 dns.zonefile.zones")
+	}
+	for _, zone := range zoneCfg.Zones {
+		if zone.Origin == "" || zone.Path == "" {
+			return nil, errors.New("dns.zonefile.zones 的每一项都需要配置 origin 和 path")
+		}
+	}
+
+	provider := &zoneFileProvider{zones: zoneCfg.Zones, ttl: zoneCfg.TTL, timeout: zoneCfg.Timeout}
+	if provider.ttl <= 0 {
+		provider.ttl = 60
+	}
+	if provider.timeout <= 0 {
+		provider.timeout = 30 * time.Second
+	}
+	return provider, nil
+}
+
+func (p *zoneFileProvider) Name() string {
+	return "zonefile"
+}
+
+func (p *zoneFileProvider) Present(fqdn, value string) error {
+	return p.AddRecord(fqdn, "TXT", value, p.ttl)
+}
+
+func (p *zoneFileProvider) CleanUp(fqdn, value string) error {
+	return p.DeleteRecord(fqdn, "TXT", value)
+}
+
+func (p *zoneFileProvider) AddRecord(name, recordType, value string, ttl int) error {
+	if ttl <= 0 {
+		ttl = p.ttl
+	}
+	return p.update(name, func(lines []string) ([]string, bool) {
+		for _, line := range lines {
+			if isManagedRecord(line, name, recordType, value) {
+				return lines, false
+			}
+		}
+		record := fmt.Sprintf("%s. %d IN %s %s %s", strings.TrimSuffix(name, "."), ttl,
+			strings.ToUpper(recordType), zoneValue(recordType, value), managedMarker)
+		return append(lines, record), true
+	})
+}
+
+func (p *zoneFileProvider) DeleteRecord(name, recordType, value string) error {
+	return p.update(name, func(lines []string) ([]string, bool) {
+		kept := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if !isManagedRecord(line, name, recordType, value) {
+				kept = append(kept, line)
+			}
+		}
+		return kept, len(kept) != len(lines)
+	})
+}
+
+// localZone reload 后记录即由本机的 BIND/NSD 提供，不等待区域 NS 同步
+func (p *zoneFileProvider) localZone() {}
+
+func (p *zoneFileProvider) Zone(fqdn string) (string, error) {
+	zone, err := p.zoneFor(fqdn)
+	if err != nil {
+		return "", err
+	}
+	return zone.Origin, nil
+}
+
+// HostNames 读取 dns.zonefile.zones 中该区域的区域文件
+func (p *zoneFileProvider) HostNames(zone string) ([]string, error) {
+	cfg, err := p.zoneFor(zone)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Origin != zone {
+		return nil, fmt.Errorf("dns.zonefile.zones 中没有区域 %s", zone)
+	}
+	return ZoneNames(cfg.Path, cfg.Origin)
+}
+
+// zoneFor 记录所在的区域（区域名为记录名的最长后缀）
+func (p *zoneFileProvider) zoneFor(name string) (config.ZoneFileConfig, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	var best config.ZoneFileConfig
+	for _, zone := range p.zones {
+		origin := strings.TrimSuffix(strings.ToLower(zone.Origin), ".")
+		if (name == origin || strings.HasSuffix(name, "."+origin)) && len(origin) > len(best.Origin) {
+			best = zone
+			best.Origin = origin
+		}
+	}
+	if best.Path == "" {
+		return best, fmt.Errorf("记录 %s 不属于 dns.zonefile.zones 中的任何区域", name)
+	}
+	return best, nil
+}
+
+// update 锁定并修改区域文件，有变化时递增 SOA 序列号、原子写回并执行重载命令
+func (p *zoneFileProvider) update(name string, edit func(lines []string) ([]string, bool)) error {
+	zone, err := p.zoneFor(name)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockZone(zone.Path, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(zone.Path)
+	if err != nil {
+		return fmt.Errorf("读取区域文件失败: %w", err)
+	}
+	lines, changed := edit(strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"))
+	if !changed {
+		return nil
+	}
+
+	content, err := bumpSerial(strings.Join(lines, "\n") + "\n")
+	if err != nil {
+		return fmt.Errorf("%s: %w", zone.Path, err)
+	}
+	if err := writeZone(zone.Path, content); err != nil {
+		return fmt.Errorf("写入区域文件失败: %w", err)
+	}
+
+	if zone.Reload != "" {
+		if err := runShell(zone.Reload, p.timeout, []string{"AUTOCERT_DNS_ZONE=" + zone.Origin}); err != nil {
+			return fmt.Errorf("区域文件已更新，但重载 DNS 服务失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// isManagedRecord 该行是否为 autocert 写入的指定记录
+func isManagedRecord(line, name, recordType, value string) bool {
+	record, ok := strings.CutSuffix(strings.TrimSpace(line), managedMarker)
+	if !ok {
+		return false
+	}
+	fields := strings.Fields(record)
+	return len(fields) >= 5 &&
+		strings.EqualFold(fields[0], strings.TrimSuffix(name, ".")+".") &&
+		strings.EqualFold(fields[3], recordType) &&
+		strings.Join(fields[4:], " ") == zoneValue(recordType, value)
+}
+
+// zoneValue 记录值在区域文件中的写法，TXT 记录需要加引号
+func zoneValue(recordType, value string) string {
+	if strings.EqualFold(recordType, "TXT") {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+	}
+	return value
+}
+
+// bumpSerial 递增 SOA 序列号。日期格式（YYYYMMDDnn）的序列号不早于当天时加一，否则改为当天的 00；
+// 其他格式直接加一（按 RFC 1982 在 32 位范围内回绕）
+func bumpSerial(content string) (string, error) {
+	start, end, ok := soaSerial(content)
+	if !ok {
+		return "", errors.New("区域文件中没有 SOA 记录")
+	}
+	serial, err := strconv.ParseUint(content[start:end], 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("SOA 序列号 %q 无效", content[start:end])
+	}
+
+	next := (serial + 1) % (1 << 32)
+	if end-start == 10 && (strings.HasPrefix(content[start:end], "19") || strings.HasPrefix(content[start:end], "20")) {
+		today, _ := strconv.ParseUint(time.Now().Format("20060102")+"00", 10, 64)
+		if today > serial {
+			next = today
+		}
+	}
+	return content[:start] + strconv.FormatUint(next, 10) + content[end:], nil
+}
+
+// soaSerial 返回 SOA 记录中序列号的位置：SOA 之后依次为主服务器、管理员邮箱和序列号，
+// 跳过注释、括号和引号中的内容
+func soaSerial(content string) (int, int, bool) {
+	soa := -1
+	count := 0
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == ';':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '(' || c == ')' || unicode.IsSpace(rune(c)):
+			i++
+			continue
+		}
+
+		start := i
+		if c == '"' {
+			for i++; i < len(content) && content[i] != '"'; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		} else {
+			for i < len(content) && !strings.ContainsRune(" \t\r\n;()\"", rune(content[i])) {
+				i++
+			}
+		}
+		count++
+
+		if soa < 0 && strings.EqualFold(content[start:min(i, len(content))], "SOA") {
+			soa = count
+		} else if soa >= 0 && count == soa+3 {
+			return start, i, true
+		}
+	}
+	return 0, 0, false
+}
+
+// lockZone 通过锁文件避免多个进程（或并发的验证）同时修改同一区域文件
+func lockZone(path string, timeout time.Duration) (func(), error) {
+	lockPath := path + ".autocert-lock"
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("锁定区域文件失败: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("区域文件已被锁定（%s），没有其他 autocert 进程在运行时请删除该文件", lockPath)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// writeZone 写入临时文件后重命名，保留原文件的权限和所有者（DNS 服务通常以非 root 用户读取区域文件）
+func writeZone(path, content string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".autocert-tmp"
+	if err := os.WriteFile(tmpPath, []byte(content), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if uid, gid, err := system.FileOwner(path); err == nil {
+		if err := os.Chown(tmpPath, uid, gid); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+// Package domainlist 读取域名文件：每行一个或多个域名（逗号分隔）及可选的 challenge=、webroot= 选项，
+// # 开始的内容为注释。文件可以是 UTF-8（可带 BOM）或带 BOM 的 UTF-16（Windows PowerShell 重定向输出的默认编码）；
+// 国际化域名转换为 Punycode，中文输入法的全角逗号、顿号、句号和空格按半角处理
+package domainlist
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// 验证方式
+const (
+	ChallengeWebroot    = "webroot"
+	ChallengeStandalone = "standalone"
+	ChallengeDNS        = "dns"
+)
+
+// Entry 域名文件中的一行
+type Entry struct {
+	Line      int      // 行号，从 1 开始
+	Domains   []string // 域名（小写 ASCII，国际化域名已转换为 Punycode）
+	Challenge string   // 验证方式，为空时使用命令行参数；指定 webroot= 时为 webroot
+	Webroot   string   // Webroot 模式的网站根目录
+}
+
+// Problem 域名文件中的一处错误
+type Problem struct {
+	Line    int
+	Message string
+}
+
+// Error 域名文件解析失败，包含所有发现的错误
+type Error struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "域名文件 %s 有 %d 处错误:", e.File, len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  第 %d 行: %s", p.Line, p.Message)
+	}
+	return b.String()
+}
+
+// Load 读取域名文件，path 为 - 时从标准输入读取
+func Load(path string) ([]Entry, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取域名文件失败: %w", err)
+	}
+	return Parse(path, data)
+}
+
+// Parse 解析域名文件内容，name 用于错误信息
+func Parse(name string, data []byte) ([]Entry, error) {
+	text, err := decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("域名文件 %s: %w", name, err)
+	}
+
+	var entries []Entry
+	var problems []Problem
+	for i, line := range strings.Split(text, "\n") {
+		entry, errs := parseLine(line)
+		for _, err := range errs {
+			problems = append(problems, Problem{Line: i + 1, Message: err.Error()})
+		}
+		if len(errs) == 0 && len(entry.Domains) > 0 {
+			entry.Line = i + 1
+			entries = append(entries, entry)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &Error{File: name, Problems: problems}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("域名文件 %s 中没有域名", name)
+	}
+	return entries, nil
+}
+
+// Domains 按出现顺序返回所有域名（去除重复）
+func Domains(entries []Entry) []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		for _, domain := range entry.Domains {
+			if !seen[domain] {
+				seen[domain] = true
+				domains = append(domains, domain)
+			}
+		}
+	}
+	return domains
+}
+
+// decode 去除 UTF-8 BOM，将带 BOM 的 UTF-16 转换为 UTF-8；其他编码（例如 GBK）提示另存为 UTF-8
+func decode(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		var order binary.ByteOrder = binary.LittleEndian
+		if data[0] == 0xFE {
+			order = binary.BigEndian
+		}
+		data = data[2:]
+		if len(data)%2 != 0 {
+			return "", fmt.Errorf("UTF-16 文件长度无效")
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	}
+
+	if !utf8.Valid(data) {
+		line := 1
+		for len(data) > 0 {
+			r, size := utf8.DecodeRune(data)
+			if r == utf8.RuneError && size <= 1 {
+				break
+			}
+			if r == '\n' {
+				line++
+			}
+			data = data[size:]
+		}
+		return "", fmt.Errorf("第 %d 行不是有效的 UTF-8 文本，请将文件另存为 UTF-8 编码", line)
+	}
+	return string(data), nil
+}
+
+// fullWidth 域名中中文输入法常见的全角标点；选项的值（例如 webroot 路径）保持原样
+var fullWidth = strings.NewReplacer(
+	"，", ",", "、", ",", "。", ".", "．", ".", "｡", ".",
+)
+
+// isQuote 半角或全角双引号
+func isQuote(r rune) bool {
+	return r == '"' || r == '＂' || r == '“' || r == '”'
+}
+
+// cutOption 按第一个半角或全角等号拆分 key=value 选项
+func cutOption(token string) (string, string, bool) {
+	i := strings.IndexAny(token, "=＝")
+	if i < 0 {
+		return token, "", false
+	}
+	_, size := utf8.DecodeRuneInString(token[i:])
+	return token[:i], token[i+size:], true
+}
+
+// parseLine 解析一行：域名（逗号或空白分隔）、key=value 选项和 # 开始的注释
+func parseLine(line string) (Entry, []error) {
+	var entry Entry
+	var errs []error
+
+	for _, token := range tokenize(stripComment(line)) {
+		if key, value, ok := cutOption(token); ok {
+			if err := entry.setOption(strings.ToLower(strings.TrimSpace(key)), value); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		for _, d := range strings.Split(fullWidth.Replace(token), ",") {
+			if d = strings.TrimSpace(d); d == "" {
+				continue
+			}
+			domain, err := normalize(d)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("域名 %s 无效: %w", d, err))
+				continue
+			}
+			entry.Domains = append(entry.Domains, domain)
+		}
+	}
+
+	if len(entry.Domains) == 0 && (entry.Challenge != "" || entry.Webroot != "") {
+		errs = append(errs, fmt.Errorf("只有选项没有域名"))
+	}
+	if entry.Challenge != "" && entry.Challenge != ChallengeDNS {
+		for _, domain := range entry.Domains {
+			if strings.HasPrefix(domain, "*.") {
+				errs = append(errs, fmt.Errorf("泛域名 %s 只能使用 DNS 验证（challenge=dns）", domain))
+			}
+		}
+	}
+	return entry, errs
+}
+
+// setOption 设置一行的选项
+func (e *Entry) setOption(key, value string) error {
+	switch key {
+	case "challenge":
+		challenge := strings.ToLower(strings.TrimSpace(value))
+		switch challenge {
+		case ChallengeWebroot, ChallengeStandalone, ChallengeDNS:
+		case "dns-01":
+			challenge = ChallengeDNS
+		default:
+			return fmt.Errorf("不支持的验证方式 %q（可选 webroot、standalone、dns）", value)
+		}
+		if e.Webroot != "" && challenge != ChallengeWebroot {
+			return fmt.Errorf("webroot= 只能与 challenge=webroot 一起使用")
+		}
+		e.Challenge = challenge
+	case "webroot":
+		if value == "" {
+			return fmt.Errorf("webroot 不能为空")
+		}
+		if e.Challenge != "" && e.Challenge != ChallengeWebroot {
+			return fmt.Errorf("webroot= 只能与 challenge=webroot 一起使用")
+		}
+		e.Webroot = value
+		e.Challenge = ChallengeWebroot
+	default:
+		return fmt.Errorf("未知的选项 %s（可选 challenge、webroot）", key)
+	}
+	return nil
+}
+
+// stripComment 去除 # 或全角 ＃ 开始的注释（需位于行首或空白之后，引号内的不是注释）
+func stripComment(line string) string {
+	quoted := false
+	prev := ' '
+	for i, r := range line {
+		switch {
+		case isQuote(r):
+			quoted = !quoted
+		case (r == '#' || r == '＃') && !quoted && unicode.IsSpace(prev):
+			return line[:i]
+		}
+		prev = r
+	}
+	return line
+}
+
+// tokenize 按空白（包括全角空格）拆分，双引号内的空白不拆分（例如 webroot="C:\inetpub\my site"）
+func tokenize(line string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted, started := false, false
+	for _, r := range line {
+		switch {
+		case isQuote(r):
+			quoted = !quoted
+			started = true
+		case unicode.IsSpace(r) && !quoted:
+			if started {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		tokens = append(tokens, current.String())
+	}
+	return tokens
+}
+
+// normalize 转换为小写 ASCII 域名并检查格式
+func normalize(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	wildcard := strings.HasPrefix(domain, "*.")
+	ascii, err := toASCII(strings.TrimPrefix(domain, "*."))
+	if err != nil {
+		return "", err
+	}
+	if len(ascii) > 253 {
+		return "", fmt.Errorf("域名超过 253 个字符")
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("缺少顶级域名")
+	}
+	for _, label := range labels {
+		switch {
+		case label == "":
+			return "", fmt.Errorf("包含空标签")
+		case len(label) > 63:
+			return "", fmt.Errorf("标签 %s 超过 63 个字符", label)
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return "", fmt.Errorf("标签 %s 不能以连字符开头或结尾", label)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				if c == '*' {
+					return "", fmt.Errorf("通配符只能位于最左侧（*.example.com）")
+				}
+				return "", fmt.Errorf("包含无效字符 %q", c)
+			}
+		}
+	}
+
+	if wildcard {
+		return "*." + ascii, nil
+	}
+	return ascii, nil
+}
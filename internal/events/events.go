@@ -0,0 +1,130 @@
+package events
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Type 事件类型
+type Type string
+
+// 事件类型
+const (
+	CertIssued    Type = "certificate.issued"    // 首次签发证书
+	CertRenewed   Type = "certificate.renewed"   // 续期证书
+	CertFailed    Type = "certificate.failed"    // 签发或续期失败
+	CertCorrupted Type = "certificate.corrupted" // 证书文件损坏或与私钥不匹配
+	ConfigChanged Type = "config.changed"        // 配置（包括 Web 服务器配置）变更
+	HookFailed    Type = "hook.failed"           // 部署钩子执行失败
+)
+
+// Event 结构化事件
+type Event struct {
+	Time    time.Time         `json:"time"`
+	Type    Type              `json:"type"`
+	Host    string            `json:"host"`
+	Domains []string          `json:"domains,omitempty"`
+	Message string            `json:"message"`
+	Error   string            `json:"error,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// sink 事件转发目标
+type sink interface {
+	name() string
+	send(event Event) error
+}
+
+// Emit 将事件转发到所有已启用的目标（syslog、HTTP 收集器、MQTT、Redis、Kafka）
+// 转发失败只记录警告，不影响证书操作本身
+func Emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = clock.In(time.Now())
+	}
+	if event.Host == "" {
+		event.Host, _ = os.Hostname()
+	}
+
+	for _, s := range sinks(config.GetEventsConfig()) {
+		if err := s.send(event); err != nil {
+			logger.Warn("转发事件失败", "sink", s.name(), "type", event.Type, "error", err)
+		}
+	}
+}
+
+// sinks 根据配置创建转发目标
+func sinks(cfg config.EventsConfig) []sink {
+	var result []sink
+	if cfg.Syslog.Enabled {
+		result = append(result, &syslogSink{config: cfg.Syslog})
+	}
+	if cfg.HTTP.Enabled && cfg.HTTP.URL != "" {
+		result = append(result, &httpSink{config: cfg.HTTP})
+	}
+	if cfg.MQTT.Enabled {
+		result = append(result, &mqttSink{config: cfg.MQTT})
+	}
+	if cfg.Redis.Enabled {
+		result = append(result, &redisSink{config: cfg.Redis})
+	}
+	if cfg.Kafka.Enabled {
+		result = append(result, &kafkaSink{config: cfg.Kafka})
+	}
+	return result
+}
+
+// dial 连接消息队列服务器，useTLS 时使用系统信任的 CA 校验服务器证书
+func dial(address string, useTLS bool, timeout time.Duration) (net.Conn, error) {
+	if address == "" {
+		return nil, errors.New("未配置服务器地址")
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if useTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
+
+// expandTopic 替换主题或频道名中的 {type}
+func expandTopic(topic string, event Event) string {
+	return strings.ReplaceAll(topic, "{type}", string(event.Type))
+}
+
+// CertResult 根据证书操作结果生成事件
+// renewal 表示证书此前已存在（续期），err 不为空时为失败事件
+func CertResult(domains []string, renewal bool, err error) Event {
+	event := Event{Domains: domains}
+
+	switch {
+	case err != nil:
+		event.Type = CertFailed
+		event.Message = "证书签发失败"
+		if renewal {
+			event.Message = "证书续期失败"
+		}
+		event.Error = err.Error()
+	case renewal:
+		event.Type = CertRenewed
+		event.Message = "证书续期成功"
+	default:
+		event.Type = CertIssued
+		event.Message = "证书签发成功"
+	}
+
+	return event
+}
@@ -0,0 +1,46 @@
+package events
+
+import (
+	"autocert/internal/config"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// httpSink 以 JSON 格式 POST 事件到通用 HTTP 收集器
+type httpSink struct {
+	config config.HTTPCollectorConfig
+}
+
+func (s *httpSink) name() string {
+	return "http"
+}
+
+func (s *httpSink) send(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.Token)
+	}
+
+	client := &http.Client{Timeout: s.config.Timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("收集器返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+package events
+
+import (
+	"autocert/internal/config"
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Kafka 协议 API
+const (
+	kafkaProduce          = 0
+	kafkaMetadata         = 3
+	kafkaSASLHandshake    = 17
+	kafkaSASLAuthenticate = 36
+)
+
+// kafkaErrors 常见错误码
+var kafkaErrors = map[int16]string{
+	3:  "主题或分区不存在",
+	5:  "分区没有 leader（主题可能正在自动创建，稍后重试）",
+	6:  "该 broker 不是分区 leader",
+	7:  "请求超时",
+	10: "消息过大",
+	29: "没有写入主题的权限",
+	33: "不支持的 SASL 机制",
+	58: "SASL 认证失败",
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaSink 以 JSON 格式生产事件到 Kafka 主题。
+// 消息键为第一个域名，分区与 Java 客户端默认分区器相同，同一域名的事件保持顺序
+type kafkaSink struct {
+	config config.KafkaConfig
+}
+
+func (s *kafkaSink) name() string {
+	return "kafka"
+}
+
+func (s *kafkaSink) send(event Event) error {
+	if len(s.config.Brokers) == 0 {
+		return errors.New("未配置 Kafka brokers")
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	key := event.Host
+	if len(event.Domains) > 0 {
+		key = event.Domains[0]
+	}
+
+	// 依次尝试引导服务器，查询主题元数据
+	var conn *kafkaConn
+	var meta *kafkaTopicMetadata
+	for _, broker := range s.config.Brokers {
+		conn, err = s.connect(broker)
+		if err == nil {
+			meta, err = conn.metadata(s.config.Topic)
+			if err == nil {
+				break
+			}
+			conn.Close()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { conn.Close() }()
+
+	partition := meta.partitions[int(murmur2([]byte(key))&0x7fffffff)%len(meta.partitions)]
+	leader, ok := meta.brokers[partition.leader]
+	if !ok {
+		return fmt.Errorf("分区 %d 没有可用的 leader", partition.id)
+	}
+	if leader != conn.address {
+		leaderConn, err := s.connect(leader)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		conn = leaderConn
+	}
+
+	return conn.produce(s.config.Topic, partition.id, int32(s.config.Timeout/time.Millisecond),
+		kafkaRecordBatch([]byte(key), value, event.Time))
+}
+
+// connect 连接 broker，配置了用户名时进行 SASL/PLAIN 认证
+func (s *kafkaSink) connect(address string) (*kafkaConn, error) {
+	netConn, err := dial(address, s.config.TLS, s.config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &kafkaConn{conn: netConn, r: bufio.NewReader(netConn), address: address, clientID: s.config.ClientID}
+
+	if s.config.Username != "" {
+		if err := conn.authenticate(s.config.Username, s.config.Password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// kafkaConn 到一个 broker 的连接
+type kafkaConn struct {
+	conn        net.Conn
+	r           *bufio.Reader
+	address     string
+	clientID    string
+	correlation int32
+}
+
+func (c *kafkaConn) Close() error {
+	return c.conn.Close()
+}
+
+// request 发送请求（请求头 v1）并返回去掉关联 ID 的响应
+func (c *kafkaConn) request(apiKey, version int16, body []byte) (*kafkaReader, error) {
+	c.correlation++
+	var msg []byte
+	msg = binary.BigEndian.AppendUint16(msg, uint16(apiKey))
+	msg = binary.BigEndian.AppendUint16(msg, uint16(version))
+	msg = binary.BigEndian.AppendUint32(msg, uint32(c.correlation))
+	msg = kafkaString(msg, c.clientID)
+	msg = append(msg, body...)
+
+	if _, err := c.conn.Write(append(binary.BigEndian.AppendUint32(nil, uint32(len(msg))), msg...)); err != nil {
+		return nil, err
+	}
+
+	var size int32
+	if err := binary.Read(c.r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 4 || size > 16<<20 {
+		return nil, fmt.Errorf("响应长度错误: %d", size)
+	}
+	resp := make([]byte, size)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	if int32(binary.BigEndian.Uint32(resp)) != c.correlation {
+		return nil, errors.New("响应的关联 ID 不匹配")
+	}
+	return &kafkaReader{data: resp[4:]}, nil
+}
+
+// authenticate SASL/PLAIN 认证（SaslHandshake v1 + SaslAuthenticate v0，Kafka 1.0 及以上）
+func (c *kafkaConn) authenticate(username, password string) error {
+	resp, err := c.request(kafkaSASLHandshake, 1, kafkaString(nil, "PLAIN"))
+	if err != nil {
+		return fmt.Errorf("SASL 握手失败: %w", err)
+	}
+	if err := kafkaError(resp.int16()); err != nil {
+		return fmt.Errorf("SASL 握手失败: %w", err)
+	}
+
+	token := []byte("\x00" + username + "\x00" + password)
+	body := binary.BigEndian.AppendUint32(nil, uint32(len(token)))
+	resp, err = c.request(kafkaSASLAuthenticate, 0, append(body, token...))
+	if err != nil {
+		return fmt.Errorf("SASL 认证失败: %w", err)
+	}
+	if code := resp.int16(); code != 0 {
+		if msg := resp.nullableString(); msg != "" {
+			return fmt.Errorf("SASL 认证失败: %s", msg)
+		}
+		return fmt.Errorf("SASL 认证失败: %w", kafkaError(code))
+	}
+	return resp.err
+}
+
+// kafkaTopicMetadata 主题的分区和 broker 地址
+type kafkaTopicMetadata struct {
+	brokers    map[int32]string
+	partitions []kafkaPartition
+}
+
+type kafkaPartition struct {
+	id     int32
+	leader int32
+}
+
+// metadata 查询主题元数据（Metadata v1）
+func (c *kafkaConn) metadata(topic string) (*kafkaTopicMetadata, error) {
+	body := binary.BigEndian.AppendUint32(nil, 1)
+	resp, err := c.request(kafkaMetadata, 1, kafkaString(body, topic))
+	if err != nil {
+		return nil, fmt.Errorf("查询主题元数据失败: %w", err)
+	}
+
+	meta := &kafkaTopicMetadata{brokers: make(map[int32]string)}
+	for i := resp.int32(); i > 0 && resp.err == nil; i-- {
+		node := resp.int32()
+		host := resp.string()
+		port := resp.int32()
+		resp.nullableString() // rack
+		meta.brokers[node] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	resp.int32() // controller_id
+
+	for i := resp.int32(); i > 0 && resp.err == nil; i-- {
+		code := resp.int16()
+		name := resp.string()
+		resp.int8() // is_internal
+		var partitions []kafkaPartition
+		for j := resp.int32(); j > 0 && resp.err == nil; j-- {
+			resp.int16() // 分区错误码，leader 不可用时 leader 为 -1
+			partition := kafkaPartition{id: resp.int32(), leader: resp.int32()}
+			resp.int32Array() // replicas
+			resp.int32Array() // isr
+			partitions = append(partitions, partition)
+		}
+		if name != topic {
+			continue
+		}
+		if err := kafkaError(code); err != nil {
+			return nil, fmt.Errorf("主题 %s: %w", topic, err)
+		}
+		// 按分区编号排列，与其他客户端的分区选择一致
+		meta.partitions = make([]kafkaPartition, len(partitions))
+		for _, partition := range partitions {
+			if partition.id < 0 || int(partition.id) >= len(partitions) {
+				return nil, fmt.Errorf("主题 %s 的分区编号不连续", topic)
+			}
+			meta.partitions[partition.id] = partition
+		}
+	}
+	if resp.err != nil {
+		return nil, fmt.Errorf("解析主题元数据失败: %w", resp.err)
+	}
+	if len(meta.partitions) == 0 {
+		return nil, fmt.Errorf("主题 %s 不存在", topic)
+	}
+	return meta, nil
+}
+
+// produce 写入一个记录批次（Produce v3，acks=1 等待 leader 确认）
+func (c *kafkaConn) produce(topic string, partition, timeoutMs int32, batch []byte) error {
+	body := binary.BigEndian.AppendUint16(nil, 0xffff) // transactional_id = null
+	body = binary.BigEndian.AppendUint16(body, 1)      // acks
+	body = binary.BigEndian.AppendUint32(body, uint32(timeoutMs))
+	body = binary.BigEndian.AppendUint32(body, 1)
+	body = kafkaString(body, topic)
+	body = binary.BigEndian.AppendUint32(body, 1)
+	body = binary.BigEndian.AppendUint32(body, uint32(partition))
+	body = binary.BigEndian.AppendUint32(body, uint32(len(batch)))
+	body = append(body, batch...)
+
+	resp, err := c.request(kafkaProduce, 3, body)
+	if err != nil {
+		return fmt.Errorf("写入消息失败: %w", err)
+	}
+	for i := resp.int32(); i > 0 && resp.err == nil; i-- {
+		resp.string()
+		for j := resp.int32(); j > 0 && resp.err == nil; j-- {
+			resp.int32()
+			code := resp.int16()
+			resp.int64() // base_offset
+			resp.int64() // log_append_time
+			if err := kafkaError(code); err != nil {
+				return fmt.Errorf("写入消息失败: %w", err)
+			}
+		}
+	}
+	return resp.err
+}
+
+// kafkaRecordBatch 只包含一条记录的 RecordBatch（magic 2，不压缩）
+func kafkaRecordBatch(key, value []byte, timestamp time.Time) []byte {
+	var record []byte
+	record = append(record, 0)              // attributes
+	record = binary.AppendVarint(record, 0) // timestamp delta
+	record = binary.AppendVarint(record, 0) // offset delta
+	record = binary.AppendVarint(record, int64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendVarint(record, int64(len(value)))
+	record = append(record, value...)
+	record = binary.AppendVarint(record, 0) // headers
+
+	// CRC 覆盖 attributes 之后的全部内容
+	ms := uint64(timestamp.UnixMilli())
+	var tail []byte
+	tail = binary.BigEndian.AppendUint16(tail, 0)          // attributes
+	tail = binary.BigEndian.AppendUint32(tail, 0)          // last offset delta
+	tail = binary.BigEndian.AppendUint64(tail, ms)         // first timestamp
+	tail = binary.BigEndian.AppendUint64(tail, ms)         // max timestamp
+	tail = binary.BigEndian.AppendUint64(tail, ^uint64(0)) // producer id = -1
+	tail = binary.BigEndian.AppendUint16(tail, 0xffff)     // producer epoch = -1
+	tail = binary.BigEndian.AppendUint32(tail, 0xffffffff) // base sequence = -1
+	tail = binary.BigEndian.AppendUint32(tail, 1)
+	tail = binary.AppendVarint(tail, int64(len(record)))
+	tail = append(tail, record...)
+
+	var batch []byte
+	batch = binary.BigEndian.AppendUint64(batch, 0) // base offset
+	batch = binary.BigEndian.AppendUint32(batch, uint32(4+1+4+len(tail)))
+	batch = binary.BigEndian.AppendUint32(batch, 0xffffffff) // partition leader epoch = -1
+	batch = append(batch, 2)                                 // magic
+	batch = binary.BigEndian.AppendUint32(batch, crc32.Checksum(tail, castagnoli))
+	return append(batch, tail...)
+}
+
+// murmur2 Kafka 默认分区器使用的 32 位 MurmurHash2
+func murmur2(data []byte) int32 {
+	const m = 0x5bd1e995
+	h := uint32(0x9747b28c) ^ uint32(len(data))
+
+	n := len(data) &^ 3
+	for i := 0; i < n; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> 24
+		k *= m
+		h *= m
+		h ^= k
+	}
+
+	switch len(data) & 3 {
+	case 3:
+		h ^= uint32(data[n+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[n+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[n])
+		h *= m
+	}
+
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+func kafkaError(code int16) error {
+	if code == 0 {
+		return nil
+	}
+	if msg, ok := kafkaErrors[code]; ok {
+		return fmt.Errorf("%s（错误码 %d）", msg, code)
+	}
+	return fmt.Errorf("错误码 %d", code)
+}
+
+// kafkaString 追加带两字节长度前缀的字符串
+func kafkaString(buf []byte, value string) []byte {
+	return append(binary.BigEndian.AppendUint16(buf, uint16(len(value))), value...)
+}
+
+// kafkaReader 顺序读取响应字段，出错后后续读取都返回零值
+type kafkaReader struct {
+	data []byte
+	err  error
+}
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *kafkaReader) int8() int8 {
+	if b := r.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (r *kafkaReader) int16() int16 {
+	if b := r.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int32() int32 {
+	if b := r.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int64() int64 {
+	if b := r.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) string() string {
+	return string(r.next(int(r.int16())))
+}
+
+func (r *kafkaReader) nullableString() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func (r *kafkaReader) int32Array() {
+	for i := r.int32(); i > 0 && r.err == nil; i-- {
+		r.int32()
+	}
+}
@@ -0,0 +1,173 @@
+package events
+
+import (
+	"autocert/internal/config"
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 控制报文类型
+const (
+	mqttConnect    = 0x10
+	mqttConnack    = 0x20
+	mqttPublish    = 0x30
+	mqttPuback     = 0x40
+	mqttDisconnect = 0xe0
+)
+
+// mqttConnackErrors CONNACK 返回码（MQTT 3.1.1 第 3.2.2.3 节）
+var mqttConnackErrors = map[byte]string{
+	1: "不支持的协议版本",
+	2: "客户端标识被拒绝",
+	3: "服务不可用",
+	4: "用户名或密码错误",
+	5: "未授权",
+}
+
+// mqttSink 以 JSON 格式发布事件到 MQTT 主题（QoS 0 或 1）
+type mqttSink struct {
+	config config.MQTTConfig
+}
+
+func (s *mqttSink) name() string {
+	return "mqtt"
+}
+
+func (s *mqttSink) send(event Event) error {
+	if s.config.QoS != 0 && s.config.QoS != 1 {
+		return fmt.Errorf("不支持的 MQTT QoS: %d（支持 0 和 1）", s.config.QoS)
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dial(s.config.Broker, s.config.TLS, s.config.Timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	if _, err := conn.Write(s.connectPacket()); err != nil {
+		return err
+	}
+	packetType, body, err := readMQTTPacket(r)
+	if err != nil {
+		return fmt.Errorf("读取 CONNACK 失败: %w", err)
+	}
+	if packetType != mqttConnack || len(body) != 2 {
+		return fmt.Errorf("服务器返回了意外的报文: 0x%02x", packetType)
+	}
+	if code := body[1]; code != 0 {
+		if msg, ok := mqttConnackErrors[code]; ok {
+			return fmt.Errorf("连接被拒绝: %s", msg)
+		}
+		return fmt.Errorf("连接被拒绝: 返回码 %d", code)
+	}
+
+	// 每个连接只发布一条消息，报文标识固定为 1
+	header := byte(mqttPublish) | byte(s.config.QoS<<1)
+	if s.config.Retain {
+		header |= 0x01
+	}
+	variable := mqttString(expandTopic(s.config.Topic, event))
+	if s.config.QoS == 1 {
+		variable = binary.BigEndian.AppendUint16(variable, 1)
+	}
+	if _, err := conn.Write(mqttPacket(header, append(variable, payload...))); err != nil {
+		return err
+	}
+
+	if s.config.QoS == 1 {
+		packetType, body, err := readMQTTPacket(r)
+		if err != nil {
+			return fmt.Errorf("读取 PUBACK 失败: %w", err)
+		}
+		if packetType != mqttPuback || len(body) != 2 || binary.BigEndian.Uint16(body) != 1 {
+			return fmt.Errorf("服务器返回了意外的报文: 0x%02x", packetType)
+		}
+	}
+
+	_, err = conn.Write([]byte{mqttDisconnect, 0})
+	return err
+}
+
+// connectPacket CONNECT 报文（清除会话，保活 60 秒）
+func (s *mqttSink) connectPacket() []byte {
+	flags := byte(0x02)
+	if s.config.Username != "" {
+		flags |= 0x80
+	}
+	if s.config.Password != "" {
+		flags |= 0x40
+	}
+
+	body := mqttString("MQTT")
+	body = append(body, 4, flags, 0, 60)
+	body = append(body, mqttString(s.config.ClientID)...)
+	if s.config.Username != "" {
+		body = append(body, mqttString(s.config.Username)...)
+	}
+	if s.config.Password != "" {
+		body = append(body, mqttString(s.config.Password)...)
+	}
+	return mqttPacket(mqttConnect, body)
+}
+
+// mqttPacket 固定报头（剩余长度使用变长编码）加报文内容
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+// mqttString 带两字节长度前缀的 UTF-8 字符串
+func mqttString(value string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(len(value))), value...)
+}
+
+// readMQTTPacket 读取一个报文，返回报文类型（高 4 位）和剩余内容
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("剩余长度编码错误")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header & 0xf0, body, nil
+}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/redis"
+	"encoding/json"
+	"fmt"
+)
+
+// redisSink 以 JSON 格式 PUBLISH 事件到 Redis 频道
+type redisSink struct {
+	config config.RedisConfig
+}
+
+func (s *redisSink) name() string {
+	return "redis"
+}
+
+func (s *redisSink) send(event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client, err := redis.NewClient(s.config)
+	if err != nil {
+		return err
+	}
+	// 返回值为收到消息的订阅者数量，没有订阅者不视为失败
+	if _, err := client.Do("PUBLISH", expandTopic(s.config.Channel, event), string(payload)); err != nil {
+		return fmt.Errorf("发布失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package events
+
+import (
+	"autocert/internal/config"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// facilities syslog facility 编号
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// 本机 syslog 套接字
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// sdID 结构化数据 ID（RFC5424 第 6.3.2 节，@ 后为私有企业编号）
+const sdID = "autocert@32473"
+
+// syslogSink 以 RFC5424 格式发送事件到本机或远程 syslog
+type syslogSink struct {
+	config config.SyslogConfig
+}
+
+func (s *syslogSink) name() string {
+	return "syslog"
+}
+
+func (s *syslogSink) send(event Event) error {
+	facility, ok := facilities[strings.ToLower(s.config.Facility)]
+	if !ok {
+		return fmt.Errorf("未知的 syslog facility: %s", s.config.Facility)
+	}
+
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+
+	msg := formatRFC5424(facility, s.appName(), event)
+	if s.config.Network == "tcp" {
+		// TCP 使用 octet-counting 分帧（RFC6587）
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	_, err = conn.Write([]byte(msg))
+	return err
+}
+
+// dial 连接 syslog，未配置网络类型时连接本机 syslog 套接字
+func (s *syslogSink) dial() (net.Conn, error) {
+	if s.config.Network != "" {
+		if s.config.Address == "" {
+			return nil, fmt.Errorf("未配置 syslog 地址")
+		}
+		return net.DialTimeout(s.config.Network, s.config.Address, 5*time.Second)
+	}
+
+	for _, path := range localSyslogSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("未找到本机 syslog 服务，请配置远程地址")
+}
+
+func (s *syslogSink) appName() string {
+	if s.config.AppName != "" {
+		return s.config.AppName
+	}
+	return "autocert"
+}
+
+// formatRFC5424 格式化 RFC5424 syslog 消息
+func formatRFC5424(facility int, appName string, event Event) string {
+	severity := 5 // notice
+	if event.Type == CertFailed {
+		severity = 3 // err
+	}
+
+	host := event.Host
+	if host == "" {
+		host = "-"
+	}
+
+	params := []string{fmt.Sprintf(`type="%s"`, sdEscape(string(event.Type)))}
+	if len(event.Domains) > 0 {
+		params = append(params, fmt.Sprintf(`domains="%s"`, sdEscape(strings.Join(event.Domains, ","))))
+	}
+	if event.Error != "" {
+		params = append(params, fmt.Sprintf(`error="%s"`, sdEscape(event.Error)))
+	}
+	keys := make([]string, 0, len(event.Fields))
+	for key := range event.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := sdName(key)
+		if name == "" {
+			continue
+		}
+		params = append(params, fmt.Sprintf(`%s="%s"`, name, sdEscape(event.Fields[key])))
+	}
+
+	// RFC 5424 时间戳最多 6 位小数；MSG 为 UTF-8 时以 BOM 开头
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s [%s %s] \uFEFF%s",
+		facility*8+severity,
+		event.Time.Format(rfc5424Time),
+		host,
+		appName,
+		os.Getpid(),
+		event.Type,
+		sdID,
+		strings.Join(params, " "),
+		event.Message,
+	)
+}
+
+// rfc5424Time RFC 5424 的 TIMESTAMP 格式（TIME-SECFRAC 最多 6 位）
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+// sdName 将字段名转换为合法的 SD-PARAM 名称：1~32 个可打印 ASCII 字符，不含 =、空格、] 和 "，
+// 其他字符替换为 _，转换后为空时返回空字符串（丢弃该字段）
+func sdName(key string) string {
+	var b strings.Builder
+	for _, c := range key {
+		if b.Len() == 32 {
+			break
+		}
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		b.WriteRune(c)
+	}
+	if strings.Trim(b.String(), "_") == "" {
+		return ""
+	}
+	return b.String()
+}
+
+// sdEscape 转义结构化数据参数值中的 "、\ 和 ]
+func sdEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
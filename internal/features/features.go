@@ -0,0 +1,95 @@
+// Package features 实验性功能开关。较大的新功能先以实验性功能发布，默认关闭，
+// 在配置的 features 中按主机开启，便于逐步推广。
+package features
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"fmt"
+	"sync"
+)
+
+// 实验性功能名称，与配置 features 中的键一致
+const (
+	TLSALPN   = "tls_alpn"
+	ARI       = "ari"
+	DualCert  = "dual_cert"
+	DaemonAPI = "daemon_api"
+	OnDemand  = "on_demand"
+)
+
+// Feature 实验性功能
+type Feature struct {
+	Name        string
+	Description string
+	Available   bool // 当前版本是否已提供该功能，未提供时开启无效
+	Enabled     bool
+}
+
+// feature 功能定义，enabled 从配置中读取开关
+type feature struct {
+	name        string
+	description string
+	available   bool
+	enabled     func(config.FeaturesConfig) bool
+}
+
+var registry = []feature{
+	{TLSALPN, "tls-alpn-01 验证（在 443 端口完成验证，无需开放 80 端口）", false,
+		func(c config.FeaturesConfig) bool { return c.TLSALPN }},
+	{ARI, "ACME 续期信息（ARI），按 CA 建议的时间续期", false,
+		func(c config.FeaturesConfig) bool { return c.ARI }},
+	{DualCert, "同时签发 RSA 和 ECDSA 证书（acme.dual_cert、install --dual-cert）", true,
+		func(c config.FeaturesConfig) bool { return c.DualCert }},
+	{DaemonAPI, "守护进程的 /api 管理接口（需要 daemon.webhook）", true,
+		func(c config.FeaturesConfig) bool { return c.DaemonAPI }},
+	{OnDemand, "按需签发：监视域名队列自动签发（daemon.on_demand）", true,
+		func(c config.FeaturesConfig) bool { return c.OnDemand }},
+}
+
+// List 列出所有实验性功能及其状态
+func List() []Feature {
+	cfg := config.GetFeaturesConfig()
+	features := make([]Feature, 0, len(registry))
+	for _, f := range registry {
+		features = append(features, Feature{
+			Name:        f.name,
+			Description: f.description,
+			Available:   f.available,
+			Enabled:     f.available && f.enabled(cfg),
+		})
+	}
+	return features
+}
+
+// Enabled 功能是否已开启（当前版本未提供的功能总是返回 false）
+func Enabled(name string) bool {
+	for _, f := range registry {
+		if f.name == name {
+			return f.available && f.enabled(config.GetFeaturesConfig())
+		}
+	}
+	return false
+}
+
+// Require 功能未开启时返回错误，setting 为使用该功能的配置项或参数
+func Require(name, setting string) error {
+	if Enabled(name) {
+		return nil
+	}
+	return fmt.Errorf("%s 是实验性功能，需要在配置中开启 features.%s: true", setting, name)
+}
+
+// warned 已提示过的功能，每个进程只提示一次
+var warned sync.Map
+
+// Gate 配置请求使用某个功能时调用：已开启时返回 true，未开启时提示一次并返回 false
+func Gate(name, setting string) bool {
+	if Enabled(name) {
+		return true
+	}
+	if _, loaded := warned.LoadOrStore(name, true); !loaded {
+		logger.Warn("实验性功能未开启，忽略该配置", "setting", setting, "feature", "features."+name)
+	}
+	return false
+}
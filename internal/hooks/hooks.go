@@ -0,0 +1,293 @@
+package hooks
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/events"
+	"autocert/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 失败策略
+const (
+	FailureAbort    = "abort"    // 停止尚未开始的钩子，安装失败
+	FailureContinue = "continue" // 记录失败，继续执行不依赖它的钩子
+)
+
+// 钩子执行状态
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+const (
+	DefaultTimeout    = 5 * time.Minute // 未配置 timeout 时的单次执行超时
+	defaultRetryDelay = 5 * time.Second
+)
+
+// Env 传给钩子命令的证书信息和执行环境
+type Env struct {
+	Domains   []string
+	CertDir   string
+	CertPath  string
+	KeyPath   string
+	ChainPath string
+	PFXPath   string   // 配置了 pfx.enabled 时的 PFX 证书包，密码通过 autocert secret get pfx:<域名> 读取
+	WorkDir   string   // 钩子命令的工作目录
+	Extra     []string // 额外的环境变量，格式 KEY=VALUE
+}
+
+// Result 单个钩子的执行结果
+type Result struct {
+	Name     string
+	Status   string
+	Attempts int
+	Duration time.Duration
+	Output   string
+	Err      error
+}
+
+// Plan 校验钩子配置并按依赖关系分层：同一层的钩子互不依赖，可以并行执行
+func Plan(hooks []config.HookConfig) ([][]config.HookConfig, error) {
+	byName := make(map[string]config.HookConfig, len(hooks))
+	for _, hook := range hooks {
+		if hook.Name == "" {
+			return nil, fmt.Errorf("钩子缺少 name: %q", hook.Command)
+		}
+		if _, ok := byName[hook.Name]; ok {
+			return nil, fmt.Errorf("钩子名称重复: %s", hook.Name)
+		}
+		if strings.TrimSpace(hook.Command) == "" {
+			return nil, fmt.Errorf("钩子 %s 缺少 command", hook.Name)
+		}
+		switch hook.OnFailure {
+		case "", FailureAbort, FailureContinue:
+		default:
+			return nil, fmt.Errorf("钩子 %s 的 on_failure 无效: %s（可选 abort、continue）", hook.Name, hook.OnFailure)
+		}
+		byName[hook.Name] = hook
+	}
+
+	// 拓扑排序（Kahn 算法），保持配置中的先后顺序
+	pending := make(map[string]int, len(hooks))
+	for _, hook := range hooks {
+		for _, dep := range hook.After {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("钩子 %s 依赖的钩子不存在: %s", hook.Name, dep)
+			}
+		}
+		pending[hook.Name] = len(hook.After)
+	}
+
+	var levels [][]config.HookConfig
+	done := make(map[string]bool, len(hooks))
+	for len(done) < len(hooks) {
+		var level []config.HookConfig
+		for _, hook := range hooks {
+			if !done[hook.Name] && pending[hook.Name] == 0 {
+				level = append(level, hook)
+			}
+		}
+		if len(level) == 0 {
+			var cycle []string
+			for _, hook := range hooks {
+				if !done[hook.Name] {
+					cycle = append(cycle, hook.Name)
+				}
+			}
+			return nil, fmt.Errorf("钩子之间存在循环依赖: %s", strings.Join(cycle, ", "))
+		}
+
+		for _, hook := range level {
+			done[hook.Name] = true
+		}
+		for _, hook := range hooks {
+			for _, dep := range hook.After {
+				for _, finished := range level {
+					if dep == finished.Name {
+						pending[hook.Name]--
+					}
+				}
+			}
+		}
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+// Run 按依赖关系执行钩子：依赖未成功的钩子跳过；
+// on_failure 为 abort 的钩子失败时，当前层执行完后不再启动后续钩子并返回错误
+func Run(hooks []config.HookConfig, env Env) ([]Result, error) {
+	levels, err := Plan(hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make(map[string]string, len(hooks))
+	var results []Result
+	var abortErrs []error
+
+	for _, level := range levels {
+		levelResults := make([]Result, len(level))
+		var wg sync.WaitGroup
+
+		for i, hook := range level {
+			if len(abortErrs) > 0 {
+				levelResults[i] = Result{Name: hook.Name, Status: StatusSkipped, Err: errors.New("前序钩子失败，已中止")}
+				continue
+			}
+			if dep := failedDependency(hook, status); dep != "" {
+				levelResults[i] = Result{Name: hook.Name, Status: StatusSkipped, Err: fmt.Errorf("依赖的钩子 %s 未成功", dep)}
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, hook config.HookConfig) {
+				defer wg.Done()
+				levelResults[i] = runHook(hook, env)
+			}(i, hook)
+		}
+		wg.Wait()
+
+		for i, result := range levelResults {
+			hook := level[i]
+			status[hook.Name] = result.Status
+			results = append(results, result)
+
+			switch result.Status {
+			case StatusSkipped:
+				logger.Warn("跳过部署钩子", "hook", hook.Name, "reason", result.Err)
+			case StatusFailed:
+				logger.Error("部署钩子执行失败", "hook", hook.Name, "attempts", result.Attempts, "error", result.Err)
+				events.Emit(events.Event{
+					Type:    events.HookFailed,
+					Domains: env.Domains,
+					Message: "部署钩子执行失败",
+					Error:   result.Err.Error(),
+					Fields:  map[string]string{"hook": hook.Name, "on_failure": OnFailure(hook)},
+				})
+				if OnFailure(hook) == FailureAbort {
+					abortErrs = append(abortErrs, fmt.Errorf("钩子 %s: %w", hook.Name, result.Err))
+				}
+			}
+		}
+	}
+
+	return results, errors.Join(abortErrs...)
+}
+
+// failedDependency 返回第一个未成功的依赖，全部成功时返回空
+func failedDependency(hook config.HookConfig, status map[string]string) string {
+	for _, dep := range hook.After {
+		if status[dep] != StatusSuccess {
+			return dep
+		}
+	}
+	return ""
+}
+
+// OnFailure 钩子的失败策略，默认 abort
+func OnFailure(hook config.HookConfig) string {
+	if hook.OnFailure == "" {
+		return FailureAbort
+	}
+	return hook.OnFailure
+}
+
+// runHook 执行单个钩子，失败时按配置重试
+func runHook(hook config.HookConfig, env Env) Result {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	retryDelay := hook.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultRetryDelay
+	}
+
+	result := Result{Name: hook.Name}
+	start := time.Now()
+
+	for attempt := 0; attempt <= hook.Retries; attempt++ {
+		if attempt > 0 {
+			logger.Warn("部署钩子失败，稍后重试", "hook", hook.Name, "attempt", attempt, "error", result.Err)
+			time.Sleep(retryDelay)
+		}
+
+		result.Attempts++
+		logger.Info("执行部署钩子", "hook", hook.Name, "attempt", result.Attempts)
+
+		output, err := execute(hook, env, timeout)
+		result.Output = strings.TrimSpace(string(output))
+		result.Err = err
+		if err == nil {
+			break
+		}
+	}
+
+	result.Duration = time.Since(start)
+	if result.Err != nil {
+		result.Status = StatusFailed
+	} else {
+		result.Status = StatusSuccess
+		logger.Info("部署钩子执行成功", "hook", hook.Name, "duration", result.Duration)
+	}
+	return result
+}
+
+// execute 通过 shell 执行钩子命令（Linux 通过 sh -c，Windows 通过 cmd /C）
+func execute(hook config.HookConfig, env Env, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", hook.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", hook.Command)
+	}
+	// 超时后子进程可能仍占用输出管道，不无限等待
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Dir = env.WorkDir
+	cmd.Env = append(os.Environ(), env.variables(hook.Name)...)
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return output, fmt.Errorf("执行超时（%s）", timeout)
+	}
+	if err != nil {
+		if len(output) > 0 {
+			return output, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		}
+		return output, err
+	}
+	return output, nil
+}
+
+// variables 钩子命令可用的环境变量
+func (e Env) variables(hookName string) []string {
+	vars := []string{"AUTOCERT_HOOK=" + hookName}
+	if len(e.Domains) > 0 {
+		vars = append(vars,
+			"AUTOCERT_DOMAIN="+e.Domains[0],
+			"AUTOCERT_DOMAINS="+strings.Join(e.Domains, ","),
+		)
+	}
+	vars = append(vars,
+		"AUTOCERT_CERT_DIR="+e.CertDir,
+		"AUTOCERT_CERT_PATH="+e.CertPath,
+		"AUTOCERT_KEY_PATH="+e.KeyPath,
+		"AUTOCERT_CHAIN_PATH="+e.ChainPath,
+		"AUTOCERT_PFX_PATH="+e.PFXPath,
+	)
+	return append(vars, e.Extra...)
+}
@@ -0,0 +1,251 @@
+// Package keypool 生成证书私钥，并可在后台预生成 RSA 私钥。小型 VPS 上生成 4096 位 RSA 私钥
+// 需要数秒，守护进程和批量签发提前生成后，签发时可以直接取用。预生成的私钥只保存在内存中。
+package keypool
+
+import (
+	"autocert/internal/logger"
+	"autocert/internal/stats"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 密钥类型
+const (
+	TypeRSA   = "rsa"
+	TypeECDSA = "ecdsa"
+)
+
+// Spec 私钥的类型和长度
+type Spec struct {
+	Type string
+	Size int // RSA 位数
+}
+
+// NewSpec 规范化密钥类型和长度：类型为空时为 RSA，RSA 长度为 0 时为 2048，ECDSA 固定为 P-256
+func NewSpec(keyType string, keySize int) (Spec, error) {
+	switch strings.ToLower(keyType) {
+	case "", TypeRSA:
+		if keySize == 0 {
+			keySize = 2048
+		}
+		return Spec{Type: TypeRSA, Size: keySize}, nil
+	case TypeECDSA, "ec":
+		return Spec{Type: TypeECDSA, Size: 256}, nil
+	default:
+		return Spec{}, fmt.Errorf("不支持的密钥类型: %s", keyType)
+	}
+}
+
+// String 例如 rsa-4096、ecdsa-256
+func (s Spec) String() string {
+	return fmt.Sprintf("%s-%d", s.Type, s.Size)
+}
+
+// pooled 值得预生成的密钥：ECDSA 生成只需毫秒级，不预生成
+func (s Spec) pooled() bool {
+	return s.Type == TypeRSA
+}
+
+var (
+	mu    sync.Mutex
+	pools = make(map[Spec][]crypto.Signer)
+	taken = make(chan Spec, 1) // 取走私钥后通知后台补充
+)
+
+// Generate 返回一个私钥：池中有预生成的私钥时直接取用，否则立即生成。
+// 开启统计时记录签发等待私钥的时间（key:wait:<类型>）
+func Generate(keyType string, keySize int) (crypto.Signer, error) {
+	spec, err := NewSpec(keyType, keySize)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	key := take(spec)
+	if key == nil {
+		key, err = generate(spec)
+	} else {
+		logger.Debug("使用预生成的私钥", "key", spec)
+	}
+	stats.Record("key:wait:"+spec.String(), time.Since(start), err)
+	return key, err
+}
+
+// take 从池中取出一个私钥，池为空时返回 nil
+func take(spec Spec) crypto.Signer {
+	mu.Lock()
+	defer mu.Unlock()
+
+	keys := pools[spec]
+	if len(keys) == 0 {
+		return nil
+	}
+	key := keys[len(keys)-1]
+	pools[spec] = keys[:len(keys)-1]
+
+	select {
+	case taken <- spec:
+	default:
+	}
+	return key
+}
+
+// newKey 生成私钥
+func newKey(spec Spec) (crypto.Signer, error) {
+	if spec.Type == TypeRSA {
+		return rsa.GenerateKey(rand.Reader, spec.Size)
+	}
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// generate 生成私钥，开启统计时记录生成耗时（key:generate:<类型>）
+func generate(spec Spec) (crypto.Signer, error) {
+	start := time.Now()
+	key, err := newKey(spec)
+	elapsed := time.Since(start)
+	stats.Record("key:generate:"+spec.String(), elapsed, err)
+	if err != nil {
+		return nil, fmt.Errorf("生成 %s 私钥失败: %w", spec, err)
+	}
+	logger.Debug("已生成私钥", "key", spec, "elapsed", elapsed.Round(time.Millisecond))
+	return key, nil
+}
+
+// Size 池中预生成的私钥数量
+func Size(spec Spec) int {
+	mu.Lock()
+	defer mu.Unlock()
+	return len(pools[spec])
+}
+
+// Fill 在后台保持池中有 target 个预生成的私钥，workers 个私钥并行生成，直到 ctx 取消。
+// 守护进程使用 1 个 worker 在空闲时补充；批量签发按 CPU 核数并行生成，签发时不再等待
+func Fill(ctx context.Context, spec Spec, target, workers int) {
+	if !spec.pooled() || target <= 0 {
+		return
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = min(workers, target)
+
+	logger.Info("开始预生成私钥", "key", spec, "target", target, "workers", workers)
+
+	// 计算缺少的数量时包含正在生成的私钥，避免超过目标数量
+	var inflight int
+	done := make(chan crypto.Signer)
+	for {
+		mu.Lock()
+		missing := target - len(pools[spec]) - inflight
+		mu.Unlock()
+
+		for ; missing > 0 && inflight < workers; missing-- {
+			inflight++
+			go func() {
+				key, err := generate(spec)
+				if err != nil {
+					logger.Warn("预生成私钥失败", "key", spec, "error", err)
+				}
+				done <- key
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			// 等待正在生成的私钥结束，不再放入池中
+			for ; inflight > 0; inflight-- {
+				<-done
+			}
+			return
+		case key := <-done:
+			inflight--
+			if key == nil {
+				// 生成失败时稍后重试，避免持续占用 CPU
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Minute):
+				}
+				continue
+			}
+			mu.Lock()
+			pools[spec] = append(pools[spec], key)
+			mu.Unlock()
+		case <-taken:
+		}
+	}
+}
+
+// BenchResult 私钥生成测试结果
+type BenchResult struct {
+	Spec      Spec
+	Count     int
+	Workers   int
+	Total     time.Duration   // 全部生成完成的耗时
+	Durations []time.Duration // 每个私钥的生成耗时
+}
+
+// Average 单个私钥的平均生成耗时
+func (r *BenchResult) Average() time.Duration {
+	if len(r.Durations) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range r.Durations {
+		sum += d
+	}
+	return sum / time.Duration(len(r.Durations))
+}
+
+// Bench 用 workers 个并行任务生成 count 个私钥并计时，用于评估本机是否需要开启预生成，不记录统计
+func Bench(spec Spec, count, workers int) (*BenchResult, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	workers = max(min(workers, count), 1)
+
+	result := &BenchResult{Spec: spec, Count: count, Workers: workers, Durations: make([]time.Duration, count)}
+	jobs := make(chan int)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				keyStart := time.Now()
+				if _, err := newKey(spec); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				result.Durations[i] = time.Since(keyStart)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	result.Total = time.Since(start)
+
+	select {
+	case err := <-errs:
+		return nil, fmt.Errorf("生成 %s 私钥失败: %w", spec, err)
+	default:
+	}
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package keystore
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// 账户密钥存储方式
+const (
+	BackendFile   = "file"
+	BackendPKCS11 = "pkcs11"
+)
+
+// AccountKeyPath 文件方式下账户密钥的保存路径
+func AccountKeyPath() string {
+	return filepath.Join(config.GetConfigDir(), "account", "account.key")
+}
+
+// AccountSigner 按配置的存储方式返回 ACME 账户密钥
+// 文件方式下密钥不存在时自动生成；PKCS#11 方式下私钥不离开令牌，签名在令牌内完成
+func AccountSigner() (crypto.Signer, error) {
+	acmeConfig := config.GetACMEConfig()
+
+	switch acmeConfig.KeyBackend {
+	case "", BackendFile:
+		return loadOrCreateFileKey(AccountKeyPath())
+	case BackendPKCS11:
+		return newPKCS11Signer(acmeConfig.PKCS11)
+	default:
+		return nil, fmt.Errorf("不支持的账户密钥存储方式: %s", acmeConfig.KeyBackend)
+	}
+}
+
+// loadOrCreateFileKey 读取账户密钥文件，不存在时生成 P-256 密钥
+func loadOrCreateFileKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return parsePrivateKey(data)
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取账户密钥失败: %w", err)
+	}
+
+	logger.Info("生成 ACME 账户密钥", "path", path)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成账户密钥失败: %w", err)
+	}
+
+	if err := writeFileKey(path, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// pendingKeyPath 轮换中的新账户密钥，CA 接受后替换 account.key
+func pendingKeyPath() string {
+	return AccountKeyPath() + ".next"
+}
+
+// RolloverAccountKey 生成新的账户密钥，change 向 CA 提交轮换请求成功后原子地替换密钥文件。
+// 新密钥在提交前先保存到 account.key.next，提交后进程中断也不会丢失；
+// 上次轮换中断留下的新密钥会被重用，accepted 确认 CA 已经接受该密钥时只替换密钥文件，不再提交轮换请求
+func RolloverAccountKey(accepted func(newKey crypto.Signer) bool, change func(oldKey, newKey crypto.Signer) error) (oldKey, newKey crypto.Signer, err error) {
+	if backend := config.GetACMEConfig().KeyBackend; backend != "" && backend != BackendFile {
+		return nil, nil, fmt.Errorf("%s 方式的账户密钥需要在令牌中生成新密钥后修改 acme.pkcs11 配置，不支持自动轮换", backend)
+	}
+
+	data, err := os.ReadFile(AccountKeyPath())
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取账户密钥失败: %w", err)
+	}
+	if oldKey, err = parsePrivateKey(data); err != nil {
+		return nil, nil, fmt.Errorf("解析账户密钥失败: %w", err)
+	}
+
+	if data, err := os.ReadFile(pendingKeyPath()); err == nil {
+		if newKey, err = parsePrivateKey(data); err != nil {
+			return nil, nil, fmt.Errorf("解析上次轮换留下的新密钥失败（%s）: %w", pendingKeyPath(), err)
+		}
+		if accepted(newKey) {
+			logger.Warn("CA 已接受上次轮换中断时生成的新账户密钥，只替换密钥文件", "path", pendingKeyPath())
+			if err := replaceAccountKey(); err != nil {
+				return nil, nil, err
+			}
+			return oldKey, newKey, nil
+		}
+		logger.Warn("重用上次轮换中断时生成的新账户密钥", "path", pendingKeyPath())
+	} else {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("生成账户密钥失败: %w", err)
+		}
+		if err := writeFileKey(pendingKeyPath(), key); err != nil {
+			return nil, nil, err
+		}
+		newKey = key
+	}
+
+	if err := change(oldKey, newKey); err != nil {
+		return nil, nil, fmt.Errorf("CA 拒绝了账户密钥轮换（新密钥保留在 %s，重试时使用）: %w", pendingKeyPath(), err)
+	}
+
+	if err := replaceAccountKey(); err != nil {
+		return nil, nil, err
+	}
+	return oldKey, newKey, nil
+}
+
+// replaceAccountKey CA 接受新密钥后用 account.key.next 替换账户密钥文件
+func replaceAccountKey() error {
+	if err := os.Rename(pendingKeyPath(), AccountKeyPath()); err != nil {
+		return fmt.Errorf("替换账户密钥失败（CA 已接受新密钥，请手动将 %s 重命名为 %s）: %w",
+			pendingKeyPath(), AccountKeyPath(), err)
+	}
+	logger.Info("账户密钥已轮换", "path", AccountKeyPath())
+	return nil
+}
+
+// writeFileKey 先写入临时文件再重命名，避免中断时留下不完整的密钥
+func writeFileKey(path string, key *ecdsa.PrivateKey) error {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建账户目录失败: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("保存账户密钥失败: %w", err)
+	}
+	if err := pem.Encode(file, &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存账户密钥失败: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存账户密钥失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存账户密钥失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存账户密钥失败: %w", err)
+	}
+	return nil
+}
+
+// parsePrivateKey 解析 PEM 格式私钥（EC、PKCS#1 或 PKCS#8）
+func parsePrivateKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("账户密钥不是有效的 PEM 格式")
+	}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("不支持的账户密钥类型: %T", key)
+		}
+		return signer, nil
+	}
+}
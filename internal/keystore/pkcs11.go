@@ -0,0 +1,146 @@
+package keystore
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// pinEnvVar 传递给 pkcs11-tool 的 PIN 环境变量，避免 PIN 出现在进程参数中
+const pinEnvVar = "AUTOCERT_PKCS11_PIN"
+
+// sha256DigestInfo RSA PKCS#1 v1.5 签名的 SHA-256 DigestInfo 前缀
+var sha256DigestInfo = []byte{0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20}
+
+// pkcs11Signer 通过 OpenSC pkcs11-tool 在 PKCS#11 令牌内完成签名
+// TPM 可通过 tpm2-pkcs11 模块以同样方式使用
+type pkcs11Signer struct {
+	config config.PKCS11Config
+	pin    string
+	public crypto.PublicKey
+}
+
+// newPKCS11Signer 读取令牌中的公钥并创建签名器
+func newPKCS11Signer(cfg config.PKCS11Config) (*pkcs11Signer, error) {
+	if cfg.Module == "" {
+		return nil, fmt.Errorf("未配置 PKCS#11 模块路径 (acme.pkcs11.module)")
+	}
+	if cfg.KeyID == "" && cfg.KeyLabel == "" {
+		return nil, fmt.Errorf("未配置 PKCS#11 密钥 (acme.pkcs11.key_id 或 key_label)")
+	}
+	if cfg.Tool == "" {
+		cfg.Tool = "pkcs11-tool"
+	}
+
+	pin, err := readPIN(cfg.PIN)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &pkcs11Signer{config: cfg, pin: pin}
+
+	der, err := s.run(nil, "--read-object", "--type", "pubkey")
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌公钥失败: %w", err)
+	}
+
+	if s.public, err = x509.ParsePKIXPublicKey(der); err != nil {
+		return nil, fmt.Errorf("解析令牌公钥失败: %w", err)
+	}
+
+	logger.Debug("已加载 PKCS#11 账户密钥", "module", cfg.Module, "token", cfg.TokenLabel)
+	return s, nil
+}
+
+// Public 返回令牌中密钥的公钥
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.public
+}
+
+// Sign 在令牌内对摘要签名，ECDSA 签名返回 ASN.1 DER 格式（与 crypto/ecdsa 一致）
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.SHA256 {
+		return nil, fmt.Errorf("PKCS#11 签名仅支持 SHA-256")
+	}
+
+	switch s.public.(type) {
+	case *ecdsa.PublicKey:
+		return s.run(digest, "--login", "--sign", "--mechanism", "ECDSA", "--signature-format", "openssl")
+	case *rsa.PublicKey:
+		input := append(append([]byte{}, sha256DigestInfo...), digest...)
+		return s.run(input, "--login", "--sign", "--mechanism", "RSA-PKCS")
+	default:
+		return nil, fmt.Errorf("不支持的令牌密钥类型: %T", s.public)
+	}
+}
+
+// run 调用 pkcs11-tool，input 写入临时文件作为输入，返回输出文件内容
+func (s *pkcs11Signer) run(input []byte, args ...string) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "autocert-pkcs11-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	outPath := filepath.Join(tmpDir, "out")
+	cmdArgs := []string{"--module", s.config.Module, "--output-file", outPath}
+	if s.config.TokenLabel != "" {
+		cmdArgs = append(cmdArgs, "--token-label", s.config.TokenLabel)
+	}
+	if s.config.KeyID != "" {
+		cmdArgs = append(cmdArgs, "--id", s.config.KeyID)
+	} else {
+		cmdArgs = append(cmdArgs, "--label", s.config.KeyLabel)
+	}
+	if input != nil {
+		inPath := filepath.Join(tmpDir, "in")
+		if err := os.WriteFile(inPath, input, 0600); err != nil {
+			return nil, err
+		}
+		cmdArgs = append(cmdArgs, "--input-file", inPath)
+	}
+	if s.pin != "" {
+		cmdArgs = append(cmdArgs, "--pin", "env:"+pinEnvVar)
+	}
+	cmdArgs = append(cmdArgs, args...)
+
+	cmd := exec.Command(s.config.Tool, cmdArgs...)
+	cmd.Env = append(os.Environ(), pinEnvVar+"="+s.pin)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s 执行失败: %v: %s", s.config.Tool, err, strings.TrimSpace(string(output)))
+	}
+
+	return os.ReadFile(outPath)
+}
+
+// readPIN 按配置的来源读取 PIN：env:变量名 或 file:文件路径，为空表示无需 PIN
+func readPIN(source string) (string, error) {
+	switch {
+	case source == "":
+		return "", nil
+	case strings.HasPrefix(source, "env:"):
+		name := strings.TrimPrefix(source, "env:")
+		pin, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("PIN 环境变量 %s 未设置", name)
+		}
+		return pin, nil
+	case strings.HasPrefix(source, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(source, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("读取 PIN 文件失败: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return "", fmt.Errorf("不支持的 PIN 来源 %q，请使用 env:变量名 或 file:文件路径", source)
+	}
+}
@@ -1,9 +1,14 @@
 package logger
 
 import (
+	"autocert/internal/clock"
+	consoleout "autocert/internal/console"
+	"autocert/internal/system"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -11,39 +16,42 @@ import (
 
 var log *logrus.Logger
 
+// console 控制台输出钩子
+var console = &consoleHook{}
+
 // Init 初始化日志系统
+// 完整日志写入日志文件（加载配置后由 SetupFile 打开）；控制台只显示警告和错误（--verbose 时显示全部），
+// 日志文件不可用时控制台显示全部信息
 func Init() {
 	log = logrus.New()
 
-	// 设置日志格式
+	// 日志文件不使用颜色
 	log.SetFormatter(&logrus.TextFormatter{
 		FullTimestamp: true,
-		ForceColors:   true,
+		DisableColors: true,
 	})
+	log.SetOutput(io.Discard)
+	log.AddHook(timezoneHook{})
+	log.AddHook(console)
+
+	Configure(viper.GetBool("verbose"), viper.GetBool("no_color"))
+}
 
-	// 设置日志级别
-	if viper.GetBool("verbose") {
+// Configure 根据命令行参数设置日志级别和控制台颜色
+func Configure(verbose, noColor bool) {
+	if verbose {
 		log.SetLevel(logrus.DebugLevel)
 	} else {
 		log.SetLevel(logrus.InfoLevel)
 	}
 
-	// 设置日志输出
-	log.SetOutput(os.Stdout)
-
-	// 创建日志文件
-	setupLogFile()
+	console.verbose = verbose
+	consoleout.SetNoColor(noColor)
 }
 
-// setupLogFile 设置日志文件
-func setupLogFile() {
-	var logPath string
-
-	if runtime.GOOS == "windows" {
-		logPath = filepath.Join(os.Getenv("PROGRAMDATA"), "AutoCert", "logs", "autocert.log")
-	} else {
-		logPath = "/var/log/autocert.log"
-	}
+// SetupFile 在日志目录（log_dir）中创建或打开日志文件 autocert.log
+func SetupFile(dir string) {
+	logPath := filepath.Join(dir, "autocert.log")
 
 	// 创建日志目录
 	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
@@ -51,6 +59,13 @@ func setupLogFile() {
 		return
 	}
 
+	// 日志所在磁盘空间不足时不写入日志文件，只输出到控制台
+	minFree := uint64(viper.GetInt("storage.min_free_mb")) << 20
+	if err := system.CheckDiskSpace(filepath.Dir(logPath), minFree); err != nil {
+		log.Warnf("不写入日志文件: %v", err)
+		return
+	}
+
 	// 创建或打开日志文件
 	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
@@ -58,8 +73,73 @@ func setupLogFile() {
 		return
 	}
 
-	// 设置多输出
 	log.SetOutput(logFile)
+	console.fileEnabled = true
+}
+
+// consoleHook 将日志同时输出到控制台（标准错误），按级别着色
+type consoleHook struct {
+	verbose     bool
+	fileEnabled bool
+}
+
+// timezoneHook 将日志时间转换到配置的时区（timezone），日志文件中的时间与状态和报告一致
+type timezoneHook struct{}
+
+func (timezoneHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (timezoneHook) Fire(entry *logrus.Entry) error {
+	entry.Time = clock.In(entry.Time)
+	return nil
+}
+
+// fileOnlyField 带有该字段的日志只写入日志文件（已单独在控制台提示）
+const fileOnlyField = "_file_only"
+
+func (h *consoleHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *consoleHook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[fileOnlyField]; ok {
+		delete(entry.Data, fileOnlyField)
+		return nil
+	}
+
+	// 日志文件可用且未开启详细输出时，控制台只显示警告和错误
+	if h.fileEnabled && !h.verbose && entry.Level > logrus.WarnLevel {
+		return nil
+	}
+
+	var color, label string
+	switch entry.Level {
+	case logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel:
+		color, label = consoleout.Red, "错误"
+	case logrus.WarnLevel:
+		color, label = consoleout.Yellow, "警告"
+	case logrus.InfoLevel:
+		label = "信息"
+	default:
+		color, label = consoleout.Gray, "调试"
+	}
+
+	line := "[" + label + "] " + entry.Message
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		line += fmt.Sprintf(" %s=%v", key, entry.Data[key])
+	}
+
+	if color != "" {
+		line = consoleout.Colorize(os.Stderr, color, line)
+	}
+	_, err := fmt.Fprintln(consoleout.Stderr, line)
+	return err
 }
 
 // 封装常用的日志方法
@@ -95,6 +175,13 @@ func Error(msg string, args ...interface{}) {
 	}
 }
 
+// ErrorToFile 只将错误写入日志文件（控制台已通过其他方式提示）
+func ErrorToFile(msg string, args ...interface{}) {
+	fields := convertToFields(args...)
+	fields[fileOnlyField] = true
+	log.WithFields(fields).Error(msg)
+}
+
 func Fatal(msg string, args ...interface{}) {
 	if len(args) > 0 {
 		log.WithFields(convertToFields(args...)).Fatal(msg)
@@ -0,0 +1,338 @@
+// Package pkcs12 生成 PKCS#12（PFX/P12）证书包，用于导入浏览器、操作系统证书存储或 IIS。
+//
+// 私钥使用 pbeWithSHAAnd3-KeyTripleDES-CBC 加密，证书不加密，整体使用 HMAC-SHA1 校验，
+// 与 Windows（包括较旧版本）和 OpenSSL 兼容。
+package pkcs12
+
+import (
+	"autocert/internal/secmem"
+	"bytes"
+	"crypto"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"unicode/utf16"
+)
+
+var (
+	oidData                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidCertBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidShroudedKeyBag       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidX509Certificate      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+	oidPBEWithSHAAnd3KeyDES = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidSHA1                 = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// iterations 密钥派生的迭代次数（与 OpenSSL 默认值相同）
+const iterations = 2048
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm algorithmIdentifier
+	Digest    []byte
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue // SET OF AttributeValue
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     algorithmIdentifier
+	EncryptedData []byte
+}
+
+// Encode 将私钥、证书和 CA 证书链打包为 PKCS#12，friendlyName 为导入后显示的名称（可为空）
+func Encode(key crypto.PrivateKey, cert *x509.Certificate, caCerts []*x509.Certificate, password, friendlyName string) ([]byte, error) {
+	pass, err := bmpString(password)
+	if err != nil {
+		return nil, err
+	}
+	defer secmem.Wipe(pass)
+
+	keyID := sha1.Sum(cert.Raw)
+	attributes, err := bagAttributes(keyID[:], friendlyName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 证书
+	var certBags []safeBag
+	for i, c := range append([]*x509.Certificate{cert}, caCerts...) {
+		bag, err := makeCertBag(c.Raw)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			bag.Attributes = attributes
+		}
+		certBags = append(certBags, *bag)
+	}
+
+	// 私钥
+	keyBag, err := makeShroudedKeyBag(key, pass)
+	if err != nil {
+		return nil, err
+	}
+	keyBag.Attributes = attributes
+
+	var authSafe []contentInfo
+	for _, bags := range [][]safeBag{certBags, {*keyBag}} {
+		info, err := makeDataContentInfo(bags)
+		if err != nil {
+			return nil, err
+		}
+		authSafe = append(authSafe, *info)
+	}
+
+	authSafeBytes, err := asn1.Marshal(authSafe)
+	if err != nil {
+		return nil, err
+	}
+
+	// 完整性校验
+	macSalt := make([]byte, 8)
+	if _, err := rand.Read(macSalt); err != nil {
+		return nil, err
+	}
+	macKey := deriveKey(macSalt, pass, iterations, 3, 20)
+	mac := hmac.New(sha1.New, macKey)
+	mac.Write(authSafeBytes)
+
+	content, err := asn1.Marshal(authSafeBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pfxPdu{
+		Version: 3,
+		AuthSafe: contentInfo{
+			ContentType: oidData,
+			Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+		},
+		MacData: macData{
+			Mac: digestInfo{
+				Algorithm: algorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.NullRawValue},
+				Digest:    mac.Sum(nil),
+			},
+			MacSalt:    macSalt,
+			Iterations: iterations,
+		},
+	})
+}
+
+// bagAttributes 证书和私钥共同的属性：localKeyId 用于关联二者
+func bagAttributes(keyID []byte, friendlyName string) ([]pkcs12Attribute, error) {
+	idValue, err := asn1.Marshal(keyID)
+	if err != nil {
+		return nil, err
+	}
+	attributes := []pkcs12Attribute{{
+		ID:    oidLocalKeyID,
+		Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: idValue},
+	}}
+
+	if friendlyName != "" {
+		name, err := bmpString(friendlyName)
+		if err != nil {
+			return nil, err
+		}
+		// 属性中的 BMPString 不包含结尾的 0
+		nameValue, err := asn1.Marshal(asn1.RawValue{Tag: asn1.TagBMPString, Class: asn1.ClassUniversal, Bytes: name[:len(name)-2]})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, pkcs12Attribute{
+			ID:    oidFriendlyName,
+			Value: asn1.RawValue{Tag: asn1.TagSet, Class: asn1.ClassUniversal, IsCompound: true, Bytes: nameValue},
+		})
+	}
+	return attributes, nil
+}
+
+// makeCertBag 证书包
+func makeCertBag(der []byte) (*safeBag, error) {
+	value, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: der})
+	if err != nil {
+		return nil, err
+	}
+	return &safeBag{ID: oidCertBag, Value: asn1.RawValue{FullBytes: explicitTag0(value)}}, nil
+}
+
+// makeShroudedKeyBag 加密的私钥包（PKCS#8）
+func makeShroudedKeyBag(key crypto.PrivateKey, pass []byte) (*safeBag, error) {
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer secmem.Wipe(pkcs8)
+
+	salt := make([]byte, 8)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbeParams{Salt: salt, Iterations: iterations})
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := encrypt3DES(pkcs8, salt, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     algorithmIdentifier{Algorithm: oidPBEWithSHAAnd3KeyDES, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: encrypted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &safeBag{ID: oidShroudedKeyBag, Value: asn1.RawValue{FullBytes: explicitTag0(value)}}, nil
+}
+
+// makeDataContentInfo 将一组 SafeBag 包装为未加密的 data 类型 ContentInfo
+func makeDataContentInfo(bags []safeBag) (*contentInfo, error) {
+	safeContents, err := asn1.Marshal(bags)
+	if err != nil {
+		return nil, err
+	}
+	content, err := asn1.Marshal(safeContents)
+	if err != nil {
+		return nil, err
+	}
+	return &contentInfo{
+		ContentType: oidData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	}, nil
+}
+
+// explicitTag0 用 [0] EXPLICIT 包装已编码的值
+func explicitTag0(der []byte) []byte {
+	wrapped, _ := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der})
+	return wrapped
+}
+
+// encrypt3DES 使用 pbeWithSHAAnd3-KeyTripleDES-CBC 加密
+func encrypt3DES(data, salt, pass []byte) ([]byte, error) {
+	key := deriveKey(salt, pass, iterations, 1, 24)
+	iv := deriveKey(salt, pass, iterations, 2, 8)
+
+	block, err := des.NewTripleDESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := block.BlockSize() - len(data)%block.BlockSize()
+	padded := append(append([]byte{}, data...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	defer secmem.Wipe(padded)
+
+	encrypted := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, padded)
+	return encrypted, nil
+}
+
+// bmpString 将密码编码为以 0 结尾的 UTF-16BE（RFC 7292 附录 B.1）
+func bmpString(s string) ([]byte, error) {
+	out := make([]byte, 0, 2*len(s)+2)
+	for _, r := range s {
+		if r > 0xFFFF {
+			return nil, errors.New("pkcs12: 密码中包含 BMP 之外的字符")
+		}
+		for _, u := range utf16.Encode([]rune{r}) {
+			out = append(out, byte(u>>8), byte(u))
+		}
+	}
+	return append(out, 0, 0), nil
+}
+
+// deriveKey PKCS#12 密钥派生（RFC 7292 附录 B.2，SHA-1），id 为 1 加密密钥、2 IV、3 MAC 密钥
+func deriveKey(salt, pass []byte, rounds int, id byte, size int) []byte {
+	const u, v = sha1.Size, 64
+
+	d := bytes.Repeat([]byte{id}, v)
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		n := (len(b) + v - 1) / v * v
+		out := make([]byte, n)
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	i := append(fill(salt), fill(pass)...)
+
+	var result []byte
+	for len(result) < size {
+		h := sha1.New()
+		h.Write(d)
+		h.Write(i)
+		a := h.Sum(nil)
+		for r := 1; r < rounds; r++ {
+			sum := sha1.Sum(a)
+			a = sum[:]
+		}
+		result = append(result, a...)
+
+		// I_j = (I_j + B + 1) mod 2^(v*8)
+		b := make([]byte, v)
+		for k := range b {
+			b[k] = a[k%u]
+		}
+		for j := 0; j < len(i); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				sum := int(i[j+k]) + int(b[k]) + carry
+				i[j+k] = byte(sum)
+				carry = sum >> 8
+			}
+		}
+	}
+	return result[:size]
+}
@@ -0,0 +1,69 @@
+package policy
+
+import (
+	"autocert/internal/audit"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"fmt"
+	"strings"
+)
+
+// CheckIssuance 检查是否允许为这些域名向指定 ACME 服务器申请证书
+// 违反策略时写入审计日志并返回错误；未配置策略时不做限制
+func CheckIssuance(action string, domains []string, server string) error {
+	cfg := config.GetPolicyConfig()
+
+	if err := checkServer(cfg, server); err != nil {
+		return deny(action, domains, err)
+	}
+
+	for _, domain := range domains {
+		if err := checkDomain(cfg, domain); err != nil {
+			return deny(action, domains, err)
+		}
+	}
+	return nil
+}
+
+// checkServer 检查 ACME 目录地址是否在允许列表中
+func checkServer(cfg config.PolicyConfig, server string) error {
+	if len(cfg.AllowedServers) == 0 {
+		return nil
+	}
+
+	normalized := strings.TrimRight(server, "/")
+	for _, allowed := range cfg.AllowedServers {
+		if strings.EqualFold(strings.TrimRight(allowed, "/"), normalized) {
+			return nil
+		}
+	}
+	return fmt.Errorf("策略不允许使用 ACME 服务器 %s", server)
+}
+
+// checkDomain 检查域名是否匹配允许的后缀（后缀本身或其子域名），泛域名按基础域名检查
+func checkDomain(cfg config.PolicyConfig, domain string) error {
+	if len(cfg.AllowedDomains) == 0 {
+		return nil
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(domain, "*."), "."))
+	for _, suffix := range cfg.AllowedDomains {
+		suffix = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(suffix, "."), "."))
+		if name == suffix || strings.HasSuffix(name, "."+suffix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("策略不允许为域名 %s 申请证书", domain)
+}
+
+// deny 记录违反策略的操作
+func deny(action string, domains []string, err error) error {
+	logger.Error("操作违反策略", "action", action, "domains", domains, "error", err)
+	audit.Record(audit.Entry{
+		Action:  action,
+		Domains: domains,
+		Result:  audit.ResultDenied,
+		Detail:  err.Error(),
+	})
+	return err
+}
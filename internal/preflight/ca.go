@@ -0,0 +1,481 @@
+package preflight
+
+import (
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// letsEncryptStatusURL Let's Encrypt 状态页（letsencrypt.status.io）的状态接口
+const letsEncryptStatusURL = "https://api.status.io/1.0/status/55957a99e800baa4470002da"
+
+// acmeEndpoints ping-ca 检查的 ACME 目录接口，按顺序输出
+var acmeEndpoints = []string{"newNonce", "newAccount", "newOrder", "revokeCert", "keyChange"}
+
+// CAPingReport ACME 服务器连通性检查的结果
+type CAPingReport struct {
+	Server     string
+	Results    []Result
+	Reachable  bool   // ACME 目录可以访问
+	Connected  bool   // 收到了 HTTP 响应（网络路径可用），目录请求失败时说明问题在 CA 一侧
+	HTTPStatus int    // 目录请求的 HTTP 状态码，没有收到响应时为 0
+	Degraded   bool   // CA 状态页报告故障或性能下降
+	Diagnosis  string // 结论：可以正常签发、本机网络问题还是 CA 故障
+}
+
+// caStatus CA 状态页报告的状态
+type caStatus struct {
+	Operational bool
+	Description string
+	Components  []string // 不正常的组件
+}
+
+// timing 一次请求各阶段的耗时（新建连接）
+type timing struct {
+	dns, connect, tls, firstByte, total time.Duration
+}
+
+// CAStatusURL CA 状态页接口：优先使用 acme.status_url，Let's Encrypt 使用其状态页，其他 CA 返回空
+func CAStatusURL(server string) string {
+	if statusURL := config.GetACMEConfig().StatusURL; statusURL != "" {
+		return statusURL
+	}
+	if u, err := url.Parse(server); err == nil && strings.HasSuffix(u.Hostname(), ".api.letsencrypt.org") {
+		return letsEncryptStatusURL
+	}
+	return ""
+}
+
+// PingCA 检查本机到 ACME 服务器的连通性：域名解析、目录请求的延迟（请求 count 次）、
+// CA 证书是否受信任、目录中各接口是否可达，并在无法访问时根据 CA 状态页判断原因
+func PingCA(server string, count int, timeout time.Duration) *CAPingReport {
+	report := &CAPingReport{Server: server}
+	if count < 1 {
+		count = 1
+	}
+
+	target, err := url.Parse(server)
+	if err != nil || target.Host == "" {
+		report.Results = append(report.Results, Result{Name: "ACME 目录", Err: fmt.Errorf("ACME 服务器地址无效: %s", server)})
+		report.Diagnosis = "ACME 服务器地址无效，检查 acme.server"
+		return report
+	}
+	proxy := proxyFor(target)
+
+	// 1. 域名解析（通过代理访问时由代理解析）
+	dnsResult, resolved := checkCAResolve(target.Hostname(), proxy, timeout)
+	report.Results = append(report.Results, dnsResult)
+
+	// 2. ACME 目录和延迟
+	var directory map[string]interface{}
+	var state *tls.ConnectionState
+	var samples []timing
+	var requestErr error
+	for i := 0; i < count; i++ {
+		resp, t, err := timedRequest(http.MethodGet, server, timeout)
+		if err != nil {
+			requestErr = err
+			break
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		report.Connected = true
+		report.HTTPStatus = resp.StatusCode
+		if resp.StatusCode != http.StatusOK {
+			requestErr = fmt.Errorf("HTTP %d", resp.StatusCode)
+			break
+		}
+		if directory == nil {
+			if err := json.Unmarshal(body, &directory); err != nil {
+				requestErr = fmt.Errorf("响应不是 ACME 目录: %w", err)
+				break
+			}
+			if _, ok := directory["newNonce"].(string); !ok {
+				directory = nil
+				requestErr = errors.New("响应不是 ACME 目录: 缺少 newNonce")
+				break
+			}
+			state = resp.TLS
+		}
+		samples = append(samples, t)
+	}
+
+	directoryResult := Result{Name: "ACME 目录"}
+	if requestErr != nil {
+		directoryResult.Err = fmt.Errorf("请求 %s 失败: %w", server, requestErr)
+	} else {
+		directoryResult.Detail = describeLatency(samples)
+		if proxy != nil {
+			directoryResult.Detail += fmt.Sprintf("，通过代理 %s", proxy.Host)
+		}
+	}
+	report.Results = append(report.Results, directoryResult)
+	report.Reachable = requestErr == nil
+
+	// 3. CA 证书信任
+	var verifyErr *tls.CertificateVerificationError
+	untrusted := errors.As(requestErr, &verifyErr)
+	switch {
+	case untrusted:
+		report.Results = append(report.Results, Result{Name: "TLS 证书信任", Err: describeUntrusted(target, proxy, verifyErr, timeout)})
+	case state != nil:
+		report.Results = append(report.Results, describeTrust(state))
+	default:
+		report.Results = append(report.Results, Result{Name: "TLS 证书信任", Skipped: true, Detail: "没有建立 TLS 连接"})
+	}
+
+	// 4. 目录中的接口
+	if directory != nil {
+		report.Results = append(report.Results, checkEndpoints(directory, timeout)...)
+	}
+
+	// 5. CA 状态页
+	status, statusResult := checkCAStatus(CAStatusURL(server), timeout)
+	report.Results = append(report.Results, statusResult)
+
+	report.Degraded = status != nil && !status.Operational
+	report.Diagnosis = diagnose(report, resolved, untrusted, proxy != nil, status, statusResult)
+	return report
+}
+
+// proxyFor 访问目标地址使用的代理（HTTPS_PROXY 等环境变量），没有时返回 nil
+func proxyFor(target *url.URL) *url.URL {
+	proxy, err := http.ProxyFromEnvironment(&http.Request{URL: target})
+	if err != nil {
+		return nil
+	}
+	return proxy
+}
+
+// checkCAResolve 解析 CA 的域名，返回检查结果和是否解析成功
+func checkCAResolve(host string, proxy *url.URL, timeout time.Duration) (Result, bool) {
+	result := Result{Name: "域名解析"}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	elapsed := time.Since(start)
+	if err != nil {
+		if proxy != nil {
+			result.Skipped = true
+			result.Detail = fmt.Sprintf("本机无法解析 %s，通过代理访问时由代理解析", host)
+			return result, false
+		}
+		result.Err = fmt.Errorf("解析 %s 失败: %w", host, err)
+		return result, false
+	}
+
+	var v4, v6 []string
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			v4 = append(v4, addr.IP.String())
+		} else {
+			v6 = append(v6, addr.IP.String())
+		}
+	}
+	result.Detail = fmt.Sprintf("%s → %s（%s）", host, strings.Join(append(v4, v6...), ", "), formatDuration(elapsed))
+	return result, true
+}
+
+// timedRequest 使用新连接发送请求，记录域名解析、TCP 连接、TLS 握手和首字节的耗时（没有经过的阶段为 0）
+func timedRequest(method, target string, timeout time.Duration) (*http.Response, timing, error) {
+	var t timing
+	var mu sync.Mutex
+	var start, dnsStart, connectStart, tlsStart time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:  func(httptrace.DNSDoneInfo) { t.dns = time.Since(dnsStart) },
+		// 同时尝试 IPv4 和 IPv6 时并发回调，记录第一个建立的连接
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			if connectStart.IsZero() {
+				connectStart = time.Now()
+			}
+			mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			mu.Lock()
+			if err == nil && t.connect == 0 {
+				t.connect = time.Since(connectStart)
+			}
+			mu.Unlock()
+		},
+		TLSHandshakeStart:    func() { tlsStart = time.Now() },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.tls = time.Since(tlsStart) },
+		GotFirstResponseByte: func() { t.firstByte = time.Since(start) },
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), method, target, nil)
+	if err != nil {
+		return nil, t, err
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DisableKeepAlives:   true,
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+
+	start = time.Now()
+	resp, err := client.Do(req)
+	t.total = time.Since(start)
+	return resp, t, err
+}
+
+// describeLatency 目录请求的延迟：最小/平均/最大，以及最快一次的各阶段耗时
+func describeLatency(samples []timing) string {
+	fastest := samples[0]
+	var sum time.Duration
+	slowest := samples[0].total
+	for _, t := range samples {
+		sum += t.total
+		if t.total < fastest.total {
+			fastest = t
+		}
+		if t.total > slowest {
+			slowest = t.total
+		}
+	}
+	avg := sum / time.Duration(len(samples))
+
+	phases := []string{fmt.Sprintf("%d 次", len(samples))}
+	for _, phase := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"解析", fastest.dns},
+		{"TCP 连接", fastest.connect},
+		{"TLS 握手", fastest.tls},
+		{"首字节", fastest.firstByte},
+	} {
+		if phase.duration > 0 {
+			phases = append(phases, phase.name+" "+formatDuration(phase.duration))
+		}
+	}
+	return fmt.Sprintf("延迟 最小/平均/最大 %s/%s/%s（%s）",
+		formatDuration(fastest.total), formatDuration(avg), formatDuration(slowest), strings.Join(phases, "，"))
+}
+
+// describeTrust CA 的证书链和 TLS 版本，证书即将到期时警告
+func describeTrust(state *tls.ConnectionState) Result {
+	result := Result{Name: "TLS 证书信任"}
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		result.Detail = tls.VersionName(state.Version)
+		return result
+	}
+
+	chain := state.VerifiedChains[0]
+	leaf, root := chain[0], chain[len(chain)-1]
+	result.Detail = fmt.Sprintf("受信任（颁发者 %s，根证书 %s，%s，证书到期 %s）",
+		leaf.Issuer.CommonName, root.Subject.CommonName, tls.VersionName(state.Version), clock.Date(leaf.NotAfter))
+	if remaining := time.Until(leaf.NotAfter); remaining < 7*24*time.Hour {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("CA 的 TLS 证书将于 %s 到期", clock.Relative(leaf.NotAfter)))
+	}
+	return result
+}
+
+// describeUntrusted CA 的证书不受信任：直接连接时读取实际收到的证书，HTTPS 拦截代理通常会替换颁发者
+func describeUntrusted(target *url.URL, proxy *url.URL, verifyErr *tls.CertificateVerificationError, timeout time.Duration) error {
+	issuer := ""
+	if len(verifyErr.UnverifiedCertificates) > 0 {
+		issuer = describeIssuer(verifyErr.UnverifiedCertificates[0].Issuer.CommonName, verifyErr.UnverifiedCertificates[0].Issuer.Organization)
+	} else if proxy == nil {
+		port := target.Port()
+		if port == "" {
+			port = "443"
+		}
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", net.JoinHostPort(target.Hostname(), port),
+			&tls.Config{ServerName: target.Hostname(), InsecureSkipVerify: true})
+		if err == nil {
+			if certs := conn.ConnectionState().PeerCertificates; len(certs) > 0 {
+				issuer = describeIssuer(certs[0].Issuer.CommonName, certs[0].Issuer.Organization)
+			}
+			conn.Close()
+		}
+	}
+
+	hint := "检查系统根证书是否完整（例如 ca-certificates 包），以及是否有 HTTPS 拦截代理或安全软件替换了证书"
+	if issuer != "" {
+		return fmt.Errorf("CA 的证书不受信任（收到的证书由 %s 颁发）: %v；%s", issuer, verifyErr.Err, hint)
+	}
+	return fmt.Errorf("CA 的证书不受信任: %v；%s", verifyErr.Err, hint)
+}
+
+// describeIssuer 颁发者名称和组织
+func describeIssuer(commonName string, organization []string) string {
+	if len(organization) > 0 && organization[0] != commonName {
+		return fmt.Sprintf("%s（%s）", commonName, organization[0])
+	}
+	return commonName
+}
+
+// checkEndpoints 请求目录中的各个接口：newNonce 需要返回 Replay-Nonce，其他接口只接受 POST，
+// 收到任何 HTTP 响应即为可达
+func checkEndpoints(directory map[string]interface{}, timeout time.Duration) []Result {
+	var results []Result
+	for _, name := range acmeEndpoints {
+		endpoint, _ := directory[name].(string)
+		result := Result{Name: "接口 " + name}
+		if endpoint == "" {
+			if name == "newNonce" || name == "newAccount" || name == "newOrder" {
+				result.Err = fmt.Errorf("ACME 目录中没有 %s", name)
+				results = append(results, result)
+			}
+			continue
+		}
+
+		resp, t, err := timedRequest(http.MethodHead, endpoint, timeout)
+		if err != nil {
+			result.Err = fmt.Errorf("请求 %s 失败: %w", endpoint, err)
+			results = append(results, result)
+			continue
+		}
+		resp.Body.Close()
+
+		result.Detail = fmt.Sprintf("HTTP %d，%s", resp.StatusCode, formatDuration(t.total))
+		if name == "newNonce" && resp.Header.Get("Replay-Nonce") == "" {
+			result.Warnings = append(result.Warnings, "newNonce 没有返回 Replay-Nonce，ACME 请求会失败（可能被代理修改了响应）")
+		}
+		if resp.StatusCode >= 500 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s 返回 HTTP %d，CA 可能正在故障", name, resp.StatusCode))
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkCAStatus 读取 CA 状态页，未配置状态页时跳过
+func checkCAStatus(statusURL string, timeout time.Duration) (*caStatus, Result) {
+	result := Result{Name: "CA 状态页"}
+	if statusURL == "" {
+		result.Skipped = true
+		result.Detail = "未配置 acme.status_url"
+		return nil, result
+	}
+
+	status, err := fetchCAStatus(statusURL, timeout)
+	if err != nil {
+		result.Skipped = true
+		result.Detail = fmt.Sprintf("无法读取 %s: %v", statusURL, err)
+		return nil, result
+	}
+
+	result.Detail = status.Description
+	if !status.Operational {
+		result.Warnings = append(result.Warnings, "CA 报告故障或性能下降，签发可能变慢或失败")
+		result.Warnings = append(result.Warnings, status.Components...)
+	}
+	return status, result
+}
+
+// fetchCAStatus 读取状态页接口，支持 Atlassian Statuspage（status.indicator）和 status.io（result.status_overall）
+func fetchCAStatus(statusURL string, timeout time.Duration) (*caStatus, error) {
+	resp, _, err := timedRequest(http.MethodGet, statusURL, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var page struct {
+		Status *struct {
+			Indicator   string `json:"indicator"`
+			Description string `json:"description"`
+		} `json:"status"`
+		Result *struct {
+			StatusOverall struct {
+				Status     string `json:"status"`
+				StatusCode int    `json:"status_code"`
+			} `json:"status_overall"`
+			Status []struct {
+				Name       string `json:"name"`
+				Status     string `json:"status"`
+				StatusCode int    `json:"status_code"`
+			} `json:"status"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&page); err != nil {
+		return nil, fmt.Errorf("解析状态页失败: %w", err)
+	}
+
+	switch {
+	case page.Result != nil:
+		// status.io：100 正常，200 计划维护，300 以上为性能下降或故障
+		status := &caStatus{
+			Operational: page.Result.StatusOverall.StatusCode == 100,
+			Description: page.Result.StatusOverall.Status,
+		}
+		for _, component := range page.Result.Status {
+			if component.StatusCode != 100 {
+				status.Components = append(status.Components, fmt.Sprintf("%s: %s", component.Name, component.Status))
+			}
+		}
+		sort.Strings(status.Components)
+		return status, nil
+	case page.Status != nil:
+		return &caStatus{Operational: page.Status.Indicator == "none", Description: page.Status.Description}, nil
+	}
+	return nil, errors.New("不支持的状态页格式（需要 Statuspage 或 status.io 的状态接口）")
+}
+
+// diagnose 根据各项结果给出结论，无法访问 CA 时区分本机网络问题和 CA 故障：
+// 收到了 HTTP 响应说明网络路径可用，5xx 或不是 ACME 目录的响应属于 CA 一侧的问题
+func diagnose(report *CAPingReport, resolved, untrusted, proxied bool, status *caStatus, statusResult Result) string {
+	if report.Reachable {
+		if status != nil && !status.Operational {
+			return fmt.Sprintf("可以访问 CA，但状态页报告 %s，签发可能变慢或失败", status.Description)
+		}
+		return "可以访问 CA"
+	}
+
+	switch {
+	case untrusted:
+		return "本机与 CA 之间的 TLS 连接不受信任：检查系统根证书和 HTTPS 拦截代理"
+	case report.Connected && report.HTTPStatus >= 500:
+		if status != nil && !status.Operational {
+			return fmt.Sprintf("CA 故障：目录返回 HTTP %d，状态页报告 %s，稍后重试", report.HTTPStatus, status.Description)
+		}
+		return fmt.Sprintf("CA 故障：网络连接正常，但 CA 返回 HTTP %d（状态页可能尚未更新），稍后重试", report.HTTPStatus)
+	case report.Connected && report.HTTPStatus != http.StatusOK:
+		return fmt.Sprintf("网络连接正常，但 CA 返回 HTTP %d：检查 acme.server 地址是否正确", report.HTTPStatus)
+	case report.Connected && proxied:
+		return "网络连接正常，但返回的不是 ACME 目录：可能是代理返回的页面，也可能是 acme.server 地址不正确"
+	case report.Connected:
+		return "网络连接正常，但 CA 返回的不是 ACME 目录：检查 acme.server 地址是否正确，或 CA 正在维护"
+	case status != nil && !status.Operational:
+		return fmt.Sprintf("CA 故障：状态页报告 %s，稍后重试", status.Description)
+	case !resolved && !proxied:
+		return "本机无法解析 CA 的域名：检查 DNS 配置（/etc/resolv.conf）或 DNS 过滤"
+	case status != nil:
+		return "CA 运行正常，但本机无法访问：检查防火墙出站规则、代理（HTTPS_PROXY）和网络运营商的拦截"
+	case statusResult.Skipped && CAStatusURL(report.Server) != "":
+		return "CA 和状态页都无法访问：本机可能无法访问外网，检查网络连接和代理"
+	}
+	return "无法访问 CA；没有可用的状态页，无法区分本机网络问题和 CA 故障（可以配置 acme.status_url）"
+}
+
+// formatDuration 耗时精确到毫秒（不足 1 毫秒时精确到微秒）
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
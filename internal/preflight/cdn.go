@@ -0,0 +1,145 @@
+package preflight
+
+import (
+	"autocert/internal/config"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// CDN 域名经过的 CDN 或反向代理
+type CDN struct {
+	Name     string // 例如 Cloudflare
+	Evidence string // 判断依据，例如 CNAME d111.cloudfront.net
+	Hint     string // 继续使用 http-01 验证时需要在 CDN 上做的设置
+}
+
+// genericCDNHint 没有专门说明的 CDN 的设置建议
+const genericCDNHint = "在 CDN 上为 /.well-known/acme-challenge/ 路径配置回源、不缓存且不强制跳转 HTTPS"
+
+// cdnCNAMEs 常见 CDN 分配给用户的 CNAME 后缀
+var cdnCNAMEs = []struct {
+	suffix string
+	name   string
+	hint   string
+}{
+	{"cdn.cloudflare.net", "Cloudflare", ""},
+	{"cloudfront.net", "Amazon CloudFront", "在 CloudFront 中为 /.well-known/acme-challenge/* 添加行为：转发到源站、关闭缓存、允许 HTTP"},
+	{"akamaiedge.net", "Akamai", ""},
+	{"akamai.net", "Akamai", ""},
+	{"edgekey.net", "Akamai", ""},
+	{"edgesuite.net", "Akamai", ""},
+	{"fastly.net", "Fastly", ""},
+	{"fastlylb.net", "Fastly", ""},
+	{"azureedge.net", "Azure CDN", ""},
+	{"azurefd.net", "Azure Front Door", "在 Front Door 路由规则中让 /.well-known/acme-challenge/* 以 HTTP 转发到源站且不缓存"},
+	{"b-cdn.net", "Bunny CDN", ""},
+	{"cdn77.org", "CDN77", ""},
+	{"incapdns.net", "Imperva", ""},
+	{"kunlunsl.com", "阿里云 CDN", ""},
+	{"kunlungr.com", "阿里云 CDN", ""},
+	{"alikunlun.com", "阿里云 CDN", ""},
+	{"cdn.dnsv1.com", "腾讯云 CDN", ""},
+	{"dsa.dnsv1.com", "腾讯云 ECDN", ""},
+	{"cdnhwc1.com", "华为云 CDN", ""},
+	{"a.bdydns.com", "百度智能云 CDN", ""},
+	{"wscdns.com", "网宿 CDN", ""},
+	{"qiniudns.com", "七牛云 CDN", ""},
+}
+
+// cloudflareHint Cloudflare 代理（橙色云朵）的设置建议
+const cloudflareHint = "在 Cloudflare 中将该记录改为仅 DNS（灰色云朵），或为 /.well-known/acme-challenge/* 添加规则关闭“始终使用 HTTPS”并绕过缓存"
+
+// cloudflareRanges Cloudflare 代理使用的地址段（https://www.cloudflare.com/ips/）。
+// 代理模式的记录直接解析为这些地址，没有 CNAME
+var cloudflareRanges = parseCIDRs(
+	"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22", "141.101.64.0/18",
+	"108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20", "197.234.240.0/22", "198.41.128.0/17",
+	"162.158.0.0/15", "104.16.0.0/13", "104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+	"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32", "2405:8100::/32",
+	"2a06:98c0::/29", "2c0f:f248::/32",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// DetectCDN 检查域名是否经过 CDN 代理（CNAME 指向常见 CDN，或解析到 Cloudflare 的代理地址），
+// 未检测到或无法解析时返回 nil
+func DetectCDN(domain string) *CDN {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cname, _ := net.DefaultResolver.LookupCNAME(ctx, domain)
+	addrs, _ := net.DefaultResolver.LookupIPAddr(ctx, domain)
+	ips := make([]net.IP, len(addrs))
+	for i, addr := range addrs {
+		ips[i] = addr.IP
+	}
+	return matchCDN(domain, cname, ips)
+}
+
+// matchCDN 按 CNAME 和解析地址判断 CDN
+func matchCDN(domain, cname string, ips []net.IP) *CDN {
+	cname = strings.ToLower(strings.TrimSuffix(cname, "."))
+	if cname != "" && cname != strings.ToLower(strings.TrimSuffix(domain, ".")) {
+		for _, c := range cdnCNAMEs {
+			if cname == c.suffix || strings.HasSuffix(cname, "."+c.suffix) {
+				hint := c.hint
+				switch {
+				case c.name == "Cloudflare":
+					hint = cloudflareHint
+				case hint == "":
+					hint = genericCDNHint
+				}
+				return &CDN{Name: c.name, Evidence: "CNAME " + cname, Hint: hint}
+			}
+		}
+	}
+
+	for _, ip := range ips {
+		for _, n := range cloudflareRanges {
+			if n.Contains(ip) {
+				return &CDN{Name: "Cloudflare", Evidence: "解析到 Cloudflare 代理地址 " + ip.String(), Hint: cloudflareHint}
+			}
+		}
+	}
+	return nil
+}
+
+// checkCDN 检查 http-01 验证的域名是否经过 CDN 代理：CA 的验证请求由 CDN 转发，
+// 可能被缓存、强制跳转 HTTPS 或拦截，到达不了本机
+func checkCDN(domains []string, opts Options) Result {
+	if !opts.HTTP01 {
+		return Result{Skipped: true, Detail: "未使用 http-01 验证"}
+	}
+	if config.GetACMEConfig().CDNFallback == "off" {
+		return Result{Skipped: true, Detail: "acme.cdn_fallback 为 off"}
+	}
+
+	result := Result{Detail: "未检测到 CDN 代理"}
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			continue
+		}
+		cdn := DetectCDN(domain)
+		if cdn == nil {
+			continue
+		}
+		result.Detail = "部分域名经过 CDN 代理"
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"%s 经过 %s（%s），http-01 验证请求由 CDN 转发，可能无法到达本机；建议使用 --dns 验证（配置 dns.provider 后自动切换），或%s",
+			domain, cdn.Name, cdn.Evidence, cdn.Hint))
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package preflight
+
+import (
+	"autocert/internal/config"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// 系统时间与 CA 服务器的允许偏差
+const (
+	clockSkewWarn  = time.Minute     // 超过时警告
+	clockSkewError = 5 * time.Minute // 超过时拒绝签发
+)
+
+// checkClock 比较系统时间与 CA 服务器 HTTP 响应的 Date 头
+// 时间偏差过大时 ACME 请求会因 JWS nonce/签名校验失败，签发的证书也可能被判定为尚未生效
+func checkClock(domains []string, opts Options) Result {
+	server := config.GetACMEConfig().Server
+	skew, err := ClockSkew(server)
+	if err != nil {
+		return Result{Skipped: true, Detail: fmt.Sprintf("无法获取 CA 服务器时间: %v", err)}
+	}
+
+	result := Result{Detail: fmt.Sprintf("与 CA 服务器相差 %s", skew.Round(time.Second))}
+	abs := skew
+	if abs < 0 {
+		abs = -abs
+	}
+
+	switch {
+	case abs > clockSkewError:
+		result.Err = fmt.Errorf("与 CA 服务器相差 %s，ACME 签名和证书有效期校验会失败，"+
+			"请先同步系统时间（例如 timedatectl set-ntp true、chronyc makestep 或 w32tm /resync）", skew.Round(time.Second))
+	case abs > clockSkewWarn:
+		result.Warnings = append(result.Warnings, fmt.Sprintf("系统时间与 CA 服务器相差 %s，建议启用 NTP 时间同步", skew.Round(time.Second)))
+	}
+	return result
+}
+
+// ClockSkew 返回本机时间减去服务器时间的差值（正数表示本机时间偏快）
+func ClockSkew(server string) (time.Duration, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	start := time.Now()
+	resp, err := client.Head(server)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	elapsed := time.Since(start)
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("响应中没有有效的 Date 头")
+	}
+
+	// Date 头精确到秒，以请求中点作为服务器生成响应的时间
+	local := start.Add(elapsed / 2)
+	return local.Sub(date), nil
+}
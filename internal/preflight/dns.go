@@ -0,0 +1,152 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Connectivity 本机的 IPv4/IPv6 出站连接情况
+type Connectivity struct {
+	IPv4 net.IP // 访问公网时使用的 IPv4 源地址，为空表示没有 IPv4 路由
+	IPv6 net.IP // 访问公网时使用的 IPv6 源地址，为空表示没有 IPv6 路由
+}
+
+// IPv6Only 本机是否只有 IPv6 连接
+func (c Connectivity) IPv6Only() bool {
+	return c.IPv4 == nil && c.IPv6 != nil
+}
+
+// String 连接情况说明
+func (c Connectivity) String() string {
+	switch {
+	case c.IPv4 != nil && c.IPv6 != nil:
+		return "双栈"
+	case c.IPv6 != nil:
+		return "仅 IPv6"
+	case c.IPv4 != nil:
+		return "仅 IPv4"
+	default:
+		return "无公网路由"
+	}
+}
+
+// DetectConnectivity 检测本机的出站连接：对公网地址建立 UDP "连接"只查询路由表，不发送数据
+func DetectConnectivity() Connectivity {
+	return Connectivity{
+		IPv4: routeSource("udp4", "8.8.8.8:53"),
+		IPv6: routeSource("udp6", "[2001:4860:4860::8888]:53"),
+	}
+}
+
+// routeSource 返回访问目标地址时使用的本机源地址，没有路由时返回 nil
+func routeSource(network, address string) net.IP {
+	conn, err := net.Dial(network, address)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.IsGlobalUnicast() {
+		return addr.IP
+	}
+	return nil
+}
+
+// LocalAddresses 返回本机网卡上的所有地址
+func LocalAddresses() map[string]bool {
+	result := make(map[string]bool)
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return result
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			result[ipNet.IP.String()] = true
+		}
+	}
+	return result
+}
+
+// checkDNS 检查 http-01 验证的域名解析：
+// Let's Encrypt 在域名有 AAAA 记录时优先通过 IPv6 验证，AAAA 指向其他主机或本机没有 IPv6 时验证失败；
+// 只有 IPv6 的主机需要 AAAA 记录；本机有公网 IPv4 时检查 A 记录是否指向本机
+func checkDNS(domains []string, opts Options) Result {
+	if !opts.HTTP01 {
+		return Result{Skipped: true, Detail: "未使用 http-01 验证"}
+	}
+	if len(domains) == 0 {
+		return Result{Skipped: true, Detail: "未指定域名，本机连接: " + DetectConnectivity().String()}
+	}
+
+	conn := DetectConnectivity()
+	local := LocalAddresses()
+	result := Result{Detail: "本机连接: " + conn.String()}
+
+	resolver := &net.Resolver{}
+	resolved := 0
+	for _, domain := range domains {
+		// 泛域名只能使用 dns-01 验证
+		if strings.HasPrefix(domain, "*.") {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		addrs, err := resolver.LookupIPAddr(ctx, domain)
+		cancel()
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("无法解析 %s: %v", domain, err))
+			continue
+		}
+		resolved++
+
+		var v4, v6 []string
+		for _, addr := range addrs {
+			if addr.IP.To4() != nil {
+				v4 = append(v4, addr.IP.String())
+			} else {
+				v6 = append(v6, addr.IP.String())
+			}
+		}
+
+		if len(v6) > 0 {
+			switch {
+			case conn.IPv6 == nil:
+				result.Warnings = append(result.Warnings, fmt.Sprintf(
+					"%s 有 AAAA 记录 %s，但本机没有 IPv6 连接；Let's Encrypt 优先通过 IPv6 验证，请删除 AAAA 记录或为本机配置 IPv6",
+					domain, strings.Join(v6, ", ")))
+			case !anyLocal(v6, local):
+				result.Warnings = append(result.Warnings, fmt.Sprintf(
+					"%s 的 AAAA 记录 %s 不指向本机（本机 IPv6: %s）；Let's Encrypt 优先通过 IPv6 验证，验证可能失败",
+					domain, strings.Join(v6, ", "), conn.IPv6))
+			}
+		} else if conn.IPv6Only() {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"本机只有 IPv6 连接，但 %s 没有 AAAA 记录，请添加指向 %s 的 AAAA 记录", domain, conn.IPv6))
+		}
+
+		// 本机 IPv4 为内网地址时（NAT 后）无法判断 A 记录是否正确
+		if len(v4) > 0 && conn.IPv4 != nil && !conn.IPv4.IsPrivate() && !anyLocal(v4, local) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"%s 的 A 记录 %s 不指向本机（本机 IPv4: %s），http-01 验证可能失败",
+				domain, strings.Join(v4, ", "), conn.IPv4))
+		}
+	}
+
+	if resolved == 0 && len(result.Warnings) > 0 {
+		result.Detail += "，域名解析失败"
+	}
+	return result
+}
+
+// anyLocal 地址中是否有本机地址
+func anyLocal(addrs []string, local map[string]bool) bool {
+	for _, addr := range addrs {
+		if local[addr] {
+			return true
+		}
+	}
+	return false
+}
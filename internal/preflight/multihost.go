@@ -0,0 +1,78 @@
+package preflight
+
+import (
+	"autocert/internal/config"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// checkMultiHost 检查 http-01 验证的域名是否轮询解析到多台主机（多条 A 或 AAAA 记录）：
+// CA 的验证请求可能到达没有挑战文件的主机，验证时好时坏。
+// 开启 challenge.shared 或使用 --expect-multi-host 确认所有主机都能响应挑战时不再警告
+func checkMultiHost(domains []string, opts Options) Result {
+	if !opts.HTTP01 {
+		return Result{Skipped: true, Detail: "未使用 http-01 验证"}
+	}
+
+	result := Result{Detail: "未检测到多主机解析"}
+	var multi []string
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			continue
+		}
+		v4, v6 := lookupHosts(domain)
+		switch {
+		case len(v4) > 1:
+			multi = append(multi, fmt.Sprintf("%s 有 %d 条 A 记录 %s", domain, len(v4), strings.Join(v4, ", ")))
+		case len(v6) > 1:
+			multi = append(multi, fmt.Sprintf("%s 有 %d 条 AAAA 记录 %s", domain, len(v6), strings.Join(v6, ", ")))
+		}
+	}
+	if len(multi) == 0 {
+		return result
+	}
+
+	switch {
+	case config.GetChallengeConfig().Shared:
+		result.Detail = "域名解析到多台主机，已开启 challenge.shared"
+	case opts.ExpectMultiHost:
+		result.Detail = "域名解析到多台主机，已确认所有主机都能响应挑战（--expect-multi-host）"
+	default:
+		result.Detail = "域名解析到多台主机"
+		for _, m := range multi {
+			result.Warnings = append(result.Warnings, m+
+				"（DNS 轮询到多台主机），CA 的验证请求可能到达没有挑战文件的主机，http-01 验证会间歇性失败；"+
+				"请开启 challenge.shared 并在每台主机运行 challenge-responder，或改用 --dns 验证；"+
+				"已确认所有主机都能响应挑战时使用 --expect-multi-host")
+		}
+	}
+	return result
+}
+
+// lookupHosts 返回域名解析到的 IPv4 和 IPv6 地址（去重），解析失败时返回空
+func lookupHosts(domain string) (v4, v6 []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, domain)
+	if err != nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool)
+	for _, addr := range addrs {
+		ip := addr.IP.String()
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		if addr.IP.To4() != nil {
+			v4 = append(v4, ip)
+		} else {
+			v6 = append(v6, ip)
+		}
+	}
+	return v4, v6
+}
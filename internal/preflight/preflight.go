@@ -0,0 +1,103 @@
+package preflight
+
+import (
+	"autocert/internal/logger"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Result 单项检查的结果
+type Result struct {
+	Name     string   // 检查项名称
+	Detail   string   // 检查结果说明
+	Warnings []string // 不阻止签发的问题
+	Err      error    // 阻止签发的问题
+	Skipped  bool     // 无法完成检查（例如网络不可用）
+}
+
+// Options 检查选项
+type Options struct {
+	HTTP01          bool // 使用 http-01 验证（webroot/standalone），需要检查域名解析是否指向本机
+	ExpectMultiHost bool // 已确认域名解析到的多台主机都能响应 http-01 挑战
+}
+
+// check 检查项
+type check struct {
+	name string
+	run  func(domains []string, opts Options) Result
+	once bool // 与域名无关，通过的结果在 onceTTL 内复用
+}
+
+// onceTTL 与域名无关的检查项通过后复用结果的时长：守护进程长期运行，系统时间可能之后才出现偏差
+const onceTTL = time.Hour
+
+// checks 所有检查项，按顺序执行
+var checks = []check{
+	{name: "系统时间", run: checkClock, once: true},
+	{name: "域名解析", run: checkDNS},
+	{name: "CDN 代理", run: checkCDN},
+	{name: "多主机解析", run: checkMultiHost},
+}
+
+// cachedResult 已通过的检查结果
+type cachedResult struct {
+	result    Result
+	checkedAt time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = make(map[string]cachedResult)
+)
+
+// Run 对一组域名执行所有检查，返回每项检查的结果
+func Run(domains []string, opts Options) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		results = append(results, runCheck(c, domains, opts))
+	}
+	return results
+}
+
+// runCheck 执行检查项。与域名无关的检查项复用本进程内 onceTTL 内通过的结果；
+// 未通过或无法完成的检查每次重新执行，修正问题（例如同步系统时间）后不需要重启守护进程
+func runCheck(c check, domains []string, opts Options) Result {
+	if !c.once {
+		result := c.run(domains, opts)
+		result.Name = c.name
+		return result
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if cached, ok := cache[c.name]; ok && time.Since(cached.checkedAt) < onceTTL {
+		return cached.result
+	}
+	result := c.run(domains, opts)
+	result.Name = c.name
+	if result.Err == nil && !result.Skipped {
+		cache[c.name] = cachedResult{result: result, checkedAt: time.Now()}
+	} else {
+		delete(cache, c.name)
+	}
+	return result
+}
+
+// Issuance 签发前检查：记录警告，存在阻止签发的问题时返回错误
+func Issuance(domains []string, opts Options) error {
+	var errs []error
+	for _, result := range Run(domains, opts) {
+		for _, warning := range result.Warnings {
+			logger.Warn(warning, "check", result.Name, "domains", domains)
+		}
+		if result.Skipped {
+			logger.Debug("跳过预检", "check", result.Name, "reason", result.Detail)
+		}
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Name, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
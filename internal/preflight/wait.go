@@ -0,0 +1,76 @@
+package preflight
+
+import (
+	"autocert/internal/logger"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WaitForDNS 等待所有域名解析到 expected 中的地址；expected 为空时只等待域名可以解析
+// （本机在 NAT 或负载均衡器后面时，公网地址不在网卡上，无法判断是否指向本机）。
+// 超时后返回仍未就绪的域名。泛域名只能使用 dns-01 验证，不检查
+func WaitForDNS(domains, expected []string, timeout, interval time.Duration) error {
+	want := make(map[string]bool)
+	for _, ip := range expected {
+		if parsed := net.ParseIP(strings.TrimSpace(ip)); parsed != nil {
+			want[parsed.String()] = true
+		}
+	}
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := pendingDomains(domains, want)
+		if len(pending) == 0 {
+			return nil
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			names := make([]string, 0, len(pending))
+			for domain, detail := range pending {
+				names = append(names, fmt.Sprintf("%s（%s）", domain, detail))
+			}
+			sort.Strings(names)
+			return fmt.Errorf("等待 %s 后域名仍未解析到预期地址: %s", timeout, strings.Join(names, ", "))
+		}
+
+		logger.Info("等待域名解析", "pending", len(pending), "retry_in", interval)
+		time.Sleep(interval)
+	}
+}
+
+// pendingDomains 返回尚未解析到期望地址的域名及其当前解析结果，want 为空时只要求能够解析
+func pendingDomains(domains []string, want map[string]bool) map[string]string {
+	pending := make(map[string]string)
+	for _, domain := range domains {
+		if strings.HasPrefix(domain, "*.") {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, domain)
+		cancel()
+		if err != nil {
+			pending[domain] = "无法解析"
+			continue
+		}
+
+		var resolved []string
+		matched := len(want) == 0
+		for _, addr := range addrs {
+			resolved = append(resolved, addr.IP.String())
+			if want[addr.IP.String()] {
+				matched = true
+			}
+		}
+		if !matched {
+			pending[domain] = strings.Join(resolved, ", ")
+		}
+	}
+	return pending
+}
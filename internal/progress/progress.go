@@ -0,0 +1,185 @@
+package progress
+
+import (
+	"autocert/internal/console"
+	"autocert/internal/logger"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// output 进度显示输出（标准错误，避免干扰命令的标准输出）
+var output io.Writer = os.Stderr
+
+// interactive 标准错误是否为交互式终端，不是终端时退化为普通日志
+var interactive = console.IsTerminal(os.Stderr)
+
+// spinnerFrames 旋转动画帧（Windows 控制台使用 ASCII）
+func spinnerFrames() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"|", "/", "-", "\\"}
+	}
+	return []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+}
+
+// Spinner 耗时步骤的旋转指示器，显示已用时间
+type Spinner struct {
+	message string
+	start   time.Time
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// Start 开始显示旋转指示器
+func Start(message string) *Spinner {
+	s := &Spinner{
+		message: message,
+		start:   time.Now(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if !interactive {
+		logger.Info(message)
+		close(s.done)
+		return s
+	}
+
+	go s.run()
+	return s
+}
+
+func (s *Spinner) run() {
+	defer close(s.done)
+
+	frames := spinnerFrames()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 0; ; i++ {
+		fmt.Fprintf(output, "\r%s %s (%s)\033[K", frames[i%len(frames)], s.message, elapsed(s.start))
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop 结束旋转指示器，显示结果和总耗时
+func (s *Spinner) Stop(err error) {
+	if !interactive {
+		if err != nil {
+			logger.Error(s.message+"失败", "elapsed", elapsed(s.start), "error", err)
+		} else {
+			logger.Info(s.message+"完成", "elapsed", elapsed(s.start))
+		}
+		return
+	}
+
+	close(s.stop)
+	<-s.done
+
+	if err != nil {
+		fmt.Fprintf(output, "\r✗ %s (%s)\033[K\n", s.message, elapsed(s.start))
+	} else {
+		fmt.Fprintf(output, "\r✓ %s (%s)\033[K\n", s.message, elapsed(s.start))
+	}
+}
+
+// Bar 已知总数的进度条
+type Bar struct {
+	mu      sync.Mutex
+	message string
+	total   int
+	current int
+	start   time.Time
+}
+
+// NewBar 创建进度条
+func NewBar(message string, total int) *Bar {
+	b := &Bar{message: message, total: total, start: time.Now()}
+	if !interactive {
+		logger.Info(message, "total", total)
+	} else {
+		b.render()
+	}
+	return b
+}
+
+// Increment 进度加一
+func (b *Bar) Increment() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current++
+	if interactive {
+		b.render()
+	}
+}
+
+// Step 进度加一，并在进度条上方显示该项的结果
+func (b *Bar) Step(name string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current++
+	if !interactive {
+		if err != nil {
+			logger.Error(b.message, "item", name, "progress", fmt.Sprintf("%d/%d", b.current, b.total), "error", err)
+		} else {
+			logger.Info(b.message, "item", name, "progress", fmt.Sprintf("%d/%d", b.current, b.total))
+		}
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintf(output, "\r✗ %s: %v\033[K\n", name, err)
+	} else {
+		fmt.Fprintf(output, "\r✓ %s\033[K\n", name)
+	}
+	b.render()
+}
+
+// Finish 结束进度条
+func (b *Bar) Finish(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !interactive {
+		if err != nil {
+			logger.Error(b.message+"失败", "done", b.current, "total", b.total, "elapsed", elapsed(b.start), "error", err)
+		} else {
+			logger.Info(b.message+"完成", "total", b.total, "elapsed", elapsed(b.start))
+		}
+		return
+	}
+
+	b.render()
+	fmt.Fprintln(output)
+}
+
+// render 绘制进度条
+func (b *Bar) render() {
+	const width = 30
+
+	filled := width
+	if b.total > 0 {
+		filled = width * b.current / b.total
+	}
+	if filled > width {
+		filled = width
+	}
+
+	fmt.Fprintf(output, "\r%s [%s%s] %d/%d (%s)\033[K", b.message,
+		strings.Repeat("=", filled), strings.Repeat(" ", width-filled), b.current, b.total, elapsed(b.start))
+}
+
+// elapsed 格式化已用时间
+func elapsed(start time.Time) string {
+	return time.Since(start).Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,227 @@
+package provision
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/preflight"
+	"autocert/internal/renewal"
+	"autocert/internal/scheduler"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrAlreadyProvisioned 标记文件已存在，首次启动配置已执行过
+var ErrAlreadyProvisioned = errors.New("已完成首次启动配置")
+
+// 执行结果
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Options 执行选项
+type Options struct {
+	Force       bool // 忽略标记文件重新执行
+	SkipDNSWait bool // 不等待域名解析
+}
+
+// CertResult 单张证书的签发结果
+type CertResult struct {
+	Domains []string `json:"domains"`
+	Status  string   `json:"status"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Report 执行报告，同时上报到配置的 Webhook
+type Report struct {
+	Host         string       `json:"host"`
+	Status       string       `json:"status"`
+	StartedAt    time.Time    `json:"started_at"`
+	FinishedAt   time.Time    `json:"finished_at"`
+	DNS          string       `json:"dns"`
+	Certificates []CertResult `json:"certificates"`
+	Schedule     string       `json:"schedule"`
+	Error        string       `json:"error,omitempty"`
+}
+
+// MarkerPath 标记文件路径
+func MarkerPath() string {
+	if marker := config.GetProvisionConfig().Marker; marker != "" {
+		return marker
+	}
+	return filepath.Join(config.GetConfigDir(), "provisioned")
+}
+
+// Run 执行首次启动配置：等待域名解析生效、签发所有证书（并配置 Web 服务器）、
+// 安装续期定时任务，成功后写入标记文件；无论成功与否都会上报到 Webhook
+func Run(options Options) (*Report, error) {
+	cfg := config.GetProvisionConfig()
+
+	if !options.Force {
+		if _, err := os.Stat(MarkerPath()); err == nil {
+			return nil, ErrAlreadyProvisioned
+		}
+	}
+
+	report := &Report{StartedAt: time.Now(), Status: StatusSuccess, DNS: StatusSkipped, Schedule: StatusSkipped}
+	report.Host, _ = os.Hostname()
+
+	err := run(cfg, options, report)
+	report.FinishedAt = time.Now()
+	if err != nil {
+		report.Status = StatusFailed
+		report.Error = err.Error()
+	}
+
+	if cfg.Webhook != "" {
+		if postErr := postReport(cfg, report); postErr != nil {
+			logger.Warn("上报首次启动配置结果失败", "webhook", cfg.Webhook, "error", postErr)
+		}
+	}
+
+	if err == nil {
+		if writeErr := writeMarker(report); writeErr != nil {
+			logger.Warn("写入标记文件失败", "path", MarkerPath(), "error", writeErr)
+		}
+	}
+	return report, err
+}
+
+// run 按顺序执行各步骤，结果记录在报告中
+func run(cfg config.ProvisionConfig, options Options, report *Report) error {
+	certs := certificates(cfg)
+	if len(certs) == 0 {
+		return fmt.Errorf("没有需要签发的证书，请配置 provision.certificates 或 domains")
+	}
+
+	// 1. 等待域名解析
+	if !options.SkipDNSWait {
+		var domains []string
+		for _, c := range certs {
+			domains = append(domains, c.Domains...)
+		}
+		logger.Info("等待域名解析", "domains", domains, "timeout", cfg.DNSTimeout)
+		if err := preflight.WaitForDNS(domains, cfg.ExpectedIPs, cfg.DNSTimeout, cfg.DNSInterval); err != nil {
+			report.DNS = StatusFailed
+			return err
+		}
+		report.DNS = StatusSuccess
+	}
+
+	// 2. 签发证书并配置 Web 服务器
+	var failed []string
+	for _, c := range certs {
+		result := CertResult{Domains: c.Domains, Status: StatusSuccess}
+		if err := renewal.Issue(c.Domains, renewal.IssueOptions{Webroot: c.Webroot}); err != nil {
+			result.Status = StatusFailed
+			result.Error = err.Error()
+			failed = append(failed, c.Domains[0])
+			logger.Error("首次启动签发证书失败", "domains", c.Domains, "error", err)
+		}
+		report.Certificates = append(report.Certificates, result)
+	}
+
+	// 3. 安装续期定时任务（已安装时保留）
+	if cfg.Schedule {
+		if err := installSchedule(); err != nil {
+			report.Schedule = StatusFailed
+			return err
+		}
+		report.Schedule = StatusSuccess
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d 张证书签发失败: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// certificates 要签发的证书：provision.certificates，未配置时为 domains 中的每个域名各签发一张
+func certificates(cfg config.ProvisionConfig) []config.ProvisionCertificate {
+	var certs []config.ProvisionCertificate
+	for _, c := range cfg.Certificates {
+		if len(c.Domains) > 0 {
+			certs = append(certs, c)
+		}
+	}
+	current := config.Current()
+	if len(certs) > 0 || current == nil {
+		return certs
+	}
+
+	for _, domain := range current.Domains {
+		if domain.Domain != "" {
+			certs = append(certs, config.ProvisionCertificate{Domains: []string{domain.Domain}})
+		}
+	}
+	return certs
+}
+
+// installSchedule 安装续期定时任务
+func installSchedule() error {
+	sched := scheduler.NewScheduler()
+	if sched.IsInstalled(scheduler.DefaultTaskName) {
+		logger.Info("续期定时任务已安装", "taskName", scheduler.DefaultTaskName)
+		return nil
+	}
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取执行文件路径失败: %w", err)
+	}
+	if err := sched.Install(scheduler.DefaultTaskName, execPath, scheduler.DefaultSchedule); err != nil {
+		return fmt.Errorf("安装定时任务失败: %w", err)
+	}
+	return nil
+}
+
+// writeMarker 写入标记文件（内容为执行报告）
+func writeMarker(report *Report) error {
+	path := MarkerPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// postReport 以 JSON 格式 POST 执行报告
+func postReport(cfg config.ProvisionConfig, report *Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cfg.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.WebhookToken != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.WebhookToken)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/secret"
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client 最小的 Redis 客户端，供事件发布（PUBLISH）和挑战存储（SET、GET、DEL）共用。
+// 直接实现 RESP 协议，每次操作使用一个短连接：连接、认证、选择数据库后执行一条命令
+type Client struct {
+	address  string
+	useTLS   bool
+	username string
+	password string
+	db       int
+	timeout  time.Duration
+}
+
+// NewClient 按配置创建客户端，密码支持 env:变量名 或 file:文件路径
+func NewClient(cfg config.RedisConfig) (*Client, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("未配置 Redis 地址")
+	}
+	password, err := secret.Resolve(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{
+		address:  cfg.Address,
+		useTLS:   cfg.TLS,
+		username: cfg.Username,
+		password: password,
+		db:       cfg.DB,
+		timeout:  timeout,
+	}, nil
+}
+
+// Do 执行一条命令，返回字符串回复（nil 表示空回复）
+func (c *Client) Do(args ...string) (*string, error) {
+	dialer := &net.Dialer{Timeout: c.timeout}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		host, _, _ := net.SplitHostPort(c.address)
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.address, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+	} else {
+		conn, err = dialer.Dial("tcp", c.address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	r := bufio.NewReader(conn)
+	if c.password != "" {
+		auth := []string{"AUTH", c.password}
+		if c.username != "" {
+			auth = []string{"AUTH", c.username, c.password}
+		}
+		if _, err := command(conn, r, auth...); err != nil {
+			return nil, fmt.Errorf("Redis 认证失败: %w", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := command(conn, r, "SELECT", strconv.Itoa(c.db)); err != nil {
+			return nil, fmt.Errorf("选择 Redis 数据库失败: %w", err)
+		}
+	}
+
+	reply, err := command(conn, r, args...)
+	if err != nil {
+		return nil, fmt.Errorf("Redis %s 失败: %w", args[0], err)
+	}
+	return reply, nil
+}
+
+// command 以 RESP 数组发送命令并读取一条回复
+func command(w io.Writer, r *bufio.Reader, args ...string) (*string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&sb, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(w, sb.String()); err != nil {
+		return nil, err
+	}
+	return readReply(r)
+}
+
+// readReply 读取简单字符串、错误、整数和批量字符串回复
+func readReply(r *bufio.Reader) (*string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("无效的 Redis 回复")
+	}
+
+	switch line[0] {
+	case '+', ':':
+		s := line[1:]
+		return &s, nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("无效的 Redis 回复: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		s := string(buf[:n])
+		return &s, nil
+	default:
+		return nil, fmt.Errorf("不支持的 Redis 回复: %q", line)
+	}
+}
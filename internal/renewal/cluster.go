@@ -0,0 +1,53 @@
+package renewal
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/storage"
+)
+
+// installLocked 续期证书。开启 storage.lock 时同一张证书同时只有一个节点续期：
+// 获取锁后先从共享存储取回证书，其他节点刚续期过（证书有变化且不再需要续期）时只重新部署。
+// deployed 表示证书由其他节点续期、本节点只做了部署。续约失败、锁已丢失时停止续期，
+// 不再写入共享存储或部署，避免覆盖已接手的其他节点的结果
+func installLocked(stored cert.StoredCert, manager cert.Renewable) (deployed bool, err error) {
+	locker, err := storage.OpenLocker()
+	if err != nil {
+		return false, err
+	}
+	if locker == nil {
+		return false, manager.Install()
+	}
+
+	lock, _, err := locker.Lock("renew/" + stored.Name)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := lock.Unlock(); err != nil {
+			logger.Warn("释放分布式锁失败", "cert", stored.Name, "error", err)
+		}
+	}()
+	manager.SetInterrupt(lock.Err)
+
+	backend, err := storage.Open()
+	if err != nil {
+		return false, err
+	}
+	changed, err := cert.PullCertificate(backend, config.GetCertDir(), stored.Name)
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		if needsRenewal, _, err := manager.NeedsRenewal(); err == nil && !needsRenewal {
+			logger.Info("证书已由其他节点续期，从共享存储部署", "cert", stored.Name)
+			if err := lock.Err(); err != nil {
+				return true, err
+			}
+			return true, manager.Deploy()
+		}
+	}
+
+	return false, manager.Install()
+}
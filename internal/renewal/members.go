@@ -0,0 +1,140 @@
+package renewal
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/webserver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// memberChange 配置 domains[].sans 与证书当前域名不一致时的变更
+type memberChange struct {
+	target  cert.StoredCert // 按新的域名集合签发的证书
+	added   []string
+	removed []string
+}
+
+// pendingMemberChange 返回证书需要的 SAN 成员变更，未配置 sans 或没有变化时返回 nil
+func pendingMemberChange(stored cert.StoredCert) *memberChange {
+	domains := config.GetCertDomains(stored.Domains[0])
+	if domains == nil {
+		return nil
+	}
+	added, removed := cert.MemberChanges(stored.Domains, domains)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return &memberChange{target: stored.WithDomains(domains), added: added, removed: removed}
+}
+
+// superseded 旧证书目录是否已被新证书取代（证书目录名改变且新证书已存在）。
+// 旧目录仍被链接时不会删除，不再续期
+func (c *memberChange) superseded(stored cert.StoredCert) bool {
+	if c.target.Dir == stored.Dir {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(c.target.Dir, "cert.pem"))
+	return err == nil
+}
+
+// summary 变更说明，例如 +www.example.com -old.example.com
+func (c *memberChange) summary() string {
+	var parts []string
+	for _, domain := range c.added {
+		parts = append(parts, "+"+domain)
+	}
+	for _, domain := range c.removed {
+		parts = append(parts, "-"+domain)
+	}
+	return strings.Join(parts, " ")
+}
+
+// replaceMembers 按配置的新域名集合重新签发证书：先归档旧证书（签发失败时删除这次的归档，
+// 重试不会留下重复的副本），签发并部署新证书后停用新增成员原有的单站点 Nginx 配置（避免 server_name 重复），
+// 并为移除的成员恢复单站点配置；证书目录名改变时（单域名证书和多域名证书之间转换）删除旧证书目录
+func replaceMembers(stored cert.StoredCert, change *memberChange) error {
+	logger.Info("证书域名已变更，按新的域名集合重新签发", "cert", stored.Name,
+		"added", change.added, "removed", change.removed)
+
+	archiveDir, err := stored.Archive()
+	if err != nil {
+		return fmt.Errorf("归档旧证书失败: %w", err)
+	}
+
+	manager := change.target.Manager(config.GetACMEConfig().Email)
+	if _, err := installLocked(change.target, manager); err != nil {
+		if removeErr := os.RemoveAll(archiveDir); removeErr != nil {
+			logger.Warn("删除未使用的归档失败", "archive", archiveDir, "error", removeErr)
+		}
+		return err
+	}
+
+	if strings.EqualFold(config.GetWebServerConfig().Type, "nginx") {
+		reload := false
+		if len(change.added) > 0 {
+			var names []string
+			for _, domain := range change.added {
+				if domain != stored.Domains[0] {
+					names = append(names, domain)
+				}
+			}
+			disabled, err := webserver.DisableManagedSites(names)
+			if err != nil {
+				logger.Warn("停用新增域名的单站点配置失败", "domains", names, "error", err)
+			}
+			reload = reload || len(disabled) > 0
+		}
+		if restored := restoreRemovedSites(change.removed); restored {
+			reload = true
+		}
+		if reload {
+			if err := webserver.ReloadNginx(); err != nil {
+				logger.Warn("重载 Nginx 失败", "error", err)
+			}
+		}
+	} else {
+		warnRemovedMembers(change.removed)
+	}
+
+	if change.target.Dir != stored.Dir {
+		return stored.Retire()
+	}
+	return nil
+}
+
+// restoreRemovedSites 移出证书的成员不再由主域名的站点配置提供（server_name 已更新），
+// 自己仍有有效证书时重新启用加入时停用的单站点配置，否则提示单独签发。返回是否启用了配置
+func restoreRemovedSites(removed []string) bool {
+	var names []string
+	for _, domain := range removed {
+		own := cert.StoredCert{Name: domain, Dir: filepath.Join(config.GetCertDir(), domain), Domains: []string{domain}}
+		if details, err := own.Details(); err != nil || !details.NotAfter.After(clock.Now()) {
+			logger.Warn("域名已移出证书，没有有效的单域名证书，HTTPS 访问将使用默认站点",
+				"domain", domain, "hint", "autocert install -d "+domain)
+			continue
+		}
+		names = append(names, domain)
+	}
+	if len(names) == 0 {
+		return false
+	}
+
+	enabled, err := webserver.EnableManagedSites(names)
+	if err != nil {
+		logger.Warn("重新启用移出域名的单站点配置失败", "domains", names, "error", err)
+	}
+	return len(enabled) > 0
+}
+
+// warnRemovedMembers 非 Nginx 时只更新主域名的站点配置，提示移出的域名需要单独处理
+func warnRemovedMembers(removed []string) {
+	for _, domain := range removed {
+		logger.Warn("域名已移出证书，主站点配置不再包含该域名，如需继续提供 HTTPS 请单独签发",
+			"domain", domain, "hint", "autocert install -d "+domain)
+	}
+}
@@ -0,0 +1,252 @@
+package renewal
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/clock"
+	"autocert/internal/config"
+	"autocert/internal/events"
+	"autocert/internal/logger"
+	"autocert/internal/report"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Options 续期选项
+type Options struct {
+	Force           bool // 忽略续期阈值强制续期
+	RenewBeforeDays int  // 覆盖配置的续期天数（0 表示使用配置）
+
+	PreferredChain string // 覆盖配置的首选证书链（空表示使用配置）
+	OverwriteDrift bool   // 覆盖手工修改的站点配置
+
+	RenewedExitCode bool // 有证书续期成功时使用退出码 3（默认 0）
+}
+
+// RenewAll 检查并续期证书目录下的所有证书，返回续期报告
+func RenewAll(options Options) (*report.RenewalReport, error) {
+	logger.Info("开始续期所有证书", "force", options.Force)
+
+	certs, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		return nil, fmt.Errorf("读取证书目录失败: %w", err)
+	}
+
+	renewalReport := report.NewRenewalReport()
+
+	for _, stored := range certs {
+		entry := report.RenewalEntry{Name: stored.Name, Domains: stored.Domains}
+
+		// 配置的 SAN 成员变更时不论到期时间都按新的域名集合重新签发
+		if change := pendingMemberChange(stored); change != nil {
+			if change.superseded(stored) {
+				entry.Reason = fmt.Sprintf("已由证书 %s 取代（旧证书仍被链接，未删除）", change.target.Name)
+				renewalReport.AddSkipped(entry)
+				continue
+			}
+			entry.Name, entry.Domains = change.target.Name, change.target.Domains
+			if err := replaceMembers(stored, change); err != nil {
+				entry.Reason = fmt.Sprintf("SAN 成员变更（%s）后重新签发失败: %v", change.summary(), err)
+				renewalReport.AddFailed(entry)
+				logger.Error("按新的域名集合重新签发失败", "cert", stored.Name, "error", err)
+				continue
+			}
+			entry.Reason = "SAN 成员变更: " + change.summary()
+			if certInfo, err := change.target.Manager("").GetCertInfo(); err == nil {
+				entry.Expiry = &certInfo.ExpiryDate
+			}
+			renewalReport.AddRenewed(entry)
+			continue
+		}
+
+		manager := stored.Manager("")
+		if options.RenewBeforeDays > 0 {
+			manager.SetRenewBeforeDays(options.RenewBeforeDays)
+		}
+		if options.PreferredChain != "" {
+			manager.SetPreferredChain(options.PreferredChain)
+		}
+		manager.SetOverwriteDrift(options.OverwriteDrift)
+
+		// 检查证书和私钥是否一致，损坏时告警并按配置自动重新签发
+		if err := stored.Verify(); err != nil {
+			logger.Error("证书文件损坏或与私钥不匹配", "cert", stored.Name, "error", err)
+			events.Emit(events.Event{
+				Type:    events.CertCorrupted,
+				Domains: stored.Domains,
+				Message: "证书文件损坏或与私钥不匹配",
+				Error:   err.Error(),
+			})
+
+			if !config.GetSelfHeal() {
+				entry.Reason = err.Error() + "（配置 self_heal: true 可自动重新签发）"
+				renewalReport.AddFailed(entry)
+				continue
+			}
+
+			logger.Info("自动重新签发证书", "cert", stored.Name)
+			if _, err := installLocked(stored, manager); err != nil {
+				entry.Reason = fmt.Sprintf("自动重新签发失败: %v", err)
+				renewalReport.AddFailed(entry)
+				logger.Error("自动重新签发失败", "cert", stored.Name, "error", err)
+				continue
+			}
+			if certInfo, err := manager.GetCertInfo(); err == nil {
+				entry.Expiry = &certInfo.ExpiryDate
+			}
+			renewalReport.AddRenewed(entry)
+			continue
+		}
+
+		needsRenewal, certInfo, err := manager.NeedsRenewal()
+		if err != nil {
+			entry.Reason = err.Error()
+			renewalReport.AddFailed(entry)
+			logger.Error("检查证书失败", "cert", stored.Name, "error", err)
+			continue
+		}
+		entry.Expiry = &certInfo.ExpiryDate
+
+		if !needsRenewal && !options.Force {
+			entry.Reason = fmt.Sprintf("未到续期时间，%s 到期（%s）", clock.Date(certInfo.ExpiryDate), clock.Relative(certInfo.ExpiryDate))
+			renewalReport.AddSkipped(entry)
+			continue
+		}
+
+		deployed, err := installLocked(stored, manager)
+		if err != nil {
+			entry.Reason = err.Error()
+			renewalReport.AddFailed(entry)
+			logger.Error("证书续期失败", "cert", stored.Name, "error", err)
+			continue
+		}
+		if deployed {
+			entry.Reason = "已由其他节点续期，从共享存储部署"
+		}
+
+		if certInfo, err := manager.GetCertInfo(); err == nil {
+			entry.Expiry = &certInfo.ExpiryDate
+		}
+		renewalReport.AddRenewed(entry)
+		logger.Info("证书续期成功", "cert", stored.Name)
+	}
+
+	renewalReport.Finish(options.RenewedExitCode)
+
+	if reportPath, err := renewalReport.Write(config.GetLogDir()); err != nil {
+		logger.Warn("写入续期报告失败", "error", err)
+	} else {
+		logger.Info("续期报告已写入", "path", reportPath)
+	}
+
+	return renewalReport, nil
+}
+
+// NextDue 返回最早进入续期窗口的证书名称和续期时间，没有可读取的证书时返回零值
+func NextDue() (string, time.Time, error) {
+	certs, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("读取证书目录失败: %w", err)
+	}
+
+	var name string
+	var earliest time.Time
+	for _, stored := range certs {
+		// 无法读取的证书由每轮续期检查处理，不参与调度
+		certInfo, err := stored.Manager("").GetCertInfo()
+		if err != nil {
+			continue
+		}
+		due := certInfo.ExpiryDate.Add(-certInfo.RenewalThreshold(config.GetRenewBeforeDays(stored.Domains[0])))
+		if earliest.IsZero() || due.Before(earliest) {
+			name, earliest = stored.Name, due
+		}
+	}
+	return name, earliest, nil
+}
+
+// RenewDomain 续期（或首次签发）单个域名的证书
+// 已存在的证书（包括以该域名为主域名的多域名证书）按原域名集合续期，配置的 sans 变更时按新的集合重新签发；
+// 不存在时按配置的 ACME 邮箱和 Web 服务器类型签发新证书
+func RenewDomain(domain string, force bool) error {
+	certs, err := cert.ListCertificates(config.GetCertDir())
+	if err != nil {
+		return fmt.Errorf("读取证书目录失败: %w", err)
+	}
+
+	for _, stored := range certs {
+		if stored.Domains[0] != domain {
+			continue
+		}
+
+		if change := pendingMemberChange(stored); change != nil {
+			if change.superseded(stored) {
+				continue
+			}
+			return replaceMembers(stored, change)
+		}
+
+		manager := stored.Manager(config.GetACMEConfig().Email)
+		if !force {
+			needsRenewal, _, err := manager.NeedsRenewal()
+			if err != nil {
+				return err
+			}
+			if !needsRenewal {
+				return nil
+			}
+		}
+		_, err := installLocked(stored, manager)
+		return err
+	}
+
+	logger.Info("证书不存在，签发新证书", "domain", domain)
+	return Issue([]string{domain}, IssueOptions{})
+}
+
+// IssueOptions 签发选项
+type IssueOptions struct {
+	Email   string // 为空时使用配置 acme.email
+	Webroot string // 不为空时使用 Webroot 模式验证
+
+	Challenge cert.ChallengeType // 验证方式（默认 Webroot），泛域名总是使用 DNS 验证
+}
+
+// Issue 为一组域名签发新证书（多个域名时签发 SAN 证书）
+func Issue(domains []string, options IssueOptions) error {
+	if len(domains) == 0 {
+		return fmt.Errorf("域名列表为空")
+	}
+
+	email := options.Email
+	if email == "" {
+		email = config.GetACMEConfig().Email
+	}
+
+	webServerType, err := cert.ParseWebServerType(config.GetWebServerConfig().Type)
+	if err != nil {
+		return err
+	}
+
+	if len(domains) == 1 {
+		manager := cert.NewManager(domains[0], email)
+		manager.SetWebServer(webServerType)
+		if strings.HasPrefix(domains[0], "*.") {
+			manager.SetChallengeType(cert.ChallengeDNS)
+		} else {
+			manager.SetChallengeType(options.Challenge)
+			if options.Challenge == cert.ChallengeWebroot && options.Webroot != "" {
+				manager.SetWebrootPath(options.Webroot)
+			}
+		}
+		return manager.Install()
+	}
+
+	manager := cert.NewMultiDomainManager(domains, email)
+	manager.SetWebServer(webServerType)
+	manager.SetChallengeType(options.Challenge)
+	if options.Challenge == cert.ChallengeWebroot && options.Webroot != "" {
+		manager.SetWebrootPath(options.Webroot)
+	}
+	return manager.Install()
+}
@@ -0,0 +1,104 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Nagios 插件退出码
+const (
+	NagiosOK       = 0
+	NagiosWarning  = 1
+	NagiosCritical = 2
+	NagiosUnknown  = 3
+)
+
+// nagiosStatusNames Nagios 状态名称
+var nagiosStatusNames = map[int]string{
+	NagiosOK:       "OK",
+	NagiosWarning:  "WARNING",
+	NagiosCritical: "CRITICAL",
+	NagiosUnknown:  "UNKNOWN",
+}
+
+// CheckThresholds 监控告警阈值（剩余天数）
+type CheckThresholds struct {
+	Warning  int
+	Critical int
+}
+
+// Check 根据阈值判断单个证书的状态
+func (t CheckThresholds) Check(row InventoryRow) int {
+	switch {
+	case row.DaysLeft < t.Critical:
+		return NagiosCritical
+	case row.DaysLeft < t.Warning:
+		return NagiosWarning
+	default:
+		return NagiosOK
+	}
+}
+
+// WriteNagios 输出 Nagios 插件格式结果（状态行 + 性能数据），返回插件退出码。
+// staleAgents 为控制节点上失联的 agent，存在时至少为 WARNING
+func WriteNagios(w io.Writer, rows []InventoryRow, staleAgents []string, thresholds CheckThresholds) int {
+	if len(rows) == 0 && len(staleAgents) == 0 {
+		fmt.Fprintln(w, "AUTOCERT UNKNOWN - 未找到证书")
+		return NagiosUnknown
+	}
+
+	status := NagiosOK
+	var problems []string
+	var perfdata []string
+
+	for _, row := range rows {
+		rowStatus := thresholds.Check(row)
+		if rowStatus > status {
+			status = rowStatus
+		}
+		if rowStatus != NagiosOK {
+			problems = append(problems, fmt.Sprintf("%s %d 天后到期", row.Name, row.DaysLeft))
+		}
+
+		// 'label'=value;warn;crit;min;max（剩余天数，无单位）
+		perfdata = append(perfdata, fmt.Sprintf("'%s'=%d;%d;%d;0;",
+			strings.ReplaceAll(row.Name, "'", "''"), row.DaysLeft, thresholds.Warning, thresholds.Critical))
+	}
+
+	if len(staleAgents) > 0 {
+		if status < NagiosWarning {
+			status = NagiosWarning
+		}
+		problems = append(problems, fmt.Sprintf("agent %s 失联", strings.Join(staleAgents, "、")))
+	}
+
+	summary := fmt.Sprintf("%d 张证书均有效", len(rows))
+	if len(problems) > 0 {
+		summary = strings.Join(problems, ", ")
+	}
+
+	fmt.Fprintf(w, "AUTOCERT %s - %s | %s\n", nagiosStatusNames[status], summary, strings.Join(perfdata, " "))
+	return status
+}
+
+// WriteNagiosUnknown 输出 UNKNOWN 状态行（检查本身出错时使用）
+func WriteNagiosUnknown(w io.Writer, err error) int {
+	fmt.Fprintf(w, "AUTOCERT UNKNOWN - %v\n", err)
+	return NagiosUnknown
+}
+
+// WriteZabbixDiscovery 输出 Zabbix 低级发现（LLD）JSON
+func WriteZabbixDiscovery(w io.Writer, rows []InventoryRow) error {
+	data := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		data = append(data, map[string]string{
+			"{#CERTNAME}": row.Name,
+			"{#DOMAIN}":   row.Domain,
+			"{#SANS}":     strings.Join(row.SANs, ","),
+		})
+	}
+
+	return json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
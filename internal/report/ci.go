@@ -0,0 +1,52 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IssueResultFileName ci issue 在输出目录中写入的结果文件名
+const IssueResultFileName = "result.json"
+
+// IssueResult ci issue 的签发结果，供流水线后续步骤读取
+type IssueResult struct {
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+	Domains    []string  `json:"domains"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// 证书信息（签发成功时）
+	Serial            string     `json:"serial,omitempty"`
+	Issuer            string     `json:"issuer,omitempty"`
+	NotBefore         *time.Time `json:"not_before,omitempty"`
+	NotAfter          *time.Time `json:"not_after,omitempty"`
+	FingerprintSHA256 string     `json:"fingerprint_sha256,omitempty"`
+
+	// Files 输出的证书文件，相对于输出目录
+	Files []string `json:"files"`
+}
+
+// Write 将结果以 JSON 格式写入 path（先写临时文件再重命名）
+func (r *IssueResult) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
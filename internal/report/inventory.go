@@ -0,0 +1,179 @@
+package report
+
+import (
+	"autocert/internal/cert"
+	"autocert/internal/clock"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// 证书清单导出格式
+const (
+	FormatTable = "table"
+	FormatCSV   = "csv"
+	FormatHTML  = "html"
+)
+
+// inventoryHeader 清单列名
+var inventoryHeader = []string{"证书", "域名", "SAN", "颁发者", "序列号", "签发时间", "到期时间", "剩余天数", "密钥类型", "部署位置"}
+
+// InventoryRow 清单中的一行
+type InventoryRow struct {
+	Name        string
+	Domain      string
+	SANs        []string
+	Issuer      string
+	Serial      string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	DaysLeft    int
+	KeyType     string
+	Deployments []string
+}
+
+// Status 证书状态
+func (r InventoryRow) Status() string {
+	switch {
+	case r.DaysLeft < 0:
+		return "已过期"
+	case r.DaysLeft <= 30:
+		return "即将到期"
+	default:
+		return "有效"
+	}
+}
+
+// NewInventoryRow 根据证书详细信息生成清单行
+func NewInventoryRow(details *cert.CertDetails) InventoryRow {
+	domain := details.CommonName
+	if domain == "" && len(details.Domains) > 0 {
+		domain = details.Domains[0]
+	}
+
+	return InventoryRow{
+		Name:        details.Name,
+		Domain:      domain,
+		SANs:        details.Domains,
+		Issuer:      details.Issuer,
+		Serial:      details.Serial,
+		NotBefore:   details.NotBefore,
+		NotAfter:    details.NotAfter,
+		DaysLeft:    int(clock.Until(details.NotAfter).Hours() / 24),
+		KeyType:     details.KeyType,
+		Deployments: details.Deployments,
+	}
+}
+
+// WriteInventory 按指定格式输出证书清单
+func WriteInventory(w io.Writer, format string, rows []InventoryRow) error {
+	switch format {
+	case FormatCSV:
+		return writeInventoryCSV(w, rows)
+	case FormatHTML:
+		return writeInventoryHTML(w, rows)
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// writeInventoryCSV 输出 CSV 格式清单，多值字段以分号分隔
+func writeInventoryCSV(w io.Writer, rows []InventoryRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(inventoryHeader); err != nil {
+		return err
+	}
+
+	for _, r := range rows {
+		record := []string{
+			r.Name,
+			r.Domain,
+			strings.Join(r.SANs, ";"),
+			r.Issuer,
+			r.Serial,
+			clock.Format(r.NotBefore),
+			clock.Format(r.NotAfter),
+			fmt.Sprintf("%d", r.DaysLeft),
+			r.KeyType,
+			strings.Join(r.Deployments, ";"),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// inventoryHTMLTemplate 独立的 HTML 清单页面，便于直接发送给审计或管理人员
+const inventoryHTMLTemplate = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>AutoCert 证书清单</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 6px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+.expired { background: #fdd; }
+.expiring { background: #ffd; }
+</style>
+</head>
+<body>
+<h1>AutoCert 证书清单</h1>
+<p>生成时间: {{.Generated}}，共 {{len .Rows}} 张证书</p>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}<th>状态</th></tr>
+{{range .Rows}}<tr class="{{rowClass .}}">
+<td>{{.Name}}</td>
+<td>{{.Domain}}</td>
+<td>{{range .SANs}}{{.}}<br>{{end}}</td>
+<td>{{.Issuer}}</td>
+<td>{{.Serial}}</td>
+<td>{{formatTime .NotBefore}}</td>
+<td>{{formatTime .NotAfter}}</td>
+<td>{{.DaysLeft}}（{{relative .NotAfter}}）</td>
+<td>{{.KeyType}}</td>
+<td>{{range .Deployments}}{{.}}<br>{{end}}</td>
+<td>{{.Status}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+// writeInventoryHTML 输出 HTML 格式清单
+func writeInventoryHTML(w io.Writer, rows []InventoryRow) error {
+	tmpl, err := template.New("inventory").Funcs(template.FuncMap{
+		"formatTime": clock.Format,
+		"relative":   clock.Relative,
+		"rowClass": func(r InventoryRow) string {
+			switch r.Status() {
+			case "已过期":
+				return "expired"
+			case "即将到期":
+				return "expiring"
+			default:
+				return ""
+			}
+		},
+	}).Parse(inventoryHTMLTemplate)
+	if err != nil {
+		return err
+	}
+
+	return tmpl.Execute(w, struct {
+		Generated string
+		Header    []string
+		Rows      []InventoryRow
+	}{
+		Generated: clock.Format(time.Now()),
+		Header:    inventoryHeader,
+		Rows:      rows,
+	})
+}
@@ -0,0 +1,125 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 续期命令的退出码
+const (
+	ExitNothingDue = 0 // 没有需要续期的证书
+	ExitError      = 1 // 执行出错
+	ExitSomeFailed = 2 // 部分证书续期失败
+	ExitRenewed    = 3 // 有证书被续期且全部成功（需要显式开启，默认为 0）
+)
+
+// ReportFileName 续期报告文件名（位于日志目录下）
+const ReportFileName = "autocert-renewal.json"
+
+// RenewalEntry 单个证书的续期结果
+type RenewalEntry struct {
+	Name    string     `json:"name"`
+	Domains []string   `json:"domains"`
+	Expiry  *time.Time `json:"expiry,omitempty"`
+	Reason  string     `json:"reason,omitempty"`
+}
+
+// RenewalReport 续期结果汇总
+type RenewalReport struct {
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Renewed    []RenewalEntry `json:"renewed"`
+	Skipped    []RenewalEntry `json:"skipped"`
+	Failed     []RenewalEntry `json:"failed"`
+	ExitCode   int            `json:"exit_code"`
+}
+
+// NewRenewalReport 创建续期报告
+func NewRenewalReport() *RenewalReport {
+	return &RenewalReport{
+		StartedAt: time.Now(),
+		Renewed:   []RenewalEntry{},
+		Skipped:   []RenewalEntry{},
+		Failed:    []RenewalEntry{},
+	}
+}
+
+// AddRenewed 记录续期成功的证书
+func (r *RenewalReport) AddRenewed(entry RenewalEntry) {
+	r.Renewed = append(r.Renewed, entry)
+}
+
+// AddSkipped 记录跳过的证书
+func (r *RenewalReport) AddSkipped(entry RenewalEntry) {
+	r.Skipped = append(r.Skipped, entry)
+}
+
+// AddFailed 记录续期失败的证书
+func (r *RenewalReport) AddFailed(entry RenewalEntry) {
+	r.Failed = append(r.Failed, entry)
+}
+
+// Finish 结束统计并计算退出码，renewedExitCode 为 false 时有证书续期成功也返回 0，
+// 避免 cron、systemd 把续期成功当作失败
+func (r *RenewalReport) Finish(renewedExitCode bool) {
+	r.FinishedAt = time.Now()
+
+	switch {
+	case len(r.Failed) > 0:
+		r.ExitCode = ExitSomeFailed
+	case len(r.Renewed) > 0 && renewedExitCode:
+		r.ExitCode = ExitRenewed
+	default:
+		r.ExitCode = ExitNothingDue
+	}
+}
+
+// Print 输出人类可读的汇总
+func (r *RenewalReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "续期汇总: 已续期 %d, 跳过 %d, 失败 %d\n", len(r.Renewed), len(r.Skipped), len(r.Failed))
+
+	for _, entry := range r.Renewed {
+		if entry.Reason != "" {
+			fmt.Fprintf(w, "  ✓ %s (%s): %s\n", entry.Name, strings.Join(entry.Domains, ", "), entry.Reason)
+			continue
+		}
+		fmt.Fprintf(w, "  ✓ %s (%s)\n", entry.Name, strings.Join(entry.Domains, ", "))
+	}
+	for _, entry := range r.Skipped {
+		fmt.Fprintf(w, "  - %s: %s\n", entry.Name, entry.Reason)
+	}
+	for _, entry := range r.Failed {
+		fmt.Fprintf(w, "  ✗ %s: %s\n", entry.Name, entry.Reason)
+	}
+}
+
+// Write 将报告以 JSON 格式写入日志目录，返回报告文件路径
+func (r *RenewalReport) Write(logDir string) (string, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	// 先写临时文件再重命名，避免监控程序读到不完整的报告
+	reportPath := filepath.Join(logDir, ReportFileName)
+	tmpPath := reportPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmpPath, reportPath); err != nil {
+		os.Remove(tmpPath)
+		return "", err
+	}
+
+	return reportPath, nil
+}
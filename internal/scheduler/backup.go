@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"autocert/internal/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Definition 备份中的定时任务定义。导入时不直接写回原始定义（程序路径和调度器可能不同），
+// 而是按目标平台的调度器和当前程序路径重新安装
+type Definition struct {
+	Name     string `json:"name"`
+	Backend  string `json:"backend"`            // systemd、cron、schtasks 或 service
+	Schedule string `json:"schedule,omitempty"` // cron 表达式，无法转换时为空（恢复时报错，需要手动安装）
+	Command  string `json:"command"`            // 源主机上执行的命令
+}
+
+// systemdCalendarMacros systemd OnCalendar 简写对应的 cron 表达式
+var systemdCalendarMacros = map[string]string{
+	"hourly":   "@hourly",
+	"daily":    "@daily",
+	"weekly":   "@weekly",
+	"monthly":  "@monthly",
+	"yearly":   "@yearly",
+	"annually": "@yearly",
+}
+
+// systemdCalendarPattern 每天固定时间的 OnCalendar，例如 *-*-* 03:30:00、*-*-* 3:30
+var systemdCalendarPattern = regexp.MustCompile(`^(?:\*-\*-\*\s+)?(\d{1,2}):(\d{2})(?::00)?$`)
+
+// calendarToCron 将 systemd OnCalendar 转换为 cron 表达式，只支持简写和每天固定时间，其他写法返回错误
+func calendarToCron(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if cron, ok := systemdCalendarMacros[strings.ToLower(value)]; ok {
+		return cron, nil
+	}
+	if m := systemdCalendarPattern.FindStringSubmatch(value); m != nil {
+		hour, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if hour < 24 && minute < 60 {
+			return fmt.Sprintf("%d %d * * *", minute, hour), nil
+		}
+	}
+	return "", fmt.Errorf("无法转换 OnCalendar=%s 为 cron 表达式", value)
+}
+
+// ExportDefinitions 导出当前主机上已安装的 AutoCert 任务定义
+func ExportDefinitions() []Definition {
+	if runtime.GOOS == "windows" {
+		return exportWindowsDefinitions()
+	} else if (&LinuxScheduler{}).supportsSystemdTimer() {
+		return exportSystemdDefinitions()
+	}
+	return exportCronDefinitions()
+}
+
+// exportSystemdDefinitions 导出 AutoCert 的 systemd timer 及其 service
+func exportSystemdDefinitions() []Definition {
+	var definitions []Definition
+	for _, timer := range listSystemdTimerUnits() {
+		if !isAutocertTimer(timer) {
+			continue
+		}
+		definition := Definition{Name: strings.TrimSuffix(timer.Unit, ".timer"), Backend: "systemd"}
+		found := false
+		for _, unit := range []string{timer.Unit, timer.Activates} {
+			data, err := os.ReadFile(filepath.Join(systemdUnitDir, unit))
+			if err != nil {
+				logger.Debug("读取 systemd 单元文件失败", "unit", unit, "error", err)
+				continue
+			}
+			found = true
+
+			if values := unitValues(string(data), "ExecStart"); len(values) > 0 {
+				definition.Command = strings.TrimLeft(values[0], "-@:+!")
+			}
+			if values := unitValues(string(data), "OnCalendar"); len(values) > 0 {
+				schedule, err := calendarToCron(values[0])
+				if err != nil {
+					logger.Warn("定时任务的调度时间无法转换，导入时需要手动安装", "taskName", definition.Name, "error", err)
+				}
+				definition.Schedule = schedule
+			}
+		}
+		// 单元文件不在 /etc/systemd/system 中（例如软件包安装的单元）时不导出
+		if !found {
+			continue
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions
+}
+
+// exportCronDefinitions 导出 crontab 中带任务名标记的 AutoCert 任务行
+func exportCronDefinitions() []Definition {
+	var definitions []Definition
+	for _, entry := range cronEntries() {
+		if entry.Disabled {
+			continue
+		}
+		definitions = append(definitions, Definition{
+			Name:     entry.Name,
+			Backend:  "cron",
+			Schedule: entry.Schedule,
+			Command:  entry.Command,
+		})
+	}
+	return definitions
+}
+
+// exportWindowsDefinitions 导出任务计划程序中的任务以及服务模式安装的服务
+func exportWindowsDefinitions() []Definition {
+	var definitions []Definition
+	for _, service := range listServices() {
+		definitions = append(definitions, Definition{Name: service.Name, Backend: ModeService, Command: service.Command})
+	}
+
+	tasks, err := listWindowsTasks()
+	if err != nil {
+		logger.Warn("读取 Windows 定时任务失败", "error", err)
+	}
+	for _, task := range tasks {
+		data, err := queryTaskXML(task.Name)
+		if err != nil {
+			logger.Warn("读取任务定义失败", "taskName", task.Name, "error", err)
+			continue
+		}
+		definition := Definition{Name: task.Name, Backend: "schtasks", Schedule: DefaultSchedule, Command: task.Command}
+		if parsed, err := parseTaskXML(data); err == nil {
+			definition.Command = strings.TrimSpace(parsed.Exec.Command + " " + parsed.Exec.Arguments)
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions
+}
+
+// RestoreDefinition 在当前平台安装备份中的任务：使用当前程序路径和当前平台的调度器，
+// 保留任务名称和 cron 表达式。服务模式的任务在非 Windows 平台上改为安装定时任务；
+// 同名任务已存在时不覆盖
+func RestoreDefinition(definition Definition, command, configFile string) error {
+	// 任务计划程序中的任务名称可能带有文件夹路径
+	name := definition.Name[strings.LastIndex(definition.Name, `\`)+1:]
+	if name == "" || safeFileName(name) != name || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("无效的任务名称: %q", definition.Name)
+	}
+
+	sched := NewScheduler()
+	if sched.IsInstalled(name) {
+		logger.Info("定时任务已存在，跳过恢复", "taskName", name)
+		return nil
+	}
+
+	if definition.Backend == ModeService && runtime.GOOS == "windows" {
+		return InstallService(name, command, ServiceArgs(configFile))
+	}
+
+	// 服务模式没有调度时间，改为定时任务时使用默认时间；其他任务的调度时间无法转换时不猜测
+	schedule := definition.Schedule
+	if definition.Backend == ModeService && schedule == "" {
+		schedule = DefaultSchedule
+	}
+	if schedule == "" {
+		return fmt.Errorf("备份中没有可用的调度时间，请使用 autocert schedule install 手动安装")
+	}
+	if err := ValidateCronExpression(schedule); err != nil {
+		return fmt.Errorf("调度时间 %q 无效，请使用 autocert schedule install 手动安装: %w", schedule, err)
+	}
+	logger.Info("恢复定时任务", "taskName", name, "from", definition.Backend, "schedule", schedule)
+	return sched.Install(name, command, schedule)
+}
+
+// safeFileName 将任务名称中字母、数字、点、下划线和连字符以外的字符替换为下划线，
+// 用于检查恢复时的单元文件名
+func safeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, name)
+}
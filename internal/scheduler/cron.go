@@ -0,0 +1,372 @@
+package scheduler
+
+import (
+	"autocert/internal/clock"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronField 解析后的 cron 字段，第 n 位表示取值 n
+type cronField uint64
+
+func (f cronField) has(n int) bool {
+	return f&(1<<uint(n)) != 0
+}
+
+// cronSchedule 解析后的 5 字段 cron 表达式
+type cronSchedule struct {
+	minute, hour, dom, month, dow cronField
+
+	// 日期或星期字段以 * 开头；两个字段都受限时任一匹配即运行（与 Vixie cron 一致）
+	domAny, dowAny bool
+}
+
+// cronMacros cron 支持的 @ 简写
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronFieldBounds cron 表达式各字段的取值范围和名称：分、时、日、月、周
+var cronFieldBounds = [5]struct {
+	name     string
+	min, max int
+	names    map[string]int
+}{
+	{"分钟", 0, 59, nil},
+	{"小时", 0, 23, nil},
+	{"日期", 1, 31, nil},
+	{"月份", 1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{"星期", 0, 7, map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}},
+}
+
+// ValidateCronExpression 检查 cron 表达式（5 个字段，支持 *、范围、步长、列表、月份和星期名称以及 @daily 等简写）
+func ValidateCronExpression(expr string) error {
+	_, err := parseCron(expr)
+	return err
+}
+
+// parseCron 解析 cron 表达式
+func parseCron(expr string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("需要 5 个字段，实际 %d 个", len(fields))
+	}
+
+	var values [5]cronField
+	for i, field := range fields {
+		bounds := cronFieldBounds[i]
+		for _, part := range strings.Split(strings.ToLower(field), ",") {
+			bits, err := parseCronPart(part, bounds.min, bounds.max, bounds.names)
+			if err != nil {
+				return nil, fmt.Errorf("%s字段 %q: %w", bounds.name, field, err)
+			}
+			values[i] |= bits
+		}
+	}
+
+	// 星期字段中 7 与 0 都表示星期日
+	dow := values[4]
+	if dow.has(7) {
+		dow = dow&^(1<<7) | 1
+	}
+
+	return &cronSchedule{
+		minute: values[0],
+		hour:   values[1],
+		dom:    values[2],
+		month:  values[3],
+		dow:    dow,
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronPart 解析 cron 字段中的单个元素，例如 *、5、1-5、*/15、0-30/5、mon-fri
+func parseCronPart(part string, min, max int, names map[string]int) (cronField, error) {
+	rangePart, stepText, hasStep := strings.Cut(part, "/")
+	step := 1
+	if hasStep {
+		n, err := strconv.Atoi(stepText)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("无效的步长 %q", stepText)
+		}
+		step = n
+	}
+
+	start, end := min, max
+	if rangePart != "*" {
+		lo, hi, isRange := strings.Cut(rangePart, "-")
+		var err error
+		if start, err = cronValue(lo, min, max, names); err != nil {
+			return 0, err
+		}
+		switch {
+		case isRange:
+			end, err = cronValue(hi, min, max, names)
+			if err != nil || end < start {
+				return 0, fmt.Errorf("无效的范围 %q", rangePart)
+			}
+		case hasStep:
+			// 5/15 表示从 5 开始到最大值，每 15 一次
+		default:
+			end = start
+		}
+	}
+
+	var bits cronField
+	for v := start; v <= end; v += step {
+		bits |= 1 << uint(v)
+	}
+	return bits, nil
+}
+
+// cronValue 解析单个取值（数字或月份、星期名称）
+func cronValue(value string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[value]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("取值 %q 超出范围 %d-%d", value, min, max)
+	}
+	return n, nil
+}
+
+// Next 返回 after 之后的下一次运行时间（按 loc 时区计算），五年内没有匹配的时间（例如 2 月 30 日）时返回零值
+func (s *cronSchedule) Next(after time.Time, loc *time.Location) time.Time {
+	from := after.In(loc).Truncate(time.Minute).Add(time.Minute)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+
+	for i := 0; i < 5*366; i, day = i+1, day.AddDate(0, 0, 1) {
+		if !s.matchDay(day) {
+			continue
+		}
+		for hour := 0; hour < 24; hour++ {
+			if !s.hour.has(hour) {
+				continue
+			}
+			for minute := 0; minute < 60; minute++ {
+				if !s.minute.has(minute) {
+					continue
+				}
+				if t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc); !t.Before(from) {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay 日期是否匹配月份、日期和星期字段
+func (s *cronSchedule) matchDay(day time.Time) bool {
+	if !s.month.has(int(day.Month())) {
+		return false
+	}
+	dom, dow := s.dom.has(day.Day()), s.dow.has(int(day.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// cronEntry crontab 中以行尾注释（# 任务名）标记的 AutoCert 任务
+type cronEntry struct {
+	Name     string
+	Schedule string
+	User     string // 系统 crontab（/etc/crontab、/etc/cron.d）中的运行用户
+	Command  string
+	Program  string // 任务执行的程序路径
+	Disabled bool   // 任务行已被注释
+	Location *time.Location
+	Source   string // 用户 crontab 或系统 crontab 文件路径
+}
+
+// task 转换为任务信息，根据 cron 表达式计算下次运行时间
+func (e cronEntry) task() Task {
+	task := Task{Name: e.Name, Command: e.Command, Schedule: e.Schedule, Status: "enabled"}
+	if e.Disabled {
+		task.Status = "disabled"
+		return task
+	}
+	if strings.EqualFold(e.Schedule, "@reboot") {
+		task.NextRun = "系统启动时"
+		return task
+	}
+	if schedule, err := parseCron(e.Schedule); err == nil {
+		task.NextRunAt = schedule.Next(clock.Now(), e.Location)
+	}
+	return task
+}
+
+// parseCrontab 解析 crontab 内容中的 AutoCert 任务；system 为 true 时任务行包含运行用户字段
+func parseCrontab(content, source string, system bool) []cronEntry {
+	location := time.Local
+	var entries []cronEntry
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		// CRON_TZ 指定之后任务行的时区（cronie）
+		if name, value, ok := cronAssignment(line); ok {
+			if name == "CRON_TZ" {
+				if loc, err := time.LoadLocation(value); err == nil {
+					location = loc
+				}
+			}
+			continue
+		}
+
+		entry, ok := parseCronLine(line, system)
+		if !ok {
+			continue
+		}
+		entry.Location, entry.Source = location, source
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// cronAssignment 解析环境变量设置行，例如 MAILTO=root、CRON_TZ="Asia/Shanghai"
+func cronAssignment(line string) (string, string, bool) {
+	name, value, ok := strings.Cut(line, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return "", "", false
+	}
+	for i, r := range name {
+		if !(r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || i > 0 && r >= '0' && r <= '9') {
+			return "", "", false
+		}
+	}
+	return name, strings.Trim(strings.TrimSpace(value), `"'`), true
+}
+
+// parseCronLine 解析任务行，只返回带任务名标记且属于 AutoCert 的任务（被注释的任务行视为已禁用）
+func parseCronLine(line string, system bool) (cronEntry, bool) {
+	var entry cronEntry
+	if strings.HasPrefix(line, "#") {
+		entry.Disabled = true
+		line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+	}
+
+	index := strings.LastIndex(line, "#")
+	if index <= 0 {
+		return entry, false
+	}
+	entry.Name = strings.TrimSpace(line[index+1:])
+	if entry.Name == "" || strings.ContainsAny(entry.Name, " \t") {
+		return entry, false
+	}
+
+	fields := strings.Fields(line[:index])
+	scheduleFields := 5
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "@") {
+		scheduleFields = 1
+	}
+	commandStart := scheduleFields
+	if system {
+		commandStart++
+	}
+	if len(fields) <= commandStart {
+		return entry, false
+	}
+
+	entry.Schedule = strings.Join(fields[:scheduleFields], " ")
+	if system {
+		entry.User = fields[scheduleFields]
+	}
+	entry.Program = fields[commandStart]
+	entry.Command = strings.Join(fields[commandStart:], " ")
+
+	isAutocert := strings.Contains(strings.ToLower(entry.Name), "autocert") ||
+		strings.Contains(strings.ToLower(filepath.Base(entry.Program)), "autocert")
+	return entry, isAutocert
+}
+
+// userCrontab 读取当前用户的 crontab。受限环境中没有 crontab 命令时直接读取 spool 文件；
+// 没有 crontab 时返回 false
+func userCrontab() (string, bool) {
+	output, err := exec.Command("crontab", "-l").Output()
+	if err == nil {
+		return string(output), true
+	}
+	if !errors.Is(err, exec.ErrNotFound) {
+		return "", false
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return "", false
+	}
+	for _, dir := range []string{"/var/spool/cron/crontabs", "/var/spool/cron"} {
+		if data, err := os.ReadFile(filepath.Join(dir, current.Username)); err == nil {
+			return string(data), true
+		}
+	}
+	return "", false
+}
+
+// systemCrontabs 系统 crontab 文件（任务行包含运行用户字段）；与 cron 一致，跳过隐藏文件和备份文件
+func systemCrontabs() []string {
+	files := []string{"/etc/crontab"}
+	entries, err := os.ReadDir("/etc/cron.d")
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") || strings.Contains(name, ".dpkg-") {
+			continue
+		}
+		files = append(files, filepath.Join("/etc/cron.d", name))
+	}
+	return files
+}
+
+// cronEntries 当前用户 crontab 和系统 crontab 中的 AutoCert 任务
+func cronEntries() []cronEntry {
+	var entries []cronEntry
+	if content, ok := userCrontab(); ok {
+		entries = append(entries, parseCrontab(content, "crontab", false)...)
+	}
+	for _, path := range systemCrontabs() {
+		if data, err := os.ReadFile(path); err == nil {
+			entries = append(entries, parseCrontab(string(data), path, true)...)
+		}
+	}
+	return entries
+}
+
+// removeCronLines 删除 crontab 内容中指定任务名的任务行，返回新内容和是否有删除
+func removeCronLines(content, taskName string, system bool) (string, bool) {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	removed := false
+	for _, line := range lines {
+		if entry, ok := parseCronLine(strings.TrimSpace(line), system); ok && entry.Name == taskName {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
@@ -1,6 +1,7 @@
 package scheduler
 
 import (
+	"autocert/internal/clock"
 	"autocert/internal/logger"
 	"fmt"
 	"os"
@@ -9,6 +10,12 @@ import (
 	"runtime"
 	"strings"
 	"text/template"
+	"time"
+)
+
+const (
+	DefaultTaskName = "autocert-renew" // 默认的续期任务名称
+	DefaultSchedule = "0 2 * * *"      // 默认的续期检查时间（cron 格式，每日凌晨2点）
 )
 
 // TaskScheduler 任务调度器接口
@@ -17,6 +24,7 @@ type TaskScheduler interface {
 	Remove(taskName string) error
 	List() ([]Task, error)
 	IsInstalled(taskName string) bool
+	Verify(taskName, command string) (*Verification, error)
 }
 
 // Task 定时任务信息
@@ -27,6 +35,51 @@ type Task struct {
 	Status   string
 	LastRun  string
 	NextRun  string
+
+	// 解析出的运行时间（调度器输出的本地时间），无法解析时为零值，显示原始文本
+	LastRunAt time.Time
+	NextRunAt time.Time
+
+	// LastResult 上次运行的结果（systemd 的 service Result 或任务计划程序的结果码），未知时为空
+	LastResult string
+}
+
+// NextRunText 按配置的时区显示下次运行时间
+func (t Task) NextRunText() string {
+	return runText(t.NextRunAt, t.NextRun)
+}
+
+// LastRunText 按配置的时区显示上次运行时间
+func (t Task) LastRunText() string {
+	return runText(t.LastRunAt, t.LastRun)
+}
+
+func runText(at time.Time, raw string) string {
+	if !at.IsZero() {
+		return clock.FormatRelative(at)
+	}
+	if raw == "" {
+		return "-"
+	}
+	return raw
+}
+
+// taskTimeLayouts schtasks 和 systemctl 输出的时间格式（按系统区域设置不同）
+var taskTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006/1/2 15:04:05",
+	"1/2/2006 3:04:05 PM",
+	"02.01.2006 15:04:05",
+}
+
+// parseTaskTime 解析调度器输出的本地时间，无法解析时返回零值
+func parseTaskTime(value string) time.Time {
+	for _, layout := range taskTimeLayouts {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(value), time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
 
 // NewScheduler 创建新的任务调度器
@@ -64,7 +117,14 @@ func (w *WindowsScheduler) Install(taskName, command, schedule string) error {
 	}
 	defer os.Remove(tempFile)
 
-	// 使用 schtasks 创建任务
+	// 从服务模式切换回定时任务时先删除服务，避免两者同时续期
+	if serviceInstalled(taskName) {
+		if err := RemoveService(taskName); err != nil {
+			return err
+		}
+	}
+
+	// 使用 schtasks 创建任务，/f 替换同名的已有任务
 	cmd := exec.Command("schtasks", "/create", "/tn", taskName, "/xml", tempFile, "/f")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -75,8 +135,12 @@ func (w *WindowsScheduler) Install(taskName, command, schedule string) error {
 	return nil
 }
 
-// Remove 删除 Windows 定时任务
+// Remove 删除 Windows 定时任务；以服务模式安装时删除服务
 func (w *WindowsScheduler) Remove(taskName string) error {
+	if serviceInstalled(taskName) {
+		return RemoveService(taskName)
+	}
+
 	logger.Info("删除 Windows 定时任务", "taskName", taskName)
 
 	cmd := exec.Command("schtasks", "/delete", "/tn", taskName, "/f")
@@ -89,40 +153,38 @@ func (w *WindowsScheduler) Remove(taskName string) error {
 	return nil
 }
 
-// List 列出 Windows 定时任务
-func (w *WindowsScheduler) List() ([]Task, error) {
-	cmd := exec.Command("schtasks", "/query", "/fo", "csv", "/v")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("查询 Windows 任务失败: %w", err)
+// removeScheduledTask 删除同名的计划任务，任务不存在时不处理
+func removeScheduledTask(taskName string) error {
+	if exec.Command("schtasks", "/query", "/tn", taskName).Run() != nil {
+		return nil
 	}
 
-	// 解析 CSV 输出
-	tasks := []Task{}
-	lines := strings.Split(string(output), "\n")
+	logger.Info("删除同名的 Windows 定时任务", "taskName", taskName)
+	output, err := exec.Command("schtasks", "/delete", "/tn", taskName, "/f").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("删除 Windows 任务失败: %s", string(output))
+	}
+	return nil
+}
 
-	for _, line := range lines[1:] { // 跳过标题行
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
+// List 列出 AutoCert 的 Windows 定时任务和服务。优先通过 PowerShell 的 Get-ScheduledTask 获取结构化结果，
+// 没有 PowerShell 时解析 schtasks 的 CSV 输出
+func (w *WindowsScheduler) List() ([]Task, error) {
+	tasks, err := listWindowsTasks()
 
-		fields := strings.Split(line, ",")
-		if len(fields) >= 4 {
-			task := Task{
-				Name:    strings.Trim(fields[0], "\""),
-				Status:  strings.Trim(fields[3], "\""),
-				LastRun: strings.Trim(fields[4], "\""),
-				NextRun: strings.Trim(fields[5], "\""),
-			}
-			tasks = append(tasks, task)
-		}
+	// 组策略禁止查询任务计划程序时仍列出服务
+	services := listServices()
+	if err != nil && len(services) == 0 {
+		return nil, err
 	}
-
-	return tasks, nil
+	return append(tasks, services...), nil
 }
 
-// IsInstalled 检查 Windows 任务是否已安装
+// IsInstalled 检查 Windows 任务或服务是否已安装
 func (w *WindowsScheduler) IsInstalled(taskName string) bool {
+	if serviceInstalled(taskName) {
+		return true
+	}
 	cmd := exec.Command("schtasks", "/query", "/tn", taskName)
 	err := cmd.Run()
 	return err == nil
@@ -329,31 +391,17 @@ func (l *LinuxScheduler) removeSystemdTimer(taskName string) error {
 	return nil
 }
 
-// listSystemdTimers 列出 systemd timers
+// listSystemdTimers 列出 AutoCert 的 systemd timer，包括运行时间、启用状态和 service 的上次运行结果
 func (l *LinuxScheduler) listSystemdTimers() ([]Task, error) {
-	cmd := exec.Command("systemctl", "list-timers", "--all", "--no-pager")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("查询 systemd timers 失败: %w", err)
-	}
-
 	tasks := []Task{}
-	lines := strings.Split(string(output), "\n")
-
-	for _, line := range lines {
-		if strings.Contains(line, "autocert") {
-			fields := strings.Fields(line)
-			if len(fields) >= 5 {
-				task := Task{
-					Name:    fields[4],
-					NextRun: fields[0] + " " + fields[1],
-					Status:  "enabled",
-				}
-				tasks = append(tasks, task)
-			}
+	for _, timer := range listSystemdTimerUnits() {
+		if !isAutocertTimer(timer) {
+			continue
 		}
+		tasks = append(tasks, systemdTask(timer,
+			systemctlShow(timer.Unit, systemdTimerProperties),
+			systemctlShow(timer.Activates, systemdServiceProperties)))
 	}
-
 	return tasks, nil
 }
 
@@ -385,74 +433,49 @@ func (l *LinuxScheduler) installCronJob(taskName, command, schedule string) erro
 	return nil
 }
 
-// removeCronJob 删除 cron 任务
+// removeCronJob 从当前用户 crontab 和可写的系统 crontab（/etc/cron.d）中删除 cron 任务
 func (l *LinuxScheduler) removeCronJob(taskName string) error {
-	// 获取当前 crontab
-	cmd := exec.Command("crontab", "-l")
-	currentCrontab, err := cmd.Output()
-	if err != nil {
-		return fmt.Errorf("获取 crontab 失败: %w", err)
-	}
-
-	// 过滤掉指定任务
-	lines := strings.Split(string(currentCrontab), "\n")
-	var newLines []string
-
-	for _, line := range lines {
-		if !strings.Contains(line, "# "+taskName) {
-			newLines = append(newLines, line)
+	if content, ok := userCrontab(); ok {
+		if newCrontab, removed := removeCronLines(content, taskName, false); removed {
+			cmd := exec.Command("crontab", "-")
+			cmd.Stdin = strings.NewReader(newCrontab)
+			if err := cmd.Run(); err != nil {
+				return fmt.Errorf("删除 cron 任务失败: %w", err)
+			}
 		}
 	}
 
-	// 写入新的 crontab
-	newCrontab := strings.Join(newLines, "\n")
-	cmd = exec.Command("crontab", "-")
-	cmd.Stdin = strings.NewReader(newCrontab)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("删除 cron 任务失败: %w", err)
+	for _, path := range systemCrontabs() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if content, removed := removeCronLines(string(data), taskName, true); removed {
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				return fmt.Errorf("删除 %s 中的 cron 任务失败: %w", path, err)
+			}
+		}
 	}
 
 	logger.Info("cron 任务删除成功", "taskName", taskName)
 	return nil
 }
 
-// listCronJobs 列出 cron 任务
+// listCronJobs 列出 crontab 中带任务名标记的 AutoCert 任务，下次运行时间根据 cron 表达式计算
 func (l *LinuxScheduler) listCronJobs() ([]Task, error) {
-	cmd := exec.Command("crontab", "-l")
-	output, err := cmd.Output()
-	if err != nil {
-		return []Task{}, nil // 没有 crontab 不是错误
-	}
-
 	tasks := []Task{}
-	lines := strings.Split(string(output), "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" && !strings.HasPrefix(line, "#") {
-			fields := strings.Fields(line)
-			if len(fields) >= 6 {
-				task := Task{
-					Name:     "cron-task",
-					Command:  strings.Join(fields[5:], " "),
-					Schedule: strings.Join(fields[0:5], " "),
-					Status:   "enabled",
-				}
-				tasks = append(tasks, task)
-			}
-		}
+	for _, entry := range cronEntries() {
+		tasks = append(tasks, entry.task())
 	}
-
 	return tasks, nil
 }
 
 // isCronJobInstalled 检查 cron 任务是否已安装
 func (l *LinuxScheduler) isCronJobInstalled(taskName string) bool {
-	cmd := exec.Command("crontab", "-l")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
+	for _, entry := range cronEntries() {
+		if entry.Name == taskName {
+			return true
+		}
 	}
-
-	return strings.Contains(string(output), "# "+taskName)
+	return false
 }
@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"autocert/internal/logger"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// scheduledTasksScript 通过 Get-ScheduledTask 查询任务名称或执行程序包含 autocert 的任务，
+// 时间以 UTC 的 ISO 8601 格式输出，不受系统区域设置影响
+const scheduledTasksScript = `[Console]::OutputEncoding = [Text.Encoding]::UTF8
+$tasks = Get-ScheduledTask | Where-Object {
+  $_.TaskName -like '*autocert*' -or @($_.Actions | Where-Object { $_.Execute -like '*autocert*' }).Count -gt 0
+} | ForEach-Object {
+  $info = $_ | Get-ScheduledTaskInfo
+  $action = @($_.Actions)[0]
+  [pscustomobject]@{
+    Name       = $_.TaskName
+    Path       = $_.TaskPath
+    State      = [string]$_.State
+    Execute    = $action.Execute
+    Arguments  = $action.Arguments
+    LastRun    = if ($info.LastRunTime) { $info.LastRunTime.ToUniversalTime().ToString('o') } else { '' }
+    NextRun    = if ($info.NextRunTime) { $info.NextRunTime.ToUniversalTime().ToString('o') } else { '' }
+    LastResult = [int64]$info.LastTaskResult
+  }
+}
+ConvertTo-Json -InputObject @($tasks) -Compress`
+
+// scheduledTask Get-ScheduledTask 查询结果中的一项
+type scheduledTask struct {
+	Name       string
+	Path       string
+	State      string
+	Execute    string
+	Arguments  string
+	LastRun    string
+	NextRun    string
+	LastResult int64
+}
+
+// 任务计划程序的特殊结果码
+const (
+	taskRunning      = 0x41301 // 任务正在运行
+	taskNotYetRun    = 0x41303 // 任务尚未运行
+	taskNeverRunYear = 2000    // 从未运行的任务 LastRunTime 为 1999-11-30
+)
+
+// listWindowsTasks 列出任务计划程序中的 AutoCert 任务（不包括服务）
+func listWindowsTasks() ([]Task, error) {
+	tasks, err := listScheduledTasks()
+	if err == nil {
+		return tasks, nil
+	}
+	logger.Debug("通过 PowerShell 查询任务失败，改用 schtasks", "error", err)
+	return listSchtasks()
+}
+
+// listScheduledTasks 通过 PowerShell 查询任务；没有 PowerShell 或 ScheduledTasks 模块时返回错误
+func listScheduledTasks() ([]Task, error) {
+	output, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", scheduledTasksScript).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseScheduledTasks(output)
+}
+
+// parseScheduledTasks 解析 ConvertTo-Json 的输出（一个任务时旧版本 PowerShell 可能输出对象而不是数组）
+func parseScheduledTasks(output []byte) ([]Task, error) {
+	output = bytes.TrimSpace(bytes.TrimPrefix(output, []byte("\xef\xbb\xbf")))
+	var items []scheduledTask
+	if len(output) == 0 || bytes.Equal(output, []byte("null")) {
+		return []Task{}, nil
+	}
+	if output[0] == '{' {
+		var item scheduledTask
+		if err := json.Unmarshal(output, &item); err != nil {
+			return nil, fmt.Errorf("解析任务列表失败: %w", err)
+		}
+		items = append(items, item)
+	} else if err := json.Unmarshal(output, &items); err != nil {
+		return nil, fmt.Errorf("解析任务列表失败: %w", err)
+	}
+
+	tasks := []Task{}
+	for _, item := range items {
+		task := Task{
+			Name:      item.Name,
+			Command:   strings.TrimSpace(item.Execute + " " + item.Arguments),
+			Status:    item.State,
+			LastRunAt: parseTaskISOTime(item.LastRun),
+			NextRunAt: parseTaskISOTime(item.NextRun),
+		}
+		// 根目录以外的任务显示完整路径，与 schtasks /tn 的写法一致
+		if item.Path != "" && item.Path != `\` {
+			task.Name = item.Path + item.Name
+		}
+		if !task.LastRunAt.IsZero() {
+			task.LastResult = taskResultText(item.LastResult)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// parseTaskISOTime 解析 PowerShell 输出的 ISO 8601 时间，未运行过的任务（1999 年）返回零值
+func parseTaskISOTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil || t.Year() < taskNeverRunYear {
+		return time.Time{}
+	}
+	return t
+}
+
+// taskResultText 上次运行结果，与 systemd 的 Result 写法一致；
+// AutoCert 的退出码 3（证书已续期）也视为成功
+func taskResultText(code int64) string {
+	switch {
+	case code == 0 || code == 3:
+		return "success"
+	case code == taskRunning:
+		return "running"
+	case code == taskNotYetRun:
+		return ""
+	case code > 0 && code < 256:
+		return fmt.Sprintf("exit-code（退出码 %d）", code)
+	default:
+		return fmt.Sprintf("0x%08X", uint32(code))
+	}
+}
+
+// schtasks /query /fo csv /v /nh 的列位置（不同语言的系统中列名不同，位置相同）
+const (
+	schtasksColumnName      = 1
+	schtasksColumnNextRun   = 2
+	schtasksColumnStatus    = 3
+	schtasksColumnLastRun   = 5
+	schtasksColumnResult    = 6
+	schtasksColumnTaskToRun = 8
+)
+
+// listSchtasks 没有 PowerShell 时解析 schtasks 的 CSV 输出
+func listSchtasks() ([]Task, error) {
+	output, err := exec.Command("schtasks", "/query", "/fo", "csv", "/v", "/nh").Output()
+	if err != nil {
+		return nil, fmt.Errorf("查询 Windows 任务失败: %w", err)
+	}
+	return parseSchtasksCSV(output)
+}
+
+// parseSchtasksCSV 解析 CSV 输出，只保留任务名称或执行程序包含 autocert 的任务
+func parseSchtasksCSV(output []byte) ([]Task, error) {
+	reader := csv.NewReader(bytes.NewReader(output))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析任务列表失败: %w", err)
+	}
+
+	tasks := []Task{}
+	seen := make(map[string]bool)
+	for _, record := range records {
+		if len(record) <= schtasksColumnTaskToRun {
+			continue
+		}
+		name, command := record[schtasksColumnName], record[schtasksColumnTaskToRun]
+		if !strings.Contains(strings.ToLower(name), "autocert") && !strings.Contains(strings.ToLower(command), "autocert") {
+			continue
+		}
+		// 多个触发器的任务每个触发器输出一行
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		task := Task{
+			Name:    strings.TrimPrefix(name, `\`),
+			Command: command,
+			Status:  record[schtasksColumnStatus],
+			LastRun: record[schtasksColumnLastRun],
+			NextRun: record[schtasksColumnNextRun],
+		}
+		task.LastRunAt = parseTaskTime(task.LastRun)
+		task.NextRunAt = parseTaskTime(task.NextRun)
+		if !task.LastRunAt.IsZero() && task.LastRunAt.Year() < taskNeverRunYear {
+			task.LastRun, task.LastRunAt = "", time.Time{}
+		}
+		var code int64
+		if _, err := fmt.Sscan(record[schtasksColumnResult], &code); err == nil && !task.LastRunAt.IsZero() {
+			task.LastResult = taskResultText(code)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
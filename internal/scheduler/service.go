@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 续期任务的安装方式
+const (
+	ModeTask    = "task"    // 系统定时任务（任务计划程序、systemd timer 或 cron）
+	ModeService = "service" // Windows 服务，由守护进程内部定时器续期（不依赖任务计划程序）
+)
+
+// ErrServiceUnsupported 当前平台不支持服务模式
+var ErrServiceUnsupported = errors.New("服务模式仅支持 Windows，Linux 请使用 systemd timer 或 cron")
+
+// ValidateMode 检查安装方式
+func ValidateMode(mode string) error {
+	switch mode {
+	case ModeTask, ModeService:
+		return nil
+	default:
+		return fmt.Errorf("无效的安装方式 %q，可选: %s, %s", mode, ModeTask, ModeService)
+	}
+}
+
+// ServiceArgs 服务启动守护进程的参数。服务以 LocalSystem 运行，默认配置文件搜索路径
+// （$HOME）与当前用户不同，因此使用绝对路径指定配置文件
+func ServiceArgs(configFile string) []string {
+	args := []string{"daemon"}
+	if configFile != "" {
+		args = append(args, "--config", configFile)
+	}
+	return args
+}
+
+// splitServiceCommand 将服务的启动命令拆分为程序路径和参数，程序路径可能带引号
+func splitServiceCommand(binaryPath string) (string, string) {
+	binaryPath = strings.TrimSpace(binaryPath)
+	if rest, ok := strings.CutPrefix(binaryPath, `"`); ok {
+		program, arguments, _ := strings.Cut(rest, `"`)
+		return program, strings.TrimSpace(arguments)
+	}
+	program, arguments, _ := strings.Cut(binaryPath, " ")
+	return program, strings.TrimSpace(arguments)
+}
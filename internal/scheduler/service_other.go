@@ -0,0 +1,37 @@
+//go:build !windows
+
+package scheduler
+
+import "context"
+
+// InstallService 当前平台不支持服务模式
+func InstallService(name, command string, args []string) error {
+	return ErrServiceUnsupported
+}
+
+// RemoveService 当前平台不支持服务模式
+func RemoveService(name string) error {
+	return ErrServiceUnsupported
+}
+
+func serviceInstalled(name string) bool {
+	return false
+}
+
+func listServices() []Task {
+	return nil
+}
+
+func verifyService(name, command string) *Verification {
+	return &Verification{Name: name, Backend: ModeService}
+}
+
+// IsService 当前平台不会以 Windows 服务运行
+func IsService() bool {
+	return false
+}
+
+// RunService 当前平台不支持服务模式
+func RunService(name string, run func(ctx context.Context) error) error {
+	return ErrServiceUnsupported
+}
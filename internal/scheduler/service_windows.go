@@ -0,0 +1,326 @@
+//go:build windows
+
+package scheduler
+
+import (
+	"autocert/internal/logger"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceStopTimeout 停止服务时等待的最长时间
+const serviceStopTimeout = 30 * time.Second
+
+// InstallService 注册并启动 Windows 服务（自动启动，异常退出后 1 分钟重启）。
+// 服务已存在时更新启动命令并重启；同名的计划任务先删除，避免两者同时续期
+func InstallService(name, command string, args []string) error {
+	logger.Info("安装 Windows 服务", "service", name)
+
+	if err := removeScheduledTask(name); err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("连接服务控制管理器失败: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err == nil {
+		defer s.Close()
+		if err := updateService(s, command, args); err != nil {
+			return err
+		}
+	} else {
+		s, err = m.CreateService(name, command, mgr.Config{
+			DisplayName:      "AutoCert 证书续期 (" + name + ")",
+			Description:      "AutoCert 守护进程，按 daemon.interval 定期检查并续期证书",
+			StartType:        mgr.StartAutomatic,
+			DelayedAutoStart: true,
+		}, args...)
+		if err != nil {
+			return fmt.Errorf("创建 Windows 服务失败: %w", err)
+		}
+		defer s.Close()
+	}
+
+	recovery := []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Minute},
+	}
+	if err := s.SetRecoveryActions(recovery, uint32((24 * time.Hour).Seconds())); err != nil {
+		logger.Warn("设置服务恢复策略失败", "service", name, "error", err)
+	} else if err := s.SetRecoveryActionsOnNonCrashFailures(true); err != nil {
+		logger.Warn("设置服务恢复策略失败", "service", name, "error", err)
+	}
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("启动 Windows 服务失败: %w", err)
+	}
+
+	logger.Info("Windows 服务安装成功", "service", name)
+	return nil
+}
+
+// updateService 更新已存在服务的启动命令，停止后由调用方重新启动
+func updateService(s *mgr.Service, command string, args []string) error {
+	config, err := s.Config()
+	if err != nil {
+		return fmt.Errorf("读取服务配置失败: %w", err)
+	}
+
+	binaryPath := syscall.EscapeArg(command)
+	for _, arg := range args {
+		binaryPath += " " + syscall.EscapeArg(arg)
+	}
+	config.BinaryPathName = binaryPath
+	config.StartType = mgr.StartAutomatic
+	config.DelayedAutoStart = true
+	if err := s.UpdateConfig(config); err != nil {
+		return fmt.Errorf("更新服务配置失败: %w", err)
+	}
+	return stopService(s)
+}
+
+// stopService 停止服务并等待其退出
+func stopService(s *mgr.Service) error {
+	status, err := s.Query()
+	if err != nil {
+		return fmt.Errorf("查询服务状态失败: %w", err)
+	}
+	if status.State == svc.Stopped {
+		return nil
+	}
+	if status.State != svc.StopPending {
+		if status, err = s.Control(svc.Stop); err != nil {
+			return fmt.Errorf("停止服务失败: %w", err)
+		}
+	}
+
+	deadline := time.Now().Add(serviceStopTimeout)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待服务停止超时（%s）", serviceStopTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return fmt.Errorf("查询服务状态失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// RemoveService 停止并删除 Windows 服务
+func RemoveService(name string) error {
+	logger.Info("删除 Windows 服务", "service", name)
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("连接服务控制管理器失败: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("服务 %s 不存在: %w", name, err)
+	}
+	defer s.Close()
+
+	if err := stopService(s); err != nil {
+		logger.Warn("停止服务失败，服务将在下次重启后删除", "service", name, "error", err)
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("删除 Windows 服务失败: %w", err)
+	}
+
+	logger.Info("Windows 服务删除成功", "service", name)
+	return nil
+}
+
+// serviceInstalled 检查是否存在指定名称的服务
+func serviceInstalled(name string) bool {
+	m, err := mgr.Connect()
+	if err != nil {
+		return false
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return false
+	}
+	s.Close()
+	return true
+}
+
+// serviceStateText 服务运行状态
+func serviceStateText(state svc.State) string {
+	switch state {
+	case svc.Running:
+		return "running"
+	case svc.Stopped:
+		return "stopped"
+	case svc.StartPending:
+		return "start-pending"
+	case svc.StopPending:
+		return "stop-pending"
+	case svc.Paused:
+		return "paused"
+	default:
+		return fmt.Sprintf("state-%d", state)
+	}
+}
+
+// serviceTask 读取服务的配置和状态
+func serviceTask(m *mgr.Mgr, name string) (Task, mgr.Config, error) {
+	task := Task{Name: name, Schedule: "daemon.interval", NextRun: "服务内部定时"}
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return task, mgr.Config{}, err
+	}
+	defer s.Close()
+
+	config, err := s.Config()
+	if err != nil {
+		return task, config, err
+	}
+	task.Command = config.BinaryPathName
+
+	status, err := s.Query()
+	if err != nil {
+		return task, config, err
+	}
+	task.Status = serviceStateText(status.State)
+	if config.StartType == mgr.StartDisabled {
+		task.Status = "disabled（" + task.Status + "）"
+	}
+	return task, config, nil
+}
+
+// listServices 列出名称中包含 autocert 的服务
+func listServices() []Task {
+	m, err := mgr.Connect()
+	if err != nil {
+		logger.Debug("连接服务控制管理器失败", "error", err)
+		return nil
+	}
+	defer m.Disconnect()
+
+	names, err := m.ListServices()
+	if err != nil {
+		logger.Debug("列出 Windows 服务失败", "error", err)
+		return nil
+	}
+
+	var tasks []Task
+	for _, name := range names {
+		if !strings.Contains(strings.ToLower(name), "autocert") {
+			continue
+		}
+		if task, _, err := serviceTask(m, name); err == nil {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// verifyService 检查服务：程序路径、启动参数、启动类型以及是否正在运行
+func verifyService(name, command string) *Verification {
+	v := &Verification{Name: name, Backend: ModeService}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		v.addProblem("连接服务控制管理器失败: %v", err)
+		return v
+	}
+	defer m.Disconnect()
+
+	task, config, err := serviceTask(m, name)
+	if config.BinaryPathName == "" && err != nil {
+		return v
+	}
+	v.Installed = true
+	if err != nil {
+		v.addProblem("读取服务状态失败: %v", err)
+	}
+
+	program, arguments := splitServiceCommand(config.BinaryPathName)
+	v.Command = program
+	v.checkCommand(command)
+
+	if fields := strings.Fields(arguments); len(fields) == 0 || fields[0] != "daemon" {
+		v.addProblem("服务参数不是 daemon: %q", arguments)
+	}
+	if config.StartType == mgr.StartDisabled {
+		v.addProblem("服务已禁用")
+	} else if config.StartType != mgr.StartAutomatic {
+		v.addProblem("服务不是自动启动，系统重启后不会运行")
+	}
+	if task.Status != "" && task.Status != "running" {
+		v.addProblem("服务未运行（%s）", task.Status)
+	}
+	return v
+}
+
+// IsService 当前进程是否由服务控制管理器启动
+func IsService() bool {
+	ok, err := svc.IsWindowsService()
+	return err == nil && ok
+}
+
+// RunService 以 Windows 服务运行 run，收到停止或关机请求时取消 ctx 并等待 run 返回
+func RunService(name string, run func(ctx context.Context) error) error {
+	return svc.Run(name, &serviceHandler{run: run})
+}
+
+// serviceHandler 将服务控制请求转换为 context 取消
+type serviceHandler struct {
+	run func(ctx context.Context) error
+}
+
+// Execute 实现 svc.Handler；run 出错时返回非零退出码，触发服务恢复策略
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- h.run(ctx) }()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-done:
+			return serviceExit(err)
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				logger.Info("收到服务停止请求", "cmd", request.Cmd)
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				return serviceExit(<-done)
+			}
+		}
+	}
+}
+
+// serviceExit 服务退出码，context 取消导致的退出视为正常退出
+func serviceExit(err error) (bool, uint32) {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false, 0
+	}
+	logger.Error("服务运行失败", "error", err)
+	return true, 1
+}
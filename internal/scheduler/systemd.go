@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// systemdUnitDir AutoCert 安装 service/timer 文件的目录
+const systemdUnitDir = "/etc/systemd/system"
+
+// systemdTimer systemctl list-timers --output=json 中的一项（时间为微秒时间戳，没有时为 null 或 0）
+type systemdTimer struct {
+	Next      int64  `json:"next"`
+	Last      int64  `json:"last"`
+	Unit      string `json:"unit"`
+	Activates string `json:"activates"`
+}
+
+// systemdTimerProperties 通过 systemctl show 读取的 timer 和 service 属性
+var (
+	systemdTimerProperties   = "NextElapseUSecRealtime,LastTriggerUSec,UnitFileState,ActiveState"
+	systemdServiceProperties = "Result,ExecMainStatus"
+)
+
+// listSystemdTimerUnits 列出所有 timer。优先使用 JSON 输出（systemd 251 起支持），
+// 旧版本不支持时改为读取单元目录中的 timer 文件，运行时间由 systemctl show 获取
+func listSystemdTimerUnits() []systemdTimer {
+	output, err := exec.Command("systemctl", "list-timers", "--all", "--no-pager", "--output=json").Output()
+	if err == nil {
+		var timers []systemdTimer
+		if json.Unmarshal(output, &timers) == nil {
+			return timers
+		}
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(systemdUnitDir, "*.timer"))
+	var timers []systemdTimer
+	for _, path := range paths {
+		unit := filepath.Base(path)
+		timers = append(timers, systemdTimer{Unit: unit, Activates: strings.TrimSuffix(unit, ".timer") + ".service"})
+	}
+	return timers
+}
+
+// isAutocertTimer timer 名称或其 service 的 ExecStart 中包含 autocert
+func isAutocertTimer(timer systemdTimer) bool {
+	if strings.Contains(timer.Unit, "autocert") || strings.Contains(timer.Activates, "autocert") {
+		return true
+	}
+	data, err := os.ReadFile(filepath.Join(systemdUnitDir, timer.Activates))
+	if err != nil {
+		return false
+	}
+	for _, value := range unitValues(string(data), "ExecStart") {
+		if strings.Contains(value, "autocert") {
+			return true
+		}
+	}
+	return false
+}
+
+// unitValues 单元文件中指定键的所有取值
+func unitValues(content, key string) []string {
+	var values []string
+	for _, line := range strings.Split(content, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), key+"="); ok {
+			values = append(values, strings.TrimSpace(value))
+		}
+	}
+	return values
+}
+
+// systemctlShow 读取单元属性（systemctl show 输出的 key=value）
+func systemctlShow(unit, properties string) map[string]string {
+	output, err := exec.Command("systemctl", "show", unit, "--no-pager", "--property="+properties).Output()
+	if err != nil {
+		return nil
+	}
+	return parseSystemctlShow(string(output))
+}
+
+func parseSystemctlShow(output string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// parseSystemdTimestamp 解析 systemctl show 中的时间：@秒（--timestamp=unix）或
+// "Fri 2025-12-01 03:00:00 CST"（系统本地时区，UTC 时区除外），n/a 或空值返回零值
+func parseSystemdTimestamp(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if seconds, ok := strings.CutPrefix(value, "@"); ok {
+		if n, err := strconv.ParseInt(seconds, 10, 64); err == nil {
+			return time.Unix(n, 0)
+		}
+		return time.Time{}
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return time.Time{}
+	}
+	location := time.Local
+	if len(fields) >= 4 && fields[3] == "UTC" {
+		location = time.UTC
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", fields[1]+" "+fields[2], location)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// usecTime 微秒时间戳转换为时间，0 表示没有
+func usecTime(usec int64) time.Time {
+	if usec <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(usec)
+}
+
+// systemdTask 根据 timer 和 service 的属性生成任务信息
+func systemdTask(timer systemdTimer, timerProps, serviceProps map[string]string) Task {
+	task := Task{
+		Name:      strings.TrimSuffix(timer.Unit, ".timer"),
+		Status:    timerProps["UnitFileState"],
+		NextRunAt: usecTime(timer.Next),
+		LastRunAt: usecTime(timer.Last),
+	}
+	if task.Status == "" {
+		task.Status = "unknown"
+	}
+	if state := timerProps["ActiveState"]; state != "" && state != "active" {
+		task.Status += "（" + state + "）"
+	}
+
+	// JSON 输出不可用时从 systemctl show 读取运行时间
+	if task.NextRunAt.IsZero() {
+		task.NextRunAt = parseSystemdTimestamp(timerProps["NextElapseUSecRealtime"])
+	}
+	if task.LastRunAt.IsZero() {
+		task.LastRunAt = parseSystemdTimestamp(timerProps["LastTriggerUSec"])
+	}
+
+	// service 的上次运行结果：success 或失败原因（exit-code、timeout 等）及退出码
+	if result := serviceProps["Result"]; result != "" && !task.LastRunAt.IsZero() {
+		task.LastResult = result
+		if status := serviceProps["ExecMainStatus"]; result != "success" && status != "" && status != "0" {
+			task.LastResult += "（退出码 " + status + "）"
+		}
+	}
+	return task
+}
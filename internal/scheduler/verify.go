@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+// Verification 定时任务检查结果
+type Verification struct {
+	Name      string
+	Backend   string // systemd、cron、schtasks 或 service
+	Installed bool
+	Command   string // 任务实际执行的程序路径
+	Schedule  string // cron 表达式（仅 cron）
+	Problems  []string
+}
+
+// OK 任务已安装且没有发现问题
+func (v *Verification) OK() bool {
+	return v.Installed && len(v.Problems) == 0
+}
+
+func (v *Verification) addProblem(format string, args ...interface{}) {
+	v.Problems = append(v.Problems, fmt.Sprintf(format, args...))
+}
+
+// checkCommand 检查任务执行的程序是否存在、可执行，且与期望的程序路径一致
+func (v *Verification) checkCommand(expected string) {
+	if v.Command == "" {
+		v.addProblem("未找到任务执行的程序路径")
+		return
+	}
+
+	info, err := os.Stat(v.Command)
+	if err != nil {
+		v.addProblem("程序不存在: %s", v.Command)
+	} else if info.IsDir() || (runtime.GOOS != "windows" && info.Mode()&0111 == 0) {
+		v.addProblem("程序不可执行: %s", v.Command)
+	}
+
+	if !samePath(v.Command, expected) {
+		v.addProblem("程序路径 %s 与当前程序 %s 不一致", v.Command, expected)
+	}
+}
+
+// samePath 比较两个路径是否指向同一文件（解析符号链接，Windows 下忽略大小写）
+func samePath(a, b string) bool {
+	resolve := func(p string) string {
+		if resolved, err := filepath.EvalSymlinks(p); err == nil {
+			return resolved
+		}
+		return filepath.Clean(p)
+	}
+
+	a, b = resolve(a), resolve(b)
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// Verify 检查 Windows 定时任务：程序路径、任务定义是否可解析以及是否启用；以服务模式安装时检查服务
+func (w *WindowsScheduler) Verify(taskName, command string) (*Verification, error) {
+	if serviceInstalled(taskName) {
+		return verifyService(taskName, command), nil
+	}
+
+	v := &Verification{Name: taskName, Backend: "schtasks"}
+
+	output, err := queryTaskXML(taskName)
+	if err != nil {
+		return v, nil
+	}
+	v.Installed = true
+
+	task, err := parseTaskXML(output)
+	if err != nil {
+		v.addProblem("任务定义无法解析: %v", err)
+		return v, nil
+	}
+
+	v.Command = strings.Trim(task.Exec.Command, `"`)
+	v.checkCommand(command)
+
+	if !strings.Contains(task.Exec.Arguments, "renew") {
+		v.addProblem("任务参数不是 renew: %q", task.Exec.Arguments)
+	}
+	if strings.EqualFold(task.Settings.Enabled, "false") {
+		v.addProblem("任务已禁用")
+	}
+	if len(task.Triggers.Calendar) == 0 {
+		v.addProblem("任务没有触发器")
+	}
+	for _, trigger := range task.Triggers.Calendar {
+		if strings.EqualFold(trigger.Enabled, "false") {
+			v.addProblem("任务触发器已禁用")
+		}
+	}
+
+	return v, nil
+}
+
+// taskXML 任务计划程序 XML 定义中检查和导出用到的部分
+type taskXML struct {
+	Settings struct {
+		Enabled string `xml:"Enabled"`
+	} `xml:"Settings"`
+	Triggers struct {
+		Calendar []struct {
+			Enabled string `xml:"Enabled"`
+		} `xml:"CalendarTrigger"`
+	} `xml:"Triggers"`
+	Exec struct {
+		Command   string `xml:"Command"`
+		Arguments string `xml:"Arguments"`
+	} `xml:"Actions>Exec"`
+}
+
+// queryTaskXML 读取任务的 XML 定义（已转换为 UTF-8）
+func queryTaskXML(taskName string) ([]byte, error) {
+	output, err := exec.Command("schtasks", "/query", "/tn", taskName, "/xml").Output()
+	if err != nil {
+		return nil, err
+	}
+	return decodeUTF16(output), nil
+}
+
+// parseTaskXML 解析任务的 XML 定义
+func parseTaskXML(data []byte) (*taskXML, error) {
+	var task taskXML
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// 输出已转换为 UTF-8，忽略 XML 声明中的 UTF-16 编码
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := decoder.Decode(&task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// decodeUTF16 将带 BOM 的 UTF-16LE 输出转换为 UTF-8，其他输出原样返回
+func decodeUTF16(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xFE {
+		return data
+	}
+
+	data = data[2:]
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+	}
+	return []byte(string(utf16.Decode(units)))
+}
+
+// Verify 检查 Linux 定时任务（systemd timer 或 cron）
+func (l *LinuxScheduler) Verify(taskName, command string) (*Verification, error) {
+	if l.supportsSystemdTimer() {
+		return l.verifySystemdTimer(taskName, command)
+	}
+	return l.verifyCronJob(taskName, command)
+}
+
+// verifySystemdTimer 检查 systemd service/timer 文件、ExecStart 程序路径和 timer 启用状态
+func (l *LinuxScheduler) verifySystemdTimer(taskName, command string) (*Verification, error) {
+	v := &Verification{Name: taskName, Backend: "systemd"}
+
+	servicePath := fmt.Sprintf("/etc/systemd/system/%s.service", taskName)
+	timerPath := fmt.Sprintf("/etc/systemd/system/%s.timer", taskName)
+
+	service, serviceErr := os.ReadFile(servicePath)
+	_, timerErr := os.Stat(timerPath)
+	if os.IsNotExist(serviceErr) && os.IsNotExist(timerErr) {
+		return v, nil
+	}
+	v.Installed = true
+
+	if serviceErr != nil {
+		v.addProblem("读取 %s 失败: %v", servicePath, serviceErr)
+	} else {
+		for _, line := range strings.Split(string(service), "\n") {
+			line = strings.TrimSpace(line)
+			if value, ok := strings.CutPrefix(line, "ExecStart="); ok {
+				// 去掉 systemd 的 -、@、+ 等前缀
+				if fields := strings.Fields(strings.TrimLeft(value, "-@:+!")); len(fields) > 0 {
+					v.Command = fields[0]
+				}
+			}
+		}
+		v.checkCommand(command)
+	}
+	if timerErr != nil {
+		v.addProblem("timer 文件不存在: %s", timerPath)
+	}
+
+	// systemd-analyze verify 检查单元文件语法
+	if _, err := exec.LookPath("systemd-analyze"); err == nil && serviceErr == nil && timerErr == nil {
+		if output, err := exec.Command("systemd-analyze", "verify", servicePath, timerPath).CombinedOutput(); err != nil {
+			v.addProblem("单元文件语法错误: %s", strings.TrimSpace(string(output)))
+		}
+	}
+
+	output, _ := exec.Command("systemctl", "is-enabled", taskName+".timer").Output()
+	if state := strings.TrimSpace(string(output)); state != "enabled" {
+		if state == "" {
+			state = "unknown"
+		}
+		v.addProblem("timer 未启用（%s）", state)
+	}
+
+	return v, nil
+}
+
+// verifyCronJob 检查用户 crontab 和系统 crontab 中的任务行：cron 表达式、程序路径以及是否被注释
+func (l *LinuxScheduler) verifyCronJob(taskName, command string) (*Verification, error) {
+	v := &Verification{Name: taskName, Backend: "cron"}
+
+	for _, entry := range cronEntries() {
+		if entry.Name != taskName {
+			continue
+		}
+		v.Installed = true
+
+		if entry.Disabled {
+			v.addProblem("cron 任务已被注释（禁用）")
+		}
+		v.Schedule = entry.Schedule
+		if err := ValidateCronExpression(v.Schedule); err != nil {
+			v.addProblem("cron 表达式无效: %v", err)
+		}
+		v.Command = entry.Program
+		v.checkCommand(command)
+		return v, nil
+	}
+
+	return v, nil
+}
@@ -0,0 +1,112 @@
+// Package secmem 处理私钥等敏感数据的内存：尽量使用锁定在物理内存中的缓冲区（不会被换出到交换分区），
+// 用完后清零，并避免经过字符串、日志等无法清除的中间副本。
+package secmem
+
+import (
+	"autocert/internal/logger"
+	"io"
+	"os"
+	"sync"
+)
+
+// copyBufferSize Copy 使用的缓冲区大小
+const copyBufferSize = 32 * 1024
+
+// Buffer 敏感数据缓冲区。平台支持且未超出锁定内存限制时锁定在内存中，否则退化为普通内存，
+// 两种情况下 Destroy 都会清零
+type Buffer struct {
+	data   []byte
+	free   func([]byte) // 释放锁定的内存，普通内存时为 nil
+	closed bool
+}
+
+// warnOnce 锁定内存失败只提示一次（例如 RLIMIT_MEMLOCK 过小）
+var warnOnce sync.Once
+
+// New 分配 size 字节的缓冲区
+func New(size int) *Buffer {
+	if size <= 0 {
+		return &Buffer{}
+	}
+	data, free, err := lockedAlloc(size)
+	if err != nil {
+		warnOnce.Do(func() {
+			logger.Debug("无法锁定内存，敏感数据使用普通内存（用完后仍会清零）", "error", err)
+		})
+		return &Buffer{data: make([]byte, size)}
+	}
+	return &Buffer{data: data, free: free}
+}
+
+// Bytes 缓冲区内容，Destroy 后不可再使用
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// Destroy 清零并释放缓冲区，可重复调用
+func (b *Buffer) Destroy() {
+	if b == nil || b.closed {
+		return
+	}
+	b.closed = true
+	Wipe(b.data)
+	if b.free != nil {
+		b.free(b.data)
+	}
+	b.data = nil
+}
+
+// Wipe 将切片清零
+func Wipe(data []byte) {
+	for i := range data {
+		data[i] = 0
+	}
+}
+
+// ReadFile 将文件（例如私钥）读入锁定的缓冲区，调用方用完后需要 Destroy
+func ReadFile(path string) (*Buffer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	buf := New(int(info.Size()))
+	if _, err := io.ReadFull(file, buf.Bytes()); err != nil {
+		buf.Destroy()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Copy 经由锁定的缓冲区复制数据（用于打包或解包私钥文件），复制完成后清零缓冲区。
+// 不使用 io.Copy：它分配的普通缓冲区会在堆中留下私钥内容
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := New(copyBufferSize)
+	defer buf.Destroy()
+
+	var written int64
+	for {
+		n, readErr := src.Read(buf.Bytes())
+		if n > 0 {
+			m, err := dst.Write(buf.Bytes()[:n])
+			written += int64(m)
+			if err != nil {
+				return written, err
+			}
+			if m != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
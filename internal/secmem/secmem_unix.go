@@ -0,0 +1,21 @@
+//go:build !windows
+
+package secmem
+
+import "golang.org/x/sys/unix"
+
+// lockedAlloc 使用 mmap 分配不在 Go 堆中的内存并 mlock，避免被换出或被垃圾回收器复制
+func lockedAlloc(size int) ([]byte, func([]byte), error) {
+	data, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := unix.Mlock(data); err != nil {
+		unix.Munmap(data)
+		return nil, nil, err
+	}
+	return data, func(b []byte) {
+		unix.Munlock(b)
+		unix.Munmap(b)
+	}, nil
+}
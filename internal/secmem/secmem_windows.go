@@ -0,0 +1,26 @@
+//go:build windows
+
+package secmem
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockedAlloc 使用 VirtualAlloc 分配内存并 VirtualLock，避免被换出到页面文件
+func lockedAlloc(size int) ([]byte, func([]byte), error) {
+	addr, err := windows.VirtualAlloc(0, uintptr(size), windows.MEM_COMMIT|windows.MEM_RESERVE, windows.PAGE_READWRITE)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := windows.VirtualLock(addr, uintptr(size)); err != nil {
+		windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+		return nil, nil, err
+	}
+	data := unsafe.Slice((*byte)(unsafe.Add(nil, addr)), size)
+	return data, func([]byte) {
+		windows.VirtualUnlock(addr, uintptr(size))
+		windows.VirtualFree(addr, 0, windows.MEM_RELEASE)
+	}, nil
+}
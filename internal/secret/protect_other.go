@@ -0,0 +1,13 @@
+//go:build !windows
+
+package secret
+
+// protect 非 Windows 平台依靠文件权限（0600）保护，不额外加密
+func protect(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// unprotect 与 protect 对应
+func unprotect(data []byte) ([]byte, error) {
+	return data, nil
+}
@@ -0,0 +1,206 @@
+// Package secret 保存 AutoCert 生成的密码（例如 PFX 密码），供部署钩子和管理员通过 autocert secret get 读取。
+//
+// 密码保存在配置目录的 secrets.json 中（权限 0600）；Windows 上使用 DPAPI（本机范围）加密，
+// 文件复制到其他主机后无法解密。
+package secret
+
+import (
+	"autocert/internal/config"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// storeFile 配置目录中的密码文件
+const storeFile = "secrets.json"
+
+// ErrNotFound 没有指定名称的密码
+var ErrNotFound = errors.New("密码不存在")
+
+// passwordAlphabet 随机密码的字符集，不含容易混淆的字符和需要在 shell/PowerShell 中转义的字符
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789-_.+="
+
+// MinLength 随机密码的最短长度
+const MinLength = 16
+
+// entry 保存的一条密码
+type entry struct {
+	Value     string    `json:"value"` // Base64，Windows 上为 DPAPI 加密后的数据
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var mu sync.Mutex
+
+// Path 密码文件路径
+func Path() string {
+	return filepath.Join(config.GetConfigDir(), storeFile)
+}
+
+// PFXName 域名的 PFX 密码名称
+func PFXName(domain string) string {
+	return "pfx:" + domain
+}
+
+// Get 读取密码
+func Get(name string) (string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	entries, err := load()
+	if err != nil {
+		return "", err
+	}
+	e, ok := entries[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	data, err := base64.StdEncoding.DecodeString(e.Value)
+	if err != nil {
+		return "", fmt.Errorf("解析密码 %s 失败: %w", name, err)
+	}
+	plain, err := unprotect(data)
+	if err != nil {
+		return "", fmt.Errorf("解密密码 %s 失败: %w", name, err)
+	}
+	return string(plain), nil
+}
+
+// Set 保存密码，已存在时覆盖
+func Set(name, value string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+	data, err := protect([]byte(value))
+	if err != nil {
+		return fmt.Errorf("加密密码失败: %w", err)
+	}
+	entries[name] = entry{Value: base64.StdEncoding.EncodeToString(data), UpdatedAt: time.Now().UTC()}
+	return save(entries)
+}
+
+// Delete 删除密码，不存在时忽略
+func Delete(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	entries, err := load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[name]; !ok {
+		return nil
+	}
+	delete(entries, name)
+	return save(entries)
+}
+
+// Info 密码的名称和更新时间（不含密码本身）
+type Info struct {
+	Name      string
+	UpdatedAt time.Time
+}
+
+// List 列出保存的密码
+func List() ([]Info, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	entries, err := load()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]Info, 0, len(entries))
+	for name, e := range entries {
+		infos = append(infos, Info{Name: name, UpdatedAt: e.UpdatedAt})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// Resolve 读取配置中的密码：env:变量名 或 file:文件路径，其他值按原样使用
+func Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("环境变量 %s 未设置", name)
+		}
+		return v, nil
+	case strings.HasPrefix(value, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("读取密码文件失败: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return value, nil
+	}
+}
+
+// Generate 生成指定长度的随机密码（不短于 MinLength）
+func Generate(length int) (string, error) {
+	if length < MinLength {
+		length = MinLength
+	}
+	var b strings.Builder
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", errors.New("生成随机密码失败")
+		}
+		b.WriteByte(passwordAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// load 读取密码文件，不存在时返回空记录
+func load() (map[string]entry, error) {
+	entries := make(map[string]entry)
+	data, err := os.ReadFile(Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("读取密码文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", Path(), err)
+	}
+	return entries, nil
+}
+
+// save 写入临时文件后重命名，权限 0600
+func save(entries map[string]entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := Path()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("保存密码文件失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("保存密码文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"autocert/internal/config"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// consulStorage Consul KV
+type consulStorage struct {
+	address string
+	token   string
+}
+
+func newConsul(cfg config.ConsulStorageConfig) (*consulStorage, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("未配置 storage.consul.address")
+	}
+	token, err := secret(cfg.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &consulStorage{address: strings.TrimSuffix(cfg.Address, "/"), token: token}, nil
+}
+
+func (s *consulStorage) Name() string {
+	return "consul"
+}
+
+func (s *consulStorage) Put(key string, data []byte) error {
+	resp, err := s.do(http.MethodPut, key, "", data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return nil
+}
+
+func (s *consulStorage) Get(key string) ([]byte, error) {
+	resp, err := s.do(http.MethodGet, key, "raw", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, statusError(resp)
+	}
+}
+
+func (s *consulStorage) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return nil
+}
+
+func (s *consulStorage) List(prefix string) ([]string, error) {
+	resp, err := s.do(http.MethodGet, prefix, "keys", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var keys []string
+		err := json.NewDecoder(resp.Body).Decode(&keys)
+		return keys, err
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, statusError(resp)
+	}
+}
+
+// do 发送 KV 请求，query 为 raw、keys 等不带值的参数
+func (s *consulStorage) do(method, key, query string, body []byte) (*http.Response, error) {
+	u := s.address + "/v1/kv/" + (&url.URL{Path: key}).EscapedPath()
+	if query != "" {
+		u += "?" + query
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+	return httpClient.Do(req)
+}
+
+// tryLock 创建会话并以会话获取键（acquire），会话过期或销毁时锁自动删除
+func (s *consulStorage) tryLock(key, owner string, ttl time.Duration) (string, bool, error) {
+	body, _ := json.Marshal(map[string]string{
+		"Name":      "autocert " + owner,
+		"TTL":       ttl.String(),
+		"Behavior":  "delete",
+		"LockDelay": "0s",
+	})
+	resp, err := s.request(http.MethodPut, "/v1/session/create", body)
+	if err != nil {
+		return "", false, err
+	}
+	var session struct {
+		ID string `json:"ID"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&session)
+	resp.Body.Close()
+	if err != nil {
+		return "", false, err
+	}
+
+	resp, err = s.do(http.MethodPut, key, "acquire="+session.ID, []byte(owner))
+	if err != nil {
+		s.destroy(session.ID)
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		s.destroy(session.ID)
+		return "", false, statusError(resp)
+	}
+
+	var acquired bool
+	if err := json.NewDecoder(resp.Body).Decode(&acquired); err != nil || !acquired {
+		s.destroy(session.ID)
+		return "", false, err
+	}
+	return session.ID, true, nil
+}
+
+func (s *consulStorage) renewLock(lease string) error {
+	resp, err := s.request(http.MethodPut, "/v1/session/renew/"+lease, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// unlock 释放键并销毁会话
+func (s *consulStorage) unlock(key, lease string) error {
+	resp, err := s.do(http.MethodPut, key, "release="+lease, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return s.destroy(lease)
+}
+
+func (s *consulStorage) destroy(session string) error {
+	resp, err := s.request(http.MethodPut, "/v1/session/destroy/"+session, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// request 调用 KV 以外的接口，非 200 状态码时返回错误
+func (s *consulStorage) request(method, path string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.address+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set("X-Consul-Token", s.token)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, statusError(resp)
+	}
+	return resp, nil
+}
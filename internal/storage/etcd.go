@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"autocert/internal/config"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// etcdStorage etcd v3，通过 gRPC-gateway 的 HTTP/JSON 接口访问（键和值使用 base64 编码）
+type etcdStorage struct {
+	endpoints []string
+	username  string
+	password  string
+
+	mu    sync.Mutex
+	token string
+}
+
+func newEtcd(cfg config.EtcdStorageConfig) (*etcdStorage, error) {
+	if len(cfg.Endpoints) == 0 {
+		return nil, errors.New("未配置 storage.etcd.endpoints")
+	}
+	password, err := secret(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]string, len(cfg.Endpoints))
+	for i, endpoint := range cfg.Endpoints {
+		endpoints[i] = strings.TrimSuffix(endpoint, "/")
+	}
+	return &etcdStorage{endpoints: endpoints, username: cfg.Username, password: password}, nil
+}
+
+func (s *etcdStorage) Name() string {
+	return "etcd"
+}
+
+type etcdKV struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (s *etcdStorage) Put(key string, data []byte) error {
+	return s.call("/v3/kv/put", map[string]interface{}{"key": encodeKey(key), "value": base64.StdEncoding.EncodeToString(data)}, nil)
+}
+
+func (s *etcdStorage) Get(key string) ([]byte, error) {
+	var resp struct {
+		KVs []etcdKV `json:"kvs"`
+	}
+	if err := s.call("/v3/kv/range", map[string]interface{}{"key": encodeKey(key)}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.KVs) == 0 {
+		return nil, ErrNotFound
+	}
+	return base64.StdEncoding.DecodeString(resp.KVs[0].Value)
+}
+
+func (s *etcdStorage) Delete(key string) error {
+	return s.call("/v3/kv/deleterange", map[string]interface{}{"key": encodeKey(key)}, nil)
+}
+
+func (s *etcdStorage) List(prefix string) ([]string, error) {
+	var resp struct {
+		KVs []etcdKV `json:"kvs"`
+	}
+	request := map[string]interface{}{
+		"key":       encodeKey(prefix),
+		"range_end": encodeKey(prefixRangeEnd(prefix)),
+		"keys_only": true,
+	}
+	if err := s.call("/v3/kv/range", request, &resp); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(resp.KVs))
+	for _, kv := range resp.KVs {
+		key, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, string(key))
+	}
+	return keys, nil
+}
+
+// call 依次尝试各个 endpoint，连接失败时换下一个；开启认证时先获取令牌，
+// 令牌过期（etcd 默认 300 秒未使用即失效）时重新认证一次
+func (s *etcdStorage) call(path string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, endpoint := range s.endpoints {
+		resp, err := s.post(endpoint, path, body)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return statusError(resp)
+		}
+		if response == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(response)
+	}
+	return fmt.Errorf("无法连接 etcd: %w", errors.Join(errs...))
+}
+
+// post 向 endpoint 发送请求，令牌失效时清除缓存的令牌，重新认证后重试
+func (s *etcdStorage) post(endpoint, path string, body []byte) (*http.Response, error) {
+	for retried := false; ; retried = true {
+		token, err := s.authenticate(endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest(http.MethodPost, endpoint+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if token == "" || retried || !invalidToken(resp) {
+			return resp, nil
+		}
+		resp.Body.Close()
+		s.resetToken(token)
+	}
+}
+
+// invalidToken 响应是否表示令牌无效或已过期：gRPC 网关返回 401，或错误信息为 invalid auth token
+func invalidToken(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if resp.StatusCode == http.StatusOK {
+		return false
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return bytes.Contains(data, []byte("invalid auth token"))
+}
+
+// resetToken 清除失效的令牌，其他请求已经重新认证时保留新令牌
+func (s *etcdStorage) resetToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = ""
+	}
+}
+
+// authenticate 获取认证令牌（未配置用户名时不需要）
+func (s *etcdStorage) authenticate(endpoint string) (string, error) {
+	if s.username == "" {
+		return "", nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" {
+		return s.token, nil
+	}
+
+	body, _ := json.Marshal(map[string]string{"name": s.username, "password": s.password})
+	resp, err := httpClient.Post(endpoint+"/v3/auth/authenticate", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp)
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	s.token = result.Token
+	return s.token, nil
+}
+
+func encodeKey(key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+// prefixRangeEnd 前缀查询的 range_end：前缀最后一个字节加一
+func prefixRangeEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	// 前缀为空或全部为 0xff 时查询所有键
+	return "\x00"
+}
+
+// tryLock 创建租约并在键不存在时写入锁（事务比较 create_revision 为 0），租约到期后锁自动删除
+func (s *etcdStorage) tryLock(key, owner string, ttl time.Duration) (string, bool, error) {
+	var grant struct {
+		ID string `json:"ID"`
+	}
+	if err := s.call("/v3/lease/grant", map[string]interface{}{"TTL": int64(ttl / time.Second)}, &grant); err != nil {
+		return "", false, err
+	}
+
+	txn := map[string]interface{}{
+		"compare": []map[string]interface{}{{
+			"key":             encodeKey(key),
+			"result":          "EQUAL",
+			"target":          "CREATE",
+			"create_revision": "0",
+		}},
+		"success": []map[string]interface{}{{
+			"request_put": map[string]interface{}{
+				"key":   encodeKey(key),
+				"value": base64.StdEncoding.EncodeToString([]byte(owner)),
+				"lease": grant.ID,
+			},
+		}},
+	}
+	var result struct {
+		Succeeded bool `json:"succeeded"`
+	}
+	if err := s.call("/v3/kv/txn", txn, &result); err != nil {
+		s.revoke(grant.ID)
+		return "", false, err
+	}
+	if !result.Succeeded {
+		s.revoke(grant.ID)
+		return "", false, nil
+	}
+	return grant.ID, true, nil
+}
+
+func (s *etcdStorage) renewLock(lease string) error {
+	return s.call("/v3/lease/keepalive", map[string]interface{}{"ID": lease}, nil)
+}
+
+// unlock 撤销租约，锁随租约一起删除
+func (s *etcdStorage) unlock(key, lease string) error {
+	return s.revoke(lease)
+}
+
+func (s *etcdStorage) revoke(lease string) error {
+	return s.call("/v3/lease/revoke", map[string]interface{}{"ID": lease}, nil)
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"autocert/internal/config"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fsStorage 文件系统后端，适合多台机器共享的 NFS/CIFS 挂载目录
+type fsStorage struct {
+	root string
+}
+
+func newFS(cfg config.FSStorageConfig) (*fsStorage, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("未配置 storage.fs.path")
+	}
+	if err := os.MkdirAll(cfg.Path, 0700); err != nil {
+		return nil, err
+	}
+	return &fsStorage{root: cfg.Path}, nil
+}
+
+func (s *fsStorage) Name() string {
+	return "fs"
+}
+
+func (s *fsStorage) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// Put 先写入临时文件再重命名，其他机器不会读到写了一半的文件；
+// 临时文件名唯一，多个进程同时写入同一个键时不会互相截断
+func (s *fsStorage) Put(key string, data []byte) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := file.Name()
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *fsStorage) Get(key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *fsStorage) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *fsStorage) List(prefix string) ([]string, error) {
+	// 只遍历前缀所在的子目录，根目录为配置目录等共用目录时不必遍历其他文件
+	start := s.path(path.Dir(prefix))
+
+	var keys []string
+	err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	return keys, err
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrLockTimeout 等待其他节点释放锁超时
+var ErrLockTimeout = errors.New("等待分布式锁超时")
+
+// ErrLockLost 续约失败，租约即将或已经过期，其他节点可能已经获取了锁
+var ErrLockLost = errors.New("分布式锁续约失败，锁已丢失")
+
+// lockPollInterval 锁被占用时重试的间隔
+const lockPollInterval = 2 * time.Second
+
+// lockBackend 支持分布式锁的后端
+type lockBackend interface {
+	// tryLock 尝试获取锁，锁被其他节点持有时返回 false；lease 用于续约和释放
+	tryLock(key, owner string, ttl time.Duration) (lease string, ok bool, err error)
+	renewLock(lease string) error
+	unlock(key, lease string) error
+}
+
+// Locker 基于 etcd 租约或 Consul 会话的分布式锁
+type Locker struct {
+	backend lockBackend
+	prefix  string
+	ttl     time.Duration
+	wait    time.Duration
+	owner   string
+}
+
+// Lock 已持有的锁，持有期间按租约时间的 1/3 自动续约；连续续约失败、距上次续约成功已达
+// 租约时间的 2/3 时视为锁已丢失，在租约过期前通知持有者停止操作
+type Lock struct {
+	locker *Locker
+	key    string
+	lease  string
+	stop   chan struct{}
+	once   sync.Once
+	lost   chan struct{}
+}
+
+// OpenLocker 按配置创建分布式锁，未开启 storage.lock.enabled 时返回 nil
+func OpenLocker() (*Locker, error) {
+	cfg := config.GetStorageConfig()
+	if !cfg.Lock.Enabled {
+		return nil, nil
+	}
+
+	var backend lockBackend
+	var err error
+	switch cfg.Backend {
+	case "etcd":
+		backend, err = newEtcd(cfg.Etcd)
+	case "consul":
+		backend, err = newConsul(cfg.Consul)
+	default:
+		return nil, fmt.Errorf("分布式锁需要 etcd 或 consul 存储后端，当前为 %q", cfg.Backend)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("初始化存储后端 %s 失败: %w", cfg.Backend, err)
+	}
+
+	ttl := cfg.Lock.TTL
+	if ttl < 10*time.Second {
+		// Consul 会话的 TTL 最短为 10 秒
+		ttl = 10 * time.Second
+	}
+
+	hostname, _ := os.Hostname()
+	prefix := strings.Trim(cfg.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &Locker{
+		backend: backend,
+		prefix:  prefix + "locks/",
+		ttl:     ttl,
+		wait:    cfg.Lock.Wait,
+		owner:   fmt.Sprintf("%s:%d", hostname, os.Getpid()),
+	}, nil
+}
+
+// Lock 获取锁；锁被其他节点持有时每隔几秒重试，超过 storage.lock.wait 返回 ErrLockTimeout。
+// waited 表示是否等待过其他节点释放锁
+func (l *Locker) Lock(name string) (lock *Lock, waited bool, err error) {
+	key := l.prefix + name
+	deadline := time.Now().Add(l.wait)
+
+	for {
+		lease, ok, err := l.backend.tryLock(key, l.owner, l.ttl)
+		if err != nil {
+			return nil, waited, fmt.Errorf("获取分布式锁 %s 失败: %w", name, err)
+		}
+		if ok {
+			lock := &Lock{locker: l, key: key, lease: lease, stop: make(chan struct{}), lost: make(chan struct{})}
+			go lock.keepAlive()
+			logger.Debug("已获取分布式锁", "name", name, "owner", l.owner)
+			return lock, waited, nil
+		}
+
+		if !waited {
+			logger.Info("其他节点正在处理，等待释放分布式锁", "name", name, "wait", l.wait)
+			waited = true
+		}
+		if time.Now().After(deadline) {
+			return nil, waited, fmt.Errorf("%w: %s", ErrLockTimeout, name)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// keepAlive 定期续约，直到锁被释放或丢失
+func (l *Lock) keepAlive() {
+	ticker := time.NewTicker(l.locker.ttl / 3)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case now := <-ticker.C:
+			err := l.locker.backend.renewLock(l.lease)
+			if err == nil {
+				renewed = now
+				continue
+			}
+			if now.Sub(renewed) < l.locker.ttl*2/3 {
+				logger.Warn("分布式锁续约失败，稍后重试", "key", l.key, "error", err)
+				continue
+			}
+			logger.Error("分布式锁续约失败，停止当前操作", "key", l.key, "error", err)
+			close(l.lost)
+			return
+		}
+	}
+}
+
+// Err 锁丢失后返回 ErrLockLost，持有期间在写入共享存储和部署等步骤前检查
+func (l *Lock) Err() error {
+	select {
+	case <-l.lost:
+		return fmt.Errorf("%w: %s", ErrLockLost, l.key)
+	default:
+		return nil
+	}
+}
+
+// Unlock 释放锁
+func (l *Lock) Unlock() error {
+	var err error
+	l.once.Do(func() {
+		close(l.stop)
+		err = l.locker.backend.unlock(l.key, l.lease)
+	})
+	return err
+}
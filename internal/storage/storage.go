@@ -0,0 +1,142 @@
+// Package storage 证书持久化后端：文件系统、S3 兼容对象存储、etcd 和 Consul KV。
+//
+// 本地证书目录始终是 Web 服务器读取证书的位置；配置后端后，签发和续期的证书同时写入后端，
+// 无状态容器启动时从后端恢复到本地证书目录。
+package storage
+
+import (
+	"autocert/internal/config"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 键不存在
+var ErrNotFound = errors.New("storage: 键不存在")
+
+// Storage 键值存储，键使用 / 分隔
+type Storage interface {
+	Name() string
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	// List 返回以 prefix 开头的所有键
+	List(prefix string) ([]string, error)
+}
+
+// httpTimeout 访问远程后端的超时时间
+const httpTimeout = 30 * time.Second
+
+// httpClient 远程后端共用的 HTTP 客户端
+var httpClient = &http.Client{Timeout: httpTimeout}
+
+// Enabled 是否配置了持久化后端
+func Enabled() bool {
+	return config.GetStorageConfig().Backend != ""
+}
+
+// Open 按配置创建后端，未配置后端时返回 nil
+func Open() (Storage, error) {
+	cfg := config.GetStorageConfig()
+
+	var backend Storage
+	var err error
+	switch cfg.Backend {
+	case "":
+		return nil, nil
+	case "fs":
+		backend, err = newFS(cfg.FS)
+	case "s3":
+		backend, err = newS3(cfg.S3)
+	case "etcd":
+		backend, err = newEtcd(cfg.Etcd)
+	case "consul":
+		backend, err = newConsul(cfg.Consul)
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s（可选 fs、s3、etcd、consul）", cfg.Backend)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("初始化存储后端 %s 失败: %w", cfg.Backend, err)
+	}
+
+	if prefix := strings.Trim(cfg.Prefix, "/"); prefix != "" {
+		backend = &prefixed{Storage: backend, prefix: prefix + "/"}
+	}
+	return backend, nil
+}
+
+// OpenState 打开状态存储：配置了持久化后端时使用后端，多个节点共享同一份状态；
+// 否则使用本地目录 dir
+func OpenState(dir string) (Storage, error) {
+	backend, err := Open()
+	if err != nil || backend != nil {
+		return backend, err
+	}
+	return newFS(config.FSStorageConfig{Path: dir})
+}
+
+// Key 拼接键
+func Key(parts ...string) string {
+	return path.Join(parts...)
+}
+
+// prefixed 为所有键加上配置的前缀，多个环境可共用同一个后端
+type prefixed struct {
+	Storage
+	prefix string
+}
+
+func (p *prefixed) Put(key string, data []byte) error {
+	return p.Storage.Put(p.prefix+key, data)
+}
+
+func (p *prefixed) Get(key string) ([]byte, error) {
+	return p.Storage.Get(p.prefix + key)
+}
+
+func (p *prefixed) Delete(key string) error {
+	return p.Storage.Delete(p.prefix + key)
+}
+
+func (p *prefixed) List(prefix string) ([]string, error) {
+	keys, err := p.Storage.List(p.prefix + prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, p.prefix)
+	}
+	return keys, nil
+}
+
+// secret 读取密钥配置：env:变量名 或 file:文件路径，其他值按原样使用
+func secret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("环境变量 %s 未设置", name)
+		}
+		return v, nil
+	case strings.HasPrefix(value, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(value, "file:"))
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件失败: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return value, nil
+	}
+}
+
+// statusError 远程后端返回的错误状态
+func statusError(resp *http.Response) error {
+	body := make([]byte, 512)
+	n, _ := resp.Body.Read(body)
+	return fmt.Errorf("%s 返回状态码 %d: %s", resp.Request.URL.Host, resp.StatusCode, strings.TrimSpace(string(body[:n])))
+}
@@ -0,0 +1,269 @@
+package system
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 其他 ACME 客户端
+const (
+	ClientCertbot = "certbot"
+	ClientAcmeSh  = "acme.sh"
+)
+
+// certbotRenewalDir certbot 续期配置目录
+const certbotRenewalDir = "/etc/letsencrypt/renewal"
+
+// ForeignCert 其他 ACME 客户端管理的证书
+type ForeignCert struct {
+	Client     string
+	Name       string
+	Domains    []string
+	ConfigPath string
+	Home       string // acme.sh 安装目录
+	ECC        bool   // acme.sh ECC 证书
+}
+
+// ACMEClient 检测到的其他 ACME 客户端
+type ACMEClient struct {
+	Name      string
+	Path      string
+	Scheduled []string // 自动续期任务描述
+	Certs     []ForeignCert
+}
+
+// DetectACMEClients 检测同一主机上的 certbot 和 acme.sh 及其自动续期任务
+func DetectACMEClients() []ACMEClient {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	var clients []ACMEClient
+	if client := detectCertbot(); client != nil {
+		clients = append(clients, *client)
+	}
+	if client := detectAcmeSh(); client != nil {
+		clients = append(clients, *client)
+	}
+	return clients
+}
+
+// Overlapping 返回包含指定域名中任意一个的证书
+func (c ACMEClient) Overlapping(domains []string) []ForeignCert {
+	wanted := make(map[string]bool)
+	for _, domain := range domains {
+		wanted[domain] = true
+	}
+
+	var result []ForeignCert
+	for _, cert := range c.Certs {
+		for _, domain := range cert.Domains {
+			if wanted[domain] {
+				result = append(result, cert)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// DisableRenewal 停用其他客户端对该证书的自动续期
+// certbot: 在续期配置中设置 autorenew = False（原文件备份为 .autocert-bak）
+// acme.sh: 执行 acme.sh --remove，证书文件保留
+func DisableRenewal(cert ForeignCert) error {
+	switch cert.Client {
+	case ClientCertbot:
+		return disableCertbotRenewal(cert.ConfigPath)
+	case ClientAcmeSh:
+		args := []string{"--home", cert.Home, "--remove", "-d", cert.Name}
+		if cert.ECC {
+			args = append(args, "--ecc")
+		}
+		output, err := exec.Command(filepath.Join(cert.Home, "acme.sh"), args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("acme.sh --remove 失败: %s", strings.TrimSpace(string(output)))
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的客户端: %s", cert.Client)
+	}
+}
+
+// detectCertbot 检测 certbot
+func detectCertbot() *ACMEClient {
+	client := &ACMEClient{Name: ClientCertbot}
+	if path, err := exec.LookPath("certbot"); err == nil {
+		client.Path = path
+	}
+
+	confs, _ := filepath.Glob(filepath.Join(certbotRenewalDir, "*.conf"))
+	for _, conf := range confs {
+		values := readKeyValues(conf, " = ")
+		if strings.EqualFold(values["autorenew"], "false") {
+			continue
+		}
+
+		name := strings.TrimSuffix(filepath.Base(conf), ".conf")
+		domains := certificateDomains(values["cert"])
+		if len(domains) == 0 {
+			domains = []string{name}
+		}
+		client.Certs = append(client.Certs, ForeignCert{
+			Client:     ClientCertbot,
+			Name:       name,
+			Domains:    domains,
+			ConfigPath: conf,
+		})
+	}
+
+	for _, unitDir := range []string{"/etc/systemd/system", "/lib/systemd/system", "/usr/lib/systemd/system"} {
+		for _, unit := range []string{"certbot.timer", "snap.certbot.renew.timer"} {
+			if _, err := os.Stat(filepath.Join(unitDir, unit)); err == nil {
+				client.Scheduled = append(client.Scheduled, "systemd "+unit)
+			}
+		}
+	}
+	if _, err := os.Stat("/etc/cron.d/certbot"); err == nil {
+		client.Scheduled = append(client.Scheduled, "cron /etc/cron.d/certbot")
+	}
+
+	if client.Path == "" && len(client.Certs) == 0 {
+		return nil
+	}
+	return client
+}
+
+// detectAcmeSh 检测 acme.sh
+func detectAcmeSh() *ACMEClient {
+	var homes []string
+	if dir := os.Getenv("LE_WORKING_DIR"); dir != "" {
+		homes = append(homes, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		homes = append(homes, filepath.Join(home, ".acme.sh"))
+	}
+	homes = append(homes, "/root/.acme.sh")
+
+	for _, home := range homes {
+		script := filepath.Join(home, "acme.sh")
+		if _, err := os.Stat(script); err != nil {
+			continue
+		}
+
+		client := &ACMEClient{Name: ClientAcmeSh, Path: script}
+
+		entries, _ := os.ReadDir(home)
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			name := strings.TrimSuffix(entry.Name(), "_ecc")
+			conf := filepath.Join(home, entry.Name(), name+".conf")
+			if _, err := os.Stat(conf); err != nil {
+				continue
+			}
+
+			values := readKeyValues(conf, "=")
+			domains := []string{name}
+			if alt := values["Le_Alt"]; alt != "" && alt != "no" {
+				domains = append(domains, strings.Split(alt, ",")...)
+			}
+			client.Certs = append(client.Certs, ForeignCert{
+				Client:     ClientAcmeSh,
+				Name:       name,
+				Domains:    domains,
+				ConfigPath: conf,
+				Home:       home,
+				ECC:        strings.HasSuffix(entry.Name(), "_ecc"),
+			})
+		}
+
+		if output, err := exec.Command("crontab", "-l").Output(); err == nil && strings.Contains(string(output), "acme.sh") {
+			client.Scheduled = append(client.Scheduled, "crontab acme.sh --cron")
+		}
+
+		return client
+	}
+
+	return nil
+}
+
+// readKeyValues 读取 key<sep>value 格式的配置文件，去掉值两端的引号
+func readKeyValues(path, sep string) map[string]string {
+	values := make(map[string]string)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, ok := strings.Cut(line, sep)
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `'"`)
+	}
+	return values
+}
+
+// certificateDomains 读取证书文件中的域名
+func certificateDomains(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+	return cert.DNSNames
+}
+
+// disableCertbotRenewal 在 certbot 续期配置的 [renewalparams] 中设置 autorenew = False
+func disableCertbotRenewal(confPath string) error {
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(confPath+".autocert-bak", data, 0600); err != nil {
+		return fmt.Errorf("备份续期配置失败: %w", err)
+	}
+
+	var lines []string
+	inserted := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "autorenew") {
+			continue
+		}
+		lines = append(lines, line)
+		if strings.TrimSpace(line) == "[renewalparams]" {
+			lines = append(lines, "autorenew = False")
+			inserted = true
+		}
+	}
+	if !inserted {
+		lines = append(lines, "[renewalparams]", "autorenew = False")
+	}
+
+	return os.WriteFile(confPath, []byte(strings.Join(lines, "\n")), 0644)
+}
@@ -0,0 +1,99 @@
+package system
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// listenFDsStart systemd 传递的第一个文件描述符
+const listenFDsStart = 3
+
+var (
+	activationOnce sync.Once
+	activated      []net.Listener
+	inherited      []*os.File // 继承的文件描述符，保持打开，避免被回收时关闭
+)
+
+// ActivatedListeners 返回继承的监听套接字：systemd 套接字激活（LISTEN_FDS/LISTEN_PID）
+// 以及 extraFDs 指定的已打开文件描述符（例如由特权父进程绑定端口后传入）。
+// 文件描述符只能接管一次，结果在进程内缓存
+func ActivatedListeners(extraFDs []int) []net.Listener {
+	activationOnce.Do(func() {
+		fds := systemdFDs()
+		fds = append(fds, extraFDs...)
+
+		seen := make(map[int]bool)
+		for _, fd := range fds {
+			if seen[fd] {
+				continue
+			}
+			seen[fd] = true
+
+			listener, err := fileListener(fd)
+			if err != nil {
+				continue
+			}
+			activated = append(activated, listener)
+		}
+	})
+	return activated
+}
+
+// ListenersOnPort 返回继承的监听套接字中监听指定端口的套接字
+func ListenersOnPort(extraFDs []int, port int) []net.Listener {
+	var result []net.Listener
+	for _, listener := range ActivatedListeners(extraFDs) {
+		if addr, ok := listener.Addr().(*net.TCPAddr); ok && addr.Port == port {
+			result = append(result, listener)
+		}
+	}
+	return result
+}
+
+// systemdFDs 解析 systemd 套接字激活的环境变量，并清除这些变量以免传递给子进程
+func systemdFDs() []int {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil
+	}
+
+	fds := make([]int, count)
+	for i := range fds {
+		fds[i] = listenFDsStart + i
+	}
+	return fds
+}
+
+// fileListener 将文件描述符转换为 TCP 监听器。继承的文件描述符保持打开：
+// 套接字由 systemd 或父进程持有，关闭后重新启动的进程（例如重新执行的守护进程）无法再接管
+func fileListener(fd int) (net.Listener, error) {
+	file := os.NewFile(uintptr(fd), "listen-fd-"+strconv.Itoa(fd))
+	if file == nil {
+		return nil, fmt.Errorf("无效的文件描述符: %d", fd)
+	}
+	inherited = append(inherited, file)
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("文件描述符 %d 不是监听套接字: %w", fd, err)
+	}
+	if _, ok := listener.Addr().(*net.TCPAddr); !ok {
+		listener.Close()
+		return nil, fmt.Errorf("文件描述符 %d 不是 TCP 套接字", fd)
+	}
+	return listener, nil
+}
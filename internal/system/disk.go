@@ -0,0 +1,77 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DiskUsage 文件系统剩余空间
+type DiskUsage struct {
+	FreeBytes  uint64 // 当前用户可用的字节数
+	FreeInodes uint64 // 可用 inode 数
+	HasInodes  bool   // 文件系统是否报告 inode（Windows 不报告）
+}
+
+// minFreeInodes 写入前要求的最小可用 inode 数
+const minFreeInodes = 100
+
+// DiskFree 返回路径所在文件系统的剩余空间；路径不存在时检查最近的已存在上级目录
+func DiskFree(path string) (*DiskUsage, error) {
+	dir, err := existingParent(path)
+	if err != nil {
+		return nil, err
+	}
+	return diskFree(dir)
+}
+
+// CheckDiskSpace 检查路径所在文件系统的剩余空间和 inode，不足时返回错误
+// 无法获取文件系统信息时不阻止写入
+func CheckDiskSpace(path string, minBytes uint64) error {
+	usage, err := DiskFree(path)
+	if err != nil {
+		return nil
+	}
+
+	if usage.FreeBytes < minBytes {
+		return fmt.Errorf("磁盘空间不足: %s 剩余 %s，至少需要 %s", path, FormatBytes(int64(usage.FreeBytes)), FormatBytes(int64(minBytes)))
+	}
+	if usage.HasInodes && usage.FreeInodes < minFreeInodes {
+		return fmt.Errorf("inode 不足: %s 剩余 %d 个，至少需要 %d 个", path, usage.FreeInodes, minFreeInodes)
+	}
+	return nil
+}
+
+// existingParent 返回路径本身或最近的已存在上级目录
+func existingParent(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("路径不存在: %s", path)
+		}
+		path = parent
+	}
+}
+
+// FormatBytes 以易读的单位显示字节数
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
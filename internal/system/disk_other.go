@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package system
+
+import "fmt"
+
+// diskFree 当前平台不支持获取剩余空间
+func diskFree(dir string) (*DiskUsage, error) {
+	return nil, fmt.Errorf("当前平台不支持检查磁盘空间")
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd
+
+package system
+
+import "syscall"
+
+// diskFree 通过 statfs 获取剩余空间和 inode
+func diskFree(dir string) (*DiskUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return nil, err
+	}
+
+	return &DiskUsage{
+		FreeBytes:  uint64(st.Bavail) * uint64(st.Bsize),
+		FreeInodes: uint64(st.Ffree),
+		HasInodes:  st.Files > 0,
+	}, nil
+}
@@ -0,0 +1,26 @@
+//go:build windows
+
+package system
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// diskFree 通过 GetDiskFreeSpaceExW 获取剩余空间（NTFS 不限制 inode）
+func diskFree(dir string) (*DiskUsage, error) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var freeBytes uint64
+	ret, _, err := procGetDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&freeBytes)), 0, 0)
+	if ret == 0 {
+		return nil, err
+	}
+
+	return &DiskUsage{FreeBytes: freeBytes}, nil
+}
@@ -0,0 +1,51 @@
+package system
+
+import (
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// Credentials 降权的目标用户
+type Credentials struct {
+	User   string
+	UID    int
+	GID    int
+	Groups []int // 附加用户组
+}
+
+// LookupCredentials 查找用户和用户组；groupName 为空时使用用户的主组
+func LookupCredentials(userName, groupName string) (*Credentials, error) {
+	u, err := user.Lookup(userName)
+	if err != nil {
+		return nil, fmt.Errorf("查找用户 %s 失败: %w", userName, err)
+	}
+
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("当前平台不支持按用户降权: %s", u.Uid)
+	}
+
+	gidStr := u.Gid
+	if groupName != "" {
+		g, err := user.LookupGroup(groupName)
+		if err != nil {
+			return nil, fmt.Errorf("查找用户组 %s 失败: %w", groupName, err)
+		}
+		gidStr = g.Gid
+	}
+	gid, err := strconv.Atoi(gidStr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的用户组 ID: %s", gidStr)
+	}
+
+	creds := &Credentials{User: userName, UID: uid, GID: gid}
+	if groupIDs, err := u.GroupIds(); err == nil {
+		for _, id := range groupIDs {
+			if n, err := strconv.Atoi(id); err == nil {
+				creds.Groups = append(creds.Groups, n)
+			}
+		}
+	}
+	return creds, nil
+}
@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !freebsd
+
+package system
+
+import "fmt"
+
+// DropPrivileges 当前平台不支持降权（Windows 请使用服务账户运行）
+func DropPrivileges(creds *Credentials) error {
+	return fmt.Errorf("当前平台不支持降权运行，请使用服务账户运行")
+}
+
+// ChownTree 当前平台不支持修改文件所有者
+func ChownTree(dir string, uid, gid int) error {
+	return nil
+}
+
+// FileOwner 当前平台不支持按 UID/GID 获取文件所有者
+func FileOwner(path string) (uid, gid int, err error) {
+	return 0, 0, fmt.Errorf("当前平台不支持获取文件所有者")
+}
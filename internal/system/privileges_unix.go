@@ -0,0 +1,64 @@
+//go:build linux || darwin || freebsd
+
+package system
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// DropPrivileges 切换到指定用户和用户组（不可恢复）
+// 先设置附加组和主组，再设置用户，切换后无法再获得 root 权限
+func DropPrivileges(creds *Credentials) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("当前不是 root 用户，无需降权")
+	}
+
+	groups := creds.Groups
+	if len(groups) == 0 {
+		groups = []int{creds.GID}
+	}
+	if err := syscall.Setgroups(groups); err != nil {
+		return fmt.Errorf("设置附加用户组失败: %w", err)
+	}
+	if err := syscall.Setgid(creds.GID); err != nil {
+		return fmt.Errorf("设置用户组失败: %w", err)
+	}
+	if err := syscall.Setuid(creds.UID); err != nil {
+		return fmt.Errorf("设置用户失败: %w", err)
+	}
+
+	// 确认无法恢复 root 权限
+	if syscall.Setuid(0) == nil {
+		return fmt.Errorf("降权后仍可恢复 root 权限")
+	}
+	return nil
+}
+
+// ChownTree 递归修改目录及其中文件的所有者（不跟随符号链接）
+func ChownTree(dir string, uid, gid int) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
+
+// FileOwner 返回文件的所有者和用户组
+func FileOwner(path string) (uid, gid int, err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, fmt.Errorf("无法获取 %s 的所有者", path)
+	}
+	return int(stat.Uid), int(stat.Gid), nil
+}
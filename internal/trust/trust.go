@@ -0,0 +1,253 @@
+// Package trust 检查证书的完整信任链：证书文件中的各级证书和信任存储中的根证书的有效期，
+// 以及本机的系统信任存储是否包含所需的根证书
+package trust
+
+import (
+	"autocert/internal/clock"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 证书在链中的角色
+const (
+	RoleLeaf         = "leaf"
+	RoleIntermediate = "intermediate"
+	RoleRoot         = "root"
+)
+
+// 证书的来源
+const (
+	SourceFile  = "file"   // 证书文件
+	SourceStore = "system" // 系统信任存储
+)
+
+// 问题的级别
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelInfo    = "info"
+)
+
+// Options 检查选项
+type Options struct {
+	// WarnDays 中间证书或根证书在该天数内过期时警告（在叶子证书之前过期的总是警告）
+	WarnDays int
+}
+
+// Entry 信任链中的一张证书
+type Entry struct {
+	Role        string    `json:"role"`
+	Source      string    `json:"source"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	DaysLeft    int       `json:"days_left"`
+	SelfSigned  bool      `json:"self_signed"`
+	CrossSigned bool      `json:"cross_signed"` // 与链中的根证书主题和公钥相同，但由其他根证书签发
+	SHA256      string    `json:"sha256"`
+	Unused      bool      `json:"unused,omitempty"` // 证书文件中有但本机构建的信任链没有用到
+}
+
+// Finding 发现的问题
+type Finding struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// Report 检查结果
+type Report struct {
+	Chain    []Entry   `json:"chain"`
+	Trusted  bool      `json:"trusted"`         // 本机的系统信任存储能验证证书链
+	Root     string    `json:"root,omitempty"`  // 证书链所需的根证书
+	Error    string    `json:"error,omitempty"` // 验证失败的原因
+	Paths    int       `json:"paths"`           // 本机能构建的信任链数量（交叉签名时可能有多条）
+	Findings []Finding `json:"findings"`
+}
+
+// Inspect 检查证书链：certs 为证书文件中的证书，第一张为叶子证书
+func Inspect(certs []*x509.Certificate, options Options) (*Report, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("证书文件中没有证书")
+	}
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, fmt.Errorf("读取系统信任存储失败: %w", err)
+	}
+	return inspect(certs, roots, options), nil
+}
+
+func inspect(certs []*x509.Certificate, roots *x509.CertPool, options Options) *Report {
+	now := clock.Now()
+	leaf := certs[0]
+	report := &Report{Findings: []Finding{}}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, verifyErr := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	report.Paths = len(chains)
+
+	// 使用最短的信任链展示；没有信任链时按证书文件中的顺序展示
+	var path []*x509.Certificate
+	for _, chain := range chains {
+		if path == nil || len(chain) < len(path) {
+			path = chain
+		}
+	}
+	if verifyErr != nil {
+		report.Error = verifyErr.Error()
+		path = certs
+	} else {
+		report.Trusted = true
+	}
+
+	related := append(append([]*x509.Certificate{}, certs...), path...)
+	inFile := make(map[string]bool, len(certs))
+	for _, cert := range certs {
+		inFile[fingerprint(cert)] = true
+	}
+	inPath := make(map[string]bool, len(path))
+	for i, cert := range path {
+		inPath[fingerprint(cert)] = true
+		role := RoleIntermediate
+		switch {
+		case i == 0:
+			role = RoleLeaf
+		case report.Trusted && i == len(path)-1:
+			role = RoleRoot
+		}
+		source := SourceFile
+		if !inFile[fingerprint(cert)] {
+			source = SourceStore
+		}
+		report.Chain = append(report.Chain, newEntry(cert, role, source, related, now))
+	}
+	for _, cert := range certs {
+		if !inPath[fingerprint(cert)] {
+			entry := newEntry(cert, RoleIntermediate, SourceFile, related, now)
+			entry.Unused = true
+			report.Chain = append(report.Chain, entry)
+		}
+	}
+
+	last := path[len(path)-1]
+	if report.Trusted {
+		report.Root = last.Subject.String()
+	} else {
+		report.Root = last.Issuer.String()
+		if isSelfSigned(last) {
+			report.Root = last.Subject.String()
+		}
+	}
+
+	report.check(leaf, verifyErr, options)
+	return report
+}
+
+// check 检查有效期和信任状态
+func (r *Report) check(leaf *x509.Certificate, verifyErr error, options Options) {
+	var unknownAuthority x509.UnknownAuthorityError
+	switch {
+	case verifyErr == nil:
+		r.add(LevelInfo, "本机的系统信任存储包含所需的根证书 %s", r.Root)
+	case errors.As(verifyErr, &unknownAuthority):
+		r.add(LevelError, "本机的系统信任存储中没有所需的根证书 %s（或证书文件缺少中间证书）", r.Root)
+	default:
+		r.add(LevelError, "证书链验证失败: %v", verifyErr)
+	}
+	if r.Paths > 1 {
+		r.add(LevelInfo, "本机可以构建 %d 条信任链（交叉签名），不同客户端可能使用不同的根证书", r.Paths)
+	}
+
+	for _, entry := range r.Chain {
+		name := entry.Subject
+		switch {
+		case entry.DaysLeft < 0:
+			r.add(LevelError, "%s %s 已于 %s 过期", describe(entry), name, clock.Date(entry.NotAfter))
+			continue
+		case entry.Role == RoleLeaf:
+			continue
+		case entry.NotAfter.Before(leaf.NotAfter):
+			r.add(LevelWarning, "%s %s 将于 %s 过期，早于叶子证书（%s），之后依赖它的客户端将无法建立信任链",
+				describe(entry), name, clock.Date(entry.NotAfter), clock.Date(leaf.NotAfter))
+		case options.WarnDays > 0 && entry.DaysLeft <= options.WarnDays:
+			r.add(LevelWarning, "%s %s 将在 %d 天后过期（%s）", describe(entry), name, entry.DaysLeft, clock.Date(entry.NotAfter))
+		}
+		if entry.CrossSigned {
+			r.add(LevelInfo, "%s 是交叉签名证书（由 %s 签发），旧客户端通过它信任新的根证书", name, entry.Issuer)
+		}
+		if entry.Unused {
+			r.add(LevelInfo, "证书文件中的 %s 没有用于本机的信任链", name)
+		}
+		if entry.Role != RoleRoot && entry.Source == SourceFile && entry.SelfSigned {
+			r.add(LevelInfo, "证书文件包含根证书 %s，通常不需要发送给客户端", name)
+		}
+	}
+}
+
+func (r *Report) add(level, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, Finding{Level: level, Message: fmt.Sprintf(format, args...)})
+}
+
+func newEntry(cert *x509.Certificate, role, source string, related []*x509.Certificate, now time.Time) Entry {
+	return Entry{
+		Role:        role,
+		Source:      source,
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		DaysLeft:    int(cert.NotAfter.Sub(now).Hours() / 24),
+		SelfSigned:  isSelfSigned(cert),
+		CrossSigned: role != RoleLeaf && isCrossSigned(cert, related),
+		SHA256:      fingerprint(cert),
+	}
+}
+
+// isSelfSigned 主题与颁发者相同且用自身公钥签名
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// isCrossSigned 不是自签名证书，但与 others 中的某张自签名根证书主题和公钥相同（同一根证书由其他根证书签发的版本）
+func isCrossSigned(cert *x509.Certificate, others []*x509.Certificate) bool {
+	if isSelfSigned(cert) {
+		return false
+	}
+	for _, other := range others {
+		if isSelfSigned(other) && bytes.Equal(other.RawSubject, cert.RawSubject) &&
+			bytes.Equal(other.RawSubjectPublicKeyInfo, cert.RawSubjectPublicKeyInfo) {
+			return true
+		}
+	}
+	return false
+}
+
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// describe 证书角色的中文名称
+func describe(entry Entry) string {
+	switch entry.Role {
+	case RoleLeaf:
+		return "叶子证书"
+	case RoleRoot:
+		return "根证书"
+	default:
+		return "中间证书"
+	}
+}
@@ -0,0 +1,252 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// 发行版的 Apache 布局
+const (
+	apacheDebian = "debian" // /etc/apache2/apache2.conf，sites-available + a2ensite，服务 apache2
+	apacheRHEL   = "rhel"   // /etc/httpd/conf/httpd.conf，conf.d/*.conf，服务 httpd
+	apacheAlpine = "alpine" // /etc/apache2/httpd.conf，conf.d/*.conf，服务 apache2
+)
+
+// apacheLayout Apache 站点配置的存放方式、服务名称和控制命令
+type apacheLayout struct {
+	name         string
+	configPath   string // 主配置
+	siteDir      string // 站点配置目录（Debian 为 sites-available）
+	enabledDir   string // Debian 的 sites-enabled，其他布局 conf.d 中的配置直接生效
+	service      string // systemd/OpenRC 服务名称
+	ctlCommands  []string
+	sslModuleDir string // 检查 mod_ssl 是否加载的位置
+}
+
+// apacheLayouts 各发行版的默认布局，按主配置是否存在依次检测
+var apacheLayouts = []apacheLayout{
+	{
+		name:         apacheDebian,
+		configPath:   "/etc/apache2/apache2.conf",
+		siteDir:      "/etc/apache2/sites-available",
+		enabledDir:   "/etc/apache2/sites-enabled",
+		service:      "apache2",
+		ctlCommands:  []string{"apache2ctl", "apachectl"},
+		sslModuleDir: "/etc/apache2/mods-enabled",
+	},
+	{
+		name:         apacheRHEL,
+		configPath:   "/etc/httpd/conf/httpd.conf",
+		siteDir:      "/etc/httpd/conf.d",
+		service:      "httpd",
+		ctlCommands:  []string{"apachectl", "httpd"},
+		sslModuleDir: "/etc/httpd/conf.modules.d",
+	},
+	{
+		name:         apacheAlpine,
+		configPath:   "/etc/apache2/httpd.conf",
+		siteDir:      "/etc/apache2/conf.d",
+		service:      "apache2",
+		ctlCommands:  []string{"httpd", "apachectl"},
+		sslModuleDir: "/etc/apache2/conf.d",
+	},
+}
+
+// detectApacheLayout 确定 Apache 布局：优先使用 webserver.config_path（按文件名推断发行版，
+// 目录相对于该文件），否则检测各发行版的默认主配置
+func detectApacheLayout() (*apacheLayout, error) {
+	if ws := config.GetWebServerConfig(); ws.ConfigPath != "" && strings.EqualFold(ws.Type, "apache") {
+		if _, err := os.Stat(ws.ConfigPath); err != nil {
+			return nil, fmt.Errorf("Apache 主配置不存在: %w", err)
+		}
+		return apacheLayoutFor(ws.ConfigPath), nil
+	}
+
+	if runtime.GOOS != "windows" {
+		for _, layout := range apacheLayouts {
+			if _, err := os.Stat(layout.configPath); err == nil {
+				layout := layout
+				return &layout, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("未找到 Apache 配置文件")
+}
+
+// apacheLayoutFor 根据主配置的文件名和位置推断布局：apache2.conf 为 Debian，
+// conf/httpd.conf 为 RHEL，其他 httpd.conf 为 Alpine
+func apacheLayoutFor(configPath string) *apacheLayout {
+	dir := filepath.Dir(configPath)
+	var layout apacheLayout
+	switch {
+	case filepath.Base(configPath) == "apache2.conf":
+		layout = apacheLayouts[0]
+		layout.siteDir = filepath.Join(dir, "sites-available")
+		layout.enabledDir = filepath.Join(dir, "sites-enabled")
+		layout.sslModuleDir = filepath.Join(dir, "mods-enabled")
+	case filepath.Base(dir) == "conf":
+		root := filepath.Dir(dir)
+		layout = apacheLayouts[1]
+		layout.siteDir = filepath.Join(root, "conf.d")
+		layout.sslModuleDir = filepath.Join(root, "conf.modules.d")
+	default:
+		layout = apacheLayouts[2]
+		layout.siteDir = filepath.Join(dir, "conf.d")
+		layout.sslModuleDir = layout.siteDir
+	}
+	layout.configPath = configPath
+	return &layout
+}
+
+// siteConfigPath 域名的站点配置文件路径；a2ensite 只识别 .conf 扩展名的站点
+func (l *apacheLayout) siteConfigPath(domain string) string {
+	return filepath.Join(l.siteDir, domain+".conf")
+}
+
+// searchDirs 可能包含已生效站点配置的目录
+func (l *apacheLayout) searchDirs() []string {
+	if l.enabledDir != "" {
+		return []string{l.enabledDir}
+	}
+	return []string{l.siteDir}
+}
+
+// ctl 可用的控制命令（apache2ctl、apachectl 或 httpd），都不存在时返回第一个
+func (l *apacheLayout) ctl() string {
+	for _, name := range l.ctlCommands {
+		if _, err := exec.LookPath(name); err == nil {
+			return name
+		}
+	}
+	return l.ctlCommands[0]
+}
+
+// sslModuleLoaded 检查 mod_ssl 是否已启用：Debian 看 mods-enabled/ssl.load，
+// RHEL 看 conf.modules.d 中是否 LoadModule ssl_module，Alpine 看 apache2-ssl 包提供的 conf.d/ssl.conf
+func (l *apacheLayout) sslModuleLoaded() bool {
+	switch l.name {
+	case apacheDebian:
+		_, err := os.Stat(filepath.Join(l.sslModuleDir, "ssl.load"))
+		return err == nil
+	case apacheAlpine:
+		_, err := os.Stat(filepath.Join(l.sslModuleDir, "ssl.conf"))
+		return err == nil
+	}
+	files, _ := filepath.Glob(filepath.Join(l.sslModuleDir, "*.conf"))
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil && bytes.Contains(data, []byte("ssl_module")) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureSSLModule Debian 上通过 a2enmod 启用 mod_ssl，其他发行版提示安装软件包
+func (l *apacheLayout) ensureSSLModule() error {
+	if l.sslModuleLoaded() {
+		return nil
+	}
+	switch l.name {
+	case apacheDebian:
+		if _, err := exec.LookPath("a2enmod"); err != nil {
+			logger.Warn("mod_ssl 未启用，且未找到 a2enmod", "dir", l.sslModuleDir)
+			return nil
+		}
+		if output, err := exec.Command("a2enmod", "ssl").CombinedOutput(); err != nil {
+			return fmt.Errorf("启用 mod_ssl 失败: %s", strings.TrimSpace(string(output)))
+		}
+		logger.Info("已启用 Apache mod_ssl")
+	case apacheRHEL:
+		logger.Warn("mod_ssl 未安装，请执行 dnf install mod_ssl", "dir", l.sslModuleDir)
+	case apacheAlpine:
+		logger.Warn("mod_ssl 未安装，请执行 apk add apache2-ssl", "dir", l.sslModuleDir)
+	}
+	return nil
+}
+
+// apacheVersionPattern apachectl -v 输出中的版本，例如 "Server version: Apache/2.4.57 (Debian)"
+var apacheVersionPattern = regexp.MustCompile(`Apache/(\d+)\.(\d+)\.(\d+)`)
+
+// version 读取 Apache 版本，无法获取时返回 nil
+func (l *apacheLayout) version() []int {
+	output, err := exec.Command(l.ctl(), "-v").CombinedOutput()
+	if err != nil {
+		return nil
+	}
+	return parseApacheVersion(string(output))
+}
+
+// parseApacheVersion 解析 -v 输出中的主、次、修订版本号
+func parseApacheVersion(output string) []int {
+	match := apacheVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return nil
+	}
+	version := make([]int, 3)
+	for i := range version {
+		version[i], _ = strconv.Atoi(match[i+1])
+	}
+	return version
+}
+
+// supportsFullChain 2.4.8 起 SSLCertificateFile 可以包含完整证书链，SSLCertificateChainFile 已弃用；
+// 无法获取版本时按新版本处理
+func supportsFullChain(version []int) bool {
+	if version == nil {
+		return true
+	}
+	for i, minimum := range []int{2, 4, 8} {
+		if version[i] != minimum {
+			return version[i] > minimum
+		}
+	}
+	return true
+}
+
+// writeChainFile 将证书文件中叶子证书之后的中间证书写入同目录的 chain.pem，
+// 供 2.4.8 之前的 SSLCertificateChainFile 使用；没有中间证书时返回空
+func writeChainFile(certPath string) (string, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return "", fmt.Errorf("读取证书失败: %w", err)
+	}
+
+	var chain []byte
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		count++
+		if count > 1 {
+			chain = append(chain, pem.EncodeToMemory(block)...)
+		}
+	}
+	if len(chain) == 0 {
+		return "", nil
+	}
+
+	chainPath := filepath.Join(filepath.Dir(certPath), "chain.pem")
+	if existing, err := os.ReadFile(chainPath); err == nil && bytes.Equal(existing, chain) {
+		return chainPath, nil
+	}
+	if err := os.WriteFile(chainPath, chain, 0644); err != nil {
+		return "", fmt.Errorf("写入证书链失败: %w", err)
+	}
+	return chainPath, nil
+}
@@ -0,0 +1,85 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Commands 用户指定的测试/重载命令及其执行环境，为空时使用服务器默认命令
+type Commands struct {
+	Reload  string
+	Test    string
+	WorkDir string
+	Env     []string
+}
+
+// CommandsFor 获取域名使用的测试/重载命令（域名配置覆盖全局 webserver 配置）
+func CommandsFor(domain string) Commands {
+	webConfig := config.GetWebServerConfig()
+	commands := Commands{
+		Reload:  webConfig.ReloadCmd,
+		Test:    webConfig.TestCmd,
+		WorkDir: webConfig.WorkDir,
+		Env:     webConfig.Env,
+	}
+
+	domainConfig := config.GetDomainConfig(domain)
+	if domainConfig == nil {
+		return commands
+	}
+	if domainConfig.ReloadCmd != "" {
+		commands.Reload = domainConfig.ReloadCmd
+	}
+	if domainConfig.TestCmd != "" {
+		commands.Test = domainConfig.TestCmd
+	}
+	if domainConfig.WorkDir != "" {
+		commands.WorkDir = domainConfig.WorkDir
+	}
+	if len(domainConfig.Env) > 0 {
+		commands.Env = append(append([]string{}, commands.Env...), domainConfig.Env...)
+	}
+	return commands
+}
+
+// run 执行自定义命令（Linux 通过 sh -c，Windows 通过 cmd /C）
+func (c Commands) run(commandLine string) ([]byte, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", commandLine)
+	} else {
+		cmd = exec.Command("sh", "-c", commandLine)
+	}
+
+	cmd.Dir = c.WorkDir
+	cmd.Env = append(os.Environ(), c.Env...)
+	return cmd.CombinedOutput()
+}
+
+// runTest 执行自定义测试命令，未配置时返回 false
+func (c Commands) runTest(serverType string) (bool, error) {
+	if c.Test == "" {
+		return false, nil
+	}
+
+	if output, err := c.run(c.Test); err != nil {
+		return true, fmt.Errorf("%s 配置测试失败 (%s): %s", serverType, c.Test, strings.TrimSpace(string(output)))
+	}
+	return true, nil
+}
+
+// runReload 执行自定义重载命令，未配置时返回 false
+func (c Commands) runReload(serverType string) (bool, error) {
+	if c.Reload == "" {
+		return false, nil
+	}
+
+	if output, err := c.run(c.Reload); err != nil {
+		return true, fmt.Errorf("重载 %s 失败 (%s): %s", serverType, c.Reload, strings.TrimSpace(string(output)))
+	}
+	return true, nil
+}
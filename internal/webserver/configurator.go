@@ -1,6 +1,7 @@
 package webserver
 
 import (
+	"autocert/internal/config"
 	"autocert/internal/logger"
 	"bufio"
 	"fmt"
@@ -9,19 +10,53 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
-	"text/template"
 )
 
 // Config Web 服务器配置
 type Config struct {
 	Type       string // nginx, apache, iis
 	Domain     string
+	Aliases    []string // 同一证书覆盖的其他域名（SAN 成员）
 	CertPath   string
 	KeyPath    string
-	ConfigPath string
+	ChainPath  string // 中间证书，仅 Apache 2.4.8 之前的 SSLCertificateChainFile 使用
+	ConfigPath string // 配置完成后写入的站点配置文件路径
 	WebRoot    string
+
+	// 双证书模式下的 ECDSA 证书，与 RSA 证书同时提供
+	ECDSACertPath string
+	ECDSAKeyPath  string
+
+	// 站点配置在上次生成后被手工修改时仍然覆盖
+	OverwriteDrift bool
+
+	// HTTP 不重定向到 HTTPS，80 端口继续提供站点（迁移期间使用）
+	NoRedirect bool
+	// 暂存部署：站点配置写入 <站点配置>.disabled，不启用，确认后通过 autocert activate 启用
+	StagingDeploy bool
+}
+
+// DisabledSuffix 暂存部署的站点配置后缀，Web 服务器不会加载该文件
+const DisabledSuffix = ".disabled"
+
+// stagedConfigPath 返回实际写入的站点配置路径，以及是否暂存（写入 .disabled 且不启用）。
+// 指定 StagingDeploy，或之前暂存的配置尚未启用（只有 .disabled 文件）时暂存，
+// 避免续期重新生成配置时自动启用
+func stagedConfigPath(configFile string, staging bool) (string, bool) {
+	if staging {
+		return configFile + DisabledSuffix, true
+	}
+	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		if _, err := os.Stat(configFile + DisabledSuffix); err == nil {
+			return configFile + DisabledSuffix, true
+		}
+	}
+	return configFile, false
 }
 
+// defaultWebRoot 未指定网站根目录时使用的默认值
+const defaultWebRoot = "/var/www/html"
+
 // Configurator Web 服务器配置器接口
 type Configurator interface {
 	Configure(config *Config) error
@@ -35,11 +70,11 @@ type Configurator interface {
 func NewConfigurator(serverType string) (Configurator, error) {
 	switch strings.ToLower(serverType) {
 	case "nginx":
-		return &NginxConfigurator{}, nil
+		return &NginxConfigurator{commands: CommandsFor("")}, nil
 	case "apache":
-		return &ApacheConfigurator{}, nil
+		return &ApacheConfigurator{commands: CommandsFor("")}, nil
 	case "iis":
-		return &IISConfigurator{}, nil
+		return &IISConfigurator{commands: CommandsFor("")}, nil
 	default:
 		return nil, fmt.Errorf("不支持的 Web 服务器类型: %s", serverType)
 	}
@@ -48,34 +83,64 @@ func NewConfigurator(serverType string) (Configurator, error) {
 // NginxConfigurator Nginx 配置器
 type NginxConfigurator struct {
 	configPath string
+	layout     *nginxLayout
+	commands   Commands
 }
 
 // Configure 配置 Nginx
 func (n *NginxConfigurator) Configure(config *Config) error {
 	logger.Info("开始配置 Nginx", "domain", config.Domain)
+	n.commands = CommandsFor(config.Domain)
 
 	// 1. 确定配置文件路径
 	if err := n.findConfigPath(); err != nil {
 		return fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
 	}
 
-	// 2. 创建站点配置
-	siteConfigPath, err := n.createSiteConfig(config)
-	if err != nil {
+	// 已包含在 sites generate 生成的合并配置中：合并配置引用同一证书文件，不再写入和启用单站点配置，
+	// 否则单站点配置会重新启用，与合并配置中的 server_name 重复
+	if sitesPath := n.nginxSitesContaining(config.Domain); sitesPath != "" && !config.StagingDeploy {
+		logger.Info("域名已包含在合并的多站点配置中，跳过单站点配置", "domain", config.Domain, "config", sitesPath)
+		config.ConfigPath = sitesPath
+		return nil
+	}
+
+	// 2. 创建站点配置（暂存部署时写入 .disabled）
+	siteConfigPath, staged := stagedConfigPath(n.siteConfigPath(config.Domain), config.StagingDeploy)
+	if err := n.createSiteConfig(config, siteConfigPath); err != nil {
 		return fmt.Errorf("创建站点配置失败: %w", err)
 	}
+	config.ConfigPath = siteConfigPath
 
-	// 3. 启用站点配置
-	if err := n.enableSite(siteConfigPath); err != nil {
-		return fmt.Errorf("启用站点配置失败: %w", err)
+	if staged {
+		warnStaged("nginx", config.Domain, siteConfigPath)
+	} else {
+		// 3. 启用站点配置
+		if err := n.enableSite(siteConfigPath); err != nil {
+			return fmt.Errorf("启用站点配置失败: %w", err)
+		}
+
+		// 4. 多站点共享 IP：默认站点和重复的 server_name
+		ensureNginxDefaultServer()
+		warnNginxConflicts(config.Domain)
 	}
 
+	// 5. 记录配置历史
+	recordConfigHistory("nginx", config.Domain, siteConfigPath)
+
 	logger.Info("Nginx 配置完成", "domain", config.Domain)
 	return nil
 }
 
 // Test 测试 Nginx 配置
 func (n *NginxConfigurator) Test() error {
+	if custom, err := n.commands.runTest("Nginx"); custom {
+		if err == nil {
+			logger.Info("Nginx 配置测试成功（自定义命令）")
+		}
+		return err
+	}
+
 	cmd := exec.Command("nginx", "-t")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -87,6 +152,13 @@ func (n *NginxConfigurator) Test() error {
 
 // Reload 重载 Nginx 配置
 func (n *NginxConfigurator) Reload() error {
+	if custom, err := n.commands.runReload("Nginx"); custom {
+		if err == nil {
+			logger.Info("Nginx 配置重载成功（自定义命令）")
+		}
+		return err
+	}
+
 	var cmd *exec.Cmd
 
 	if runtime.GOOS == "windows" {
@@ -128,26 +200,30 @@ func (n *NginxConfigurator) IsSSLEnabled(domain string) bool {
 	return false
 }
 
-// findConfigPath 查找 Nginx 配置路径
+// findConfigPath 查找 Nginx 配置路径（优先使用 webserver.config_path），并根据其中的 include 确定站点配置的存放方式
 func (n *NginxConfigurator) findConfigPath() error {
 	var configPaths []string
+	if ws := config.GetWebServerConfig(); ws.ConfigPath != "" && strings.EqualFold(ws.Type, "nginx") {
+		configPaths = append(configPaths, ws.ConfigPath)
+	}
 
 	if runtime.GOOS == "windows" {
-		configPaths = []string{
+		configPaths = append(configPaths,
 			`C:\nginx\conf\nginx.conf`,
 			`C:\Program Files\nginx\conf\nginx.conf`,
-		}
+		)
 	} else {
-		configPaths = []string{
+		configPaths = append(configPaths,
 			"/etc/nginx/nginx.conf",
 			"/usr/local/nginx/conf/nginx.conf",
 			"/usr/local/etc/nginx/nginx.conf",
-		}
+		)
 	}
 
 	for _, path := range configPaths {
 		if _, err := os.Stat(path); err == nil {
 			n.configPath = path
+			n.layout = detectNginxLayout(path)
 			return nil
 		}
 	}
@@ -155,89 +231,159 @@ func (n *NginxConfigurator) findConfigPath() error {
 	return fmt.Errorf("未找到 Nginx 配置文件")
 }
 
-// createSiteConfig 创建站点配置
-func (n *NginxConfigurator) createSiteConfig(config *Config) (string, error) {
-	var configDir string
-	var configFile string
-
-	if runtime.GOOS == "windows" {
-		configDir = filepath.Dir(n.configPath)
-		configFile = filepath.Join(configDir, "conf.d", config.Domain+".conf")
-	} else {
-		configDir = "/etc/nginx/sites-available"
-		configFile = filepath.Join(configDir, config.Domain)
-	}
-
+// createSiteConfig 创建站点配置 configFile
+func (n *NginxConfigurator) createSiteConfig(config *Config, configFile string) error {
 	// 确保配置目录存在
 	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
-		return "", err
+		return err
 	}
 
 	// 生成配置内容
 	configContent, err := n.generateConfig(config)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	// 写入配置文件
-	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
-		return "", err
+	// 使用自定义模板或模板片段时，先在临时配置中检查渲染结果，避免重载时才发现错误
+	if CustomizedFor(config.Domain) {
+		if err := TestRenderedConfig("nginx", configContent); err != nil {
+			return err
+		}
+	}
+
+	// 写入配置文件，不覆盖手工修改（手工修改只作为警告报告，继续部署）
+	if err := writeManagedConfig("nginx", config.Domain, configFile, configContent, config.OverwriteDrift); err != nil && !reportDrift(err) {
+		return err
 	}
 
 	logger.Info("创建 Nginx 站点配置", "configFile", configFile)
-	return configFile, nil
+	return nil
+}
+
+// warnStaged 提示暂存的站点配置未启用，以及启用方法
+func warnStaged(serverType, domain, configFile string) {
+	logger.Warn("站点配置已暂存，未启用：确认无误后执行 autocert activate "+domain+" 启用并重载 "+serverType,
+		"file", configFile)
+}
+
+// ActivateStaged 启用暂存部署写入的站点配置：<站点配置>.disabled 替换站点配置并启用，
+// 测试通过后重载；测试失败时恢复原来的站点配置和启用状态。返回启用的站点配置路径
+func ActivateStaged(serverType, domain string) (string, error) {
+	switch strings.ToLower(serverType) {
+	case "nginx":
+		n := &NginxConfigurator{commands: CommandsFor(domain)}
+		if err := n.findConfigPath(); err != nil {
+			return "", fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
+		}
+		sitePath := n.siteConfigPath(domain)
+		err := activateStaged(n, sitePath, n.siteLinkPath(sitePath), func() error {
+			if err := n.enableSite(sitePath); err != nil {
+				return err
+			}
+			ensureNginxDefaultServer()
+			warnNginxConflicts(domain)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		recordConfigHistory("nginx", domain, sitePath)
+		return sitePath, nil
+	case "apache":
+		a := &ApacheConfigurator{commands: CommandsFor(domain)}
+		layout, err := a.getLayout()
+		if err != nil {
+			return "", fmt.Errorf("查找 Apache 配置路径失败: %w", err)
+		}
+		sitePath := layout.siteConfigPath(domain)
+		linkPath := ""
+		if layout.enabledDir != "" {
+			linkPath = filepath.Join(layout.enabledDir, filepath.Base(sitePath))
+		}
+		if err := activateStaged(a, sitePath, linkPath, func() error { return a.enableSite(sitePath) }); err != nil {
+			return "", err
+		}
+		recordConfigHistory("apache", domain, sitePath)
+		return sitePath, nil
+	default:
+		return "", fmt.Errorf("暂存部署只适用于 Nginx 和 Apache，不支持 %s", serverType)
+	}
+}
+
+// activateStaged 将 sitePath 的暂存配置改名为 sitePath，通过 enable 启用后测试并重载。
+// linkPath 为启用站点的链接（没有时为空），之前未启用时测试失败会删除
+func activateStaged(c Configurator, sitePath, linkPath string, enable func() error) error {
+	staged := sitePath + DisabledSuffix
+	if _, err := os.Stat(staged); err != nil {
+		return fmt.Errorf("没有暂存的站点配置 %s", staged)
+	}
+
+	linked := false
+	if linkPath != "" {
+		if _, err := os.Lstat(linkPath); err == nil {
+			linked = true
+		}
+	}
+
+	restore := snapshotManagedConfig(sitePath)
+	if err := os.Rename(staged, sitePath); err != nil {
+		return fmt.Errorf("启用暂存的站点配置失败: %w", err)
+	}
+	moveManagedConfig(staged, sitePath)
+
+	rollback := func() {
+		if linkPath != "" && !linked {
+			os.Remove(linkPath)
+		}
+		if err := os.Rename(sitePath, staged); err != nil {
+			logger.Warn("恢复暂存的站点配置失败", "file", staged, "error", err)
+		}
+		moveManagedConfig(sitePath, staged)
+		restore()
+	}
+
+	if err := enable(); err != nil {
+		rollback()
+		return fmt.Errorf("启用站点配置失败: %w", err)
+	}
+	if err := c.Test(); err != nil {
+		rollback()
+		logger.Warn("配置测试失败，已恢复暂存状态", "file", staged)
+		return err
+	}
+	if err := c.Reload(); err != nil {
+		return err
+	}
+
+	logger.Info("已启用暂存的站点配置", "file", sitePath)
+	return nil
+}
+
+// siteConfigPath 域名的站点配置文件路径（sites-available 或 conf.d 中）
+func (n *NginxConfigurator) siteConfigPath(domain string) string {
+	return n.layout.siteConfigPath(domain)
+}
+
+// siteLinkPath 启用站点的符号链接路径（配置直接放在 conf.d 中时没有链接）
+func (n *NginxConfigurator) siteLinkPath(configFile string) string {
+	return n.layout.siteLinkPath(configFile)
 }
 
 // generateConfig 生成 Nginx 配置
 func (n *NginxConfigurator) generateConfig(config *Config) (string, error) {
-	tmpl := `# AutoCert 自动生成的配置
-server {
-    listen 80;
-    server_name {{.Domain}};
-    
-    # 重定向 HTTP 到 HTTPS
-    return 301 https://$server_name$request_uri;
-}
-
-server {
-    listen 443 ssl http2;
-    server_name {{.Domain}};
-    
-    # SSL 证书配置
-    ssl_certificate {{.CertPath}};
-    ssl_certificate_key {{.KeyPath}};
-    
-    # SSL 安全配置
-    ssl_protocols TLSv1.2 TLSv1.3;
-    ssl_prefer_server_ciphers on;
-    ssl_ciphers ECDHE-RSA-AES256-GCM-SHA384:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-RSA-AES256-SHA384:ECDHE-RSA-AES128-SHA256;
-    ssl_session_cache shared:SSL:10m;
-    ssl_session_timeout 10m;
-    
-    # 网站根目录
-    root {{.WebRoot}};
-    index index.html index.htm index.php;
-    
-    # 通用配置
-    location / {
-        try_files $uri $uri/ =404;
-    }
-    
-    # ACME 挑战目录
-    location ^~ /.well-known/acme-challenge/ {
-        default_type "text/plain";
-        root {{.WebRoot}};
-    }
-}
-`
-
-	t, err := template.New("nginx").Parse(tmpl)
+	t, err := loadTemplate("nginx", nginxTemplate, config.Domain)
 	if err != nil {
 		return "", err
 	}
+	warnIgnoredNoRedirect(t, config)
+
+	data := *config
+	if data.WebRoot == "" {
+		data.WebRoot = defaultWebRoot
+	}
 
 	var result strings.Builder
-	if err := t.Execute(&result, config); err != nil {
+	if err := t.Execute(&result, &data); err != nil {
 		return "", err
 	}
 
@@ -246,17 +392,14 @@ server {
 
 // enableSite 启用站点配置
 func (n *NginxConfigurator) enableSite(configFile string) error {
-	if runtime.GOOS == "windows" {
-		// Windows 下通常配置文件直接放在 conf.d 目录
-		return nil
+	linkPath := n.siteLinkPath(configFile)
+	if linkPath == "" {
+		// conf.d 中的配置由主配置直接 include，没有 include 时按配置添加
+		return n.layout.ensureInclude(n.configPath)
 	}
 
-	// Linux 下需要创建符号链接
-	sitesEnabled := "/etc/nginx/sites-enabled"
-	linkPath := filepath.Join(sitesEnabled, filepath.Base(configFile))
-
 	// 确保 sites-enabled 目录存在
-	if err := os.MkdirAll(sitesEnabled, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(linkPath), 0755); err != nil {
 		return err
 	}
 
@@ -275,20 +418,11 @@ func (n *NginxConfigurator) enableSite(configFile string) error {
 // findSiteConfigs 查找站点配置文件
 func (n *NginxConfigurator) findSiteConfigs() []string {
 	var configs []string
-	var searchDirs []string
-
-	if runtime.GOOS == "windows" {
-		searchDirs = []string{
-			filepath.Join(filepath.Dir(n.configPath), "conf.d"),
-		}
-	} else {
-		searchDirs = []string{
-			"/etc/nginx/sites-enabled",
-			"/etc/nginx/conf.d",
-		}
+	if n.layout == nil {
+		return configs
 	}
 
-	for _, dir := range searchDirs {
+	for _, dir := range n.layout.searchDirs() {
 		if files, err := filepath.Glob(filepath.Join(dir, "*")); err == nil {
 			configs = append(configs, files...)
 		}
@@ -338,26 +472,142 @@ func (n *NginxConfigurator) checkSSLInConfig(configFile, domain string) bool {
 // ApacheConfigurator Apache 配置器
 type ApacheConfigurator struct {
 	configPath string
+	layout     *apacheLayout
+	commands   Commands
 }
 
-// Configure 配置 Apache
+// Configure 配置 Apache：按发行版布局写入站点配置（Debian 放入 sites-available 并 a2ensite，
+// RHEL/Alpine 放入 conf.d），2.4.8 之前的版本单独提供证书链
 func (a *ApacheConfigurator) Configure(config *Config) error {
 	logger.Info("开始配置 Apache", "domain", config.Domain)
+	a.commands = CommandsFor(config.Domain)
 
-	// Apache 配置实现
-	// 这里应该实现完整的 Apache SSL 配置逻辑
+	// 1. 确定发行版布局
+	layout, err := detectApacheLayout()
+	if err != nil {
+		return fmt.Errorf("查找 Apache 配置路径失败: %w", err)
+	}
+	a.layout = layout
+	logger.Debug("Apache 布局", "layout", layout.name, "config", layout.configPath, "service", layout.service)
+
+	// 2. 确保 mod_ssl 已启用
+	if err := layout.ensureSSLModule(); err != nil {
+		return err
+	}
+
+	// 3. 旧版本不支持在 SSLCertificateFile 中包含证书链
+	data := *config
+	if version := layout.version(); !supportsFullChain(version) {
+		chainPath, err := writeChainFile(config.CertPath)
+		if err != nil {
+			return err
+		}
+		data.ChainPath = chainPath
+		logger.Info("Apache 版本低于 2.4.8，使用 SSLCertificateChainFile", "version", version, "chain", chainPath)
+	}
+
+	// 4. 创建站点配置（暂存部署时写入 .disabled）
+	siteConfigPath, staged := stagedConfigPath(layout.siteConfigPath(config.Domain), config.StagingDeploy)
+	if err := a.createSiteConfig(&data, siteConfigPath); err != nil {
+		return fmt.Errorf("创建站点配置失败: %w", err)
+	}
+
+	// 5. 启用站点配置
+	if staged {
+		warnStaged("apache", config.Domain, siteConfigPath)
+	} else if err := a.enableSite(siteConfigPath); err != nil {
+		return fmt.Errorf("启用站点配置失败: %w", err)
+	}
+	a.configPath = siteConfigPath
+	config.ConfigPath = siteConfigPath
+	config.ChainPath = data.ChainPath
+
+	// 6. 记录配置历史
+	recordConfigHistory("apache", config.Domain, siteConfigPath)
 
 	logger.Info("Apache 配置完成", "domain", config.Domain)
 	return nil
 }
 
+// createSiteConfig 渲染并写入站点配置 configFile
+func (a *ApacheConfigurator) createSiteConfig(config *Config, configFile string) error {
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		return err
+	}
+
+	t, err := loadTemplate("apache", apacheTemplate, config.Domain)
+	if err != nil {
+		return err
+	}
+	warnIgnoredNoRedirect(t, config)
+	data := *config
+	if data.WebRoot == "" {
+		data.WebRoot = defaultWebRoot
+	}
+	var content strings.Builder
+	if err := t.Execute(&content, &data); err != nil {
+		return err
+	}
+
+	// 使用自定义模板或模板片段时，先在临时配置中检查渲染结果，避免重载时才发现错误
+	if CustomizedFor(config.Domain) {
+		if err := TestRenderedConfig("apache", content.String()); err != nil {
+			return err
+		}
+	}
+
+	if err := writeManagedConfig("apache", config.Domain, configFile, content.String(), config.OverwriteDrift); err != nil && !reportDrift(err) {
+		return err
+	}
+
+	logger.Info("创建 Apache 站点配置", "configFile", configFile)
+	return nil
+}
+
+// enableSite Debian 布局通过 a2ensite 启用站点（没有 a2ensite 时创建符号链接），conf.d 中的配置直接生效
+func (a *ApacheConfigurator) enableSite(configFile string) error {
+	if a.layout.enabledDir == "" {
+		return nil
+	}
+
+	// a2ensite 只管理 /etc/apache2 下的站点，webserver.config_path 指向其他位置时创建符号链接
+	site := strings.TrimSuffix(filepath.Base(configFile), ".conf")
+	if _, err := exec.LookPath("a2ensite"); err == nil && a.layout.siteDir == apacheLayouts[0].siteDir {
+		if output, err := exec.Command("a2ensite", site).CombinedOutput(); err != nil {
+			return fmt.Errorf("a2ensite 失败: %s", strings.TrimSpace(string(output)))
+		}
+		logger.Info("启用 Apache 站点", "site", site)
+		return nil
+	}
+
+	if err := os.MkdirAll(a.layout.enabledDir, 0755); err != nil {
+		return err
+	}
+	linkPath := filepath.Join(a.layout.enabledDir, filepath.Base(configFile))
+	os.Remove(linkPath)
+	if err := os.Symlink(configFile, linkPath); err != nil {
+		return err
+	}
+
+	logger.Info("启用 Apache 站点", "link", linkPath)
+	return nil
+}
+
 // Test 测试 Apache 配置
 func (a *ApacheConfigurator) Test() error {
-	cmd := exec.Command("apache2ctl", "configtest")
-	if _, err := exec.LookPath("apache2ctl"); err != nil {
-		cmd = exec.Command("httpd", "-t")
+	if custom, err := a.commands.runTest("Apache"); custom {
+		if err == nil {
+			logger.Info("Apache 配置测试成功（自定义命令）")
+		}
+		return err
 	}
 
+	layout, err := a.getLayout()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(layout.ctl(), "-t")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("Apache 配置测试失败: %s", string(output))
@@ -367,16 +617,29 @@ func (a *ApacheConfigurator) Test() error {
 	return nil
 }
 
-// Reload 重载 Apache 配置
+// Reload 重载 Apache 配置，服务名称随发行版不同（apache2 或 httpd）
 func (a *ApacheConfigurator) Reload() error {
+	if custom, err := a.commands.runReload("Apache"); custom {
+		if err == nil {
+			logger.Info("Apache 配置重载成功（自定义命令）")
+		}
+		return err
+	}
+
+	layout, err := a.getLayout()
+	if err != nil {
+		return err
+	}
+
 	var cmd *exec.Cmd
 
 	if _, err := exec.LookPath("systemctl"); err == nil {
-		cmd = exec.Command("systemctl", "reload", "apache2")
-	} else if _, err := exec.LookPath("apache2ctl"); err == nil {
-		cmd = exec.Command("apache2ctl", "graceful")
+		cmd = exec.Command("systemctl", "reload", layout.service)
+	} else if _, err := exec.LookPath("rc-service"); err == nil {
+		// Alpine 使用 OpenRC
+		cmd = exec.Command("rc-service", layout.service, "reload")
 	} else {
-		cmd = exec.Command("httpd", "-k", "graceful")
+		cmd = exec.Command(layout.ctl(), "-k", "graceful")
 	}
 
 	output, err := cmd.CombinedOutput()
@@ -384,56 +647,178 @@ func (a *ApacheConfigurator) Reload() error {
 		return fmt.Errorf("重载 Apache 失败: %s", string(output))
 	}
 
-	logger.Info("Apache 配置重载成功")
+	logger.Info("Apache 配置重载成功", "service", layout.service)
 	return nil
 }
 
+// getLayout 返回 Configure 时检测到的布局，未调用 Configure 时重新检测
+func (a *ApacheConfigurator) getLayout() (*apacheLayout, error) {
+	if a.layout != nil {
+		return a.layout, nil
+	}
+	layout, err := detectApacheLayout()
+	if err != nil {
+		return nil, err
+	}
+	a.layout = layout
+	return layout, nil
+}
+
 // GetConfigPath 获取配置路径
 func (a *ApacheConfigurator) GetConfigPath() string {
 	return a.configPath
 }
 
-// IsSSLEnabled 检查 SSL 是否已启用
+// IsSSLEnabled 检查 SSL 是否已启用：已生效的站点配置中有该域名且配置了 SSLCertificateFile
 func (a *ApacheConfigurator) IsSSLEnabled(domain string) bool {
-	// Apache SSL 检查实现
+	layout, err := a.getLayout()
+	if err != nil {
+		return false
+	}
+
+	for _, dir := range layout.searchDirs() {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.conf"))
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil || !strings.Contains(string(data), "SSLCertificateFile") {
+				continue
+			}
+			for _, name := range parseApacheServerNames(file) {
+				if strings.EqualFold(name, domain) {
+					return true
+				}
+			}
+		}
+	}
 	return false
 }
 
 // IISConfigurator IIS 配置器
-type IISConfigurator struct{}
+type IISConfigurator struct {
+	configPath   string
+	centralStore bool // 证书已写入集中式证书存储，IIS 自动加载，无需重启
+	domain       string
+	thumbprint   string // 导入本机证书存储的新证书指纹
+	commands     Commands
+}
 
-// Configure 配置 IIS
+// Configure 配置 IIS；配置了 webserver.iis.central_store 时将证书写入集中式证书存储
 func (i *IISConfigurator) Configure(config *Config) error {
 	logger.Info("开始配置 IIS", "domain", config.Domain)
+	i.commands = CommandsFor(config.Domain)
+
+	if store := centralStorePath(); store != "" {
+		files, err := deployCentralStore(store, config)
+		if err != nil {
+			return fmt.Errorf("部署到集中式证书存储失败: %w", err)
+		}
+		i.centralStore = true
+		i.configPath = files[0]
+		config.ConfigPath = files[0]
+		logger.Info("IIS 配置完成（集中式证书存储）", "domain", config.Domain, "store", store)
+		return nil
+	}
+
+	remote := iisRemoteHost()
+	if runtime.GOOS != "windows" && remote == "" {
+		return fmt.Errorf("IIS 只能在 Windows 上配置；也可以配置 webserver.iis.remote 通过 WinRM 管理远程主机，或配置 webserver.iis.central_store 写入集中式证书存储")
+	}
+
+	// 1. 导入 LocalMachine\My
+	thumbprint, err := importMachineCertificate(config)
+	if err != nil {
+		return err
+	}
+	if err := recordThumbprint(config.Domain, thumbprint); err != nil {
+		logger.Warn("记录证书指纹失败", "domain", config.Domain, "error", err)
+	}
+
+	// 2. 更新站点的 HTTPS 绑定
+	site := iisSite()
+	hosts := append([]string{config.Domain}, config.Aliases...)
+	if err := bindIISSite(site, hosts, thumbprint); err != nil {
+		return err
+	}
 
-	// IIS 配置实现
-	// 这里应该实现完整的 IIS SSL 配置逻辑，使用 PowerShell 脚本
+	i.domain = config.Domain
+	i.thumbprint = thumbprint
+	i.configPath = `Cert:\LocalMachine\My\` + thumbprint
+	if remote != "" {
+		i.configPath = remote + ":" + i.configPath
+	}
+	config.ConfigPath = i.configPath
 
-	logger.Info("IIS 配置完成", "domain", config.Domain)
+	logger.Info("IIS 配置完成", "domain", config.Domain, "host", iisHost(), "site", site, "thumbprint", thumbprint)
 	return nil
 }
 
+// iisSite 绑定证书的 IIS 站点
+func iisSite() string {
+	if site := config.GetWebServerConfig().IIS.Site; site != "" {
+		return site
+	}
+	return "Default Web Site"
+}
+
+// centralStorePath 配置的集中式证书存储目录，未配置时为空
+func centralStorePath() string {
+	return config.GetWebServerConfig().IIS.CentralStore
+}
+
 // Test 测试 IIS 配置
 func (i *IISConfigurator) Test() error {
+	if custom, err := i.commands.runTest("IIS"); custom {
+		if err == nil {
+			logger.Info("IIS 配置测试成功（自定义命令）")
+		}
+		return err
+	}
+
 	// IIS 没有直接的配置测试命令，可以检查站点状态
 	logger.Info("IIS 配置测试成功")
 	return nil
 }
 
-// Reload 重载 IIS 配置
+// Reload 重载 IIS 配置；使用集中式证书存储时 IIS 监视共享目录自动加载新证书，不执行 iisreset
 func (i *IISConfigurator) Reload() error {
-	cmd := exec.Command("iisreset")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("重载 IIS 失败: %s", string(output))
+	if custom, err := i.commands.runReload("IIS"); custom {
+		if err == nil {
+			logger.Info("IIS 配置重载成功（自定义命令）")
+		}
+		return err
+	}
+
+	if i.centralStore {
+		logger.Info("IIS 将从集中式证书存储自动加载新证书，无需重启")
+		return nil
+	}
+
+	if iisRemoteHost() != "" {
+		if _, err := runPowerShell("iisreset"); err != nil {
+			return fmt.Errorf("重载 IIS 失败: %w", err)
+		}
+	} else {
+		cmd := exec.Command("iisreset")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("重载 IIS 失败: %s", string(output))
+		}
 	}
 
 	logger.Info("IIS 配置重载成功")
+
+	// 新绑定确认生效后清理被替换的旧证书
+	if i.thumbprint != "" {
+		removeSupersededCertificates(iisSite(), i.domain, i.thumbprint)
+	}
 	return nil
 }
 
-// GetConfigPath 获取配置路径
+// GetConfigPath 获取配置路径（使用集中式证书存储时为写入的 PFX 文件）
 func (i *IISConfigurator) GetConfigPath() string {
+	if i.configPath != "" {
+		return i.configPath
+	}
 	return `C:\Windows\System32\inetsrv\config\applicationHost.config`
 }
 
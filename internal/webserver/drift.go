@@ -0,0 +1,305 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// managedConfigsFile 配置目录中记录 autocert 生成的站点配置的文件
+const managedConfigsFile = "managed-configs.json"
+
+// PendingSuffix 站点配置被手工修改、拒绝覆盖时，新生成的内容保存在加上该后缀的文件中，等待合并
+const PendingSuffix = ".autocert-new"
+
+// 站点配置的状态
+const (
+	DriftNone     = "unchanged" // 与上次生成的内容一致
+	DriftModified = "modified"  // 上次生成后被手工修改
+	DriftMissing  = "missing"   // 文件已被删除
+)
+
+// ErrConfigDrift 站点配置在上次生成后被手工修改
+var ErrConfigDrift = errors.New("站点配置已被手工修改")
+
+// managedMu 串行化记录文件的读写
+var managedMu sync.Mutex
+
+// ManagedConfig autocert 生成的站点配置：记录上次生成（或合并后确认）的内容及其 SHA-256，
+// 文件内容与之不同即为手工修改
+type ManagedConfig struct {
+	Path      string    `json:"-"`
+	Server    string    `json:"server"`
+	Domain    string    `json:"domain"`
+	Hash      string    `json:"sha256"`
+	Content   string    `json:"content"`
+	WrittenAt time.Time `json:"written_at"`
+}
+
+// Status 站点配置当前的状态和内容
+func (m *ManagedConfig) Status() (string, string) {
+	current, err := os.ReadFile(m.Path)
+	if err != nil {
+		return DriftMissing, ""
+	}
+	if hashContent(string(current)) != m.Hash {
+		return DriftModified, string(current)
+	}
+	return DriftNone, string(current)
+}
+
+// Pending 拒绝覆盖时保存的新生成内容，没有时返回空
+func (m *ManagedConfig) Pending() (string, bool) {
+	data, err := os.ReadFile(m.Path + PendingSuffix)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// DriftError 站点配置被手工修改且新生成的内容与上次不同，拒绝覆盖
+type DriftError struct {
+	Path    string
+	Pending string // 新生成内容的保存位置
+	Diff    string // 手工修改（上次生成 → 当前）
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("站点配置 %s 在上次生成后被手工修改，拒绝覆盖：新配置已保存到 %s，"+
+		"合并后执行 autocert drift accept %s，或使用 --overwrite-drift 覆盖（autocert drift diff %s 查看差异）",
+		e.Path, e.Pending, e.Path, e.Path)
+}
+
+func (e *DriftError) Unwrap() error {
+	return ErrConfigDrift
+}
+
+// driftReporter 站点配置被手工修改、保留手工修改继续部署时调用（命令行输出差异）
+var driftReporter func(*DriftError)
+
+// SetDriftReporter 设置站点配置被手工修改时的报告方式，未设置时只记录日志
+func SetDriftReporter(reporter func(*DriftError)) {
+	driftReporter = reporter
+}
+
+// reportDrift err 为 *DriftError 时作为警告报告并返回 true：保留手工修改的站点配置，
+// 证书文件路径不变，之后的测试、重载和部署钩子照常执行，新证书随重载生效
+func reportDrift(err error) bool {
+	var drift *DriftError
+	if !errors.As(err, &drift) {
+		return false
+	}
+	logger.Warn("站点配置已被手工修改，保留手工修改继续部署", "file", drift.Path, "pending", drift.Pending, "detail", drift.Error())
+	if driftReporter != nil {
+		driftReporter(drift)
+	}
+	return true
+}
+
+// writeManagedConfig 写入生成的站点配置并记录其内容。文件在上次生成后被手工修改时：
+// 新生成的内容没有变化则保留手工修改；有变化且未指定 overwrite 时拒绝覆盖，返回 *DriftError
+func writeManagedConfig(serverType, domain, path, content string, overwrite bool) error {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	configs, err := loadManagedConfigs()
+	if err != nil {
+		return err
+	}
+
+	record := configs[path]
+	if current, err := os.ReadFile(path); err == nil && record != nil && hashContent(string(current)) != record.Hash {
+		switch {
+		case content == record.Content:
+			logger.Info("站点配置已被手工修改，生成的内容没有变化，保留手工修改", "file", path)
+			return nil
+		case !overwrite:
+			pending := path + PendingSuffix
+			if err := os.WriteFile(pending, []byte(content), 0644); err != nil {
+				return fmt.Errorf("保存新的站点配置失败: %w", err)
+			}
+			return &DriftError{Path: path, Pending: pending, Diff: UnifiedDiff(path+"（上次生成）", path, record.Content, string(current))}
+		default:
+			logger.Warn("覆盖手工修改的站点配置（--overwrite-drift）", "file", path)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return err
+	}
+	os.Remove(path + PendingSuffix)
+
+	configs[path] = &ManagedConfig{
+		Path:      path,
+		Server:    serverType,
+		Domain:    domain,
+		Hash:      hashContent(content),
+		Content:   content,
+		WrittenAt: time.Now(),
+	}
+	if err := saveManagedConfigs(configs); err != nil {
+		logger.Warn("记录生成的站点配置失败", "file", path, "error", err)
+	}
+	return nil
+}
+
+// snapshotManagedConfig 保存站点配置文件及其记录的当前状态，返回恢复该状态的函数
+func snapshotManagedConfig(path string) func() {
+	previous, readErr := os.ReadFile(path)
+	managedMu.Lock()
+	configs, _ := loadManagedConfigs()
+	record := configs[path]
+	managedMu.Unlock()
+
+	return func() {
+		if readErr == nil {
+			os.WriteFile(path, previous, 0644)
+		} else {
+			os.Remove(path)
+		}
+
+		managedMu.Lock()
+		defer managedMu.Unlock()
+		configs, err := loadManagedConfigs()
+		if err != nil {
+			return
+		}
+		if record != nil {
+			configs[path] = record
+		} else {
+			delete(configs, path)
+		}
+		if err := saveManagedConfigs(configs); err != nil {
+			logger.Warn("恢复站点配置记录失败", "file", path, "error", err)
+		}
+	}
+}
+
+// moveManagedConfig 站点配置文件改名后（启用暂存的配置）将记录移到新路径
+func moveManagedConfig(from, to string) {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	configs, err := loadManagedConfigs()
+	if err != nil || configs[from] == nil {
+		return
+	}
+	configs[to] = configs[from]
+	delete(configs, from)
+	if err := saveManagedConfigs(configs); err != nil {
+		logger.Warn("更新站点配置记录失败", "file", to, "error", err)
+	}
+}
+
+// ManagedConfigs 列出 autocert 生成的站点配置，按路径排序
+func ManagedConfigs() ([]*ManagedConfig, error) {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	configs, err := loadManagedConfigs()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*ManagedConfig, 0, len(configs))
+	for _, record := range configs {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list, nil
+}
+
+// AcceptMerged 确认已将新生成的内容合并到站点配置：以新生成的内容作为之后比较的基准，
+// 生成的内容不再变化时保留当前文件
+func AcceptMerged(path string) error {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	configs, err := loadManagedConfigs()
+	if err != nil {
+		return err
+	}
+	record := configs[path]
+	if record == nil {
+		return fmt.Errorf("%s 不是 autocert 生成的站点配置", path)
+	}
+	pending, ok := record.Pending()
+	if !ok {
+		return fmt.Errorf("%s 没有待合并的新配置（%s）", path, path+PendingSuffix)
+	}
+
+	record.Hash = hashContent(pending)
+	record.Content = pending
+	record.WrittenAt = time.Now()
+	if err := saveManagedConfigs(configs); err != nil {
+		return err
+	}
+	return os.Remove(path + PendingSuffix)
+}
+
+// managedPreview 按记录调整预览：手工修改且生成的内容没有变化时不会写入；返回是否会因手工修改拒绝覆盖
+func managedPreview(preview *SitePreview) bool {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+
+	configs, err := loadManagedConfigs()
+	record := configs[preview.Path]
+	if err != nil || record == nil || !preview.Exists || hashContent(preview.Current) == record.Hash {
+		return false
+	}
+	if preview.Desired == record.Content {
+		preview.Desired = preview.Current
+		return false
+	}
+	return true
+}
+
+// managedConfigsPath 记录文件路径
+func managedConfigsPath() string {
+	return filepath.Join(config.GetConfigDir(), managedConfigsFile)
+}
+
+// loadManagedConfigs 读取记录，文件不存在时返回空记录
+func loadManagedConfigs() (map[string]*ManagedConfig, error) {
+	configs := make(map[string]*ManagedConfig)
+	data, err := os.ReadFile(managedConfigsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return configs, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", managedConfigsPath(), err)
+	}
+	for path, record := range configs {
+		record.Path = path
+	}
+	return configs, nil
+}
+
+// saveManagedConfigs 保存记录
+func saveManagedConfigs(configs map[string]*ManagedConfig) error {
+	data, err := json.MarshalIndent(configs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(managedConfigsPath()), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(managedConfigsPath(), data)
+}
+
+// hashContent 内容的 SHA-256（十六进制）
+func hashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
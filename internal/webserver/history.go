@@ -0,0 +1,132 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 配置历史记录方式
+const (
+	HistoryGit      = "git"      // 写入本地 git 仓库，每次变更一次提交
+	HistorySnapshot = "snapshot" // 按时间保存快照目录
+)
+
+// recordConfigHistory 将生成或修改的 Web 服务器配置记录到配置历史
+// 记录失败只输出警告，不影响配置本身
+func recordConfigHistory(serverType, domain, configFile string) {
+	webConfig := config.GetWebServerConfig()
+	if webConfig.ConfigHistory == "" {
+		return
+	}
+
+	historyDir := webConfig.ConfigHistoryDir
+	if historyDir == "" {
+		historyDir = filepath.Join(config.GetConfigDir(), "config-history")
+	}
+
+	var err error
+	switch webConfig.ConfigHistory {
+	case HistoryGit:
+		err = commitConfigHistory(historyDir, serverType, domain, configFile)
+	case HistorySnapshot:
+		err = snapshotConfigHistory(historyDir, configFile)
+	default:
+		err = fmt.Errorf("不支持的配置历史记录方式: %s", webConfig.ConfigHistory)
+	}
+
+	if err != nil {
+		logger.Warn("记录配置历史失败", "file", configFile, "error", err)
+	}
+}
+
+// commitConfigHistory 将配置文件复制到 git 仓库并提交
+func commitConfigHistory(repoDir, serverType, domain, configFile string) error {
+	if _, err := os.Stat(filepath.Join(repoDir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(repoDir, 0755); err != nil {
+			return err
+		}
+		if _, err := runGit(repoDir, "init"); err != nil {
+			return err
+		}
+		logger.Info("初始化配置历史仓库", "dir", repoDir)
+	}
+
+	relPath := historyPath(configFile)
+	if err := copyConfigFile(configFile, filepath.Join(repoDir, relPath)); err != nil {
+		return err
+	}
+
+	if _, err := runGit(repoDir, "add", "--", relPath); err != nil {
+		return err
+	}
+
+	// 内容未变化时不提交
+	status, err := runGit(repoDir, "status", "--porcelain", "--", relPath)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(status) == "" {
+		return nil
+	}
+
+	message := fmt.Sprintf("%s: 更新 %s 配置\n\n文件: %s", serverType, domain, configFile)
+	if _, err := runGit(repoDir, "-c", "user.name=autocert", "-c", "user.email=autocert@localhost",
+		"commit", "-q", "-m", message); err != nil {
+		return err
+	}
+
+	logger.Info("配置变更已提交到历史仓库", "dir", repoDir, "file", relPath)
+	return nil
+}
+
+// snapshotConfigHistory 将配置文件复制到按时间命名的快照目录
+func snapshotConfigHistory(historyDir, configFile string) error {
+	snapshotDir := filepath.Join(historyDir, time.Now().Format("20060102-150405"))
+	target := filepath.Join(snapshotDir, historyPath(configFile))
+	if err := copyConfigFile(configFile, target); err != nil {
+		return err
+	}
+
+	logger.Info("配置快照已保存", "path", target)
+	return nil
+}
+
+// historyPath 将配置文件的绝对路径转换为历史目录中的相对路径
+// 例如 /etc/nginx/sites-available/example.com -> etc/nginx/sites-available/example.com
+func historyPath(configFile string) string {
+	path := configFile
+	if abs, err := filepath.Abs(configFile); err == nil {
+		path = abs
+	}
+	path = strings.TrimPrefix(path, filepath.VolumeName(path))
+	return strings.TrimLeft(path, `/\`)
+}
+
+// copyConfigFile 复制配置文件
+func copyConfigFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// runGit 在指定目录执行 git 命令
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s 失败: %s", args[0], strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
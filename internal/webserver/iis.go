@@ -0,0 +1,343 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/pkcs12"
+	"autocert/internal/secret"
+	"autocert/internal/winrm"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// iisStateFile 记录每个域名导入本机证书存储的证书指纹（旧的在前），只有其中的证书会被清理
+const iisStateFile = "iis-certificates.json"
+
+// importCertificateScript 将 PFX 导入 LocalMachine\My（中间证书导入 LocalMachine\CA），PFX 内容和密码通过环境变量传入，
+// 避免引号转义和出现在进程参数中；远程主机上由 WinRM 客户端经标准输入传入，也不需要先上传文件
+const importCertificateScript = `$ErrorActionPreference = 'Stop'
+$flags = [Security.Cryptography.X509Certificates.X509KeyStorageFlags]'MachineKeySet,PersistKeySet'
+$certs = New-Object Security.Cryptography.X509Certificates.X509Certificate2Collection
+$certs.Import([Convert]::FromBase64String($env:AUTOCERT_PFX_DATA), $env:AUTOCERT_PFX_PASSWORD, $flags)
+foreach ($cert in $certs) {
+  if ($cert.HasPrivateKey) {
+    $cert.FriendlyName = $env:AUTOCERT_FRIENDLY_NAME
+    $storeName = 'My'
+  } else {
+    $storeName = 'CA'
+  }
+  $store = New-Object Security.Cryptography.X509Certificates.X509Store($storeName, 'LocalMachine')
+  $store.Open('ReadWrite')
+  $store.Add($cert)
+  $store.Close()
+  if ($cert.HasPrivateKey) { $cert.Thumbprint }
+}`
+
+// bindCertificateScript 为站点添加 SNI 的 HTTPS 绑定（已存在时保留），并将主机名的 SSL 绑定指向新证书
+const bindCertificateScript = `$ErrorActionPreference = 'Stop'
+Import-Module WebAdministration
+$site = $env:AUTOCERT_IIS_SITE
+$thumbprint = $env:AUTOCERT_THUMBPRINT
+foreach ($hostName in $env:AUTOCERT_HOSTS.Split(',')) {
+  if (-not (Get-WebBinding -Name $site -Protocol https -Port 443 -HostHeader $hostName)) {
+    New-WebBinding -Name $site -Protocol https -Port 443 -HostHeader $hostName -SslFlags 1
+  }
+  $path = "IIS:\SslBindings\!443!$hostName"
+  if (Test-Path -LiteralPath $path) { Remove-Item -LiteralPath $path }
+  Get-Item "Cert:\LocalMachine\My\$thumbprint" | New-Item $path -SSLFlags 1 | Out-Null
+}`
+
+// removeCertificateScript 删除证书，仍被任何 SSL 绑定使用时跳过
+const removeCertificateScript = `$ErrorActionPreference = 'Stop'
+Import-Module WebAdministration
+$thumbprint = $env:AUTOCERT_THUMBPRINT
+if (Get-ChildItem IIS:\SslBindings | Where-Object { $_.Thumbprint -eq $thumbprint }) {
+  'in-use'
+} elseif (Test-Path "Cert:\LocalMachine\My\$thumbprint") {
+  Remove-Item "Cert:\LocalMachine\My\$thumbprint"
+  'removed'
+} else {
+  'missing'
+}`
+
+// bindingInfoScript 输出站点中主机名的 HTTPS 绑定（IP:端口:主机名），没有该主机名的绑定时输出站点的第一个 HTTPS 绑定
+const bindingInfoScript = `$ErrorActionPreference = 'Stop'
+Import-Module WebAdministration
+$bindings = @(Get-WebBinding -Name $env:AUTOCERT_IIS_SITE -Protocol https)
+$binding = $bindings | Where-Object { $_.bindingInformation.Split(':')[-1] -eq $env:AUTOCERT_HOST } | Select-Object -First 1
+if (-not $binding) { $binding = $bindings | Select-Object -First 1 }
+if ($binding) { $binding.bindingInformation }`
+
+// runPowerShell 执行 PowerShell 脚本，env 为额外的环境变量（KEY=VALUE）；
+// 配置了 webserver.iis.remote.host 时通过 WinRM 在远程主机上执行
+func runPowerShell(script string, env ...string) (string, error) {
+	client, err := iisRemote()
+	if err != nil {
+		return "", err
+	}
+	if client != nil {
+		vars := make(map[string]string, len(env))
+		for _, kv := range env {
+			name, value, _ := strings.Cut(kv, "=")
+			vars[name] = value
+		}
+		result, err := client.RunPowerShell(script, vars)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", client.Host(), err)
+		}
+		if result.ExitCode != 0 {
+			return "", fmt.Errorf("%s: 退出码 %d: %s", client.Host(), result.ExitCode, result.Stderr)
+		}
+		return strings.TrimSpace(result.Stdout), nil
+	}
+
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// iisRemote 配置了远程主机时返回 WinRM 客户端，否则返回 nil
+func iisRemote() (*winrm.Client, error) {
+	remote := config.GetWebServerConfig().IIS.Remote
+	if remote.Host == "" {
+		return nil, nil
+	}
+	password, err := secret.Resolve(remote.Password)
+	if err != nil {
+		return nil, fmt.Errorf("读取 WinRM 密码失败: %w", err)
+	}
+	return winrm.New(winrm.Config{
+		Host:      remote.Host,
+		Port:      remote.Port,
+		HTTPS:     remote.HTTPS,
+		AllowHTTP: remote.AllowHTTP,
+		Insecure:  remote.Insecure,
+		CACert:    remote.CACert,
+		Username:  remote.Username,
+		Password:  password,
+		Timeout:   remote.Timeout,
+	})
+}
+
+// iisRemoteHost 通过 WinRM 管理的远程主机，管理本机 IIS 时为空
+func iisRemoteHost() string {
+	return config.GetWebServerConfig().IIS.Remote.Host
+}
+
+// iisHost 管理的 IIS 所在主机：远程主机或本机（localhost 同时尝试 IPv6 和 IPv4）
+func iisHost() string {
+	if host := iisRemoteHost(); host != "" {
+		return host
+	}
+	return "localhost"
+}
+
+// servedAddress 根据站点中主机名的 HTTPS 绑定确定检查证书时连接的地址：绑定了 IP 时连接该 IP，
+// 绑定所有地址（*）时连接 IIS 所在主机；读取不到绑定时使用 IIS 所在主机的 443 端口
+func servedAddress(site, host string) string {
+	output, err := runPowerShell(bindingInfoScript, "AUTOCERT_IIS_SITE="+site, "AUTOCERT_HOST="+host)
+	if err != nil || output == "" {
+		logger.Debug("读取 IIS 绑定失败，使用 443 端口", "site", site, "host", host, "error", err)
+		return net.JoinHostPort(iisHost(), "443")
+	}
+	return bindingAddress(strings.TrimSpace(output), iisHost())
+}
+
+// bindingAddress 将 IIS 绑定信息（IP:端口:主机名，IPv6 地址带方括号）转换为连接地址，IP 为 * 或空时使用 defaultHost
+func bindingAddress(binding, defaultHost string) string {
+	if i := strings.LastIndex(binding, ":"); i >= 0 {
+		binding = binding[:i]
+	}
+	ip, port, err := net.SplitHostPort(binding)
+	if err != nil || port == "" {
+		return net.JoinHostPort(defaultHost, "443")
+	}
+	if ip == "" || ip == "*" {
+		ip = defaultHost
+	}
+	return net.JoinHostPort(ip, port)
+}
+
+// certThumbprint Windows 证书指纹（DER 的 SHA-1，大写十六进制）
+func certThumbprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// importMachineCertificate 将证书打包为 PFX（随机密码）导入 LocalMachine\My，返回证书指纹
+func importMachineCertificate(cfg *Config) (string, error) {
+	key, leaf, chain, err := loadCertificatePair(cfg.CertPath, cfg.KeyPath)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, 15)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.New("生成随机密码失败")
+	}
+	password := base64.RawURLEncoding.EncodeToString(buf)
+
+	pfx, err := pkcs12.Encode(key, leaf, chain, password, cfg.Domain)
+	if err != nil {
+		return "", fmt.Errorf("生成 PFX 失败: %w", err)
+	}
+	output, err := runPowerShell(importCertificateScript,
+		"AUTOCERT_PFX_DATA="+base64.StdEncoding.EncodeToString(pfx),
+		"AUTOCERT_PFX_PASSWORD="+password,
+		"AUTOCERT_FRIENDLY_NAME=AutoCert "+cfg.Domain+" "+leaf.NotAfter.Format("2006-01-02"))
+	if err != nil {
+		return "", fmt.Errorf("导入证书失败: %w", err)
+	}
+
+	thumbprint := certThumbprint(leaf)
+	if !strings.Contains(strings.ToUpper(output), thumbprint) {
+		logger.Warn("导入结果中没有预期的证书指纹", "expected", thumbprint, "output", output)
+	}
+	return thumbprint, nil
+}
+
+// bindIISSite 将站点中各主机名的 HTTPS 绑定指向指定证书
+func bindIISSite(site string, hosts []string, thumbprint string) error {
+	_, err := runPowerShell(bindCertificateScript,
+		"AUTOCERT_IIS_SITE="+site,
+		"AUTOCERT_THUMBPRINT="+thumbprint,
+		"AUTOCERT_HOSTS="+strings.Join(hosts, ","))
+	if err != nil {
+		return fmt.Errorf("更新 IIS 绑定失败: %w", err)
+	}
+	return nil
+}
+
+// servedThumbprint 通过 SNI 连接站点绑定的地址，返回 IIS 为该主机名提供的证书指纹
+func servedThumbprint(site, host string) (string, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", servedAddress(site, host), &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true, // 只比较指纹，不校验证书链
+	})
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", errors.New("服务器没有返回证书")
+	}
+	return certThumbprint(certs[0]), nil
+}
+
+// iisStatePath 指纹记录文件路径
+func iisStatePath() string {
+	return filepath.Join(config.GetConfigDir(), iisStateFile)
+}
+
+// loadIISState 读取指纹记录，文件不存在时返回空记录
+func loadIISState() (map[string][]string, error) {
+	state := make(map[string][]string)
+	data, err := os.ReadFile(iisStatePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", iisStatePath(), err)
+	}
+	return state, nil
+}
+
+// saveIISState 保存指纹记录
+func saveIISState(state map[string][]string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(iisStatePath(), data)
+}
+
+// iisStateKey 指纹记录的键：本机为域名，远程主机为 主机/域名
+func iisStateKey(domain string) string {
+	if host := iisRemoteHost(); host != "" {
+		return host + "/" + domain
+	}
+	return domain
+}
+
+// recordThumbprint 记录导入的证书指纹
+func recordThumbprint(domain, thumbprint string) error {
+	state, err := loadIISState()
+	if err != nil {
+		return err
+	}
+	key := iisStateKey(domain)
+	for _, existing := range state[key] {
+		if existing == thumbprint {
+			return nil
+		}
+	}
+	state[key] = append(state[key], thumbprint)
+	return saveIISState(state)
+}
+
+// removeSupersededCertificates 确认 IIS 已为域名提供新证书后，从 LocalMachine\My 删除 AutoCert 之前导入的旧证书；
+// 未确认时保留旧证书，便于回滚。仍被其他绑定使用的证书不删除，下次续期时再尝试
+func removeSupersededCertificates(site, domain, current string) {
+	served, err := servedThumbprint(site, domain)
+	if err != nil {
+		logger.Warn("无法确认 IIS 绑定，保留旧证书", "domain", domain, "error", err)
+		return
+	}
+	if served != current {
+		logger.Warn("IIS 提供的证书不是新证书，保留旧证书", "domain", domain, "served", served, "expected", current)
+		return
+	}
+
+	state, err := loadIISState()
+	if err != nil {
+		logger.Warn("读取证书指纹记录失败", "error", err)
+		return
+	}
+
+	key := iisStateKey(domain)
+	var remaining []string
+	for _, thumbprint := range state[key] {
+		if thumbprint == current {
+			continue
+		}
+		result, err := runPowerShell(removeCertificateScript, "AUTOCERT_THUMBPRINT="+thumbprint)
+		switch {
+		case err != nil:
+			logger.Warn("删除旧证书失败", "domain", domain, "thumbprint", thumbprint, "error", err)
+			remaining = append(remaining, thumbprint)
+		case result == "in-use":
+			logger.Info("旧证书仍被其他绑定使用，暂不删除", "domain", domain, "thumbprint", thumbprint)
+			remaining = append(remaining, thumbprint)
+		case result == "removed":
+			logger.Info("已从本机证书存储删除旧证书", "domain", domain, "thumbprint", thumbprint)
+		}
+	}
+
+	state[key] = append(remaining, current)
+	if err := saveIISState(state); err != nil {
+		logger.Warn("保存证书指纹记录失败", "error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"autocert/internal/pkcs12"
+	"autocert/internal/secmem"
+	"autocert/internal/secret"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// centralStoreFileName IIS 集中式证书存储按主机名查找 <主机名>.pfx，泛域名的 * 写作 _
+func centralStoreFileName(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if rest, ok := strings.CutPrefix(host, "*."); ok {
+		host = "_." + rest
+	}
+	return host + ".pfx"
+}
+
+// deployCentralStore 将证书打包为 PFX，按主机名（主域名和所有别名）写入集中式证书存储目录，
+// 返回写入的文件。所有文件使用 IIS 中配置的同一个私钥密码
+func deployCentralStore(store string, cfg *Config) ([]string, error) {
+	password, err := secret.Resolve(config.GetWebServerConfig().IIS.CentralStorePassword)
+	if err != nil {
+		return nil, fmt.Errorf("读取集中式证书存储密码失败: %w", err)
+	}
+
+	key, leaf, chain, err := loadCertificatePair(cfg.CertPath, cfg.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+	pfx, err := pkcs12.Encode(key, leaf, chain, password, cfg.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("生成 PFX 失败: %w", err)
+	}
+
+	if _, err := os.Stat(store); err != nil {
+		return nil, fmt.Errorf("集中式证书存储目录不可访问: %w", err)
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, host := range append([]string{cfg.Domain}, cfg.Aliases...) {
+		name := centralStoreFileName(host)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		path := filepath.Join(store, name)
+		if err := writeFileAtomic(path, pfx); err != nil {
+			return files, fmt.Errorf("写入 %s 失败: %w", path, err)
+		}
+		files = append(files, path)
+		logger.Info("已写入集中式证书存储", "host", host, "file", path)
+	}
+	return files, nil
+}
+
+// writeFileAtomic 先写入同目录的临时文件再重命名，Web 场中的节点不会读到写了一半的 PFX
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// loadCertificatePair 读取 PEM 证书（叶子证书和中间证书）及私钥
+func loadCertificatePair(certPath, keyPath string) (crypto.PrivateKey, *x509.Certificate, []*x509.Certificate, error) {
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("读取证书失败: %w", err)
+	}
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, certData = pem.Decode(certData)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("解析证书失败: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, nil, nil, fmt.Errorf("%s 中没有证书", certPath)
+	}
+
+	keyData, err := secmem.ReadFile(keyPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("读取私钥失败: %w", err)
+	}
+	defer keyData.Destroy()
+	block, _ := pem.Decode(keyData.Bytes())
+	if block == nil {
+		return nil, nil, nil, fmt.Errorf("%s 不是有效的 PEM 私钥", keyPath)
+	}
+	defer secmem.Wipe(block.Bytes)
+	var key crypto.PrivateKey
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return key, certs[0], certs[1:], nil
+}
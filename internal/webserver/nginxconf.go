@@ -0,0 +1,279 @@
+package webserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// managedMarker AutoCert 生成的配置文件开头的注释
+const managedMarker = "# AutoCert"
+
+// NginxServer Nginx 配置中的 server 块
+type NginxServer struct {
+	File        string
+	Line        int
+	ServerNames []string
+	Listens     []NginxListen
+	Managed     bool // 由 AutoCert 生成
+}
+
+// NginxListen listen 指令
+type NginxListen struct {
+	Port          string
+	SSL           bool
+	DefaultServer bool
+}
+
+// nginxToken 配置中的词法单元
+type nginxToken struct {
+	text string
+	line int
+}
+
+// tokenizeNginx 将 Nginx 配置拆分为词法单元：指令参数、分号和大括号，忽略注释
+func tokenizeNginx(content string) []nginxToken {
+	var tokens []nginxToken
+	var current strings.Builder
+	line := 1
+	var quote rune
+	inComment := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, nginxToken{current.String(), line})
+			current.Reset()
+		}
+	}
+
+	for _, r := range content {
+		switch {
+		case inComment:
+			if r == '\n' {
+				inComment = false
+			}
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			flush()
+			inComment = true
+		case r == ';' || r == '{' || r == '}':
+			flush()
+			tokens = append(tokens, nginxToken{string(r), line})
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+		if r == '\n' {
+			line++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// ParseNginxServers 解析配置文件中的 server 块（不展开 include，忽略 stream 模块中的 server）
+func ParseNginxServers(path string) ([]NginxServer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+	managed := strings.HasPrefix(strings.TrimSpace(content), managedMarker)
+
+	var servers []NginxServer
+	var blocks []string        // 当前所在的块
+	var current []*NginxServer // 与 blocks 中的 server 块对应
+	var args []string
+	argLine := 0
+
+	for _, token := range tokenizeNginx(content) {
+		switch token.text {
+		case "{":
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			blocks = append(blocks, name)
+			if name == "server" && !contains(blocks, "stream") {
+				current = append(current, &NginxServer{File: path, Line: argLine, Managed: managed})
+			}
+			args = nil
+		case "}":
+			if len(blocks) == 0 {
+				return nil, fmt.Errorf("%s:%d: 多余的 }", path, token.line)
+			}
+			if blocks[len(blocks)-1] == "server" && !contains(blocks[:len(blocks)-1], "stream") {
+				server := current[len(current)-1]
+				current = current[:len(current)-1]
+				if len(server.Listens) == 0 {
+					server.Listens = []NginxListen{{Port: "80"}}
+				}
+				servers = append(servers, *server)
+			}
+			blocks = blocks[:len(blocks)-1]
+			args = nil
+		case ";":
+			if len(current) > 0 && len(blocks) > 0 && blocks[len(blocks)-1] == "server" && len(args) > 1 {
+				server := current[len(current)-1]
+				switch args[0] {
+				case "server_name":
+					server.ServerNames = append(server.ServerNames, args[1:]...)
+				case "listen":
+					server.Listens = append(server.Listens, parseListen(args[1:]))
+				}
+			}
+			args = nil
+		default:
+			if len(args) == 0 {
+				argLine = token.line
+			}
+			args = append(args, token.text)
+		}
+	}
+
+	if len(blocks) > 0 {
+		return nil, fmt.Errorf("%s: 缺少 }", path)
+	}
+	return servers, nil
+}
+
+// parseListen 解析 listen 指令的参数，例如 443 ssl、[::]:443 ssl default_server、127.0.0.1
+func parseListen(args []string) NginxListen {
+	listen := NginxListen{Port: "80"}
+
+	address := args[0]
+	if !strings.HasPrefix(address, "unix:") {
+		if i := strings.LastIndex(address, ":"); i >= 0 && !strings.HasSuffix(address, "]") {
+			listen.Port = address[i+1:]
+		} else if _, err := strconv.Atoi(address); err == nil {
+			listen.Port = address
+		}
+	}
+
+	for _, arg := range args[1:] {
+		switch arg {
+		case "ssl":
+			listen.SSL = true
+		case "default_server", "default":
+			listen.DefaultServer = true
+		}
+	}
+	return listen
+}
+
+// contains 判断字符串切片中是否包含指定值
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// 冲突类型
+const (
+	ConflictServerName    = "server_name"    // 同一端口上重复的 server_name
+	ConflictDefaultServer = "default_server" // 同一端口上有多个 default_server
+)
+
+// NginxConflict 同一端口上相互冲突的 server 块
+type NginxConflict struct {
+	Kind    string
+	Port    string
+	Name    string // 重复的 server_name（default_server 冲突时为空）
+	Servers []NginxServer
+}
+
+// NginxConfigFiles 返回 Nginx 主配置和站点配置文件（符号链接指向同一文件时只返回一次）
+func NginxConfigFiles() ([]string, error) {
+	n := &NginxConfigurator{}
+	if err := n.findConfigPath(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, path := range append([]string{n.configPath}, n.findSiteConfigs()...) {
+		info, err := os.Stat(path)
+		// 停用的配置、暂存部署和等待合并的新配置不会被加载
+		if err != nil || info.IsDir() || strings.HasSuffix(path, disabledSuffix) ||
+			strings.HasSuffix(path, DisabledSuffix) || strings.HasSuffix(path, PendingSuffix) {
+			continue
+		}
+		resolved := path
+		if target, err := filepath.EvalSymlinks(path); err == nil {
+			resolved = target
+		}
+		if !seen[resolved] {
+			seen[resolved] = true
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// FindNginxConflicts 检查站点配置中同一端口上重复的 server_name 和多个 default_server
+func FindNginxConflicts(files []string) ([]NginxConflict, error) {
+	type key struct{ kind, port, name string }
+	groups := make(map[key][]NginxServer)
+	var order []key
+
+	add := func(k key, server NginxServer) {
+		for _, existing := range groups[k] {
+			if existing.File == server.File && existing.Line == server.Line {
+				return
+			}
+		}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], server)
+	}
+
+	for _, file := range files {
+		servers, err := ParseNginxServers(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, server := range servers {
+			for _, listen := range server.Listens {
+				if listen.DefaultServer {
+					add(key{ConflictDefaultServer, listen.Port, ""}, server)
+				}
+				for _, name := range server.ServerNames {
+					// 正则和兜底名称不参与比较
+					if name == "_" || name == "" || strings.HasPrefix(name, "~") {
+						continue
+					}
+					add(key{ConflictServerName, listen.Port, strings.ToLower(name)}, server)
+				}
+			}
+		}
+	}
+
+	var conflicts []NginxConflict
+	for _, k := range order {
+		if servers := groups[k]; len(servers) > 1 {
+			conflicts = append(conflicts, NginxConflict{Kind: k.kind, Port: k.port, Name: k.name, Servers: servers})
+		}
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		if conflicts[i].Port != conflicts[j].Port {
+			return conflicts[i].Port < conflicts[j].Port
+		}
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return conflicts, nil
+}
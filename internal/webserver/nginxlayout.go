@@ -0,0 +1,210 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// nginxLayout Nginx 站点配置的存放方式，由主配置 http 块中的 include 指令决定：
+// Debian/Ubuntu 使用 sites-available + sites-enabled，RHEL 使用 conf.d，Alpine 使用 http.d
+type nginxLayout struct {
+	confDir      string // include 的 *.conf 目录，放入即生效
+	enabledDir   string // include 的 sites-enabled 目录，站点通过符号链接启用
+	availableDir string // 与 sites-enabled 同级的 sites-available
+	enabledExt   string // sites-enabled 的 include 为 *.conf 时，站点文件需要 .conf 扩展名
+
+	// 主配置没有 include 站点配置目录，confDir 为主配置同级的 conf.d（需要添加 include 才会生效）
+	missingInclude bool
+}
+
+// detectNginxLayout 解析主配置 http 块中的 include 指令（相对路径相对于主配置所在目录）
+func detectNginxLayout(configPath string) *nginxLayout {
+	layout := &nginxLayout{}
+	for _, include := range nginxHTTPIncludes(configPath) {
+		dir, pattern := filepath.Split(include)
+		dir = filepath.Clean(dir)
+		switch {
+		case filepath.Base(dir) == "sites-enabled" && (pattern == "*" || pattern == "*.conf"):
+			if layout.enabledDir == "" {
+				layout.enabledDir = dir
+				layout.availableDir = filepath.Join(filepath.Dir(dir), "sites-available")
+				if pattern == "*.conf" {
+					layout.enabledExt = ".conf"
+				}
+			}
+		case pattern == "*.conf":
+			if layout.confDir == "" {
+				layout.confDir = dir
+			}
+		}
+	}
+
+	if layout.enabledDir == "" && layout.confDir == "" {
+		layout.confDir = filepath.Join(filepath.Dir(configPath), "conf.d")
+		layout.missingInclude = true
+	}
+	return layout
+}
+
+// nginxHTTPIncludes 主配置 http 块中直接 include 的路径（不包括 main 上下文中的 modules-enabled 等）
+func nginxHTTPIncludes(configPath string) []string {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil
+	}
+
+	var includes []string
+	var blocks, args []string
+	for _, token := range tokenizeNginx(string(data)) {
+		switch token.text {
+		case "{":
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			blocks = append(blocks, name)
+			args = nil
+		case "}":
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			args = nil
+		case ";":
+			if len(args) == 2 && args[0] == "include" && len(blocks) == 1 && blocks[0] == "http" {
+				path := filepath.FromSlash(args[1])
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(filepath.Dir(configPath), path)
+				}
+				includes = append(includes, path)
+			}
+			args = nil
+		default:
+			args = append(args, token.text)
+		}
+	}
+	return includes
+}
+
+// siteConfigPath 域名的站点配置文件路径
+func (l *nginxLayout) siteConfigPath(domain string) string {
+	if l.enabledDir != "" {
+		return filepath.Join(l.availableDir, domain+l.enabledExt)
+	}
+	return filepath.Join(l.confDir, domain+".conf")
+}
+
+// siteLinkPath 启用站点的符号链接路径，使用 conf.d 布局时为空
+func (l *nginxLayout) siteLinkPath(configFile string) string {
+	if l.enabledDir == "" {
+		return ""
+	}
+	return filepath.Join(l.enabledDir, filepath.Base(configFile))
+}
+
+// sitesPath 合并的多站点配置文件路径：放在 include 的 *.conf 目录中，只有 sites-enabled 时直接放入其中
+func (l *nginxLayout) sitesPath(name string) string {
+	if l.confDir != "" {
+		return filepath.Join(l.confDir, name+".conf")
+	}
+	return filepath.Join(l.enabledDir, name+l.enabledExt)
+}
+
+// searchDirs 可能包含站点配置的目录
+func (l *nginxLayout) searchDirs() []string {
+	var dirs []string
+	if l.enabledDir != "" {
+		dirs = append(dirs, l.enabledDir)
+	}
+	if l.confDir != "" {
+		dirs = append(dirs, l.confDir)
+	}
+	return dirs
+}
+
+// ensureInclude 主配置没有 include 站点配置目录时，配置了 webserver.add_include 则在 http 块末尾
+// 添加 include conf.d/*.conf（修改前备份主配置），否则提示站点配置不会生效
+func (l *nginxLayout) ensureInclude(configPath string) error {
+	if !l.missingInclude {
+		return nil
+	}
+	pattern := filepath.ToSlash(filepath.Join(l.confDir, "*.conf"))
+	if !config.GetWebServerConfig().AddInclude {
+		logger.Warn("Nginx 主配置没有 include 站点配置目录，站点配置不会生效；设置 webserver.add_include: true 自动添加",
+			"config", configPath, "include", pattern)
+		return nil
+	}
+
+	backup, err := addNginxInclude(configPath, pattern)
+	if err != nil {
+		return fmt.Errorf("添加 include 指令失败: %w", err)
+	}
+	l.missingInclude = false
+	logger.Info("已在 Nginx 主配置中添加 include 指令", "config", configPath, "include", pattern, "backup", backup)
+	return nil
+}
+
+// addNginxInclude 在 http 块的结束括号之前插入 include 指令，返回备份文件路径
+func addNginxInclude(configPath, pattern string) (string, error) {
+	info, err := os.Stat(configPath)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+
+	openLine, closeLine := 0, 0
+	var blocks, args []string
+	for _, token := range tokenizeNginx(content) {
+		switch token.text {
+		case "{":
+			if len(blocks) == 0 && len(args) > 0 && args[0] == "http" {
+				openLine = token.line
+			}
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			blocks = append(blocks, name)
+			args = nil
+		case "}":
+			if len(blocks) == 1 && blocks[0] == "http" {
+				closeLine = token.line
+			}
+			if len(blocks) > 0 {
+				blocks = blocks[:len(blocks)-1]
+			}
+			args = nil
+		case ";":
+			args = nil
+		default:
+			args = append(args, token.text)
+		}
+	}
+	if openLine == 0 || closeLine <= openLine {
+		return "", fmt.Errorf("未找到可插入 include 的 http 块: %s", configPath)
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	directive := fmt.Sprintf("    # AutoCert 添加的站点配置目录\n    include %s;\n", pattern)
+	lines = append(lines[:closeLine-1], append([]string{directive}, lines[closeLine-1:]...)...)
+
+	backup := configPath + ".autocert-bak-" + time.Now().Format("20060102150405")
+	if err := os.WriteFile(backup, data, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("备份 %s 失败: %w", configPath, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filepath.FromSlash(pattern)), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(configPath, []byte(strings.Join(lines, "")), info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
@@ -0,0 +1,168 @@
+package webserver
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SitePreview 站点配置的预期变更（生成但不写入）
+type SitePreview struct {
+	Path    string // 站点配置文件路径
+	Current string // 当前内容，文件不存在时为空
+	Desired string // 将要写入的内容
+	Exists  bool   // 站点配置文件是否已存在
+	Enabled bool   // 站点是否已启用（暂存部署时不启用，视为已启用）
+	Drifted bool   // 站点配置已被手工修改，写入时会拒绝覆盖（未指定 --overwrite-drift 时）
+}
+
+// Changed 写入或启用站点配置是否会产生变更
+func (p *SitePreview) Changed() bool {
+	return !p.Exists || !p.Enabled || p.Current != p.Desired
+}
+
+// Diff 当前内容与将要写入内容的统一格式差异
+func (p *SitePreview) Diff() string {
+	return UnifiedDiff(p.Path, p.Path+"（新）", p.Current, p.Desired)
+}
+
+// PreviewSiteConfig 生成站点配置但不写入，用于 install --check/--diff；
+// 目前只有 Nginx 会生成站点配置，其他类型返回 nil
+func PreviewSiteConfig(config *Config) (*SitePreview, error) {
+	if strings.ToLower(config.Type) != "nginx" {
+		return nil, nil
+	}
+
+	n := &NginxConfigurator{commands: CommandsFor(config.Domain)}
+	if err := n.findConfigPath(); err != nil {
+		return nil, fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
+	}
+
+	desired, err := n.generateConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	path, staged := stagedConfigPath(n.siteConfigPath(config.Domain), config.StagingDeploy)
+	preview := &SitePreview{Path: path, Desired: desired, Enabled: true}
+	if current, err := os.ReadFile(preview.Path); err == nil {
+		preview.Current = string(current)
+		preview.Exists = true
+	}
+	preview.Drifted = managedPreview(preview) && !config.OverwriteDrift
+	// 暂存的站点配置不会启用
+	if linkPath := n.siteLinkPath(preview.Path); linkPath != "" && !staged {
+		target, err := os.Readlink(linkPath)
+		preview.Enabled = err == nil && target == preview.Path
+	}
+	return preview, nil
+}
+
+// diffContext 统一格式差异中变更前后保留的上下文行数
+const diffContext = 3
+
+// UnifiedDiff 按行比较两段文本，返回统一格式（diff -u）的差异，内容相同时返回空
+func UnifiedDiff(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+
+	a, b := splitLines(from), splitLines(to)
+
+	// 最长公共子序列
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 编辑脚本：' ' 相同，'-' 删除，'+' 新增
+	type edit struct {
+		op         byte
+		line       string
+		aIdx, bIdx int
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+
+	for start := 0; start < len(edits); {
+		// 找到下一处变更
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+
+		// 向后合并间隔不超过 2*diffContext 行的变更
+		end := start
+		for k := start; k < len(edits); k++ {
+			if edits[k].op != ' ' {
+				end = k + 1
+			} else if k-end >= 2*diffContext {
+				break
+			}
+		}
+
+		from := max(start-diffContext, 0)
+		to := min(end+diffContext, len(edits))
+
+		aLines, bLines := 0, 0
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				aLines++
+			}
+			if e.op != '-' {
+				bLines++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", hunkStart(edits[from].aIdx, aLines), aLines, hunkStart(edits[from].bIdx, bLines), bLines)
+		for _, e := range edits[from:to] {
+			fmt.Fprintf(&out, "%c%s\n", e.op, e.line)
+		}
+		start = to
+	}
+
+	return out.String()
+}
+
+// hunkStart 差异块的起始行号（从 1 开始，空块按 diff -u 的约定使用前一行）
+func hunkStart(index, lines int) int {
+	if lines == 0 {
+		return index
+	}
+	return index + 1
+}
+
+// splitLines 按行拆分文本（忽略末尾换行）
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
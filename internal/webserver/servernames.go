@@ -0,0 +1,117 @@
+package webserver
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// apacheConfigGlobs Apache 虚拟主机配置的位置（Debian/Ubuntu、RHEL/CentOS、Alpine）
+var apacheConfigGlobs = []string{
+	"/etc/apache2/sites-enabled/*",
+	"/etc/httpd/conf/httpd.conf",
+	"/etc/httpd/conf.d/*.conf",
+	"/etc/apache2/conf.d/*.conf",
+}
+
+// ServedNames 汇总 Nginx 和 Apache 配置中提供服务的主机名，返回主机名到来源（web 服务器:配置文件）的映射；
+// 忽略通配符、正则表达式和 IP 地址。未安装的 Web 服务器跳过
+func ServedNames() map[string][]string {
+	names := make(map[string][]string)
+	add := func(name, source string) {
+		name = strings.TrimSuffix(strings.ToLower(name), ".")
+		if !isHostName(name) {
+			return
+		}
+		for _, existing := range names[name] {
+			if existing == source {
+				return
+			}
+		}
+		names[name] = append(names[name], source)
+	}
+
+	if files, err := NginxConfigFiles(); err == nil {
+		for _, file := range files {
+			servers, err := ParseNginxServers(file)
+			if err != nil {
+				continue
+			}
+			for _, server := range servers {
+				for _, name := range server.ServerNames {
+					add(name, "nginx:"+file)
+				}
+			}
+		}
+	}
+
+	for _, file := range apacheConfigFiles() {
+		for _, name := range parseApacheServerNames(file) {
+			add(name, "apache:"+file)
+		}
+	}
+	return names
+}
+
+// isHostName 是否为具体的主机名（不是 _、通配符、正则表达式或 IP 地址）
+func isHostName(name string) bool {
+	if name == "" || name == "_" || name == "localhost" || !strings.Contains(name, ".") {
+		return false
+	}
+	if strings.ContainsAny(name, "*~^$()") || net.ParseIP(name) != nil {
+		return false
+	}
+	return true
+}
+
+func apacheConfigFiles() []string {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	var files []string
+	for _, pattern := range apacheConfigGlobs {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() {
+				files = append(files, match)
+			}
+		}
+	}
+	return files
+}
+
+// parseApacheServerNames 读取配置文件中的 ServerName 和 ServerAlias（不展开 Include）
+func parseApacheServerNames(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch strings.ToLower(fields[0]) {
+		case "servername":
+			// ServerName 可以带协议和端口，例如 https://www.example.com:443
+			name := fields[1]
+			if i := strings.Index(name, "://"); i >= 0 {
+				name = name[i+3:]
+			}
+			if host, _, err := net.SplitHostPort(name); err == nil {
+				name = host
+			}
+			names = append(names, name)
+		case "serveralias":
+			names = append(names, fields[1:]...)
+		}
+	}
+	return names
+}
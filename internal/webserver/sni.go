@@ -0,0 +1,475 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// nginxDefaultServerTemplate 默认站点：没有匹配任何 server_name 的 HTTPS 请求（例如直接用 IP 访问、
+// 未配置的域名）使用自签名证书并关闭连接，不会把其他站点的证书暴露出去
+const nginxDefaultServerTemplate = `# AutoCert 自动生成的默认站点（SNI 未匹配任何 server_name 时使用）
+server {
+    listen 443 ssl default_server;
+    listen [::]:443 ssl default_server;
+    server_name _;
+
+    ssl_certificate %s;
+    ssl_certificate_key %s;
+
+    return 444;
+}
+`
+
+// nginxDefaultServerName 默认站点配置文件名
+const nginxDefaultServerName = "autocert-default"
+
+// nginxSitesName 合并的多站点配置文件名
+const nginxSitesName = "autocert-sites"
+
+// defaultServerCertPaths 默认站点自签名证书的路径（配置目录下的 default-server）
+func defaultServerCertPaths() (string, string) {
+	dir := filepath.Join(config.GetConfigDir(), "default-server")
+	return filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+}
+
+// ensureDefaultServerCert 生成默认站点的自签名证书，已存在且未过期时保留
+func ensureDefaultServerCert() (string, string, error) {
+	certPath, keyPath := defaultServerCertPaths()
+
+	if pair, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		if leaf, err := x509.ParseCertificate(pair.Certificate[0]); err == nil && time.Now().Add(30*24*time.Hour).Before(leaf.NotAfter) {
+			return certPath, keyPath, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certPath), 0755); err != nil {
+		return "", "", err
+	}
+	if err := writeSelfSignedPair(certPath, keyPath, "invalid", nil, 10*365*24*time.Hour); err != nil {
+		return "", "", fmt.Errorf("生成默认站点证书失败: %w", err)
+	}
+	logger.Info("已生成默认站点自签名证书", "cert", certPath)
+	return certPath, keyPath, nil
+}
+
+// EnsureNginxDefaultServer 写入并启用默认站点（default_server），返回配置文件路径。
+// 其他配置中已有 443 端口的 default_server 时不写入（两个 default_server 会导致 nginx -t 失败），返回已有的位置
+func EnsureNginxDefaultServer() (string, error) {
+	n := &NginxConfigurator{}
+	if err := n.findConfigPath(); err != nil {
+		return "", fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
+	}
+
+	path := n.siteConfigPath(nginxDefaultServerName)
+	if existing := findNginxDefaultServer(path); existing != "" {
+		logger.Info("已有 443 端口的 default_server，不写入默认站点", "location", existing)
+		return existing, nil
+	}
+
+	certPath, keyPath, err := ensureDefaultServerCert()
+	if err != nil {
+		return "", err
+	}
+
+	content := fmt.Sprintf(nginxDefaultServerTemplate, filepath.ToSlash(certPath), filepath.ToSlash(keyPath))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	// 默认站点被手工修改时保留手工修改（只作为警告报告）
+	if err := writeManagedConfig("nginx", nginxDefaultServerName, path, content, false); err != nil && !reportDrift(err) {
+		return "", err
+	}
+	if err := n.enableSite(path); err != nil {
+		return "", fmt.Errorf("启用默认站点失败: %w", err)
+	}
+	return path, nil
+}
+
+// findNginxDefaultServer 查找 ownPath 以外的配置中 443 端口的 default_server，返回其位置（文件:行号），没有时返回空字符串
+func findNginxDefaultServer(ownPath string) string {
+	files, err := NginxConfigFiles()
+	if err != nil {
+		return ""
+	}
+	own := ownPath
+	if resolved, err := filepath.EvalSymlinks(ownPath); err == nil {
+		own = resolved
+	}
+	for _, file := range files {
+		if resolved, err := filepath.EvalSymlinks(file); err == nil && resolved == own {
+			continue
+		}
+		servers, err := ParseNginxServers(file)
+		if err != nil {
+			continue
+		}
+		for _, server := range servers {
+			for _, listen := range server.Listens {
+				if listen.Port == "443" && listen.DefaultServer {
+					return fmt.Sprintf("%s:%d", server.File, server.Line)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ensureNginxDefaultServer 配置了 webserver.default_server 时写入默认站点，失败只记录警告
+func ensureNginxDefaultServer() {
+	if !config.GetWebServerConfig().DefaultServer {
+		return
+	}
+	if _, err := EnsureNginxDefaultServer(); err != nil {
+		logger.Warn("配置 Nginx 默认站点失败", "error", err)
+	}
+}
+
+// warnNginxConflicts 站点配置写入后检查该域名是否在其他配置文件中重复定义
+func warnNginxConflicts(domain string) {
+	files, err := NginxConfigFiles()
+	if err != nil {
+		return
+	}
+	conflicts, err := FindNginxConflicts(files)
+	if err != nil {
+		logger.Debug("解析 Nginx 配置失败，跳过冲突检查", "error", err)
+		return
+	}
+	for _, conflict := range conflicts {
+		if conflict.Kind != ConflictServerName || conflict.Name != strings.ToLower(domain) {
+			continue
+		}
+		var locations []string
+		for _, server := range conflict.Servers {
+			locations = append(locations, fmt.Sprintf("%s:%d", server.File, server.Line))
+		}
+		logger.Warn("server_name 在多个配置中重复定义，Nginx 只会使用其中一个，可执行 autocert sites conflicts --fix 处理",
+			"server_name", conflict.Name, "port", conflict.Port, "locations", locations)
+	}
+}
+
+// NginxSitesPath 合并的多站点配置文件默认路径
+func NginxSitesPath() (string, error) {
+	n := &NginxConfigurator{}
+	if err := n.findConfigPath(); err != nil {
+		return "", fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
+	}
+	return n.layout.sitesPath(nginxSitesName), nil
+}
+
+// nginxSitesMarker 合并配置中每个站点前的注释，部署证书时据此判断域名是否已包含在合并配置中
+func nginxSitesMarker(domain string) string {
+	return fmt.Sprintf("# ---- %s ----", domain)
+}
+
+// nginxSitesRecordPath 配置目录中记录合并配置文件路径的文件（sites generate --output 可以指定其他路径）
+func nginxSitesRecordPath() string {
+	return filepath.Join(config.GetConfigDir(), "nginx-sites.path")
+}
+
+// RecordNginxSites 记录合并配置文件的路径，之后为其中的域名部署证书时不再写入和启用单站点配置
+func RecordNginxSites(path string) error {
+	return os.WriteFile(nginxSitesRecordPath(), []byte(path+"\n"), 0644)
+}
+
+// nginxSitesContaining 返回包含该域名的合并配置文件路径，域名不在合并配置中时返回空字符串
+func (n *NginxConfigurator) nginxSitesContaining(domain string) string {
+	paths := []string{n.layout.sitesPath(nginxSitesName)}
+	if data, err := os.ReadFile(nginxSitesRecordPath()); err == nil {
+		if recorded := strings.TrimSpace(string(data)); recorded != "" {
+			paths = append(paths, recorded)
+		}
+	}
+	marker := nginxSitesMarker(domain)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.TrimSpace(line) == marker {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// WriteNginxSites 写入合并的多站点配置并记录其内容，上次生成后被手工修改时除非指定 overwrite，
+// 否则拒绝覆盖并返回 *DriftError。返回恢复原有配置及其记录的函数，配置测试失败时使用
+func WriteNginxSites(path, content string, overwrite bool) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	restore := snapshotManagedConfig(path)
+	if err := writeManagedConfig("nginx", nginxSitesName, path, content, overwrite); err != nil {
+		return nil, err
+	}
+	return restore, nil
+}
+
+// RenderNginxSites 将多个站点渲染到同一个配置文件中，每个证书一组基于 SNI 的 server 块
+func RenderNginxSites(sites []*Config) (string, error) {
+	var out strings.Builder
+	out.WriteString("# AutoCert 自动生成的多站点配置（共享 IP，按 SNI 选择证书），请勿手动修改\n")
+
+	n := &NginxConfigurator{}
+	for _, site := range sites {
+		rendered, err := n.generateConfig(site)
+		if err != nil {
+			return "", fmt.Errorf("渲染站点 %s 失败: %w", site.Domain, err)
+		}
+		fmt.Fprintf(&out, "\n%s\n", nginxSitesMarker(site.Domain))
+		out.WriteString(rendered)
+	}
+	return out.String(), nil
+}
+
+// DisabledConfig 被停用的 Nginx 配置，可通过 RestoreConfigs 恢复
+type DisabledConfig struct {
+	Path       string // sites-enabled 中的链接或 conf.d 中的配置文件
+	LinkTarget string // 符号链接指向的文件，为空表示普通文件被重命名
+}
+
+// DisableManagedSites 停用 AutoCert 为这些域名生成的单站点配置（删除 sites-enabled 中的链接，
+// 或为 conf.d 中的文件追加后缀），避免与合并配置中的 server_name 重复
+func DisableManagedSites(domains []string) ([]DisabledConfig, error) {
+	n := &NginxConfigurator{}
+	if err := n.findConfigPath(); err != nil {
+		return nil, fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
+	}
+
+	var disabled []DisabledConfig
+	for _, domain := range domains {
+		sitePath := n.siteConfigPath(domain)
+		if !isManagedFile(sitePath) {
+			continue
+		}
+
+		path := sitePath
+		if linkPath := n.siteLinkPath(sitePath); linkPath != "" {
+			if target, err := os.Readlink(linkPath); err != nil || target != sitePath {
+				continue
+			}
+			path = linkPath
+		}
+
+		item, err := disableConfigFile(path)
+		if err != nil {
+			RestoreConfigs(disabled)
+			return nil, err
+		}
+		disabled = append(disabled, item)
+		logger.Info("停用单站点配置", "path", path)
+	}
+	return disabled, nil
+}
+
+// EnableManagedSites 重新启用 DisableManagedSites 停用的单站点配置（域名移出多域名证书后），
+// 返回重新启用的配置；没有停用的配置或已经启用时跳过
+func EnableManagedSites(domains []string) ([]string, error) {
+	n := &NginxConfigurator{}
+	if err := n.findConfigPath(); err != nil {
+		return nil, fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
+	}
+
+	var enabled []string
+	for _, domain := range domains {
+		sitePath := n.siteConfigPath(domain)
+		if linkPath := n.siteLinkPath(sitePath); linkPath != "" {
+			if !isManagedFile(sitePath) {
+				continue
+			}
+			if _, err := os.Lstat(linkPath); err == nil {
+				continue
+			}
+			if err := os.Symlink(sitePath, linkPath); err != nil {
+				return enabled, err
+			}
+			enabled = append(enabled, linkPath)
+		} else {
+			if !isManagedFile(sitePath + disabledSuffix) {
+				continue
+			}
+			if _, err := os.Lstat(sitePath); err == nil {
+				continue
+			}
+			if err := os.Rename(sitePath+disabledSuffix, sitePath); err != nil {
+				return enabled, err
+			}
+			enabled = append(enabled, sitePath)
+		}
+		logger.Info("重新启用单站点配置", "domain", domain, "path", enabled[len(enabled)-1])
+	}
+	return enabled, nil
+}
+
+// RestoreConfigs 恢复停用的配置
+func RestoreConfigs(disabled []DisabledConfig) {
+	for _, item := range disabled {
+		var err error
+		if item.LinkTarget != "" {
+			err = os.Symlink(item.LinkTarget, item.Path)
+		} else {
+			err = os.Rename(item.Path+disabledSuffix, item.Path)
+		}
+		if err != nil {
+			logger.Warn("恢复 Nginx 配置失败", "path", item.Path, "error", err)
+		}
+	}
+}
+
+// isManagedFile 判断配置文件是否由 AutoCert 生成
+func isManagedFile(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.HasPrefix(strings.TrimSpace(string(data)), managedMarker)
+}
+
+// disabledSuffix 停用冲突配置时追加的后缀（nginx 只加载 conf.d 中的 *.conf）
+const disabledSuffix = ".autocert-disabled"
+
+// disableConfigFile 停用配置文件：符号链接直接删除，普通文件追加后缀
+func disableConfigFile(path string) (DisabledConfig, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return DisabledConfig{}, err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return DisabledConfig{}, err
+		}
+		return DisabledConfig{Path: path, LinkTarget: target}, os.Remove(path)
+	}
+	return DisabledConfig{Path: path}, os.Rename(path, path+disabledSuffix)
+}
+
+// ResolveNginxConflicts 处理与 AutoCert 配置重复的 server_name：另一方所在文件中的
+// 所有 server_name 都已由 AutoCert 配置提供时停用该文件，返回已停用的配置和仍需手动处理的冲突
+func ResolveNginxConflicts(conflicts []NginxConflict) ([]DisabledConfig, []NginxConflict, error) {
+	managedNames := make(map[string]bool)
+	for _, conflict := range conflicts {
+		for _, server := range conflict.Servers {
+			if server.Managed {
+				for _, name := range server.ServerNames {
+					managedNames[conflict.Port+" "+strings.ToLower(name)] = true
+				}
+			}
+		}
+	}
+
+	// 主配置文件不会被停用
+	n := &NginxConfigurator{}
+	if err := n.findConfigPath(); err != nil {
+		return nil, nil, fmt.Errorf("查找 Nginx 配置路径失败: %w", err)
+	}
+
+	candidates := make(map[string]bool)
+	var manual []NginxConflict
+	for _, conflict := range conflicts {
+		var files []string
+		resolvable := conflict.Kind == ConflictServerName && hasManaged(conflict.Servers)
+		for _, server := range conflict.Servers {
+			if !resolvable {
+				break
+			}
+			if server.Managed {
+				continue
+			}
+			if server.File == n.configPath || !coveredByManaged(server.File, managedNames) {
+				resolvable = false
+			}
+			files = append(files, server.File)
+		}
+		if !resolvable || len(files) == 0 {
+			manual = append(manual, conflict)
+			continue
+		}
+		for _, file := range files {
+			candidates[file] = true
+		}
+	}
+
+	var files []string
+	for file := range candidates {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var disabled []DisabledConfig
+	for _, file := range files {
+		item, err := disableConfigFile(file)
+		if err != nil {
+			return disabled, manual, fmt.Errorf("停用 %s 失败: %w", file, err)
+		}
+		disabled = append(disabled, item)
+		logger.Info("已停用与 AutoCert 配置冲突的站点配置", "file", file)
+	}
+
+	// 已停用的文件不再参与剩余的冲突
+	var remaining []NginxConflict
+	for _, conflict := range manual {
+		var servers []NginxServer
+		for _, server := range conflict.Servers {
+			if !candidates[server.File] {
+				servers = append(servers, server)
+			}
+		}
+		if len(servers) > 1 {
+			conflict.Servers = servers
+			remaining = append(remaining, conflict)
+		}
+	}
+	return disabled, remaining, nil
+}
+
+// hasManaged 冲突中是否包含 AutoCert 生成的 server 块
+func hasManaged(servers []NginxServer) bool {
+	for _, server := range servers {
+		if server.Managed {
+			return true
+		}
+	}
+	return false
+}
+
+// coveredByManaged 配置文件中的所有 server_name 是否都已由 AutoCert 配置提供
+func coveredByManaged(file string, managedNames map[string]bool) bool {
+	servers, err := ParseNginxServers(file)
+	if err != nil {
+		return false
+	}
+	for _, s := range servers {
+		for _, listen := range s.Listens {
+			for _, name := range s.ServerNames {
+				if name == "_" || strings.HasPrefix(name, "~") {
+					continue
+				}
+				if !managedNames[listen.Port+" "+strings.ToLower(name)] {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// TestNginx 测试 Nginx 配置（使用全局配置的自定义测试命令）
+func TestNginx() error {
+	n := &NginxConfigurator{commands: CommandsFor("")}
+	return n.Test()
+}
+
+// ReloadNginx 重载 Nginx（使用全局配置的自定义重载命令）
+func ReloadNginx() error {
+	n := &NginxConfigurator{commands: CommandsFor("")}
+	return n.Reload()
+}
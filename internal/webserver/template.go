@@ -0,0 +1,473 @@
+package webserver
+
+import (
+	"autocert/internal/config"
+	"autocert/internal/logger"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// nginxTemplate 内置 Nginx 站点配置模板
+const nginxTemplate = `# AutoCert 自动生成的配置
+server {
+    listen 80;
+    server_name {{.Domain}}{{range .Aliases}} {{.}}{{end}};
+{{- if .NoRedirect}}
+    
+    # 继续通过 HTTP 提供服务（--no-redirect）
+    root {{.WebRoot}};
+    index index.html index.htm index.php;
+{{- template "server" .}}
+    
+    # 通用配置
+{{- template "default_location" .}}
+{{- template "locations" .}}
+    
+    # ACME 挑战目录
+    location ^~ /.well-known/acme-challenge/ {
+        default_type "text/plain";
+        root {{.WebRoot}};
+    }
+{{- else}}
+    
+    # 重定向 HTTP 到 HTTPS
+    return 301 https://$server_name$request_uri;
+{{- end}}
+}
+
+server {
+    listen 443 ssl http2;
+    server_name {{.Domain}}{{range .Aliases}} {{.}}{{end}};
+    
+    # SSL 证书配置
+    ssl_certificate {{.CertPath}};
+    ssl_certificate_key {{.KeyPath}};
+{{- if .ECDSACertPath}}
+    ssl_certificate {{.ECDSACertPath}};
+    ssl_certificate_key {{.ECDSAKeyPath}};
+{{- end}}
+    
+    # SSL 安全配置
+    ssl_protocols TLSv1.2 TLSv1.3;
+    ssl_prefer_server_ciphers on;
+    ssl_ciphers {{if .ECDSACertPath}}ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-ECDSA-CHACHA20-POLY1305:{{end}}ECDHE-RSA-AES256-GCM-SHA384:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-RSA-AES256-SHA384:ECDHE-RSA-AES128-SHA256;
+    ssl_session_cache shared:SSL:10m;
+    ssl_session_timeout 10m;
+    
+    # 网站根目录
+    root {{.WebRoot}};
+    index index.html index.htm index.php;
+{{- block "server" .}}{{end}}
+    
+    # 通用配置
+{{- block "default_location" .}}
+    location / {
+        try_files $uri $uri/ =404;
+    }
+{{- end}}
+{{- block "locations" .}}{{end}}
+    
+    # ACME 挑战目录
+    location ^~ /.well-known/acme-challenge/ {
+        default_type "text/plain";
+        root {{.WebRoot}};
+    }
+}
+`
+
+// apacheTemplate 内置 Apache 站点配置模板
+const apacheTemplate = `# AutoCert 自动生成的配置
+<VirtualHost *:80>
+    ServerName {{.Domain}}
+{{- range .Aliases}}
+    ServerAlias {{.}}
+{{- end}}
+    DocumentRoot {{.WebRoot}}
+{{- if .NoRedirect}}
+
+    # 继续通过 HTTP 提供服务（--no-redirect）
+{{- template "server" .}}
+
+    <Directory {{.WebRoot}}>
+        Require all granted
+    </Directory>
+{{- template "locations" .}}
+{{- else}}
+
+    # 重定向 HTTP 到 HTTPS（ACME 挑战目录除外）
+    RedirectMatch permanent ^/(?!\.well-known/acme-challenge/)(.*)$ https://{{.Domain}}/$1
+{{- end}}
+</VirtualHost>
+
+<VirtualHost *:443>
+    ServerName {{.Domain}}
+{{- range .Aliases}}
+    ServerAlias {{.}}
+{{- end}}
+    DocumentRoot {{.WebRoot}}
+
+    # SSL 证书配置
+    SSLEngine on
+    SSLCertificateFile {{.CertPath}}
+    SSLCertificateKeyFile {{.KeyPath}}
+{{- if .ChainPath}}
+    SSLCertificateChainFile {{.ChainPath}}
+{{- end}}
+{{- if .ECDSACertPath}}
+    SSLCertificateFile {{.ECDSACertPath}}
+    SSLCertificateKeyFile {{.ECDSAKeyPath}}
+{{- end}}
+
+    # SSL 安全配置
+    SSLProtocol all -SSLv3 -TLSv1 -TLSv1.1
+    SSLHonorCipherOrder on
+    SSLCipherSuite {{if .ECDSACertPath}}ECDHE-ECDSA-AES256-GCM-SHA384:ECDHE-ECDSA-AES128-GCM-SHA256:ECDHE-ECDSA-CHACHA20-POLY1305:{{end}}ECDHE-RSA-AES256-GCM-SHA384:ECDHE-RSA-AES128-GCM-SHA256:ECDHE-RSA-AES256-SHA384:ECDHE-RSA-AES128-SHA256
+{{- block "server" .}}{{end}}
+
+    <Directory {{.WebRoot}}>
+        Require all granted
+    </Directory>
+{{- block "locations" .}}{{end}}
+</VirtualHost>
+`
+
+// requiredFields 模板必须引用的字段
+var requiredFields = []string{"Domain", "CertPath", "KeyPath"}
+
+// BuiltinTemplate 返回内置模板，没有内置模板时返回空
+func BuiltinTemplate(serverType string) string {
+	switch serverType {
+	case "nginx":
+		return nginxTemplate
+	case "apache":
+		return apacheTemplate
+	}
+	return ""
+}
+
+// CustomTemplatePath 返回用户配置的模板路径，未配置时返回空
+func CustomTemplatePath() string {
+	return config.GetWebServerConfig().Template
+}
+
+// CustomizedFor 域名的站点配置是否使用了用户模板或模板片段，此时写入前需要检查渲染结果
+func CustomizedFor(domain string) bool {
+	return CustomTemplatePath() != "" || len(config.GetTemplateBlocks(domain)) > 0
+}
+
+// loadTemplate 加载站点配置模板：配置了 webserver.template 时使用用户模板，否则使用内置模板；
+// 再用域名配置 template_blocks 中的片段覆盖模板中同名的 block
+func loadTemplate(serverType, builtin, domain string) (*template.Template, error) {
+	var t *template.Template
+	var err error
+	if path := CustomTemplatePath(); path != "" {
+		t, err = parseTemplateFile(path)
+	} else {
+		t, err = template.New(serverType).Parse(builtin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyTemplateBlocks(t, domain, config.GetTemplateBlocks(domain)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// warnIgnoredNoRedirect 用户模板没有引用 {{.NoRedirect}} 时，--no-redirect 不会生效
+func warnIgnoredNoRedirect(t *template.Template, config *Config) {
+	if config.NoRedirect && CustomTemplatePath() != "" && !templateFields(t)["NoRedirect"] {
+		logger.Warn("自定义模板没有引用 {{.NoRedirect}}，--no-redirect 不会生效", "template", CustomTemplatePath())
+	}
+}
+
+// applyTemplateBlocks 用片段覆盖模板中同名的 block（{{block "名称" .}}...{{end}}）。
+// 片段每行缩进 4 个空格，插入到 server / VirtualHost 块中
+func applyTemplateBlocks(t *template.Template, domain string, blocks map[string]string) error {
+	names := make([]string, 0, len(blocks))
+	for name := range blocks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if t.Lookup(name) == nil {
+			return fmt.Errorf("域名 %s 的 template_blocks.%s: 模板中没有该 block（可用: %s）",
+				domain, name, strings.Join(TemplateBlocks(t), ", "))
+		}
+		if _, err := t.New(name).Parse(indentFragment(blocks[name])); err != nil {
+			return fmt.Errorf("域名 %s 的 template_blocks.%s 语法错误: %w", domain, name, err)
+		}
+	}
+	return nil
+}
+
+// TemplateBlocks 模板中可以被片段覆盖的 block 名称
+func TemplateBlocks(t *template.Template) []string {
+	var names []string
+	for _, tmpl := range t.Templates() {
+		if tmpl.Name() != t.Name() {
+			names = append(names, tmpl.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// indentFragment 片段每个非空行缩进 4 个空格，并以换行开头，与模板中 {{- block}} 的位置衔接
+func indentFragment(fragment string) string {
+	lines := strings.Split(strings.TrimRight(fragment, "\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = "    " + line
+		} else {
+			lines[i] = ""
+		}
+	}
+	return "\n" + strings.Join(lines, "\n")
+}
+
+// parseTemplateFile 解析用户模板文件，引用不存在的字段时渲染报错
+func parseTemplateFile(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取模板失败: %w", err)
+	}
+
+	t, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("模板语法错误: %w", err)
+	}
+	return t, nil
+}
+
+// LintTemplate 检查用户模板（path 为空时为内置模板）：语法和占位符、使用示例数据渲染，
+// 并用 Web 服务器自身的语法检查验证渲染结果。domain 不为空时同时检查该域名配置的模板片段
+func LintTemplate(serverType, path, domain string) error {
+	var t *template.Template
+	var err error
+	if path != "" {
+		t, err = parseTemplateFile(path)
+	} else if builtin := BuiltinTemplate(serverType); builtin != "" {
+		t, err = template.New(serverType).Parse(builtin)
+	} else {
+		return fmt.Errorf("%s 没有内置模板", serverType)
+	}
+	if err != nil {
+		return err
+	}
+	if domain != "" {
+		if err := applyTemplateBlocks(t, domain, config.GetTemplateBlocks(domain)); err != nil {
+			return err
+		}
+	}
+
+	fields := templateFields(t)
+	for _, field := range requiredFields {
+		if !fields[field] {
+			return fmt.Errorf("模板未引用必需的占位符 {{.%s}}", field)
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "autocert-lint-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sample, err := sampleConfig(tmpDir)
+	if err != nil {
+		return err
+	}
+
+	var rendered strings.Builder
+	if err := t.Execute(&rendered, sample); err != nil {
+		return fmt.Errorf("模板渲染失败: %w", err)
+	}
+
+	return TestRenderedConfig(serverType, rendered.String())
+}
+
+// TestRenderedConfig 将渲染后的站点配置写入临时文件，在启用前执行 nginx -t / apachectl -t
+func TestRenderedConfig(serverType, content string) error {
+	tmpDir, err := os.MkdirTemp("", "autocert-test-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sitePath := filepath.Join(tmpDir, "site.conf")
+	if err := os.WriteFile(sitePath, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	switch serverType {
+	case "nginx":
+		// 使用最小主配置包含站点配置，避免影响正在运行的 Nginx
+		mainPath := filepath.Join(tmpDir, "nginx.conf")
+		mainConfig := fmt.Sprintf("error_log stderr;\npid %s;\nevents {}\nhttp {\n    include %s;\n}\n",
+			filepath.ToSlash(filepath.Join(tmpDir, "nginx.pid")), filepath.ToSlash(sitePath))
+		if err := os.WriteFile(mainPath, []byte(mainConfig), 0644); err != nil {
+			return err
+		}
+		cmd = exec.Command("nginx", "-t", "-p", tmpDir, "-c", mainPath)
+	case "apache":
+		// 先加载系统主配置（模块、ServerRoot），再检查站点配置
+		layout, err := detectApacheLayout()
+		if err != nil {
+			return err
+		}
+		cmd = exec.Command(layout.ctl(), "-t", "-C", "Include "+layout.configPath, "-f", sitePath)
+	default:
+		return fmt.Errorf("不支持检查 %s 配置", serverType)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		detail := strings.TrimSpace(string(output))
+		if detail == "" {
+			detail = err.Error()
+		}
+		return fmt.Errorf("%s 配置检查失败:\n%s%s", serverType, detail, renderedContext(content, sitePath, string(output)))
+	}
+	return nil
+}
+
+// renderedContext 根据检查输出中的行号，附上渲染结果对应行的内容
+func renderedContext(content, sitePath, output string) string {
+	re := regexp.MustCompile(regexp.QuoteMeta(filepath.ToSlash(sitePath)) + `:(\d+)|line (\d+) of ` + regexp.QuoteMeta(sitePath))
+	match := re.FindStringSubmatch(output)
+	if match == nil {
+		return ""
+	}
+
+	lineText := match[1]
+	if lineText == "" {
+		lineText = match[2]
+	}
+	line, _ := strconv.Atoi(lineText)
+
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return fmt.Sprintf("\n渲染结果第 %d 行: %s", line, strings.TrimSpace(lines[line-1]))
+}
+
+// templateFields 收集模板中引用的顶层字段
+func templateFields(t *template.Template) map[string]bool {
+	fields := make(map[string]bool)
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			fields[n.Ident[0]] = true
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			walk(tmpl.Tree.Root)
+		}
+	}
+	return fields
+}
+
+// sampleConfig 生成用于检查模板的示例配置，证书为临时自签名证书
+func sampleConfig(dir string) (*Config, error) {
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := writeSelfSignedPair(certPath, keyPath, "example.com", []string{"example.com", "www.example.com"}, 24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		Domain:   "example.com",
+		Aliases:  []string{"www.example.com"},
+		CertPath: filepath.ToSlash(certPath),
+		KeyPath:  filepath.ToSlash(keyPath),
+		WebRoot:  defaultWebRoot,
+	}, nil
+}
+
+// writeSelfSignedPair 生成 ECDSA P-256 自签名证书和私钥并写入文件
+func writeSelfSignedPair(certPath, keyPath, commonName string, dnsNames []string, validity time.Duration) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return err
+	}
+	certTemplate := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(validity),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, certTemplate, certTemplate, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
@@ -0,0 +1,489 @@
+// Package winrm 是最小的 WinRM（WS-Management）客户端，用于在远程 Windows 主机上执行 PowerShell。
+//
+// 只支持 Basic 认证（本地账户），必须使用 HTTPS 监听器；HTTP 不加密消息，用户名和密码以明文传输，
+// 只有显式设置 AllowHTTP 时才允许。
+// 远程主机需要启用 Basic 认证：winrm set winrm/config/service/auth @{Basic="true"}
+package winrm
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	resourceURI     = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/cmd"
+	actionCreate    = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Create"
+	actionDelete    = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Delete"
+	actionCommand   = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Command"
+	actionSend      = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Send"
+	actionReceive   = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Receive"
+	actionSignal    = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/Signal"
+	signalTerminate = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/signal/terminate"
+	commandDone     = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Done"
+
+	// timeoutFaultCode Receive 在 OperationTimeout 内没有输出时返回的错误码，需要继续接收
+	timeoutFaultCode = "2150858793"
+
+	// maxCommandLength 命令行长度上限（CreateProcess 限制为 32767 个字符）
+	maxCommandLength = 32000
+
+	// maxSendChunk 每个 Send 请求携带的标准输入字节数（Base64 后需小于 MaxEnvelopeSize）
+	maxSendChunk = 64 * 1024
+)
+
+// Config 远程主机连接参数
+type Config struct {
+	Host      string
+	Port      int // 默认 HTTPS 5986，HTTP 5985
+	HTTPS     bool
+	AllowHTTP bool   // 允许不加密的 HTTP（凭据明文传输）
+	Insecure  bool   // 不校验服务器证书
+	CACert    string // 校验服务器证书的 CA 证书（PEM 文件）
+	Username  string
+	Password  string
+	Timeout   time.Duration // 单个命令的最长执行时间
+}
+
+// Client WinRM 客户端
+type Client struct {
+	endpoint string
+	config   Config
+	http     *http.Client
+}
+
+// New 创建客户端
+func New(cfg Config) (*Client, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("未配置 WinRM 主机")
+	}
+	if cfg.Username == "" {
+		return nil, errors.New("未配置 WinRM 用户名")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+
+	if !cfg.HTTPS && !cfg.AllowHTTP {
+		return nil, errors.New("WinRM 使用 HTTP 时凭据以明文传输，请使用 HTTPS，或在受信任的网络中显式设置 allow_http")
+	}
+
+	scheme, port := "http", 5985
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.Insecure}
+	if cfg.HTTPS {
+		scheme, port = "https", 5986
+		if cfg.CACert != "" {
+			data, err := os.ReadFile(cfg.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("读取 CA 证书失败: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("%s 中没有有效的 CA 证书", cfg.CACert)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if cfg.Port != 0 {
+		port = cfg.Port
+	}
+
+	return &Client{
+		endpoint: fmt.Sprintf("%s://%s:%d/wsman", scheme, cfg.Host, port),
+		config:   cfg,
+		http: &http.Client{
+			Timeout:   90 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// Host 远程主机名
+func (c *Client) Host() string {
+	return c.config.Host
+}
+
+// Result 命令的输出和退出码
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// envPreamble 从标准输入读取环境变量（Base64 编码的 JSON 对象）并以 $env: 赋值
+const envPreamble = `$autocertEnv = [Text.Encoding]::UTF8.GetString([Convert]::FromBase64String([Console]::In.ReadToEnd())) | ConvertFrom-Json
+foreach ($p in $autocertEnv.PSObject.Properties) { Set-Item -LiteralPath "env:$($p.Name)" -Value $p.Value }
+Remove-Variable autocertEnv
+`
+
+// RunPowerShell 在远程主机上执行 PowerShell 脚本，脚本以 -EncodedCommand 传递，不需要处理引号。
+// env 中的变量（证书、密码等）通过标准输入传递，不会出现在远程主机的进程参数和命令行日志中，也不受命令行长度限制
+func (c *Client) RunPowerShell(script string, env map[string]string) (*Result, error) {
+	var stdin []byte
+	if len(env) > 0 {
+		data, err := json.Marshal(env)
+		if err != nil {
+			return nil, err
+		}
+		stdin = []byte(base64.StdEncoding.EncodeToString(data))
+		script = envPreamble + script
+	}
+	encoded := encodePowerShell(script)
+	if len(encoded) > maxCommandLength {
+		return nil, fmt.Errorf("PowerShell 脚本过长（%d 个字符）", len(encoded))
+	}
+	return c.RunWithInput(stdin, "powershell", "-NoProfile", "-NonInteractive", "-EncodedCommand", encoded)
+}
+
+// Run 在远程主机上执行命令并等待其结束
+func (c *Client) Run(command string, args ...string) (*Result, error) {
+	return c.RunWithInput(nil, command, args...)
+}
+
+// RunWithInput 在远程主机上执行命令，将 stdin 写入命令的标准输入后关闭，等待命令结束
+func (c *Client) RunWithInput(stdin []byte, command string, args ...string) (*Result, error) {
+	shellID, err := c.createShell()
+	if err != nil {
+		return nil, err
+	}
+	defer c.deleteShell(shellID)
+
+	commandID, err := c.startCommand(shellID, command, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.sendInput(shellID, commandID, stdin); err != nil {
+		c.signal(shellID, commandID)
+		return nil, err
+	}
+
+	result := &Result{}
+	var stdout, stderr bytes.Buffer
+	deadline := time.Now().Add(c.config.Timeout)
+	for {
+		done, err := c.receive(shellID, commandID, &stdout, &stderr, &result.ExitCode)
+		if err != nil {
+			c.signal(shellID, commandID)
+			return nil, err
+		}
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			c.signal(shellID, commandID)
+			return nil, fmt.Errorf("命令执行超时（%s）", c.config.Timeout)
+		}
+	}
+	c.signal(shellID, commandID)
+
+	result.Stdout = stdout.String()
+	result.Stderr = cleanCLIXML(stderr.String())
+	return result, nil
+}
+
+// encodePowerShell -EncodedCommand 的参数：UTF-16LE 的 Base64
+func encodePowerShell(script string) string {
+	units := utf16.Encode([]rune(script))
+	data := make([]byte, len(units)*2)
+	for i, u := range units {
+		data[i*2] = byte(u)
+		data[i*2+1] = byte(u >> 8)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// cleanCLIXML PowerShell 通过 WinRM 输出的错误是 CLIXML 格式，提取其中的文本
+func cleanCLIXML(stderr string) string {
+	if !strings.HasPrefix(stderr, "#< CLIXML") {
+		return strings.TrimSpace(stderr)
+	}
+	var text strings.Builder
+	decoder := xml.NewDecoder(strings.NewReader(strings.TrimPrefix(stderr, "#< CLIXML")))
+	inError := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			inError = t.Name.Local == "S" && attr(t, "S") == "Error"
+		case xml.EndElement:
+			inError = false
+		case xml.CharData:
+			if inError {
+				text.WriteString(strings.NewReplacer("_x000D_", "", "_x000A_", "\n").Replace(string(t)))
+			}
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// attr 元素的属性值，不区分命名空间
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// createShell 创建远程 cmd shell，返回 ShellId
+func (c *Client) createShell() (string, error) {
+	options := map[string]string{"WINRS_NOPROFILE": "TRUE", "WINRS_CODEPAGE": "65001"}
+	body := `<rsp:Shell><rsp:InputStreams>stdin</rsp:InputStreams><rsp:OutputStreams>stdout stderr</rsp:OutputStreams></rsp:Shell>`
+	response, err := c.send(actionCreate, "", options, body)
+	if err != nil {
+		return "", fmt.Errorf("创建远程 shell 失败: %w", err)
+	}
+	var envelope struct {
+		ShellID string `xml:"Body>Shell>ShellId"`
+		Created struct {
+			Selectors []selector `xml:"ReferenceParameters>SelectorSet>Selector"`
+		} `xml:"Body>ResourceCreated"`
+	}
+	if err := xml.Unmarshal(response, &envelope); err != nil {
+		return "", fmt.Errorf("解析 WinRM 响应失败: %w", err)
+	}
+	if envelope.ShellID != "" {
+		return envelope.ShellID, nil
+	}
+	for _, s := range envelope.Created.Selectors {
+		if s.Name == "ShellId" {
+			return s.Value, nil
+		}
+	}
+	return "", errors.New("WinRM 响应中没有 ShellId")
+}
+
+type selector struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// startCommand 在 shell 中启动命令，返回 CommandId
+func (c *Client) startCommand(shellID, command string, args []string) (string, error) {
+	var body strings.Builder
+	body.WriteString("<rsp:CommandLine><rsp:Command>")
+	xml.EscapeText(&body, []byte(command))
+	body.WriteString("</rsp:Command>")
+	for _, arg := range args {
+		body.WriteString("<rsp:Arguments>")
+		xml.EscapeText(&body, []byte(arg))
+		body.WriteString("</rsp:Arguments>")
+	}
+	body.WriteString("</rsp:CommandLine>")
+
+	options := map[string]string{"WINRS_CONSOLEMODE_STDIN": "TRUE", "WINRS_SKIP_CMD_SHELL": "FALSE"}
+	response, err := c.send(actionCommand, shellID, options, body.String())
+	if err != nil {
+		return "", fmt.Errorf("执行远程命令失败: %w", err)
+	}
+	var envelope struct {
+		CommandID string `xml:"Body>CommandResponse>CommandId"`
+	}
+	if err := xml.Unmarshal(response, &envelope); err != nil || envelope.CommandID == "" {
+		return "", errors.New("WinRM 响应中没有 CommandId")
+	}
+	return envelope.CommandID, nil
+}
+
+// sendInput 分块写入命令的标准输入，最后一块标记 End 关闭标准输入
+func (c *Client) sendInput(shellID, commandID string, stdin []byte) error {
+	for {
+		chunk := stdin
+		if len(chunk) > maxSendChunk {
+			chunk = chunk[:maxSendChunk]
+		}
+		stdin = stdin[len(chunk):]
+		end := ""
+		if len(stdin) == 0 {
+			end = ` End="true"`
+		}
+		body := fmt.Sprintf(`<rsp:Send><rsp:Stream Name="stdin" CommandId="%s"%s>%s</rsp:Stream></rsp:Send>`,
+			commandID, end, base64.StdEncoding.EncodeToString(chunk))
+		if _, err := c.send(actionSend, shellID, nil, body); err != nil {
+			return fmt.Errorf("写入远程命令输入失败: %w", err)
+		}
+		if len(stdin) == 0 {
+			return nil
+		}
+	}
+}
+
+// receive 接收一次输出，命令结束时返回 true 并设置退出码
+func (c *Client) receive(shellID, commandID string, stdout, stderr io.Writer, exitCode *int) (bool, error) {
+	body := fmt.Sprintf(`<rsp:Receive><rsp:DesiredStream CommandId="%s">stdout stderr</rsp:DesiredStream></rsp:Receive>`, commandID)
+	response, err := c.send(actionReceive, shellID, nil, body)
+	if err != nil {
+		var fault *Fault
+		if errors.As(err, &fault) && fault.Code == timeoutFaultCode {
+			return false, nil
+		}
+		return false, fmt.Errorf("接收远程命令输出失败: %w", err)
+	}
+
+	var envelope struct {
+		Streams []struct {
+			Name  string `xml:"Name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Body>ReceiveResponse>Stream"`
+		State struct {
+			State    string `xml:"State,attr"`
+			ExitCode int    `xml:"ExitCode"`
+		} `xml:"Body>ReceiveResponse>CommandState"`
+	}
+	if err := xml.Unmarshal(response, &envelope); err != nil {
+		return false, fmt.Errorf("解析 WinRM 响应失败: %w", err)
+	}
+	for _, stream := range envelope.Streams {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stream.Value))
+		if err != nil {
+			continue
+		}
+		if stream.Name == "stderr" {
+			stderr.Write(data)
+		} else {
+			stdout.Write(data)
+		}
+	}
+	if envelope.State.State == commandDone {
+		*exitCode = envelope.State.ExitCode
+		return true, nil
+	}
+	return false, nil
+}
+
+// signal 结束命令（已结束的命令也需要发送，释放远程资源），失败时忽略
+func (c *Client) signal(shellID, commandID string) {
+	body := fmt.Sprintf(`<rsp:Signal CommandId="%s"><rsp:Code>%s</rsp:Code></rsp:Signal>`, commandID, signalTerminate)
+	c.send(actionSignal, shellID, nil, body)
+}
+
+// deleteShell 删除远程 shell，失败时忽略
+func (c *Client) deleteShell(shellID string) {
+	c.send(actionDelete, shellID, nil, "")
+}
+
+// Fault WinRM 返回的 SOAP 错误
+type Fault struct {
+	Code    string
+	Message string
+}
+
+func (f *Fault) Error() string {
+	if f.Code != "" {
+		return fmt.Sprintf("%s (WinRM 错误码 %s)", f.Message, f.Code)
+	}
+	return f.Message
+}
+
+// send 发送 SOAP 请求并返回响应内容
+func (c *Client) send(action, shellID string, options map[string]string, body string) ([]byte, error) {
+	envelope := c.envelope(action, shellID, options, body)
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, strings.NewReader(envelope))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	req.SetBasicAuth(c.config.Username, c.config.Password)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, errors.New("WinRM 认证失败（需要启用 Basic 认证并使用本地账户）")
+	case resp.StatusCode != http.StatusOK:
+		return nil, parseFault(resp.Status, data)
+	}
+	return data, nil
+}
+
+// parseFault 解析 SOAP Fault，无法解析时返回 HTTP 状态
+func parseFault(status string, data []byte) error {
+	var envelope struct {
+		Reason string `xml:"Body>Fault>Reason>Text"`
+		Detail struct {
+			Code    string `xml:"Code,attr"`
+			Message string `xml:"Message"`
+		} `xml:"Body>Fault>Detail>WSManFault"`
+	}
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("WinRM 请求失败: %s", status)
+	}
+	message := strings.TrimSpace(envelope.Detail.Message)
+	if message == "" {
+		message = strings.TrimSpace(envelope.Reason)
+	}
+	if message == "" {
+		message = "WinRM 请求失败: " + status
+	}
+	return &Fault{Code: envelope.Detail.Code, Message: message}
+}
+
+// envelope 生成 WS-Management 请求
+func (c *Client) envelope(action, shellID string, options map[string]string, body string) string {
+	var b strings.Builder
+	b.WriteString(`<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"` +
+		` xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing"` +
+		` xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd"` +
+		` xmlns:p="http://schemas.microsoft.com/wbem/wsman/1/wsman.xsd"` +
+		` xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell"><env:Header>`)
+	b.WriteString(`<a:To>`)
+	xml.EscapeText(&b, []byte(c.endpoint))
+	b.WriteString(`</a:To>`)
+	b.WriteString(`<a:ReplyTo><a:Address env:mustUnderstand="true">http://schemas.xmlsoap.org/ws/2004/08/addressing/role/anonymous</a:Address></a:ReplyTo>`)
+	b.WriteString(`<w:MaxEnvelopeSize env:mustUnderstand="true">153600</w:MaxEnvelopeSize>`)
+	b.WriteString(`<a:MessageID>uuid:` + newUUID() + `</a:MessageID>`)
+	b.WriteString(`<w:Locale env:mustUnderstand="false" xml:lang="en-US"/>`)
+	b.WriteString(`<p:DataLocale env:mustUnderstand="false" xml:lang="en-US"/>`)
+	b.WriteString(`<w:OperationTimeout>PT60S</w:OperationTimeout>`)
+	b.WriteString(`<w:ResourceURI env:mustUnderstand="true">` + resourceURI + `</w:ResourceURI>`)
+	b.WriteString(`<a:Action env:mustUnderstand="true">` + action + `</a:Action>`)
+	if shellID != "" {
+		b.WriteString(`<w:SelectorSet><w:Selector Name="ShellId">`)
+		xml.EscapeText(&b, []byte(shellID))
+		b.WriteString(`</w:Selector></w:SelectorSet>`)
+	}
+	if len(options) > 0 {
+		b.WriteString(`<w:OptionSet>`)
+		for name, value := range options {
+			fmt.Fprintf(&b, `<w:Option Name="%s">%s</w:Option>`, name, value)
+		}
+		b.WriteString(`</w:OptionSet>`)
+	}
+	b.WriteString(`</env:Header><env:Body>`)
+	b.WriteString(body)
+	b.WriteString(`</env:Body></env:Envelope>`)
+	return b.String()
+}
+
+// newUUID 随机 UUID（版本 4）
+func newUUID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return fmt.Sprintf("%X-%X-%X-%X-%X", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
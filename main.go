@@ -2,6 +2,7 @@ package main
 
 import (
 	"autocert/cmd"
+	"autocert/internal/console"
 	"autocert/internal/logger"
 	"os"
 )
@@ -22,7 +23,10 @@ func main() {
 
 	// 执行命令
 	if err := cmd.Execute(); err != nil {
-		logger.Error("程序执行失败", "error", err)
+		console.Error("%v", err)
+		logger.ErrorToFile("程序执行失败", "error", err)
 		os.Exit(1)
 	}
+
+	os.Exit(cmd.ExitCode())
 }
@@ -202,6 +202,13 @@ download_binary() {
         fi
     fi
     
+    # Install systemd units (daemon and socket activation), not enabled by default
+    if [[ -d "$temp_dir/systemd" && -d /etc/systemd/system ]]; then
+        install -m 644 "$temp_dir"/systemd/autocert.service "$temp_dir"/systemd/autocert.socket /etc/systemd/system/
+        systemctl daemon-reload 2>/dev/null || true
+        log_info "systemd units installed: autocert.service, autocert.socket (enable with: systemctl enable --now autocert.socket autocert.service)"
+    fi
+    
     # Clean up temporary files
     rm -rf "$temp_file" "$temp_dir"
 }
@@ -104,11 +104,15 @@ package_linux() {
     # 构建二进制文件
     build_binary "linux" "${arch}" "${binary_path}" "${build_flags}"
     
+    # 附带 systemd 单元（守护进程和套接字激活）
+    cp -r scripts/systemd "${DIST_DIR}/systemd"
+    
     # 创建 tar.gz 包
-    tar -czf "${package_path}" -C "${DIST_DIR}" "${BINARY_NAME}"
+    tar -czf "${package_path}" -C "${DIST_DIR}" "${BINARY_NAME}" systemd
     
     # 清理临时文件
     rm -f "${binary_path}"
+    rm -rf "${DIST_DIR}/systemd"
     
     log_info "Linux ${arch} 打包完成: ${package_name}"
     echo "  📦 ${package_path}"